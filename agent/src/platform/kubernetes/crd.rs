@@ -171,3 +171,198 @@ pub mod calico {
         }
     }
 }
+
+// batch/v1 CronJob is not available in k8s-openapi with feature v1_19
+pub mod batch {
+    use super::*;
+
+    use k8s_openapi::api::batch::v1::JobSpec;
+
+    #[derive(CustomResource, Clone, Debug, Serialize, Deserialize, JsonSchema)]
+    #[kube(group = "batch", version = "v1", kind = "CronJob", namespaced)]
+    #[serde(rename_all = "camelCase")]
+    pub struct CronJobSpec {
+        pub job_template: JobTemplateSpec,
+    }
+
+    #[derive(Clone, Debug, Default, Serialize, Deserialize, JsonSchema)]
+    pub struct JobTemplateSpec {
+        pub spec: Option<JobSpec>,
+    }
+
+    impl Trimmable for CronJob {
+        fn trim(mut self) -> Self {
+            let name = if let Some(name) = self.metadata.name.as_ref() {
+                name
+            } else {
+                ""
+            };
+            let spec = CronJobSpec {
+                job_template: JobTemplateSpec {
+                    spec: self.spec.job_template.spec.take().map(|s| JobSpec {
+                        template: s.template,
+                        ..Default::default()
+                    }),
+                },
+            };
+            let mut cj = Self::new(name, spec);
+            cj.metadata = ObjectMeta {
+                uid: self.metadata.uid.take(),
+                name: self.metadata.name.take(),
+                namespace: self.metadata.namespace.take(),
+                labels: self.metadata.labels.take(),
+                ..Default::default()
+            };
+            cj
+        }
+    }
+}
+
+pub mod gateway {
+    use super::*;
+
+    #[derive(Clone, Debug, Default, Serialize, Deserialize, JsonSchema)]
+    pub struct ParentReference {
+        pub kind: Option<String>,
+        pub namespace: Option<String>,
+        pub name: String,
+    }
+
+    #[derive(Clone, Debug, Default, Serialize, Deserialize, JsonSchema)]
+    pub struct BackendRef {
+        pub kind: Option<String>,
+        pub namespace: Option<String>,
+        pub name: String,
+        pub port: Option<i32>,
+    }
+
+    // matches of httproute and grpcroute are different, they are kept as they are
+    #[derive(Clone, Debug, Default, Serialize, Deserialize, JsonSchema)]
+    #[serde(rename_all = "camelCase")]
+    pub struct RouteRule {
+        pub matches: Option<Vec<serde_json::Value>>,
+        pub backend_refs: Option<Vec<BackendRef>>,
+    }
+
+    macro_rules! impl_trimmable {
+        ($($t:ty),+) => {
+            $(
+                impl Trimmable for $t {
+                    fn trim(mut self) -> Self {
+                        let name = if let Some(name) = self.metadata.name.as_ref() {
+                            name
+                        } else {
+                            ""
+                        };
+                        let mut res = Self::new(name, self.spec);
+                        res.metadata = ObjectMeta {
+                            uid: self.metadata.uid.take(),
+                            name: self.metadata.name.take(),
+                            namespace: self.metadata.namespace.take(),
+                            ..Default::default()
+                        };
+                        res
+                    }
+                }
+            )+
+        };
+    }
+
+    pub mod v1 {
+        use super::*;
+
+        #[derive(CustomResource, Clone, Debug, Serialize, Deserialize, JsonSchema)]
+        #[kube(
+            group = "gateway.networking.k8s.io",
+            version = "v1",
+            kind = "Gateway",
+            namespaced
+        )]
+        #[serde(rename_all = "camelCase")]
+        pub struct GatewaySpec {
+            pub gateway_class_name: Option<String>,
+        }
+
+        #[derive(CustomResource, Clone, Debug, Serialize, Deserialize, JsonSchema)]
+        #[kube(
+            group = "gateway.networking.k8s.io",
+            version = "v1",
+            kind = "HTTPRoute",
+            namespaced
+        )]
+        #[serde(rename_all = "camelCase")]
+        pub struct HTTPRouteSpec {
+            pub hostnames: Option<Vec<String>>,
+            pub parent_refs: Option<Vec<ParentReference>>,
+            pub rules: Option<Vec<RouteRule>>,
+        }
+
+        #[derive(CustomResource, Clone, Debug, Serialize, Deserialize, JsonSchema)]
+        #[kube(
+            group = "gateway.networking.k8s.io",
+            version = "v1",
+            kind = "GRPCRoute",
+            namespaced
+        )]
+        #[serde(rename_all = "camelCase")]
+        pub struct GRPCRouteSpec {
+            pub hostnames: Option<Vec<String>>,
+            pub parent_refs: Option<Vec<ParentReference>>,
+            pub rules: Option<Vec<RouteRule>>,
+        }
+
+        impl_trimmable!(Gateway, HTTPRoute, GRPCRoute);
+    }
+
+    pub mod v1beta1 {
+        use super::*;
+
+        #[derive(CustomResource, Clone, Debug, Serialize, Deserialize, JsonSchema)]
+        #[kube(
+            group = "gateway.networking.k8s.io",
+            version = "v1beta1",
+            kind = "Gateway",
+            namespaced
+        )]
+        #[serde(rename_all = "camelCase")]
+        pub struct GatewaySpec {
+            pub gateway_class_name: Option<String>,
+        }
+
+        #[derive(CustomResource, Clone, Debug, Serialize, Deserialize, JsonSchema)]
+        #[kube(
+            group = "gateway.networking.k8s.io",
+            version = "v1beta1",
+            kind = "HTTPRoute",
+            namespaced
+        )]
+        #[serde(rename_all = "camelCase")]
+        pub struct HTTPRouteSpec {
+            pub hostnames: Option<Vec<String>>,
+            pub parent_refs: Option<Vec<ParentReference>>,
+            pub rules: Option<Vec<RouteRule>>,
+        }
+
+        impl_trimmable!(Gateway, HTTPRoute);
+    }
+
+    pub mod v1alpha2 {
+        use super::*;
+
+        #[derive(CustomResource, Clone, Debug, Serialize, Deserialize, JsonSchema)]
+        #[kube(
+            group = "gateway.networking.k8s.io",
+            version = "v1alpha2",
+            kind = "GRPCRoute",
+            namespaced
+        )]
+        #[serde(rename_all = "camelCase")]
+        pub struct GRPCRouteSpec {
+            pub hostnames: Option<Vec<String>>,
+            pub parent_refs: Option<Vec<ParentReference>>,
+            pub rules: Option<Vec<RouteRule>>,
+        }
+
+        impl_trimmable!(GRPCRoute);
+    }
+}
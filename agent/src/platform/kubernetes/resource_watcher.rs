@@ -34,6 +34,10 @@ use k8s_openapi::{
             DaemonSet, DaemonSetSpec, Deployment, DeploymentSpec, ReplicaSet, ReplicaSetSpec,
             StatefulSet, StatefulSetSpec,
         },
+        batch::{
+            v1::{Job, JobSpec},
+            v1beta1::{CronJob as V1beta1CronJob, CronJobSpec, JobTemplateSpec},
+        },
         core::v1::{
             Container, ContainerStatus, Namespace, Node, NodeSpec, NodeStatus, Pod, PodSpec,
             PodStatus, ReplicationController, ReplicationControllerSpec, Service, ServiceSpec,
@@ -54,7 +58,9 @@ use serde::ser::Serialize;
 use tokio::{runtime::Handle, sync::Mutex, task::JoinHandle, time};
 
 use super::crd::{
+    batch::CronJob,
     calico::IpPool,
+    gateway::{v1 as gateway_v1, v1alpha2 as gateway_v1alpha2, v1beta1 as gateway_v1beta1},
     kruise::{CloneSet, StatefulSet as KruiseStatefulSet},
     pingan::ServiceRule,
 };
@@ -92,12 +98,21 @@ pub enum GenericResourceWatcher {
     V1beta1Ingress(ResourceWatcher<networking::v1beta1::Ingress>),
     ExtV1beta1Ingress(ResourceWatcher<extensions::v1beta1::Ingress>),
     Route(ResourceWatcher<Route>),
+    Job(ResourceWatcher<Job>),
+    V1beta1CronJob(ResourceWatcher<V1beta1CronJob>),
 
     // CRDs
     ServiceRule(ResourceWatcher<ServiceRule>),
     CloneSet(ResourceWatcher<CloneSet>),
     KruiseStatefulSet(ResourceWatcher<KruiseStatefulSet>),
     IpPool(ResourceWatcher<IpPool>),
+    CronJob(ResourceWatcher<CronJob>),
+    V1Gateway(ResourceWatcher<gateway_v1::Gateway>),
+    V1beta1Gateway(ResourceWatcher<gateway_v1beta1::Gateway>),
+    V1HTTPRoute(ResourceWatcher<gateway_v1::HTTPRoute>),
+    V1beta1HTTPRoute(ResourceWatcher<gateway_v1beta1::HTTPRoute>),
+    V1GRPCRoute(ResourceWatcher<gateway_v1::GRPCRoute>),
+    V1alpha2GRPCRoute(ResourceWatcher<gateway_v1alpha2::GRPCRoute>),
 }
 
 #[derive(Clone, Copy, Debug, PartialEq, Eq)]
@@ -233,6 +248,30 @@ pub fn default_resources() -> Vec<Resource> {
             ],
             selected_gv: None,
         },
+        Resource {
+            name: "jobs",
+            pb_name: "*v1.Job",
+            group_versions: vec![GroupVersion {
+                group: "batch",
+                version: "v1",
+            }],
+            selected_gv: None,
+        },
+        Resource {
+            name: "cronjobs",
+            pb_name: "*v1.CronJob",
+            group_versions: vec![
+                GroupVersion {
+                    group: "batch",
+                    version: "v1",
+                },
+                GroupVersion {
+                    group: "batch",
+                    version: "v1beta1",
+                },
+            ],
+            selected_gv: None,
+        },
     ]
 }
 
@@ -353,6 +392,30 @@ pub fn supported_resources() -> Vec<Resource> {
             }],
             selected_gv: None,
         },
+        Resource {
+            name: "jobs",
+            pb_name: "*v1.Job",
+            group_versions: vec![GroupVersion {
+                group: "batch",
+                version: "v1",
+            }],
+            selected_gv: None,
+        },
+        Resource {
+            name: "cronjobs",
+            pb_name: "*v1.CronJob",
+            group_versions: vec![
+                GroupVersion {
+                    group: "batch",
+                    version: "v1",
+                },
+                GroupVersion {
+                    group: "batch",
+                    version: "v1beta1",
+                },
+            ],
+            selected_gv: None,
+        },
         Resource {
             name: "servicerules",
             pb_name: "*v1.ServiceRule",
@@ -380,6 +443,52 @@ pub fn supported_resources() -> Vec<Resource> {
             }],
             selected_gv: None,
         },
+        // pb_name of gateway api resources is the one of selected version
+        Resource {
+            name: "gateways",
+            pb_name: "*v1.Gateway",
+            group_versions: vec![
+                GroupVersion {
+                    group: "gateway.networking.k8s.io",
+                    version: "v1",
+                },
+                GroupVersion {
+                    group: "gateway.networking.k8s.io",
+                    version: "v1beta1",
+                },
+            ],
+            selected_gv: None,
+        },
+        Resource {
+            name: "httproutes",
+            pb_name: "*v1.HTTPRoute",
+            group_versions: vec![
+                GroupVersion {
+                    group: "gateway.networking.k8s.io",
+                    version: "v1",
+                },
+                GroupVersion {
+                    group: "gateway.networking.k8s.io",
+                    version: "v1beta1",
+                },
+            ],
+            selected_gv: None,
+        },
+        Resource {
+            name: "grpcroutes",
+            pb_name: "*v1.GRPCRoute",
+            group_versions: vec![
+                GroupVersion {
+                    group: "gateway.networking.k8s.io",
+                    version: "v1",
+                },
+                GroupVersion {
+                    group: "gateway.networking.k8s.io",
+                    version: "v1alpha2",
+                },
+            ],
+            selected_gv: None,
+        },
     ]
 }
 
@@ -1148,6 +1257,54 @@ impl Trimmable for Service {
     }
 }
 
+impl Trimmable for Job {
+    fn trim(mut self) -> Self {
+        let mut trim_job = Job::default();
+        trim_job.metadata = ObjectMeta {
+            uid: self.metadata.uid.take(),
+            name: self.metadata.name.take(),
+            namespace: self.metadata.namespace.take(),
+            owner_references: self.metadata.owner_references.take(),
+            labels: self.metadata.labels.take(),
+            ..Default::default()
+        };
+        if let Some(job_spec) = self.spec.take() {
+            trim_job.spec = Some(JobSpec {
+                parallelism: job_spec.parallelism,
+                template: job_spec.template,
+                ..Default::default()
+            });
+        }
+        trim_job
+    }
+}
+
+impl Trimmable for V1beta1CronJob {
+    fn trim(mut self) -> Self {
+        let mut trim_cj = V1beta1CronJob::default();
+        trim_cj.metadata = ObjectMeta {
+            uid: self.metadata.uid.take(),
+            name: self.metadata.name.take(),
+            namespace: self.metadata.namespace.take(),
+            labels: self.metadata.labels.take(),
+            ..Default::default()
+        };
+        if let Some(cj_spec) = self.spec.take() {
+            trim_cj.spec = Some(CronJobSpec {
+                job_template: JobTemplateSpec {
+                    spec: cj_spec.job_template.spec.map(|s| JobSpec {
+                        template: s.template,
+                        ..Default::default()
+                    }),
+                    ..Default::default()
+                },
+                ..Default::default()
+            });
+        }
+        trim_cj
+    }
+}
+
 impl Trimmable for Namespace {
     fn trim(mut self) -> Self {
         let mut trim_ns = Namespace::default();
@@ -1207,7 +1364,7 @@ impl ResourceWatcherFactory {
 
     pub fn new_watcher(
         &self,
-        resource: Resource,
+        mut resource: Resource,
         namespace: Option<&str>,
         stats_collector: &stats::Collector,
         config: &WatcherConfig,
@@ -1348,6 +1505,112 @@ impl ResourceWatcherFactory {
                 namespace,
                 config,
             )),
+            "jobs" => GenericResourceWatcher::Job(self.new_watcher_inner(
+                resource,
+                stats_collector,
+                namespace,
+                config,
+            )),
+            "cronjobs" => match resource.selected_gv.as_ref().unwrap() {
+                GroupVersion {
+                    group: "batch",
+                    version: "v1",
+                } => GenericResourceWatcher::CronJob(self.new_watcher_inner(
+                    resource,
+                    stats_collector,
+                    namespace,
+                    config,
+                )),
+                GroupVersion {
+                    group: "batch",
+                    version: "v1beta1",
+                } => GenericResourceWatcher::V1beta1CronJob(self.new_watcher_inner(
+                    resource,
+                    stats_collector,
+                    namespace,
+                    config,
+                )),
+                _ => {
+                    warn!(
+                        "unsupported resource {} group version {}",
+                        resource.name,
+                        resource.selected_gv.as_ref().unwrap()
+                    );
+                    return None;
+                }
+            },
+            "gateways" => match resource.selected_gv.unwrap().version {
+                "v1" => GenericResourceWatcher::V1Gateway(self.new_watcher_inner(
+                    resource,
+                    stats_collector,
+                    namespace,
+                    config,
+                )),
+                "v1beta1" => {
+                    resource.pb_name = "*v1beta1.Gateway";
+                    GenericResourceWatcher::V1beta1Gateway(self.new_watcher_inner(
+                        resource,
+                        stats_collector,
+                        namespace,
+                        config,
+                    ))
+                }
+                version => {
+                    warn!(
+                        "unsupported resource {} group version {}",
+                        resource.name, version
+                    );
+                    return None;
+                }
+            },
+            "httproutes" => match resource.selected_gv.unwrap().version {
+                "v1" => GenericResourceWatcher::V1HTTPRoute(self.new_watcher_inner(
+                    resource,
+                    stats_collector,
+                    namespace,
+                    config,
+                )),
+                "v1beta1" => {
+                    resource.pb_name = "*v1beta1.HTTPRoute";
+                    GenericResourceWatcher::V1beta1HTTPRoute(self.new_watcher_inner(
+                        resource,
+                        stats_collector,
+                        namespace,
+                        config,
+                    ))
+                }
+                version => {
+                    warn!(
+                        "unsupported resource {} group version {}",
+                        resource.name, version
+                    );
+                    return None;
+                }
+            },
+            "grpcroutes" => match resource.selected_gv.unwrap().version {
+                "v1" => GenericResourceWatcher::V1GRPCRoute(self.new_watcher_inner(
+                    resource,
+                    stats_collector,
+                    namespace,
+                    config,
+                )),
+                "v1alpha2" => {
+                    resource.pb_name = "*v1alpha2.GRPCRoute";
+                    GenericResourceWatcher::V1alpha2GRPCRoute(self.new_watcher_inner(
+                        resource,
+                        stats_collector,
+                        namespace,
+                        config,
+                    ))
+                }
+                version => {
+                    warn!(
+                        "unsupported resource {} group version {}",
+                        resource.name, version
+                    );
+                    return None;
+                }
+            },
             _ => {
                 warn!("unsupported resource {}", resource.name);
                 return None;
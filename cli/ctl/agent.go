@@ -123,6 +123,9 @@ deepflow-ctl agent rebalance --type=analyzer`,
 	agent.AddCommand(update)
 	agent.AddCommand(updateExample)
 	agent.AddCommand(rebalanceCmd)
+	for _, execCmd := range registerAgentExecCommands() {
+		agent.AddCommand(execCmd)
+	}
 	return agent
 }
 
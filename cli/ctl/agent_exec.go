@@ -0,0 +1,300 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ctl
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/spf13/cobra"
+
+	"github.com/deepflowio/deepflow/cli/ctl/common"
+	"github.com/deepflowio/deepflow/cli/ctl/common/table"
+)
+
+const (
+	agentCMDJobStateRunning  = 0
+	agentCMDJobStateFinished = 1 // other states mean the job is aborted
+
+	agentCMDResultStatePending = 0
+	agentCMDResultStateSuccess = 1
+
+	agentCMDJobPollInterval = time.Second
+)
+
+type agentExecOptions struct {
+	agentIDs    []int
+	group       string
+	commandID   uint32
+	params      []string
+	nsPid       uint32
+	concurrency int
+	timeout     int
+}
+
+func registerAgentExecCommands() []*cobra.Command {
+	opts := &agentExecOptions{}
+	exec := &cobra.Command{
+		Use:   "exec",
+		Short: "run a command on agents of a group or of the ids in parallel",
+		Example: `deepflow-ctl agent exec --group g-xxx --command-id 1
+deepflow-ctl agent exec --agent-ids 1,2,3 --command-id 2 --param pid=1234 --concurrency 5 --job-timeout 30`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := execAgentCMD(cmd, opts); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		},
+	}
+	exec.Flags().IntSliceVar(&opts.agentIDs, "agent-ids", nil, "ids of the agents, separated by ','")
+	exec.Flags().StringVar(&opts.group, "group", "", "lcuuid or name of the agent group")
+	exec.Flags().Uint32Var(&opts.commandID, "command-id", 0, "id of the command, run 'deepflow-ctl agent exec-commands <agent-id>' to get the ids")
+	exec.Flags().StringArrayVar(&opts.params, "param", nil, "parameter of the command as key=value, could be specified multiple times")
+	exec.Flags().Uint32Var(&opts.nsPid, "ns-pid", 0, "run the command in the linux namespace of the pid")
+	exec.Flags().IntVar(&opts.concurrency, "concurrency", 0, "number of agents running the command at the same time, default: 10")
+	exec.Flags().IntVar(&opts.timeout, "job-timeout", 0, "timeout of the command on each agent in seconds, default: 60")
+	exec.MarkFlagRequired("command-id")
+
+	execCommands := &cobra.Command{
+		Use:     "exec-commands <agent-id>",
+		Short:   "list commands and linux namespaces of the agent",
+		Example: "deepflow-ctl agent exec-commands 1",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := listAgentCMD(cmd, args); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		},
+	}
+
+	execJob := &cobra.Command{
+		Use:   "exec-job [id]",
+		Short: "list agent command jobs or show results of the job",
+		Example: `deepflow-ctl agent exec-job
+deepflow-ctl agent exec-job 1`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := getAgentCMDJob(cmd, args); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		},
+	}
+	return []*cobra.Command{exec, execCommands, execJob}
+}
+
+func execAgentCMD(cmd *cobra.Command, opts *agentExecOptions) error {
+	if len(opts.agentIDs) == 0 && opts.group == "" {
+		return fmt.Errorf("must specify --agent-ids or --group.\nExample: %s", cmd.Example)
+	}
+	httpOpts := []common.HTTPOption{common.WithTimeout(common.GetTimeout(cmd)), common.WithORGID(common.GetORGID(cmd))}
+	server := common.GetServerInfo(cmd)
+
+	body := map[string]interface{}{
+		"COMMAND_ID":   opts.commandID,
+		"LINUX_NS_PID": opts.nsPid,
+		"CONCURRENCY":  opts.concurrency,
+		"TIMEOUT":      opts.timeout,
+	}
+	if len(opts.agentIDs) > 0 {
+		body["AGENT_IDS"] = opts.agentIDs
+	}
+	if opts.group != "" {
+		groupLcuuid, err := getAgentGroupLcuuid(cmd, opts.group)
+		if err != nil {
+			return err
+		}
+		body["AGENT_GROUP_LCUUID"] = groupLcuuid
+	}
+	params := make([]map[string]string, 0, len(opts.params))
+	for _, param := range opts.params {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid param (%s), should be key=value", param)
+		}
+		params = append(params, map[string]string{"key": kv[0], "value": kv[1]})
+	}
+	body["PARAMS"] = params
+
+	url := fmt.Sprintf("http://%s:%d/v1/agent-cmd-jobs/", server.IP, server.Port)
+	response, err := common.CURLPerform("POST", url, body, "", httpOpts...)
+	if err != nil {
+		return err
+	}
+	job := response.Get("DATA")
+	jobID := job.Get("ID").MustInt()
+	agentCount := len(job.Get("AGENT_IDS").MustArray())
+	fmt.Printf("job (%d) is running on %d agents\n", jobID, agentCount)
+
+	// agents run the command in batches of the concurrency, give the job some more time than that
+	concurrency := job.Get("CONCURRENCY").MustInt()
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	batches := (agentCount + concurrency - 1) / concurrency
+	deadline := time.Now().Add(time.Duration(batches*job.Get("TIMEOUT").MustInt())*time.Second + common.GetTimeout(cmd))
+	url = fmt.Sprintf("http://%s:%d/v1/agent-cmd-jobs/%d/", server.IP, server.Port, jobID)
+	for job.Get("STATE").MustInt() == agentCMDJobStateRunning {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("wait for job (%d) timeout, run 'deepflow-ctl agent exec-job %d' to get results later", jobID, jobID)
+		}
+		time.Sleep(agentCMDJobPollInterval)
+		response, err = common.CURLPerform("GET", url, nil, "", httpOpts...)
+		if err != nil {
+			return err
+		}
+		job = response.Get("DATA")
+	}
+	printAgentCMDJobResults(job)
+	return nil
+}
+
+// getAgentGroupLcuuid accepts both the lcuuid and the name of the agent group
+func getAgentGroupLcuuid(cmd *cobra.Command, group string) (string, error) {
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/vtap-groups/", server.IP, server.Port)
+	response, err := common.CURLPerform("GET", url, nil, "",
+		[]common.HTTPOption{common.WithTimeout(common.GetTimeout(cmd)), common.WithORGID(common.GetORGID(cmd))}...)
+	if err != nil {
+		return "", err
+	}
+	for i := range response.Get("DATA").MustArray() {
+		agentGroup := response.Get("DATA").GetIndex(i)
+		if agentGroup.Get("LCUUID").MustString() == group || agentGroup.Get("NAME").MustString() == group {
+			return agentGroup.Get("LCUUID").MustString(), nil
+		}
+	}
+	return "", fmt.Errorf("agent group (%s) not found", group)
+}
+
+func listAgentCMD(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("must specify agent id.\nExample: %s", cmd.Example)
+	}
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v1/agent/%s/cmd", server.IP, server.Port, args[0])
+	response, err := common.CURLPerform("GET", url, nil, "",
+		[]common.HTTPOption{common.WithTimeout(common.GetTimeout(cmd)), common.WithORGID(common.GetORGID(cmd))}...)
+	if err != nil {
+		return err
+	}
+
+	commands := response.Get("DATA").Get("remote_commands")
+	t := table.New()
+	t.SetHeader([]string{"ID", "COMMAND", "PARAMS"})
+	for i := range commands.MustArray() {
+		command := commands.GetIndex(i)
+		t.Append([]string{
+			strconv.Itoa(command.Get("id").MustInt()),
+			command.Get("cmd").MustString(),
+			strings.Join(command.Get("param_names").MustStringArray(), ","),
+		})
+	}
+	t.Render()
+
+	namespaces := response.Get("DATA").Get("linux_namespaces")
+	t = table.New()
+	t.SetHeader([]string{"NS_ID", "NS_TYPE", "PID", "USER", "CMD"})
+	for i := range namespaces.MustArray() {
+		namespace := namespaces.GetIndex(i)
+		t.Append([]string{
+			strconv.FormatUint(namespace.Get("id").MustUint64(), 10),
+			namespace.Get("ns_type").MustString(),
+			strconv.Itoa(namespace.Get("pid").MustInt()),
+			namespace.Get("user").MustString(),
+			namespace.Get("cmd").MustString(),
+		})
+	}
+	t.Render()
+	return nil
+}
+
+func getAgentCMDJob(cmd *cobra.Command, args []string) error {
+	server := common.GetServerInfo(cmd)
+	httpOpts := []common.HTTPOption{common.WithTimeout(common.GetTimeout(cmd)), common.WithORGID(common.GetORGID(cmd))}
+	if len(args) > 0 {
+		url := fmt.Sprintf("http://%s:%d/v1/agent-cmd-jobs/%s/", server.IP, server.Port, args[0])
+		response, err := common.CURLPerform("GET", url, nil, "", httpOpts...)
+		if err != nil {
+			return err
+		}
+		printAgentCMDJobResults(response.Get("DATA"))
+		return nil
+	}
+
+	url := fmt.Sprintf("http://%s:%d/v1/agent-cmd-jobs/", server.IP, server.Port)
+	response, err := common.CURLPerform("GET", url, nil, "", httpOpts...)
+	if err != nil {
+		return err
+	}
+	t := table.New()
+	t.SetHeader([]string{"ID", "USER_ID", "COMMAND_ID", "AGENT_NUM", "STATE", "SUCCESS", "FAILED", "CREATED_AT", "FINISHED_AT"})
+	for i := range response.Get("DATA").MustArray() {
+		job := response.Get("DATA").GetIndex(i)
+		t.Append([]string{
+			strconv.Itoa(job.Get("ID").MustInt()),
+			strconv.Itoa(job.Get("USER_ID").MustInt()),
+			strconv.Itoa(job.Get("COMMAND_ID").MustInt()),
+			strconv.Itoa(len(job.Get("AGENT_IDS").MustArray())),
+			agentCMDJobState(job),
+			strconv.Itoa(job.Get("SUCCESS_COUNT").MustInt()),
+			strconv.Itoa(job.Get("FAILED_COUNT").MustInt()),
+			job.Get("CREATED_AT").MustString(),
+			job.Get("FINISHED_AT").MustString(),
+		})
+	}
+	t.Render()
+	return nil
+}
+
+func agentCMDJobState(job *simplejson.Json) string {
+	switch job.Get("STATE").MustInt() {
+	case agentCMDJobStateRunning:
+		return "RUNNING"
+	case agentCMDJobStateFinished:
+		return "FINISHED"
+	default:
+		return "ABORTED"
+	}
+}
+
+func printAgentCMDJobResults(job *simplejson.Json) {
+	results := job.Get("RESULTS")
+	for i := range results.MustArray() {
+		result := results.GetIndex(i)
+		state := "FAILED"
+		switch result.Get("STATE").MustInt() {
+		case agentCMDResultStatePending:
+			state = "PENDING"
+		case agentCMDResultStateSuccess:
+			state = "SUCCESS"
+		}
+		fmt.Printf("==================== agent %s (id: %d) %s ====================\n",
+			result.Get("AGENT_NAME").MustString(), result.Get("AGENT_ID").MustInt(), state)
+		if content := result.Get("CONTENT").MustString(); content != "" {
+			fmt.Println(strings.TrimRight(content, "\n"))
+		}
+		if errMsg := result.Get("ERRMSG").MustString(); errMsg != "" {
+			fmt.Fprintln(os.Stderr, errMsg)
+		}
+	}
+	fmt.Printf("job (%d) %s, success: %d, failed: %d\n", job.Get("ID").MustInt(), agentCMDJobState(job),
+		job.Get("SUCCESS_COUNT").MustInt(), job.Get("FAILED_COUNT").MustInt())
+	if job.Get("STATE").MustInt() != agentCMDJobStateRunning && job.Get("FAILED_COUNT").MustInt() > 0 {
+		fmt.Fprintln(os.Stderr, "the command failed on some agents")
+	}
+}
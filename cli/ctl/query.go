@@ -0,0 +1,293 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ctl
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	simplejson "github.com/bitly/go-simplejson"
+	"github.com/spf13/cobra"
+
+	"github.com/deepflowio/deepflow/cli/ctl/common"
+	"github.com/deepflowio/deepflow/cli/ctl/common/table"
+)
+
+type QueryOutputFormat string
+
+const (
+	QUERY_OUTPUT_TABLE QueryOutputFormat = "table"
+	QUERY_OUTPUT_CSV   QueryOutputFormat = "csv"
+	QUERY_OUTPUT_JSON  QueryOutputFormat = "json"
+)
+
+func RegisterQueryCommand() *cobra.Command {
+	query := &cobra.Command{
+		Use:   "query",
+		Short: "query data from deepflow-server querier",
+		Example: "deepflow-ctl query sql --db flow_log \"SELECT Sum(byte) AS total FROM l4_flow_log WHERE time>=now()-300\"\n" +
+			"deepflow-ctl query promql --since 10m --step 1m \"sum(rate(flow_metrics__network__byte[1m]))\" -o csv",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println("please run with 'sql | promql'.")
+		},
+	}
+	query.PersistentFlags().StringP("output", "o", string(QUERY_OUTPUT_TABLE), "output format, one of [table, csv, json]")
+
+	query.AddCommand(sqlQuerySubCommand())
+	query.AddCommand(promQLQuerySubCommand())
+	return query
+}
+
+func sqlQuerySubCommand() *cobra.Command {
+	var db, dataPrecision string
+	sql := &cobra.Command{
+		Use:   "sql",
+		Short: "query with deepflow SQL",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := sqlQuery(cmd, db, dataPrecision, args[0]); err != nil {
+				fmt.Fprintf(os.Stderr, "sql query error: %v\n", err)
+			}
+		},
+	}
+	sql.Flags().StringVarP(&db, "db", "d", "flow_log", "database to query, e.g.: flow_log, flow_metrics, event, profile")
+	sql.Flags().StringVarP(&dataPrecision, "data-precision", "", "", "data precision of flow_metrics, e.g.: 1s, 1m")
+	return sql
+}
+
+func promQLQuerySubCommand() *cobra.Command {
+	var step string
+	var instant bool
+	promql := &cobra.Command{
+		Use:   "promql",
+		Short: "query with PromQL",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			from, to, err := getQueryTime(cmd)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "parse time error: %v\n", err)
+				return
+			}
+			if err := promQLQuery(cmd, args[0], from, to, step, instant); err != nil {
+				fmt.Fprintf(os.Stderr, "promql query error: %v\n", err)
+			}
+		},
+	}
+	promql.Flags().String("since", "1h", "query since time duration like [5s,1m,5m,1h], default: 1h")
+	promql.Flags().String("from", "", "query from a specific time(RFC3339), e.g.: 2000-01-01T00:00:00Z")
+	promql.Flags().String("to", "", "query to a specific time(RFC3339), e.g.: 2000-01-01T00:00:00Z")
+	promql.Flags().StringVarP(&step, "step", "", "60s", "query resolution step of range query")
+	promql.Flags().BoolVarP(&instant, "instant", "", false, "instant query at the end of time range")
+	return promql
+}
+
+func getQueryOutputFormat(cmd *cobra.Command) (QueryOutputFormat, error) {
+	output, _ := cmd.Flags().GetString("output")
+	switch QueryOutputFormat(output) {
+	case QUERY_OUTPUT_TABLE, QUERY_OUTPUT_CSV, QUERY_OUTPUT_JSON:
+		return QueryOutputFormat(output), nil
+	}
+	return "", fmt.Errorf("unknown output format %s", output)
+}
+
+func sqlQuery(cmd *cobra.Command, db, dataPrecision, sql string) error {
+	format, err := getQueryOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	server := common.GetServerInfo(cmd)
+	queryURL := fmt.Sprintf("http://%s:%d/v1/query/", server.IP, server.QuerierPort)
+	values := url.Values{}
+	values.Set("db", db)
+	values.Set("sql", sql)
+	if dataPrecision != "" {
+		values.Set("data_precision", dataPrecision)
+	}
+	response, err := common.CURLPerform("POST", queryURL, nil, values.Encode(),
+		[]common.HTTPOption{common.WithTimeout(common.GetTimeout(cmd)), common.WithORGID(common.GetORGID(cmd))}...)
+	if err != nil {
+		return err
+	}
+
+	result := response.Get("result")
+	if format == QUERY_OUTPUT_JSON {
+		common.PrettyPrint(result.Interface())
+		return nil
+	}
+	header := result.Get("columns").MustStringArray()
+	rows := make([][]string, 0, len(result.Get("values").MustArray()))
+	for _, value := range result.Get("values").MustArray() {
+		columns, _ := value.([]interface{})
+		row := make([]string, 0, len(columns))
+		for _, column := range columns {
+			row = append(row, formatQueryValue(column))
+		}
+		rows = append(rows, row)
+	}
+	return renderQueryResult(format, header, rows)
+}
+
+func promQLQuery(cmd *cobra.Command, promql string, from, to int64, step string, instant bool) error {
+	format, err := getQueryOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	server := common.GetServerInfo(cmd)
+	values := url.Values{}
+	values.Set("query", promql)
+	queryURL := fmt.Sprintf("http://%s:%d/prom/api/v1/query_range", server.IP, server.QuerierPort)
+	if instant {
+		queryURL = fmt.Sprintf("http://%s:%d/prom/api/v1/query", server.IP, server.QuerierPort)
+		values.Set("time", strconv.FormatInt(to, 10))
+	} else {
+		stepDuration, err := time.ParseDuration(step)
+		if err != nil {
+			return err
+		}
+		values.Set("start", strconv.FormatInt(from, 10))
+		values.Set("end", strconv.FormatInt(to, 10))
+		values.Set("step", strconv.FormatFloat(stepDuration.Seconds(), 'f', -1, 64))
+	}
+	response, err := common.CURLPerform("POST", queryURL, nil, values.Encode(),
+		[]common.HTTPOption{common.WithTimeout(common.GetTimeout(cmd)), common.WithORGID(common.GetORGID(cmd))}...)
+	if err != nil {
+		return err
+	}
+	if status := response.Get("status").MustString(); status != "success" {
+		return fmt.Errorf("%s: %s", response.Get("errorType").MustString(), response.Get("error").MustString())
+	}
+
+	data := response.Get("data")
+	if format == QUERY_OUTPUT_JSON {
+		common.PrettyPrint(data.Interface())
+		return nil
+	}
+	header, rows := promQLResultToRows(data)
+	return renderQueryResult(format, header, rows)
+}
+
+// promQLResultToRows flattens matrix, vector and scalar results, each sample is a row of series labels, time and value
+func promQLResultToRows(data *simplejson.Json) ([]string, [][]string) {
+	type series struct {
+		labels  map[string]string
+		samples [][]interface{}
+	}
+	var seriesList []series
+	switch data.Get("resultType").MustString() {
+	case "scalar", "string":
+		sample, _ := data.Get("result").Interface().([]interface{})
+		seriesList = append(seriesList, series{samples: [][]interface{}{sample}})
+	default:
+		for i := range data.Get("result").MustArray() {
+			item := data.Get("result").GetIndex(i)
+			s := series{labels: map[string]string{}}
+			for k, v := range item.Get("metric").MustMap() {
+				s.labels[k], _ = v.(string)
+			}
+			if sample, ok := item.CheckGet("value"); ok {
+				s.samples = append(s.samples, sample.MustArray())
+			}
+			for _, sample := range item.Get("values").MustArray() {
+				if sample, ok := sample.([]interface{}); ok {
+					s.samples = append(s.samples, sample)
+				}
+			}
+			seriesList = append(seriesList, s)
+		}
+	}
+
+	labelSet := map[string]bool{}
+	for _, s := range seriesList {
+		for k := range s.labels {
+			labelSet[k] = true
+		}
+	}
+	labelNames := make([]string, 0, len(labelSet))
+	for k := range labelSet {
+		labelNames = append(labelNames, k)
+	}
+	// __name__ is the first column
+	sort.Slice(labelNames, func(i, j int) bool {
+		if labelNames[i] == "__name__" || labelNames[j] == "__name__" {
+			return labelNames[i] == "__name__"
+		}
+		return labelNames[i] < labelNames[j]
+	})
+
+	header := append(append([]string{}, labelNames...), "time", "value")
+	var rows [][]string
+	for _, s := range seriesList {
+		for _, sample := range s.samples {
+			if len(sample) != 2 {
+				continue
+			}
+			row := make([]string, 0, len(header))
+			for _, name := range labelNames {
+				row = append(row, s.labels[name])
+			}
+			timestamp, _ := strconv.ParseFloat(formatQueryValue(sample[0]), 64)
+			row = append(row, time.Unix(int64(timestamp), 0).Format(time.RFC3339), formatQueryValue(sample[1]))
+			rows = append(rows, row)
+		}
+	}
+	return header, rows
+}
+
+func formatQueryValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+func renderQueryResult(format QueryOutputFormat, header []string, rows [][]string) error {
+	if format == QUERY_OUTPUT_CSV {
+		w := csv.NewWriter(os.Stdout)
+		if err := w.Write(header); err != nil {
+			return err
+		}
+		if err := w.WriteAll(rows); err != nil {
+			return err
+		}
+		return nil
+	}
+	t := table.New()
+	t.SetHeader(header)
+	t.AppendBulk(rows)
+	t.Render()
+	return nil
+}
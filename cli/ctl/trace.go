@@ -0,0 +1,190 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/deepflowio/deepflow/cli/ctl/common"
+	"github.com/deepflowio/deepflow/cli/ctl/common/table"
+)
+
+// tempo trace in the json format of the querier tempo api
+type tempoTrace struct {
+	Batches []struct {
+		Resource struct {
+			Attributes []tempoKeyValue `json:"attributes"`
+		} `json:"resource"`
+		InstrumentationLibrarySpans []struct {
+			Spans []tempoSpan `json:"spans"`
+		} `json:"instrumentationLibrarySpans"`
+	} `json:"batches"`
+}
+
+type tempoKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type tempoSpan struct {
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId"`
+	Name              string          `json:"name"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []tempoKeyValue `json:"attributes"`
+}
+
+type traceSpan struct {
+	service   string
+	name      string
+	tapSide   string
+	startTime int64 // ns
+	endTime   int64 // ns
+	children  []*traceSpan
+}
+
+func RegisterTraceCommand() *cobra.Command {
+	trace := &cobra.Command{
+		Use:     "trace <trace_id>",
+		Short:   "print the span tree of a trace",
+		Example: "deepflow-ctl trace 5a9b7c8d0e1f2a3b4c5d6e7f8a9b0c1d --since 24h",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			from, to, err := getQueryTime(cmd)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "parse time error: %v\n", err)
+				return
+			}
+			if err := printTrace(cmd, args[0], from, to); err != nil {
+				fmt.Fprintf(os.Stderr, "trace query error: %v\n", err)
+			}
+		},
+	}
+	trace.Flags().String("since", "1h", "search trace since time duration like [5s,1m,5m,1h], default: 1h")
+	trace.Flags().String("from", "", "search trace from a specific time(RFC3339), e.g.: 2000-01-01T00:00:00Z")
+	trace.Flags().String("to", "", "search trace to a specific time(RFC3339), e.g.: 2000-01-01T00:00:00Z")
+	return trace
+}
+
+func printTrace(cmd *cobra.Command, traceID string, from, to int64) error {
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/api/traces/%s?start=%d&end=%d", server.IP, server.QuerierPort, traceID, from, to)
+	response, err := common.CURLPerform("GET", url, nil, "",
+		[]common.HTTPOption{common.WithTimeout(common.GetTimeout(cmd)), common.WithORGID(common.GetORGID(cmd))}...)
+	if err != nil {
+		return err
+	}
+	data, err := response.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	trace := tempoTrace{}
+	if err := json.Unmarshal(data, &trace); err != nil {
+		return err
+	}
+
+	roots := buildSpanTree(&trace)
+	if len(roots) == 0 {
+		return fmt.Errorf("trace %s not found", traceID)
+	}
+	traceStart := roots[0].startTime
+	t := table.New()
+	t.SetHeader([]string{"SPAN", "SERVICE", "TAP_SIDE", "START", "DURATION"})
+	var appendSpan func(span *traceSpan, prefix, childPrefix string)
+	appendSpan = func(span *traceSpan, prefix, childPrefix string) {
+		t.Append([]string{
+			prefix + span.name,
+			span.service,
+			span.tapSide,
+			"+" + time.Duration(span.startTime-traceStart).String(),
+			time.Duration(span.endTime - span.startTime).String(),
+		})
+		for i, child := range span.children {
+			if i == len(span.children)-1 {
+				appendSpan(child, childPrefix+"└─ ", childPrefix+"   ")
+			} else {
+				appendSpan(child, childPrefix+"├─ ", childPrefix+"│  ")
+			}
+		}
+	}
+	for _, root := range roots {
+		appendSpan(root, "", "")
+	}
+	t.Render()
+	return nil
+}
+
+// buildSpanTree returns root spans sorted by start time, spans whose parent is not in the trace are roots
+func buildSpanTree(trace *tempoTrace) []*traceSpan {
+	spans := make(map[string]*traceSpan)
+	parents := make(map[string]string)
+	order := []string{}
+	for _, batch := range trace.Batches {
+		service := ""
+		for _, attr := range batch.Resource.Attributes {
+			if attr.Key == "service.name" {
+				service = attr.Value.StringValue
+			}
+		}
+		for _, library := range batch.InstrumentationLibrarySpans {
+			for _, s := range library.Spans {
+				span := &traceSpan{service: service, name: s.Name}
+				span.startTime, _ = strconv.ParseInt(s.StartTimeUnixNano, 10, 64)
+				span.endTime, _ = strconv.ParseInt(s.EndTimeUnixNano, 10, 64)
+				for _, attr := range s.Attributes {
+					if attr.Key == "tap_side" {
+						span.tapSide = attr.Value.StringValue
+					}
+				}
+				spans[s.SpanID] = span
+				parents[s.SpanID] = s.ParentSpanID
+				order = append(order, s.SpanID)
+			}
+		}
+	}
+
+	var roots []*traceSpan
+	for _, id := range order {
+		span := spans[id]
+		if parent, ok := spans[parents[id]]; ok && parents[id] != id {
+			parent.children = append(parent.children, span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+	sortSpans(roots)
+	return roots
+}
+
+func sortSpans(spans []*traceSpan) {
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].startTime < spans[j].startTime
+	})
+	for _, span := range spans {
+		sortSpans(span.children)
+	}
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/op/go-logging"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+)
+
+var log = logging.MustGetLogger("cloud.azure")
+
+const (
+	API_VERSION_RESOURCES = "2021-04-01"
+	API_VERSION_LOCATIONS = "2022-12-01"
+	API_VERSION_COMPUTE   = "2023-09-01"
+	API_VERSION_NETWORK   = "2023-09-01"
+)
+
+// Azure learns resources of a subscription by the azure resource manager rest api,
+// every virtual network is regarded as a vpc, and every subnet of it is regarded as a network
+type Azure struct {
+	orgID          int
+	teamID         int
+	lcuuid         string
+	lcuuidGenerate string
+	name           string
+	httpTimeout    int
+	config         *Config
+	token          *Token
+	toolDataSet    *ToolDataSet       // 处理资源数据时，构建的需要提供给其他资源使用的工具数据
+	cloudStatsd    statsd.CloudStatsd // 性能监控
+	debugger       *cloudcommon.Debugger
+}
+
+func NewAzure(orgID int, domain mysql.Domain, globalCloudCfg config.CloudConfig) (*Azure, error) {
+	conf := &Config{}
+	err := conf.LoadFromString(domain.Config)
+	if err != nil {
+		return nil, err
+	}
+	return newAzure(orgID, domain, globalCloudCfg, conf), nil
+}
+
+func newAzure(orgID int, domain mysql.Domain, globalCloudCfg config.CloudConfig, conf *Config) *Azure {
+	conf.tidy()
+	return &Azure{
+		orgID:  orgID,
+		teamID: domain.TeamID,
+		lcuuid: domain.Lcuuid,
+		// TODO: display_name后期需要修改为uuid_generate
+		lcuuidGenerate: domain.DisplayName,
+		name:           domain.Name,
+		httpTimeout:    globalCloudCfg.HTTPTimeout,
+		config:         conf,
+		debugger:       cloudcommon.NewDebugger(domain.Name),
+	}
+}
+
+func (a *Azure) ClearDebugLog() {
+	a.debugger.Clear()
+}
+
+func (a *Azure) CheckAuth() error {
+	token, err := a.createToken()
+	if err != nil {
+		return err
+	}
+	_, err = a.requestGet(fmt.Sprintf("%s/subscriptions/%s?api-version=%s", a.config.ManagementURL, a.config.SubscriptionID, API_VERSION_RESOURCES), token)
+	return err
+}
+
+func (a *Azure) GetCloudData() (model.Resource, error) {
+	a.cloudStatsd = statsd.NewCloudStatsd()
+	a.toolDataSet = NewToolDataSet()
+	var resource model.Resource
+	token, err := a.getToken()
+	if err != nil {
+		return resource, err
+	}
+
+	err = a.getResourceGroups(token)
+	if err != nil {
+		return resource, err
+	}
+
+	regions, err := a.getRegions(token)
+	if err != nil {
+		return resource, err
+	}
+
+	vpcs, networks, subnets, err := a.getVPCs(token)
+	if err != nil {
+		return resource, err
+	}
+	resource.VPCs = vpcs
+	resource.Networks = networks
+	resource.Subnets = subnets
+
+	err = a.getPublicIPs(token)
+	if err != nil {
+		return resource, err
+	}
+
+	err = a.getNICs(token)
+	if err != nil {
+		return resource, err
+	}
+
+	vms, err := a.getVMs(token)
+	if err != nil {
+		return resource, err
+	}
+	resource.VMs = vms
+
+	vinterfaces, ips, floatingIPs := a.getVInterfaces()
+	resource.VInterfaces = vinterfaces
+	resource.IPs = ips
+	resource.FloatingIPs = floatingIPs
+
+	lbs, lbListeners, lbTargetServers, err := a.getLBs(token)
+	if err != nil {
+		return resource, err
+	}
+	resource.LBs = lbs
+	resource.LBListeners = lbListeners
+	resource.LBTargetServers = lbTargetServers
+
+	natGateways, natVInterfaces, natIPs, err := a.getNATGateways(token)
+	if err != nil {
+		return resource, err
+	}
+	resource.NATGateways = natGateways
+	resource.VInterfaces = append(resource.VInterfaces, natVInterfaces...)
+	resource.IPs = append(resource.IPs, natIPs...)
+
+	log.Debugf("region resource num info: %v", a.toolDataSet.regionLcuuidToResourceNum)
+	log.Debugf("az resource num info: %v", a.toolDataSet.azLcuuidToResourceNum)
+	resource.Regions = cloudcommon.EliminateEmptyRegions(regions, a.toolDataSet.regionLcuuidToResourceNum)
+	resource.AZs = cloudcommon.EliminateEmptyAZs(a.getAZs(), a.toolDataSet.azLcuuidToResourceNum)
+
+	a.cloudStatsd.ResCount = statsd.GetResCount(resource)
+	statsd.MetaStatsd.RegisterStatsdTable(a)
+
+	a.debugger.Refresh()
+	return resource, nil
+}
+
+func (a *Azure) GetStatter() statsd.StatsdStatter {
+	globalTags := map[string]string{
+		"domain_name": a.name,
+		"domain":      a.lcuuid,
+		"platform":    common.AZURE_EN,
+	}
+
+	return statsd.StatsdStatter{
+		OrgID:      a.orgID,
+		TeamID:     a.teamID,
+		GlobalTags: globalTags,
+		Element:    statsd.GetCloudStatsd(a.cloudStatsd),
+	}
+}
+
+// getRawData lists all resources of the subscription, and follows the 'nextLink' when the result is paginated
+func (a *Azure) getRawData(resourceName, path, apiVersion string, token *Token) (jsonList []*simplejson.Json, err error) {
+	statsdAPIStartTime := time.Now()
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	url := fmt.Sprintf("%s/subscriptions/%s/%s%sapi-version=%s", a.config.ManagementURL, a.config.SubscriptionID, path, sep, apiVersion)
+	nextURL := url
+	for nextURL != "" {
+		resp, err := a.requestGet(nextURL, token)
+		if err != nil {
+			return []*simplejson.Json{}, err
+		}
+		jData := resp.Get("value")
+		for i := range jData.MustArray() {
+			jsonList = append(jsonList, jData.GetIndex(i))
+		}
+		nextURL = resp.Get("nextLink").MustString()
+	}
+	a.cloudStatsd.RefreshAPIMoniter(resourceName, len(jsonList), statsdAPIStartTime)
+
+	a.debugger.WriteJson(resourceName, url, jsonList)
+	return
+}
+
+// getResourceGroupName returns the resource group name of the resource id,
+// such as /subscriptions/{subscription}/resourceGroups/{resource group}/providers/...
+func getResourceGroupName(id string) string {
+	segments := strings.Split(id, "/")
+	for i := 0; i < len(segments)-1; i++ {
+		if strings.EqualFold(segments[i], "resourceGroups") {
+			return strings.ToLower(segments[i+1])
+		}
+	}
+	return ""
+}
+
+// checkResource checks whether the resource is in included resource groups and regions
+func (a *Azure) checkResource(id, location string) (RegionInfo, bool) {
+	if !a.toolDataSet.resourceGroups[getResourceGroupName(id)] {
+		return RegionInfo{}, false
+	}
+	region, ok := a.toolDataSet.locationToRegion[strings.ToLower(location)]
+	return region, ok
+}
+
+func (a *Azure) getResourceLcuuid(id string) string {
+	return common.GetUUIDByOrgID(a.orgID, strings.ToLower(id))
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	mysqlcommon "github.com/deepflowio/deepflow/server/controller/db/mysql/common"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+	statsdcfg "github.com/deepflowio/deepflow/server/controller/statsd/config"
+)
+
+const (
+	testTenantID       = "tenant-1"
+	testSubscriptionID = "00000000-0000-0000-0000-000000000001"
+	testClientSecret   = "secret"
+	testToken          = "eyJ0eXAiOiJKV1QiLCJhbGciOiJSUzI1NiJ9.test-token"
+)
+
+var testRoutes = map[string]string{
+	"":                "subscription.json",
+	"/resourcegroups": "resource_groups.json",
+	"/locations":      "locations.json",
+	"/providers/Microsoft.Network/virtualNetworks":   "virtual_networks.json",
+	"/providers/Microsoft.Network/publicIPAddresses": "public_ip_addresses.json",
+	"/providers/Microsoft.Network/networkInterfaces": "network_interfaces.json",
+	"/providers/Microsoft.Compute/virtualMachines":   "virtual_machines.json",
+	"/providers/Microsoft.Network/loadBalancers":     "load_balancers.json",
+	"/providers/Microsoft.Network/natGateways":       "nat_gateways.json",
+}
+
+// newARMStub serves the recorded responses of azure resource manager in testdata,
+// '{{endpoint}}' in them is replaced by the stub url
+func newARMStub() *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var file string
+		if r.URL.Path == "/"+testTenantID+"/oauth2/v2.0/token" && r.Method == http.MethodPost {
+			if r.PostFormValue("grant_type") != "client_credentials" || r.PostFormValue("client_secret") != testClientSecret {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			file = "token.json"
+		} else {
+			if r.Header.Get("Authorization") != "Bearer "+testToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("api-version") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			prefix := "/subscriptions/" + testSubscriptionID
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				file = testRoutes[strings.TrimPrefix(r.URL.Path, prefix)]
+			}
+			if file == "virtual_machines.json" && r.URL.Query().Get("$skiptoken") != "" {
+				file = "virtual_machines_page2.json"
+			}
+		}
+		data, err := os.ReadFile("testdata/" + file)
+		if file == "" || err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(strings.ReplaceAll(string(data), "{{endpoint}}", server.URL)))
+	}))
+	return server
+}
+
+func newTestAzure(url, secret string) *Azure {
+	domain := mysql.Domain{
+		Name:        "test_azure",
+		DisplayName: "test_azure",
+	}
+	conf := &Config{
+		TenantID:              testTenantID,
+		ClientID:              "client-1",
+		ClientSecret:          secret,
+		SubscriptionID:        testSubscriptionID,
+		LoginURL:              url,
+		ManagementURL:         url + "/",
+		ExcludeRegions:        []string{"centralus"},
+		ExcludeResourceGroups: []string{"RG-Excluded"},
+	}
+	return newAzure(mysqlcommon.DEFAULT_ORG_ID, domain, config.CloudConfig{HTTPTimeout: 5}, conf)
+}
+
+func TestAzure(t *testing.T) {
+	config.SetCloudGlobalConfig(config.CloudConfig{HTTPTimeout: 5})
+	statsd.NewStatsdMonitor(statsdcfg.StatsdConfig{})
+	server := newARMStub()
+	defer server.Close()
+
+	Convey("TestAzure_CheckAuth", t, func() {
+		So(newTestAzure(server.URL, testClientSecret).CheckAuth(), ShouldBeNil)
+		So(newTestAzure(server.URL, "wrong").CheckAuth(), ShouldNotBeNil)
+	})
+
+	Convey("TestAzure_GetCloudData", t, func() {
+		azure := newTestAzure(server.URL, testClientSecret)
+		data, err := azure.GetCloudData()
+		So(err, ShouldBeNil)
+
+		Convey("empty and logical regions should be excluded", func() {
+			So(len(data.Regions), ShouldEqual, 1)
+			So(data.Regions[0].Name, ShouldEqual, "East US")
+			So(data.Regions[0].Label, ShouldEqual, "eastus")
+		})
+
+		Convey("zonal and regional vms should be in different azs", func() {
+			So(len(data.AZs), ShouldEqual, 2)
+			names := []string{data.AZs[0].Name, data.AZs[1].Name}
+			So(names, ShouldContain, "East US")
+			So(names, ShouldContain, "East US 1")
+		})
+
+		Convey("vnets in excluded resource groups should be excluded", func() {
+			So(len(data.VPCs), ShouldEqual, 1)
+			So(data.VPCs[0].Name, ShouldEqual, "vnet-prod")
+			So(data.VPCs[0].CIDR, ShouldEqual, "10.0.0.0/16")
+			So(len(data.Networks), ShouldEqual, 2)
+			So(len(data.Subnets), ShouldEqual, 2)
+		})
+
+		Convey("paginated vms should be learned", func() {
+			So(len(data.VMs), ShouldEqual, 2)
+			for _, vm := range data.VMs {
+				So(vm.VPCLcuuid, ShouldEqual, data.VPCs[0].Lcuuid)
+				if vm.Name == "vm-web" {
+					So(vm.State, ShouldEqual, common.VM_STATE_RUNNING)
+					So(vm.CloudTags["env"], ShouldEqual, "prod")
+					So(vm.CreatedAt.IsZero(), ShouldBeFalse)
+				} else {
+					So(vm.State, ShouldEqual, common.VM_STATE_STOPPED)
+				}
+			}
+		})
+
+		Convey("network interfaces and public ips should be learned", func() {
+			// 2 vm vinterfaces and 1 nat gateway vinterface, the private endpoint one is excluded
+			So(len(data.VInterfaces), ShouldEqual, 3)
+			So(len(data.IPs), ShouldEqual, 3)
+			for _, vif := range data.VInterfaces {
+				if vif.Name == "nic-web" {
+					So(vif.Mac, ShouldEqual, "00:0d:3a:1b:2c:3d")
+					So(vif.DeviceType, ShouldEqual, common.VIF_DEVICE_TYPE_VM)
+				}
+			}
+			So(len(data.FloatingIPs), ShouldEqual, 1)
+			So(data.FloatingIPs[0].IP, ShouldEqual, "20.1.1.1")
+		})
+
+		Convey("load balancers should be learned", func() {
+			So(len(data.LBs), ShouldEqual, 1)
+			So(data.LBs[0].Model, ShouldEqual, common.LB_MODEL_EXTERNAL)
+			So(data.LBs[0].VIP, ShouldEqual, "20.1.1.2")
+			So(len(data.LBListeners), ShouldEqual, 1)
+			So(data.LBListeners[0].Protocol, ShouldEqual, "TCP")
+			So(data.LBListeners[0].Port, ShouldEqual, 80)
+			So(len(data.LBTargetServers), ShouldEqual, 2)
+			for _, server := range data.LBTargetServers {
+				So(server.Port, ShouldEqual, 8080)
+				So(server.VMLcuuid, ShouldNotBeEmpty)
+			}
+		})
+
+		Convey("nat gateways should be learned", func() {
+			So(len(data.NATGateways), ShouldEqual, 1)
+			So(data.NATGateways[0].FloatingIPs, ShouldEqual, "20.1.1.3")
+			So(data.NATGateways[0].VPCLcuuid, ShouldEqual, data.VPCs[0].Lcuuid)
+		})
+	})
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+
+	"github.com/bitly/go-simplejson"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+const (
+	DEFAULT_LOGIN_URL      = "https://login.microsoftonline.com"
+	DEFAULT_MANAGEMENT_URL = "https://management.azure.com"
+)
+
+type Config struct {
+	RegionLcuuid          string
+	TenantID              string
+	ClientID              string
+	ClientSecret          string
+	SubscriptionID        string
+	LoginURL              string // microsoft entra id endpoint, such as https://login.chinacloudapi.cn for azure china
+	ManagementURL         string // azure resource manager endpoint, such as https://management.chinacloudapi.cn for azure china
+	ExcludeRegions        []string
+	IncludeRegions        []string
+	ExcludeResourceGroups []string
+	IncludeResourceGroups []string
+}
+
+func (c *Config) LoadFromString(sConf string) (err error) {
+	jConf, err := simplejson.NewJson([]byte(sConf))
+	if err != nil {
+		log.Errorf("convert config string: %s to json failed: %v", sConf, err)
+		return
+	}
+	c.TenantID, err = jConf.Get("tenant_id").String()
+	if err != nil {
+		log.Error("tenant_id must be specified")
+		return
+	}
+	c.ClientID, err = jConf.Get("client_id").String()
+	if err != nil {
+		log.Error("client_id must be specified")
+		return
+	}
+	secret, err := jConf.Get("client_secret").String()
+	if err != nil {
+		log.Error("client_secret must be specified")
+		return
+	}
+	decryptSecret, err := common.DecryptSecretKey(secret)
+	if err != nil {
+		log.Error("decrypt client_secret failed")
+		return
+	}
+	c.ClientSecret = decryptSecret
+	c.SubscriptionID, err = jConf.Get("subscription_id").String()
+	if err != nil {
+		log.Error("subscription_id must be specified")
+		return
+	}
+	c.LoginURL = jConf.Get("login_url").MustString(DEFAULT_LOGIN_URL)
+	c.ManagementURL = jConf.Get("management_url").MustString(DEFAULT_MANAGEMENT_URL)
+	c.RegionLcuuid = jConf.Get("region_uuid").MustString()
+	c.ExcludeRegions = splitConfigList(jConf.Get("exclude_regions").MustString())
+	c.IncludeRegions = splitConfigList(jConf.Get("include_regions").MustString())
+	c.ExcludeResourceGroups = splitConfigList(jConf.Get("exclude_resource_groups").MustString())
+	c.IncludeResourceGroups = splitConfigList(jConf.Get("include_resource_groups").MustString())
+	c.tidy()
+	return
+}
+
+func (c *Config) tidy() {
+	if c.LoginURL == "" {
+		c.LoginURL = DEFAULT_LOGIN_URL
+	}
+	if c.ManagementURL == "" {
+		c.ManagementURL = DEFAULT_MANAGEMENT_URL
+	}
+	c.LoginURL = strings.TrimSuffix(c.LoginURL, "/")
+	c.ManagementURL = strings.TrimSuffix(c.ManagementURL, "/")
+}
+
+// splitConfigList splits the comma separated config value
+func splitConfigList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
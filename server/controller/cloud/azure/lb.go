@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bitly/go-simplejson"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (a *Azure) getLBs(token *Token) ([]model.LB, []model.LBListener, []model.LBTargetServer, error) {
+	var lbs []model.LB
+	var lbListeners []model.LBListener
+	var lbTargetServers []model.LBTargetServer
+	jLBs, err := a.getRawData("load_balancers", "providers/Microsoft.Network/loadBalancers", API_VERSION_NETWORK, token)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	requiredAttrs := []string{"id", "name", "location"}
+	for i := range jLBs {
+		jLB := jLBs[i]
+		name := jLB.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jLB, requiredAttrs) {
+			log.Infof("exclude lb: %s, missing attr", name)
+			continue
+		}
+		lbID := jLB.Get("id").MustString()
+		region, ok := a.checkResource(lbID, jLB.Get("location").MustString())
+		if !ok {
+			log.Infof("exclude lb: %s, not in included regions or resource groups", name)
+			continue
+		}
+
+		// frontend ip config id -> frontend ip
+		frontendIDToIP := make(map[string]string)
+		var vips []string
+		var vpcLcuuid string
+		lbModel := common.LB_MODEL_INTERNAL
+		jFrontends := jLB.Get("properties").Get("frontendIPConfigurations")
+		for j := range jFrontends.MustArray() {
+			jFrontend := jFrontends.GetIndex(j)
+			var ip string
+			if publicIP, ok := a.toolDataSet.publicIPIDToIP[strings.ToLower(jFrontend.Get("properties").Get("publicIPAddress").Get("id").MustString())]; ok {
+				ip = publicIP
+				lbModel = common.LB_MODEL_EXTERNAL
+			} else {
+				ip = jFrontend.Get("properties").Get("privateIPAddress").MustString()
+				subnetID := jFrontend.Get("properties").Get("subnet").Get("id").MustString()
+				if network, ok := a.toolDataSet.subnetIDToNetwork[strings.ToLower(subnetID)]; ok && vpcLcuuid == "" {
+					vpcLcuuid = network.VPCLcuuid
+				}
+			}
+			if ip == "" {
+				continue
+			}
+			frontendIDToIP[strings.ToLower(jFrontend.Get("id").MustString())] = ip
+			vips = append(vips, ip)
+		}
+
+		// backend pool id -> backend ip configs
+		poolIDToBackends := make(map[string][]*simplejson.Json)
+		jPools := jLB.Get("properties").Get("backendAddressPools")
+		for j := range jPools.MustArray() {
+			jPool := jPools.GetIndex(j)
+			poolID := strings.ToLower(jPool.Get("id").MustString())
+			jBackends := jPool.Get("properties").Get("backendIPConfigurations")
+			for k := range jBackends.MustArray() {
+				jBackend := jBackends.GetIndex(k)
+				poolIDToBackends[poolID] = append(poolIDToBackends[poolID], jBackend)
+				if ipConfig, ok := a.toolDataSet.ipConfigIDToIPConfig[strings.ToLower(jBackend.Get("id").MustString())]; ok && vpcLcuuid == "" {
+					vpcLcuuid = ipConfig.vpcLcuuid
+				}
+			}
+		}
+		if vpcLcuuid == "" {
+			log.Infof("exclude lb: %s, vpc not found", name)
+			continue
+		}
+
+		lbLcuuid := a.getResourceLcuuid(lbID)
+		lbs = append(lbs, model.LB{
+			Lcuuid:       lbLcuuid,
+			Name:         name,
+			Label:        lbID,
+			Model:        lbModel,
+			VIP:          strings.Join(vips, ","),
+			VPCLcuuid:    vpcLcuuid,
+			RegionLcuuid: region.lcuuid,
+		})
+		a.toolDataSet.regionLcuuidToResourceNum[region.lcuuid]++
+
+		jRules := jLB.Get("properties").Get("loadBalancingRules")
+		for j := range jRules.MustArray() {
+			jRule := jRules.GetIndex(j)
+			ruleID := jRule.Get("id").MustString()
+			jRuleProperties := jRule.Get("properties")
+			protocol := strings.ToUpper(jRuleProperties.Get("protocol").MustString())
+			port := jRuleProperties.Get("frontendPort").MustInt()
+			backendPort := jRuleProperties.Get("backendPort").MustInt()
+			frontendIP := frontendIDToIP[strings.ToLower(jRuleProperties.Get("frontendIPConfiguration").Get("id").MustString())]
+			listenerLcuuid := a.getResourceLcuuid(ruleID)
+			lbListeners = append(lbListeners, model.LBListener{
+				Lcuuid:   listenerLcuuid,
+				LBLcuuid: lbLcuuid,
+				Name:     jRule.Get("name").MustString(),
+				Label:    ruleID,
+				IPs:      frontendIP,
+				Protocol: protocol,
+				Port:     port,
+			})
+
+			for _, jBackend := range poolIDToBackends[strings.ToLower(jRuleProperties.Get("backendAddressPool").Get("id").MustString())] {
+				backendID := jBackend.Get("id").MustString()
+				ipConfig, ok := a.toolDataSet.ipConfigIDToIPConfig[strings.ToLower(backendID)]
+				if !ok || ipConfig.privateIP == "" {
+					log.Infof("lb target server (%s) ip not found", backendID)
+					continue
+				}
+				vmLcuuid := a.getResourceLcuuid(ipConfig.vmID)
+				if _, ok := a.toolDataSet.lcuuidToVM[vmLcuuid]; !ok {
+					log.Infof("lb target server (%s) vm not found", backendID)
+					continue
+				}
+				lbTargetServers = append(lbTargetServers, model.LBTargetServer{
+					Lcuuid:           common.GetUUIDByOrgID(a.orgID, listenerLcuuid+ipConfig.privateIP+strconv.Itoa(backendPort)),
+					LBLcuuid:         lbLcuuid,
+					LBListenerLcuuid: listenerLcuuid,
+					Type:             common.LB_SERVER_TYPE_VM,
+					IP:               ipConfig.privateIP,
+					VMLcuuid:         vmLcuuid,
+					Protocol:         protocol,
+					Port:             backendPort,
+					VPCLcuuid:        ipConfig.vpcLcuuid,
+				})
+			}
+		}
+	}
+	return lbs, lbListeners, lbTargetServers, nil
+}
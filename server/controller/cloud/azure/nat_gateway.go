@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (a *Azure) getNATGateways(token *Token) ([]model.NATGateway, []model.VInterface, []model.IP, error) {
+	var natGateways []model.NATGateway
+	var natVInterfaces []model.VInterface
+	var natIPs []model.IP
+	jNATs, err := a.getRawData("nat_gateways", "providers/Microsoft.Network/natGateways", API_VERSION_NETWORK, token)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	requiredAttrs := []string{"id", "name", "location"}
+	for i := range jNATs {
+		jNAT := jNATs[i]
+		name := jNAT.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jNAT, requiredAttrs) {
+			log.Infof("exclude nat gateway: %s, missing attr", name)
+			continue
+		}
+		natID := jNAT.Get("id").MustString()
+		region, ok := a.checkResource(natID, jNAT.Get("location").MustString())
+		if !ok {
+			log.Infof("exclude nat gateway: %s, not in included regions or resource groups", name)
+			continue
+		}
+		// nat gateway is associated with subnets of a single virtual network
+		var vpcLcuuid string
+		jSubnets := jNAT.Get("properties").Get("subnets")
+		for j := range jSubnets.MustArray() {
+			if network, ok := a.toolDataSet.subnetIDToNetwork[strings.ToLower(jSubnets.GetIndex(j).Get("id").MustString())]; ok {
+				vpcLcuuid = network.VPCLcuuid
+				break
+			}
+		}
+		if vpcLcuuid == "" {
+			log.Infof("exclude nat gateway: %s, vpc not found", name)
+			continue
+		}
+
+		floatingIPs := []string{}
+		jPublicIPs := jNAT.Get("properties").Get("publicIpAddresses")
+		for j := range jPublicIPs.MustArray() {
+			if ip, ok := a.toolDataSet.publicIPIDToIP[strings.ToLower(jPublicIPs.GetIndex(j).Get("id").MustString())]; ok {
+				floatingIPs = append(floatingIPs, ip)
+			}
+		}
+
+		natGatewayLcuuid := a.getResourceLcuuid(natID)
+		natGateways = append(natGateways, model.NATGateway{
+			Lcuuid:       natGatewayLcuuid,
+			Name:         name,
+			Label:        natID,
+			FloatingIPs:  strings.Join(floatingIPs, ","),
+			VPCLcuuid:    vpcLcuuid,
+			RegionLcuuid: region.lcuuid,
+		})
+		a.toolDataSet.regionLcuuidToResourceNum[region.lcuuid]++
+
+		vinterfaceLcuuid := common.GetUUIDByOrgID(a.orgID, natGatewayLcuuid)
+		natVInterfaces = append(natVInterfaces, model.VInterface{
+			Lcuuid:        vinterfaceLcuuid,
+			Type:          common.VIF_TYPE_WAN,
+			Mac:           common.VIF_DEFAULT_MAC,
+			DeviceLcuuid:  natGatewayLcuuid,
+			DeviceType:    common.VIF_DEVICE_TYPE_NAT_GATEWAY,
+			NetworkLcuuid: common.NETWORK_ISP_LCUUID,
+			VPCLcuuid:     vpcLcuuid,
+			RegionLcuuid:  region.lcuuid,
+		})
+		for _, ip := range floatingIPs {
+			natIPs = append(natIPs, model.IP{
+				Lcuuid:           common.GetUUIDByOrgID(a.orgID, vinterfaceLcuuid+ip),
+				VInterfaceLcuuid: vinterfaceLcuuid,
+				IP:               ip,
+				RegionLcuuid:     region.lcuuid,
+			})
+		}
+	}
+	return natGateways, natVInterfaces, natIPs, nil
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"sort"
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+// only physical regions can hold resources, logical regions such as 'global' are excluded
+const REGION_TYPE_PHYSICAL = "Physical"
+
+func containsFold(items []string, s string) bool {
+	for _, item := range items {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Azure) getResourceGroups(token *Token) error {
+	jGroups, err := a.getRawData("resource_groups", "resourcegroups", API_VERSION_RESOURCES, token)
+	if err != nil {
+		return err
+	}
+	for i := range jGroups {
+		name := jGroups[i].Get("name").MustString()
+		if name == "" {
+			continue
+		}
+		if len(a.config.IncludeResourceGroups) > 0 && !containsFold(a.config.IncludeResourceGroups, name) {
+			log.Infof("exclude resource group: %s, not included", name)
+			continue
+		}
+		if containsFold(a.config.ExcludeResourceGroups, name) {
+			log.Infof("exclude resource group: %s", name)
+			continue
+		}
+		a.toolDataSet.resourceGroups[strings.ToLower(name)] = true
+	}
+	return nil
+}
+
+func (a *Azure) getRegions(token *Token) ([]model.Region, error) {
+	var regions []model.Region
+	jLocations, err := a.getRawData("locations", "locations", API_VERSION_LOCATIONS, token)
+	if err != nil {
+		return nil, err
+	}
+
+	requiredAttrs := []string{"name", "displayName"}
+	for i := range jLocations {
+		jLocation := jLocations[i]
+		name := jLocation.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jLocation, requiredAttrs) {
+			log.Infof("exclude region: %s, missing attr", name)
+			continue
+		}
+		if jLocation.Get("metadata").Get("regionType").MustString(REGION_TYPE_PHYSICAL) != REGION_TYPE_PHYSICAL {
+			log.Debugf("exclude region: %s, not physical", name)
+			continue
+		}
+		if len(a.config.IncludeRegions) > 0 && !containsFold(a.config.IncludeRegions, name) {
+			log.Infof("exclude region: %s, not included", name)
+			continue
+		}
+		if containsFold(a.config.ExcludeRegions, name) {
+			log.Infof("exclude region: %s", name)
+			continue
+		}
+
+		displayName := jLocation.Get("displayName").MustString()
+		region := model.Region{
+			Lcuuid: common.GenerateUUIDByOrgID(a.orgID, name+"_"+a.lcuuidGenerate),
+			Label:  name,
+			Name:   displayName,
+		}
+		regions = append(regions, region)
+		regionLcuuid := region.Lcuuid
+		if a.config.RegionLcuuid != "" {
+			regionLcuuid = a.config.RegionLcuuid
+		}
+		a.toolDataSet.locationToRegion[strings.ToLower(name)] = RegionInfo{
+			name:        name,
+			displayName: displayName,
+			lcuuid:      regionLcuuid,
+		}
+	}
+	return regions, nil
+}
+
+// getAZLcuuid returns the lcuuid of the availability zone, the resources not deployed in zones
+// belong to a default az named after the region
+func (a *Azure) getAZLcuuid(region RegionInfo, zone string) string {
+	key := AZKey{region.name, zone}
+	if az, ok := a.toolDataSet.keyToAZ[key]; ok {
+		return az.Lcuuid
+	}
+	name := region.displayName
+	if zone != "" {
+		name = region.displayName + " " + zone
+	}
+	az := model.AZ{
+		Lcuuid:       common.GenerateUUIDByOrgID(a.orgID, region.name+"_"+zone+"_"+a.lcuuidGenerate),
+		Label:        zone,
+		Name:         name,
+		RegionLcuuid: region.lcuuid,
+	}
+	a.toolDataSet.keyToAZ[key] = az
+	return az.Lcuuid
+}
+
+func (a *Azure) getAZs() []model.AZ {
+	keys := make([]AZKey, 0, len(a.toolDataSet.keyToAZ))
+	for key := range a.toolDataSet.keyToAZ {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].location+keys[i].zone < keys[j].location+keys[j].zone
+	})
+	azs := make([]model.AZ, 0, len(keys))
+	for _, key := range keys {
+		azs = append(azs, a.toolDataSet.keyToAZ[key])
+	}
+	return azs
+}
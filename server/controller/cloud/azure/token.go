@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+)
+
+type Token struct {
+	accessToken string
+	expiresAt   time.Time
+}
+
+// 离失效时间小于5m，则认为已经过期
+func (t *Token) isExpired() bool {
+	return t.expiresAt.Sub(time.Now()).Minutes() < 5
+}
+
+func (a *Azure) getToken() (*Token, error) {
+	if a.token == nil || a.token.isExpired() {
+		token, err := a.createToken()
+		if err != nil {
+			return nil, err
+		}
+		a.token = token
+	}
+	return a.token, nil
+}
+
+// createToken gets an access token of azure resource manager by the oauth2 client credentials flow
+// ref: https://learn.microsoft.com/en-us/entra/identity-platform/v2-oauth2-client-creds-grant-flow
+func (a *Azure) createToken() (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", a.config.ClientID)
+	form.Set("client_secret", a.config.ClientSecret)
+	form.Set("scope", a.config.ManagementURL+"/.default")
+	tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", a.config.LoginURL, a.config.TenantID)
+	req, err := http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := a.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	accessToken := resp.Get("access_token").MustString()
+	if accessToken == "" {
+		return nil, fmt.Errorf("request url: %s, response has no access_token", tokenURL)
+	}
+	return &Token{
+		accessToken: accessToken,
+		expiresAt:   time.Now().Add(time.Duration(resp.Get("expires_in").MustInt(3599)) * time.Second),
+	}, nil
+}
+
+func (a *Azure) requestGet(url string, token *Token) (*simplejson.Json, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.accessToken)
+	req.Header.Set("Accept", "application/json")
+	return a.doRequest(req)
+}
+
+func (a *Azure) doRequest(req *http.Request) (*simplejson.Json, error) {
+	log.Debugf("url: %s", req.URL)
+	client := cloudcommon.GetUnverifyHTTPClient(time.Second * time.Duration(a.httpTimeout))
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Errorf("request url: %s, failed: %s", req.URL, err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Errorf("request url: %s, read failed: %s", req.URL, err.Error())
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = errors.New(fmt.Sprintf("request url: %s, failed: (%d) %s", req.URL, resp.StatusCode, string(body)))
+		log.Error(err.Error())
+		return nil, err
+	}
+	jsonResp, err := simplejson.NewJson(body)
+	if err != nil {
+		log.Errorf("request url: %s, JSONiz failed: %s", req.URL, err.Error())
+		return nil, err
+	}
+	return jsonResp, nil
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+)
+
+// all resource ids used as keys are in lower case, since azure resource ids are case-insensitive
+type ToolDataSet struct {
+	resourceGroups       map[string]bool
+	locationToRegion     map[string]RegionInfo
+	keyToAZ              map[AZKey]model.AZ
+	subnetIDToNetwork    map[string]model.Network
+	subnetIDToSubnets    map[string][]model.Subnet
+	publicIPIDToIP       map[string]string
+	nics                 []NICInfo
+	ipConfigIDToIPConfig map[string]IPConfigInfo
+	vmIDToVPCLcuuid      map[string]string
+	lcuuidToVM           map[string]model.VM
+
+	regionLcuuidToResourceNum map[string]int
+	azLcuuidToResourceNum     map[string]int
+}
+
+func NewToolDataSet() *ToolDataSet {
+	return &ToolDataSet{
+		resourceGroups:            make(map[string]bool),
+		locationToRegion:          make(map[string]RegionInfo),
+		keyToAZ:                   make(map[AZKey]model.AZ),
+		subnetIDToNetwork:         make(map[string]model.Network),
+		subnetIDToSubnets:         make(map[string][]model.Subnet),
+		publicIPIDToIP:            make(map[string]string),
+		ipConfigIDToIPConfig:      make(map[string]IPConfigInfo),
+		vmIDToVPCLcuuid:           make(map[string]string),
+		lcuuidToVM:                make(map[string]model.VM),
+		regionLcuuidToResourceNum: make(map[string]int),
+		azLcuuidToResourceNum:     make(map[string]int),
+	}
+}
+
+type RegionInfo struct {
+	name        string
+	displayName string
+	lcuuid      string
+}
+
+type AZKey struct {
+	location string
+	zone     string
+}
+
+type NICInfo struct {
+	id        string
+	name      string
+	mac       string
+	vmID      string
+	vpcLcuuid string
+	location  string
+	ipConfigs []IPConfigInfo
+}
+
+type IPConfigInfo struct {
+	id         string
+	privateIP  string
+	subnetID   string
+	publicIPID string
+	vmID       string
+	nicLcuuid  string
+	vpcLcuuid  string
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (a *Azure) getPublicIPs(token *Token) error {
+	jPublicIPs, err := a.getRawData("public_ip_addresses", "providers/Microsoft.Network/publicIPAddresses", API_VERSION_NETWORK, token)
+	if err != nil {
+		return err
+	}
+	for i := range jPublicIPs {
+		jPublicIP := jPublicIPs[i]
+		ip := jPublicIP.Get("properties").Get("ipAddress").MustString()
+		if ip == "" {
+			log.Debugf("public ip (%s) is not allocated", jPublicIP.Get("name").MustString())
+			continue
+		}
+		a.toolDataSet.publicIPIDToIP[strings.ToLower(jPublicIP.Get("id").MustString())] = ip
+	}
+	return nil
+}
+
+// getNICs parses network interfaces, vinterfaces are generated after vms are learned
+func (a *Azure) getNICs(token *Token) error {
+	jNICs, err := a.getRawData("network_interfaces", "providers/Microsoft.Network/networkInterfaces", API_VERSION_NETWORK, token)
+	if err != nil {
+		return err
+	}
+
+	requiredAttrs := []string{"id", "name", "location"}
+	for i := range jNICs {
+		jNIC := jNICs[i]
+		name := jNIC.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jNIC, requiredAttrs) {
+			log.Infof("exclude vinterface: %s, missing attr", name)
+			continue
+		}
+		nicID := jNIC.Get("id").MustString()
+		location := jNIC.Get("location").MustString()
+		if _, ok := a.checkResource(nicID, location); !ok {
+			log.Infof("exclude vinterface: %s, not in included regions or resource groups", name)
+			continue
+		}
+		// network interfaces of private endpoints and others are not attached to vms
+		vmID := strings.ToLower(jNIC.Get("properties").Get("virtualMachine").Get("id").MustString())
+		if vmID == "" {
+			log.Debugf("exclude vinterface: %s, not attached to vm", name)
+			continue
+		}
+		nic := NICInfo{
+			id:       nicID,
+			name:     name,
+			mac:      formatMac(jNIC.Get("properties").Get("macAddress").MustString()),
+			vmID:     vmID,
+			location: location,
+		}
+
+		jIPConfigs := jNIC.Get("properties").Get("ipConfigurations")
+		for j := range jIPConfigs.MustArray() {
+			jIPConfig := jIPConfigs.GetIndex(j)
+			subnetID := jIPConfig.Get("properties").Get("subnet").Get("id").MustString()
+			network, ok := a.toolDataSet.subnetIDToNetwork[strings.ToLower(subnetID)]
+			if !ok {
+				log.Infof("exclude ip config: %s, subnet not found", jIPConfig.Get("id").MustString())
+				continue
+			}
+			if nic.vpcLcuuid == "" {
+				nic.vpcLcuuid = network.VPCLcuuid
+			}
+			ipConfig := IPConfigInfo{
+				id:         jIPConfig.Get("id").MustString(),
+				privateIP:  jIPConfig.Get("properties").Get("privateIPAddress").MustString(),
+				subnetID:   subnetID,
+				publicIPID: jIPConfig.Get("properties").Get("publicIPAddress").Get("id").MustString(),
+				vmID:       vmID,
+				nicLcuuid:  a.getResourceLcuuid(nicID),
+				vpcLcuuid:  network.VPCLcuuid,
+			}
+			nic.ipConfigs = append(nic.ipConfigs, ipConfig)
+			a.toolDataSet.ipConfigIDToIPConfig[strings.ToLower(ipConfig.id)] = ipConfig
+		}
+		if nic.vpcLcuuid == "" {
+			log.Infof("exclude vinterface: %s, vpc not found", name)
+			continue
+		}
+		// the vpc of a vm is decided by its primary (the first) network interface
+		if _, ok := a.toolDataSet.vmIDToVPCLcuuid[vmID]; !ok {
+			a.toolDataSet.vmIDToVPCLcuuid[vmID] = nic.vpcLcuuid
+		}
+		a.toolDataSet.nics = append(a.toolDataSet.nics, nic)
+	}
+	return nil
+}
+
+func (a *Azure) getVInterfaces() ([]model.VInterface, []model.IP, []model.FloatingIP) {
+	var vinterfaces []model.VInterface
+	var ips []model.IP
+	var floatingIPs []model.FloatingIP
+	for _, nic := range a.toolDataSet.nics {
+		vmLcuuid := a.getResourceLcuuid(nic.vmID)
+		vm, ok := a.toolDataSet.lcuuidToVM[vmLcuuid]
+		if !ok {
+			log.Infof("exclude vinterface: %s, vm not found", nic.name)
+			continue
+		}
+		vinterfaceLcuuid := a.getResourceLcuuid(nic.id)
+		vinterfaces = append(vinterfaces, model.VInterface{
+			Lcuuid:        vinterfaceLcuuid,
+			Name:          nic.name,
+			Type:          common.VIF_TYPE_LAN,
+			Mac:           nic.mac,
+			DeviceLcuuid:  vmLcuuid,
+			DeviceType:    common.VIF_DEVICE_TYPE_VM,
+			NetworkLcuuid: a.toolDataSet.subnetIDToNetwork[strings.ToLower(nic.ipConfigs[0].subnetID)].Lcuuid,
+			VPCLcuuid:     nic.vpcLcuuid,
+			RegionLcuuid:  vm.RegionLcuuid,
+		})
+
+		for _, ipConfig := range nic.ipConfigs {
+			if ipConfig.privateIP != "" {
+				ips = append(ips, model.IP{
+					Lcuuid:           common.GetUUIDByOrgID(a.orgID, vinterfaceLcuuid+ipConfig.privateIP),
+					VInterfaceLcuuid: vinterfaceLcuuid,
+					IP:               ipConfig.privateIP,
+					SubnetLcuuid:     a.getSubnetLcuuid(ipConfig.subnetID, ipConfig.privateIP),
+					RegionLcuuid:     vm.RegionLcuuid,
+				})
+			}
+
+			publicIP, ok := a.toolDataSet.publicIPIDToIP[strings.ToLower(ipConfig.publicIPID)]
+			if !ok {
+				continue
+			}
+			floatingIPs = append(floatingIPs, model.FloatingIP{
+				Lcuuid:        common.GetUUIDByOrgID(a.orgID, vinterfaceLcuuid+publicIP),
+				IP:            publicIP,
+				VMLcuuid:      vmLcuuid,
+				NetworkLcuuid: common.NETWORK_ISP_LCUUID,
+				VPCLcuuid:     vm.VPCLcuuid,
+				RegionLcuuid:  vm.RegionLcuuid,
+			})
+		}
+	}
+	return vinterfaces, ips, floatingIPs
+}
+
+// formatMac converts the mac address such as 00-0D-3A-1B-2C-3D to 00:0d:3a:1b:2c:3d
+func formatMac(mac string) string {
+	if mac == "" {
+		return common.VIF_DEFAULT_MAC
+	}
+	return strings.ToLower(strings.ReplaceAll(mac, "-", ":"))
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+const POWER_STATE_PREFIX = "PowerState/"
+
+// ref: https://learn.microsoft.com/en-us/azure/virtual-machines/states-billing
+var STATE_CONVERTION = map[string]int{
+	"running":      common.VM_STATE_RUNNING,
+	"stopped":      common.VM_STATE_STOPPED,
+	"deallocated":  common.VM_STATE_STOPPED,
+	"deallocating": common.VM_STATE_STOPPED,
+}
+
+func (a *Azure) getVMs(token *Token) ([]model.VM, error) {
+	var vms []model.VM
+	// statusOnly returns the instance view of vms, which contains the power state
+	jVMs, err := a.getRawData("virtual_machines", "providers/Microsoft.Compute/virtualMachines?statusOnly=true", API_VERSION_COMPUTE, token)
+	if err != nil {
+		return nil, err
+	}
+
+	requiredAttrs := []string{"id", "name", "location"}
+	for i := range jVMs {
+		jVM := jVMs[i]
+		name := jVM.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jVM, requiredAttrs) {
+			log.Infof("exclude vm: %s, missing attr", name)
+			continue
+		}
+		vmID := jVM.Get("id").MustString()
+		region, ok := a.checkResource(vmID, jVM.Get("location").MustString())
+		if !ok {
+			log.Infof("exclude vm: %s, not in included regions or resource groups", name)
+			continue
+		}
+		vpcLcuuid, ok := a.toolDataSet.vmIDToVPCLcuuid[strings.ToLower(vmID)]
+		if !ok {
+			log.Infof("exclude vm: %s, vpc not found", name)
+			continue
+		}
+
+		var zone string
+		if zones := jVM.Get("zones").MustStringArray(); len(zones) > 0 {
+			zone = zones[0]
+		}
+		azLcuuid := a.getAZLcuuid(region, zone)
+		vmLcuuid := a.getResourceLcuuid(vmID)
+		vm := model.VM{
+			Lcuuid:       vmLcuuid,
+			Name:         name,
+			Label:        jVM.Get("properties").Get("vmId").MustString(),
+			Hostname:     jVM.Get("properties").Get("osProfile").Get("computerName").MustString(),
+			HType:        common.VM_HTYPE_VM_C,
+			State:        a.getVMState(jVM.Get("properties").Get("instanceView")),
+			AZLcuuid:     azLcuuid,
+			RegionLcuuid: region.lcuuid,
+			VPCLcuuid:    vpcLcuuid,
+			CloudTags:    a.formatVMCloudTags(jVM.Get("tags")),
+		}
+		if created := jVM.Get("properties").Get("timeCreated").MustString(); created != "" {
+			createdAt, err := time.Parse(time.RFC3339, created)
+			if err != nil {
+				log.Errorf("parse timeCreated failed: %s", created)
+			} else {
+				vm.CreatedAt = createdAt
+			}
+		}
+		vms = append(vms, vm)
+		a.toolDataSet.lcuuidToVM[vmLcuuid] = vm
+		a.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		a.toolDataSet.regionLcuuidToResourceNum[region.lcuuid]++
+	}
+	return vms, nil
+}
+
+func (a *Azure) getVMState(instanceView *simplejson.Json) int {
+	jStatuses := instanceView.Get("statuses")
+	for i := range jStatuses.MustArray() {
+		code := jStatuses.GetIndex(i).Get("code").MustString()
+		if !strings.HasPrefix(code, POWER_STATE_PREFIX) {
+			continue
+		}
+		if state, ok := STATE_CONVERTION[strings.TrimPrefix(code, POWER_STATE_PREFIX)]; ok {
+			return state
+		}
+		return common.VM_STATE_EXCEPTION
+	}
+	return common.VM_STATE_EXCEPTION
+}
+
+// tags of azure resource are used as cloud tags
+func (a *Azure) formatVMCloudTags(tags *simplejson.Json) map[string]string {
+	resp := make(map[string]string)
+	for key := range tags.MustMap() {
+		resp[key] = tags.Get(key).MustString()
+	}
+	return resp
+}
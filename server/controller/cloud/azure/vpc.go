@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (a *Azure) getVPCs(token *Token) ([]model.VPC, []model.Network, []model.Subnet, error) {
+	var vpcs []model.VPC
+	var networks []model.Network
+	var subnets []model.Subnet
+	jVNets, err := a.getRawData("virtual_networks", "providers/Microsoft.Network/virtualNetworks", API_VERSION_NETWORK, token)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	requiredAttrs := []string{"id", "name", "location"}
+	for i := range jVNets {
+		jVNet := jVNets[i]
+		name := jVNet.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jVNet, requiredAttrs) {
+			log.Infof("exclude vpc: %s, missing attr", name)
+			continue
+		}
+		vnetID := jVNet.Get("id").MustString()
+		region, ok := a.checkResource(vnetID, jVNet.Get("location").MustString())
+		if !ok {
+			log.Infof("exclude vpc: %s, not in included regions or resource groups", name)
+			continue
+		}
+		vpcLcuuid := a.getResourceLcuuid(vnetID)
+		vpcs = append(vpcs, model.VPC{
+			Lcuuid:       vpcLcuuid,
+			Name:         name,
+			Label:        vnetID,
+			CIDR:         strings.Join(jVNet.Get("properties").Get("addressSpace").Get("addressPrefixes").MustStringArray(), ","),
+			RegionLcuuid: region.lcuuid,
+		})
+		a.toolDataSet.regionLcuuidToResourceNum[region.lcuuid]++
+
+		jSubnets := jVNet.Get("properties").Get("subnets")
+		for j := range jSubnets.MustArray() {
+			jSubnet := jSubnets.GetIndex(j)
+			subnetID := jSubnet.Get("id").MustString()
+			subnetName := jSubnet.Get("name").MustString()
+			if subnetID == "" || subnetName == "" {
+				log.Infof("exclude network: %s, missing attr", subnetName)
+				continue
+			}
+			// subnets of ipv4 and ipv6 dual stack have multiple address prefixes
+			cidrs := jSubnet.Get("properties").Get("addressPrefixes").MustStringArray()
+			if prefix := jSubnet.Get("properties").Get("addressPrefix").MustString(); prefix != "" {
+				cidrs = append([]string{prefix}, cidrs...)
+			}
+			if len(cidrs) == 0 {
+				log.Infof("exclude network: %s, missing cidr", subnetName)
+				continue
+			}
+
+			networkLcuuid := a.getResourceLcuuid(subnetID)
+			network := model.Network{
+				Lcuuid:       networkLcuuid,
+				Name:         subnetName,
+				Label:        subnetID,
+				NetType:      common.NETWORK_TYPE_LAN,
+				VPCLcuuid:    vpcLcuuid,
+				RegionLcuuid: region.lcuuid,
+			}
+			networks = append(networks, network)
+			a.toolDataSet.subnetIDToNetwork[strings.ToLower(subnetID)] = network
+			a.toolDataSet.regionLcuuidToResourceNum[region.lcuuid]++
+
+			seen := map[string]bool{}
+			for _, cidr := range cidrs {
+				if seen[cidr] {
+					continue
+				}
+				seen[cidr] = true
+				subnet := model.Subnet{
+					Lcuuid:        common.GetUUIDByOrgID(a.orgID, networkLcuuid+cidr),
+					Name:          subnetName,
+					CIDR:          cidr,
+					NetworkLcuuid: networkLcuuid,
+					VPCLcuuid:     vpcLcuuid,
+				}
+				subnets = append(subnets, subnet)
+				a.toolDataSet.subnetIDToSubnets[strings.ToLower(subnetID)] = append(a.toolDataSet.subnetIDToSubnets[strings.ToLower(subnetID)], subnet)
+			}
+		}
+	}
+	return vpcs, networks, subnets, nil
+}
+
+// getSubnetLcuuid returns the lcuuid of the subnet which contains the ip
+func (a *Azure) getSubnetLcuuid(subnetID, ip string) string {
+	subnets := a.toolDataSet.subnetIDToSubnets[strings.ToLower(subnetID)]
+	for _, subnet := range subnets {
+		if cloudcommon.IsIPInCIDR(ip, subnet.CIDR) {
+			return subnet.Lcuuid
+		}
+	}
+	if len(subnets) > 0 {
+		return subnets[0].Lcuuid
+	}
+	return ""
+}
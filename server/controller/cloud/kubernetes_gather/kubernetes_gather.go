@@ -64,6 +64,7 @@ type KubernetesGather struct {
 	nodeIPToLcuuid               map[string]string
 	namespaceToLcuuid            map[string]string
 	rsLcuuidToPodGroupLcuuid     map[string]string
+	jobLcuuidToPodGroupLcuuid    map[string]string
 	serviceLcuuidToIngressLcuuid map[string]string
 	k8sInfo                      map[string][]string
 	pgLcuuidToPSLcuuids          map[string][]string
@@ -202,6 +203,7 @@ func NewKubernetesGather(db *mysql.DB, domain *mysql.Domain, subDomain *mysql.Su
 		nodeIPToLcuuid:               map[string]string{},
 		namespaceToLcuuid:            map[string]string{},
 		rsLcuuidToPodGroupLcuuid:     map[string]string{},
+		jobLcuuidToPodGroupLcuuid:    map[string]string{},
 		serviceLcuuidToIngressLcuuid: map[string]string{},
 		k8sInfo:                      map[string][]string{},
 		pgLcuuidToPSLcuuids:          map[string][]string{},
@@ -257,6 +259,7 @@ func (k *KubernetesGather) GetKubernetesGatherData() (model.KubernetesGatherReso
 	k.nodeIPToLcuuid = map[string]string{}
 	k.namespaceToLcuuid = map[string]string{}
 	k.rsLcuuidToPodGroupLcuuid = map[string]string{}
+	k.jobLcuuidToPodGroupLcuuid = map[string]string{}
 	k.serviceLcuuidToIngressLcuuid = map[string]string{}
 	k.nsLabelToGroupLcuuids = map[string]mapset.Set{}
 	k.pgLcuuidToPSLcuuids = map[string][]string{}
@@ -341,6 +344,14 @@ func (k *KubernetesGather) GetKubernetesGatherData() (model.KubernetesGatherReso
 	if err != nil {
 		return model.KubernetesGatherResource{}, err
 	}
+
+	gateways, gatewayRules, gatewayRuleBackends, err := k.getPodGateways()
+	if err != nil {
+		return model.KubernetesGatherResource{}, err
+	}
+	ingresses = append(ingresses, gateways...)
+	ingressRules = append(ingressRules, gatewayRules...)
+	ingressRuleBackends = append(ingressRuleBackends, gatewayRuleBackends...)
 	for index, s := range podServices {
 		if ingressLcuuid, ok := k.serviceLcuuidToIngressLcuuid[s.Lcuuid]; ok {
 			podServices[index].PodIngressLcuuid = ingressLcuuid
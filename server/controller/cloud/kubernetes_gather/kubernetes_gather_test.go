@@ -21,9 +21,11 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"regexp"
 	"testing"
 
 	"github.com/agiledragon/gomonkey/v2"
+	mapset "github.com/deckarep/golang-set"
 	. "github.com/smartystreets/goconvey/convey"
 
 	cloudconfig "github.com/deepflowio/deepflow/server/controller/cloud/config"
@@ -123,3 +125,56 @@ func TestKubernetes(t *testing.T) {
 		})
 	})
 }
+
+func TestGatewayAndJob(t *testing.T) {
+	Convey("TestGatewayAndJob", t, func() {
+		k8s := &KubernetesGather{
+			labelRegex:                   regexp.MustCompile(common.DEFAULT_ALL_MATCH_REGEX),
+			podGroupLcuuids:              mapset.NewSet(),
+			namespaceToLcuuid:            map[string]string{"default": "ns-lcuuid", "gateway": "gateway-ns-lcuuid"},
+			serviceLcuuidToIngressLcuuid: map[string]string{},
+			jobLcuuidToPodGroupLcuuid:    map[string]string{},
+			nsLabelToGroupLcuuids:        map[string]mapset.Set{},
+			pgLcuuidTopodTargetPorts:     map[string]map[string]int{},
+			nsServiceNameToService: map[string]map[string]map[string]int{
+				"defaultweb":  {"web-lcuuid": {}},
+				"defaultgrpc": {"grpc-lcuuid": {}},
+			},
+			k8sInfo: map[string][]string{
+				"*v1.Gateway": {`{"metadata": {"uid": "gw", "name": "gw", "namespace": "gateway"}}`},
+				"*v1.HTTPRoute": {`{"metadata": {"uid": "http-route", "name": "http-route", "namespace": "default"},
+					"spec": {"parentRefs": [{"name": "gw", "namespace": "gateway"}], "hostnames": ["a.example.com", "b.example.com"],
+					"rules": [{"matches": [{"path": {"value": "/api"}}, {"path": {"value": "/web"}}], "backendRefs": [{"name": "web", "port": 80}]}]}}`},
+				"*v1.GRPCRoute": {`{"metadata": {"uid": "grpc-route", "name": "grpc-route", "namespace": "default"},
+					"spec": {"parentRefs": [{"name": "gw", "namespace": "gateway"}, {"name": "unknown"}],
+					"rules": [{"matches": [{"method": {"service": "foo.Bar", "method": "Get"}}], "backendRefs": [{"name": "grpc", "port": 9090}]}]}}`},
+				"*v1.CronJob": {`{"metadata": {"uid": "cronjob", "name": "cronjob", "namespace": "default"}}`},
+				"*v1.Job": {
+					`{"metadata": {"uid": "scheduled-job", "name": "cronjob-1", "namespace": "default", "ownerReferences": [{"kind": "CronJob", "uid": "cronjob"}]}}`,
+					`{"metadata": {"uid": "job", "name": "job", "namespace": "default"}, "spec": {"parallelism": 2}}`,
+				},
+			},
+		}
+
+		ingresses, rules, backends, err := k8s.getPodGateways()
+		So(err, ShouldBeNil)
+		So(len(ingresses), ShouldEqual, 1)
+		So(ingresses[0].PodNamespaceLcuuid, ShouldEqual, "gateway-ns-lcuuid")
+		So(len(rules), ShouldEqual, 3)
+		So(len(backends), ShouldEqual, 5)
+		paths := map[string]string{}
+		for _, backend := range backends {
+			paths[backend.Path] = backend.PodServiceLcuuid
+		}
+		So(paths, ShouldResemble, map[string]string{"/api": "web-lcuuid", "/web": "web-lcuuid", "/foo.Bar/Get": "grpc-lcuuid"})
+		So(k8s.serviceLcuuidToIngressLcuuid["grpc-lcuuid"], ShouldEqual, ingresses[0].Lcuuid)
+
+		podGroups, err := k8s.getPodGroups()
+		So(err, ShouldBeNil)
+		So(len(podGroups), ShouldEqual, 2)
+		So(podGroups[0].Type, ShouldEqual, common.POD_GROUP_CRONJOB)
+		So(podGroups[1].Type, ShouldEqual, common.POD_GROUP_JOB)
+		So(podGroups[1].PodNum, ShouldEqual, 2)
+		So(k8s.jobLcuuidToPodGroupLcuuid[common.IDGenerateUUID(0, "scheduled-job")], ShouldEqual, podGroups[0].Lcuuid)
+	})
+}
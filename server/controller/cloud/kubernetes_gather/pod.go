@@ -35,6 +35,7 @@ func (k *KubernetesGather) getPods() (pods []model.Pod, err error) {
 		"DaemonSet":             false,
 		"Deployment":            false,
 		"InPlaceSet":            false,
+		"Job":                   false,
 		"ReplicaSet":            false,
 		"StatefulSet":           false,
 		"ReplicationController": false,
@@ -119,6 +120,8 @@ func (k *KubernetesGather) getPods() (pods []model.Pod, err error) {
 		if gLcuuid, ok := k.rsLcuuidToPodGroupLcuuid[ID]; ok {
 			podRSLcuuid = ID
 			podGroupLcuuid = gLcuuid
+		} else if gLcuuid, ok := k.jobLcuuidToPodGroupLcuuid[ID]; ok {
+			podGroupLcuuid = gLcuuid
 		} else {
 			if !k.podGroupLcuuids.Contains(ID) {
 				log.Debugf("pod (%s) pod group not found", name)
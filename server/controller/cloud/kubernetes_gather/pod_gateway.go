@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kubernetes_gather
+
+import (
+	"strconv"
+
+	"github.com/bitly/go-simplejson"
+	mapset "github.com/deckarep/golang-set"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+// getPodGateways maps the resources of kubernetes gateway api to ingresses, each gateway is an ingress,
+// the hostnames of httproutes and grpcroutes attached to the gateway are its rules, and backendRefs are the rule backends
+func (k *KubernetesGather) getPodGateways() (ingresses []model.PodIngress, ingressRules []model.PodIngressRule, ingressRuleBackends []model.PodIngressRuleBackend, err error) {
+	log.Debug("get gateways starting")
+	var gatewayInfo []string
+	switch {
+	case len(k.k8sInfo["*v1.Gateway"]) != 0:
+		gatewayInfo = k.k8sInfo["*v1.Gateway"]
+	case len(k.k8sInfo["*v1beta1.Gateway"]) != 0:
+		gatewayInfo = k.k8sInfo["*v1beta1.Gateway"]
+	}
+	nsNameToGatewayLcuuid := map[string]string{}
+	for _, g := range gatewayInfo {
+		gData, gErr := simplejson.NewJson([]byte(g))
+		if gErr != nil {
+			err = gErr
+			log.Errorf("gateway initialization simplejson error: (%s)", gErr.Error())
+			return
+		}
+		metaData, ok := gData.CheckGet("metadata")
+		if !ok {
+			log.Info("gateway metadata not found")
+			continue
+		}
+		uID := metaData.Get("uid").MustString()
+		if uID == "" {
+			log.Info("gateway uid not found")
+			continue
+		}
+		name := metaData.Get("name").MustString()
+		if name == "" {
+			log.Infof("gateway (%s) name not found", uID)
+			continue
+		}
+		namespace := metaData.Get("namespace").MustString()
+		namespaceLcuuid, ok := k.namespaceToLcuuid[namespace]
+		if !ok {
+			log.Infof("gateway (%s) namespace not found", name)
+			continue
+		}
+		uLcuuid := common.IDGenerateUUID(k.orgID, uID)
+		ingress := model.PodIngress{
+			Lcuuid:             uLcuuid,
+			Name:               name,
+			PodNamespaceLcuuid: namespaceLcuuid,
+			AZLcuuid:           k.azLcuuid,
+			RegionLcuuid:       k.RegionUUID,
+			PodClusterLcuuid:   k.podClusterLcuuid,
+		}
+		ingresses = append(ingresses, ingress)
+		nsNameToGatewayLcuuid[namespace+name] = uLcuuid
+	}
+	if len(nsNameToGatewayLcuuid) == 0 {
+		log.Debug("get gateways complete")
+		return
+	}
+
+	httpRouteInfo := k.k8sInfo["*v1.HTTPRoute"]
+	if len(httpRouteInfo) == 0 {
+		httpRouteInfo = k.k8sInfo["*v1beta1.HTTPRoute"]
+	}
+	grpcRouteInfo := k.k8sInfo["*v1.GRPCRoute"]
+	if len(grpcRouteInfo) == 0 {
+		grpcRouteInfo = k.k8sInfo["*v1alpha2.GRPCRoute"]
+	}
+	backendLcuuids := mapset.NewSet()
+	// routes are handled in a fixed order, so that the gateway associated with a service is stable
+	routeInfos := []struct {
+		protocol string
+		info     []string
+	}{
+		{"HTTP", httpRouteInfo},
+		{"GRPC", grpcRouteInfo},
+	}
+	for _, routeInfo := range routeInfos {
+		protocol := routeInfo.protocol
+		for _, r := range routeInfo.info {
+			rData, rErr := simplejson.NewJson([]byte(r))
+			if rErr != nil {
+				err = rErr
+				log.Errorf("route initialization simplejson error: (%s)", rErr.Error())
+				return
+			}
+			metaData, ok := rData.CheckGet("metadata")
+			if !ok {
+				log.Info("route metadata not found")
+				continue
+			}
+			uID := metaData.Get("uid").MustString()
+			if uID == "" {
+				log.Info("route uid not found")
+				continue
+			}
+			name := metaData.Get("name").MustString()
+			namespace := metaData.Get("namespace").MustString()
+			spec := rData.Get("spec")
+
+			hosts := spec.Get("hostnames").MustStringArray()
+			if len(hosts) == 0 {
+				hosts = []string{""}
+			}
+			parentRefs := spec.Get("parentRefs")
+			for p := range parentRefs.MustArray() {
+				parentRef := parentRefs.GetIndex(p)
+				if kind := parentRef.Get("kind").MustString(); kind != "" && kind != "Gateway" {
+					continue
+				}
+				gatewayNamespace := parentRef.Get("namespace").MustString()
+				if gatewayNamespace == "" {
+					gatewayNamespace = namespace
+				}
+				gatewayName := parentRef.Get("name").MustString()
+				gatewayLcuuid, ok := nsNameToGatewayLcuuid[gatewayNamespace+gatewayName]
+				if !ok {
+					log.Infof("route (%s) gateway (%s) not found", name, gatewayName)
+					continue
+				}
+				for _, host := range hosts {
+					ruleLcuuid := common.GetUUIDByOrgID(k.orgID, gatewayLcuuid+uID+host)
+					ingressRule := model.PodIngressRule{
+						Lcuuid:           ruleLcuuid,
+						Name:             name,
+						Host:             host,
+						Protocol:         protocol,
+						PodIngressLcuuid: gatewayLcuuid,
+					}
+					ingressRules = append(ingressRules, ingressRule)
+
+					rules := spec.Get("rules")
+					for i := range rules.MustArray() {
+						rule := rules.GetIndex(i)
+						paths := getRouteMatchPaths(protocol, rule.Get("matches"))
+						backendRefs := rule.Get("backendRefs")
+						for j := range backendRefs.MustArray() {
+							backendRef := backendRefs.GetIndex(j)
+							if kind := backendRef.Get("kind").MustString(); kind != "" && kind != "Service" {
+								continue
+							}
+							serviceNamespace := backendRef.Get("namespace").MustString()
+							if serviceNamespace == "" {
+								serviceNamespace = namespace
+							}
+							serviceName := backendRef.Get("name").MustString()
+							service, ok := k.nsServiceNameToService[serviceNamespace+serviceName]
+							if !ok {
+								log.Infof("route backend service (%s) not found", serviceName)
+								continue
+							}
+							serviceLcuuid := ""
+							for key := range service {
+								serviceLcuuid = key
+								break
+							}
+							if ingressLcuuid, ok := k.serviceLcuuidToIngressLcuuid[serviceLcuuid]; ok && ingressLcuuid != gatewayLcuuid {
+								log.Infof("ingress (%s) is already associated with the service (%s), and gateway (%s) cannot be associated", ingressLcuuid, serviceLcuuid, gatewayName)
+							} else {
+								k.serviceLcuuidToIngressLcuuid[serviceLcuuid] = gatewayLcuuid
+							}
+							port := backendRef.Get("port").MustInt()
+							if port == 0 {
+								log.Infof("route (%s) backend service (%s) no port", uID, serviceName)
+								continue
+							}
+							key := serviceName + "_" + strconv.Itoa(port)
+							for _, path := range paths {
+								backendLcuuid := common.GetUUIDByOrgID(k.orgID, ruleLcuuid+key+path)
+								if backendLcuuids.Contains(backendLcuuid) {
+									continue
+								}
+								backendLcuuids.Add(backendLcuuid)
+								ingressRuleBackend := model.PodIngressRuleBackend{
+									Lcuuid:               backendLcuuid,
+									Path:                 path,
+									Port:                 port,
+									PodServiceLcuuid:     serviceLcuuid,
+									PodIngressRuleLcuuid: ruleLcuuid,
+									PodIngressLcuuid:     gatewayLcuuid,
+								}
+								ingressRuleBackends = append(ingressRuleBackends, ingressRuleBackend)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	log.Debug("get gateways complete")
+	return
+}
+
+// getRouteMatchPaths returns the paths of the route rule matches, methods of grpcroute are formatted as /service/method
+func getRouteMatchPaths(protocol string, matches *simplejson.Json) []string {
+	paths := []string{}
+	for i := range matches.MustArray() {
+		match := matches.GetIndex(i)
+		path := match.Get("path").Get("value").MustString()
+		if protocol == "GRPC" {
+			method := match.Get("method")
+			if service := method.Get("service").MustString(); service != "" {
+				path = "/" + service + "/" + method.Get("method").MustString()
+			}
+		}
+		if path == "" {
+			path = "/"
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		// rules without matches match all requests
+		paths = append(paths, "/")
+	}
+	return paths
+}
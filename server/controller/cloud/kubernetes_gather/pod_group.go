@@ -27,12 +27,15 @@ import (
 
 func (k *KubernetesGather) getPodGroups() (podGroups []model.PodGroup, err error) {
 	log.Debug("get podgroups starting")
-	podControllers := [5][]string{}
+	podControllers := [7][]string{}
 	podControllers[0] = k.k8sInfo["*v1.Deployment"]
 	podControllers[1] = k.k8sInfo["*v1.StatefulSet"]
 	podControllers[2] = k.k8sInfo["*v1.DaemonSet"]
 	podControllers[3] = k.k8sInfo["*v1.CloneSet"]
-	podControllers[4] = k.k8sInfo["*v1.Pod"]
+	// cronjobs must be handled before jobs, jobs scheduled by a cronjob are attributed to it
+	podControllers[4] = k.k8sInfo["*v1.CronJob"]
+	podControllers[5] = k.k8sInfo["*v1.Job"]
+	podControllers[6] = k.k8sInfo["*v1.Pod"]
 	pgNameToTypeID := map[string]int{
 		"deployment":            common.POD_GROUP_DEPLOYMENT,
 		"statefulset":           common.POD_GROUP_STATEFULSET,
@@ -40,6 +43,8 @@ func (k *KubernetesGather) getPodGroups() (podGroups []model.PodGroup, err error
 		"daemonset":             common.POD_GROUP_DAEMON_SET,
 		"replicationcontroller": common.POD_GROUP_RC,
 		"cloneset":              common.POD_GROUP_CLONESET,
+		"job":                   common.POD_GROUP_JOB,
+		"cronjob":               common.POD_GROUP_CRONJOB,
 	}
 	for t, podController := range podControllers {
 		for _, c := range podController {
@@ -79,6 +84,7 @@ func (k *KubernetesGather) getPodGroups() (podGroups []model.PodGroup, err error
 			serviceType := common.POD_GROUP_STATEFULSET
 			label := "statefulset:" + namespace + ":" + name
 			replicas := cData.Get("spec").Get("replicas").MustInt()
+			template := cData.Get("spec").Get("template")
 			switch t {
 			case 0:
 				serviceType = common.POD_GROUP_DEPLOYMENT
@@ -91,6 +97,23 @@ func (k *KubernetesGather) getPodGroups() (podGroups []model.PodGroup, err error
 				serviceType = common.POD_GROUP_CLONESET
 				label = "cloneset:" + namespace + ":" + name
 			case 4:
+				replicas = 0
+				template = cData.GetPath("spec", "jobTemplate", "spec", "template")
+				serviceType = common.POD_GROUP_CRONJOB
+				label = "cronjob:" + namespace + ":" + name
+			case 5:
+				ownerReference := metaData.Get("ownerReferences").GetIndex(0)
+				if ownerReference.Get("kind").MustString() == "CronJob" {
+					cronJobLcuuid := common.IDGenerateUUID(k.orgID, ownerReference.Get("uid").MustString())
+					if k.podGroupLcuuids.Contains(cronJobLcuuid) {
+						k.jobLcuuidToPodGroupLcuuid[uLcuuid] = cronJobLcuuid
+						continue
+					}
+				}
+				replicas = cData.Get("spec").Get("parallelism").MustInt()
+				serviceType = common.POD_GROUP_JOB
+				label = "job:" + namespace + ":" + name
+			case 6:
 				replicas = 0
 				if metaData.Get("ownerReferences").GetIndex(0).Get("kind").MustString() == "InPlaceSet" {
 					uLcuuid = common.IDGenerateUUID(k.orgID, metaData.Get("ownerReferences").GetIndex(0).Get("uid").MustString())
@@ -145,7 +168,7 @@ func (k *KubernetesGather) getPodGroups() (podGroups []model.PodGroup, err error
 				groupIDsSet.Add(uLcuuid)
 				k.nsLabelToGroupLcuuids[namespace+label] = groupIDsSet
 			}
-			mLabels := cData.GetPath("spec", "template", "metadata", "labels").MustMap()
+			mLabels := template.GetPath("metadata", "labels").MustMap()
 			for key, v := range mLabels {
 				vString, ok := v.(string)
 				if !ok {
@@ -178,7 +201,7 @@ func (k *KubernetesGather) getPodGroups() (podGroups []model.PodGroup, err error
 				}
 			}
 
-			containers := cData.Get("spec").Get("template").Get("spec").Get("containers")
+			containers := template.Get("spec").Get("containers")
 			for i := range containers.MustArray() {
 				container := containers.GetIndex(i)
 				cPorts, ok := container.CheckGet("ports")
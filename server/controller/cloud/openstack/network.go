@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"fmt"
+
+	"github.com/bitly/go-simplejson"
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (o *OpenStack) getNetworks(region RegionInfo, token *Token) ([]model.Network, []model.Subnet, error) {
+	var networks []model.Network
+	var subnets []model.Subnet
+
+	jNetworks, err := o.getRawData(fmt.Sprintf("%s/networks", region.networkURL), token.token, "networks")
+	if err != nil {
+		return nil, nil, err
+	}
+	requiredAttrs := []string{"id", "name", "project_id"}
+	for i := range jNetworks {
+		jNetwork := jNetworks[i]
+		name := jNetwork.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jNetwork, requiredAttrs) {
+			log.Infof("exclude network: %s, missing attr", name)
+			continue
+		}
+		id := common.IDGenerateUUID(o.orgID, jNetwork.Get("id").MustString())
+		external := jNetwork.Get("router:external").MustBool()
+		netType := common.NETWORK_TYPE_LAN
+		if external {
+			netType = common.NETWORK_TYPE_WAN
+		}
+		azLcuuid := o.getNetworkAZLcuuid(region, jNetwork)
+		network := model.Network{
+			Lcuuid:         id,
+			Name:           name,
+			SegmentationID: jNetwork.Get("provider:segmentation_id").MustInt(),
+			Shared:         jNetwork.Get("shared").MustBool(),
+			External:       external,
+			NetType:        netType,
+			VPCLcuuid:      o.getVPCLcuuid(region, jNetwork.Get("project_id").MustString()),
+			AZLcuuid:       azLcuuid,
+			RegionLcuuid:   region.lcuuid,
+		}
+		networks = append(networks, network)
+		o.toolDataSet.lcuuidToNetwork[id] = network
+		o.toolDataSet.regionLcuuidToResourceNum[region.lcuuid]++
+		if azLcuuid != "" {
+			o.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		}
+	}
+
+	jSubnets, err := o.getRawData(fmt.Sprintf("%s/subnets", region.networkURL), token.token, "subnets")
+	if err != nil {
+		return nil, nil, err
+	}
+	requiredAttrs = []string{"id", "cidr", "network_id"}
+	for i := range jSubnets {
+		jSubnet := jSubnets[i]
+		id := common.IDGenerateUUID(o.orgID, jSubnet.Get("id").MustString())
+		if !cloudcommon.CheckJsonAttributes(jSubnet, requiredAttrs) {
+			log.Infof("exclude subnet: %s, missing attr", id)
+			continue
+		}
+		network, ok := o.toolDataSet.lcuuidToNetwork[common.IDGenerateUUID(o.orgID, jSubnet.Get("network_id").MustString())]
+		if !ok {
+			log.Infof("exclude subnet: %s, missing network info", id)
+			continue
+		}
+		name := jSubnet.Get("name").MustString()
+		if name == "" {
+			name = network.Name
+		}
+		subnet := model.Subnet{
+			Lcuuid:        id,
+			Name:          name,
+			CIDR:          jSubnet.Get("cidr").MustString(),
+			GatewayIP:     jSubnet.Get("gateway_ip").MustString(),
+			NetworkLcuuid: network.Lcuuid,
+			VPCLcuuid:     network.VPCLcuuid,
+		}
+		subnets = append(subnets, subnet)
+		o.toolDataSet.lcuuidToSubnet[id] = subnet
+	}
+	return networks, subnets, nil
+}
+
+// getNetworkAZLcuuid returns the az of network, only when the network is limited in one az
+func (o *OpenStack) getNetworkAZLcuuid(region RegionInfo, jNetwork *simplejson.Json) string {
+	zones := jNetwork.Get("availability_zones").MustStringArray()
+	if len(zones) != 1 {
+		return ""
+	}
+	return o.toolDataSet.keyToAZLcuuid[AZKey{region.name, zones[0]}]
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/op/go-logging"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+)
+
+var log = logging.MustGetLogger("cloud.openstack")
+
+// OpenStack learns resources from keystone, nova and neutron,
+// every project in a region is regarded as a vpc.
+// security groups are not learned, since they are not part of model.Resource
+type OpenStack struct {
+	orgID          int
+	teamID         int
+	lcuuid         string
+	lcuuidGenerate string
+	name           string
+	httpTimeout    int
+	config         *Config
+	token          *Token
+	toolDataSet    *ToolDataSet       // 处理资源数据时，构建的需要提供给其他资源使用的工具数据
+	cloudStatsd    statsd.CloudStatsd // 性能监控
+	debugger       *cloudcommon.Debugger
+}
+
+func NewOpenStack(orgID int, domain mysql.Domain, globalCloudCfg config.CloudConfig) (*OpenStack, error) {
+	conf := &Config{}
+	err := conf.LoadFromString(domain.Config)
+	if err != nil {
+		return nil, err
+	}
+	return newOpenStack(orgID, domain, globalCloudCfg, conf), nil
+}
+
+func newOpenStack(orgID int, domain mysql.Domain, globalCloudCfg config.CloudConfig, conf *Config) *OpenStack {
+	conf.tidy()
+	return &OpenStack{
+		orgID:  orgID,
+		teamID: domain.TeamID,
+		lcuuid: domain.Lcuuid,
+		// TODO: display_name后期需要修改为uuid_generate
+		lcuuidGenerate: domain.DisplayName,
+		name:           domain.Name,
+		httpTimeout:    globalCloudCfg.HTTPTimeout,
+		config:         conf,
+		debugger:       cloudcommon.NewDebugger(domain.Name),
+	}
+}
+
+func (o *OpenStack) ClearDebugLog() {
+	o.debugger.Clear()
+}
+
+func (o *OpenStack) CheckAuth() error {
+	_, err := o.createToken()
+	return err
+}
+
+func (o *OpenStack) GetCloudData() (model.Resource, error) {
+	o.cloudStatsd = statsd.NewCloudStatsd()
+	o.toolDataSet = NewToolDataSet()
+	var resource model.Resource
+	token, err := o.getToken()
+	if err != nil {
+		return resource, err
+	}
+
+	err = o.getProjects(token)
+	if err != nil {
+		return resource, err
+	}
+
+	regions, regionInfos := o.getRegions(token)
+	for _, region := range regionInfos {
+		azs, hosts, err := o.getAZsAndHosts(region, token)
+		if err != nil {
+			return resource, err
+		}
+		resource.AZs = append(resource.AZs, azs...)
+		resource.Hosts = append(resource.Hosts, hosts...)
+
+		networks, subnets, err := o.getNetworks(region, token)
+		if err != nil {
+			return resource, err
+		}
+		resource.Networks = append(resource.Networks, networks...)
+		resource.Subnets = append(resource.Subnets, subnets...)
+
+		vrouters, routingTables, err := o.getRouters(region, token)
+		if err != nil {
+			return resource, err
+		}
+		resource.VRouters = append(resource.VRouters, vrouters...)
+		resource.RoutingTables = append(resource.RoutingTables, routingTables...)
+
+		vms, err := o.getVMs(region, token)
+		if err != nil {
+			return resource, err
+		}
+		resource.VMs = append(resource.VMs, vms...)
+
+		dhcpPorts, vifs, ips, err := o.getVInterfaces(region, token)
+		if err != nil {
+			return resource, err
+		}
+		resource.DHCPPorts = append(resource.DHCPPorts, dhcpPorts...)
+		resource.VInterfaces = append(resource.VInterfaces, vifs...)
+		resource.IPs = append(resource.IPs, ips...)
+
+		fIPs, err := o.getFloatingIPs(region, token)
+		if err != nil {
+			return resource, err
+		}
+		resource.FloatingIPs = append(resource.FloatingIPs, fIPs...)
+	}
+	resource.VPCs = o.getVPCs()
+
+	log.Debugf("region resource num info: %v", o.toolDataSet.regionLcuuidToResourceNum)
+	log.Debugf("az resource num info: %v", o.toolDataSet.azLcuuidToResourceNum)
+	resource.Regions = cloudcommon.EliminateEmptyRegions(regions, o.toolDataSet.regionLcuuidToResourceNum)
+	resource.AZs = cloudcommon.EliminateEmptyAZs(resource.AZs, o.toolDataSet.azLcuuidToResourceNum)
+
+	o.cloudStatsd.ResCount = statsd.GetResCount(resource)
+	statsd.MetaStatsd.RegisterStatsdTable(o)
+
+	o.debugger.Refresh()
+	return resource, nil
+}
+
+func (o *OpenStack) GetStatter() statsd.StatsdStatter {
+	globalTags := map[string]string{
+		"domain_name": o.name,
+		"domain":      o.lcuuid,
+		"platform":    common.OPENSTACK_EN,
+	}
+
+	return statsd.StatsdStatter{
+		OrgID:      o.orgID,
+		TeamID:     o.teamID,
+		GlobalTags: globalTags,
+		Element:    statsd.GetCloudStatsd(o.cloudStatsd),
+	}
+}
+
+// getRawData gets all items of resultKey, and follows the '<resultKey>_links' next link when the result is paginated
+func (o *OpenStack) getRawData(url, token, resultKey string) (jsonList []*simplejson.Json, err error) {
+	statsdAPIStartTime := time.Now()
+
+	nextURL := url
+	for nextURL != "" {
+		resp, err := cloudcommon.RequestGet(nextURL, token, time.Duration(o.httpTimeout))
+		if err != nil {
+			return []*simplejson.Json{}, err
+		}
+		jData := resp.Get(resultKey)
+		for i := range jData.MustArray() {
+			jsonList = append(jsonList, jData.GetIndex(i))
+		}
+
+		nextURL = ""
+		jLinks := resp.Get(resultKey + "_links")
+		for i := range jLinks.MustArray() {
+			jLink := jLinks.GetIndex(i)
+			if jLink.Get("rel").MustString() == "next" && len(jData.MustArray()) > 0 {
+				nextURL = jLink.Get("href").MustString()
+				break
+			}
+		}
+	}
+	o.cloudStatsd.RefreshAPIMoniter(resultKey, len(jsonList), statsdAPIStartTime)
+
+	o.debugger.WriteJson(resultKey, url, jsonList)
+	return
+}
+
+func (o *OpenStack) getProjects(token *Token) error {
+	jProjects, err := o.getRawData(fmt.Sprintf("%s/projects", o.config.AuthURL), token.token, "projects")
+	if err != nil {
+		return err
+	}
+	for i := range jProjects {
+		jProject := jProjects[i]
+		if !cloudcommon.CheckJsonAttributes(jProject, []string{"id", "name"}) {
+			continue
+		}
+		o.toolDataSet.projectIDToName[jProject.Get("id").MustString()] = jProject.Get("name").MustString()
+	}
+	return nil
+}
+
+// getVPCs returns the vpcs of projects which have resources in regions
+func (o *OpenStack) getVPCs() []model.VPC {
+	keys := make([]VPCKey, 0, len(o.toolDataSet.keyToVPC))
+	for key := range o.toolDataSet.keyToVPC {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].regionName+keys[i].projectID < keys[j].regionName+keys[j].projectID
+	})
+	vpcs := make([]model.VPC, 0, len(keys))
+	for _, key := range keys {
+		vpc := o.toolDataSet.keyToVPC[key]
+		vpcs = append(vpcs, vpc)
+		o.toolDataSet.regionLcuuidToResourceNum[vpc.RegionLcuuid]++
+	}
+	return vpcs
+}
+
+func (o *OpenStack) getVPCLcuuid(region RegionInfo, projectID string) string {
+	key := VPCKey{region.name, projectID}
+	if vpc, ok := o.toolDataSet.keyToVPC[key]; ok {
+		return vpc.Lcuuid
+	}
+	name, ok := o.toolDataSet.projectIDToName[projectID]
+	if !ok {
+		name = projectID
+	}
+	vpc := model.VPC{
+		Lcuuid:       common.GenerateUUIDByOrgID(o.orgID, projectID+"_"+region.name+"_"+o.lcuuidGenerate),
+		Name:         name,
+		RegionLcuuid: region.lcuuid,
+	}
+	o.toolDataSet.keyToVPC[key] = vpc
+	return vpc.Lcuuid
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	mysqlcommon "github.com/deepflowio/deepflow/server/controller/db/mysql/common"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+	statsdcfg "github.com/deepflowio/deepflow/server/controller/statsd/config"
+)
+
+const (
+	testPassword = "secret"
+	testToken    = "gAAAAABtest-token"
+)
+
+var testRoutes = map[string]string{
+	"/identity/v3/projects":                     "projects.json",
+	"/compute/v2.1/os-availability-zone/detail": "availability_zones.json",
+	"/compute/v2.1/os-hypervisors/detail":       "hypervisors.json",
+	"/compute/v2.1/servers/detail":              "servers.json",
+	"/network/v2.0/networks":                    "networks.json",
+	"/network/v2.0/subnets":                     "subnets.json",
+	"/network/v2.0/routers":                     "routers.json",
+	"/network/v2.0/ports":                       "ports.json",
+	"/network/v2.0/floatingips":                 "floatingips.json",
+}
+
+// newOpenStackStub serves the recorded responses in testdata, '{{endpoint}}' in them is replaced by the stub url
+func newOpenStackStub() *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var file string
+		if r.URL.Path == "/identity/v3/auth/tokens" && r.Method == http.MethodPost {
+			var body struct {
+				Auth struct {
+					Identity struct {
+						Password struct {
+							User struct {
+								Password string `json:"password"`
+							} `json:"user"`
+						} `json:"password"`
+					} `json:"identity"`
+				} `json:"auth"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.Auth.Identity.Password.User.Password != testPassword {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("X-Subject-Token", testToken)
+			file = "token.json"
+		} else {
+			if r.Header.Get("X-Auth-Token") != testToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			file = testRoutes[r.URL.Path]
+			if file == "servers.json" && r.URL.Query().Get("marker") != "" {
+				file = "servers_page2.json"
+			}
+		}
+		data, err := os.ReadFile("testdata/" + file)
+		if file == "" || err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if file == "token.json" {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(strings.ReplaceAll(string(data), "{{endpoint}}", server.URL)))
+	}))
+	return server
+}
+
+func newTestOpenStack(authURL, password string) *OpenStack {
+	domain := mysql.Domain{
+		Name:        "test_openstack",
+		DisplayName: "test_openstack",
+	}
+	conf := &Config{
+		AuthURL:     authURL,
+		UserName:    "admin",
+		Password:    password,
+		ProjectName: "admin",
+	}
+	return newOpenStack(mysqlcommon.DEFAULT_ORG_ID, domain, config.CloudConfig{HTTPTimeout: 5}, conf)
+}
+
+func TestOpenStack(t *testing.T) {
+	config.SetCloudGlobalConfig(config.CloudConfig{HTTPTimeout: 5})
+	statsd.NewStatsdMonitor(statsdcfg.StatsdConfig{})
+	server := newOpenStackStub()
+	defer server.Close()
+
+	Convey("TestOpenStack_CheckAuth", t, func() {
+		So(newTestOpenStack(server.URL+"/identity", testPassword).CheckAuth(), ShouldBeNil)
+		So(newTestOpenStack(server.URL+"/identity/v3/", "wrong").CheckAuth(), ShouldNotBeNil)
+	})
+
+	Convey("TestOpenStack_GetCloudData", t, func() {
+		openstack := newTestOpenStack(server.URL+"/identity", testPassword)
+		data, err := openstack.GetCloudData()
+		So(err, ShouldBeNil)
+
+		Convey("region without network endpoint should be excluded", func() {
+			So(len(data.Regions), ShouldEqual, 1)
+			So(data.Regions[0].Name, ShouldEqual, "RegionOne")
+		})
+
+		Convey("internal az should be excluded", func() {
+			So(len(data.AZs), ShouldEqual, 1)
+			So(data.AZs[0].Name, ShouldEqual, "nova")
+			So(len(data.Hosts), ShouldEqual, 2)
+		})
+
+		Convey("projects should be vpcs", func() {
+			So(len(data.VPCs), ShouldEqual, 2)
+			names := []string{data.VPCs[0].Name, data.VPCs[1].Name}
+			So(names, ShouldContain, "admin")
+			So(names, ShouldContain, "demo")
+		})
+
+		Convey("networks and routers should be learned", func() {
+			So(len(data.Networks), ShouldEqual, 2)
+			So(len(data.Subnets), ShouldEqual, 2)
+			So(len(data.VRouters), ShouldEqual, 1)
+			So(len(data.RoutingTables), ShouldEqual, 1)
+			So(data.RoutingTables[0].Destination, ShouldEqual, "192.168.100.0/24")
+			for _, network := range data.Networks {
+				if network.Name == "public" {
+					So(network.External, ShouldBeTrue)
+					So(network.NetType, ShouldEqual, common.NETWORK_TYPE_WAN)
+				} else {
+					So(network.SegmentationID, ShouldEqual, 101)
+				}
+			}
+		})
+
+		Convey("paginated vms should be learned", func() {
+			So(len(data.VMs), ShouldEqual, 2)
+			for _, vm := range data.VMs {
+				if vm.Name == "web-1" {
+					So(vm.State, ShouldEqual, common.VM_STATE_RUNNING)
+					So(vm.LaunchServer, ShouldEqual, "10.0.0.11")
+					So(vm.CloudTags["app"], ShouldEqual, "web")
+				} else {
+					So(vm.State, ShouldEqual, common.VM_STATE_STOPPED)
+					So(vm.LaunchServer, ShouldEqual, "10.0.0.12")
+				}
+			}
+		})
+
+		Convey("ports and floating ips should be learned", func() {
+			So(len(data.VInterfaces), ShouldEqual, 5)
+			So(len(data.IPs), ShouldEqual, 5)
+			So(len(data.DHCPPorts), ShouldEqual, 1)
+			So(len(data.FloatingIPs), ShouldEqual, 1)
+			So(data.FloatingIPs[0].IP, ShouldEqual, "172.24.4.100")
+			So(data.FloatingIPs[0].VMLcuuid, ShouldEqual, "0f1e2d3c-4b5a-4968-8776-a5b4c3d2e1f0")
+		})
+	})
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"fmt"
+	"sort"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+const INTERNAL_ZONE_NAME = "internal"
+
+func (o *OpenStack) getRegions(token *Token) ([]model.Region, []RegionInfo) {
+	names := make([]string, 0, len(token.endpoints))
+	for name := range token.endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var regions []model.Region
+	var regionInfos []RegionInfo
+	for _, name := range names {
+		if len(o.config.IncludeRegions) > 0 && !common.Contains(o.config.IncludeRegions, name) {
+			log.Infof("exclude region: %s, not included", name)
+			continue
+		}
+		if common.Contains(o.config.ExcludeRegions, name) {
+			log.Infof("exclude region: %s", name)
+			continue
+		}
+		endpoints := token.endpoints[name]
+		if endpoints[SERVICE_TYPE_COMPUTE] == "" || endpoints[SERVICE_TYPE_NETWORK] == "" {
+			log.Infof("exclude region: %s, missing compute or network endpoint", name)
+			continue
+		}
+
+		region := model.Region{
+			Lcuuid: common.GenerateUUIDByOrgID(o.orgID, name+"_"+o.lcuuidGenerate),
+			Name:   name,
+		}
+		regions = append(regions, region)
+		regionLcuuid := region.Lcuuid
+		if o.config.RegionLcuuid != "" {
+			regionLcuuid = o.config.RegionLcuuid
+		}
+		regionInfos = append(regionInfos, RegionInfo{
+			name:       name,
+			lcuuid:     regionLcuuid,
+			computeURL: endpoints[SERVICE_TYPE_COMPUTE],
+			networkURL: endpoints[SERVICE_TYPE_NETWORK],
+		})
+	}
+	return regions, regionInfos
+}
+
+func (o *OpenStack) getAZsAndHosts(region RegionInfo, token *Token) ([]model.AZ, []model.Host, error) {
+	var azs []model.AZ
+	var hosts []model.Host
+	jAZs, err := o.getRawData(fmt.Sprintf("%s/os-availability-zone/detail", region.computeURL), token.token, "availabilityZoneInfo")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hostNameToAZLcuuid := make(map[string]string)
+	for i := range jAZs {
+		jAZ := jAZs[i]
+		zoneName := jAZ.Get("zoneName").MustString()
+		if !cloudcommon.CheckJsonAttributes(jAZ, []string{"zoneName"}) || zoneName == INTERNAL_ZONE_NAME {
+			log.Infof("exclude az: %s", zoneName)
+			continue
+		}
+		lcuuid := common.GenerateUUIDByOrgID(o.orgID, region.name+"_"+zoneName+"_"+o.lcuuidGenerate)
+		azs = append(azs, model.AZ{
+			Lcuuid:       lcuuid,
+			Name:         zoneName,
+			RegionLcuuid: region.lcuuid,
+		})
+		o.toolDataSet.keyToAZLcuuid[AZKey{region.name, zoneName}] = lcuuid
+		for hostName := range jAZ.Get("hosts").MustMap() {
+			hostNameToAZLcuuid[hostName] = lcuuid
+		}
+	}
+
+	// hypervisors can only be listed by admin, ignore the error and learn vms without hosts
+	jHypervisors, err := o.getRawData(fmt.Sprintf("%s/os-hypervisors/detail", region.computeURL), token.token, "hypervisors")
+	if err != nil {
+		log.Warningf("get hypervisors of region (%s) failed, hosts are not learned: %s", region.name, err)
+		return azs, hosts, nil
+	}
+	requiredAttrs := []string{"hypervisor_hostname", "host_ip"}
+	for i := range jHypervisors {
+		jHypervisor := jHypervisors[i]
+		name := jHypervisor.Get("hypervisor_hostname").MustString()
+		if !cloudcommon.CheckJsonAttributes(jHypervisor, requiredAttrs) {
+			log.Infof("exclude host: %s, missing attr", name)
+			continue
+		}
+		// service.host is the host name used by nova services and vms
+		serviceHost := jHypervisor.Get("service").Get("host").MustString(name)
+		azLcuuid, ok := hostNameToAZLcuuid[serviceHost]
+		if !ok {
+			log.Infof("exclude host: %s, missing az info", name)
+			continue
+		}
+		ip := jHypervisor.Get("host_ip").MustString()
+		hosts = append(hosts, model.Host{
+			Lcuuid:       common.GenerateUUIDByOrgID(o.orgID, ip+"_"+o.lcuuidGenerate),
+			IP:           ip,
+			Name:         name,
+			Hostname:     serviceHost,
+			HType:        common.HOST_HTYPE_KVM,
+			VCPUNum:      jHypervisor.Get("vcpus").MustInt(),
+			MemTotal:     jHypervisor.Get("memory_mb").MustInt(),
+			Type:         common.HOST_TYPE_VM,
+			AZLcuuid:     azLcuuid,
+			RegionLcuuid: region.lcuuid,
+		})
+		o.toolDataSet.hostNameToIP[serviceHost] = ip
+		o.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		o.toolDataSet.regionLcuuidToResourceNum[region.lcuuid]++
+	}
+	return azs, hosts, nil
+}
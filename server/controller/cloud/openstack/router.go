@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"fmt"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (o *OpenStack) getRouters(region RegionInfo, token *Token) ([]model.VRouter, []model.RoutingTable, error) {
+	var vrouters []model.VRouter
+	var routingTables []model.RoutingTable
+
+	jRouters, err := o.getRawData(fmt.Sprintf("%s/routers", region.networkURL), token.token, "routers")
+	if err != nil {
+		return nil, nil, err
+	}
+	requiredAttrs := []string{"id", "name", "project_id"}
+	for i := range jRouters {
+		jRouter := jRouters[i]
+		name := jRouter.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jRouter, requiredAttrs) {
+			log.Infof("exclude vrouter: %s, missing attr", name)
+			continue
+		}
+		id := common.IDGenerateUUID(o.orgID, jRouter.Get("id").MustString())
+		vpcLcuuid := o.getVPCLcuuid(region, jRouter.Get("project_id").MustString())
+		vrouters = append(vrouters, model.VRouter{
+			Lcuuid:       id,
+			Name:         name,
+			VPCLcuuid:    vpcLcuuid,
+			RegionLcuuid: region.lcuuid,
+		})
+		o.toolDataSet.routerLcuuidToVPCLcuuid[id] = vpcLcuuid
+		o.toolDataSet.regionLcuuidToResourceNum[region.lcuuid]++
+
+		jRoutes := jRouter.Get("routes")
+		for j := range jRoutes.MustArray() {
+			jRoute := jRoutes.GetIndex(j)
+			if !cloudcommon.CheckJsonAttributes(jRoute, []string{"destination", "nexthop"}) {
+				continue
+			}
+			destination := jRoute.Get("destination").MustString()
+			nexthop := jRoute.Get("nexthop").MustString()
+			routingTables = append(routingTables, model.RoutingTable{
+				Lcuuid:        common.GenerateUUIDByOrgID(o.orgID, id+destination+nexthop),
+				VRouterLcuuid: id,
+				Destination:   destination,
+				Nexthop:       nexthop,
+				NexthopType:   common.ROUTING_TABLE_TYPE_IP,
+			})
+		}
+	}
+	return vrouters, routingTables, nil
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+)
+
+const (
+	SERVICE_TYPE_COMPUTE = "compute"
+	SERVICE_TYPE_NETWORK = "network"
+)
+
+type Token struct {
+	token     string
+	expiresAt string
+	// region name -> service type -> endpoint url
+	endpoints map[string]map[string]string
+}
+
+// 检查token是否过期
+// 离失效时间小于5m，则认为已经过期
+func (t *Token) isExpired() bool {
+	expire, err := time.Parse(time.RFC3339, t.expiresAt)
+	if err != nil {
+		log.Errorf("parse expire time error: %s, %v", t.expiresAt, err)
+		return true
+	}
+	return expire.Sub(time.Now().UTC()).Minutes() < 5
+}
+
+func (o *OpenStack) getToken() (*Token, error) {
+	if o.token == nil || o.token.isExpired() {
+		token, err := o.createToken()
+		if err != nil {
+			return nil, err
+		}
+		o.token = token
+	}
+	return o.token, nil
+}
+
+// createToken gets a project scoped token by keystone v3 password authentication,
+// and the service endpoints of every region are parsed from the catalog
+func (o *OpenStack) createToken() (*Token, error) {
+	authBody := map[string]interface{}{
+		"auth": map[string]interface{}{
+			"identity": map[string]interface{}{
+				"methods": []string{"password"},
+				"password": map[string]interface{}{
+					"user": map[string]interface{}{
+						"domain": map[string]interface{}{
+							"name": o.config.UserDomainName,
+						},
+						"name":     o.config.UserName,
+						"password": o.config.Password,
+					},
+				},
+			},
+			"scope": map[string]interface{}{
+				"project": map[string]interface{}{
+					"domain": map[string]interface{}{
+						"name": o.config.ProjectDomainName,
+					},
+					"name": o.config.ProjectName,
+				},
+			},
+		},
+	}
+	resp, err := cloudcommon.RequestPost(fmt.Sprintf("%s/auth/tokens", o.config.AuthURL), time.Duration(o.httpTimeout), authBody)
+	if err != nil {
+		return nil, err
+	}
+	token := &Token{
+		token:     resp.Get("X-Subject-Token").MustString(),
+		expiresAt: resp.Get("token").Get("expires_at").MustString(),
+		endpoints: make(map[string]map[string]string),
+	}
+	if token.token == "" {
+		return nil, errors.New("keystone response has no X-Subject-Token")
+	}
+
+	jCatalog := resp.Get("token").Get("catalog")
+	for i := range jCatalog.MustArray() {
+		jService := jCatalog.GetIndex(i)
+		serviceType := jService.Get("type").MustString()
+		if serviceType != SERVICE_TYPE_COMPUTE && serviceType != SERVICE_TYPE_NETWORK {
+			continue
+		}
+		jEndpoints := jService.Get("endpoints")
+		for j := range jEndpoints.MustArray() {
+			jEndpoint := jEndpoints.GetIndex(j)
+			if jEndpoint.Get("interface").MustString() != o.config.EndpointType {
+				continue
+			}
+			region := jEndpoint.Get("region_id").MustString()
+			if region == "" {
+				region = jEndpoint.Get("region").MustString()
+			}
+			if _, ok := token.endpoints[region]; !ok {
+				token.endpoints[region] = make(map[string]string)
+			}
+			url := strings.TrimSuffix(jEndpoint.Get("url").MustString(), "/")
+			if serviceType == SERVICE_TYPE_NETWORK && !strings.HasSuffix(url, "/v2.0") {
+				url += "/v2.0"
+			}
+			token.endpoints[region][serviceType] = url
+		}
+	}
+	return token, nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+)
+
+type ToolDataSet struct {
+	projectIDToName         map[string]string
+	keyToVPC                map[VPCKey]model.VPC
+	keyToAZLcuuid           map[AZKey]string
+	hostNameToIP            map[string]string
+	lcuuidToNetwork         map[string]model.Network
+	lcuuidToSubnet          map[string]model.Subnet
+	lcuuidToVM              map[string]model.VM
+	lcuuidToVInterface      map[string]model.VInterface
+	routerLcuuidToVPCLcuuid map[string]string
+
+	regionLcuuidToResourceNum map[string]int
+	azLcuuidToResourceNum     map[string]int
+}
+
+func NewToolDataSet() *ToolDataSet {
+	return &ToolDataSet{
+		projectIDToName:           make(map[string]string),
+		keyToVPC:                  make(map[VPCKey]model.VPC),
+		keyToAZLcuuid:             make(map[AZKey]string),
+		hostNameToIP:              make(map[string]string),
+		lcuuidToNetwork:           make(map[string]model.Network),
+		lcuuidToSubnet:            make(map[string]model.Subnet),
+		lcuuidToVM:                make(map[string]model.VM),
+		lcuuidToVInterface:        make(map[string]model.VInterface),
+		routerLcuuidToVPCLcuuid:   make(map[string]string),
+		regionLcuuidToResourceNum: make(map[string]int),
+		azLcuuidToResourceNum:     make(map[string]int),
+	}
+}
+
+type RegionInfo struct {
+	name       string
+	lcuuid     string
+	computeURL string
+	networkURL string
+}
+
+type VPCKey struct {
+	regionName string
+	projectID  string
+}
+
+type AZKey struct {
+	regionName string
+	zoneName   string
+}
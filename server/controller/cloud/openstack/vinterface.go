@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"fmt"
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+const (
+	DEVICE_OWNER_VM_PRE     = "compute:"
+	DEVICE_OWNER_ROUTER_PRE = "network:router_"
+	DEVICE_OWNER_HA_ROUTER  = "network:ha_router_replicated_interface"
+	DEVICE_OWNER_DHCP       = "network:dhcp"
+)
+
+func (o *OpenStack) getVInterfaces(region RegionInfo, token *Token) ([]model.DHCPPort, []model.VInterface, []model.IP, error) {
+	var dhcpPorts []model.DHCPPort
+	var vifs []model.VInterface
+	var ips []model.IP
+
+	jPorts, err := o.getRawData(fmt.Sprintf("%s/ports", region.networkURL), token.token, "ports")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	requiredAttrs := []string{"id", "mac_address", "network_id", "device_id", "device_owner"}
+	for i := range jPorts {
+		jPort := jPorts[i]
+		mac := jPort.Get("mac_address").MustString()
+		if !cloudcommon.CheckJsonAttributes(jPort, requiredAttrs) {
+			log.Infof("exclude vinterface: %s, missing attr", mac)
+			continue
+		}
+		id := common.IDGenerateUUID(o.orgID, jPort.Get("id").MustString())
+		network, ok := o.toolDataSet.lcuuidToNetwork[common.IDGenerateUUID(o.orgID, jPort.Get("network_id").MustString())]
+		if !ok {
+			log.Infof("exclude vinterface: %s, missing network info", mac)
+			continue
+		}
+
+		deviceLcuuid := common.IDGenerateUUID(o.orgID, jPort.Get("device_id").MustString())
+		deviceOwner := jPort.Get("device_owner").MustString()
+		var deviceType int
+		if strings.HasPrefix(deviceOwner, DEVICE_OWNER_VM_PRE) {
+			if _, ok := o.toolDataSet.lcuuidToVM[deviceLcuuid]; !ok {
+				log.Infof("exclude vinterface: %s, missing vm info", mac)
+				continue
+			}
+			deviceType = common.VIF_DEVICE_TYPE_VM
+		} else if strings.HasPrefix(deviceOwner, DEVICE_OWNER_ROUTER_PRE) || deviceOwner == DEVICE_OWNER_HA_ROUTER {
+			if _, ok := o.toolDataSet.routerLcuuidToVPCLcuuid[deviceLcuuid]; !ok {
+				log.Infof("exclude vinterface: %s, missing vrouter info", mac)
+				continue
+			}
+			deviceType = common.VIF_DEVICE_TYPE_VROUTER
+		} else if deviceOwner == DEVICE_OWNER_DHCP {
+			deviceType = common.VIF_DEVICE_TYPE_DHCP_PORT
+			deviceLcuuid = id
+			name := network.Name + "_DHCP"
+			if len(name) > 256 {
+				name = name[:256]
+			}
+			dhcpPorts = append(dhcpPorts, model.DHCPPort{
+				Lcuuid:       id,
+				Name:         name,
+				VPCLcuuid:    network.VPCLcuuid,
+				AZLcuuid:     network.AZLcuuid,
+				RegionLcuuid: region.lcuuid,
+			})
+		} else {
+			log.Infof("exclude vinterface: %s, %s", mac, deviceOwner)
+			continue
+		}
+
+		vifType := common.VIF_TYPE_LAN
+		if network.External {
+			vifType = common.VIF_TYPE_WAN
+		}
+		vif := model.VInterface{
+			Lcuuid:        id,
+			Name:          jPort.Get("name").MustString(),
+			Type:          vifType,
+			Mac:           mac,
+			DeviceLcuuid:  deviceLcuuid,
+			DeviceType:    deviceType,
+			NetworkLcuuid: network.Lcuuid,
+			VPCLcuuid:     network.VPCLcuuid,
+			RegionLcuuid:  region.lcuuid,
+		}
+		vifs = append(vifs, vif)
+		o.toolDataSet.lcuuidToVInterface[id] = vif
+
+		jIPs := jPort.Get("fixed_ips")
+		for j := range jIPs.MustArray() {
+			jIP := jIPs.GetIndex(j)
+			if !cloudcommon.CheckJsonAttributes(jIP, []string{"ip_address", "subnet_id"}) {
+				continue
+			}
+			ipAddr := jIP.Get("ip_address").MustString()
+			ips = append(ips, model.IP{
+				Lcuuid:           common.GenerateUUIDByOrgID(o.orgID, id+ipAddr),
+				VInterfaceLcuuid: id,
+				IP:               ipAddr,
+				SubnetLcuuid:     common.IDGenerateUUID(o.orgID, jIP.Get("subnet_id").MustString()),
+				RegionLcuuid:     region.lcuuid,
+			})
+		}
+	}
+	return dhcpPorts, vifs, ips, nil
+}
+
+func (o *OpenStack) getFloatingIPs(region RegionInfo, token *Token) ([]model.FloatingIP, error) {
+	var fIPs []model.FloatingIP
+	jFIPs, err := o.getRawData(fmt.Sprintf("%s/floatingips", region.networkURL), token.token, "floatingips")
+	if err != nil {
+		return nil, err
+	}
+	requiredAttrs := []string{"id", "floating_ip_address", "floating_network_id", "port_id"}
+	for i := range jFIPs {
+		jFIP := jFIPs[i]
+		ip := jFIP.Get("floating_ip_address").MustString()
+		if !cloudcommon.CheckJsonAttributes(jFIP, requiredAttrs) {
+			log.Infof("exclude floating_ip: %s, missing attr", ip)
+			continue
+		}
+		vif, ok := o.toolDataSet.lcuuidToVInterface[common.IDGenerateUUID(o.orgID, jFIP.Get("port_id").MustString())]
+		if !ok || vif.DeviceType != common.VIF_DEVICE_TYPE_VM {
+			log.Infof("exclude floating_ip: %s, not bound to vm", ip)
+			continue
+		}
+		networkLcuuid := common.IDGenerateUUID(o.orgID, jFIP.Get("floating_network_id").MustString())
+		if _, ok := o.toolDataSet.lcuuidToNetwork[networkLcuuid]; !ok {
+			log.Infof("exclude floating_ip: %s, missing network info", ip)
+			continue
+		}
+		fIPs = append(fIPs, model.FloatingIP{
+			Lcuuid:        common.IDGenerateUUID(o.orgID, jFIP.Get("id").MustString()),
+			IP:            ip,
+			VMLcuuid:      vif.DeviceLcuuid,
+			NetworkLcuuid: networkLcuuid,
+			VPCLcuuid:     vif.VPCLcuuid,
+			RegionLcuuid:  region.lcuuid,
+		})
+	}
+	return fIPs, nil
+}
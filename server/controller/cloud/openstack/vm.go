@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+var STATE_CONVERTION = map[string]int{
+	"ACTIVE":  common.VM_STATE_RUNNING,
+	"SHUTOFF": common.VM_STATE_STOPPED,
+	"ERROR":   common.VM_STATE_EXCEPTION,
+}
+
+func (o *OpenStack) getVMs(region RegionInfo, token *Token) ([]model.VM, error) {
+	var vms []model.VM
+	jVMs, err := o.getRawData(fmt.Sprintf("%s/servers/detail?all_tenants=1", region.computeURL), token.token, "servers")
+	if err != nil {
+		return nil, err
+	}
+
+	requiredAttrs := []string{"id", "name", "status", "tenant_id"}
+	for i := range jVMs {
+		jVM := jVMs[i]
+		name := jVM.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jVM, requiredAttrs) {
+			log.Infof("exclude vm: %s, missing attr", name)
+			continue
+		}
+		id := common.IDGenerateUUID(o.orgID, jVM.Get("id").MustString())
+		azLcuuid := o.toolDataSet.keyToAZLcuuid[AZKey{region.name, jVM.Get("OS-EXT-AZ:availability_zone").MustString()}]
+		if azLcuuid == "" {
+			log.Infof("exclude vm: %s, missing az info", name)
+			continue
+		}
+		state, ok := STATE_CONVERTION[jVM.Get("status").MustString()]
+		if !ok {
+			state = common.VM_STATE_EXCEPTION
+		}
+		hostName := jVM.Get("OS-EXT-SRV-ATTR:host").MustString()
+		vm := model.VM{
+			Lcuuid:       id,
+			Name:         name,
+			Label:        jVM.Get("id").MustString(),
+			Hostname:     jVM.Get("OS-EXT-SRV-ATTR:hostname").MustString(),
+			HType:        common.VM_HTYPE_VM_C,
+			State:        state,
+			LaunchServer: o.toolDataSet.hostNameToIP[hostName],
+			AZLcuuid:     azLcuuid,
+			RegionLcuuid: region.lcuuid,
+			VPCLcuuid:    o.getVPCLcuuid(region, jVM.Get("tenant_id").MustString()),
+			CloudTags:    o.formatVMCloudTags(jVM.Get("metadata")),
+		}
+		if created := jVM.Get("created").MustString(); created != "" {
+			createdAt, err := time.Parse(time.RFC3339, created)
+			if err != nil {
+				log.Errorf("parse created failed: %s", created)
+			} else {
+				vm.CreatedAt = createdAt
+			}
+		}
+		vms = append(vms, vm)
+		o.toolDataSet.lcuuidToVM[id] = vm
+		o.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		o.toolDataSet.regionLcuuidToResourceNum[region.lcuuid]++
+	}
+	return vms, nil
+}
+
+// nova metadata of server is used as cloud tags
+func (o *OpenStack) formatVMCloudTags(metadata *simplejson.Json) map[string]string {
+	resp := make(map[string]string)
+	for key := range metadata.MustMap() {
+		resp[key] = metadata.Get(key).MustString()
+	}
+	return resp
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/deepflowio/deepflow/server/controller/cloud/aliyun"
 	"github.com/deepflowio/deepflow/server/controller/cloud/aws"
+	"github.com/deepflowio/deepflow/server/controller/cloud/azure"
 	"github.com/deepflowio/deepflow/server/controller/cloud/baidubce"
 	"github.com/deepflowio/deepflow/server/controller/cloud/config"
 	"github.com/deepflowio/deepflow/server/controller/cloud/filereader"
@@ -31,8 +32,10 @@ import (
 	"github.com/deepflowio/deepflow/server/controller/cloud/huawei"
 	"github.com/deepflowio/deepflow/server/controller/cloud/kubernetes"
 	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/cloud/openstack"
 	"github.com/deepflowio/deepflow/server/controller/cloud/qingcloud"
 	"github.com/deepflowio/deepflow/server/controller/cloud/tencent"
+	"github.com/deepflowio/deepflow/server/controller/cloud/zstack"
 	"github.com/deepflowio/deepflow/server/controller/common"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 )
@@ -66,6 +69,12 @@ func NewPlatform(domain mysql.Domain, cfg config.CloudConfig, db *mysql.DB) (Pla
 		platform, err = kubernetes.NewKubernetes(db.ORGID, domain)
 	case common.HUAWEI:
 		platform, err = huawei.NewHuaWei(db.ORGID, domain, cfg)
+	case common.OPENSTACK:
+		platform, err = openstack.NewOpenStack(db.ORGID, domain, cfg)
+	case common.AZURE:
+		platform, err = azure.NewAzure(db.ORGID, domain, cfg)
+	case common.ZSTACK:
+		platform, err = zstack.NewZStack(db.ORGID, domain, cfg)
 	case common.FILEREADER:
 		platform, err = filereader.NewFileReader(db.ORGID, domain)
 	// TODO: other platform
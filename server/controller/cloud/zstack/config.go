@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zstack
+
+import (
+	"strings"
+
+	"github.com/bitly/go-simplejson"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+const DEFAULT_ACCOUNT_NAME = "admin"
+
+type Config struct {
+	RegionLcuuid string
+	URL          string // management node url, such as http://zstack:8080
+	AccountName  string
+	Password     string
+}
+
+func (c *Config) LoadFromString(sConf string) (err error) {
+	jConf, err := simplejson.NewJson([]byte(sConf))
+	if err != nil {
+		log.Errorf("convert config string: %s to json failed: %v", sConf, err)
+		return
+	}
+	c.URL, err = jConf.Get("url").String()
+	if err != nil {
+		log.Error("url must be specified")
+		return
+	}
+	c.AccountName = jConf.Get("account_name").MustString(DEFAULT_ACCOUNT_NAME)
+	pswd, err := jConf.Get("password").String()
+	if err != nil {
+		log.Error("password must be specified")
+		return
+	}
+	dpswd, err := common.DecryptSecretKey(pswd)
+	if err != nil {
+		log.Error("decrypt password failed")
+		return
+	}
+	c.Password = dpswd
+	c.RegionLcuuid = jConf.Get("region_uuid").MustString()
+	c.tidy()
+	return
+}
+
+func (c *Config) tidy() {
+	c.URL = strings.TrimSuffix(strings.TrimSuffix(c.URL, "/"), "/zstack")
+	if c.AccountName == "" {
+		c.AccountName = DEFAULT_ACCOUNT_NAME
+	}
+}
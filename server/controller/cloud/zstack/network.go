@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zstack
+
+import (
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+const (
+	L3_CATEGORY_PUBLIC = "Public"
+	L3_CATEGORY_SYSTEM = "System"
+)
+
+func (z *ZStack) getNetworks(regionLcuuid, basicVPCLcuuid, session string) ([]model.Network, []model.Subnet, error) {
+	var networks []model.Network
+	var subnets []model.Subnet
+	jL3s, err := z.getRawData("l3_networks", "l3-networks", session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	requiredAttrs := []string{"uuid", "name"}
+	for i := range jL3s {
+		jL3 := jL3s[i]
+		name := jL3.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jL3, requiredAttrs) {
+			log.Infof("exclude network: %s, missing attr", name)
+			continue
+		}
+		// system networks, such as the management network, are used by zstack itself
+		category := jL3.Get("category").MustString()
+		if category == L3_CATEGORY_SYSTEM || jL3.Get("system").MustBool() {
+			log.Infof("exclude network: %s, system network", name)
+			continue
+		}
+
+		l3UUID := jL3.Get("uuid").MustString()
+		// flat networks and public networks belong to the basic vpc
+		vpcLcuuid, ok := z.toolDataSet.l3UUIDToVPCLcuuid[l3UUID]
+		if !ok {
+			vpcLcuuid = basicVPCLcuuid
+			z.toolDataSet.l3UUIDToVPCLcuuid[l3UUID] = vpcLcuuid
+		}
+		netType := common.NETWORK_TYPE_LAN
+		if category == L3_CATEGORY_PUBLIC {
+			netType = common.NETWORK_TYPE_WAN
+		}
+		networkLcuuid := common.IDGenerateUUID(z.orgID, l3UUID)
+		network := model.Network{
+			Lcuuid:       networkLcuuid,
+			Name:         name,
+			Label:        l3UUID,
+			External:     category == L3_CATEGORY_PUBLIC,
+			NetType:      netType,
+			VPCLcuuid:    vpcLcuuid,
+			AZLcuuid:     z.toolDataSet.zoneUUIDToAZLcuuid[jL3.Get("zoneUuid").MustString()],
+			RegionLcuuid: regionLcuuid,
+		}
+		networks = append(networks, network)
+		z.toolDataSet.l3UUIDToNetwork[l3UUID] = network
+		z.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+
+		// an l3 network may have several ip ranges of the same cidr
+		cidrs := map[string]bool{}
+		jRanges := jL3.Get("ipRanges")
+		for j := range jRanges.MustArray() {
+			jRange := jRanges.GetIndex(j)
+			cidr := jRange.Get("networkCidr").MustString()
+			if cidr == "" || cidrs[cidr] {
+				continue
+			}
+			cidrs[cidr] = true
+			subnet := model.Subnet{
+				Lcuuid:        common.IDGenerateUUID(z.orgID, jRange.Get("uuid").MustString()),
+				Name:          jRange.Get("name").MustString(name),
+				Label:         jRange.Get("uuid").MustString(),
+				CIDR:          cidr,
+				GatewayIP:     jRange.Get("gateway").MustString(),
+				NetworkLcuuid: networkLcuuid,
+				VPCLcuuid:     vpcLcuuid,
+			}
+			subnets = append(subnets, subnet)
+			z.toolDataSet.l3UUIDToSubnets[l3UUID] = append(z.toolDataSet.l3UUIDToSubnets[l3UUID], subnet)
+		}
+	}
+	return networks, subnets, nil
+}
+
+// getSubnetLcuuid returns the lcuuid of the subnet which contains the ip
+func (z *ZStack) getSubnetLcuuid(l3UUID, ip string) string {
+	subnets := z.toolDataSet.l3UUIDToSubnets[l3UUID]
+	for _, subnet := range subnets {
+		if cloudcommon.IsIPInCIDR(ip, subnet.CIDR) {
+			return subnet.Lcuuid
+		}
+	}
+	if len(subnets) > 0 {
+		return subnets[0].Lcuuid
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zstack
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+)
+
+// login gets a session by account name and the sha512 of password
+// ref: https://www.zstack.io/help/dev_manual/dev_guide/
+func (z *ZStack) login() (string, error) {
+	hash := sha512.Sum512([]byte(z.config.Password))
+	body, _ := json.Marshal(map[string]interface{}{
+		"logInByAccount": map[string]string{
+			"accountName": z.config.AccountName,
+			"password":    hex.EncodeToString(hash[:]),
+		},
+	})
+	resp, err := z.request(http.MethodPut, z.config.URL+"/zstack/v1/accounts/login", "", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	session := resp.Get("inventory").Get("uuid").MustString()
+	if session == "" {
+		return "", errors.New("zstack login response has no session uuid")
+	}
+	return session, nil
+}
+
+func (z *ZStack) logout(session string) {
+	_, err := z.request(http.MethodDelete, fmt.Sprintf("%s/zstack/v1/accounts/sessions/%s", z.config.URL, session), session, nil)
+	if err != nil {
+		log.Warningf("logout zstack session failed: %s", err)
+	}
+}
+
+func (z *ZStack) request(method, url, session string, body io.Reader) (*simplejson.Json, error) {
+	log.Debugf("url: %s", url)
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if session != "" {
+		req.Header.Set("Authorization", "OAuth "+session)
+	}
+	client := cloudcommon.GetUnverifyHTTPClient(time.Second * time.Duration(z.httpTimeout))
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Errorf("request url: %s, failed: %s", url, err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Errorf("request url: %s, read failed: %s", url, err.Error())
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("request url: %s, failed: (%d) %s", url, resp.StatusCode, string(respBody))
+		log.Error(err.Error())
+		return nil, err
+	}
+	if len(respBody) == 0 {
+		return simplejson.New(), nil
+	}
+	jsonResp, err := simplejson.NewJson(respBody)
+	if err != nil {
+		log.Errorf("request url: %s, JSONiz failed: %s", url, err.Error())
+		return nil, err
+	}
+	return jsonResp, nil
+}
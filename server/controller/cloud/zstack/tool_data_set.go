@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zstack
+
+import (
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+)
+
+type ToolDataSet struct {
+	zoneUUIDToAZLcuuid  map[string]string
+	hostUUIDToIP        map[string]string
+	l3UUIDToVPCLcuuid   map[string]string
+	l3UUIDToNetwork     map[string]model.Network
+	l3UUIDToSubnets     map[string][]model.Subnet
+	vmNicUUIDToVMLcuuid map[string]string
+	lcuuidToVM          map[string]model.VM
+
+	regionLcuuidToResourceNum map[string]int
+	azLcuuidToResourceNum     map[string]int
+}
+
+func NewToolDataSet() *ToolDataSet {
+	return &ToolDataSet{
+		zoneUUIDToAZLcuuid:        make(map[string]string),
+		hostUUIDToIP:              make(map[string]string),
+		l3UUIDToVPCLcuuid:         make(map[string]string),
+		l3UUIDToNetwork:           make(map[string]model.Network),
+		l3UUIDToSubnets:           make(map[string][]model.Subnet),
+		vmNicUUIDToVMLcuuid:       make(map[string]string),
+		lcuuidToVM:                make(map[string]model.VM),
+		regionLcuuidToResourceNum: make(map[string]int),
+		azLcuuidToResourceNum:     make(map[string]int),
+	}
+}
+
+type NICInfo struct {
+	uuid         string
+	l3UUID       string
+	mac          string
+	ips          []string
+	deviceLcuuid string
+	deviceType   int
+}
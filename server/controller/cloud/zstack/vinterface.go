@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zstack
+
+import (
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (z *ZStack) getVInterfacesAndIPs(regionLcuuid string, nics []NICInfo) ([]model.VInterface, []model.IP) {
+	var vinterfaces []model.VInterface
+	var ips []model.IP
+	for _, nic := range nics {
+		network, ok := z.toolDataSet.l3UUIDToNetwork[nic.l3UUID]
+		if !ok {
+			log.Infof("exclude vinterface: %s, network not found", nic.uuid)
+			continue
+		}
+		vifType := common.VIF_TYPE_LAN
+		if network.NetType == common.NETWORK_TYPE_WAN {
+			vifType = common.VIF_TYPE_WAN
+		}
+		vinterfaceLcuuid := common.IDGenerateUUID(z.orgID, nic.uuid)
+		vinterfaces = append(vinterfaces, model.VInterface{
+			Lcuuid:        vinterfaceLcuuid,
+			Type:          vifType,
+			Mac:           nic.mac,
+			DeviceLcuuid:  nic.deviceLcuuid,
+			DeviceType:    nic.deviceType,
+			NetworkLcuuid: network.Lcuuid,
+			VPCLcuuid:     network.VPCLcuuid,
+			RegionLcuuid:  regionLcuuid,
+		})
+		for _, ip := range nic.ips {
+			ips = append(ips, model.IP{
+				Lcuuid:           common.GenerateUUIDByOrgID(z.orgID, vinterfaceLcuuid+ip),
+				VInterfaceLcuuid: vinterfaceLcuuid,
+				IP:               ip,
+				SubnetLcuuid:     z.getSubnetLcuuid(nic.l3UUID, ip),
+				RegionLcuuid:     regionLcuuid,
+			})
+		}
+	}
+	return vinterfaces, ips
+}
+
+// getFloatingIPs learns eips attached to vms, the network of eip is the l3 network of its vip
+func (z *ZStack) getFloatingIPs(regionLcuuid, session string) ([]model.FloatingIP, error) {
+	var floatingIPs []model.FloatingIP
+	jVIPs, err := z.getRawData("vips", "vips", session)
+	if err != nil {
+		return nil, err
+	}
+	vipUUIDToL3UUID := make(map[string]string)
+	for i := range jVIPs {
+		vipUUIDToL3UUID[jVIPs[i].Get("uuid").MustString()] = jVIPs[i].Get("l3NetworkUuid").MustString()
+	}
+
+	jEIPs, err := z.getRawData("eips", "eips", session)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jEIPs {
+		jEIP := jEIPs[i]
+		name := jEIP.Get("name").MustString()
+		ip := jEIP.Get("vipIp").MustString()
+		vmLcuuid, ok := z.toolDataSet.vmNicUUIDToVMLcuuid[jEIP.Get("vmNicUuid").MustString()]
+		if ip == "" || !ok {
+			log.Infof("exclude floating ip: %s, not attached to vm", name)
+			continue
+		}
+		vm := z.toolDataSet.lcuuidToVM[vmLcuuid]
+		networkLcuuid := common.NETWORK_ISP_LCUUID
+		if network, ok := z.toolDataSet.l3UUIDToNetwork[vipUUIDToL3UUID[jEIP.Get("vipUuid").MustString()]]; ok {
+			networkLcuuid = network.Lcuuid
+		}
+		floatingIPs = append(floatingIPs, model.FloatingIP{
+			Lcuuid:        common.IDGenerateUUID(z.orgID, jEIP.Get("uuid").MustString()),
+			IP:            ip,
+			VMLcuuid:      vmLcuuid,
+			NetworkLcuuid: networkLcuuid,
+			VPCLcuuid:     vm.VPCLcuuid,
+			RegionLcuuid:  regionLcuuid,
+		})
+	}
+	return floatingIPs, nil
+}
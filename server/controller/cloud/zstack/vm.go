@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zstack
+
+import (
+	"strings"
+	"time"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+// createDate of zstack inventories is formatted by gson, such as 'Jan 2, 2024 3:04:05 PM'
+const CREATE_DATE_LAYOUT = "Jan 2, 2006 3:04:05 PM"
+
+var STATE_CONVERTION = map[string]int{
+	"Running": common.VM_STATE_RUNNING,
+	"Stopped": common.VM_STATE_STOPPED,
+	"Paused":  common.VM_STATE_STOPPED,
+}
+
+// vms in the recycle bin are not learned
+var EXCLUDED_STATES = []string{"Destroyed", "Expunging"}
+
+func (z *ZStack) getVMs(regionLcuuid, basicVPCLcuuid, session string) ([]model.VM, []NICInfo, error) {
+	var vms []model.VM
+	var nics []NICInfo
+	vmUUIDToTags, err := z.getVMTags(session)
+	if err != nil {
+		return nil, nil, err
+	}
+	jVMs, err := z.getRawData("vm_instances", "vm-instances?q=type=UserVm", session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	requiredAttrs := []string{"uuid", "name", "state", "zoneUuid"}
+	for i := range jVMs {
+		jVM := jVMs[i]
+		name := jVM.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jVM, requiredAttrs) {
+			log.Infof("exclude vm: %s, missing attr", name)
+			continue
+		}
+		state := jVM.Get("state").MustString()
+		if common.Contains(EXCLUDED_STATES, state) {
+			log.Infof("exclude vm: %s, state is %s", name, state)
+			continue
+		}
+		azLcuuid, ok := z.toolDataSet.zoneUUIDToAZLcuuid[jVM.Get("zoneUuid").MustString()]
+		if !ok {
+			log.Infof("exclude vm: %s, missing az info", name)
+			continue
+		}
+
+		vmUUID := jVM.Get("uuid").MustString()
+		vmLcuuid := common.IDGenerateUUID(z.orgID, vmUUID)
+		vpcLcuuid := basicVPCLcuuid
+		jNICs := jVM.Get("vmNics")
+		for j := range jNICs.MustArray() {
+			nic := z.formatNIC(jNICs.GetIndex(j), vmLcuuid, common.VIF_DEVICE_TYPE_VM)
+			if _, ok := z.toolDataSet.l3UUIDToNetwork[nic.l3UUID]; !ok {
+				log.Infof("exclude vinterface: %s, network not found", nic.uuid)
+				continue
+			}
+			// the vpc of a vm is decided by its first nic in vpc networks
+			if l3VPCLcuuid := z.toolDataSet.l3UUIDToVPCLcuuid[nic.l3UUID]; vpcLcuuid == basicVPCLcuuid && l3VPCLcuuid != "" {
+				vpcLcuuid = l3VPCLcuuid
+			}
+			nics = append(nics, nic)
+			z.toolDataSet.vmNicUUIDToVMLcuuid[nic.uuid] = vmLcuuid
+		}
+
+		vmState, ok := STATE_CONVERTION[state]
+		if !ok {
+			vmState = common.VM_STATE_EXCEPTION
+		}
+		// stopped vms have no hostUuid
+		hostUUID := jVM.Get("hostUuid").MustString(jVM.Get("lastHostUuid").MustString())
+		vm := model.VM{
+			Lcuuid:       vmLcuuid,
+			Name:         name,
+			Label:        vmUUID,
+			HType:        common.VM_HTYPE_VM_C,
+			State:        vmState,
+			LaunchServer: z.toolDataSet.hostUUIDToIP[hostUUID],
+			VPCLcuuid:    vpcLcuuid,
+			AZLcuuid:     azLcuuid,
+			RegionLcuuid: regionLcuuid,
+			// leave cloud tags empty when vm has no tag, so that tags in domain additional resources can be appended
+			CloudTags: vmUUIDToTags[vmUUID],
+		}
+		if createDate := jVM.Get("createDate").MustString(); createDate != "" {
+			createdAt, err := time.Parse(CREATE_DATE_LAYOUT, createDate)
+			if err != nil {
+				log.Errorf("parse createDate failed: %s", createDate)
+			} else {
+				vm.CreatedAt = createdAt
+			}
+		}
+		vms = append(vms, vm)
+		z.toolDataSet.lcuuidToVM[vmLcuuid] = vm
+		z.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		z.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+	}
+	return vms, nics, nil
+}
+
+// getVMTags returns user tags of vms, the tag is formatted as 'key::value', 'key=value' or 'key'
+func (z *ZStack) getVMTags(session string) (map[string]map[string]string, error) {
+	vmUUIDToTags := make(map[string]map[string]string)
+	jTags, err := z.getRawData("user_tags", "user-tags?q=resourceType=VmInstanceVO", session)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jTags {
+		resourceUUID := jTags[i].Get("resourceUuid").MustString()
+		tag := jTags[i].Get("tag").MustString()
+		if resourceUUID == "" || tag == "" {
+			continue
+		}
+		key, value := tag, ""
+		if index := strings.Index(tag, "::"); index > 0 {
+			key, value = tag[:index], tag[index+2:]
+		} else if index := strings.Index(tag, "="); index > 0 {
+			key, value = tag[:index], tag[index+1:]
+		}
+		if _, ok := vmUUIDToTags[resourceUUID]; !ok {
+			vmUUIDToTags[resourceUUID] = make(map[string]string)
+		}
+		vmUUIDToTags[resourceUUID][key] = value
+	}
+	return vmUUIDToTags, nil
+}
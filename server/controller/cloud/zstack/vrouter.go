@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zstack
+
+import (
+	"github.com/bitly/go-simplejson"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+// getVRouters learns vpc routers, every vpc router and the private l3 networks attached to it make up a vpc
+func (z *ZStack) getVRouters(regionLcuuid, session string) ([]model.VPC, []model.VRouter, []NICInfo, error) {
+	var vpcs []model.VPC
+	var vrouters []model.VRouter
+	var nics []NICInfo
+	jRouters, err := z.getRawData("vpc_routers", "vpc/virtual-routers", session)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	requiredAttrs := []string{"uuid", "name"}
+	for i := range jRouters {
+		jRouter := jRouters[i]
+		name := jRouter.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jRouter, requiredAttrs) {
+			log.Infof("exclude vrouter: %s, missing attr", name)
+			continue
+		}
+		routerUUID := jRouter.Get("uuid").MustString()
+		vpcLcuuid := common.GenerateUUIDByOrgID(z.orgID, routerUUID+"_vpc_"+z.lcuuidGenerate)
+		vpcs = append(vpcs, model.VPC{
+			Lcuuid:       vpcLcuuid,
+			Name:         name,
+			Label:        routerUUID,
+			RegionLcuuid: regionLcuuid,
+		})
+		vrouterLcuuid := common.IDGenerateUUID(z.orgID, routerUUID)
+		vrouters = append(vrouters, model.VRouter{
+			Lcuuid:         vrouterLcuuid,
+			Name:           name,
+			Label:          routerUUID,
+			GWLaunchServer: z.toolDataSet.hostUUIDToIP[jRouter.Get("hostUuid").MustString()],
+			VPCLcuuid:      vpcLcuuid,
+			RegionLcuuid:   regionLcuuid,
+		})
+		z.toolDataSet.regionLcuuidToResourceNum[regionLcuuid] += 2
+
+		publicL3UUID := jRouter.Get("publicNetworkUuid").MustString()
+		managementL3UUID := jRouter.Get("managementNetworkUuid").MustString()
+		jNICs := jRouter.Get("vmNics")
+		for j := range jNICs.MustArray() {
+			jNIC := jNICs.GetIndex(j)
+			l3UUID := jNIC.Get("l3NetworkUuid").MustString()
+			// the management nic is only used by the zstack management node
+			if l3UUID == managementL3UUID && l3UUID != publicL3UUID {
+				continue
+			}
+			if l3UUID != publicL3UUID {
+				z.toolDataSet.l3UUIDToVPCLcuuid[l3UUID] = vpcLcuuid
+			}
+			nics = append(nics, z.formatNIC(jNIC, vrouterLcuuid, common.VIF_DEVICE_TYPE_VROUTER))
+		}
+	}
+	return vpcs, vrouters, nics, nil
+}
+
+// formatNIC parses the vm nic inventory of vm instances and vpc routers
+func (z *ZStack) formatNIC(jNIC *simplejson.Json, deviceLcuuid string, deviceType int) NICInfo {
+	nic := NICInfo{
+		uuid:         jNIC.Get("uuid").MustString(),
+		l3UUID:       jNIC.Get("l3NetworkUuid").MustString(),
+		mac:          jNIC.Get("mac").MustString(),
+		deviceLcuuid: deviceLcuuid,
+		deviceType:   deviceType,
+	}
+	// usedIps contains both ipv4 and ipv6 addresses of the nic
+	jUsedIPs := jNIC.Get("usedIps")
+	for i := range jUsedIPs.MustArray() {
+		if ip := jUsedIPs.GetIndex(i).Get("ip").MustString(); ip != "" {
+			nic.ips = append(nic.ips, ip)
+		}
+	}
+	if ip := jNIC.Get("ip").MustString(); len(nic.ips) == 0 && ip != "" {
+		nic.ips = append(nic.ips, ip)
+	}
+	return nic
+}
@@ -0,0 +1,278 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zstack
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/op/go-logging"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+)
+
+var log = logging.MustGetLogger("cloud.zstack")
+
+const DEFAULT_PAGE_SIZE = 1000
+
+// ZStack learns resources from the zstack rest api, zstack has no region, all zones belong to one region.
+// every vpc router is regarded as a vpc, and flat or public l3 networks belong to the basic vpc of the region
+type ZStack struct {
+	orgID          int
+	teamID         int
+	lcuuid         string
+	lcuuidGenerate string
+	name           string
+	httpTimeout    int
+	pageSize       int
+	config         *Config
+	toolDataSet    *ToolDataSet       // 处理资源数据时，构建的需要提供给其他资源使用的工具数据
+	cloudStatsd    statsd.CloudStatsd // 性能监控
+	debugger       *cloudcommon.Debugger
+}
+
+func NewZStack(orgID int, domain mysql.Domain, globalCloudCfg config.CloudConfig) (*ZStack, error) {
+	conf := &Config{}
+	err := conf.LoadFromString(domain.Config)
+	if err != nil {
+		return nil, err
+	}
+	return newZStack(orgID, domain, globalCloudCfg, conf), nil
+}
+
+func newZStack(orgID int, domain mysql.Domain, globalCloudCfg config.CloudConfig, conf *Config) *ZStack {
+	conf.tidy()
+	return &ZStack{
+		orgID:  orgID,
+		teamID: domain.TeamID,
+		lcuuid: domain.Lcuuid,
+		// TODO: display_name后期需要修改为uuid_generate
+		lcuuidGenerate: domain.DisplayName,
+		name:           domain.Name,
+		httpTimeout:    globalCloudCfg.HTTPTimeout,
+		pageSize:       DEFAULT_PAGE_SIZE,
+		config:         conf,
+		debugger:       cloudcommon.NewDebugger(domain.Name),
+	}
+}
+
+func (z *ZStack) ClearDebugLog() {
+	z.debugger.Clear()
+}
+
+func (z *ZStack) CheckAuth() error {
+	session, err := z.login()
+	if err != nil {
+		return err
+	}
+	z.logout(session)
+	return nil
+}
+
+func (z *ZStack) GetCloudData() (model.Resource, error) {
+	z.cloudStatsd = statsd.NewCloudStatsd()
+	z.toolDataSet = NewToolDataSet()
+	var resource model.Resource
+	session, err := z.login()
+	if err != nil {
+		return resource, err
+	}
+	defer z.logout(session)
+
+	regions, regionLcuuid := z.getRegions()
+
+	azs, err := z.getAZs(regionLcuuid, session)
+	if err != nil {
+		return resource, err
+	}
+
+	hosts, err := z.getHosts(regionLcuuid, session)
+	if err != nil {
+		return resource, err
+	}
+	resource.Hosts = hosts
+
+	basicVPCs, basicNetworks := cloudcommon.GetBasicVPCAndNetworks(z.orgID, regions, z.config.RegionLcuuid, z.name, z.lcuuidGenerate)
+	z.toolDataSet.regionLcuuidToResourceNum[regionLcuuid] += len(basicVPCs) + len(basicNetworks)
+
+	vpcs, vrouters, vrouterNICs, err := z.getVRouters(regionLcuuid, session)
+	if err != nil {
+		return resource, err
+	}
+	resource.VPCs = append(basicVPCs, vpcs...)
+	resource.VRouters = vrouters
+
+	networks, subnets, err := z.getNetworks(regionLcuuid, basicVPCs[0].Lcuuid, session)
+	if err != nil {
+		return resource, err
+	}
+	resource.Networks = append(basicNetworks, networks...)
+	resource.Subnets = subnets
+
+	vms, vmNICs, err := z.getVMs(regionLcuuid, basicVPCs[0].Lcuuid, session)
+	if err != nil {
+		return resource, err
+	}
+	resource.VMs = vms
+
+	resource.VInterfaces, resource.IPs = z.getVInterfacesAndIPs(regionLcuuid, append(vrouterNICs, vmNICs...))
+
+	floatingIPs, err := z.getFloatingIPs(regionLcuuid, session)
+	if err != nil {
+		return resource, err
+	}
+	resource.FloatingIPs = floatingIPs
+
+	log.Debugf("region resource num info: %v", z.toolDataSet.regionLcuuidToResourceNum)
+	log.Debugf("az resource num info: %v", z.toolDataSet.azLcuuidToResourceNum)
+	resource.Regions = cloudcommon.EliminateEmptyRegions(regions, z.toolDataSet.regionLcuuidToResourceNum)
+	resource.AZs = cloudcommon.EliminateEmptyAZs(azs, z.toolDataSet.azLcuuidToResourceNum)
+
+	z.cloudStatsd.ResCount = statsd.GetResCount(resource)
+	statsd.MetaStatsd.RegisterStatsdTable(z)
+
+	z.debugger.Refresh()
+	return resource, nil
+}
+
+func (z *ZStack) GetStatter() statsd.StatsdStatter {
+	globalTags := map[string]string{
+		"domain_name": z.name,
+		"domain":      z.lcuuid,
+		"platform":    common.ZSTACK_EN,
+	}
+
+	return statsd.StatsdStatter{
+		OrgID:      z.orgID,
+		TeamID:     z.teamID,
+		GlobalTags: globalTags,
+		Element:    statsd.GetCloudStatsd(z.cloudStatsd),
+	}
+}
+
+// getRawData queries all inventories of the resource page by page, path may contain query conditions,
+// such as vm-instances?q=type=UserVm
+func (z *ZStack) getRawData(resourceName, path, session string) (jsonList []*simplejson.Json, err error) {
+	statsdAPIStartTime := time.Now()
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	url := fmt.Sprintf("%s/zstack/v1/%s", z.config.URL, path)
+	for start := 0; ; start += z.pageSize {
+		resp, err := z.request(http.MethodGet, fmt.Sprintf("%s%slimit=%d&start=%d", url, sep, z.pageSize, start), session, nil)
+		if err != nil {
+			return []*simplejson.Json{}, err
+		}
+		jData := resp.Get("inventories")
+		for i := range jData.MustArray() {
+			jsonList = append(jsonList, jData.GetIndex(i))
+		}
+		if len(jData.MustArray()) < z.pageSize {
+			break
+		}
+	}
+	z.cloudStatsd.RefreshAPIMoniter(resourceName, len(jsonList), statsdAPIStartTime)
+
+	z.debugger.WriteJson(resourceName, url, jsonList)
+	return
+}
+
+func (z *ZStack) getRegions() ([]model.Region, string) {
+	if z.config.RegionLcuuid != "" {
+		return []model.Region{}, z.config.RegionLcuuid
+	}
+	region := model.Region{
+		Lcuuid: common.GenerateUUIDByOrgID(z.orgID, "zstack_region_"+z.lcuuidGenerate),
+		Name:   z.name,
+	}
+	return []model.Region{region}, region.Lcuuid
+}
+
+func (z *ZStack) getAZs(regionLcuuid, session string) ([]model.AZ, error) {
+	var azs []model.AZ
+	jZones, err := z.getRawData("zones", "zones", session)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jZones {
+		jZone := jZones[i]
+		if !cloudcommon.CheckJsonAttributes(jZone, []string{"uuid", "name"}) {
+			log.Infof("exclude az: %s, missing attr", jZone.Get("name").MustString())
+			continue
+		}
+		zoneUUID := jZone.Get("uuid").MustString()
+		lcuuid := common.IDGenerateUUID(z.orgID, zoneUUID)
+		azs = append(azs, model.AZ{
+			Lcuuid:       lcuuid,
+			Label:        zoneUUID,
+			Name:         jZone.Get("name").MustString(),
+			RegionLcuuid: regionLcuuid,
+		})
+		z.toolDataSet.zoneUUIDToAZLcuuid[zoneUUID] = lcuuid
+	}
+	return azs, nil
+}
+
+func (z *ZStack) getHosts(regionLcuuid, session string) ([]model.Host, error) {
+	var hosts []model.Host
+	jHosts, err := z.getRawData("hosts", "hosts", session)
+	if err != nil {
+		return nil, err
+	}
+
+	requiredAttrs := []string{"uuid", "name", "managementIp", "zoneUuid"}
+	for i := range jHosts {
+		jHost := jHosts[i]
+		name := jHost.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jHost, requiredAttrs) {
+			log.Infof("exclude host: %s, missing attr", name)
+			continue
+		}
+		azLcuuid, ok := z.toolDataSet.zoneUUIDToAZLcuuid[jHost.Get("zoneUuid").MustString()]
+		if !ok {
+			log.Infof("exclude host: %s, missing az info", name)
+			continue
+		}
+		hostUUID := jHost.Get("uuid").MustString()
+		ip := jHost.Get("managementIp").MustString()
+		hosts = append(hosts, model.Host{
+			Lcuuid:       common.IDGenerateUUID(z.orgID, hostUUID),
+			IP:           ip,
+			Name:         name,
+			HType:        common.HOST_HTYPE_KVM,
+			VCPUNum:      jHost.Get("cpuNum").MustInt(),
+			MemTotal:     int(jHost.Get("totalMemoryCapacity").MustInt64() / 1024 / 1024),
+			Type:         common.HOST_TYPE_VM,
+			AZLcuuid:     azLcuuid,
+			RegionLcuuid: regionLcuuid,
+		})
+		z.toolDataSet.hostUUIDToIP[hostUUID] = ip
+		z.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		z.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+	}
+	return hosts, nil
+}
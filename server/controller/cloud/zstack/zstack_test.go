@@ -0,0 +1,197 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zstack
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	mysqlcommon "github.com/deepflowio/deepflow/server/controller/db/mysql/common"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+	statsdcfg "github.com/deepflowio/deepflow/server/controller/statsd/config"
+)
+
+const (
+	testPassword = "password"
+	testSession  = "b1c2d3e4f5a60718293a4b5c6d7e8f90"
+)
+
+var testRoutes = map[string]string{
+	"zones":               "zones.json",
+	"hosts":               "hosts.json",
+	"vpc/virtual-routers": "vpc_routers.json",
+	"l3-networks":         "l3_networks.json",
+	"vm-instances":        "vm_instances.json",
+	"user-tags":           "user_tags.json",
+	"vips":                "vips.json",
+	"eips":                "eips.json",
+}
+
+// newZStackStub serves the recorded inventories in testdata page by page
+func newZStackStub() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/zstack/v1/")
+		if path == "accounts/login" && r.Method == http.MethodPut {
+			var body struct {
+				LogInByAccount struct {
+					AccountName string `json:"accountName"`
+					Password    string `json:"password"`
+				} `json:"logInByAccount"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			hash := sha512.Sum512([]byte(testPassword))
+			if body.LogInByAccount.Password != hex.EncodeToString(hash[:]) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			data, _ := os.ReadFile("testdata/login.json")
+			w.Write(data)
+			return
+		}
+		if r.Header.Get("Authorization") != "OAuth "+testSession {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if path == "accounts/sessions/"+testSession && r.Method == http.MethodDelete {
+			return
+		}
+		data, err := os.ReadFile("testdata/" + testRoutes[path])
+		if testRoutes[path] == "" || err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var resp struct {
+			Inventories []interface{} `json:"inventories"`
+		}
+		json.Unmarshal(data, &resp)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		if start > len(resp.Inventories) {
+			start = len(resp.Inventories)
+		}
+		end := start + limit
+		if limit == 0 || end > len(resp.Inventories) {
+			end = len(resp.Inventories)
+		}
+		resp.Inventories = resp.Inventories[start:end]
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func newTestZStack(url, password string) *ZStack {
+	domain := mysql.Domain{
+		Name:        "test_zstack",
+		DisplayName: "test_zstack",
+	}
+	conf := &Config{
+		URL:      url + "/zstack/",
+		Password: password,
+	}
+	zstack := newZStack(mysqlcommon.DEFAULT_ORG_ID, domain, config.CloudConfig{HTTPTimeout: 5}, conf)
+	zstack.pageSize = 2
+	return zstack
+}
+
+func TestZStack(t *testing.T) {
+	config.SetCloudGlobalConfig(config.CloudConfig{HTTPTimeout: 5})
+	statsd.NewStatsdMonitor(statsdcfg.StatsdConfig{})
+	server := newZStackStub()
+	defer server.Close()
+
+	Convey("TestZStack_CheckAuth", t, func() {
+		So(newTestZStack(server.URL, testPassword).CheckAuth(), ShouldBeNil)
+		So(newTestZStack(server.URL, "wrong").CheckAuth(), ShouldNotBeNil)
+	})
+
+	Convey("TestZStack_GetCloudData", t, func() {
+		zstack := newTestZStack(server.URL, testPassword)
+		data, err := zstack.GetCloudData()
+		So(err, ShouldBeNil)
+
+		Convey("zones and hosts should be learned", func() {
+			So(len(data.Regions), ShouldEqual, 1)
+			So(len(data.AZs), ShouldEqual, 1)
+			So(data.AZs[0].Name, ShouldEqual, "ZONE-1")
+			So(len(data.Hosts), ShouldEqual, 2)
+			So(data.Hosts[0].MemTotal, ShouldEqual, 65536)
+		})
+
+		Convey("vpc routers should be vpcs", func() {
+			// the basic vpc and the vpc of vpc router
+			So(len(data.VPCs), ShouldEqual, 2)
+			So(len(data.VRouters), ShouldEqual, 1)
+			So(data.VRouters[0].GWLaunchServer, ShouldEqual, "10.10.0.11")
+			So(data.VRouters[0].VPCLcuuid, ShouldEqual, data.VPCs[1].Lcuuid)
+		})
+
+		Convey("system networks should be excluded", func() {
+			// the basic network and 3 l3 networks
+			So(len(data.Networks), ShouldEqual, 4)
+			So(len(data.Subnets), ShouldEqual, 3)
+			for _, network := range data.Networks {
+				switch network.Name {
+				case "public":
+					So(network.NetType, ShouldEqual, common.NETWORK_TYPE_WAN)
+					So(network.VPCLcuuid, ShouldEqual, data.VPCs[0].Lcuuid)
+				case "vpc-net":
+					So(network.VPCLcuuid, ShouldEqual, data.VPCs[1].Lcuuid)
+				case "flat-net":
+					So(network.VPCLcuuid, ShouldEqual, data.VPCs[0].Lcuuid)
+				}
+			}
+		})
+
+		Convey("paginated vms should be learned", func() {
+			So(len(data.VMs), ShouldEqual, 2)
+			for _, vm := range data.VMs {
+				if vm.Name == "vm-web" {
+					So(vm.State, ShouldEqual, common.VM_STATE_RUNNING)
+					So(vm.LaunchServer, ShouldEqual, "10.10.0.11")
+					So(vm.VPCLcuuid, ShouldEqual, data.VPCs[1].Lcuuid)
+					So(vm.CloudTags, ShouldResemble, map[string]string{"env": "prod", "team": "web"})
+					So(vm.CreatedAt.IsZero(), ShouldBeFalse)
+				} else {
+					So(vm.State, ShouldEqual, common.VM_STATE_STOPPED)
+					So(vm.LaunchServer, ShouldEqual, "10.10.0.12")
+					So(vm.VPCLcuuid, ShouldEqual, data.VPCs[0].Lcuuid)
+					So(vm.CloudTags, ShouldBeEmpty)
+				}
+			}
+		})
+
+		Convey("vinterfaces and eips should be learned", func() {
+			// 2 vrouter vinterfaces without the management one, and 2 vm vinterfaces
+			So(len(data.VInterfaces), ShouldEqual, 4)
+			So(len(data.IPs), ShouldEqual, 4)
+			So(len(data.FloatingIPs), ShouldEqual, 1)
+			So(data.FloatingIPs[0].IP, ShouldEqual, "172.20.0.100")
+			So(data.FloatingIPs[0].VMLcuuid, ShouldEqual, "00000000000000000000000000000051")
+		})
+	})
+}
@@ -414,6 +414,8 @@ const (
 	VIF_DEVICE_TYPE_POD_GROUP_DAEMON_SET            = 133
 	VIF_DEVICE_TYPE_POD_GROUP_REPLICASET_CONTROLLER = 134
 	VIF_DEVICE_TYPE_POD_GROUP_CLONESET              = 135
+	VIF_DEVICE_TYPE_POD_GROUP_JOB                   = 136
+	VIF_DEVICE_TYPE_POD_GROUP_CRONJOB               = 137
 	VIF_DEVICE_TYPE_IP                              = 255
 )
 
@@ -532,6 +534,8 @@ const (
 	POD_GROUP_DAEMON_SET            = 4
 	POD_GROUP_REPLICASET_CONTROLLER = 5
 	POD_GROUP_CLONESET              = 6
+	POD_GROUP_JOB                   = 7
+	POD_GROUP_CRONJOB               = 8
 )
 
 const (
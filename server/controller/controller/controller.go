@@ -38,6 +38,7 @@ import (
 	_ "github.com/deepflowio/deepflow/server/controller/grpc/synchronizer"
 	"github.com/deepflowio/deepflow/server/controller/http"
 	"github.com/deepflowio/deepflow/server/controller/http/router"
+	"github.com/deepflowio/deepflow/server/controller/http/service"
 	"github.com/deepflowio/deepflow/server/controller/manager"
 	"github.com/deepflowio/deepflow/server/controller/monitor"
 	"github.com/deepflowio/deepflow/server/controller/prometheus"
@@ -144,6 +145,15 @@ func Start(ctx context.Context, configPath, serverLogFile string, shared *server
 		os.Exit(0)
 	}
 
+	// subscribe before manager for the same reason as tagrecorder
+	router.SetInitStageForHealthChecker("Resource change feed init")
+	err = recorderResource.ChangeFeed.Subscribe()
+	if err != nil {
+		log.Errorf("resource change feed subscribe failed: %s", err.Error())
+		time.Sleep(time.Second)
+		os.Exit(0)
+	}
+
 	router.SetInitStageForHealthChecker("Manager init")
 	// 启动resource manager
 	// 每个云平台启动一个cloud和recorder
@@ -172,6 +182,9 @@ func Start(ctx context.Context, configPath, serverLogFile string, shared *server
 	analyzerCheck := monitor.NewAnalyzerCheck(cfg, ctx)
 	go checkAndStartMasterFunctions(cfg, ctx, controllerCheck, analyzerCheck)
 
+	router.SetInitStageForHealthChecker("Agent command job init")
+	service.AbortOrphanedAgentCMDJobs()
+
 	router.SetInitStageForHealthChecker("Register routers init")
 	httpServer.SetControllerChecker(controllerCheck)
 	httpServer.SetAnalyzerChecker(analyzerCheck)
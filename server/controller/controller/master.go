@@ -84,6 +84,7 @@ func checkAndStartMasterFunctions(
 	// - license分配和检查
 	// - resource id manager
 	// - clean deleted/dirty resource data
+	// - deliver resource change feed
 	// - prometheus encoder
 	// - prometheus app label layout updater
 	// - http resource refresh task manager
@@ -156,6 +157,9 @@ func checkAndStartMasterFunctions(
 				// 资源数据清理
 				recorderResource.Cleaners.Start(sCtx)
 
+				// 资源变更事件投递
+				recorderResource.ChangeFeed.Start(sCtx)
+
 				// domain检查及自愈
 				domainChecker.Start(sCtx)
 
@@ -179,6 +183,7 @@ func checkAndStartMasterFunctions(
 				// stop prometheus related
 				// stop http task mananger
 				// stop resource cleaner
+				// stop resource change feed
 				if sCancel != nil {
 					sCancel()
 				}
@@ -50,6 +50,20 @@ CREATE TABLE IF NOT EXISTS domain_additional_resource (
 ) ENGINE=innodb DEFAULT CHARSET=utf8 AUTO_INCREMENT=1;
 TRUNCATE TABLE domain_additional_resource;
 
+CREATE TABLE IF NOT EXISTS resource_change_event (
+    id                  BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    resource_type       VARCHAR(64) DEFAULT '',
+    event               MEDIUMTEXT COMMENT 'json encoded change event',
+    created_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX created_at_index(created_at)
+)ENGINE=innodb AUTO_INCREMENT=1 DEFAULT CHARSET=utf8 COMMENT='outbox of resource change feed';
+
+CREATE TABLE IF NOT EXISTS resource_change_feed_cursor (
+    sink                VARCHAR(256) NOT NULL PRIMARY KEY COMMENT 'name of the sink',
+    event_id            BIGINT NOT NULL DEFAULT 0 COMMENT 'id of the last delivered resource_change_event',
+    updated_at          DATETIME NOT NULL ON UPDATE CURRENT_TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)ENGINE=innodb DEFAULT CHARSET=utf8;
+
 CREATE TABLE IF NOT EXISTS process (
     id                  INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
     name                TEXT,
@@ -1072,6 +1086,37 @@ CREATE TABLE IF NOT EXISTS vtap_group (
 ) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8;
 TRUNCATE TABLE vtap_group;
 
+CREATE TABLE IF NOT EXISTS agent_cmd_job (
+    id                      INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id                 INTEGER,
+    user_type               INTEGER,
+    agent_group_lcuuid      CHAR(64) DEFAULT '',
+    agent_ids               TEXT COMMENT 'separated by ,',
+    command_id              INTEGER DEFAULT 0,
+    params                  TEXT COMMENT 'json encoded command parameters',
+    linux_ns_pid            INTEGER DEFAULT 0,
+    concurrency             INTEGER DEFAULT 0,
+    timeout                 INTEGER DEFAULT 0 COMMENT 'unit: s',
+    state                   INTEGER DEFAULT 0 COMMENT '0: running 1: finished 2: aborted',
+    node_ip                 CHAR(64) DEFAULT '' COMMENT 'controller running the job',
+    created_at              DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at             DATETIME DEFAULT NULL,
+    INDEX created_at_index(created_at)
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8 COMMENT='audit of remote commands run on agents';
+
+CREATE TABLE IF NOT EXISTS agent_cmd_result (
+    id                      INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    job_id                  INTEGER NOT NULL,
+    agent_id                INTEGER NOT NULL,
+    agent_name              VARCHAR(256) DEFAULT '',
+    state                   INTEGER DEFAULT 0 COMMENT '0: pending 1: success 2: failed',
+    content                 MEDIUMTEXT,
+    errmsg                  TEXT,
+    started_at              DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at             DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX job_id_index(job_id)
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8;
+
 CREATE TABLE IF NOT EXISTS topo_position (
     id                      INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
     type                    INTEGER DEFAULT 1 COMMENT '3-link topo',
@@ -1814,6 +1859,14 @@ CREATE TABLE IF NOT EXISTS controller (
 )ENGINE=innodb AUTO_INCREMENT=1 DEFAULT CHARSET=utf8;
 TRUNCATE TABLE controller;
 
+CREATE TABLE IF NOT EXISTS election_lease (
+    name                VARCHAR(128) NOT NULL PRIMARY KEY,
+    holder_identity     VARCHAR(256) DEFAULT '' COMMENT 'node_name/node_ip/pod_name/pod_ip of leader',
+    acquire_time        DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    renew_time          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    lease_duration      INTEGER DEFAULT 15 COMMENT 'unit: s'
+)ENGINE=innodb DEFAULT CHARSET=utf8;
+
 CREATE TABLE IF NOT EXISTS analyzer (
     id                      INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
     state                   INTEGER COMMENT '0.Temp 1.Creating 2.Complete 3.Modifying 4.Exception',
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS election_lease (
+    name                VARCHAR(128) NOT NULL PRIMARY KEY,
+    holder_identity     VARCHAR(256) DEFAULT '' COMMENT 'node_name/node_ip/pod_name/pod_ip of leader',
+    acquire_time        DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    renew_time          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    lease_duration      INTEGER DEFAULT 15 COMMENT 'unit: s'
+)ENGINE=innodb DEFAULT CHARSET=utf8;
+
+-- whether default db or not, update db_version to latest, remember update DB_VERSION_EXPECT in migrate/init.go
+UPDATE db_version SET version='6.5.1.41';
+-- modify end
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS resource_change_event (
+    id                  BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    resource_type       VARCHAR(64) DEFAULT '',
+    event               MEDIUMTEXT COMMENT 'json encoded change event',
+    created_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX created_at_index(created_at)
+)ENGINE=innodb AUTO_INCREMENT=1 DEFAULT CHARSET=utf8 COMMENT='outbox of resource change feed';
+
+CREATE TABLE IF NOT EXISTS resource_change_feed_cursor (
+    sink                VARCHAR(256) NOT NULL PRIMARY KEY COMMENT 'name of the sink',
+    event_id            BIGINT NOT NULL DEFAULT 0 COMMENT 'id of the last delivered resource_change_event',
+    updated_at          DATETIME NOT NULL ON UPDATE CURRENT_TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)ENGINE=innodb DEFAULT CHARSET=utf8;
+
+-- whether default db or not, update db_version to latest, remember update DB_VERSION_EXPECT in migrate/init.go
+UPDATE db_version SET version='6.5.1.42';
+-- modify end
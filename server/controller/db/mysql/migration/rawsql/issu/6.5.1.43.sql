@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS agent_cmd_job (
+    id                      INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id                 INTEGER,
+    user_type               INTEGER,
+    agent_group_lcuuid      CHAR(64) DEFAULT '',
+    agent_ids               TEXT COMMENT 'separated by ,',
+    command_id              INTEGER DEFAULT 0,
+    params                  TEXT COMMENT 'json encoded command parameters',
+    linux_ns_pid            INTEGER DEFAULT 0,
+    concurrency             INTEGER DEFAULT 0,
+    timeout                 INTEGER DEFAULT 0 COMMENT 'unit: s',
+    state                   INTEGER DEFAULT 0 COMMENT '0: running 1: finished 2: aborted',
+    node_ip                 CHAR(64) DEFAULT '' COMMENT 'controller running the job',
+    created_at              DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at             DATETIME DEFAULT NULL,
+    INDEX created_at_index(created_at)
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8 COMMENT='audit of remote commands run on agents';
+
+CREATE TABLE IF NOT EXISTS agent_cmd_result (
+    id                      INTEGER NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    job_id                  INTEGER NOT NULL,
+    agent_id                INTEGER NOT NULL,
+    agent_name              VARCHAR(256) DEFAULT '',
+    state                   INTEGER DEFAULT 0 COMMENT '0: pending 1: success 2: failed',
+    content                 MEDIUMTEXT,
+    errmsg                  TEXT,
+    started_at              DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at             DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX job_id_index(job_id)
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8;
+
+-- whether default db or not, update db_version to latest, remember update DB_VERSION_EXPECT in migrate/init.go
+UPDATE db_version SET version='6.5.1.43';
+-- modify end
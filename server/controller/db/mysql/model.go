@@ -111,6 +111,15 @@ type Controller struct {
 	Lcuuid             string    `gorm:"column:lcuuid;type:char(64);not null" json:"LCUUID"`
 }
 
+// ElectionLease is the lease row of the mysql election backend, the holder is the leader until it stops renewing
+type ElectionLease struct {
+	Name           string    `gorm:"primaryKey;column:name;type:varchar(128);not null" json:"NAME"`
+	HolderIdentity string    `gorm:"column:holder_identity;type:varchar(256);default:''" json:"HOLDER_IDENTITY"`
+	AcquireTime    time.Time `gorm:"column:acquire_time;type:datetime;not null;default:CURRENT_TIMESTAMP" json:"ACQUIRE_TIME"`
+	RenewTime      time.Time `gorm:"column:renew_time;type:datetime;not null;default:CURRENT_TIMESTAMP" json:"RENEW_TIME"`
+	LeaseDuration  int       `gorm:"column:lease_duration;type:int;default:15" json:"LEASE_DURATION"` // unit: s
+}
+
 type AZControllerConnection struct {
 	ID           int    `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
 	AZ           string `gorm:"column:az;type:char(64);default:ALL" json:"AZ"`
@@ -228,6 +237,43 @@ func (VTapGroup) TableName() string {
 	return "vtap_group"
 }
 
+type AgentCMDJob struct {
+	ID               int        `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
+	UserID           int        `gorm:"column:user_id;type:int;default:null" json:"USER_ID"`
+	UserType         int        `gorm:"column:user_type;type:int;default:null" json:"USER_TYPE"`
+	AgentGroupLcuuid string     `gorm:"column:agent_group_lcuuid;type:char(64);default:''" json:"AGENT_GROUP_LCUUID"`
+	AgentIDs         string     `gorm:"column:agent_ids;type:text;default:null" json:"AGENT_IDS"` // separated by ,
+	CommandID        int        `gorm:"column:command_id;type:int;default:0" json:"COMMAND_ID"`
+	Params           string     `gorm:"column:params;type:text;default:null" json:"PARAMS"` // json encoded
+	LinuxNsPid       int        `gorm:"column:linux_ns_pid;type:int;default:0" json:"LINUX_NS_PID"`
+	Concurrency      int        `gorm:"column:concurrency;type:int;default:0" json:"CONCURRENCY"`
+	Timeout          int        `gorm:"column:timeout;type:int;default:0" json:"TIMEOUT"` // unit: s
+	State            int        `gorm:"column:state;type:int;default:0" json:"STATE"`     // 0: running 1: finished 2: aborted
+	NodeIP           string     `gorm:"column:node_ip;type:char(64);default:''" json:"NODE_IP"`
+	CreatedAt        time.Time  `gorm:"column:created_at;type:datetime;not null;default:CURRENT_TIMESTAMP" json:"CREATED_AT"`
+	FinishedAt       *time.Time `gorm:"column:finished_at;type:datetime;default:null" json:"FINISHED_AT"`
+}
+
+func (AgentCMDJob) TableName() string {
+	return "agent_cmd_job"
+}
+
+type AgentCMDResult struct {
+	ID         int       `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
+	JobID      int       `gorm:"column:job_id;type:int;not null" json:"JOB_ID"`
+	AgentID    int       `gorm:"column:agent_id;type:int;not null" json:"AGENT_ID"`
+	AgentName  string    `gorm:"column:agent_name;type:varchar(256);default:''" json:"AGENT_NAME"`
+	State      int       `gorm:"column:state;type:int;default:0" json:"STATE"` // 0: pending 1: success 2: failed
+	Content    string    `gorm:"column:content;type:mediumtext;default:null" json:"CONTENT"`
+	ErrMsg     string    `gorm:"column:errmsg;type:text;default:null" json:"ERRMSG"`
+	StartedAt  time.Time `gorm:"column:started_at;type:datetime;not null;default:CURRENT_TIMESTAMP" json:"STARTED_AT"`
+	FinishedAt time.Time `gorm:"column:finished_at;type:datetime;not null;default:CURRENT_TIMESTAMP" json:"FINISHED_AT"`
+}
+
+func (AgentCMDResult) TableName() string {
+	return "agent_cmd_result"
+}
+
 type DataSource struct {
 	ID                        int       `gorm:"primaryKey;column:id;type:int;not null" json:"ID"`
 	DisplayName               string    `gorm:"column:display_name;type:char(64);default:''" json:"DISPLAY_NAME"`
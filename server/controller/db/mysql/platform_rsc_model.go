@@ -39,6 +39,21 @@ type DomainAdditionalResource struct {
 	CreatedAt         time.Time       `gorm:"autoCreateTime;column:created_at;type:datetime" json:"CREATED_AT"`
 }
 
+// ResourceChangeEvent is the outbox of the resource change feed, events are delivered to sinks in id order
+type ResourceChangeEvent struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement;unique;column:id;type:bigint;not null" json:"ID"`
+	ResourceType string    `gorm:"column:resource_type;type:varchar(64);default:''" json:"RESOURCE_TYPE"`
+	Event        string    `gorm:"column:event;type:mediumtext" json:"EVENT"`
+	CreatedAt    time.Time `gorm:"autoCreateTime;column:created_at;type:datetime" json:"CREATED_AT"`
+}
+
+// ResourceChangeFeedCursor records the id of the last event delivered to a sink
+type ResourceChangeFeedCursor struct {
+	Sink      string    `gorm:"primaryKey;column:sink;type:varchar(256);not null" json:"SINK"`
+	EventID   int64     `gorm:"column:event_id;type:bigint;not null;default:0" json:"EVENT_ID"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime;column:updated_at;type:datetime" json:"UPDATED_AT"`
+}
+
 type Base struct {
 	ID     int    `gorm:"primaryKey;autoIncrement;unique;column:id;type:int;not null" json:"ID" mapstructure:"ID"`
 	Lcuuid string `gorm:"unique;column:lcuuid;type:char(64)" json:"LCUUID" mapstructure:"LCUUID"`
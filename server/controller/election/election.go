@@ -55,6 +55,9 @@ func GetAcquireTime() int64 {
 
 const (
 	ID_ITEM_NUM = 4
+
+	ELECTION_BACKEND_KUBERNETES = "kubernetes"
+	ELECTION_BACKEND_MYSQL      = "mysql"
 )
 
 type LeaderData struct {
@@ -173,6 +176,15 @@ func checkLeaderValid(ctx context.Context, lock *resourcelock.LeaseLock) {
 }
 
 func Start(ctx context.Context, cfg *config.ControllerConfig) {
+	switch cfg.ElectionBackend {
+	case ELECTION_BACKEND_MYSQL:
+		startMySQLElection(ctx, cfg)
+	default:
+		startKubernetesElection(ctx, cfg)
+	}
+}
+
+func startKubernetesElection(ctx context.Context, cfg *config.ControllerConfig) {
 	kubeconfig := cfg.Kubeconfig
 	electionName := cfg.ElectionName
 	electionNamespace := common.GetNameSpace()
@@ -0,0 +1,382 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package election
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/config"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	mysqlcommon "github.com/deepflowio/deepflow/server/controller/db/mysql/common"
+	mysqlcfg "github.com/deepflowio/deepflow/server/controller/db/mysql/config"
+	"github.com/deepflowio/deepflow/server/controller/trisolaris/utils"
+)
+
+// same as the lease lock of kubernetes election
+const (
+	MYSQL_LEASE_DURATION = 15 * time.Second
+	MYSQL_RENEW_DEADLINE = 10 * time.Second
+	MYSQL_RETRY_PERIOD   = 2 * time.Second
+)
+
+// election runs before mysql migration, so the lease table is created by the election itself
+const CREATE_ELECTION_LEASE_TABLE = `CREATE TABLE IF NOT EXISTS election_lease (
+    name                VARCHAR(128) NOT NULL PRIMARY KEY,
+    holder_identity     VARCHAR(256) DEFAULT '' COMMENT 'node_name/node_ip/pod_name/pod_ip of leader',
+    acquire_time        DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    renew_time          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    lease_duration      INTEGER DEFAULT 15 COMMENT 'unit: s'
+)ENGINE=innodb DEFAULT CHARSET=utf8;`
+
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// mysqlElector elects by a row of election_lease, the holder of the row is the leader until it stops renewing
+// the row for a lease duration, then any other controller can take over the row.
+// every write of the row is conditioned on the holder and renew time read before, so only one controller wins.
+// as the lease lock of kubernetes, the renew time written by others is only used to find out whether the row is
+// renewed, the lease expires a lease duration after the row is observed changed by the local clock, so the clocks
+// of controllers do not need to be synchronized.
+type mysqlElector struct {
+	db            *gorm.DB
+	clock         clock
+	name          string
+	id            string
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+	leaderData    *LeaderData
+
+	leading       bool
+	lastRenewTime time.Time
+	observedLease *mysql.ElectionLease
+	observedTime  time.Time
+}
+
+func newMySQLElector(db *gorm.DB, c clock, name, id string, leaderData *LeaderData) *mysqlElector {
+	return &mysqlElector{
+		db:            db,
+		clock:         c,
+		name:          name,
+		id:            id,
+		leaseDuration: MYSQL_LEASE_DURATION,
+		renewDeadline: MYSQL_RENEW_DEADLINE,
+		retryPeriod:   MYSQL_RETRY_PERIOD,
+		leaderData:    leaderData,
+	}
+}
+
+func (e *mysqlElector) getLease() (*mysql.ElectionLease, error) {
+	var leases []mysql.ElectionLease
+	if err := e.db.Where("name = ?", e.name).Find(&leases).Error; err != nil {
+		return nil, err
+	}
+	if len(leases) == 0 {
+		return nil, nil
+	}
+	return &leases[0], nil
+}
+
+// observe records the local time when the lease is found changed
+func (e *mysqlElector) observe(lease *mysql.ElectionLease, now time.Time) {
+	if lease == nil {
+		return
+	}
+	if e.observedLease == nil || e.observedLease.HolderIdentity != lease.HolderIdentity || !e.observedLease.RenewTime.Equal(lease.RenewTime) {
+		e.observedLease = lease
+		e.observedTime = now
+	}
+}
+
+// isExpired should be called after the lease is observed
+func (e *mysqlElector) isExpired(lease *mysql.ElectionLease, now time.Time) bool {
+	return lease.HolderIdentity == "" || !now.Before(e.observedTime.Add(time.Duration(lease.LeaseDuration)*time.Second))
+}
+
+// observeLease reads the lease and observes it
+func (e *mysqlElector) observeLease(now time.Time) (*mysql.ElectionLease, error) {
+	lease, err := e.getLease()
+	if err != nil {
+		return nil, err
+	}
+	e.observe(lease, now)
+	return lease, nil
+}
+
+// tryAcquireOrRenew acquires the lease if it is expired, or renews the lease if it is held by self,
+// returns the lease read after writing.
+func (e *mysqlElector) tryAcquireOrRenew() (*mysql.ElectionLease, error) {
+	now := e.clock.Now()
+	lease, err := e.observeLease(now)
+	if err != nil {
+		return nil, err
+	}
+	if lease == nil {
+		lease = &mysql.ElectionLease{
+			Name:           e.name,
+			HolderIdentity: e.id,
+			AcquireTime:    now,
+			RenewTime:      now,
+			LeaseDuration:  int(e.leaseDuration / time.Second),
+		}
+		if err := e.db.Clauses(clause.OnConflict{DoNothing: true}).Create(lease).Error; err != nil {
+			return nil, err
+		}
+		return e.observeLease(now)
+	}
+	if lease.HolderIdentity != e.id && !e.isExpired(lease, now) {
+		return lease, nil
+	}
+
+	// renew time is stored in seconds, it must be changed by every write even if the local clock is behind others,
+	// otherwise the renewal could not be observed by others
+	renewTime := now.Truncate(time.Second)
+	if !renewTime.After(lease.RenewTime) {
+		renewTime = lease.RenewTime.Add(time.Second)
+	}
+	updates := map[string]interface{}{
+		"renew_time":     renewTime,
+		"lease_duration": int(e.leaseDuration / time.Second),
+	}
+	if lease.HolderIdentity != e.id {
+		updates["holder_identity"] = e.id
+		updates["acquire_time"] = now
+	}
+	err = e.db.Model(&mysql.ElectionLease{}).
+		Where("name = ? AND holder_identity = ? AND renew_time = ?", e.name, lease.HolderIdentity, lease.RenewTime).
+		Updates(updates).Error
+	if err != nil {
+		return nil, err
+	}
+	// the row is not updated if others have written it first, so the holder is decided by the row read again
+	return e.observeLease(now)
+}
+
+// release gives up the lease held by self, so that others can take over without waiting for expiration
+func (e *mysqlElector) release() {
+	if !e.leading {
+		return
+	}
+	err := e.db.Model(&mysql.ElectionLease{}).
+		Where("name = ? AND holder_identity = ?", e.name, e.id).
+		Update("holder_identity", "").Error
+	if err != nil {
+		log.Errorf("release leader lease failed: %s", err.Error())
+		return
+	}
+	e.leading = false
+	log.Infof("leader lost: %s", e.id)
+}
+
+// refresh tries to acquire or renew the lease once, and updates the leader according to the lease
+func (e *mysqlElector) refresh() {
+	now := e.clock.Now()
+	lease, err := e.tryAcquireOrRenew()
+	if err != nil {
+		log.Errorf("acquire or renew leader lease failed: %s", err.Error())
+		// the lease may be taken over by others if self can not renew it in time
+		if e.leading && now.Sub(e.lastRenewTime) >= e.renewDeadline {
+			e.leading = false
+			log.Infof("leader lost: %s", e.id)
+			e.leaderData.SetLeader("")
+		}
+		return
+	}
+	if lease == nil {
+		return
+	}
+
+	leader := lease.HolderIdentity
+	if e.isExpired(lease, now) {
+		leader = ""
+	}
+	if leader == e.id {
+		e.lastRenewTime = now
+		if !e.leading {
+			e.leading = true
+			log.Infof("%s is the leader", e.id)
+		}
+	} else if e.leading {
+		e.leading = false
+		log.Infof("leader lost: %s", e.id)
+	}
+	if leader != e.leaderData.GetLeader() && leader != "" {
+		log.Infof("new leader elected: %s", leader)
+	}
+	acquireTime = lease.AcquireTime.Unix()
+	e.leaderData.SetLeader(leader)
+	e.leaderData.setValide()
+}
+
+func (e *mysqlElector) run(ctx context.Context) {
+	for {
+		e.refresh()
+		select {
+		case <-ctx.Done():
+			e.release()
+			return
+		case <-e.clock.After(e.retryPeriod):
+		}
+	}
+}
+
+// getElectionDB connects to the configured database and creates the lease table,
+// the database is created if it does not exist, and will be initialized by mysql migration later.
+func getElectionDB(cfg mysqlcfg.MySqlConfig) (*gorm.DB, error) {
+	connector, err := mysqlcommon.GetConnector(cfg, false, cfg.TimeOut, false)
+	if err != nil {
+		return nil, err
+	}
+	db, err := mysqlcommon.InitSession(connector)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", cfg.Database)).Error
+	if sqlDB, _ := db.DB(); sqlDB != nil {
+		sqlDB.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	db, err = mysqlcommon.GetSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Exec(CREATE_ELECTION_LEASE_TABLE).Error; err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// pod ip in the id of mysql election, used to find out whether this controller is the leader when there is no
+// environment variable of pod ip
+var mysqlElectionPodIP atomic.Value
+
+// getLocalIP returns the first global unicast ip of local interfaces, ipv4 is preferred
+func getLocalIP() (string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	var ipv6 string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP.String(), nil
+		}
+		if ipv6 == "" {
+			ipv6 = ipNet.IP.String()
+		}
+	}
+	if ipv6 == "" {
+		return "", errors.New("no global unicast ip found")
+	}
+	return ipv6, nil
+}
+
+// getMySQLElectionID returns node_name/node_ip/pod_name/pod_ip as the id of kubernetes election.
+// controllers deployed on VMs have no such environment variables, the hostname and a local ip are used instead,
+// otherwise all of them have the same id and all of them think they are the leader.
+func getMySQLElectionID(hostname, localIP func() (string, error)) (string, error) {
+	nodeName, nodeIP, podName, podIP := common.GetNodeName(), common.GetNodeIP(), common.GetPodName(), common.GetPodIP()
+	if nodeName == "" || podName == "" {
+		if name, err := hostname(); err != nil {
+			log.Warningf("get hostname failed: %s", err.Error())
+		} else {
+			if nodeName == "" {
+				nodeName = name
+			}
+			if podName == "" {
+				podName = name
+			}
+		}
+	}
+	if nodeIP == "" || podIP == "" {
+		if ip, err := localIP(); err != nil {
+			log.Warningf("get local ip failed: %s", err.Error())
+		} else {
+			if nodeIP == "" {
+				nodeIP = ip
+			}
+			if podIP == "" {
+				podIP = ip
+			}
+		}
+	}
+	id := fmt.Sprintf("%s/%s/%s/%s", nodeName, nodeIP, podName, podIP)
+	if nodeName == "" || nodeIP == "" || podName == "" || podIP == "" {
+		return "", fmt.Errorf("id (%s) has empty items, set environment variables %s, %s, %s and %s",
+			id, common.NODE_NAME_KEY, common.NODE_IP_KEY, common.POD_NAME_KEY, common.POD_IP_KEY)
+	}
+	return id, nil
+}
+
+func startMySQLElection(ctx context.Context, cfg *config.ControllerConfig) {
+	id, err := getMySQLElectionID(os.Hostname, getLocalIP)
+	if err != nil {
+		log.Errorf("mysql election is not started: %s", err.Error())
+		return
+	}
+	mysqlElectionPodIP.Store(strings.Split(id, "/")[3])
+	log.Infof("election id is %s", id)
+
+	var db *gorm.DB
+	for {
+		db, err = getElectionDB(cfg.MySqlCfg)
+		if err == nil {
+			break
+		}
+		log.Errorf("connect to mysql for election failed: %s", err.Error())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+
+	wg := utils.GetWaitGroupInCtx(ctx)
+	wg.Add(1)
+	defer wg.Done()
+	newMySQLElector(db, realClock{}, cfg.ElectionName, id, leaderData).run(ctx)
+}
@@ -0,0 +1,266 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package election
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/trisolaris/utils/atomicbool"
+)
+
+const (
+	testElectionName = "deepflow-server"
+	testIDA          = "node-a/10.0.0.1/server-a/10.1.0.1"
+	testIDB          = "node-b/10.0.0.2/server-b/10.1.0.2"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func (c *fakeClock) step(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestElectionDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(
+		sqlite.Open(filepath.Join(t.TempDir(), "election.db")),
+		&gorm.Config{NamingStrategy: schema.NamingStrategy{SingularTable: true}},
+	)
+	if err != nil {
+		t.Fatalf("create sqlite database failed: %s", err.Error())
+	}
+	if err = db.AutoMigrate(&mysql.ElectionLease{}); err != nil {
+		t.Fatalf("create election lease table failed: %s", err.Error())
+	}
+	return db
+}
+
+func newTestElector(db *gorm.DB, c clock, id string) *mysqlElector {
+	return newMySQLElector(db, c, testElectionName, id, &LeaderData{isValide: atomicbool.NewBool(false)})
+}
+
+func TestMySQLElectorFailover(t *testing.T) {
+	db := newTestElectionDB(t)
+	c := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	electorA := newTestElector(db, c, testIDA)
+	electorB := newTestElector(db, c, testIDB)
+
+	electorA.refresh()
+	electorB.refresh()
+	if !electorA.leading || electorB.leading {
+		t.Fatalf("the first one should be the leader, a: %v, b: %v", electorA.leading, electorB.leading)
+	}
+	if electorA.leaderData.GetLeader() != testIDA || electorB.leaderData.GetLeader() != testIDA {
+		t.Fatalf("both should observe leader %s, a: %s, b: %s", testIDA, electorA.leaderData.GetLeader(), electorB.leaderData.GetLeader())
+	}
+	if !electorB.leaderData.getValide() {
+		t.Fatal("leader data should be valid after refresh")
+	}
+	acquiredAt := c.Now()
+
+	// the leader keeps renewing the lease
+	for i := 0; i < 10; i++ {
+		c.step(MYSQL_RETRY_PERIOD)
+		electorA.refresh()
+		electorB.refresh()
+	}
+	if !electorA.leading || electorB.leaderData.GetLeader() != testIDA {
+		t.Fatalf("leader should not change while renewing, b observes %s", electorB.leaderData.GetLeader())
+	}
+	if acquireTime != acquiredAt.Unix() {
+		t.Fatalf("acquire time should not change while renewing, got %d, expected %d", acquireTime, acquiredAt.Unix())
+	}
+
+	// the leader stops renewing, others take over after the lease expires
+	c.step(MYSQL_LEASE_DURATION - time.Second)
+	electorB.refresh()
+	if electorB.leading {
+		t.Fatal("the lease should not be taken over before expiration")
+	}
+	c.step(time.Second)
+	electorB.refresh()
+	if !electorB.leading || electorB.leaderData.GetLeader() != testIDB {
+		t.Fatalf("the lease should be taken over after expiration, leader is %s", electorB.leaderData.GetLeader())
+	}
+	if acquireTime != c.Now().Unix() {
+		t.Fatalf("acquire time should be updated after failover, got %d, expected %d", acquireTime, c.Now().Unix())
+	}
+
+	// the old leader comes back and follows the new one
+	electorA.refresh()
+	if electorA.leading || electorA.leaderData.GetLeader() != testIDB {
+		t.Fatalf("the old leader should follow the new leader, leader is %s", electorA.leaderData.GetLeader())
+	}
+}
+
+func TestMySQLElectorRelease(t *testing.T) {
+	db := newTestElectionDB(t)
+	c := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	electorA := newTestElector(db, c, testIDA)
+	electorB := newTestElector(db, c, testIDB)
+
+	electorA.refresh()
+	electorA.release()
+	if electorA.leading {
+		t.Fatal("the leader should stop leading after release")
+	}
+
+	// the released lease can be taken over without waiting for expiration
+	c.step(MYSQL_RETRY_PERIOD)
+	electorB.refresh()
+	if !electorB.leading || electorB.leaderData.GetLeader() != testIDB {
+		t.Fatalf("the released lease should be taken over, leader is %s", electorB.leaderData.GetLeader())
+	}
+}
+
+func TestMySQLElectorClockSkew(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, skew := range []time.Duration{-time.Minute, time.Hour} {
+		db := newTestElectionDB(t)
+		clockA, clockB := &fakeClock{now: now.Add(skew)}, &fakeClock{now: now}
+		electorA := newTestElector(db, clockA, testIDA)
+		electorB := newTestElector(db, clockB, testIDB)
+		step := func(d time.Duration) {
+			clockA.step(d)
+			clockB.step(d)
+		}
+
+		// the lease is not taken over while the leader renews it
+		electorA.refresh()
+		electorB.refresh()
+		for i := 0; i < 10; i++ {
+			step(MYSQL_RETRY_PERIOD)
+			electorA.refresh()
+			electorB.refresh()
+		}
+		if !electorA.leading || electorB.leading || electorB.leaderData.GetLeader() != testIDA {
+			t.Fatalf("skew %v: the lease should not be taken over while renewing, b observes %s", skew, electorB.leaderData.GetLeader())
+		}
+
+		// the lease is taken over a lease duration after the leader stops renewing
+		step(MYSQL_LEASE_DURATION - time.Second)
+		electorB.refresh()
+		if electorB.leading {
+			t.Fatalf("skew %v: the lease should not be taken over before expiration", skew)
+		}
+		step(time.Second)
+		electorB.refresh()
+		if !electorB.leading || electorB.leaderData.GetLeader() != testIDB {
+			t.Fatalf("skew %v: the lease should be taken over after expiration, leader is %s", skew, electorB.leaderData.GetLeader())
+		}
+
+		// the new leader keeps the lease, even if its clock is behind the renew time written by the old leader
+		for i := 0; i < 10; i++ {
+			step(MYSQL_RETRY_PERIOD)
+			electorA.refresh()
+			electorB.refresh()
+		}
+		if electorA.leading || !electorB.leading || electorA.leaderData.GetLeader() != testIDB {
+			t.Fatalf("skew %v: a should follow b, a observes %s", skew, electorA.leaderData.GetLeader())
+		}
+	}
+}
+
+func setTestEnv(t *testing.T, nodeName, nodeIP, podName, podIP string) {
+	oldNodeName, oldNodeIP, oldPodName, oldPodIP := common.NodeName, common.NodeIP, common.PodName, common.PodIP
+	t.Cleanup(func() {
+		common.NodeName, common.NodeIP, common.PodName, common.PodIP = oldNodeName, oldNodeIP, oldPodName, oldPodIP
+	})
+	common.NodeName, common.NodeIP, common.PodName, common.PodIP = nodeName, nodeIP, podName, podIP
+}
+
+func getTestElectionID(hostname, ip string) (string, error) {
+	return getMySQLElectionID(
+		func() (string, error) {
+			if hostname == "" {
+				return "", errors.New("no hostname")
+			}
+			return hostname, nil
+		},
+		func() (string, error) {
+			if ip == "" {
+				return "", errors.New("no ip")
+			}
+			return ip, nil
+		},
+	)
+}
+
+func TestMySQLElectionIDWithoutEnv(t *testing.T) {
+	// controllers on VMs have no environment variables of kubernetes
+	setTestEnv(t, "", "", "", "")
+	idA, err := getTestElectionID("vm-a", "192.168.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idB, err := getTestElectionID("vm-b", "192.168.0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idA != "vm-a/192.168.0.1/vm-a/192.168.0.1" || idB != "vm-b/192.168.0.2/vm-b/192.168.0.2" {
+		t.Fatalf("hostname and local ip should be used, got %s and %s", idA, idB)
+	}
+
+	db := newTestElectionDB(t)
+	c := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	electorA := newTestElector(db, c, idA)
+	electorB := newTestElector(db, c, idB)
+	electorA.refresh()
+	electorB.refresh()
+	if !electorA.leading || electorB.leading {
+		t.Fatalf("only one should be the leader, a: %v, b: %v", electorA.leading, electorB.leading)
+	}
+	if electorB.leaderData.GetLeader() != idA {
+		t.Fatalf("leader should be %s, got %s", idA, electorB.leaderData.GetLeader())
+	}
+
+	// environment variables are preferred
+	setTestEnv(t, "node-a", "", "", "10.1.0.1")
+	if id, err := getTestElectionID("vm-a", "192.168.0.1"); err != nil || id != "node-a/192.168.0.1/vm-a/10.1.0.1" {
+		t.Fatalf("environment variables should be used, got %s, err %v", id, err)
+	}
+
+	// ids with empty items are rejected
+	setTestEnv(t, "", "", "", "")
+	for _, item := range [][2]string{{"", "192.168.0.1"}, {"vm-a", ""}, {"", ""}} {
+		if id, err := getTestElectionID(item[0], item[1]); err == nil || !strings.Contains(err.Error(), "empty") {
+			t.Errorf("id with empty items should be rejected, got %s, err %v", id, err)
+		}
+	}
+}
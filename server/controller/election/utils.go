@@ -25,6 +25,15 @@ import (
 	"github.com/deepflowio/deepflow/server/controller/common"
 )
 
+func getSelfPodIP() string {
+	if podIP := os.Getenv(common.POD_IP_KEY); podIP != "" {
+		return podIP
+	}
+	// controllers on VMs use the local ip in the id of mysql election
+	podIP, _ := mysqlElectionPodIP.Load().(string)
+	return podIP
+}
+
 // 功能：判断当前控制器是否为masterController
 func IsMasterController() (bool, error) {
 	// in standalone mode, the local machine is the master node because of all in one deployment
@@ -32,7 +41,7 @@ func IsMasterController() (bool, error) {
 		return true, nil
 	}
 	// get self host_ip
-	hostIP := os.Getenv(common.POD_IP_KEY)
+	hostIP := getSelfPodIP()
 	if len(hostIP) == 0 {
 		log.Error("pod_ip is null")
 		return false, errors.New("pod_ip is null")
@@ -54,7 +63,7 @@ func IsMasterControllerAndReturnIP() (bool, string, error) {
 		return true, common.GetPodIP(), nil
 	}
 	// get self host_ip
-	hostIP := os.Getenv(common.POD_IP_KEY)
+	hostIP := getSelfPodIP()
 	if len(hostIP) == 0 {
 		log.Error("pod_ip is null")
 		return false, "", errors.New("pod_ip is null")
@@ -25,13 +25,16 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/deepflowio/deepflow/message/trident"
 	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/config"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 	httpcommon "github.com/deepflowio/deepflow/server/controller/http/common"
 	. "github.com/deepflowio/deepflow/server/controller/http/router/common"
 	"github.com/deepflowio/deepflow/server/controller/http/service"
+	servicecommon "github.com/deepflowio/deepflow/server/controller/http/service/common"
 	"github.com/deepflowio/deepflow/server/controller/model"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -42,15 +45,21 @@ const (
 	DefaultForwardControllerTimes = 3
 )
 
-type AgentCMD struct{}
+type AgentCMD struct {
+	cfg *config.ControllerConfig
+}
 
-func NewAgentCMD() *AgentCMD {
-	return new(AgentCMD)
+func NewAgentCMD(cfg *config.ControllerConfig) *AgentCMD {
+	return &AgentCMD{cfg: cfg}
 }
 
 func (c *AgentCMD) RegisterTo(e *gin.Engine) {
 	e.GET("/v1/agent/:id/cmd", forwardToServerConnectedByAgent(), getCMDAndNamespaceHandler)
 	e.POST("/v1/agent/:id/cmd/run", forwardToServerConnectedByAgent(), cmdRunHandler)
+
+	e.GET("/v1/agent-cmd-jobs/", c.getCMDJobs())
+	e.GET("/v1/agent-cmd-jobs/:id/", c.getCMDJob())
+	e.POST("/v1/agent-cmd-jobs/", c.createCMDJob())
 }
 
 func forwardToServerConnectedByAgent() gin.HandlerFunc {
@@ -105,7 +114,7 @@ func forwardToServerConnectedByAgent() gin.HandlerFunc {
 		// get reverse proxy host
 		newHost := common.NodeIP
 		if common.NodeIP == agent.CurControllerIP {
-			if _, ok := service.AgentRemoteExecMap[key]; ok {
+			if _, ok := service.GetAgentRemoteExecManager(key); ok {
 				c.Next()
 				return
 			} else {
@@ -113,7 +122,7 @@ func forwardToServerConnectedByAgent() gin.HandlerFunc {
 				c.Request.Header.Set(ForwardControllerTimes, fmt.Sprintf("%d", forwardTimes+1))
 			}
 		} else if common.NodeIP == agent.ControllerIP {
-			if _, ok := service.AgentRemoteExecMap[key]; ok {
+			if _, ok := service.GetAgentRemoteExecManager(key); ok {
 				c.Next()
 				return
 			} else {
@@ -195,7 +204,7 @@ func cmdRunHandler(c *gin.Context) {
 	}
 
 	orgID, _ := c.Get(common.HEADER_KEY_X_ORG_ID)
-	content, err := service.RunAgentCMD(orgID.(int), agentID, &agentReq)
+	content, err := service.RunAgentCMD(orgID.(int), agentID, &agentReq, time.Duration(req.Timeout)*time.Second)
 	if err != nil {
 		BadRequestResponse(c, httpcommon.SERVER_ERROR, err.Error())
 		return
@@ -220,3 +229,48 @@ func sendAsFile(c *gin.Context, fileName string, content *bytes.Buffer) {
 		return
 	}
 }
+
+func (c *AgentCMD) getCMDJobs() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		args := make(map[string]interface{})
+		if value, ok := ctx.GetQuery("user_id"); ok {
+			args["user_id"] = value
+		}
+		if value, ok := ctx.GetQuery("state"); ok {
+			args["state"] = value
+		}
+		data, err := service.NewAgentCMDJob(httpcommon.GetUserInfo(ctx), c.cfg).Get(args)
+		JsonResponse(ctx, data, err)
+	}
+}
+
+func (c *AgentCMD) getCMDJob() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := strconv.Atoi(ctx.Param("id"))
+		if err != nil {
+			BadRequestResponse(ctx, httpcommon.INVALID_PARAMETERS, fmt.Sprintf("job id(%s) can not convert to int", ctx.Param("id")))
+			return
+		}
+		data, err := service.NewAgentCMDJob(httpcommon.GetUserInfo(ctx), c.cfg).Get(map[string]interface{}{"id": id})
+		if err == nil && len(data) == 0 {
+			err = servicecommon.NewError(httpcommon.RESOURCE_NOT_FOUND, fmt.Sprintf("agent command job(id: %d) not found", id))
+		}
+		if err != nil {
+			JsonResponse(ctx, nil, err)
+			return
+		}
+		JsonResponse(ctx, data[0], nil)
+	}
+}
+
+func (c *AgentCMD) createCMDJob() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var jobCreate model.AgentCMDJobCreate
+		if err := ctx.ShouldBindBodyWith(&jobCreate, binding.JSON); err != nil {
+			BadRequestResponse(ctx, httpcommon.INVALID_POST_DATA, err.Error())
+			return
+		}
+		data, err := service.NewAgentCMDJob(httpcommon.GetUserInfo(ctx), c.cfg).Create(jobCreate)
+		JsonResponse(ctx, data, err)
+	}
+}
@@ -115,7 +115,7 @@ func (s *Server) appendRegistrant() []registrant.Registrant {
 		router.NewMail(),
 		router.NewPrometheus(),
 		router.NewDatabase(s.controllerConfig),
-		router.NewAgentCMD(),
+		router.NewAgentCMD(s.controllerConfig),
 		// icon
 		router.NewIcon(s.controllerConfig),
 
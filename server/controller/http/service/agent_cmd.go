@@ -29,16 +29,38 @@ import (
 )
 
 var (
-	AgentRemoteExecMap  = make(map[string]*CMDManager)
+	// agentRemoteExecMap is written by the remote exec streams and read by http requests concurrently
+	agentRemoteExecMu   sync.RWMutex
+	agentRemoteExecMap  = make(map[string]*CMDManager)
 	agentCommandTimeout = time.Minute
 )
 
 func AddSteamToManager(key string, requestID uint64) *CMDManager {
 	m := initCMDManager(requestID)
-	AgentRemoteExecMap[key] = m
+	agentRemoteExecMu.Lock()
+	agentRemoteExecMap[key] = m
+	agentRemoteExecMu.Unlock()
 	return m
 }
 
+// RemoveSteamFromManager removes the manager of the stream, a newer stream of the same agent is kept
+func RemoveSteamFromManager(key string, m *CMDManager) bool {
+	agentRemoteExecMu.Lock()
+	defer agentRemoteExecMu.Unlock()
+	if cur, ok := agentRemoteExecMap[key]; !ok || cur != m {
+		return false
+	}
+	delete(agentRemoteExecMap, key)
+	return true
+}
+
+func GetAgentRemoteExecManager(key string) (*CMDManager, bool) {
+	agentRemoteExecMu.RLock()
+	defer agentRemoteExecMu.RUnlock()
+	m, ok := agentRemoteExecMap[key]
+	return m, ok
+}
+
 func initCMDManager(requestID uint64) *CMDManager {
 	m := &CMDManager{
 		ExecCH:               make(chan *trident.RemoteExecRequest, 1),
@@ -53,7 +75,8 @@ func initCMDManager(requestID uint64) *CMDManager {
 }
 
 type CMDManager struct {
-	mu sync.RWMutex
+	mu    sync.RWMutex
+	runMu sync.Mutex
 
 	ExecCH               chan *trident.RemoteExecRequest
 	ExecDoneCH           chan struct{}
@@ -148,7 +171,7 @@ func GetCMDAndNamespace(orgID, agentID int) (*model.RemoteExecResp, error) {
 		ctrlcommon.NodeIP, agent.CurControllerIP, agent.ControllerIP, agentID, agent.Name)
 
 	key := agent.CtrlIP + "-" + agent.CtrlMac
-	manager, ok := AgentRemoteExecMap[key]
+	manager, ok := GetAgentRemoteExecManager(key)
 	if !ok {
 		return nil, fmt.Errorf("agent(name: %s, key: %s) remote exec map not found", agent.Name, key)
 	}
@@ -180,7 +203,8 @@ func GetCMDAndNamespace(orgID, agentID int) (*model.RemoteExecResp, error) {
 	}
 }
 
-func RunAgentCMD(orgID, agentID int, req *trident.RemoteExecRequest) (string, error) {
+// RunAgentCMD runs the command on the agent connected to this controller, the default timeout is used if timeout is 0
+func RunAgentCMD(orgID, agentID int, req *trident.RemoteExecRequest, timeout time.Duration) (string, error) {
 	dbInfo, err := mysql.GetDB(orgID)
 	if err != nil {
 		return "", err
@@ -189,22 +213,32 @@ func RunAgentCMD(orgID, agentID int, req *trident.RemoteExecRequest) (string, er
 	if err := dbInfo.Where("id = ?", agentID).Find(&agent).Error; err != nil {
 		return "", err
 	}
+	if timeout == 0 {
+		timeout = agentCommandTimeout
+	}
+	return runAgentCMD(agent, req, timeout)
+}
+
+func runAgentCMD(agent *mysql.VTap, req *trident.RemoteExecRequest, timeout time.Duration) (string, error) {
 	b, _ := json.Marshal(req)
 	log.Infof("current node ip(%s) agent(cur controller ip: %s, controller ip: %s, id: %d, name: %s) run remote command, request: %s",
-		ctrlcommon.NodeIP, agent.CurControllerIP, agent.ControllerIP, agentID, agent.Name, string(b))
+		ctrlcommon.NodeIP, agent.CurControllerIP, agent.ControllerIP, agent.ID, agent.Name, string(b))
 	key := agent.CtrlIP + "-" + agent.CtrlMac
-	manager, ok := AgentRemoteExecMap[key]
+	manager, ok := GetAgentRemoteExecManager(key)
 	if !ok {
 		return "", fmt.Errorf("agent(name: %s, key: %s) remote exec map not found", agent.Name, key)
 	}
+	// the response of the agent is shared by all commands, so commands are run one by one
+	manager.runMu.Lock()
+	defer manager.runMu.Unlock()
 	manager.ResetResp()
 	manager.ExecCH <- req
 
 	content := ""
 	for {
 		select {
-		case <-time.After(agentCommandTimeout):
-			return "", fmt.Errorf("timeout(%vs) to run agent command", agentCommandTimeout.Seconds())
+		case <-time.After(timeout):
+			return "", fmt.Errorf("timeout(%vs) to run agent command", timeout.Seconds())
 		case <-manager.ExecDoneCH:
 			content = manager.resp.Content
 			log.Infof("command run content len: %d", len(content))
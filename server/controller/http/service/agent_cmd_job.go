@@ -0,0 +1,467 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	simplejson "github.com/bitly/go-simplejson"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+
+	"github.com/deepflowio/deepflow/message/trident"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/config"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	httpcommon "github.com/deepflowio/deepflow/server/controller/http/common"
+	. "github.com/deepflowio/deepflow/server/controller/http/service/common"
+	"github.com/deepflowio/deepflow/server/controller/model"
+)
+
+const (
+	AGENT_CMD_JOB_STATE_RUNNING = iota
+	AGENT_CMD_JOB_STATE_FINISHED
+	AGENT_CMD_JOB_STATE_ABORTED // the controller running the job restarted
+)
+
+const (
+	AGENT_CMD_RESULT_STATE_PENDING = iota
+	AGENT_CMD_RESULT_STATE_SUCCESS
+	AGENT_CMD_RESULT_STATE_FAILED
+)
+
+const (
+	AGENT_CMD_JOB_DEFAULT_CONCURRENCY = 10
+	// the content of each agent is truncated to avoid huge rows, large outputs should be fetched from a single agent
+	AGENT_CMD_RESULT_MAX_CONTENT_LEN = 1 << 20
+	// extra time waiting for the controller of the agent, so that its own timeout error is returned
+	AGENT_CMD_FORWARD_EXTRA_TIMEOUT = 10 * time.Second
+)
+
+// AgentCMDJob runs a remote command on a batch of agents in parallel, the job and the result of each agent
+// are persisted as the audit trail of who ran what
+type AgentCMDJob struct {
+	cfg *config.ControllerConfig
+
+	resourceAccess *ResourceAccess
+}
+
+func NewAgentCMDJob(userInfo *httpcommon.UserInfo, cfg *config.ControllerConfig) *AgentCMDJob {
+	return &AgentCMDJob{
+		cfg:            cfg,
+		resourceAccess: &ResourceAccess{fpermit: cfg.FPermit, userInfo: userInfo},
+	}
+}
+
+func (a *AgentCMDJob) Get(filter map[string]interface{}) ([]model.AgentCMDJob, error) {
+	userInfo := a.resourceAccess.userInfo
+	dbInfo, err := mysql.GetDB(userInfo.ORGID)
+	if err != nil {
+		return nil, err
+	}
+	db := dbInfo.DB
+	for _, field := range []string{"id", "user_id", "state"} {
+		if v, ok := filter[field]; ok {
+			db = db.Where(fmt.Sprintf("%s = ?", field), v)
+		}
+	}
+	// only the super admin could audit jobs of other users
+	if userInfo.Type != common.DEFAULT_USER_TYPE || userInfo.ID != common.DEFAULT_USER_ID {
+		db = db.Where("user_id = ?", userInfo.ID)
+	}
+	var jobs []mysql.AgentCMDJob
+	if err := db.Order("id DESC").Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return []model.AgentCMDJob{}, nil
+	}
+
+	jobIDs := make([]int, 0, len(jobs))
+	for _, job := range jobs {
+		jobIDs = append(jobIDs, job.ID)
+	}
+	var results []mysql.AgentCMDResult
+	resultDB := dbInfo.Where("job_id IN (?)", jobIDs)
+	if _, ok := filter["id"]; !ok {
+		// the content is only returned when a single job is queried
+		resultDB = resultDB.Select("job_id", "state")
+	}
+	if err := resultDB.Order("agent_id").Find(&results).Error; err != nil {
+		return nil, err
+	}
+	jobIDToResults := make(map[int][]mysql.AgentCMDResult)
+	for _, result := range results {
+		jobIDToResults[result.JobID] = append(jobIDToResults[result.JobID], result)
+	}
+
+	response := make([]model.AgentCMDJob, 0, len(jobs))
+	for _, job := range jobs {
+		jobResp := model.AgentCMDJob{
+			ID:               job.ID,
+			UserID:           job.UserID,
+			AgentGroupLcuuid: job.AgentGroupLcuuid,
+			AgentIDs:         []int{},
+			CommandID:        job.CommandID,
+			Params:           []*trident.Parameter{},
+			LinuxNsPid:       job.LinuxNsPid,
+			Concurrency:      job.Concurrency,
+			Timeout:          job.Timeout,
+			State:            job.State,
+			NodeIP:           job.NodeIP,
+			CreatedAt:        job.CreatedAt.Format(common.GO_BIRTHDAY),
+		}
+		if job.FinishedAt != nil {
+			jobResp.FinishedAt = job.FinishedAt.Format(common.GO_BIRTHDAY)
+		}
+		for _, idStr := range strings.Split(job.AgentIDs, ",") {
+			if id, err := strconv.Atoi(idStr); err == nil {
+				jobResp.AgentIDs = append(jobResp.AgentIDs, id)
+			}
+		}
+		if job.Params != "" {
+			json.Unmarshal([]byte(job.Params), &jobResp.Params)
+		}
+		for _, result := range jobIDToResults[job.ID] {
+			switch result.State {
+			case AGENT_CMD_RESULT_STATE_SUCCESS:
+				jobResp.SuccessCount++
+			case AGENT_CMD_RESULT_STATE_FAILED:
+				jobResp.FailedCount++
+			default:
+				jobResp.PendingCount++
+			}
+			if _, ok := filter["id"]; !ok {
+				continue
+			}
+			jobResp.Results = append(jobResp.Results, model.AgentCMDResult{
+				AgentID:    result.AgentID,
+				AgentName:  result.AgentName,
+				State:      result.State,
+				Content:    result.Content,
+				ErrMsg:     result.ErrMsg,
+				StartedAt:  result.StartedAt.Format(common.GO_BIRTHDAY),
+				FinishedAt: result.FinishedAt.Format(common.GO_BIRTHDAY),
+			})
+		}
+		response = append(response, jobResp)
+	}
+	return response, nil
+}
+
+func (a *AgentCMDJob) Create(jobCreate model.AgentCMDJobCreate) (model.AgentCMDJob, error) {
+	userInfo := a.resourceAccess.userInfo
+	if len(jobCreate.AgentIDs) == 0 && jobCreate.AgentGroupLcuuid == "" {
+		return model.AgentCMDJob{}, NewError(httpcommon.INVALID_PARAMETERS, "must specify AGENT_IDS or AGENT_GROUP_LCUUID")
+	}
+	dbInfo, err := mysql.GetDB(userInfo.ORGID)
+	if err != nil {
+		return model.AgentCMDJob{}, err
+	}
+	agents, err := a.getAgents(dbInfo, jobCreate)
+	if err != nil {
+		return model.AgentCMDJob{}, err
+	}
+
+	concurrency := jobCreate.Concurrency
+	if concurrency == 0 {
+		concurrency = AGENT_CMD_JOB_DEFAULT_CONCURRENCY
+	}
+	timeout := jobCreate.Timeout
+	if timeout == 0 {
+		timeout = int(agentCommandTimeout.Seconds())
+	}
+	agentIDs := make([]string, 0, len(agents))
+	for _, agent := range agents {
+		agentIDs = append(agentIDs, strconv.Itoa(agent.ID))
+	}
+	params, _ := json.Marshal(jobCreate.Params)
+	job := &mysql.AgentCMDJob{
+		UserID:           userInfo.ID,
+		UserType:         userInfo.Type,
+		AgentGroupLcuuid: jobCreate.AgentGroupLcuuid,
+		AgentIDs:         strings.Join(agentIDs, ","),
+		CommandID:        int(jobCreate.CommandID),
+		Params:           string(params),
+		LinuxNsPid:       int(jobCreate.LinuxNsPid),
+		Concurrency:      concurrency,
+		Timeout:          timeout,
+		State:            AGENT_CMD_JOB_STATE_RUNNING,
+		NodeIP:           common.NodeIP,
+	}
+	results, err := createAgentCMDJob(dbInfo, job, agents)
+	if err != nil {
+		return model.AgentCMDJob{}, err
+	}
+	log.Infof("ORG(id=%d) user(type: %d, id: %d) create agent command job(id: %d, command id: %d, params: %s) on agents(%s)",
+		userInfo.ORGID, userInfo.Type, userInfo.ID, job.ID, job.CommandID, job.Params, job.AgentIDs)
+
+	newRequest := func() *trident.RemoteExecRequest {
+		req := &trident.RemoteExecRequest{
+			ExecType:  trident.ExecutionType_RUN_COMMAND.Enum(),
+			CommandId: proto.Uint32(jobCreate.CommandID),
+			Params:    jobCreate.Params,
+		}
+		if jobCreate.LinuxNsPid != 0 {
+			req.LinuxNsPid = proto.Uint32(jobCreate.LinuxNsPid)
+		}
+		return req
+	}
+	exec := func(agent *mysql.VTap) (string, error) {
+		return execAgentCMD(userInfo, agent, newRequest(), time.Duration(job.Timeout)*time.Second)
+	}
+	go runAgentCMDJob(dbInfo, job, agents, results, exec)
+
+	response, err := a.Get(map[string]interface{}{"id": job.ID})
+	if err != nil || len(response) == 0 {
+		return model.AgentCMDJob{}, err
+	}
+	return response[0], nil
+}
+
+func (a *AgentCMDJob) getAgents(dbInfo *mysql.DB, jobCreate model.AgentCMDJobCreate) ([]mysql.VTap, error) {
+	var agents []mysql.VTap
+	if len(jobCreate.AgentIDs) > 0 {
+		if err := dbInfo.Where("id IN (?)", jobCreate.AgentIDs).Find(&agents).Error; err != nil {
+			return nil, err
+		}
+	}
+	if jobCreate.AgentGroupLcuuid != "" {
+		var agentGroup mysql.VTapGroup
+		if err := dbInfo.Where("lcuuid = ?", jobCreate.AgentGroupLcuuid).First(&agentGroup).Error; err != nil {
+			return nil, NewError(httpcommon.RESOURCE_NOT_FOUND, fmt.Sprintf("agent group (%s) not found", jobCreate.AgentGroupLcuuid))
+		}
+		var groupAgents []mysql.VTap
+		if err := dbInfo.Where("vtap_group_lcuuid = ?", jobCreate.AgentGroupLcuuid).Find(&groupAgents).Error; err != nil {
+			return nil, err
+		}
+		agents = append(agents, groupAgents...)
+	}
+	agents, err := getAgentByUser(a.resourceAccess.userInfo, &a.cfg.FPermit, agents)
+	if err != nil {
+		return nil, err
+	}
+
+	idToAgent := make(map[int]mysql.VTap, len(agents))
+	for _, agent := range agents {
+		idToAgent[agent.ID] = agent
+	}
+	var notFoundIDs []string
+	for _, id := range jobCreate.AgentIDs {
+		if _, ok := idToAgent[id]; !ok {
+			notFoundIDs = append(notFoundIDs, strconv.Itoa(id))
+		}
+	}
+	if len(notFoundIDs) > 0 {
+		return nil, NewError(httpcommon.RESOURCE_NOT_FOUND, fmt.Sprintf("agent (%s) not found", strings.Join(notFoundIDs, ",")))
+	}
+	if len(idToAgent) == 0 {
+		return nil, NewError(httpcommon.RESOURCE_NOT_FOUND, fmt.Sprintf("no agent in agent group (%s)", jobCreate.AgentGroupLcuuid))
+	}
+	result := make([]mysql.VTap, 0, len(idToAgent))
+	for _, agent := range idToAgent {
+		result = append(result, agent)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// createAgentCMDJob saves the job with a pending result of each agent, so that the agents not run yet
+// could be found if the controller restarts
+func createAgentCMDJob(dbInfo *mysql.DB, job *mysql.AgentCMDJob, agents []mysql.VTap) ([]*mysql.AgentCMDResult, error) {
+	results := make([]*mysql.AgentCMDResult, 0, len(agents))
+	err := dbInfo.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, agent := range agents {
+			results = append(results, &mysql.AgentCMDResult{
+				JobID:      job.ID,
+				AgentID:    agent.ID,
+				AgentName:  agent.Name,
+				State:      AGENT_CMD_RESULT_STATE_PENDING,
+				StartedAt:  now,
+				FinishedAt: now,
+			})
+		}
+		return tx.Create(&results).Error
+	})
+	return results, err
+}
+
+// runAgentCMDJob runs the command on the agents with limited concurrency, results[i] belongs to agents[i]
+func runAgentCMDJob(dbInfo *mysql.DB, job *mysql.AgentCMDJob, agents []mysql.VTap, results []*mysql.AgentCMDResult,
+	exec func(agent *mysql.VTap) (string, error)) {
+	limiter := make(chan struct{}, job.Concurrency)
+	var wg sync.WaitGroup
+	for i := range agents {
+		agent := &agents[i]
+		result := results[i]
+		limiter <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+			result.StartedAt = time.Now()
+			content, err := exec(agent)
+			result.FinishedAt = time.Now()
+			if err != nil {
+				result.State = AGENT_CMD_RESULT_STATE_FAILED
+				result.ErrMsg = err.Error()
+			} else {
+				result.State = AGENT_CMD_RESULT_STATE_SUCCESS
+				if len(content) > AGENT_CMD_RESULT_MAX_CONTENT_LEN {
+					content = content[:AGENT_CMD_RESULT_MAX_CONTENT_LEN]
+					result.ErrMsg = fmt.Sprintf("content is truncated to %d bytes", AGENT_CMD_RESULT_MAX_CONTENT_LEN)
+				}
+				result.Content = content
+			}
+			if err := dbInfo.Save(result).Error; err != nil {
+				log.Errorf("ORG(id=%d) save result of agent command job(id: %d) on agent(id: %d) failed: %s",
+					dbInfo.ORGID, job.ID, agent.ID, err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := dbInfo.Model(job).Updates(map[string]interface{}{
+		"state": AGENT_CMD_JOB_STATE_FINISHED, "finished_at": time.Now(),
+	}).Error; err != nil {
+		log.Errorf("ORG(id=%d) finish agent command job(id: %d) failed: %s", dbInfo.ORGID, job.ID, err.Error())
+		return
+	}
+	log.Infof("ORG(id=%d) agent command job(id: %d) finished", dbInfo.ORGID, job.ID)
+}
+
+// AbortOrphanedAgentCMDJobs marks the jobs left running by the previous process of this controller as aborted,
+// it should be called before serving http requests.
+func AbortOrphanedAgentCMDJobs() {
+	orgIDs, err := mysql.GetORGIDs()
+	if err != nil {
+		log.Errorf("failed to get org ids: %s", err.Error())
+		return
+	}
+	for _, orgID := range orgIDs {
+		dbInfo, err := mysql.GetDB(orgID)
+		if err != nil {
+			log.Errorf("failed to get db of org(id=%d): %s", orgID, err.Error())
+			continue
+		}
+		if err := abortAgentCMDJobs(dbInfo, common.NodeIP); err != nil {
+			log.Errorf("ORG(id=%d) abort orphaned agent command jobs failed: %s", orgID, err.Error())
+		}
+	}
+}
+
+func abortAgentCMDJobs(dbInfo *mysql.DB, nodeIP string) error {
+	var jobIDs []int
+	if err := dbInfo.Model(&mysql.AgentCMDJob{}).Where("state = ? AND node_ip = ?", AGENT_CMD_JOB_STATE_RUNNING, nodeIP).
+		Pluck("id", &jobIDs).Error; err != nil {
+		return err
+	}
+	if len(jobIDs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	err := dbInfo.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&mysql.AgentCMDResult{}).
+			Where("job_id IN (?) AND state = ?", jobIDs, AGENT_CMD_RESULT_STATE_PENDING).
+			Updates(map[string]interface{}{
+				"state": AGENT_CMD_RESULT_STATE_FAILED, "errmsg": "job is aborted as the controller restarted", "finished_at": now,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&mysql.AgentCMDJob{}).Where("id IN (?)", jobIDs).Updates(map[string]interface{}{
+			"state": AGENT_CMD_JOB_STATE_ABORTED, "finished_at": now,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("ORG(id=%d) agent command jobs(id: %v) are aborted", dbInfo.ORGID, jobIDs)
+	return nil
+}
+
+// execAgentCMD runs the command through the stream of the agent if it connects to this controller,
+// otherwise the command is forwarded to the controller of the agent.
+func execAgentCMD(userInfo *httpcommon.UserInfo, agent *mysql.VTap, req *trident.RemoteExecRequest, timeout time.Duration) (string, error) {
+	if _, ok := GetAgentRemoteExecManager(agent.CtrlIP + "-" + agent.CtrlMac); ok {
+		return runAgentCMD(agent, req, timeout)
+	}
+
+	host := agent.CurControllerIP
+	if host == "" || host == common.NodeIP {
+		host = agent.ControllerIP
+	}
+	if host == "" || host == common.NodeIP {
+		return "", fmt.Errorf("agent(name: %s) is not connected to any controller", agent.Name)
+	}
+	return forwardAgentCMD(userInfo, agent, host, req, timeout)
+}
+
+func forwardAgentCMD(userInfo *httpcommon.UserInfo, agent *mysql.VTap, host string, req *trident.RemoteExecRequest, timeout time.Duration) (string, error) {
+	body := map[string]interface{}{
+		"command_id":    req.GetCommandId(),
+		"params":        req.GetParams(),
+		"output_format": trident.OutputFormat_TEXT,
+		"timeout":       int(timeout.Seconds()),
+	}
+	if req.LinuxNsPid != nil {
+		body["linux_ns_pid"] = req.GetLinuxNsPid()
+	}
+	bodyStr, _ := json.Marshal(body)
+	url := fmt.Sprintf("http://%s:%d/v1/agent/%d/cmd/run", common.GetCURLIP(host), common.GConfig.HTTPNodePort, agent.ID)
+	httpReq, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(bodyStr))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(common.HEADER_KEY_X_ORG_ID, strconv.Itoa(userInfo.ORGID))
+	httpReq.Header.Set(common.HEADER_KEY_X_USER_TYPE, strconv.Itoa(userInfo.Type))
+	httpReq.Header.Set(common.HEADER_KEY_X_USER_ID, strconv.Itoa(userInfo.ID))
+
+	client := &http.Client{Timeout: timeout + AGENT_CMD_FORWARD_EXTRA_TIMEOUT}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("forward command to controller(%s) failed: %s", host, err.Error())
+	}
+	defer resp.Body.Close()
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	response, err := simplejson.NewJson(respBytes)
+	if err != nil {
+		return "", fmt.Errorf("parse response of controller(%s) failed: %s", host, err.Error())
+	}
+	if resp.StatusCode != http.StatusOK || response.Get("OPT_STATUS").MustString() != common.SUCCESS {
+		return "", fmt.Errorf("controller(%s) run command failed: %s", host, response.Get("DESCRIPTION").MustString())
+	}
+	return response.Get("DATA").MustString(), nil
+}
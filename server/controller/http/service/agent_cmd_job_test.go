@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/deepflowio/deepflow/message/trident"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	httpcommon "github.com/deepflowio/deepflow/server/controller/http/common"
+)
+
+func newAgentCMDTestDB(t *testing.T) *mysql.DB {
+	db, err := gorm.Open(
+		sqlite.Open(filepath.Join(t.TempDir(), "agent_cmd.db")),
+		&gorm.Config{NamingStrategy: schema.NamingStrategy{SingularTable: true}},
+	)
+	if err != nil {
+		t.Fatalf("create sqlite database failed: %s", err.Error())
+	}
+	if err = db.AutoMigrate(&mysql.AgentCMDJob{}, &mysql.AgentCMDResult{}); err != nil {
+		t.Fatalf("create agent command tables failed: %s", err.Error())
+	}
+	return &mysql.DB{DB: db, ORGID: common.DEFAULT_ORG_ID}
+}
+
+func newAgentCMDTestJob(t *testing.T, db *mysql.DB, nodeIP string, agentCount int) (*mysql.AgentCMDJob, []mysql.VTap, []*mysql.AgentCMDResult) {
+	agents := make([]mysql.VTap, agentCount)
+	for i := range agents {
+		agents[i].ID = i + 1
+		agents[i].Name = "agent-" + strconv.Itoa(i+1)
+	}
+	job := &mysql.AgentCMDJob{Concurrency: 2, Timeout: 1, State: AGENT_CMD_JOB_STATE_RUNNING, NodeIP: nodeIP}
+	results, err := createAgentCMDJob(db, job, agents)
+	if err != nil {
+		t.Fatalf("create job failed: %s", err.Error())
+	}
+	return job, agents, results
+}
+
+func getAgentCMDTestResults(t *testing.T, db *mysql.DB, jobID int) []mysql.AgentCMDResult {
+	var results []mysql.AgentCMDResult
+	if err := db.Where("job_id = ?", jobID).Order("agent_id").Find(&results).Error; err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestCreateAgentCMDJob(t *testing.T) {
+	db := newAgentCMDTestDB(t)
+	job, _, _ := newAgentCMDTestJob(t, db, "10.0.0.1", 3)
+
+	results := getAgentCMDTestResults(t, db, job.ID)
+	if len(results) != 3 {
+		t.Fatalf("expect 3 results, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.State != AGENT_CMD_RESULT_STATE_PENDING {
+			t.Errorf("result of agent %d should be pending, got %d", result.AgentID, result.State)
+		}
+	}
+}
+
+func TestRunAgentCMDJob(t *testing.T) {
+	db := newAgentCMDTestDB(t)
+	job, agents, results := newAgentCMDTestJob(t, db, "10.0.0.1", 5)
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	exec := func(agent *mysql.VTap) (string, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		switch agent.ID {
+		case 2:
+			return "", errors.New("timeout")
+		case 3:
+			return strings.Repeat("a", AGENT_CMD_RESULT_MAX_CONTENT_LEN+1), nil
+		}
+		return agent.Name, nil
+	}
+	runAgentCMDJob(db, job, agents, results, exec)
+
+	if maxRunning > job.Concurrency {
+		t.Errorf("at most %d agents should run concurrently, got %d", job.Concurrency, maxRunning)
+	}
+	saved := getAgentCMDTestResults(t, db, job.ID)
+	if len(saved) != 5 {
+		t.Fatalf("expect 5 results, got %d", len(saved))
+	}
+	for _, result := range saved {
+		switch result.AgentID {
+		case 2:
+			if result.State != AGENT_CMD_RESULT_STATE_FAILED || result.ErrMsg != "timeout" {
+				t.Errorf("result of agent 2 should be failed, got %+v", result)
+			}
+		case 3:
+			if result.State != AGENT_CMD_RESULT_STATE_SUCCESS || len(result.Content) != AGENT_CMD_RESULT_MAX_CONTENT_LEN || result.ErrMsg == "" {
+				t.Errorf("content of agent 3 should be truncated, got %d bytes, errmsg %s", len(result.Content), result.ErrMsg)
+			}
+		default:
+			if result.State != AGENT_CMD_RESULT_STATE_SUCCESS || result.Content != result.AgentName {
+				t.Errorf("result of agent %d should be success, got %+v", result.AgentID, result)
+			}
+		}
+	}
+	var finished mysql.AgentCMDJob
+	db.First(&finished, job.ID)
+	if finished.State != AGENT_CMD_JOB_STATE_FINISHED || finished.FinishedAt == nil {
+		t.Errorf("job should be finished, got %+v", finished)
+	}
+}
+
+func TestAbortAgentCMDJobs(t *testing.T) {
+	db := newAgentCMDTestDB(t)
+	orphaned, agents, results := newAgentCMDTestJob(t, db, "10.0.0.1", 2)
+	// the first agent finished before the controller restarted
+	results[0].State = AGENT_CMD_RESULT_STATE_SUCCESS
+	db.Save(results[0])
+	other, _, _ := newAgentCMDTestJob(t, db, "10.0.0.2", 1)
+	finished, _, _ := newAgentCMDTestJob(t, db, "10.0.0.1", 1)
+	db.Model(finished).Update("state", AGENT_CMD_JOB_STATE_FINISHED)
+
+	if err := abortAgentCMDJobs(db, "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	var jobs []mysql.AgentCMDJob
+	db.Order("id").Find(&jobs)
+	expected := map[int]int{
+		orphaned.ID: AGENT_CMD_JOB_STATE_ABORTED,
+		other.ID:    AGENT_CMD_JOB_STATE_RUNNING,
+		finished.ID: AGENT_CMD_JOB_STATE_FINISHED,
+	}
+	for _, job := range jobs {
+		if job.State != expected[job.ID] {
+			t.Errorf("state of job %d should be %d, got %d", job.ID, expected[job.ID], job.State)
+		}
+	}
+	saved := getAgentCMDTestResults(t, db, orphaned.ID)
+	if saved[0].State != AGENT_CMD_RESULT_STATE_SUCCESS || saved[1].State != AGENT_CMD_RESULT_STATE_FAILED {
+		t.Errorf("pending result of agent %d should be failed, got %+v", agents[1].ID, saved)
+	}
+	if saved := getAgentCMDTestResults(t, db, other.ID); saved[0].State != AGENT_CMD_RESULT_STATE_PENDING {
+		t.Errorf("job of other controllers should not be aborted, got %+v", saved)
+	}
+}
+
+func TestForwardAgentCMD(t *testing.T) {
+	var body map[string]interface{}
+	var orgID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		orgID = r.Header.Get(common.HEADER_KEY_X_ORG_ID)
+		w.Write([]byte(`{"OPT_STATUS": "SUCCESS", "DATA": "output"}`))
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	common.GConfig.HTTPNodePort, _ = strconv.Atoi(port)
+
+	userInfo := &httpcommon.UserInfo{Type: common.DEFAULT_USER_TYPE, ID: common.DEFAULT_USER_ID, ORGID: 2}
+	req := &trident.RemoteExecRequest{CommandId: proto.Uint32(3)}
+	content, err := forwardAgentCMD(userInfo, &mysql.VTap{Name: "agent"}, host, req, 30*time.Second)
+	if err != nil || content != "output" {
+		t.Fatalf("expect output, got %s, err %v", content, err)
+	}
+	if body["timeout"] != float64(30) || body["command_id"] != float64(3) || orgID != "2" {
+		t.Errorf("timeout and command should be forwarded, got body %v, org %s", body, orgID)
+	}
+}
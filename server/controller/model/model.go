@@ -750,6 +750,7 @@ type RemoteExecReq struct {
 
 	OutputFormat   *trident.OutputFormat `json:"output_format"` // 0: "TEXT", 1: "BINARY"
 	OutputFilename string                `json:"output_filename"`
+	Timeout        int                   `json:"timeout" binding:"min=0,max=600"` // unit: s, 0 means the default timeout
 }
 
 type RemoteExecResp struct {
@@ -757,3 +758,43 @@ type RemoteExecResp struct {
 	RemoteCommand  []*trident.RemoteCommand  `json:"remote_commands,omitempty"`  // LIST_COMMAND
 	LinuxNamespace []*trident.LinuxNamespace `json:"linux_namespaces,omitempty"` // LIST_NAMESPACE
 }
+
+type AgentCMDJobCreate struct {
+	AgentIDs         []int                `json:"AGENT_IDS"`
+	AgentGroupLcuuid string               `json:"AGENT_GROUP_LCUUID"`
+	CommandID        uint32               `json:"COMMAND_ID"`
+	LinuxNsPid       uint32               `json:"LINUX_NS_PID"`
+	Params           []*trident.Parameter `json:"PARAMS"`
+	Concurrency      int                  `json:"CONCURRENCY" binding:"min=0,max=100"`
+	Timeout          int                  `json:"TIMEOUT" binding:"min=0,max=600"` // unit: s
+}
+
+type AgentCMDJob struct {
+	ID               int                  `json:"ID"`
+	UserID           int                  `json:"USER_ID"`
+	AgentGroupLcuuid string               `json:"AGENT_GROUP_LCUUID"`
+	AgentIDs         []int                `json:"AGENT_IDS"`
+	CommandID        int                  `json:"COMMAND_ID"`
+	Params           []*trident.Parameter `json:"PARAMS"`
+	LinuxNsPid       int                  `json:"LINUX_NS_PID"`
+	Concurrency      int                  `json:"CONCURRENCY"`
+	Timeout          int                  `json:"TIMEOUT"`
+	State            int                  `json:"STATE"`
+	NodeIP           string               `json:"NODE_IP"`
+	CreatedAt        string               `json:"CREATED_AT"`
+	FinishedAt       string               `json:"FINISHED_AT"`
+	SuccessCount     int                  `json:"SUCCESS_COUNT"`
+	FailedCount      int                  `json:"FAILED_COUNT"`
+	PendingCount     int                  `json:"PENDING_COUNT"`
+	Results          []AgentCMDResult     `json:"RESULTS,omitempty"`
+}
+
+type AgentCMDResult struct {
+	AgentID    int    `json:"AGENT_ID"`
+	AgentName  string `json:"AGENT_NAME"`
+	State      int    `json:"STATE"`
+	Content    string `json:"CONTENT"`
+	ErrMsg     string `json:"ERRMSG"`
+	StartedAt  string `json:"STARTED_AT"`
+	FinishedAt string `json:"FINISHED_AT"`
+}
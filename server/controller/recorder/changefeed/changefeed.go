@@ -0,0 +1,299 @@
+/**
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package changefeed mirrors resource changes published by recorder to external systems.
+//
+// Subscribers on every controller write change events to the resource_change_event table of the org,
+// the master controller delivers them to the sink in id order and records the id of the last delivered event
+// in the resource_change_feed_cursor table by the sink name, so events are delivered at least once and the feed
+// resumes after restart.
+package changefeed
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/op/go-logging"
+	"golang.org/x/exp/slices"
+	"gorm.io/gorm"
+
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/recorder/config"
+	"github.com/deepflowio/deepflow/server/controller/recorder/pubsub"
+)
+
+var log = logging.MustGetLogger("recorder.changefeed")
+
+const (
+	EVENT_INSERT_BATCH_SIZE = 100
+	PURGE_INTERVAL          = time.Hour
+	OUTBOX_RETRY_INTERVAL   = 10 * time.Second
+	OUTBOX_MAX_PENDING      = 100000
+)
+
+var (
+	changeFeedOnce sync.Once
+	changeFeed     *ChangeFeed
+)
+
+type ChangeFeed struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	cfg    config.ChangeFeedConfig
+	outbox *outbox
+
+	mux  sync.Mutex
+	sink Sink
+	// events with id larger than the max id at the previous delivery may be uncommitted,
+	// deliver them in the next round to avoid skipping them.
+	orgIDToWatermark map[int]int64
+}
+
+func GetChangeFeed() *ChangeFeed {
+	changeFeedOnce.Do(func() {
+		changeFeed = new(ChangeFeed)
+	})
+	return changeFeed
+}
+
+func (c *ChangeFeed) Init(ctx context.Context, cfg config.RecorderConfig) {
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	c.cfg = cfg.ChangeFeed
+	c.outbox = newOutbox()
+	c.orgIDToWatermark = make(map[int]int64)
+}
+
+// Subscribe should be called on all controllers before recorders start
+func (c *ChangeFeed) Subscribe() error {
+	if !c.cfg.Enabled {
+		return nil
+	}
+	for pubSubType := range pubsub.GetManager().TypeToPubSub {
+		if pubSubType == pubsub.PubSubTypeDomain {
+			continue
+		}
+		if len(c.cfg.ResourceTypes) != 0 && !slices.Contains(c.cfg.ResourceTypes, pubSubType) {
+			continue
+		}
+		if err := newSubscriber(pubSubType, c.outbox).Subscribe(); err != nil {
+			return err
+		}
+	}
+	go c.outbox.run(c.ctx)
+	log.Infof("resource change feed subscribed, resource types: %v", c.cfg.ResourceTypes)
+	return nil
+}
+
+// Start delivers events periodically, it should only be called on the master controller
+func (c *ChangeFeed) Start(sContext context.Context) error {
+	if !c.cfg.Enabled {
+		return nil
+	}
+	sink, err := NewSink(c.cfg)
+	if err != nil {
+		log.Errorf("failed to create change feed sink: %s", err.Error())
+		return err
+	}
+	c.mux.Lock()
+	c.sink = sink
+	c.orgIDToWatermark = make(map[int]int64)
+	c.mux.Unlock()
+	log.Infof("resource change feed started, sink: %s", sink.Name())
+
+	go func() {
+		deliveryTicker := time.NewTicker(time.Duration(c.cfg.DeliveryInterval) * time.Second)
+		defer deliveryTicker.Stop()
+		purgeTicker := time.NewTicker(PURGE_INTERVAL)
+		defer purgeTicker.Stop()
+		defer c.closeSink()
+
+	LOOP:
+		for {
+			select {
+			case <-deliveryTicker.C:
+				c.deliver()
+			case <-purgeTicker.C:
+				c.purge()
+			case <-sContext.Done():
+				break LOOP
+			case <-c.ctx.Done():
+				break LOOP
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *ChangeFeed) Stop() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	log.Info("resource change feed stopped")
+}
+
+func (c *ChangeFeed) closeSink() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.sink != nil {
+		if err := c.sink.Close(); err != nil {
+			log.Errorf("failed to close change feed sink %s: %s", c.sink.Name(), err.Error())
+		}
+		c.sink = nil
+	}
+}
+
+func (c *ChangeFeed) deliver() {
+	orgIDs, err := mysql.GetORGIDs()
+	if err != nil {
+		log.Errorf("failed to get db for org ids: %s", err.Error())
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.sink == nil {
+		return
+	}
+	for orgID := range c.orgIDToWatermark {
+		if !slices.Contains(orgIDs, orgID) {
+			delete(c.orgIDToWatermark, orgID)
+		}
+	}
+	for _, orgID := range orgIDs {
+		db, err := mysql.GetDB(orgID)
+		if err != nil {
+			log.Errorf("failed to get db for org id %d: %s", orgID, err.Error())
+			continue
+		}
+		if err := c.deliverORG(db); err != nil {
+			log.Errorf("failed to deliver change events of org id %d to %s, will retry: %s", orgID, c.sink.Name(), err.Error())
+		}
+	}
+}
+
+// deliverORG sends events after the cursor in batches, the cursor is moved only after a batch is sent
+func (c *ChangeFeed) deliverORG(db *mysql.DB) error {
+	var maxID int64
+	if err := db.Model(&mysql.ResourceChangeEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&maxID).Error; err != nil {
+		return err
+	}
+	watermark, ok := c.orgIDToWatermark[db.ORGID]
+	c.orgIDToWatermark[db.ORGID] = maxID
+	if !ok {
+		return nil
+	}
+
+	cursor, err := c.getCursor(db)
+	if err != nil {
+		return err
+	}
+	for cursor.EventID < watermark {
+		var rows []*mysql.ResourceChangeEvent
+		if err := db.Where("id > ? AND id <= ?", cursor.EventID, watermark).
+			Order("id").Limit(c.cfg.BatchSize).Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		events := make([]*Event, 0, len(rows))
+		for _, row := range rows {
+			event := new(Event)
+			if err := json.Unmarshal([]byte(row.Event), event); err != nil {
+				log.Errorf("skip invalid change event %d of org id %d: %s", row.ID, db.ORGID, err.Error())
+				continue
+			}
+			event.ID = row.ID
+			events = append(events, event)
+		}
+		if len(events) != 0 {
+			if err := c.sink.Send(events); err != nil {
+				return err
+			}
+		}
+		cursor.EventID = rows[len(rows)-1].ID
+		if err := db.Save(cursor).Error; err != nil {
+			return err
+		}
+		log.Debugf("delivered %d change events of org id %d to %s, cursor: %d", len(events), db.ORGID, cursor.Sink, cursor.EventID)
+	}
+	return nil
+}
+
+func (c *ChangeFeed) getCursor(db *mysql.DB) (*mysql.ResourceChangeFeedCursor, error) {
+	cursor := &mysql.ResourceChangeFeedCursor{Sink: c.sink.Name()}
+	err := db.Where("sink = ?", cursor.Sink).First(cursor).Error
+	if err == gorm.ErrRecordNotFound {
+		// a new sink receives all the retained events
+		return cursor, db.Create(cursor).Error
+	}
+	return cursor, err
+}
+
+// purge deletes events delivered to the sink and older than the retention time,
+// cursors of other sinks are deleted, since only one sink is configured.
+func (c *ChangeFeed) purge() {
+	c.mux.Lock()
+	if c.sink == nil {
+		c.mux.Unlock()
+		return
+	}
+	sinkName := c.sink.Name()
+	c.mux.Unlock()
+
+	orgIDs, err := mysql.GetORGIDs()
+	if err != nil {
+		log.Errorf("failed to get db for org ids: %s", err.Error())
+		return
+	}
+	expiredAt := time.Now().Add(-time.Duration(c.cfg.RetentionTime) * time.Hour)
+	for _, orgID := range orgIDs {
+		db, err := mysql.GetDB(orgID)
+		if err != nil {
+			log.Errorf("failed to get db for org id %d: %s", orgID, err.Error())
+			continue
+		}
+		if err := purgeORG(db, sinkName, expiredAt); err != nil {
+			log.Errorf("failed to purge change events of org id %d: %s", orgID, err.Error())
+		}
+	}
+}
+
+func purgeORG(db *mysql.DB, sinkName string, expiredAt time.Time) error {
+	result := db.Where("sink <> ?", sinkName).Delete(&mysql.ResourceChangeFeedCursor{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 0 {
+		log.Infof("deleted %d change feed cursors of previous sinks of org id %d", result.RowsAffected, db.ORGID)
+	}
+	var cursor mysql.ResourceChangeFeedCursor
+	if err := db.Where("sink = ?", sinkName).First(&cursor).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+	result = db.Where("id <= ? AND created_at < ?", cursor.EventID, expiredAt).Delete(&mysql.ResourceChangeEvent{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 0 {
+		log.Infof("purged %d change events of org id %d", result.RowsAffected, db.ORGID)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+/**
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package changefeed
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/recorder/config"
+)
+
+type fakeSink struct {
+	name string
+	err  error
+	sent []int64
+}
+
+func (s *fakeSink) Name() string {
+	return s.name
+}
+
+func (s *fakeSink) Send(events []*Event) error {
+	if s.err != nil {
+		return s.err
+	}
+	for _, event := range events {
+		s.sent = append(s.sent, event.ID)
+	}
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	return nil
+}
+
+func newTestDB(t *testing.T) *mysql.DB {
+	db, err := gorm.Open(
+		sqlite.Open(filepath.Join(t.TempDir(), "changefeed.db")),
+		&gorm.Config{NamingStrategy: schema.NamingStrategy{SingularTable: true}},
+	)
+	if err != nil {
+		t.Fatalf("create sqlite database failed: %s", err.Error())
+	}
+	if err = db.AutoMigrate(&mysql.ResourceChangeEvent{}, &mysql.ResourceChangeFeedCursor{}); err != nil {
+		t.Fatalf("create change feed tables failed: %s", err.Error())
+	}
+	return &mysql.DB{DB: db, ORGID: 1}
+}
+
+func insertTestEvents(t *testing.T, db *mysql.DB, n int) {
+	for i := 0; i < n; i++ {
+		data, _ := json.Marshal(&Event{Version: EVENT_VERSION, Type: EVENT_TYPE_ADDED})
+		if err := db.Create(&mysql.ResourceChangeEvent{ResourceType: "vm", Event: string(data)}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newTestChangeFeed(sink Sink) *ChangeFeed {
+	return &ChangeFeed{
+		cfg:              config.ChangeFeedConfig{BatchSize: 2},
+		sink:             sink,
+		orgIDToWatermark: make(map[int]int64),
+	}
+}
+
+func getTestCursor(t *testing.T, db *mysql.DB, name string) int64 {
+	cursor := &mysql.ResourceChangeFeedCursor{}
+	if err := db.Where("sink = ?", name).First(cursor).Error; err != nil {
+		t.Fatal(err)
+	}
+	return cursor.EventID
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDeliverORG(t *testing.T) {
+	db := newTestDB(t)
+	sink := &fakeSink{name: SINK_TYPE_WEBHOOK}
+	c := newTestChangeFeed(sink)
+	insertTestEvents(t, db, 3)
+
+	// events after the watermark may be uncommitted, they are delivered in the next round
+	if err := c.deliverORG(db); err != nil || len(sink.sent) != 0 {
+		t.Fatalf("no event should be delivered in the first round, sent %v, err %v", sink.sent, err)
+	}
+	insertTestEvents(t, db, 1)
+	if err := c.deliverORG(db); err != nil || !equalIDs(sink.sent, []int64{1, 2, 3}) {
+		t.Fatalf("events 1-3 should be delivered, sent %v, err %v", sink.sent, err)
+	}
+	if cursor := getTestCursor(t, db, sink.name); cursor != 3 {
+		t.Errorf("cursor should be moved to 3, got %d", cursor)
+	}
+
+	// cursor is not moved if the sink fails
+	sink.err = errors.New("unavailable")
+	insertTestEvents(t, db, 1)
+	if err := c.deliverORG(db); err == nil {
+		t.Error("delivery should fail")
+	}
+	if cursor := getTestCursor(t, db, sink.name); cursor != 3 {
+		t.Errorf("cursor should stay at 3, got %d", cursor)
+	}
+
+	// a restarted controller resumes from the cursor
+	sink = &fakeSink{name: SINK_TYPE_WEBHOOK}
+	c = newTestChangeFeed(sink)
+	c.deliverORG(db)
+	if err := c.deliverORG(db); err != nil || !equalIDs(sink.sent, []int64{4, 5}) {
+		t.Fatalf("events 4-5 should be delivered after restart, sent %v, err %v", sink.sent, err)
+	}
+	if cursor := getTestCursor(t, db, sink.name); cursor != 5 {
+		t.Errorf("cursor should be moved to 5, got %d", cursor)
+	}
+
+	// a new sink receives all the retained events
+	other := &fakeSink{name: "other"}
+	c = newTestChangeFeed(other)
+	c.deliverORG(db)
+	if err := c.deliverORG(db); err != nil || len(other.sent) != 5 {
+		t.Errorf("all events should be delivered to a new sink, sent %v, err %v", other.sent, err)
+	}
+}
+
+func TestPurgeORG(t *testing.T) {
+	db := newTestDB(t)
+	insertTestEvents(t, db, 4)
+	db.Create(&mysql.ResourceChangeFeedCursor{Sink: SINK_TYPE_KAFKA, EventID: 3})
+	// cursor of a previous sink does not block purging
+	db.Create(&mysql.ResourceChangeFeedCursor{Sink: "previous", EventID: 0})
+
+	if err := purgeORG(db, SINK_TYPE_KAFKA, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	db.Model(&mysql.ResourceChangeEvent{}).Order("id").Pluck("id", &ids)
+	if !equalIDs(ids, []int64{4}) {
+		t.Errorf("events delivered should be purged, remaining %v", ids)
+	}
+	var count int64
+	db.Model(&mysql.ResourceChangeFeedCursor{}).Count(&count)
+	if count != 1 {
+		t.Errorf("cursors of previous sinks should be deleted, remaining %d", count)
+	}
+}
+
+func TestNewSinkName(t *testing.T) {
+	cfg := config.ChangeFeedConfig{SinkType: SINK_TYPE_WEBHOOK, Webhook: config.WebhookConfig{URL: "http://127.0.0.1/" + strings.Repeat("a", 300)}}
+	sink, err := NewSink(cfg)
+	if err != nil || sink.Name() != SINK_TYPE_WEBHOOK {
+		t.Fatalf("sink name should default to sink type, got %v, err %v", sink, err)
+	}
+	cfg.SinkName = "audit"
+	if sink, err = NewSink(cfg); err != nil || sink.Name() != "audit" {
+		t.Errorf("sink name should be audit, got %v, err %v", sink, err)
+	}
+	cfg.SinkName = strings.Repeat("a", SINK_NAME_MAX_LEN+1)
+	if _, err = NewSink(cfg); err == nil {
+		t.Error("too long sink name should fail")
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	o := newOutbox()
+	var saved []string
+	var insertErr error
+	o.insert = func(orgID int, rows []*mysql.ResourceChangeEvent) error {
+		if insertErr != nil {
+			return insertErr
+		}
+		for _, row := range rows {
+			saved = append(saved, row.Event)
+		}
+		return nil
+	}
+	row := func(event string) []*mysql.ResourceChangeEvent {
+		return []*mysql.ResourceChangeEvent{{Event: event}}
+	}
+
+	insertErr = errors.New("unavailable")
+	o.save(1, row("a"))
+	o.save(1, row("b"))
+	if o.pendingCount != 2 || len(saved) != 0 {
+		t.Fatalf("events should be pending, pending %d, saved %v", o.pendingCount, saved)
+	}
+
+	// pending events are saved before newer events
+	insertErr = nil
+	o.save(1, row("c"))
+	if strings.Join(saved, "") != "abc" || o.pendingCount != 0 || len(o.orgIDToPending) != 0 {
+		t.Fatalf("events should be saved in order, pending %d, saved %v", o.pendingCount, saved)
+	}
+
+	insertErr = errors.New("unavailable")
+	o.save(2, row("d"))
+	insertErr = nil
+	o.retry()
+	if strings.Join(saved, "") != "abcd" || o.pendingCount != 0 {
+		t.Fatalf("pending events should be saved by retry, pending %d, saved %v", o.pendingCount, saved)
+	}
+
+	// the oldest events are dropped if too many events are pending
+	insertErr = errors.New("unavailable")
+	rows := make([]*mysql.ResourceChangeEvent, OUTBOX_MAX_PENDING)
+	for i := range rows {
+		rows[i] = &mysql.ResourceChangeEvent{}
+	}
+	o.save(1, row("e"))
+	o.save(1, rows)
+	if o.pendingCount != OUTBOX_MAX_PENDING || o.droppedCount != 1 || o.orgIDToPending[1][0].Event == "e" {
+		t.Errorf("the oldest event should be dropped, pending %d, dropped %d", o.pendingCount, o.droppedCount)
+	}
+}
@@ -0,0 +1,108 @@
+/**
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package changefeed
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/controller/recorder/pubsub/message"
+)
+
+// EVENT_VERSION is increased when fields of Event are changed incompatibly
+const EVENT_VERSION = 1
+
+const (
+	EVENT_TYPE_ADDED   = "added"
+	EVENT_TYPE_UPDATED = "updated"
+	EVENT_TYPE_DELETED = "deleted"
+)
+
+// Event is the change event delivered to sinks.
+// Data is the MySQL model of the added or deleted resource, keys are the upper case column names,
+// Changes is the changed fields of the updated resource, keys are the field names of message.XFieldsUpdate.
+type Event struct {
+	Version      int             `json:"version"`
+	ID           int64           `json:"id"` // id of the outbox row, increasing in one org
+	Type         string          `json:"type"`
+	ResourceType string          `json:"resource_type"`
+	ORGID        int             `json:"org_id"`
+	TeamID       int             `json:"team_id"`
+	DomainID     int             `json:"domain_id"`
+	SubDomainID  int             `json:"sub_domain_id"`
+	Time         int64           `json:"time"` // unit: s
+	ResourceID   int             `json:"resource_id"`
+	Lcuuid       string          `json:"lcuuid"`
+	SoftDelete   bool            `json:"soft_delete,omitempty"`
+	Data         json.RawMessage `json:"data,omitempty"`
+	Changes      json.RawMessage `json:"changes,omitempty"`
+}
+
+type mysqlItem interface {
+	GetID() int
+	GetLcuuid() string
+}
+
+func newEvent(md *message.Metadata, eventType, resourceType string) *Event {
+	return &Event{
+		Version:      EVENT_VERSION,
+		Type:         eventType,
+		ResourceType: resourceType,
+		ORGID:        md.ORGID,
+		TeamID:       md.TeamID,
+		DomainID:     md.DomainID,
+		SubDomainID:  md.SubDomainID,
+		Time:         time.Now().Unix(),
+	}
+}
+
+// newItemEvent returns an added or deleted event of the MySQL item
+func newItemEvent(md *message.Metadata, eventType, resourceType string, item mysqlItem) (*Event, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	event := newEvent(md, eventType, resourceType)
+	event.ResourceID = item.GetID()
+	event.Lcuuid = item.GetLcuuid()
+	event.Data = data
+	return event, nil
+}
+
+// newUpdatedEvent returns an updated event of the *message.XFieldsUpdate, nil if no field is changed
+func newUpdatedEvent(md *message.Metadata, resourceType string, fields interface{}) (*Event, error) {
+	key, ok := fields.(interface {
+		GetID() int
+		GetLcuuid() string
+	})
+	if !ok {
+		return nil, nil
+	}
+	changedFields := message.GetChangedFields(fields)
+	if len(changedFields) == 0 {
+		return nil, nil
+	}
+	changes, err := json.Marshal(changedFields)
+	if err != nil {
+		return nil, err
+	}
+	event := newEvent(md, EVENT_TYPE_UPDATED, resourceType)
+	event.ResourceID = key.GetID()
+	event.Lcuuid = key.GetLcuuid()
+	event.Changes = changes
+	return event, nil
+}
@@ -0,0 +1,72 @@
+/**
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package changefeed
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/recorder/pubsub"
+	"github.com/deepflowio/deepflow/server/controller/recorder/pubsub/message"
+)
+
+func TestNewItemEvent(t *testing.T) {
+	md := message.NewMetadata(1, 2, 3, message.MetadataSubDomainID(4))
+	vm := &mysql.VM{Name: "vm-1"}
+	vm.ID = 10
+	vm.Lcuuid = "vm-lcuuid"
+	event, err := newItemEvent(md, EVENT_TYPE_ADDED, pubsub.PubSubTypeVM, vm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Version != EVENT_VERSION || event.ResourceID != 10 || event.Lcuuid != "vm-lcuuid" ||
+		event.ORGID != 1 || event.TeamID != 2 || event.DomainID != 3 || event.SubDomainID != 4 {
+		t.Errorf("wrong event %+v", event)
+	}
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(event.Data, &data); err != nil || data["NAME"] != "vm-1" {
+		t.Errorf("wrong event data %s, err %v", event.Data, err)
+	}
+}
+
+func TestNewUpdatedEvent(t *testing.T) {
+	md := message.NewMetadata(1, 2, 3)
+	fields := &message.VMFieldsUpdate{}
+	fields.SetID(10)
+	fields.SetLcuuid("vm-lcuuid")
+	if event, err := newUpdatedEvent(md, pubsub.PubSubTypeVM, fields); err != nil || event != nil {
+		t.Errorf("no updated event should be returned if no field is changed, event %+v, err %v", event, err)
+	}
+
+	fields.Name.Set("vm-1", "vm-2")
+	fields.HostID.SetNew(5)
+	event, err := newUpdatedEvent(md, pubsub.PubSubTypeVM, fields)
+	if err != nil || event == nil {
+		t.Fatalf("new updated event failed, event %+v, err %v", event, err)
+	}
+	if event.Type != EVENT_TYPE_UPDATED || event.ResourceID != 10 || event.Lcuuid != "vm-lcuuid" {
+		t.Errorf("wrong event %+v", event)
+	}
+	changes := map[string]message.FieldChange{}
+	if err := json.Unmarshal(event.Changes, &changes); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes["Name"].Old != "vm-1" || changes["Name"].New != "vm-2" || changes["HostID"].New != float64(5) {
+		t.Errorf("wrong event changes %s", event.Changes)
+	}
+}
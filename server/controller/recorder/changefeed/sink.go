@@ -0,0 +1,173 @@
+/**
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package changefeed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/deepflowio/deepflow/server/controller/recorder/config"
+)
+
+const (
+	SINK_TYPE_KAFKA   = "kafka"
+	SINK_TYPE_WEBHOOK = "webhook"
+
+	SINK_NAME_MAX_LEN = 64
+)
+
+// Sink delivers events to an external system, Send returns nil only if all events are accepted
+type Sink interface {
+	// Name identifies the sink, the delivery cursor is recorded by name,
+	// so the address of the sink could be changed without delivering the retained events again
+	Name() string
+	Send(events []*Event) error
+	Close() error
+}
+
+func NewSink(cfg config.ChangeFeedConfig) (Sink, error) {
+	name := cfg.SinkName
+	if name == "" {
+		name = cfg.SinkType
+	}
+	if len(name) > SINK_NAME_MAX_LEN {
+		return nil, fmt.Errorf("change feed sink name %s is longer than %d", name, SINK_NAME_MAX_LEN)
+	}
+	switch cfg.SinkType {
+	case SINK_TYPE_KAFKA:
+		return newKafkaSink(name, cfg.Kafka)
+	case SINK_TYPE_WEBHOOK:
+		return newWebhookSink(name, cfg.Webhook)
+	default:
+		return nil, fmt.Errorf("unknown change feed sink type: %s", cfg.SinkType)
+	}
+}
+
+type kafkaSink struct {
+	name     string
+	topic    string
+	producer sarama.SyncProducer
+}
+
+func newKafkaSink(name string, cfg config.KafkaConfig) (*kafkaSink, error) {
+	if len(cfg.Brokers) == 0 || cfg.Topic == "" {
+		return nil, errors.New("kafka brokers and topic are required")
+	}
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Producer.RequiredAcks = sarama.WaitForAll
+	saramaConfig.Producer.Retry.Max = 3
+	saramaConfig.Producer.Return.Successes = true
+	// keep the events of a resource in order
+	saramaConfig.Producer.Partitioner = sarama.NewHashPartitioner
+	if cfg.SASLUser != "" {
+		saramaConfig.Net.SASL.Enable = true
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		saramaConfig.Net.SASL.User = cfg.SASLUser
+		saramaConfig.Net.SASL.Password = cfg.SASLPassword
+	}
+	producer, err := sarama.NewSyncProducer(cfg.Brokers, saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaSink{name: name, topic: cfg.Topic, producer: producer}, nil
+}
+
+func (s *kafkaSink) Name() string {
+	return s.name
+}
+
+func (s *kafkaSink) Send(events []*Event) error {
+	messages := make([]*sarama.ProducerMessage, 0, len(events))
+	for _, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, &sarama.ProducerMessage{
+			Topic: s.topic,
+			Key:   sarama.StringEncoder(event.Lcuuid),
+			Value: sarama.ByteEncoder(value),
+		})
+	}
+	return s.producer.SendMessages(messages)
+}
+
+func (s *kafkaSink) Close() error {
+	return s.producer.Close()
+}
+
+// webhookSink posts events as a json array, any response status other than 2xx is a failure
+type webhookSink struct {
+	name    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newWebhookSink(name string, cfg config.WebhookConfig) (*webhookSink, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("webhook url is required")
+	}
+	return &webhookSink{
+		name:    name,
+		url:     cfg.URL,
+		headers: cfg.Headers,
+		client:  &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+	}, nil
+}
+
+func (s *webhookSink) Name() string {
+	return s.name
+}
+
+func (s *webhookSink) Send(events []*Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook response status: %s, body: %s", resp.Status, respBody)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (s *webhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,208 @@
+/**
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package changefeed
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/recorder/pubsub"
+	"github.com/deepflowio/deepflow/server/controller/recorder/pubsub/message"
+)
+
+// subscriber writes change events of a resource type to the outbox table of the org,
+// events are written synchronously so that they are not lost when the controller restarts.
+type subscriber struct {
+	resourceType string
+	outbox       *outbox
+}
+
+func newSubscriber(resourceType string, outbox *outbox) *subscriber {
+	return &subscriber{resourceType: resourceType, outbox: outbox}
+}
+
+func (s *subscriber) Subscribe() error {
+	for _, topic := range []int{
+		pubsub.TopicResourceBatchAddedMySQL,
+		pubsub.TopicResourceUpdatedMessageUpdate,
+		pubsub.TopicResourceBatchDeletedMySQL,
+	} {
+		if err := pubsub.Subscribe(s.resourceType, topic, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnResourceBatchAdded implements interface ResourceBatchAddedSubscriber, msg is []*mysql.X
+func (s *subscriber) OnResourceBatchAdded(md *message.Metadata, msg interface{}) {
+	s.save(md, s.newItemEvents(md, EVENT_TYPE_ADDED, msg, false))
+}
+
+// OnResourceUpdated implements interface ResourceUpdatedSubscriber, msg is *message.XUpdate
+func (s *subscriber) OnResourceUpdated(md *message.Metadata, msg interface{}) {
+	update, ok := msg.(interface{ GetFields() interface{} })
+	if !ok {
+		return
+	}
+	event, err := newUpdatedEvent(md, s.resourceType, update.GetFields())
+	if err != nil {
+		log.Errorf("new %s updated event of org id %d failed: %s", s.resourceType, md.ORGID, err.Error())
+		return
+	}
+	if event != nil {
+		s.save(md, []*Event{event})
+	}
+}
+
+// OnResourceBatchDeleted implements interface ResourceBatchDeletedSubscriber, msg is []*mysql.X
+func (s *subscriber) OnResourceBatchDeleted(md *message.Metadata, msg interface{}, softDelete bool) {
+	s.save(md, s.newItemEvents(md, EVENT_TYPE_DELETED, msg, softDelete))
+}
+
+func (s *subscriber) newItemEvents(md *message.Metadata, eventType string, msg interface{}, softDelete bool) []*Event {
+	items := reflect.ValueOf(msg)
+	if items.Kind() != reflect.Slice {
+		return nil
+	}
+	events := make([]*Event, 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		item, ok := items.Index(i).Interface().(mysqlItem)
+		if !ok {
+			continue
+		}
+		event, err := newItemEvent(md, eventType, s.resourceType, item)
+		if err != nil {
+			log.Errorf("new %s %s event of org id %d failed: %s", s.resourceType, eventType, md.ORGID, err.Error())
+			continue
+		}
+		event.SoftDelete = softDelete
+		events = append(events, event)
+	}
+	return events
+}
+
+func (s *subscriber) save(md *message.Metadata, events []*Event) {
+	if len(events) == 0 {
+		return
+	}
+	rows := make([]*mysql.ResourceChangeEvent, 0, len(events))
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			log.Errorf("marshal %s event of org id %d failed: %s", s.resourceType, md.ORGID, err.Error())
+			continue
+		}
+		rows = append(rows, &mysql.ResourceChangeEvent{ResourceType: s.resourceType, Event: string(data)})
+	}
+	s.outbox.save(md.ORGID, rows)
+}
+
+// outbox inserts change events into the resource_change_event table of the org. events failed to be inserted
+// are kept in memory and retried before the newer events of the org, so that they are not lost when mysql is
+// temporarily unavailable. the oldest pending events are dropped if there are too many of them.
+type outbox struct {
+	mux            sync.Mutex
+	orgIDToPending map[int][]*mysql.ResourceChangeEvent
+	pendingCount   int
+	droppedCount   int
+	insert         func(orgID int, rows []*mysql.ResourceChangeEvent) error
+}
+
+func newOutbox() *outbox {
+	return &outbox{
+		orgIDToPending: make(map[int][]*mysql.ResourceChangeEvent),
+		insert:         insertEvents,
+	}
+}
+
+func insertEvents(orgID int, rows []*mysql.ResourceChangeEvent) error {
+	db, err := mysql.GetDB(orgID)
+	if err != nil {
+		return err
+	}
+	// rows are inserted in a transaction, so they are either all saved or all retried
+	return db.CreateInBatches(rows, EVENT_INSERT_BATCH_SIZE).Error
+}
+
+func (o *outbox) save(orgID int, rows []*mysql.ResourceChangeEvent) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	o.saveORG(orgID, rows)
+}
+
+// run retries pending events periodically, it should be called on all controllers
+func (o *outbox) run(ctx context.Context) {
+	ticker := time.NewTicker(OUTBOX_RETRY_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			o.retry()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (o *outbox) retry() {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	for orgID := range o.orgIDToPending {
+		o.saveORG(orgID, nil)
+	}
+}
+
+func (o *outbox) saveORG(orgID int, rows []*mysql.ResourceChangeEvent) {
+	pending := o.orgIDToPending[orgID]
+	all := append(pending[:len(pending):len(pending)], rows...)
+	if len(all) == 0 {
+		return
+	}
+	if err := o.insert(orgID, all); err != nil {
+		log.Errorf("failed to save %d change events of org id %d, will retry: %s", len(all), orgID, err.Error())
+		o.keep(orgID, all)
+		return
+	}
+	if len(pending) != 0 {
+		log.Infof("saved %d pending change events of org id %d", len(pending), orgID)
+	}
+	o.pendingCount -= len(pending)
+	delete(o.orgIDToPending, orgID)
+}
+
+func (o *outbox) keep(orgID int, rows []*mysql.ResourceChangeEvent) {
+	o.pendingCount += len(rows) - len(o.orgIDToPending[orgID])
+	if over := o.pendingCount - OUTBOX_MAX_PENDING; over > 0 {
+		if over > len(rows) {
+			over = len(rows)
+		}
+		rows = rows[over:]
+		o.pendingCount -= over
+		o.droppedCount += over
+		log.Errorf("dropped %d change events of org id %d, too many events are pending, %d dropped in total", over, orgID, o.droppedCount)
+	}
+	if len(rows) == 0 {
+		delete(o.orgIDToPending, orgID)
+		return
+	}
+	o.orgIDToPending[orgID] = rows
+}
@@ -25,7 +25,8 @@ type RecorderConfig struct {
 	ResourceMaxID0               int    `default:"64000" yaml:"resource_max_id_0"`
 	ResourceMaxID1               int    `default:"499999" yaml:"resource_max_id_1"`
 
-	LogDebug LogDebugConfig `yaml:"log_debug"`
+	LogDebug   LogDebugConfig   `yaml:"log_debug"`
+	ChangeFeed ChangeFeedConfig `yaml:"change_feed"`
 }
 
 func Get() *RecorderConfig {
@@ -41,3 +42,28 @@ type LogDebugConfig struct {
 	DetailEnabled bool     `default:"false" yaml:"detail_enabled"`
 	ResourceTypes []string `default:"" yaml:"resource_type"`
 }
+
+type ChangeFeedConfig struct {
+	Enabled          bool          `default:"false" yaml:"enabled"`
+	ResourceTypes    []string      `yaml:"resource_types"`
+	SinkType         string        `default:"kafka" yaml:"sink_type"`
+	SinkName         string        `yaml:"sink_name"` // delivery cursor is recorded by sink name, defaults to sink type
+	Kafka            KafkaConfig   `yaml:"kafka"`
+	Webhook          WebhookConfig `yaml:"webhook"`
+	BatchSize        int           `default:"500" yaml:"batch_size"`
+	DeliveryInterval int           `default:"5" yaml:"delivery_interval"` // unit: s
+	RetentionTime    int           `default:"168" yaml:"retention_time"`  // unit: h
+}
+
+type KafkaConfig struct {
+	Brokers      []string `yaml:"brokers"`
+	Topic        string   `default:"deepflow_resource_change" yaml:"topic"`
+	SASLUser     string   `yaml:"sasl_user"`
+	SASLPassword string   `yaml:"sasl_password"`
+}
+
+type WebhookConfig struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout int               `default:"10" yaml:"timeout"` // unit: s
+}
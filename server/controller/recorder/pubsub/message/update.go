@@ -17,6 +17,7 @@
 package message
 
 import (
+	"reflect"
 	"time"
 
 	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
@@ -97,6 +98,42 @@ func (d *fieldDetail[T]) SetOld(old T) {
 	d.old = old
 }
 
+func (d *fieldDetail[T]) getOldAndNew() (interface{}, interface{}) {
+	return d.old, d.new
+}
+
+type changeableField interface {
+	IsDifferent() bool
+	getOldAndNew() (interface{}, interface{})
+}
+
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// GetChangedFields returns the different fields of a *XFieldsUpdate, key is the field name
+func GetChangedFields(fields interface{}) map[string]FieldChange {
+	result := make(map[string]FieldChange)
+	v := reflect.ValueOf(fields)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return result
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Type().Field(i).IsExported() {
+			continue
+		}
+		field, ok := v.Field(i).Addr().Interface().(changeableField)
+		if !ok || !field.IsDifferent() {
+			continue
+		}
+		old, new := field.getOldAndNew()
+		result[v.Type().Field(i).Name] = FieldChange{Old: old, New: new}
+	}
+	return result
+}
+
 type MySQLData[MT constraint.MySQLModel] struct {
 	new *MT
 	old *MT
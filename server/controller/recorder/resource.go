@@ -20,6 +20,7 @@ import (
 	"context"
 	"sync"
 
+	"github.com/deepflowio/deepflow/server/controller/recorder/changefeed"
 	"github.com/deepflowio/deepflow/server/controller/recorder/config"
 	"github.com/deepflowio/deepflow/server/controller/recorder/db/idmng"
 )
@@ -32,6 +33,7 @@ var (
 type Resource struct {
 	Cleaners   *Cleaners
 	IDManagers *idmng.IDManagers
+	ChangeFeed *changefeed.ChangeFeed
 }
 
 func GetResource() *Resource {
@@ -39,6 +41,7 @@ func GetResource() *Resource {
 		resource = &Resource{
 			Cleaners:   GetCleaners(),
 			IDManagers: idmng.GetIDManagers(),
+			ChangeFeed: changefeed.GetChangeFeed(),
 		}
 	})
 	return resource
@@ -47,5 +50,6 @@ func GetResource() *Resource {
 func (r *Resource) Init(ctx context.Context, cfg config.RecorderConfig) *Resource {
 	r.Cleaners.Init(ctx, cfg)
 	r.IDManagers.Init(ctx, cfg)
+	r.ChangeFeed.Init(ctx, cfg)
 	return r
 }
@@ -37,6 +37,8 @@ var PodGroupTypeMap = map[int]uint32{
 	POD_GROUP_DAEMON_SET:            uint32(trident.AutoServiceType_AUTO_SERVICE_TYPE_POD_GROUP_DAEMON_SET),
 	POD_GROUP_REPLICASET_CONTROLLER: uint32(trident.AutoServiceType_AUTO_SERVICE_TYPE_POD_GROUP_REPLICASET_CONTROLLER),
 	POD_GROUP_CLONESET:              uint32(trident.AutoServiceType_AUTO_SERVICE_TYPE_POD_GROUP_CLONESET),
+	POD_GROUP_JOB:                   uint32(trident.AutoServiceType_AUTO_SERVICE_TYPE_POD_GROUP_JOB),
+	POD_GROUP_CRONJOB:               uint32(trident.AutoServiceType_AUTO_SERVICE_TYPE_POD_GROUP_CRONJOB),
 }
 
 type TypeIDData struct {
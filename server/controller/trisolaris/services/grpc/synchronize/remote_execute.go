@@ -31,14 +31,13 @@ import (
 
 func (e *VTapEvent) RemoteExecute(stream api.Synchronizer_RemoteExecuteServer) error {
 	key := ""
+	manager := &service.CMDManager{}
 	defer func() {
-		if _, ok := service.AgentRemoteExecMap[key]; ok {
-			delete(service.AgentRemoteExecMap, key)
+		if service.RemoveSteamFromManager(key, manager) {
 			log.Infof("delete agent(key:%s) in manager", key)
 		}
 	}()
 
-	manager := &service.CMDManager{}
 	initDone := make(chan struct{})
 
 	ctx, cancel := context.WithCancel(stream.Context())
@@ -68,7 +67,7 @@ func (e *VTapEvent) RemoteExecute(stream api.Synchronizer_RemoteExecuteServer) e
 					continue
 				}
 				key = resp.AgentId.GetIp() + "-" + resp.AgentId.GetMac()
-				if _, ok := service.AgentRemoteExecMap[key]; !ok {
+				if _, ok := service.GetAgentRemoteExecManager(key); !ok {
 					requestID := uint64(0)
 					if resp.RequestId != nil {
 						requestID = *resp.RequestId
@@ -122,7 +121,7 @@ func (e *VTapEvent) RemoteExecute(stream api.Synchronizer_RemoteExecuteServer) e
 
 func handleResponse(resp *trident.RemoteExecResponse) {
 	key := resp.AgentId.GetIp() + "-" + resp.AgentId.GetMac()
-	manager, ok := service.AgentRemoteExecMap[key]
+	manager, ok := service.GetAgentRemoteExecManager(key)
 	if !ok {
 		log.Errorf("agent(key: %s) remote exec map not found", key)
 		return
@@ -20,10 +20,13 @@ import (
 	"strconv"
 	"time"
 
+	logging "github.com/op/go-logging"
+
 	"github.com/deepflowio/deepflow/server/ingester/app_log/config"
 	"github.com/deepflowio/deepflow/server/ingester/app_log/dbwriter"
 	"github.com/deepflowio/deepflow/server/ingester/app_log/decoder"
 	dropletqueue "github.com/deepflowio/deepflow/server/ingester/droplet/queue"
+	"github.com/deepflowio/deepflow/server/ingester/exporters"
 	"github.com/deepflowio/deepflow/server/ingester/ingesterctl"
 	"github.com/deepflowio/deepflow/server/ingester/pkg/ckwriter"
 	"github.com/deepflowio/deepflow/server/libs/datatype"
@@ -33,6 +36,8 @@ import (
 	"github.com/deepflowio/deepflow/server/libs/receiver"
 )
 
+var log = logging.MustGetLogger("app_log")
+
 type ApplicationLogger struct {
 	Config      *config.Config
 	Ckwriter    *ckwriter.CKWriter
@@ -51,6 +56,7 @@ func NewApplicationLogger(
 	config *config.Config,
 	recv *receiver.Receiver,
 	platformDataManager *grpc.PlatformDataManager,
+	exporters *exporters.Exporters,
 ) (*ApplicationLogger, error) {
 	manager := dropletqueue.NewManager(ingesterctl.INGESTERCTL_APPLICATION_LOG_QUEUE)
 
@@ -58,15 +64,15 @@ func NewApplicationLogger(
 	if err != nil {
 		return nil, err
 	}
-	sysLogger, err := NewLogger(datatype.MESSAGE_TYPE_SYSLOG, config, manager, recv, platformDataManager, ckwriter)
+	sysLogger, err := NewLogger(datatype.MESSAGE_TYPE_SYSLOG, config, manager, recv, platformDataManager, ckwriter, exporters, 0*config.DecoderQueueCount)
 	if err != nil {
 		return nil, err
 	}
-	agentLogger, err := NewLogger(datatype.MESSAGE_TYPE_AGENT_LOG, config, manager, recv, platformDataManager, ckwriter)
+	agentLogger, err := NewLogger(datatype.MESSAGE_TYPE_AGENT_LOG, config, manager, recv, platformDataManager, ckwriter, exporters, 1*config.DecoderQueueCount)
 	if err != nil {
 		return nil, err
 	}
-	appLogger, err := NewLogger(datatype.MESSAGE_TYPE_APPLICATION_LOG, config, manager, recv, platformDataManager, ckwriter)
+	appLogger, err := NewLogger(datatype.MESSAGE_TYPE_APPLICATION_LOG, config, manager, recv, platformDataManager, ckwriter, exporters, 2*config.DecoderQueueCount)
 	if err != nil {
 		return nil, err
 	}
@@ -102,6 +108,8 @@ func NewLogger(
 	recv *receiver.Receiver,
 	platformDataManager *grpc.PlatformDataManager,
 	ckwriter *ckwriter.CKWriter,
+	exporters *exporters.Exporters,
+	exporterIndexBase int, // all loggers export to the same data source, so each decoder needs a distinct exporter index
 ) (*Logger, error) {
 
 	queueCount := config.DecoderQueueCount
@@ -125,12 +133,20 @@ func NewLogger(
 		if err != nil {
 			return nil, err
 		}
+		exporterIndex := exporterIndexBase + i
+		decoderExporters := exporters
+		if decoderExporters != nil && exporterIndex >= queue.MAX_QUEUE_COUNT {
+			log.Warningf("application log (%s-%d) exporter index %d exceeds %d, exporting is disabled", msgType.String(), i, exporterIndex, queue.MAX_QUEUE_COUNT)
+			decoderExporters = nil
+		}
 		decoders[i] = decoder.NewDecoder(
 			i,
 			msgType,
 			queue.QueueReader(decodeQueues.FixedMultiQueue[i]),
 			logWriter,
 			platformDatas[i],
+			decoderExporters,
+			exporterIndex,
 			config,
 		)
 	}
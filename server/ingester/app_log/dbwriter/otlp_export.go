@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbwriter
+
+import (
+	"encoding/hex"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	exportercommon "github.com/deepflowio/deepflow/server/ingester/exporters/common"
+	"github.com/deepflowio/deepflow/server/ingester/exporters/config"
+	utag "github.com/deepflowio/deepflow/server/ingester/exporters/universal_tag"
+)
+
+// the severity number of application log is the same as the decoder's 'SEVERITY_XXX'
+var otlpSeverityNumbers = []struct {
+	number plog.SeverityNumber
+	text   string
+}{
+	2: {plog.SeverityNumberFatal, "FATAL"},
+	3: {plog.SeverityNumberError, "ERROR"},
+	4: {plog.SeverityNumberWarn, "WARN"},
+	5: {plog.SeverityNumberInfo, "INFO"},
+	6: {plog.SeverityNumberDebug, "DEBUG"},
+	7: {plog.SeverityNumberTrace, "TRACE"},
+}
+
+func (l *ApplicationLogStore) EncodeToOtlp(utags *utag.UniversalTagsManager, dataTypeBits uint64) interface{} {
+	logsSlice := plog.NewResourceLogsSlice()
+	resLogs := logsSlice.AppendEmpty()
+	resAttrs := resLogs.Resource().Attributes()
+	exportercommon.PutStrWithoutEmpty(resAttrs, "service.name", l.AppService)
+	if dataTypeBits&config.UNIVERSAL_TAG != 0 {
+		exportercommon.PutUniversalTags(resAttrs, l.QueryUniversalTags(utags), "")
+	}
+	if dataTypeBits&config.K8S_LABEL != 0 && l.PodID != 0 {
+		exportercommon.PutK8sLabels(resAttrs, utags.QueryCustomK8sLabels(l.OrgId, l.PodID), "")
+	}
+
+	record := resLogs.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	record.SetTimestamp(pcommon.Timestamp(time.Duration(l.Timestamp) * time.Microsecond))
+	record.SetObservedTimestamp(pcommon.Timestamp(time.Duration(l.Time) * time.Second))
+	if int(l.SeverityNumber) < len(otlpSeverityNumbers) && otlpSeverityNumbers[l.SeverityNumber].text != "" {
+		record.SetSeverityNumber(otlpSeverityNumbers[l.SeverityNumber].number)
+		record.SetSeverityText(otlpSeverityNumbers[l.SeverityNumber].text)
+	}
+	record.Body().SetStr(l.Body)
+	record.SetFlags(plog.LogRecordFlags(l.TraceFlags))
+
+	attrs := record.Attributes()
+	exportercommon.PutStrWithoutEmpty(attrs, "df.log._type", l.Type)
+	if traceID, err := hex.DecodeString(l.TraceID); err == nil && len(traceID) == 16 {
+		id := [16]byte{}
+		copy(id[:], traceID)
+		record.SetTraceID(pcommon.TraceID(id))
+	} else {
+		exportercommon.PutStrWithoutEmpty(attrs, "df.log.trace_id", l.TraceID)
+	}
+	if spanID, err := hex.DecodeString(l.SpanID); err == nil && len(spanID) == 8 {
+		id := [8]byte{}
+		copy(id[:], spanID)
+		record.SetSpanID(pcommon.SpanID(id))
+	} else {
+		exportercommon.PutStrWithoutEmpty(attrs, "df.log.span_id", l.SpanID)
+	}
+	if dataTypeBits&config.NATIVE_TAG != 0 {
+		for i := range l.AttributeNames {
+			if i < len(l.AttributeValues) {
+				exportercommon.PutStrWithoutEmpty(attrs, l.AttributeNames[i], l.AttributeValues[i])
+			}
+		}
+	}
+	if dataTypeBits&config.METRICS != 0 {
+		for i := range l.MetricsNames {
+			if i < len(l.MetricsValues) {
+				attrs.PutDouble(l.MetricsNames[i], l.MetricsValues[i])
+			}
+		}
+	}
+
+	return logsSlice
+}
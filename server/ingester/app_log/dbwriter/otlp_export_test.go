@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbwriter
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/deepflowio/deepflow/server/ingester/exporters/config"
+	utag "github.com/deepflowio/deepflow/server/ingester/exporters/universal_tag"
+)
+
+func encodeTestLog(t *testing.T, l *ApplicationLogStore, dataTypeBits uint64) (pcommon.Map, plog.LogRecord) {
+	logsSlice, ok := l.EncodeToOtlp(&utag.UniversalTagsManager{}, dataTypeBits).(plog.ResourceLogsSlice)
+	if !ok || logsSlice.Len() != 1 {
+		t.Fatalf("expect one resource logs")
+	}
+	resLogs := logsSlice.At(0)
+	if resLogs.ScopeLogs().Len() != 1 || resLogs.ScopeLogs().At(0).LogRecords().Len() != 1 {
+		t.Fatalf("expect one log record")
+	}
+	return resLogs.Resource().Attributes(), resLogs.ScopeLogs().At(0).LogRecords().At(0)
+}
+
+func TestEncodeToOtlp(t *testing.T) {
+	l := &ApplicationLogStore{
+		Time:            1700000001,
+		Timestamp:       1700000000123456,
+		Type:            LOG_TYPE_USER,
+		TraceID:         "0102030405060708090a0b0c0d0e0f10",
+		SpanID:          "0102030405060708",
+		TraceFlags:      1,
+		SeverityNumber:  3,
+		Body:            "connection refused",
+		AppService:      "svc-a",
+		AttributeNames:  []string{"host", "empty"},
+		AttributeValues: []string{"node-1", ""},
+		MetricsNames:    []string{"latency"},
+		MetricsValues:   []float64{1.5},
+	}
+	resAttrs, record := encodeTestLog(t, l, config.NATIVE_TAG|config.METRICS)
+
+	if service, ok := resAttrs.Get("service.name"); !ok || service.Str() != "svc-a" {
+		t.Errorf("resource should have service.name, got %v", resAttrs.AsRaw())
+	}
+	if record.Timestamp() != pcommon.Timestamp(1700000000123456*time.Microsecond) {
+		t.Errorf("wrong timestamp %d", record.Timestamp())
+	}
+	if record.ObservedTimestamp() != pcommon.Timestamp(1700000001*time.Second) {
+		t.Errorf("wrong observed timestamp %d", record.ObservedTimestamp())
+	}
+	if record.SeverityNumber() != plog.SeverityNumberError || record.SeverityText() != "ERROR" {
+		t.Errorf("wrong severity %s %s", record.SeverityNumber(), record.SeverityText())
+	}
+	if record.Body().Str() != "connection refused" || record.Flags() != plog.LogRecordFlags(1) {
+		t.Errorf("wrong body %s or flags %d", record.Body().Str(), record.Flags())
+	}
+	if record.TraceID().String() != l.TraceID || record.SpanID().String() != l.SpanID {
+		t.Errorf("wrong trace id %s or span id %s", record.TraceID().String(), record.SpanID().String())
+	}
+
+	expected := map[string]interface{}{
+		"df.log._type": LOG_TYPE_USER,
+		"host":         "node-1",
+		"latency":      1.5,
+	}
+	attrs := record.Attributes().AsRaw()
+	if len(attrs) != len(expected) {
+		t.Errorf("expect attributes %v, got %v", expected, attrs)
+	}
+	for k, v := range expected {
+		if attrs[k] != v {
+			t.Errorf("attribute %s should be %v, got %v", k, v, attrs[k])
+		}
+	}
+}
+
+func TestEncodeToOtlpInvalidIDs(t *testing.T) {
+	l := &ApplicationLogStore{
+		TraceID:        "not-a-trace-id",
+		SpanID:         "0102",
+		SeverityNumber: 0,
+		AttributeNames: []string{"host"},
+		MetricsNames:   []string{"latency"},
+		MetricsValues:  []float64{1.5},
+	}
+	// attributes and metrics are not exported without NATIVE_TAG and METRICS
+	resAttrs, record := encodeTestLog(t, l, 0)
+
+	if resAttrs.Len() != 0 {
+		t.Errorf("resource should have no attributes, got %v", resAttrs.AsRaw())
+	}
+	if !record.TraceID().IsEmpty() || !record.SpanID().IsEmpty() {
+		t.Errorf("invalid trace id and span id should not be set")
+	}
+	if record.SeverityNumber() != plog.SeverityNumberUnspecified || record.SeverityText() != "" {
+		t.Errorf("unknown severity should not be set, got %s %s", record.SeverityNumber(), record.SeverityText())
+	}
+	expected := map[string]interface{}{
+		"df.log.trace_id": "not-a-trace-id",
+		"df.log.span_id":  "0102",
+	}
+	attrs := record.Attributes().AsRaw()
+	if len(attrs) != len(expected) {
+		t.Errorf("expect attributes %v, got %v", expected, attrs)
+	}
+	for k, v := range expected {
+		if attrs[k] != v {
+			t.Errorf("attribute %s should be %v, got %v", k, v, attrs[k])
+		}
+	}
+}
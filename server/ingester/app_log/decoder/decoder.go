@@ -30,6 +30,9 @@ import (
 	"github.com/deepflowio/deepflow/server/ingester/app_log/config"
 	"github.com/deepflowio/deepflow/server/ingester/app_log/dbwriter"
 	ingestercommon "github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/exporters"
+	exportercommon "github.com/deepflowio/deepflow/server/ingester/exporters/common"
+	exporterconfig "github.com/deepflowio/deepflow/server/ingester/exporters/config"
 	"github.com/deepflowio/deepflow/server/libs/ckdb"
 	"github.com/deepflowio/deepflow/server/libs/codec"
 	"github.com/deepflowio/deepflow/server/libs/datatype"
@@ -115,6 +118,8 @@ type Decoder struct {
 	platformData      *grpc.PlatformInfoTable
 	inQueue           queue.QueueReader
 	logWriter         *dbwriter.AppLogWriter
+	exporters         *exporters.Exporters
+	exporterIndex     int
 	debugEnabled      bool
 	config            *config.Config
 	appLogEntrysCache []AppLogEntry
@@ -130,6 +135,8 @@ func NewDecoder(
 	inQueue queue.QueueReader,
 	logWriter *dbwriter.AppLogWriter,
 	platformData *grpc.PlatformInfoTable,
+	exporters *exporters.Exporters,
+	exporterIndex int,
 	config *config.Config,
 ) *Decoder {
 	return &Decoder{
@@ -139,6 +146,8 @@ func NewDecoder(
 		inQueue:           inQueue,
 		debugEnabled:      log.IsEnabledFor(logging.DEBUG),
 		logWriter:         logWriter,
+		exporters:         exporters,
+		exporterIndex:     exporterIndex,
 		appLogEntrysCache: make([]AppLogEntry, 0),
 		config:            config,
 		counter:           &Counter{},
@@ -162,6 +171,7 @@ func (d *Decoder) Run() {
 		n := d.inQueue.Gets(buffer)
 		for i := 0; i < n; i++ {
 			if buffer[i] == nil {
+				d.export(nil)
 				continue
 			}
 			d.counter.InCount++
@@ -183,6 +193,13 @@ func (d *Decoder) Run() {
 	}
 }
 
+func (d *Decoder) export(item exportercommon.ExportItem) {
+	if d.exporters == nil {
+		return
+	}
+	d.exporters.Put(uint32(exporterconfig.APPLICATION_LOG), d.exporterIndex, item)
+}
+
 func (d *Decoder) handleAgentLog(agentId uint16, decoder *codec.SimpleDecoder) {
 	for !decoder.IsEnd() {
 		bytes := decoder.ReadBytes()
@@ -300,6 +317,7 @@ func (d *Decoder) WriteAgentLog(agentId uint16, bs []byte) error {
 	s.AttributeNames = append(s.AttributeNames, "module")
 	s.AttributeValues = append(s.AttributeValues, string(columns[4]))
 
+	d.export(s)
 	d.logWriter.Write(s)
 	return nil
 }
@@ -447,6 +465,7 @@ func (d *Decoder) WriteAppLog(agentId uint16, l *AppLogEntry) error {
 	s.AutoInstanceID, s.AutoInstanceType = ingestercommon.GetAutoInstance(s.PodID, 0, s.PodNodeID, s.L3DeviceID, uint8(s.L3DeviceType), s.L3EpcID)
 	s.AutoServiceID, s.AutoServiceType = ingestercommon.GetAutoService(s.ServiceID, s.PodGroupID, 0, s.PodNodeID, s.L3DeviceID, uint8(s.L3DeviceType), podGroupType, s.L3EpcID)
 
+	d.export(s)
 	d.logWriter.Write(s)
 	return nil
 }
@@ -213,6 +213,18 @@ var ColumnAdd65 = []*ColumnAdds{
 		ColumnType:   ckdb.UInt16,
 		DefaultValue: "1",
 	},
+	{
+		Dbs:         []string{"flow_log"},
+		Tables:      []string{"l4_flow_log", "l4_flow_log_local", "l7_flow_log", "l7_flow_log_local"},
+		ColumnNames: []string{"geo_country_0", "geo_country_1", "geo_region_0", "geo_region_1", "geo_city_0", "geo_city_1", "geo_org_0", "geo_org_1"},
+		ColumnType:  ckdb.LowCardinalityString,
+	},
+	{
+		Dbs:         []string{"flow_log"},
+		Tables:      []string{"l4_flow_log", "l4_flow_log_local", "l7_flow_log", "l7_flow_log_local"},
+		ColumnNames: []string{"geo_asn_0", "geo_asn_1"},
+		ColumnType:  ckdb.UInt32,
+	},
 }
 
 var TableRenames65 = []*TableRename{
@@ -30,6 +30,7 @@ import (
 
 	"github.com/deepflowio/deepflow/server/ingester/common"
 	"github.com/deepflowio/deepflow/server/libs/ckdb"
+	"github.com/deepflowio/deepflow/server/libs/receiver"
 )
 
 var log = logging.MustGetLogger("config")
@@ -55,6 +56,9 @@ const (
 	DefaultStatsInterval            = 10      // s
 	DefaultFlowTagCacheFlushTimeout = 1800    // s
 	DefaultFlowTagCacheMaxSize      = 1 << 18 // 256k
+	DefaultCKWriterSpillDir         = "/var/lib/deepflow-server/ckwriter-spill"
+	DefaultCKWriterSpillMaxSize     = 1024 // MB
+	DefaultTLSReloadInterval        = 60   // s
 	IndexTypeHash                   = "hash"
 	IndexTypeIncremetalIdLocation   = "incremental-id"
 	FormatHex                       = "hex"
@@ -109,6 +113,40 @@ type Auth struct {
 	Password string `yaml:"password"`
 }
 
+// when writing to clickhouse fails, the batches are persisted to disk and replayed after clickhouse recovers
+type CKWriterSpill struct {
+	Enabled bool     `yaml:"enabled"`
+	Dir     string   `yaml:"dir"`
+	MaxSize int      `yaml:"max-size"`    // MB, for each table
+	Tables  []string `yaml:"tables,flow"` // as 'flow_log.l7_flow_log', if empty, all tables are enabled
+}
+
+func (s *CKWriterSpill) IsTableEnabled(database, table string) bool {
+	if !s.Enabled {
+		return false
+	}
+	if len(s.Tables) == 0 {
+		return true
+	}
+	name := database + "." + table
+	for _, t := range s.Tables {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TLS of the tcp receiver, client certificates are verified if ClientCAFile is set
+type ReceiverTLS struct {
+	Enabled        bool   `yaml:"enabled"`
+	CertFile       string `yaml:"cert-file"`
+	KeyFile        string `yaml:"key-file"`
+	ClientCAFile   string `yaml:"client-ca-file"`
+	IdentityMode   string `yaml:"identity-mode"`   // shared or vtap-id
+	ReloadInterval int    `yaml:"reload-interval"` // s
+}
+
 type CKWriterConfig struct {
 	QueueCount   int `yaml:"queue-count"`
 	QueueSize    int `yaml:"queue-size"`
@@ -143,12 +181,14 @@ type Config struct {
 	CKDiskMonitor            CKDiskMonitor   `yaml:"ck-disk-monitor"`
 	ColdStorage              CKDBColdStorage `yaml:"ckdb-cold-storage"`
 	ckdbColdStorages         map[string]*ckdb.ColdStorage
-	NodeIP                   string `yaml:"node-ip"`
-	GrpcBufferSize           int    `yaml:"grpc-buffer-size"`
-	ServiceLabelerLruCap     int    `yaml:"service-labeler-lru-cap"`
-	StatsInterval            int    `yaml:"stats-interval"`
-	FlowTagCacheFlushTimeout uint32 `yaml:"flow-tag-cache-flush-timeout"`
-	FlowTagCacheMaxSize      uint32 `yaml:"flow-tag-cache-max-size"`
+	NodeIP                   string        `yaml:"node-ip"`
+	GrpcBufferSize           int           `yaml:"grpc-buffer-size"`
+	ServiceLabelerLruCap     int           `yaml:"service-labeler-lru-cap"`
+	StatsInterval            int           `yaml:"stats-interval"`
+	FlowTagCacheFlushTimeout uint32        `yaml:"flow-tag-cache-flush-timeout"`
+	FlowTagCacheMaxSize      uint32        `yaml:"flow-tag-cache-max-size"`
+	CKWriterSpill            CKWriterSpill `yaml:"ckwriter-spill"`
+	ReceiverTLS              ReceiverTLS   `yaml:"receiver-tls"`
 	LogFile                  string
 	LogLevel                 string
 	MyNodeName               string
@@ -224,6 +264,24 @@ func (c *Config) Validate() error {
 	if c.FlowTagCacheFlushTimeout == 0 {
 		c.FlowTagCacheFlushTimeout = DefaultFlowTagCacheFlushTimeout
 	}
+	if c.CKWriterSpill.Dir == "" {
+		c.CKWriterSpill.Dir = DefaultCKWriterSpillDir
+	}
+	if c.CKWriterSpill.MaxSize <= 0 {
+		c.CKWriterSpill.MaxSize = DefaultCKWriterSpillMaxSize
+	}
+	if c.ReceiverTLS.Enabled && (c.ReceiverTLS.CertFile == "" || c.ReceiverTLS.KeyFile == "") {
+		return errors.New("'cert-file' and 'key-file' of 'receiver-tls' are required when it is enabled")
+	}
+	if c.ReceiverTLS.IdentityMode == "" {
+		c.ReceiverTLS.IdentityMode = receiver.TLS_IDENTITY_MODE_SHARED
+	}
+	if c.ReceiverTLS.Enabled && c.ReceiverTLS.IdentityMode == receiver.TLS_IDENTITY_MODE_VTAP_ID && c.ReceiverTLS.ClientCAFile == "" {
+		return errors.New("'client-ca-file' of 'receiver-tls' is required when 'identity-mode' is vtap-id")
+	}
+	if c.ReceiverTLS.ReloadInterval <= 0 {
+		c.ReceiverTLS.ReloadInterval = DefaultTLSReloadInterval
+	}
 
 	level := strings.ToLower(c.LogLevel)
 	c.LogLevel = "info"
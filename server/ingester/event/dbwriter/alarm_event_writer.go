@@ -17,14 +17,21 @@
 package dbwriter
 
 import (
+	"fmt"
+	"reflect"
 	"sync/atomic"
+	"unsafe"
 
 	basecommon "github.com/deepflowio/deepflow/server/ingester/common"
 	"github.com/deepflowio/deepflow/server/ingester/event/common"
 	"github.com/deepflowio/deepflow/server/ingester/event/config"
+	exportercommon "github.com/deepflowio/deepflow/server/ingester/exporters/common"
+	exporterconfig "github.com/deepflowio/deepflow/server/ingester/exporters/config"
+	utag "github.com/deepflowio/deepflow/server/ingester/exporters/universal_tag"
 	"github.com/deepflowio/deepflow/server/ingester/pkg/ckwriter"
 	"github.com/deepflowio/deepflow/server/libs/ckdb"
 	"github.com/deepflowio/deepflow/server/libs/pool"
+	"github.com/deepflowio/deepflow/server/libs/utils"
 )
 
 var alarmEventPool = pool.NewLockFreePool(func() interface{} {
@@ -32,11 +39,13 @@ var alarmEventPool = pool.NewLockFreePool(func() interface{} {
 })
 
 func AcquireAlarmEventStore() *AlarmEventStore {
-	return alarmEventPool.Get().(*AlarmEventStore)
+	e := alarmEventPool.Get().(*AlarmEventStore)
+	e.Reset()
+	return e
 }
 
 func ReleaseAlarmEventStore(e *AlarmEventStore) {
-	if e == nil {
+	if e == nil || e.SubReferenceCount() {
 		return
 	}
 	*e = AlarmEventStore{}
@@ -44,37 +53,39 @@ func ReleaseAlarmEventStore(e *AlarmEventStore) {
 }
 
 type AlarmEventStore struct {
-	Time   uint32
+	pool.ReferenceCount
+
+	Time   uint32 `json:"time" category:"$tag" sub:"flow_info"`
 	_id    uint64
-	Lcuuid string
-	User   string
-	UserId uint32
-
-	PolicyId                uint32
-	PolicyName              string
-	PolicyLevel             uint32
-	PolicyAppType           uint32
-	PolicySubType           uint32
-	PolicyContrastType      uint32
-	PolicyDataLevel         string
-	PolicyTargetUid         string
-	PolicyTargetName        string
-	PolicyGoTo              string
-	PolicyTargetField       string
-	PolicyEndpoints         string
-	TriggerCondition        string
-	TriggerValue            float64
-	ValueUnit               string
-	EventLevel              uint32
-	AlarmTarget             string
-	RegionId                uint16
-	PolicyQueryUrl          string
-	PolicyQueryConditions   string
-	PolicyThresholdCritical string
-	PolicyThresholdError    string
-	PolicyThresholdWarning  string
-	OrgId                   uint16
-	TeamID                  uint16
+	Lcuuid string `json:"lccuid" category:"$tag" sub:"alarm_info"`
+	User   string `json:"user" category:"$tag" sub:"alarm_info"`
+	UserId uint32 `json:"user_id" category:"$tag"`
+
+	PolicyId                uint32  `json:"policy_id" category:"$tag" sub:"alarm_info"`
+	PolicyName              string  `json:"policy_name" category:"$tag" sub:"alarm_info"`
+	PolicyLevel             uint32  `json:"policy_level" category:"$tag" sub:"alarm_info"`
+	PolicyAppType           uint32  `json:"policy_app_type" category:"$tag" sub:"alarm_info"`
+	PolicySubType           uint32  `json:"policy_sub_type" category:"$tag" sub:"alarm_info"`
+	PolicyContrastType      uint32  `json:"policy_contrast_type" category:"$tag" sub:"alarm_info"`
+	PolicyDataLevel         string  `json:"policy_data_level" category:"$tag" sub:"alarm_info"`
+	PolicyTargetUid         string  `json:"policy_target_uid" category:"$tag" sub:"alarm_info"`
+	PolicyTargetName        string  `json:"policy_target_name" category:"$tag" sub:"alarm_info"`
+	PolicyGoTo              string  `json:"policy_go_to" category:"$tag" sub:"alarm_info"`
+	PolicyTargetField       string  `json:"policy_target_field" category:"$tag" sub:"alarm_info"`
+	PolicyEndpoints         string  `json:"policy_endpoints" category:"$tag" sub:"alarm_info"`
+	TriggerCondition        string  `json:"trigger_condition" category:"$tag" sub:"alarm_info"`
+	TriggerValue            float64 `json:"trigger_value" category:"$metrics"`
+	ValueUnit               string  `json:"value_unit" category:"$tag" sub:"alarm_info"`
+	EventLevel              uint32  `json:"event_level" category:"$tag" sub:"alarm_info"`
+	AlarmTarget             string  `json:"alarm_target" category:"$tag" sub:"alarm_info"`
+	RegionId                uint16  `json:"region_id" category:"$tag" sub:"universal_tag"`
+	PolicyQueryUrl          string  `json:"policy_query_url" category:"$tag" sub:"alarm_info"`
+	PolicyQueryConditions   string  `json:"policy_query_conditions" category:"$tag" sub:"alarm_info"`
+	PolicyThresholdCritical string  `json:"policy_threshold_critical" category:"$tag" sub:"alarm_info"`
+	PolicyThresholdError    string  `json:"policy_threshold_error" category:"$tag" sub:"alarm_info"`
+	PolicyThresholdWarning  string  `json:"policy_threshold_warning" category:"$tag" sub:"alarm_info"`
+	OrgId                   uint16  `json:"org_id" category:"$tag"`
+	TeamID                  uint16  `json:"team_id" category:"$tag"`
 }
 
 func (e *AlarmEventStore) SetId(time, analyzerID uint32) {
@@ -160,6 +171,38 @@ func (e *AlarmEventStore) OrgID() uint16 {
 	return e.OrgId
 }
 
+func (e *AlarmEventStore) DataSource() uint32 {
+	return uint32(exporterconfig.ALARM_EVENT)
+}
+
+func (e *AlarmEventStore) EncodeTo(protocol exporterconfig.ExportProtocol, utags *utag.UniversalTagsManager, cfg *exporterconfig.ExporterCfg) (interface{}, error) {
+	switch protocol {
+	case exporterconfig.PROTOCOL_KAFKA, exporterconfig.PROTOCOL_WEBHOOK, exporterconfig.PROTOCOL_FILE:
+		tags := e.QueryUniversalTags(utags)
+		return exportercommon.EncodeToJson(e, int(e.DataSource()), cfg, tags, tags, nil, nil), nil
+	default:
+		return nil, fmt.Errorf("alarm event unsupport export to %s", protocol)
+	}
+}
+
+// alarm events only carry the region
+func (e *AlarmEventStore) QueryUniversalTags(utags *utag.UniversalTagsManager) *utag.UniversalTags {
+	return utags.QueryUniversalTags(e.OrgId,
+		e.RegionId, 0, 0, 0, 0, 0, 0,
+		0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0,
+		true, 0, nil,
+	)
+}
+
+func (e *AlarmEventStore) GetFieldValueByOffsetAndKind(offset uintptr, kind reflect.Kind, dataType utils.DataType) interface{} {
+	return utils.GetValueByOffsetAndKind(uintptr(unsafe.Pointer(e)), offset, kind, dataType)
+}
+
+func (e *AlarmEventStore) TimestampUs() int64 {
+	return int64(e.Time) * 1000000
+}
+
 func GenAlarmEventCKTable(cluster, storagePolicy string, ttl int, coldStorage *ckdb.ColdStorage) *ckdb.Table {
 	table := common.ALARM_EVENT.TableName()
 	timeKey := "time"
@@ -179,12 +179,12 @@ func (e *EventStore) DataSource() uint32 {
 	if e.HasMetrics {
 		return uint32(config.PERF_EVENT)
 	}
-	return uint32(config.MAX_DATASOURCE_ID)
+	return uint32(config.EVENT)
 }
 
 func (e *EventStore) EncodeTo(protocol config.ExportProtocol, utags *utag.UniversalTagsManager, cfg *config.ExporterCfg) (interface{}, error) {
 	switch protocol {
-	case config.PROTOCOL_KAFKA:
+	case config.PROTOCOL_KAFKA, config.PROTOCOL_WEBHOOK, config.PROTOCOL_FILE:
 		tags := e.QueryUniversalTags(utags)
 		k8sLabels := utags.QueryCustomK8sLabels(e.OrgId, e.PodID)
 		return exportercommon.EncodeToJson(e, int(e.DataSource()), cfg, tags, tags, k8sLabels, k8sLabels), nil
@@ -94,6 +94,7 @@ func NewDecoder(
 		resourceInfoTable = NewResourceInfoTable(controllers, int(config.Base.ControllerPort), config.Base.GrpcBufferSize)
 	}
 	return &Decoder{
+		index:             index,
 		eventType:         eventType,
 		resourceInfoTable: resourceInfoTable,
 		platformData:      platformData,
@@ -421,6 +422,7 @@ func (d *Decoder) handleResourceEvent(event *eventapi.ResourceEvent) {
 		)
 
 	d.counter.OutCount++
+	d.export(s)
 	d.eventWriter.Write(s)
 }
 
@@ -481,5 +483,6 @@ func (d *Decoder) writeAlarmEvent(event *alarm_event.AlarmEvent) {
 	s.OrgId = uint16(event.GetOrgId())
 	s.TeamID = uint16(event.GetTeamId())
 
+	d.export(s)
 	d.eventWriter.WriteAlarmEvent(s)
 }
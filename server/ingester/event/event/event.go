@@ -54,7 +54,7 @@ type Eventor struct {
 
 func NewEvent(config *config.Config, resourceEventQueue *queue.OverwriteQueue, recv *receiver.Receiver, platformDataManager *grpc.PlatformDataManager, exporters *exporters.Exporters) (*Event, error) {
 	manager := dropletqueue.NewManager(ingesterctl.INGESTERCTL_EVENT_QUEUE)
-	resourceEventor, err := NewResouceEventor(resourceEventQueue, config, platformDataManager.GetMasterPlatformInfoTable())
+	resourceEventor, err := NewResouceEventor(resourceEventQueue, config, platformDataManager.GetMasterPlatformInfoTable(), exporters)
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +64,7 @@ func NewEvent(config *config.Config, resourceEventQueue *queue.OverwriteQueue, r
 		return nil, err
 	}
 
-	alarmEventor, err := NewAlarmEventor(config, recv, manager, platformDataManager.GetMasterPlatformInfoTable())
+	alarmEventor, err := NewAlarmEventor(config, recv, manager, platformDataManager.GetMasterPlatformInfoTable(), exporters)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +83,7 @@ func NewEvent(config *config.Config, resourceEventQueue *queue.OverwriteQueue, r
 	}, nil
 }
 
-func NewResouceEventor(eventQueue *queue.OverwriteQueue, config *config.Config, platformTable *grpc.PlatformInfoTable) (*Eventor, error) {
+func NewResouceEventor(eventQueue *queue.OverwriteQueue, config *config.Config, platformTable *grpc.PlatformInfoTable, exporters *exporters.Exporters) (*Eventor, error) {
 	eventWriter, err := dbwriter.NewEventWriter(common.RESOURCE_EVENT, 0, config)
 	if err != nil {
 		return nil, err
@@ -94,7 +94,7 @@ func NewResouceEventor(eventQueue *queue.OverwriteQueue, config *config.Config,
 		queue.QueueReader(eventQueue),
 		eventWriter,
 		platformTable,
-		nil,
+		exporters,
 		config,
 	)
 	return &Eventor{
@@ -103,7 +103,7 @@ func NewResouceEventor(eventQueue *queue.OverwriteQueue, config *config.Config,
 	}, nil
 }
 
-func NewAlarmEventor(config *config.Config, recv *receiver.Receiver, manager *dropletqueue.Manager, platformTable *grpc.PlatformInfoTable) (*Eventor, error) {
+func NewAlarmEventor(config *config.Config, recv *receiver.Receiver, manager *dropletqueue.Manager, platformTable *grpc.PlatformInfoTable, exporters *exporters.Exporters) (*Eventor, error) {
 	eventMsg := datatype.MESSAGE_TYPE_ALARM_EVENT
 	decodeQueues := manager.NewQueues(
 		"1-receive-to-decode-"+eventMsg.String(),
@@ -124,7 +124,7 @@ func NewAlarmEventor(config *config.Config, recv *receiver.Receiver, manager *dr
 		queue.QueueReader(decodeQueues.FixedMultiQueue[0]),
 		eventWriter,
 		platformTable,
-		nil,
+		exporters,
 		config,
 	)
 	return &Eventor{
@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	utag "github.com/deepflowio/deepflow/server/ingester/exporters/universal_tag"
+)
+
+// universal tags exported as OTLP resource attributes, named as 'df.universal_tag.<name><suffix>'
+var otlpUniversalTags = []struct {
+	id   int
+	name string
+}{
+	{utag.Region, "region"},
+	{utag.AZ, "az"},
+	{utag.Host, "host"},
+	{utag.L3Epc, "vpc"},
+	{utag.Subnet, "subnet"},
+	{utag.PodCluster, "pod_cluster"},
+	{utag.PodNS, "pod_ns"},
+	{utag.PodNode, "pod_node"},
+	{utag.PodGroup, "pod_group"},
+	{utag.Pod, "pod"},
+	{utag.Service, "service"},
+	{utag.CHost, "chost"},
+	{utag.Router, "router"},
+	{utag.DhcpGW, "dhcpgw"},
+	{utag.PodService, "pod_service"},
+	{utag.Redis, "redis"},
+	{utag.RDS, "rds"},
+	{utag.LB, "lb"},
+	{utag.NatGW, "natgw"},
+	{utag.AutoInstanceType, "auto_instance_type"},
+	{utag.AutoInstance, "auto_instance"},
+	{utag.AutoServiceType, "auto_service_type"},
+	{utag.AutoService, "auto_service"},
+}
+
+func PutStrWithoutEmpty(attrs pcommon.Map, key, value string) {
+	if value != "" {
+		attrs.PutStr(key, value)
+	}
+}
+
+func PutIntWithoutZero(attrs pcommon.Map, key string, value int64) {
+	if value != 0 {
+		attrs.PutInt(key, value)
+	}
+}
+
+// suffix is '_0'/'_1' for the client/server side of flows, and empty for single-side data
+func PutUniversalTags(attrs pcommon.Map, tags *utag.UniversalTags, suffix string) {
+	if tags == nil {
+		return
+	}
+	for _, t := range otlpUniversalTags {
+		PutStrWithoutEmpty(attrs, newAttrName("df.universal_tag.", t.name, suffix), tags[t.id])
+	}
+}
+
+func PutK8sLabels(attrs pcommon.Map, labels utag.Labels, suffix string) {
+	for name, value := range labels {
+		PutStrWithoutEmpty(attrs, newAttrName("df.custom_tag.k8s.labels.", name, suffix), value)
+	}
+}
+
+func newAttrName(prefix, name, suffix string) string {
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	sb.WriteString(name)
+	sb.WriteString(suffix)
+	return sb.String()
+}
@@ -45,6 +45,15 @@ const (
 	DefaultExportOtherBatchSize = 1024
 	SecurityProtocol            = "SASL_SSL"
 
+	DefaultExportWebhookRetryTimes   = 3
+	DefaultExportWebhookRetryBackoff = 500 // ms
+	DefaultExportWebhookTimeout      = 10  // s
+
+	DefaultExportFileDir              = "/var/log/deepflow-server/exporters"
+	DefaultExportFileRotationSize     = 100 // MB
+	DefaultExportFileRotationInterval = 60  // minute
+	DefaultExportFileMaxBackups       = 168
+
 	CATEGORY_K8S_LABEL = "$k8s.label"
 	CATEGORY_TAG       = "$tag"
 	CATEGORY_METRICS   = "$metrics"
@@ -52,7 +61,7 @@ const (
 	TOPIC_PREFIX = "deepflow."
 )
 
-var DefaultExportCategory = []string{"$service_info", "$tracing_info", "$network_layer", "$flow_info", "$transport_layer", "$application_layer", "$metrics"}
+var DefaultExportCategory = []string{"$service_info", "$tracing_info", "$network_layer", "$flow_info", "$transport_layer", "$application_layer", "$log_info", "$profile_info", "$alarm_info", "$metrics"}
 
 type DataSourceID uint32
 
@@ -70,6 +79,11 @@ const (
 	PERF_EVENT = DataSourceID(flow_metrics.METRICS_TABLE_ID_MAX) + 1 + iota
 	L4_FLOW_LOG
 	L7_FLOW_LOG
+	APPLICATION_LOG
+	EXT_METRICS
+	PROFILE
+	EVENT
+	ALARM_EVENT
 
 	MAX_DATASOURCE_ID
 )
@@ -86,6 +100,11 @@ var dataSourceStrings = []string{
 	PERF_EVENT:         "event.perf_event",
 	L4_FLOW_LOG:        "flow_log.l4_flow_log",
 	L7_FLOW_LOG:        "flow_log.l7_flow_log",
+	APPLICATION_LOG:    "application_log.log",
+	EXT_METRICS:        "ext_metrics.metrics",
+	PROFILE:            "profile.in_process",
+	EVENT:              "event.event",
+	ALARM_EVENT:        "event.alarm_event",
 	MAX_DATASOURCE_ID:  "invalid_datasource",
 }
 
@@ -101,6 +120,11 @@ var dataSourceTopicStrings = []string{
 	PERF_EVENT:         TOPIC_PREFIX + dataSourceStrings[PERF_EVENT],
 	L4_FLOW_LOG:        TOPIC_PREFIX + dataSourceStrings[L4_FLOW_LOG],
 	L7_FLOW_LOG:        TOPIC_PREFIX + dataSourceStrings[L7_FLOW_LOG],
+	APPLICATION_LOG:    TOPIC_PREFIX + dataSourceStrings[APPLICATION_LOG],
+	EXT_METRICS:        TOPIC_PREFIX + dataSourceStrings[EXT_METRICS],
+	PROFILE:            TOPIC_PREFIX + dataSourceStrings[PROFILE],
+	EVENT:              TOPIC_PREFIX + dataSourceStrings[EVENT],
+	ALARM_EVENT:        TOPIC_PREFIX + dataSourceStrings[ALARM_EVENT],
 	MAX_DATASOURCE_ID:  TOPIC_PREFIX + dataSourceStrings[MAX_DATASOURCE_ID],
 }
 
@@ -146,7 +170,8 @@ func (d DataSourceID) TopicString() string {
 
 func (d DataSourceID) IsMap() bool {
 	switch d {
-	case NETWORK_1M, APPLICATION_1M, NETWORK_1S, APPLICATION_1S, PERF_EVENT:
+	case NETWORK_1M, APPLICATION_1M, NETWORK_1S, APPLICATION_1S, PERF_EVENT,
+		APPLICATION_LOG, EXT_METRICS, PROFILE, EVENT, ALARM_EVENT:
 		return false
 	default:
 		return true
@@ -388,6 +413,17 @@ type ExporterCfg struct {
 	// kafka private configuration
 	Sasl  Sasl   `yaml:"sasl"`
 	Topic string `yaml:"topic"`
+
+	// webhook private configuration
+	RetryTimes   *int `yaml:"retry-times"`   // nil: default value, 0: no retry
+	RetryBackoff int  `yaml:"retry-backoff"` // ms, doubled after each retry
+	Timeout      int  `yaml:"timeout"`       // s
+
+	// file private configuration
+	FileDir              string `yaml:"file-dir"`
+	FileRotationSize     int    `yaml:"file-rotation-size"`     // MB
+	FileRotationInterval int    `yaml:"file-rotation-interval"` // minute
+	FileMaxBackups       int    `yaml:"file-max-backups"`
 }
 
 type Sasl struct {
@@ -417,6 +453,8 @@ const (
 	PROTOCOL_OTLP ExportProtocol = iota
 	PROTOCOL_PROMETHEUS
 	PROTOCOL_KAFKA
+	PROTOCOL_WEBHOOK
+	PROTOCOL_FILE
 
 	MAX_PROTOCOL_ID
 )
@@ -425,6 +463,8 @@ var protocolToStrings = []string{
 	PROTOCOL_OTLP:       "opentelemetry",
 	PROTOCOL_PROMETHEUS: "prometheus",
 	PROTOCOL_KAFKA:      "kafka",
+	PROTOCOL_WEBHOOK:    "webhook",
+	PROTOCOL_FILE:       "file",
 	MAX_PROTOCOL_ID:     "unknown",
 }
 
@@ -476,6 +516,31 @@ func (cfg *ExporterCfg) Validate() error {
 	}
 	cfg.Sasl.Validate()
 
+	if cfg.RetryTimes == nil {
+		retryTimes := DefaultExportWebhookRetryTimes
+		cfg.RetryTimes = &retryTimes
+	} else if *cfg.RetryTimes < 0 {
+		*cfg.RetryTimes = 0
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = DefaultExportWebhookRetryBackoff
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultExportWebhookTimeout
+	}
+	if cfg.FileDir == "" {
+		cfg.FileDir = DefaultExportFileDir
+	}
+	if cfg.FileRotationSize == 0 {
+		cfg.FileRotationSize = DefaultExportFileRotationSize
+	}
+	if cfg.FileRotationInterval == 0 {
+		cfg.FileRotationInterval = DefaultExportFileRotationInterval
+	}
+	if cfg.FileMaxBackups == 0 {
+		cfg.FileMaxBackups = DefaultExportFileMaxBackups
+	}
+
 	return nil
 }
 
@@ -526,8 +591,11 @@ const (
 	SERVICE_INFO
 	TRACING_INFO
 	CAPTURE_INFO
-	EVENT_INFO // perf_event only
+	EVENT_INFO // perf_event/event only
 	DATA_LINK_LAYER
+	LOG_INFO     // application_log only
+	PROFILE_INFO // profile only
+	ALARM_INFO   // alarm_event only
 
 	// metrics
 	L3_THROUGHPUT // network*/l4_flow_log
@@ -540,7 +608,7 @@ const (
 	DELAY         // all network/application/flow_log
 
 	K8S_LABEL
-	TAG     = FLOW_INFO | UNIVERSAL_TAG | CUSTOM_TAG | NATIVE_TAG | NETWORK_LAYER | TUNNEL_INFO | TRANSPORT_LAYER | APPLICATION_LAYER | SERVICE_INFO | TRACING_INFO | CAPTURE_INFO | DATA_LINK_LAYER
+	TAG     = FLOW_INFO | UNIVERSAL_TAG | CUSTOM_TAG | NATIVE_TAG | NETWORK_LAYER | TUNNEL_INFO | TRANSPORT_LAYER | APPLICATION_LAYER | SERVICE_INFO | TRACING_INFO | CAPTURE_INFO | DATA_LINK_LAYER | LOG_INFO | PROFILE_INFO | ALARM_INFO
 	METRICS = L3_THROUGHPUT | L4_THROUGHPUT | TCP_SLOW | TCP_ERROR | APPLICATION | THROUGHPUT | ERROR | DELAY
 )
 
@@ -558,6 +626,9 @@ var categoryStringMap = map[string]uint64{
 	"capture_info":      CAPTURE_INFO,
 	"event_info":        EVENT_INFO,
 	"data_link_layer":   DATA_LINK_LAYER,
+	"log_info":          LOG_INFO,
+	"profile_info":      PROFILE_INFO,
+	"alarm_info":        ALARM_INFO,
 	CATEGORY_K8S_LABEL:  K8S_LABEL,
 
 	CATEGORY_METRICS: METRICS, // contains the following sucategories
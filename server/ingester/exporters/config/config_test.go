@@ -49,3 +49,25 @@ func TestConfig(t *testing.T) {
 		t.Logf("yaml unmarshal, got: %s", string(bytes))
 	}
 }
+
+func TestRetryTimes(t *testing.T) {
+	cases := []struct {
+		yaml       string
+		retryTimes int
+	}{
+		{"protocol: webhook", DefaultExportWebhookRetryTimes},
+		{"protocol: webhook\nretry-times: 0", 0},
+		{"protocol: webhook\nretry-times: 5", 5},
+		{"protocol: webhook\nretry-times: -1", 0},
+	}
+	for _, c := range cases {
+		cfg := ExporterCfg{}
+		if err := yaml.Unmarshal([]byte(c.yaml), &cfg); err != nil {
+			t.Fatalf("yaml unmarshal failed: %v", err)
+		}
+		cfg.Validate()
+		if *cfg.RetryTimes != c.retryTimes {
+			t.Errorf("retry times of %q should be %d, got %d", c.yaml, c.retryTimes, *cfg.RetryTimes)
+		}
+	}
+}
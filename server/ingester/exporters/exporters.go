@@ -25,10 +25,12 @@ import (
 	"github.com/deepflowio/deepflow/server/ingester/exporters/common"
 	"github.com/deepflowio/deepflow/server/ingester/exporters/config"
 	"github.com/deepflowio/deepflow/server/ingester/exporters/enum_translation"
+	"github.com/deepflowio/deepflow/server/ingester/exporters/file_exporter"
 	"github.com/deepflowio/deepflow/server/ingester/exporters/kafka_exporter"
 	"github.com/deepflowio/deepflow/server/ingester/exporters/otlp_exporter"
 	"github.com/deepflowio/deepflow/server/ingester/exporters/prometheus_exporter"
 	"github.com/deepflowio/deepflow/server/ingester/exporters/universal_tag"
+	"github.com/deepflowio/deepflow/server/ingester/exporters/webhook_exporter"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 	"github.com/deepflowio/deepflow/server/libs/utils"
 )
@@ -97,6 +99,14 @@ func NewExporters(cfg *config.Config) *Exporters {
 			exporter = prometheus_exporter.NewPrometheusExporter(i, &cfg.Exporters[i], universalTagManager)
 		case config.PROTOCOL_KAFKA:
 			exporter = kafka_exporter.NewKafkaExporter(i, &cfg.Exporters[i], universalTagManager)
+		case config.PROTOCOL_WEBHOOK:
+			exporter = webhook_exporter.NewWebhookExporter(i, &cfg.Exporters[i], universalTagManager)
+		case config.PROTOCOL_FILE:
+			// avoid assigning a nil *FileExporter to the interface
+			exporter = nil
+			if fileExporter := file_exporter.NewFileExporter(i, &cfg.Exporters[i], universalTagManager); fileExporter != nil {
+				exporter = fileExporter
+			}
 		default:
 			exporter = nil
 			log.Warningf("unsupport export protocol %s", exporterCfg.Protocol)
@@ -317,6 +327,13 @@ func (es *Exporters) IsExportItem(item common.ExportItem, dataSourceId uint32, e
 	return true
 }
 
+func (es *Exporters) IsDataSourceExported(dataSourceId uint32) bool {
+	if es == nil || dataSourceId >= uint32(config.MAX_DATASOURCE_ID) {
+		return false
+	}
+	return len(es.dataSourceExporters[dataSourceId]) > 0
+}
+
 func (es *Exporters) getPutCache(dataSourceId, decoderId, exporterId int) *ExportersCache {
 	return &es.putCaches[(dataSourceId*queue.MAX_QUEUE_COUNT+decoderId)*MAX_EXPORTERS_PER_DATASOURCE+exporterId]
 }
@@ -0,0 +1,218 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file_exporter
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	logging "github.com/op/go-logging"
+
+	ingester_common "github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/exporters/common"
+	exporters_cfg "github.com/deepflowio/deepflow/server/ingester/exporters/config"
+	utag "github.com/deepflowio/deepflow/server/ingester/exporters/universal_tag"
+	"github.com/deepflowio/deepflow/server/ingester/ingesterctl"
+	"github.com/deepflowio/deepflow/server/libs/debug"
+	"github.com/deepflowio/deepflow/server/libs/queue"
+	"github.com/deepflowio/deepflow/server/libs/stats"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+)
+
+var log = logging.MustGetLogger("file_exporter")
+
+const (
+	QUEUE_BATCH_COUNT = 1024
+)
+
+// FileExporter writes items as NDJSON (one json object per line) to local files,
+// the file is named as 'exporter_<index>.ndjson.<time>[.<generation>]', and 'exporter_<index>.ndjson' links to the current one
+type FileExporter struct {
+	index      int
+	dataQueues queue.FixedMultiQueue
+	queueCount int
+	writer     *rotatelogs.RotateLogs // goroutine safe, shared by all queues
+
+	universalTagsManager *utag.UniversalTagsManager
+	config               *exporters_cfg.ExporterCfg
+	counter              *Counter
+	lastCounter          Counter
+	running              bool
+
+	utils.Closable
+}
+
+type Counter struct {
+	RecvCounter       int64 `statsd:"recv-count"`
+	WriteCounter      int64 `statsd:"write-count"`
+	WriteBatchCounter int64 `statsd:"write-batch-count"`
+	WriteBytes        int64 `statsd:"write-bytes"`
+	ExportUsedTimeNs  int64 `statsd:"export-used-time-ns"`
+	DropCounter       int64 `statsd:"drop-count"`
+	DropBatchCounter  int64 `statsd:"drop-batch-count"`
+}
+
+func (e *FileExporter) GetCounter() interface{} {
+	var counter Counter
+	counter, *e.counter = *e.counter, Counter{}
+	e.lastCounter = counter
+	return &counter
+}
+
+func NewFileExporter(index int, config *exporters_cfg.ExporterCfg, universalTagsManager *utag.UniversalTagsManager) *FileExporter {
+	fileName, writer, err := newFileWriter(index, config)
+	if err != nil {
+		log.Errorf("file exporter %d create file writer failed: %s", index, err)
+		return nil
+	}
+
+	dataQueues := queue.NewOverwriteQueues(
+		fmt.Sprintf("file_exporter_%d", index), queue.HashKey(config.QueueCount), config.QueueSize,
+		queue.OptionFlushIndicator(time.Second),
+		queue.OptionRelease(func(p interface{}) { p.(common.ExportItem).Release() }),
+		ingester_common.QUEUE_STATS_MODULE_INGESTER)
+
+	exporter := &FileExporter{
+		index:                index,
+		dataQueues:           dataQueues,
+		queueCount:           config.QueueCount,
+		writer:               writer,
+		universalTagsManager: universalTagsManager,
+		config:               config,
+		counter:              &Counter{},
+	}
+	debug.ServerRegisterSimple(ingesterctl.CMD_FILE_EXPORTER, exporter)
+	ingester_common.RegisterCountableForIngester("exporter", exporter, stats.OptionStatTags{
+		"type": "file", "index": strconv.Itoa(index)})
+	log.Infof("file exporter %d created, write to %s", index, fileName)
+	return exporter
+}
+
+func newFileWriter(index int, config *exporters_cfg.ExporterCfg) (string, *rotatelogs.RotateLogs, error) {
+	if err := os.MkdirAll(config.FileDir, 0755); err != nil {
+		log.Warningf("file exporter %d create dir %s failed: %s", index, config.FileDir, err)
+	}
+	fileName := filepath.Join(config.FileDir, fmt.Sprintf("exporter_%d.ndjson", index))
+	writer, err := rotatelogs.New(
+		fileName+".%Y%m%d%H%M",
+		rotatelogs.WithLinkName(fileName),
+		rotatelogs.WithRotationTime(time.Duration(config.FileRotationInterval)*time.Minute),
+		rotatelogs.WithRotationSize(int64(config.FileRotationSize)<<20),
+		rotatelogs.WithRotationCount(uint(config.FileMaxBackups)),
+	)
+	return fileName, writer, err
+}
+
+func (e *FileExporter) Put(items ...interface{}) {
+	e.counter.RecvCounter++
+	e.dataQueues.Put(queue.HashKey(int(e.counter.RecvCounter)%e.queueCount), items...)
+}
+
+func (e *FileExporter) Start() {
+	if e.running {
+		log.Warningf("file exporter %d already running", e.index)
+		return
+	}
+	e.running = true
+	for i := 0; i < e.queueCount; i++ {
+		go e.queueProcess(int(i))
+	}
+	log.Infof("file exporter %d started %d queue", e.index, e.queueCount)
+}
+
+func (e *FileExporter) Close() {
+	e.Closable.Close()
+	e.running = false
+	e.writer.Close()
+	log.Infof("file exporter %d stopping", e.index)
+}
+
+func (e *FileExporter) queueProcess(queueID int) {
+	items := make([]interface{}, QUEUE_BATCH_COUNT)
+	batch := &bytes.Buffer{}
+	batchCount := 0
+
+	doWrite := func() {
+		if batchCount == 0 {
+			return
+		}
+		now := time.Now()
+		// write the whole batch at once, so lines of different queues will not be interleaved
+		if n, err := e.writer.Write(batch.Bytes()); err != nil {
+			if e.counter.DropCounter == 0 {
+				log.Warningf("file exporter %d write failed, err: %s", e.index, err)
+			}
+			e.counter.DropCounter += int64(batchCount)
+			e.counter.DropBatchCounter++
+		} else {
+			e.counter.WriteCounter += int64(batchCount)
+			e.counter.WriteBatchCounter++
+			e.counter.WriteBytes += int64(n)
+		}
+		e.counter.ExportUsedTimeNs += int64(time.Since(now))
+		batch.Reset()
+		batchCount = 0
+	}
+
+	for e.running {
+		n := e.dataQueues.Gets(queue.HashKey(queueID), items)
+		for _, item := range items[:n] {
+			if item == nil {
+				doWrite()
+				continue
+			}
+			exportItem, ok := item.(common.ExportItem)
+			if !ok {
+				e.counter.DropCounter++
+				continue
+			}
+
+			json, err := exportItem.EncodeTo(exporters_cfg.PROTOCOL_FILE, e.universalTagsManager, e.config)
+			if err != nil {
+				if e.counter.DropCounter == 0 {
+					log.Warningf("file exporter encode failed, err: %s", err)
+				}
+				e.counter.DropCounter++
+				exportItem.Release()
+				continue
+			}
+			jsonStr := json.(string)
+			if jsonStr == "" {
+				e.counter.DropCounter++
+				exportItem.Release()
+				continue
+			}
+
+			batch.WriteString(jsonStr)
+			batch.WriteByte('\n')
+			batchCount++
+			if batchCount >= e.config.BatchSize {
+				doWrite()
+			}
+			exportItem.Release()
+		}
+	}
+}
+
+func (e *FileExporter) HandleSimpleCommand(op uint16, arg string) string {
+	return fmt.Sprintf("file exporter %d last 10s counter: %+v", e.index, e.lastCounter)
+}
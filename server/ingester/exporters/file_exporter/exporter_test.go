@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file_exporter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	exporters_cfg "github.com/deepflowio/deepflow/server/ingester/exporters/config"
+)
+
+func listBackups(t *testing.T, fileName string) []string {
+	files, err := filepath.Glob(fileName + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backups := files[:0]
+	for _, file := range files {
+		if !strings.HasSuffix(file, "_lock") && !strings.HasSuffix(file, "_symlink") {
+			backups = append(backups, file)
+		}
+	}
+	return backups
+}
+
+func TestFileWriterRotation(t *testing.T) {
+	config := &exporters_cfg.ExporterCfg{
+		FileDir:              filepath.Join(t.TempDir(), "exporter"),
+		FileRotationSize:     1, // MB
+		FileRotationInterval: 60,
+		FileMaxBackups:       2,
+	}
+	// files are also rotated every hour, avoid it during the test
+	if untilNextHour := time.Until(time.Now().Truncate(time.Hour).Add(time.Hour)); untilNextHour < 5*time.Second {
+		time.Sleep(untilNextHour + time.Second)
+	}
+	fileName, writer, err := newFileWriter(1, config)
+	if err != nil {
+		t.Fatalf("create file writer failed: %s", err)
+	}
+	defer writer.Close()
+	if fileName != filepath.Join(config.FileDir, "exporter_1.ndjson") {
+		t.Errorf("wrong file name %s", fileName)
+	}
+
+	// the file is rotated when it is larger than 1MB
+	line := append(bytes.Repeat([]byte{'a'}, 600<<10), '\n')
+	for i := 0; i < 3; i++ {
+		if _, err := writer.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backups := listBackups(t, fileName); len(backups) != 2 {
+		t.Fatalf("expect 2 files after rotation, got %v", backups)
+	}
+	current, err := os.ReadFile(fileName)
+	if err != nil || !bytes.Equal(current, line) {
+		t.Errorf("link should point to the new file, got %d bytes, err %v", len(current), err)
+	}
+
+	// the oldest files are removed when there are more than max backups
+	for i := 0; i < 4; i++ {
+		writer.Write(line)
+	}
+	var backups []string
+	for i := 0; i < 100; i++ {
+		// files are removed asynchronously
+		if backups = listBackups(t, fileName); len(backups) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(backups) != 2 {
+		t.Errorf("expect at most 2 files kept, got %v", backups)
+	}
+}
@@ -24,6 +24,10 @@ import (
 
 	logging "github.com/op/go-logging"
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
 	"golang.org/x/net/context"
@@ -52,7 +56,7 @@ type OtlpExporter struct {
 	Addr                 string
 	dataQueues           queue.FixedMultiQueue
 	queueCount           int
-	grpcExporters        []ptraceotlp.GRPCClient
+	grpcExporters        []*grpcClients
 	grpcConns            []*grpc.ClientConn
 	grpcFailedCounters   []int
 	universalTagsManager *utag.UniversalTagsManager
@@ -64,6 +68,13 @@ type OtlpExporter struct {
 	utils.Closable
 }
 
+// traces, metrics and logs share the same grpc connection
+type grpcClients struct {
+	traces  ptraceotlp.GRPCClient
+	metrics pmetricotlp.GRPCClient
+	logs    plogotlp.GRPCClient
+}
+
 type Counter struct {
 	RecvCounter      int64 `statsd:"recv-count"`
 	SendCounter      int64 `statsd:"send-count"`
@@ -94,7 +105,7 @@ func NewOtlpExporter(index int, config *exporters_cfg.ExporterCfg, universalTags
 		universalTagsManager: universalTagsManager,
 		grpcConns:            make([]*grpc.ClientConn, config.QueueCount),
 		grpcFailedCounters:   make([]int, config.QueueCount),
-		grpcExporters:        make([]ptraceotlp.GRPCClient, config.QueueCount),
+		grpcExporters:        make([]*grpcClients, config.QueueCount),
 		config:               config,
 		counter:              &Counter{},
 	}
@@ -128,8 +139,10 @@ func (e *OtlpExporter) Close() {
 }
 
 func (e *OtlpExporter) queueProcess(queueID int) {
-	var batchCount int
+	var batchCount, tracesCount, metricsCount, logsCount int
 	traces := ptrace.NewTraces()
+	metrics := pmetric.NewMetrics()
+	logs := plog.NewLogs()
 	items := make([]interface{}, QUEUE_BATCH_COUNT)
 
 	ctx := context.Background()
@@ -142,12 +155,26 @@ func (e *OtlpExporter) queueProcess(queueID int) {
 			return
 		}
 
-		if err := e.grpcExport(ctx, queueID, ptraceotlp.NewExportRequestFromTraces(traces)); err == nil {
-			e.counter.SendCounter += int64(batchCount)
+		if tracesCount > 0 {
+			if err := e.grpcExport(ctx, queueID, ptraceotlp.NewExportRequestFromTraces(traces)); err == nil {
+				e.counter.SendCounter += int64(tracesCount)
+			}
+			log.Debugf(tracesToString(traces))
+			traces = ptrace.NewTraces()
+		}
+		if metricsCount > 0 {
+			if err := e.grpcExport(ctx, queueID, pmetricotlp.NewExportRequestFromMetrics(metrics)); err == nil {
+				e.counter.SendCounter += int64(metricsCount)
+			}
+			metrics = pmetric.NewMetrics()
 		}
-		batchCount = 0
-		log.Debugf(tracesToString(traces))
-		traces = ptrace.NewTraces()
+		if logsCount > 0 {
+			if err := e.grpcExport(ctx, queueID, plogotlp.NewExportRequestFromLogs(logs)); err == nil {
+				e.counter.SendCounter += int64(logsCount)
+			}
+			logs = plog.NewLogs()
+		}
+		batchCount, tracesCount, metricsCount, logsCount = 0, 0, 0, 0
 	}
 
 	for e.running {
@@ -173,8 +200,24 @@ func (e *OtlpExporter) queueProcess(queueID int) {
 				exportItem.Release()
 				continue
 			}
-			rsSlice := dst.(ptrace.ResourceSpansSlice)
-			rsSlice.MoveAndAppendTo(traces.ResourceSpans())
+			switch rsSlice := dst.(type) {
+			case ptrace.ResourceSpansSlice:
+				rsSlice.MoveAndAppendTo(traces.ResourceSpans())
+				tracesCount++
+			case pmetric.ResourceMetricsSlice:
+				rsSlice.MoveAndAppendTo(metrics.ResourceMetrics())
+				metricsCount++
+			case plog.ResourceLogsSlice:
+				rsSlice.MoveAndAppendTo(logs.ResourceLogs())
+				logsCount++
+			default:
+				if e.counter.DropCounter == 0 {
+					log.Warningf("otlp exporter unsupport encoded type %T", dst)
+				}
+				e.counter.DropCounter++
+				exportItem.Release()
+				continue
+			}
 
 			batchCount++
 			if batchCount >= e.config.BatchSize {
@@ -185,7 +228,12 @@ func (e *OtlpExporter) queueProcess(queueID int) {
 	}
 }
 
-func (e *OtlpExporter) grpcExport(ctx context.Context, queueID int, req ptraceotlp.ExportRequest) error {
+// otlpExportRequest is one of ptraceotlp.ExportRequest, pmetricotlp.ExportRequest and plogotlp.ExportRequest
+type otlpExportRequest interface {
+	MarshalJSON() ([]byte, error)
+}
+
+func (e *OtlpExporter) grpcExport(ctx context.Context, queueID int, req otlpExportRequest) error {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Warningf("grpc otlp export error: %s", r)
@@ -206,10 +254,25 @@ func (e *OtlpExporter) grpcExport(ctx context.Context, queueID int, req ptraceot
 			return err
 		}
 	}
-	_, err := e.grpcExporters[queueID].Export(ctx, req)
+	var err error
+	var dataType string
+	clients := e.grpcExporters[queueID]
+	switch r := req.(type) {
+	case ptraceotlp.ExportRequest:
+		dataType = "traces"
+		_, err = clients.traces.Export(ctx, r)
+	case pmetricotlp.ExportRequest:
+		dataType = "metrics"
+		_, err = clients.metrics.Export(ctx, r)
+	case plogotlp.ExportRequest:
+		dataType = "logs"
+		_, err = clients.logs.Export(ctx, r)
+	default:
+		return fmt.Errorf("unsupport otlp export request %T", req)
+	}
 	if err != nil {
 		if e.counter.DropCounter == 0 {
-			log.Warningf("otlp exporter %d send grpc traces failed. faildCounter=%d, err: %s", e.index, e.grpcFailedCounters[queueID], err)
+			log.Warningf("otlp exporter %d send grpc %s failed. faildCounter=%d, err: %s", e.index, dataType, e.grpcFailedCounters[queueID], err)
 		}
 		e.counter.DropCounter++
 		e.grpcExporters[queueID] = nil
@@ -248,7 +311,11 @@ func (e *OtlpExporter) newGrpcExporter(queueID int) error {
 	}
 
 	e.grpcConns[queueID] = conn
-	e.grpcExporters[queueID] = ptraceotlp.NewGRPCClient(conn)
+	e.grpcExporters[queueID] = &grpcClients{
+		traces:  ptraceotlp.NewGRPCClient(conn),
+		metrics: pmetricotlp.NewGRPCClient(conn),
+		logs:    plogotlp.NewGRPCClient(conn),
+	}
 	return nil
 }
 
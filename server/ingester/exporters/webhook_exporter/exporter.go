@@ -0,0 +1,266 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhook_exporter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	logging "github.com/op/go-logging"
+	"golang.org/x/net/context"
+
+	ingester_common "github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/exporters/common"
+	exporters_cfg "github.com/deepflowio/deepflow/server/ingester/exporters/config"
+	utag "github.com/deepflowio/deepflow/server/ingester/exporters/universal_tag"
+	"github.com/deepflowio/deepflow/server/ingester/ingesterctl"
+	"github.com/deepflowio/deepflow/server/libs/debug"
+	"github.com/deepflowio/deepflow/server/libs/queue"
+	"github.com/deepflowio/deepflow/server/libs/stats"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+)
+
+var log = logging.MustGetLogger("webhook_exporter")
+
+const (
+	QUEUE_BATCH_COUNT = 1024
+)
+
+type WebhookExporter struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	index                 int
+	dataQueues            queue.FixedMultiQueue
+	queueCount            int
+	requestFailedCounters []int
+	client                *http.Client
+
+	universalTagsManager *utag.UniversalTagsManager
+	config               *exporters_cfg.ExporterCfg
+	counter              *Counter
+	lastCounter          Counter
+	running              bool
+
+	utils.Closable
+}
+
+type Counter struct {
+	RecvCounter      int64 `statsd:"recv-count"`
+	SendCounter      int64 `statsd:"send-count"`
+	SendBatchCounter int64 `statsd:"send-batch-count"`
+	RetryCounter     int64 `statsd:"retry-count"`
+	ExportUsedTimeNs int64 `statsd:"export-used-time-ns"`
+	DropCounter      int64 `statsd:"drop-count"`
+	DropBatchCounter int64 `statsd:"drop-batch-count"`
+}
+
+func (e *WebhookExporter) GetCounter() interface{} {
+	var counter Counter
+	counter, *e.counter = *e.counter, Counter{}
+	e.lastCounter = counter
+	return &counter
+}
+
+func NewWebhookExporter(index int, config *exporters_cfg.ExporterCfg, universalTagsManager *utag.UniversalTagsManager) *WebhookExporter {
+	ctx, cancel := context.WithCancel(context.Background())
+	dataQueues := queue.NewOverwriteQueues(
+		fmt.Sprintf("webhook_exporter_%d", index), queue.HashKey(config.QueueCount), config.QueueSize,
+		queue.OptionFlushIndicator(time.Second),
+		queue.OptionRelease(func(p interface{}) { p.(common.ExportItem).Release() }),
+		ingester_common.QUEUE_STATS_MODULE_INGESTER)
+
+	exporter := &WebhookExporter{
+		index:                 index,
+		dataQueues:            dataQueues,
+		queueCount:            config.QueueCount,
+		requestFailedCounters: make([]int, config.QueueCount),
+		client:                &http.Client{Timeout: time.Duration(config.Timeout) * time.Second},
+		universalTagsManager:  universalTagsManager,
+		config:                config,
+		counter:               &Counter{},
+		ctx:                   ctx,
+		cancel:                cancel,
+	}
+	debug.ServerRegisterSimple(ingesterctl.CMD_WEBHOOK_EXPORTER, exporter)
+	ingester_common.RegisterCountableForIngester("exporter", exporter, stats.OptionStatTags{
+		"type": "webhook", "index": strconv.Itoa(index)})
+	log.Infof("webhook exporter %d created", index)
+	return exporter
+}
+
+func (e *WebhookExporter) Put(items ...interface{}) {
+	e.counter.RecvCounter++
+	e.dataQueues.Put(queue.HashKey(int(e.counter.RecvCounter)%e.queueCount), items...)
+}
+
+func (e *WebhookExporter) Start() {
+	if e.running {
+		log.Warningf("webhook exporter %d already running", e.index)
+		return
+	}
+	e.running = true
+	for i := 0; i < e.queueCount; i++ {
+		go e.queueProcess(int(i))
+	}
+	log.Infof("webhook exporter %d started %d queue", e.index, e.queueCount)
+}
+
+func (e *WebhookExporter) Close() {
+	e.Closable.Close()
+	e.running = false
+	e.cancel()
+	log.Infof("webhook exporter %d stopping", e.index)
+}
+
+func (e *WebhookExporter) queueProcess(queueID int) {
+	items := make([]interface{}, QUEUE_BATCH_COUNT)
+	batch := &bytes.Buffer{}
+	batchCount := 0
+
+	doReq := func() {
+		if batchCount == 0 {
+			return
+		}
+		batch.WriteByte(']')
+		now := time.Now()
+		if err := e.sendRequest(queueID, batch.Bytes()); err != nil {
+			if e.counter.DropCounter == 0 {
+				log.Warningf("webhook exporter %d send request failed, requestFailedCounter=%d, err: %s", e.index, e.requestFailedCounters[queueID], err)
+			}
+			e.counter.DropCounter += int64(batchCount)
+			e.counter.DropBatchCounter++
+		} else {
+			e.counter.SendCounter += int64(batchCount)
+			e.counter.SendBatchCounter++
+		}
+		e.counter.ExportUsedTimeNs += int64(time.Since(now))
+		batch.Reset()
+		batchCount = 0
+	}
+
+	for e.running {
+		n := e.dataQueues.Gets(queue.HashKey(queueID), items)
+		for _, item := range items[:n] {
+			if item == nil {
+				doReq()
+				continue
+			}
+			exportItem, ok := item.(common.ExportItem)
+			if !ok {
+				e.counter.DropCounter++
+				continue
+			}
+
+			json, err := exportItem.EncodeTo(exporters_cfg.PROTOCOL_WEBHOOK, e.universalTagsManager, e.config)
+			if err != nil {
+				if e.counter.DropCounter == 0 {
+					log.Warningf("webhook encode failed, err: %s", err)
+				}
+				e.counter.DropCounter++
+				exportItem.Release()
+				continue
+			}
+			jsonStr := json.(string)
+			if jsonStr == "" {
+				e.counter.DropCounter++
+				exportItem.Release()
+				continue
+			}
+
+			if batchCount == 0 {
+				batch.WriteByte('[')
+			} else {
+				batch.WriteByte(',')
+			}
+			batch.WriteString(jsonStr)
+			batchCount++
+			if batchCount >= e.config.BatchSize {
+				doReq()
+			}
+			exportItem.Release()
+		}
+	}
+}
+
+func (e *WebhookExporter) HandleSimpleCommand(op uint16, arg string) string {
+	return fmt.Sprintf("webhook exporter %d last 10s counter: %+v", e.index, e.lastCounter)
+}
+
+func (e *WebhookExporter) getEndpoint(queueID int) string {
+	l := len(e.config.RandomEndpoints)
+	return e.config.RandomEndpoints[e.requestFailedCounters[queueID]%l]
+}
+
+// sendRequest posts the batch, and retries with exponential backoff when the request failed,
+// each retry will change to the next endpoint
+func (e *WebhookExporter) sendRequest(queueID int, body []byte) error {
+	backoff := time.Duration(e.config.RetryBackoff) * time.Millisecond
+	var err error
+	for i := 0; i <= *e.config.RetryTimes; i++ {
+		if i > 0 {
+			e.counter.RetryCounter++
+			select {
+			case <-e.ctx.Done():
+				return err
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		var retryable bool
+		if retryable, err = e.post(queueID, body); err == nil || !retryable {
+			return err
+		}
+		e.requestFailedCounters[queueID]++
+	}
+	return err
+}
+
+// post returns whether the request could be retried when it failed
+func (e *WebhookExporter) post(queueID int, body []byte) (bool, error) {
+	if len(e.config.RandomEndpoints) == 0 {
+		return false, fmt.Errorf("webhook exporter %d endpoints is empty", e.index)
+	}
+	endpoint := e.getEndpoint(queueID)
+	req, err := http.NewRequestWithContext(e.ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// inject extra headers
+	for k, v := range e.config.ExtraHeaders {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if resp.StatusCode >= 400 {
+		// client errors will fail again, except for timeout and rate limit
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+		return retryable, fmt.Errorf("webhook %s returned HTTP status %v: %s", endpoint, resp.Status, respBody)
+	}
+	return false, nil
+}
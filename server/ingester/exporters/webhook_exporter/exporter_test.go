@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhook_exporter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	exporters_cfg "github.com/deepflowio/deepflow/server/ingester/exporters/config"
+)
+
+type testEndpoint struct {
+	*httptest.Server
+	statuses []int // status of each request, 200 when all used
+	bodies   []string
+	headers  []http.Header
+}
+
+func newTestEndpoint(statuses ...int) *testEndpoint {
+	e := &testEndpoint{statuses: statuses}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		e.bodies = append(e.bodies, string(body))
+		e.headers = append(e.headers, r.Header)
+		status := http.StatusOK
+		if len(e.bodies) <= len(e.statuses) {
+			status = e.statuses[len(e.bodies)-1]
+		}
+		w.WriteHeader(status)
+	}))
+	return e
+}
+
+func newTestWebhookExporter(retryTimes int, endpoints ...*testEndpoint) *WebhookExporter {
+	config := &exporters_cfg.ExporterCfg{
+		RetryTimes:   &retryTimes,
+		RetryBackoff: 1,
+		ExtraHeaders: map[string]string{"Authorization": "Bearer test"},
+	}
+	for _, endpoint := range endpoints {
+		config.RandomEndpoints = append(config.RandomEndpoints, endpoint.URL)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebhookExporter{
+		ctx:                   ctx,
+		cancel:                cancel,
+		queueCount:            1,
+		requestFailedCounters: make([]int, 1),
+		client:                &http.Client{Timeout: time.Second},
+		config:                config,
+		counter:               &Counter{},
+	}
+}
+
+func TestSendRequest(t *testing.T) {
+	endpoint := newTestEndpoint()
+	defer endpoint.Close()
+	e := newTestWebhookExporter(3, endpoint)
+	if err := e.sendRequest(0, []byte(`[{"a":1}]`)); err != nil {
+		t.Fatalf("send request failed: %s", err)
+	}
+	if len(endpoint.bodies) != 1 || endpoint.bodies[0] != `[{"a":1}]` {
+		t.Fatalf("batch should be posted once, got %v", endpoint.bodies)
+	}
+	header := endpoint.headers[0]
+	if header.Get("Content-Type") != "application/json" || header.Get("Authorization") != "Bearer test" {
+		t.Errorf("wrong headers %v", header)
+	}
+}
+
+func TestSendRequestRetry(t *testing.T) {
+	endpoint := newTestEndpoint(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer endpoint.Close()
+	e := newTestWebhookExporter(3, endpoint)
+	if err := e.sendRequest(0, []byte("[]")); err != nil {
+		t.Fatalf("send request should succeed after retry: %s", err)
+	}
+	if len(endpoint.bodies) != 3 || e.counter.RetryCounter != 2 {
+		t.Errorf("expect 3 requests with 2 retries, got %d requests, %d retries", len(endpoint.bodies), e.counter.RetryCounter)
+	}
+
+	// client errors are not retried
+	endpoint = newTestEndpoint(http.StatusBadRequest)
+	defer endpoint.Close()
+	e = newTestWebhookExporter(3, endpoint)
+	if err := e.sendRequest(0, []byte("[]")); err == nil || len(endpoint.bodies) != 1 {
+		t.Errorf("bad request should fail without retry, got %d requests, err %v", len(endpoint.bodies), err)
+	}
+
+	// retry is disabled by retry-times 0
+	endpoint = newTestEndpoint(http.StatusInternalServerError)
+	defer endpoint.Close()
+	e = newTestWebhookExporter(0, endpoint)
+	if err := e.sendRequest(0, []byte("[]")); err == nil || len(endpoint.bodies) != 1 || e.counter.RetryCounter != 0 {
+		t.Errorf("request should not be retried, got %d requests, err %v", len(endpoint.bodies), err)
+	}
+
+	// gives up after retry-times
+	endpoint = newTestEndpoint(500, 500, 500, 500)
+	defer endpoint.Close()
+	e = newTestWebhookExporter(2, endpoint)
+	if err := e.sendRequest(0, []byte("[]")); err == nil || len(endpoint.bodies) != 3 {
+		t.Errorf("request should fail after 2 retries, got %d requests, err %v", len(endpoint.bodies), err)
+	}
+}
+
+func TestSendRequestNextEndpoint(t *testing.T) {
+	failed := newTestEndpoint(http.StatusBadGateway)
+	defer failed.Close()
+	ok := newTestEndpoint()
+	defer ok.Close()
+	e := newTestWebhookExporter(1, failed, ok)
+	if err := e.sendRequest(0, []byte("[]")); err != nil {
+		t.Fatalf("send request should succeed with the next endpoint: %s", err)
+	}
+	if len(failed.bodies) != 1 || len(ok.bodies) != 1 {
+		t.Errorf("retry should change to the next endpoint, got %d and %d requests", len(failed.bodies), len(ok.bodies))
+	}
+	// the following batches are posted to the available endpoint
+	e.sendRequest(0, []byte("[]"))
+	if len(failed.bodies) != 1 || len(ok.bodies) != 2 {
+		t.Errorf("batch should be posted to the available endpoint, got %d and %d requests", len(failed.bodies), len(ok.bodies))
+	}
+}
+
+func TestSendRequestCanceled(t *testing.T) {
+	endpoint := newTestEndpoint(http.StatusInternalServerError)
+	defer endpoint.Close()
+	e := newTestWebhookExporter(3, endpoint)
+	e.config.RetryBackoff = 60000
+	time.AfterFunc(10*time.Millisecond, e.cancel)
+	start := time.Now()
+	if err := e.sendRequest(0, []byte("[]")); err == nil || time.Since(start) > 10*time.Second {
+		t.Errorf("retry should be stopped by close, err %v", err)
+	}
+}
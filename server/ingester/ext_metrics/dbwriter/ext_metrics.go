@@ -17,11 +17,19 @@
 package dbwriter
 
 import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	exportercommon "github.com/deepflowio/deepflow/server/ingester/exporters/common"
+	"github.com/deepflowio/deepflow/server/ingester/exporters/config"
+	utag "github.com/deepflowio/deepflow/server/ingester/exporters/universal_tag"
 	"github.com/deepflowio/deepflow/server/ingester/flow_tag"
 	"github.com/deepflowio/deepflow/server/libs/ckdb"
 	"github.com/deepflowio/deepflow/server/libs/datatype"
 	flow_metrics "github.com/deepflowio/deepflow/server/libs/flow-metrics"
 	"github.com/deepflowio/deepflow/server/libs/pool"
+	"github.com/deepflowio/deepflow/server/libs/utils"
 )
 
 const (
@@ -29,25 +37,27 @@ const (
 )
 
 type ExtMetrics struct {
-	Timestamp uint32 // s
+	pool.ReferenceCount
+
+	Timestamp uint32 `json:"time" category:"$tag" sub:"flow_info"` // s
 	MsgType   datatype.MessageType
 
 	UniversalTag flow_metrics.UniversalTag
 
-	VTableName string
+	VTableName string `json:"virtual_table_name" category:"$tag" sub:"native_tag"`
 
 	AgentID uint16
 
 	// Not stored, only determines which database to store in.
 	// When Orgid is 0 or 1, it is stored in database '<DatabaseName()>', otherwise stored in '<OrgId>_<DatabaseName()>'.
-	OrgId  uint16
-	TeamID uint16
+	OrgId  uint16 `json:"org_id" category:"$tag"`
+	TeamID uint16 `json:"team_id" category:"$tag"`
 
-	TagNames  []string
-	TagValues []string
+	TagNames  []string `json:"tag_names" category:"$tag" sub:"native_tag" data_type:"[]string"`
+	TagValues []string `json:"tag_values" category:"$tag" sub:"native_tag" data_type:"[]string"`
 
-	MetricsFloatNames  []string
-	MetricsFloatValues []float64
+	MetricsFloatNames  []string  `json:"metrics_float_names" category:"$metrics" data_type:"[]string"`
+	MetricsFloatValues []float64 `json:"metrics_float_values" category:"$metrics" data_type:"[]float64"`
 }
 
 func (m *ExtMetrics) DatabaseName() string {
@@ -116,6 +126,43 @@ func (m *ExtMetrics) Release() {
 	ReleaseExtMetrics(m)
 }
 
+// only the metrics from telegraf are exported, deepflow_system metrics are not
+func (m *ExtMetrics) DataSource() uint32 {
+	if m.MsgType == datatype.MESSAGE_TYPE_TELEGRAF {
+		return uint32(config.EXT_METRICS)
+	}
+	return uint32(config.MAX_DATASOURCE_ID)
+}
+
+func (m *ExtMetrics) EncodeTo(protocol config.ExportProtocol, utags *utag.UniversalTagsManager, cfg *config.ExporterCfg) (interface{}, error) {
+	switch protocol {
+	case config.PROTOCOL_KAFKA, config.PROTOCOL_WEBHOOK, config.PROTOCOL_FILE:
+		tags := m.QueryUniversalTags(utags)
+		k8sLabels := utags.QueryCustomK8sLabels(m.OrgId, m.UniversalTag.PodID)
+		return exportercommon.EncodeToJson(m, int(m.DataSource()), cfg, tags, tags, k8sLabels, k8sLabels), nil
+	default:
+		return nil, fmt.Errorf("ext_metrics unsupport export to %s", protocol)
+	}
+}
+
+func (m *ExtMetrics) QueryUniversalTags(utags *utag.UniversalTagsManager) *utag.UniversalTags {
+	t := &m.UniversalTag
+	return utags.QueryUniversalTags(m.OrgId,
+		t.RegionID, t.AZID, t.HostID, t.PodNSID, t.PodClusterID, t.SubnetID, t.VTAPID,
+		uint8(t.L3DeviceType), t.AutoServiceType, t.AutoInstanceType,
+		t.L3DeviceID, t.AutoServiceID, t.AutoInstanceID, t.PodNodeID, t.PodGroupID, t.PodID, uint32(t.L3EpcID), t.GPID, t.ServiceID,
+		t.IsIPv6 == 0, t.IP, t.IP6,
+	)
+}
+
+func (m *ExtMetrics) GetFieldValueByOffsetAndKind(offset uintptr, kind reflect.Kind, dataType utils.DataType) interface{} {
+	return utils.GetValueByOffsetAndKind(uintptr(unsafe.Pointer(m)), offset, kind, dataType)
+}
+
+func (m *ExtMetrics) TimestampUs() int64 {
+	return int64(m.Timestamp) * 1000000
+}
+
 func (m *ExtMetrics) GenCKTable(cluster, storagePolicy string, ttl int, coldStorage *ckdb.ColdStorage) *ckdb.Table {
 	timeKey := "time"
 	engine := ckdb.MergeTree
@@ -226,12 +273,17 @@ var extMetricsPool = pool.NewLockFreePool(func() interface{} {
 })
 
 func AcquireExtMetrics() *ExtMetrics {
-	return extMetricsPool.Get().(*ExtMetrics)
+	m := extMetricsPool.Get().(*ExtMetrics)
+	m.Reset()
+	return m
 }
 
 var emptyUniversalTag = flow_metrics.UniversalTag{}
 
 func ReleaseExtMetrics(m *ExtMetrics) {
+	if m == nil || m.SubReferenceCount() {
+		return
+	}
 	m.UniversalTag = emptyUniversalTag
 	m.TagNames = m.TagNames[:0]
 	m.TagValues = m.TagValues[:0]
@@ -35,6 +35,9 @@ const (
 	DefaultDecoderQueueSize  = 1 << 14
 	DefaultBrokerQueueSize   = 1 << 14
 	DefaultFlowLogTTL        = 72 // hour
+	DefaultGeoReloadInterval = 60 // s
+
+	DefaultSpanToMetricsMaxDocuments = 100000
 )
 
 type FlowLogTTL struct {
@@ -43,6 +46,22 @@ type FlowLogTTL struct {
 	L4Packet  int `yaml:"l4-packet"`
 }
 
+// geo info of internet ips are looked up from MMDB files, such as GeoLite2-City.mmdb and GeoLite2-ASN.mmdb
+type Geo struct {
+	MMDBFiles      []string `yaml:"mmdb-files"`
+	Language       string   `yaml:"language"`
+	ReloadInterval int      `yaml:"reload-interval"` // s
+}
+
+// application meters (flow_metrics.application.1s/1m) are aggregated from spans imported by OpenTelemetry,
+// so that services instrumented only with OTel SDKs also have RED metrics
+type SpanToMetrics struct {
+	Enabled            bool `yaml:"enabled"`
+	DisableSecondWrite bool `yaml:"disable-second-write"`
+	// max aggregating documents of each decoder, documents are written in advance if exceeded
+	MaxDocuments int `yaml:"max-documents"`
+}
+
 type Config struct {
 	Base              *config.Config
 	CKWriterConfig    config.CKWriterConfig `yaml:"flowlog-ck-writer"`
@@ -53,6 +72,8 @@ type Config struct {
 	FlowLogTTL        FlowLogTTL            `yaml:"flow-log-ttl-hour"`
 	DecoderQueueCount int                   `yaml:"flow-log-decoder-queue-count"`
 	DecoderQueueSize  int                   `yaml:"flow-log-decoder-queue-size"`
+	Geo               Geo                   `yaml:"geo"`
+	SpanToMetrics     SpanToMetrics         `yaml:"span-to-metrics"`
 }
 
 type FlowLogConfig struct {
@@ -77,6 +98,14 @@ func (c *Config) Validate() error {
 		c.FlowLogTTL.L4Packet = DefaultFlowLogTTL
 	}
 
+	if c.Geo.ReloadInterval <= 0 {
+		c.Geo.ReloadInterval = DefaultGeoReloadInterval
+	}
+
+	if c.SpanToMetrics.MaxDocuments <= 0 {
+		c.SpanToMetrics.MaxDocuments = DefaultSpanToMetricsMaxDocuments
+	}
+
 	return nil
 }
 
@@ -90,6 +119,7 @@ func Load(base *config.Config, path string) *Config {
 			DecoderQueueSize:  DefaultDecoderQueueSize,
 			CKWriterConfig:    config.CKWriterConfig{QueueCount: 1, QueueSize: 1000000, BatchSize: 512000, FlushTimeout: 10},
 			FlowLogTTL:        FlowLogTTL{DefaultFlowLogTTL, DefaultFlowLogTTL, DefaultFlowLogTTL},
+			SpanToMetrics:     SpanToMetrics{MaxDocuments: DefaultSpanToMetricsMaxDocuments},
 		},
 	}
 	if _, err := os.Stat(path); os.IsNotExist(err) {
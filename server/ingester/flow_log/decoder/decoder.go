@@ -33,6 +33,7 @@ import (
 	exportconfig "github.com/deepflowio/deepflow/server/ingester/exporters/config"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/config"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/log_data"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/span_metrics"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/throttler"
 	"github.com/deepflowio/deepflow/server/ingester/flow_tag"
 	"github.com/deepflowio/deepflow/server/libs/codec"
@@ -83,6 +84,7 @@ type Decoder struct {
 	throttler     *throttler.ThrottlingQueue
 	flowTagWriter *flow_tag.FlowTagWriter
 	exporters     *exporters.Exporters
+	spanMetrics   *span_metrics.SpanMetrics
 	cfg           *config.Config
 	debugEnabled  bool
 
@@ -102,6 +104,7 @@ func NewDecoder(
 	throttler *throttler.ThrottlingQueue,
 	flowTagWriter *flow_tag.FlowTagWriter,
 	exporters *exporters.Exporters,
+	spanMetrics *span_metrics.SpanMetrics,
 	cfg *config.Config,
 ) *Decoder {
 	return &Decoder{
@@ -113,6 +116,7 @@ func NewDecoder(
 		throttler:      throttler,
 		flowTagWriter:  flowTagWriter,
 		exporters:      exporters,
+		spanMetrics:    spanMetrics,
 		cfg:            cfg,
 		debugEnabled:   log.IsEnabledFor(logging.DEBUG),
 		fieldsBuf:      make([]interface{}, 0, 64),
@@ -263,6 +267,10 @@ func (d *Decoder) sendOpenMetetry(tracesData *v1.TracesData) {
 			l.GenerateNewFlowTags(d.flowTagWriter.Cache)
 			d.flowTagWriter.WriteFieldsAndFieldValuesInCache()
 		}
+		// metrics are aggregated from all spans, including the spans dropped by throttling
+		if d.spanMetrics != nil {
+			d.spanMetrics.Handle(l)
+		}
 		l.Release()
 	}
 }
@@ -370,5 +378,8 @@ func (d *Decoder) flush() {
 		d.throttler.SendWithThrottling(nil)
 		d.throttler.SendWithoutThrottling(nil)
 	}
+	if d.spanMetrics != nil {
+		d.spanMetrics.Flush()
+	}
 	d.export(nil)
 }
@@ -33,7 +33,9 @@ import (
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/dbwriter"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/decoder"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/geo"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/span_metrics"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/throttler"
+	metricsdbwriter "github.com/deepflowio/deepflow/server/ingester/flow_metrics/dbwriter"
 	"github.com/deepflowio/deepflow/server/ingester/flow_tag"
 	"github.com/deepflowio/deepflow/server/ingester/ingesterctl"
 	"github.com/deepflowio/deepflow/server/libs/ckdb"
@@ -64,7 +66,8 @@ type Logger struct {
 	FlowLogWriter *dbwriter.FlowLogWriter
 }
 
-func NewFlowLog(config *config.Config, recv *receiver.Receiver, platformDataManager *grpc.PlatformDataManager, exporters *exporters.Exporters) (*FlowLog, error) {
+// appMeterWriter writes the application meters aggregated from OTel spans, it is nil if flow metrics are not stored
+func NewFlowLog(config *config.Config, recv *receiver.Receiver, platformDataManager *grpc.PlatformDataManager, exporters *exporters.Exporters, appMeterWriter metricsdbwriter.DbWriter) (*FlowLog, error) {
 	manager := dropletqueue.NewManager(ingesterctl.INGESTERCTL_FLOW_LOG_QUEUE)
 
 	if config.Base.StorageDisabled {
@@ -79,6 +82,9 @@ func NewFlowLog(config *config.Config, recv *receiver.Receiver, platformDataMana
 	}
 
 	geo.NewGeoTree()
+	if err := geo.NewGeoProvider(config.Geo.MMDBFiles, config.Geo.Language, config.Geo.ReloadInterval); err != nil {
+		return nil, err
+	}
 
 	flowLogWriter, err := dbwriter.NewFlowLogWriter(
 		config.Base.CKDB.ActualAddrs, config.Base.CKDBAuth.Username, config.Base.CKDBAuth.Password,
@@ -93,15 +99,15 @@ func NewFlowLog(config *config.Config, recv *receiver.Receiver, platformDataMana
 	if err != nil {
 		return nil, err
 	}
-	otelLogger, err := NewLogger(datatype.MESSAGE_TYPE_OPENTELEMETRY, config, platformDataManager, manager, recv, flowLogWriter, common.L7_FLOW_ID, nil)
+	otelLogger, err := NewLogger(datatype.MESSAGE_TYPE_OPENTELEMETRY, config, platformDataManager, manager, recv, flowLogWriter, common.L7_FLOW_ID, nil, appMeterWriter)
 	if err != nil {
 		return nil, err
 	}
-	otelCompressedLogger, err := NewLogger(datatype.MESSAGE_TYPE_OPENTELEMETRY_COMPRESSED, config, platformDataManager, manager, recv, flowLogWriter, common.L7_FLOW_ID, nil)
+	otelCompressedLogger, err := NewLogger(datatype.MESSAGE_TYPE_OPENTELEMETRY_COMPRESSED, config, platformDataManager, manager, recv, flowLogWriter, common.L7_FLOW_ID, nil, appMeterWriter)
 	if err != nil {
 		return nil, err
 	}
-	l4PacketLogger, err := NewLogger(datatype.MESSAGE_TYPE_PACKETSEQUENCE, config, nil, manager, recv, flowLogWriter, common.L4_PACKET_ID, nil)
+	l4PacketLogger, err := NewLogger(datatype.MESSAGE_TYPE_PACKETSEQUENCE, config, nil, manager, recv, flowLogWriter, common.L4_PACKET_ID, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +122,7 @@ func NewFlowLog(config *config.Config, recv *receiver.Receiver, platformDataMana
 	}, nil
 }
 
-func NewLogger(msgType datatype.MessageType, config *config.Config, platformDataManager *grpc.PlatformDataManager, manager *dropletqueue.Manager, recv *receiver.Receiver, flowLogWriter *dbwriter.FlowLogWriter, flowLogId common.FlowLogID, exporters *exporters.Exporters) (*Logger, error) {
+func NewLogger(msgType datatype.MessageType, config *config.Config, platformDataManager *grpc.PlatformDataManager, manager *dropletqueue.Manager, recv *receiver.Receiver, flowLogWriter *dbwriter.FlowLogWriter, flowLogId common.FlowLogID, exporters *exporters.Exporters, appMeterWriter metricsdbwriter.DbWriter) (*Logger, error) {
 	queueCount := config.DecoderQueueCount
 	decodeQueues := manager.NewQueues(
 		"1-receive-to-decode-"+datatype.MessageTypeString[msgType],
@@ -148,6 +154,10 @@ func NewLogger(msgType datatype.MessageType, config *config.Config, platformData
 				debug.ServerRegisterSimple(ingesterctl.CMD_PLATFORMDATA_FLOW_LOG, platformDatas[i])
 			}
 		}
+		var spanMetrics *span_metrics.SpanMetrics
+		if config.SpanToMetrics.Enabled && appMeterWriter != nil {
+			spanMetrics = span_metrics.NewSpanMetrics(i, msgType, &config.SpanToMetrics, appMeterWriter)
+		}
 		decoders[i] = decoder.NewDecoder(
 			i,
 			msgType,
@@ -156,6 +166,7 @@ func NewLogger(msgType datatype.MessageType, config *config.Config, platformData
 			throttlers[i],
 			flowTagWriter,
 			exporters,
+			spanMetrics,
 			config,
 		)
 	}
@@ -209,6 +220,7 @@ func NewL4FlowLogger(config *config.Config, platformDataManager *grpc.PlatformDa
 			throttlers[i],
 			nil,
 			exporters,
+			nil,
 			config,
 		)
 	}
@@ -271,6 +283,7 @@ func NewL7FlowLogger(config *config.Config, platformDataManager *grpc.PlatformDa
 			throttlers[i],
 			flowTagWriter,
 			exporters,
+			nil,
 			config,
 		)
 	}
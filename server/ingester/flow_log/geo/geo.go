@@ -17,11 +17,22 @@
 package geo
 
 import (
+	"net"
+	"time"
+
+	logging "github.com/op/go-logging"
+
 	"github.com/deepflowio/deepflow/server/libs/geo"
+	"github.com/deepflowio/deepflow/server/libs/utils"
 )
 
+var log = logging.MustGetLogger("flow_log.geo")
+
 var geoTree geo.GeoTree
 
+// geoProvider is nil if no MMDB file is configured
+var geoProvider geo.GeoProvider
+
 func NewGeoTree() {
 	geoTree = geo.NewNetmaskGeoTree()
 }
@@ -30,3 +41,27 @@ func QueryProvince(ip uint32) string {
 	region, _ := geoTree.Query(ip)
 	return geo.DecodeRegion(region)
 }
+
+func NewGeoProvider(mmdbFiles []string, language string, reloadInterval int) error {
+	if len(mmdbFiles) == 0 {
+		return nil
+	}
+	provider, err := geo.NewMMDBProvider(mmdbFiles, language, time.Duration(reloadInterval)*time.Second)
+	if err != nil {
+		return err
+	}
+	geoProvider = provider
+	log.Infof("geo provider of %v is enabled", mmdbFiles)
+	return nil
+}
+
+// QueryLocation looks up ip4 if isIPv4, otherwise ip6, returns nil if not found
+func QueryLocation(isIPv4 bool, ip4 uint32, ip6 net.IP) *geo.Location {
+	if geoProvider == nil {
+		return nil
+	}
+	if isIPv4 {
+		return geoProvider.Lookup(utils.IpFromUint32(ip4))
+	}
+	return geoProvider.Lookup(ip6)
+}
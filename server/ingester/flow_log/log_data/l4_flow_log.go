@@ -49,6 +49,7 @@ type L4FlowLog struct {
 	TransportLayer
 	ApplicationLayer
 	Internet
+	GeoLocation
 	KnowledgeGraph
 	FlowInfo
 	Metrics
@@ -232,6 +233,42 @@ func (i *Internet) WriteBlock(block *ckdb.Block) {
 	block.Write(i.Province0, i.Province1)
 }
 
+// geo info of internet ips, looked up from the MMDB files in flow log config
+type GeoLocation struct {
+	GeoCountry0 string `json:"geo_country_0" category:"$tag" sub:"network_layer"`
+	GeoCountry1 string `json:"geo_country_1" category:"$tag" sub:"network_layer"`
+	GeoRegion0  string `json:"geo_region_0" category:"$tag" sub:"network_layer"`
+	GeoRegion1  string `json:"geo_region_1" category:"$tag" sub:"network_layer"`
+	GeoCity0    string `json:"geo_city_0" category:"$tag" sub:"network_layer"`
+	GeoCity1    string `json:"geo_city_1" category:"$tag" sub:"network_layer"`
+	GeoASN0     uint32 `json:"geo_asn_0" category:"$tag" sub:"network_layer"`
+	GeoASN1     uint32 `json:"geo_asn_1" category:"$tag" sub:"network_layer"`
+	GeoOrg0     string `json:"geo_org_0" category:"$tag" sub:"network_layer"`
+	GeoOrg1     string `json:"geo_org_1" category:"$tag" sub:"network_layer"`
+}
+
+var GeoLocationColumns = []*ckdb.Column{
+	ckdb.NewColumn("geo_country_0", ckdb.LowCardinalityString),
+	ckdb.NewColumn("geo_country_1", ckdb.LowCardinalityString),
+	ckdb.NewColumn("geo_region_0", ckdb.LowCardinalityString),
+	ckdb.NewColumn("geo_region_1", ckdb.LowCardinalityString),
+	ckdb.NewColumn("geo_city_0", ckdb.LowCardinalityString),
+	ckdb.NewColumn("geo_city_1", ckdb.LowCardinalityString),
+	ckdb.NewColumn("geo_asn_0", ckdb.UInt32),
+	ckdb.NewColumn("geo_asn_1", ckdb.UInt32),
+	ckdb.NewColumn("geo_org_0", ckdb.LowCardinalityString),
+	ckdb.NewColumn("geo_org_1", ckdb.LowCardinalityString),
+}
+
+func (g *GeoLocation) WriteBlock(block *ckdb.Block) {
+	block.Write(
+		g.GeoCountry0, g.GeoCountry1,
+		g.GeoRegion0, g.GeoRegion1,
+		g.GeoCity0, g.GeoCity1,
+		g.GeoASN0, g.GeoASN1,
+		g.GeoOrg0, g.GeoOrg1)
+}
+
 type KnowledgeGraph struct {
 	RegionID0     uint16 `json:"region_id_0" category:"$tag" sub:"universal_tag"`
 	RegionID1     uint16 `json:"region_id_1" category:"$tag" sub:"universal_tag"`
@@ -707,6 +744,20 @@ func (i *Internet) Fill(f *pb.Flow) {
 	i.Province1 = geo.QueryProvince(f.FlowKey.IpDst)
 }
 
+// only internet ips are looked up, should be called after KnowledgeGraph is filled
+func (g *GeoLocation) Fill(isIPv4 bool, ip40, ip41 uint32, ip60, ip61 net.IP, l3EpcID0, l3EpcID1 int32) {
+	if l3EpcID0 == datatype.EPC_FROM_INTERNET {
+		if l := geo.QueryLocation(isIPv4, ip40, ip60); l != nil {
+			g.GeoCountry0, g.GeoRegion0, g.GeoCity0, g.GeoASN0, g.GeoOrg0 = l.Country, l.Region, l.City, l.ASN, l.Org
+		}
+	}
+	if l3EpcID1 == datatype.EPC_FROM_INTERNET {
+		if l := geo.QueryLocation(isIPv4, ip41, ip61); l != nil {
+			g.GeoCountry1, g.GeoRegion1, g.GeoCity1, g.GeoASN1, g.GeoOrg1 = l.Country, l.Region, l.City, l.ASN, l.Org
+		}
+	}
+}
+
 func isLocalIP(isIPv6 bool, ip4 uint32, ip6 net.IP) bool {
 	ip := ip6
 	if !isIPv6 {
@@ -1003,6 +1054,7 @@ func L4FlowLogColumns() []*ckdb.Column {
 	columns = append(columns, TransportLayerColumns...)
 	columns = append(columns, ApplicationLayerColumns...)
 	columns = append(columns, InternetColumns...)
+	columns = append(columns, GeoLocationColumns...)
 	columns = append(columns, FlowInfoColumns...)
 	columns = append(columns, MetricsColumns...)
 	return columns
@@ -1016,6 +1068,7 @@ func (f *L4FlowLog) WriteBlock(block *ckdb.Block) {
 	f.TransportLayer.WriteBlock(block)
 	f.ApplicationLayer.WriteBlock(block)
 	f.Internet.WriteBlock(block)
+	f.GeoLocation.WriteBlock(block)
 	f.FlowInfo.WriteBlock(block)
 	f.Metrics.WriteBlock(block)
 }
@@ -1079,6 +1132,7 @@ func TaggedFlowToL4FlowLog(orgId, teamId uint16, f *pb.TaggedFlow, platformData
 	s.ApplicationLayer.Fill(f.Flow)
 	s.Internet.Fill(f.Flow)
 	s.KnowledgeGraph.FillL4(f.Flow, isIPV6, platformData)
+	s.GeoLocation.Fill(!isIPV6, s.IP40, s.IP41, s.IP60, s.IP61, s.L3EpcID0, s.L3EpcID1)
 	s.FlowInfo.Fill(f.Flow)
 	s.Metrics.Fill(f.Flow)
 
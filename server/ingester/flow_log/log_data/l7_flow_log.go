@@ -44,6 +44,7 @@ var log = logging.MustGetLogger("flow_log.log_data")
 type L7Base struct {
 	// 知识图谱
 	KnowledgeGraph
+	GeoLocation
 
 	Time uint32 `json:"time" category:"$tag" sub:"flow_info"` // s
 	// 网络层
@@ -139,6 +140,7 @@ func L7BaseColumns() []*ckdb.Column {
 		ckdb.NewColumn("syscall_cap_seq_0", ckdb.UInt32).SetComment("Syscall序列号-请求"),
 		ckdb.NewColumn("syscall_cap_seq_1", ckdb.UInt32).SetComment("Syscall序列号-响应"),
 	)
+	columns = append(columns, GeoLocationColumns...)
 
 	return columns
 }
@@ -186,6 +188,7 @@ func (f *L7Base) WriteBlock(block *ckdb.Block) {
 		f.SyscallCoroutine1,
 		f.SyscallCapSeq0,
 		f.SyscallCapSeq1)
+	f.GeoLocation.WriteBlock(block)
 }
 
 type L7FlowLog struct {
@@ -611,6 +614,7 @@ func (b *L7Base) Fill(log *pb.AppProtoLogsData, platformData *grpc.PlatformInfoT
 	b.Protocol = uint8(log.Base.Protocol)
 
 	b.KnowledgeGraph.FillL7(l, platformData, layers.IPProtocol(b.Protocol))
+	b.GeoLocation.Fill(b.IsIPv4, b.IP40, b.IP41, b.IP60, b.IP61, b.L3EpcID0, b.L3EpcID1)
 }
 
 func (k *KnowledgeGraph) FillL7(l *pb.AppProtoLogsBaseInfo, platformData *grpc.PlatformInfoTable, protocol layers.IPProtocol) {
@@ -32,6 +32,7 @@ import (
 	"github.com/google/gopacket/layers"
 
 	"github.com/deepflowio/deepflow/server/ingester/common"
+	exportercommon "github.com/deepflowio/deepflow/server/ingester/exporters/common"
 	"github.com/deepflowio/deepflow/server/ingester/exporters/config"
 	utag "github.com/deepflowio/deepflow/server/ingester/exporters/universal_tag"
 	"github.com/deepflowio/deepflow/server/libs/datatype"
@@ -50,88 +51,20 @@ func putIntWithoutZero(attrs pcommon.Map, key string, value int64) {
 	}
 }
 
-func putUniversalTags(attrs pcommon.Map, tags0, tags1 *utag.UniversalTags, dataTypeBits uint64) {
-	if dataTypeBits&config.UNIVERSAL_TAG != 0 {
-		putStrWithoutEmpty(attrs, "df.universal_tag.region_0", tags0[utag.Region])
-		putStrWithoutEmpty(attrs, "df.universal_tag.az_0", tags0[utag.AZ])
-		putStrWithoutEmpty(attrs, "df.universal_tag.host_0", tags0[utag.Host])
-		putStrWithoutEmpty(attrs, "df.universal_tag.vpc_0", tags0[utag.L3Epc])
-		putStrWithoutEmpty(attrs, "df.universal_tag.subnet_0", tags0[utag.Subnet])
-		putStrWithoutEmpty(attrs, "df.universal_tag.pod_cluster_0", tags0[utag.PodCluster])
-		putStrWithoutEmpty(attrs, "df.universal_tag.pod_ns_0", tags0[utag.PodNS])
-		putStrWithoutEmpty(attrs, "df.universal_tag.pod_node_0", tags0[utag.PodNode])
-		putStrWithoutEmpty(attrs, "df.universal_tag.pod_group_0", tags0[utag.PodGroup])
-		putStrWithoutEmpty(attrs, "df.universal_tag.pod_0", tags0[utag.Pod])
-		putStrWithoutEmpty(attrs, "df.universal_tag.service_0", tags0[utag.Service])
-
-		putStrWithoutEmpty(attrs, "df.universal_tag.chost_0", tags0[utag.CHost])
-		putStrWithoutEmpty(attrs, "df.universal_tag.router_0", tags0[utag.Router])
-		putStrWithoutEmpty(attrs, "df.universal_tag.dhcpgw_0", tags0[utag.DhcpGW])
-		putStrWithoutEmpty(attrs, "df.universal_tag.pod_service_0", tags0[utag.PodService])
-		putStrWithoutEmpty(attrs, "df.universal_tag.redis_0", tags0[utag.Redis])
-		putStrWithoutEmpty(attrs, "df.universal_tag.rds_0", tags0[utag.RDS])
-		putStrWithoutEmpty(attrs, "df.universal_tag.lb_0", tags0[utag.LB])
-
-		putStrWithoutEmpty(attrs, "df.universal_tag.natgw_0", tags0[utag.NatGW])
-		putStrWithoutEmpty(attrs, "df.universal_tag.auto_instance_type_0", tags0[utag.AutoInstanceType])
-		putStrWithoutEmpty(attrs, "df.universal_tag.auto_instance_0", tags0[utag.AutoInstance])
-		putStrWithoutEmpty(attrs, "df.universal_tag.auto_service_type_0", tags0[utag.AutoServiceType])
-		putStrWithoutEmpty(attrs, "df.universal_tag.auto_service_0", tags0[utag.AutoService])
-
-		putStrWithoutEmpty(attrs, "df.universal_tag.region_1", tags1[utag.Region])
-		putStrWithoutEmpty(attrs, "df.universal_tag.az_1", tags1[utag.AZ])
-		putStrWithoutEmpty(attrs, "df.universal_tag.host_1", tags1[utag.Host])
-		putStrWithoutEmpty(attrs, "df.universal_tag.vpc_1", tags1[utag.L3Epc])
-		putStrWithoutEmpty(attrs, "df.universal_tag.subnet_1", tags1[utag.Subnet])
-		putStrWithoutEmpty(attrs, "df.universal_tag.pod_cluster_1", tags1[utag.PodCluster])
-		putStrWithoutEmpty(attrs, "df.universal_tag.pod_ns_1", tags1[utag.PodNS])
-		putStrWithoutEmpty(attrs, "df.universal_tag.pod_node_1", tags1[utag.PodNode])
-		putStrWithoutEmpty(attrs, "df.universal_tag.pod_group_1", tags1[utag.PodGroup])
-		putStrWithoutEmpty(attrs, "df.universal_tag.pod_1", tags1[utag.Pod])
-		putStrWithoutEmpty(attrs, "df.universal_tag.service_1", tags1[utag.Service])
-		putStrWithoutEmpty(attrs, "df.universal_tag.chost_1", tags1[utag.CHost])
-		putStrWithoutEmpty(attrs, "df.universal_tag.router_1", tags1[utag.Router])
-		putStrWithoutEmpty(attrs, "df.universal_tag.dhcpgw_1", tags1[utag.DhcpGW])
-		putStrWithoutEmpty(attrs, "df.universal_tag.pod_service_1", tags1[utag.PodService])
-		putStrWithoutEmpty(attrs, "df.universal_tag.redis_1", tags1[utag.Redis])
-		putStrWithoutEmpty(attrs, "df.universal_tag.rds_1", tags1[utag.RDS])
-		putStrWithoutEmpty(attrs, "df.universal_tag.lb_1", tags1[utag.LB])
-		putStrWithoutEmpty(attrs, "df.universal_tag.natgw_1", tags1[utag.NatGW])
-		putStrWithoutEmpty(attrs, "df.universal_tag.auto_instance_type_1", tags1[utag.AutoInstanceType])
-		putStrWithoutEmpty(attrs, "df.universal_tag.auto_instance_1", tags1[utag.AutoInstance])
-		putStrWithoutEmpty(attrs, "df.universal_tag.auto_service_type_1", tags1[utag.AutoServiceType])
-		putStrWithoutEmpty(attrs, "df.universal_tag.auto_service_1", tags1[utag.AutoService])
-	}
-}
-
-func newAttrName(prefix, name, suffix string) string {
-	var sb strings.Builder
-	sb.WriteString(prefix)
-	sb.WriteString(name)
-	sb.WriteString(suffix)
-	return sb.String()
-}
-
-func putK8sLabels(orgId uint16, attrs pcommon.Map, podID uint32, universalTagsManager *utag.UniversalTagsManager, suffix string) {
-	labels := universalTagsManager.QueryCustomK8sLabels(orgId, podID)
-	if labels != nil {
-		for name, value := range labels {
-			putStrWithoutEmpty(attrs, newAttrName("df.custom_tag.k8s.labels.", name, suffix), value)
-		}
-	}
-}
-
 func (l7 *L7FlowLog) EncodeToOtlp(utags *utag.UniversalTagsManager, dataTypeBits uint64) interface{} {
 	spanSlice := ptrace.NewResourceSpansSlice()
 	resSpan := spanSlice.AppendEmpty()
 	tags0, tags1 := l7.QueryUniversalTags(utags)
 	resAttrs := resSpan.Resource().Attributes()
-	putUniversalTags(resAttrs, tags0, tags1, dataTypeBits)
+	if dataTypeBits&config.UNIVERSAL_TAG != 0 {
+		exportercommon.PutUniversalTags(resAttrs, tags0, "_0")
+		exportercommon.PutUniversalTags(resAttrs, tags1, "_1")
+	}
 	if dataTypeBits&config.K8S_LABEL != 0 && l7.PodID0 != 0 {
-		putK8sLabels(l7.OrgId, resAttrs, l7.PodID0, utags, "_0")
+		exportercommon.PutK8sLabels(resAttrs, utags.QueryCustomK8sLabels(l7.OrgId, l7.PodID0), "_0")
 	}
 	if dataTypeBits&config.K8S_LABEL != 0 && l7.PodID1 != 0 {
-		putK8sLabels(l7.OrgId, resAttrs, l7.PodID1, utags, "_1")
+		exportercommon.PutK8sLabels(resAttrs, utags.QueryCustomK8sLabels(l7.OrgId, l7.PodID1), "_1")
 	}
 
 	span := resSpan.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
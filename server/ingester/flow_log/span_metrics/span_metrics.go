@@ -0,0 +1,255 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package span_metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	logging "github.com/op/go-logging"
+
+	"github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/config"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/log_data"
+	"github.com/deepflowio/deepflow/server/ingester/flow_metrics/dbwriter"
+	"github.com/deepflowio/deepflow/server/libs/app"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+	flow_metrics "github.com/deepflowio/deepflow/server/libs/flow-metrics"
+	"github.com/deepflowio/deepflow/server/libs/stats"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+)
+
+var log = logging.MustGetLogger("flow_log.span_metrics")
+
+const (
+	// documents are written after the end of their time window for the delay, to wait for the late spans
+	FLUSH_DELAY = 5 // s
+	MINUTE      = 60
+)
+
+type Counter struct {
+	SpanCount        int64 `statsd:"span-count"`
+	IgnoredSpanCount int64 `statsd:"ignored-span-count"`
+	DocCount         int64 `statsd:"doc-count"`
+	EarlyFlushCount  int64 `statsd:"early-flush-count"`
+}
+
+// documentKey identifies an aggregating document, other tags of the document are determined by these
+type documentKey struct {
+	timestamp uint32
+	perSecond bool
+
+	orgID, teamID, vtapID uint16
+	role                  uint8
+	isIPv4                bool
+	ip4                   uint32
+	ip6                   string
+	l3EpcID               int32
+	podID                 uint32
+	gpID                  uint32
+	serverPort            uint16
+	l7Protocol            uint8
+	appService            string
+	appInstance           string
+	endpoint              string
+}
+
+// SpanMetrics aggregates spans imported by OpenTelemetry to application meters of each service and endpoint,
+// response status of the spans are counted as client or server errors. It is not thread-safe, each decoder has its own.
+type SpanMetrics struct {
+	index              int
+	disableSecondWrite bool
+	maxDocuments       int
+	writer             dbwriter.DbWriter
+	documents          map[documentKey]*app.DocumentApp
+	counter            *Counter
+	utils.Closable
+}
+
+func NewSpanMetrics(index int, msgType datatype.MessageType, cfg *config.SpanToMetrics, writer dbwriter.DbWriter) *SpanMetrics {
+	s := &SpanMetrics{
+		index:              index,
+		disableSecondWrite: cfg.DisableSecondWrite,
+		maxDocuments:       cfg.MaxDocuments,
+		writer:             writer,
+		documents:          make(map[documentKey]*app.DocumentApp),
+		counter:            &Counter{},
+	}
+	common.RegisterCountableForIngester("span_metrics", s, stats.OptionStatTags{
+		"thread":   strconv.Itoa(index),
+		"msg_type": msgType.String()})
+	return s
+}
+
+func (s *SpanMetrics) GetCounter() interface{} {
+	var counter *Counter
+	counter, s.counter = s.counter, &Counter{}
+	return counter
+}
+
+// Handle adds the span to the documents of its second and minute
+func (s *SpanMetrics) Handle(l *log_data.L7FlowLog) {
+	var role uint8
+	var tapSide flow_metrics.TAPSideEnum
+	switch l.TapSide {
+	case flow_metrics.ServerApp.String():
+		role, tapSide = flow_metrics.ROLE_SERVER, flow_metrics.ServerApp
+	case flow_metrics.ClientApp.String():
+		role, tapSide = flow_metrics.ROLE_CLIENT, flow_metrics.ClientApp
+	default:
+		// internal spans are not requests between services
+		s.counter.IgnoredSpanCount++
+		return
+	}
+	s.counter.SpanCount++
+
+	if !s.disableSecondWrite {
+		s.add(l, l.L7Base.Time, true, role, tapSide)
+	}
+	s.add(l, l.L7Base.Time/MINUTE*MINUTE, false, role, tapSide)
+
+	if len(s.documents) >= s.maxDocuments {
+		s.counter.EarlyFlushCount++
+		s.flush(0, true)
+	}
+}
+
+func (s *SpanMetrics) add(l *log_data.L7FlowLog, timestamp uint32, perSecond bool, role uint8, tapSide flow_metrics.TAPSideEnum) {
+	key := documentKey{
+		timestamp:   timestamp,
+		perSecond:   perSecond,
+		orgID:       l.OrgId,
+		teamID:      l.TeamID,
+		vtapID:      l.VtapID,
+		role:        role,
+		isIPv4:      l.IsIPv4,
+		serverPort:  l.ServerPort,
+		l7Protocol:  l.L7Protocol,
+		appService:  l.AppService,
+		appInstance: l.AppInstance,
+		endpoint:    l.Endpoint,
+	}
+	if role == flow_metrics.ROLE_SERVER {
+		key.ip4, key.l3EpcID, key.podID, key.gpID = l.IP41, l.L3EpcID1, l.PodID1, l.GPID1
+		if !l.IsIPv4 {
+			key.ip6 = string(l.IP61)
+		}
+	} else {
+		key.ip4, key.l3EpcID, key.podID, key.gpID = l.IP40, l.L3EpcID0, l.PodID0, l.GPID0
+		if !l.IsIPv4 {
+			key.ip6 = string(l.IP60)
+		}
+	}
+
+	doc, ok := s.documents[key]
+	if !ok {
+		doc = app.AcquireDocumentApp()
+		doc.Timestamp = timestamp
+		if perSecond {
+			doc.Flags |= app.FLAG_PER_SECOND_METRICS
+		}
+		fillTag(&doc.Tag, l, role, tapSide)
+		s.documents[key] = doc
+	}
+	meter := &doc.AppMeter
+	meter.Request++
+	meter.Response++
+	if l.ResponseDuration > 0 {
+		if rrt := uint32(l.ResponseDuration); meter.RRTMax < rrt {
+			meter.RRTMax = rrt
+		}
+		meter.RRTSum += l.ResponseDuration
+		meter.RRTCount++
+	}
+	switch datatype.LogMessageStatus(l.ResponseStatus) {
+	case datatype.STATUS_CLIENT_ERROR:
+		meter.ClientError++
+	case datatype.STATUS_SERVER_ERROR:
+		meter.ServerError++
+	}
+}
+
+// fillTag fills the tag of the application table with the side of the span's service
+func fillTag(t *flow_metrics.Tag, l *log_data.L7FlowLog, role uint8, tapSide flow_metrics.TAPSideEnum) {
+	t.Code = flow_metrics.APPLICATION
+	t.OrgId, t.TeamID = l.OrgId, l.TeamID
+	t.VTAPID = l.VtapID
+	t.TAPType = flow_metrics.TAPTypeEnum(l.TapType)
+	t.TAPSide = tapSide
+	t.Role = role
+	t.Protocol = layers.IPProtocol(l.Protocol)
+	t.ServerPort = l.ServerPort
+	t.L7Protocol = datatype.L7Protocol(l.L7Protocol)
+	t.AppService = l.AppService
+	t.AppInstance = l.AppInstance
+	t.Endpoint = l.Endpoint
+	t.BizType = l.BizType
+	t.SignalSource = l.SignalSource
+	if l.IsIPv4 {
+		t.IsIPv4 = 1
+	}
+
+	k := &l.KnowledgeGraph
+	if role == flow_metrics.ROLE_SERVER {
+		t.IP, t.IP6 = l.IP41, l.IP61
+		t.L3EpcID = k.L3EpcID1
+		t.RegionID, t.AZID, t.HostID, t.SubnetID = k.RegionID1, k.AZID1, k.HostID1, k.SubnetID1
+		t.L3DeviceID, t.L3DeviceType = k.L3DeviceID1, flow_metrics.DeviceType(k.L3DeviceType1)
+		t.PodNodeID, t.PodNSID, t.PodGroupID, t.PodID, t.PodClusterID = k.PodNodeID1, k.PodNSID1, k.PodGroupID1, k.PodID1, k.PodClusterID1
+		t.ServiceID = k.ServiceID1
+		t.AutoInstanceID, t.AutoInstanceType = k.AutoInstanceID1, k.AutoInstanceType1
+		t.AutoServiceID, t.AutoServiceType = k.AutoServiceID1, k.AutoServiceType1
+		t.GPID = l.GPID1
+	} else {
+		t.IP, t.IP6 = l.IP40, l.IP60
+		t.L3EpcID = k.L3EpcID0
+		t.RegionID, t.AZID, t.HostID, t.SubnetID = k.RegionID0, k.AZID0, k.HostID0, k.SubnetID0
+		t.L3DeviceID, t.L3DeviceType = k.L3DeviceID0, flow_metrics.DeviceType(k.L3DeviceType0)
+		t.PodNodeID, t.PodNSID, t.PodGroupID, t.PodID, t.PodClusterID = k.PodNodeID0, k.PodNSID0, k.PodGroupID0, k.PodID0, k.PodClusterID0
+		t.ServiceID = k.ServiceID0
+		t.AutoInstanceID, t.AutoInstanceType = k.AutoInstanceID0, k.AutoInstanceType0
+		t.AutoServiceID, t.AutoServiceType = k.AutoServiceID0, k.AutoServiceType0
+		t.GPID = l.GPID0
+	}
+}
+
+// Flush writes the documents whose time window has ended, it is called periodically by the decoder
+func (s *SpanMetrics) Flush() {
+	s.flush(uint32(time.Now().Unix()), false)
+}
+
+func (s *SpanMetrics) flush(now uint32, force bool) {
+	docs := make([]interface{}, 0, len(s.documents))
+	for key, doc := range s.documents {
+		windowEnd := key.timestamp + MINUTE
+		if key.perSecond {
+			windowEnd = key.timestamp + 1
+		}
+		if force || windowEnd+FLUSH_DELAY <= now {
+			docs = append(docs, doc)
+			delete(s.documents, key)
+		}
+	}
+	if len(docs) == 0 {
+		return
+	}
+	s.counter.DocCount += int64(len(docs))
+	if err := s.writer.Put(docs...); err != nil {
+		log.Warningf("span metrics of decoder %d write failed: %s", s.index, err)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package span_metrics
+
+import (
+	"testing"
+
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/config"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/log_data"
+	"github.com/deepflowio/deepflow/server/libs/app"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+	flow_metrics "github.com/deepflowio/deepflow/server/libs/flow-metrics"
+)
+
+type testWriter struct {
+	docs []*app.DocumentApp
+}
+
+func (w *testWriter) Put(items ...interface{}) error {
+	for _, item := range items {
+		w.docs = append(w.docs, item.(*app.DocumentApp))
+	}
+	return nil
+}
+
+func (w *testWriter) Close() {}
+
+func newSpan(tapSide string, endpoint string, time uint32, duration uint64, status datatype.LogMessageStatus) *log_data.L7FlowLog {
+	l := &log_data.L7FlowLog{}
+	l.TapSide = tapSide
+	l.Time = time
+	l.IsIPv4 = true
+	l.IP40, l.IP41 = 1, 2
+	l.L3EpcID0, l.L3EpcID1 = 10, 20
+	l.PodID0, l.PodID1 = 100, 200
+	l.ServerPort = 8080
+	l.SignalSource = uint16(datatype.SIGNAL_SOURCE_OTEL)
+	l.AppService = "backend"
+	l.Endpoint = endpoint
+	l.ResponseDuration = duration
+	l.ResponseStatus = uint8(status)
+	return l
+}
+
+func TestSpanMetrics(t *testing.T) {
+	writer := &testWriter{}
+	s := NewSpanMetrics(0, datatype.MESSAGE_TYPE_OPENTELEMETRY, &config.SpanToMetrics{Enabled: true, MaxDocuments: 100}, writer)
+
+	s.Handle(newSpan("s-app", "GET /a", 120, 100, datatype.STATUS_OK))
+	s.Handle(newSpan("s-app", "GET /a", 120, 300, datatype.STATUS_SERVER_ERROR))
+	s.Handle(newSpan("s-app", "GET /a", 121, 200, datatype.STATUS_CLIENT_ERROR))
+	s.Handle(newSpan("s-app", "GET /b", 121, 200, datatype.STATUS_OK))
+	s.Handle(newSpan("app", "internal", 121, 200, datatype.STATUS_OK))
+	// 1s: (120, /a), (121, /a), (121, /b), 1m: (120, /a), (120, /b)
+	if len(s.documents) != 5 {
+		t.Fatalf("wrong document count %d", len(s.documents))
+	}
+
+	s.flush(121+FLUSH_DELAY, false)
+	if len(writer.docs) != 1 {
+		t.Fatalf("only the document of second 120 should be written, %d written", len(writer.docs))
+	}
+	doc := writer.docs[0]
+	if doc.Timestamp != 120 || doc.Flags&app.FLAG_PER_SECOND_METRICS == 0 || doc.Request != 2 || doc.RRTMax != 300 ||
+		doc.RRTSum != 400 || doc.RRTCount != 2 || doc.ServerError != 1 || doc.ClientError != 0 {
+		t.Errorf("wrong document %s", doc)
+	}
+	if doc.Role != flow_metrics.ROLE_SERVER || doc.IP != 2 || doc.L3EpcID != 20 || doc.PodID != 200 || doc.AppService != "backend" || doc.Endpoint != "GET /a" {
+		t.Errorf("tags of the server side should be used, %s", doc)
+	}
+	if id, err := doc.TableID(); err != nil || id != uint8(flow_metrics.APPLICATION_1S) {
+		t.Errorf("document should be written to application.1s, table id %d, err %v", id, err)
+	}
+
+	s.flush(180+FLUSH_DELAY, false)
+	if len(writer.docs) != 5 || len(s.documents) != 0 {
+		t.Fatalf("all documents should be written, %d written", len(writer.docs))
+	}
+	for _, doc := range writer.docs[1:] {
+		if doc.Flags&app.FLAG_PER_SECOND_METRICS != 0 {
+			continue
+		}
+		if id, _ := doc.TableID(); doc.Timestamp != 120 || id != uint8(flow_metrics.APPLICATION_1M) {
+			t.Errorf("wrong minute document %s", doc)
+		}
+		if doc.Endpoint == "GET /a" && (doc.Request != 3 || doc.ClientError != 1 || doc.ServerError != 1) {
+			t.Errorf("wrong minute document %s", doc)
+		}
+	}
+}
+
+func TestSpanMetricsEarlyFlush(t *testing.T) {
+	writer := &testWriter{}
+	s := NewSpanMetrics(0, datatype.MESSAGE_TYPE_OPENTELEMETRY, &config.SpanToMetrics{Enabled: true, DisableSecondWrite: true, MaxDocuments: 2}, writer)
+	s.Handle(newSpan("c-app", "GET /a", 120, 100, datatype.STATUS_OK))
+	s.Handle(newSpan("c-app", "GET /b", 120, 100, datatype.STATUS_OK))
+	if len(writer.docs) != 2 || len(s.documents) != 0 {
+		t.Fatalf("documents should be written if exceeded, %d written", len(writer.docs))
+	}
+	if doc := writer.docs[0]; doc.Role != flow_metrics.ROLE_CLIENT || doc.IP != 1 || doc.Flags&app.FLAG_PER_SECOND_METRICS != 0 {
+		t.Errorf("wrong document %s", doc)
+	}
+}
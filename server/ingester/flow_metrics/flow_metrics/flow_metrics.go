@@ -87,6 +87,11 @@ func NewFlowMetrics(cfg *config.Config, recv *receiver.Receiver, platformDataMan
 	return &flowMetrics, nil
 }
 
+// DbWriter returns the writer of flow_metrics tables, other modules could write documents through it
+func (r *FlowMetrics) DbWriter() dbwriter.DbWriter {
+	return r.dbwriter
+}
+
 func (r *FlowMetrics) Start() {
 	for i := 0; i < len(r.unmarshallers); i++ {
 		r.platformDatas[i].Start()
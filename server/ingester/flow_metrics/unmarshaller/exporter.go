@@ -131,12 +131,14 @@ func (e *ExportDocumentUsage) EncodeTo(protocol config.ExportProtocol, utags *ut
 
 func EncodeTo(e app.Document, protocol config.ExportProtocol, utags *utag.UniversalTagsManager, cfg *config.ExporterCfg) (interface{}, error) {
 	switch protocol {
-	case config.PROTOCOL_KAFKA:
+	case config.PROTOCOL_KAFKA, config.PROTOCOL_WEBHOOK, config.PROTOCOL_FILE:
 		tags0, tags1 := QueryUniversalTags0(e, utags), QueryUniversalTags1(e, utags)
 		k8sLabels0, k8sLabels1 := utags.QueryCustomK8sLabels(e.OrgID(), e.Tags().PodID), utags.QueryCustomK8sLabels(e.OrgID(), e.Tags().PodID1)
 		return exportercommon.EncodeToJson(e, int(e.DataSource()), cfg, tags0, tags1, k8sLabels0, k8sLabels1), nil
 	case config.PROTOCOL_PROMETHEUS:
 		return EncodeToPrometheus(e, utags, cfg)
+	case config.PROTOCOL_OTLP:
+		return EncodeToOtlp(e, utags, cfg)
 	default:
 		return nil, fmt.Errorf("doc unsupport export to %s", protocol)
 	}
@@ -162,16 +164,13 @@ func QueryUniversalTags1(e app.Document, utags *utag.UniversalTagsManager) *utag
 	)
 }
 
-func getPrometheusLabels(e app.Document, uTags0, uTags1 *utag.UniversalTags, cfg *config.ExporterCfg) []prompb.Label {
+// rangeExportTags calls fn with the name and string value of every exported tag of the document
+func rangeExportTags(e app.Document, uTags0, uTags1 *utag.UniversalTags, cfg *config.ExporterCfg, fn func(structTags *config.StructTags, name, value string)) {
 	dataSourceId := config.DataSourceID(e.DataSource())
-	labels := make([]prompb.Label, 0, 16)
-	labels = append(labels, prompb.Label{
-		Name:  "datasource",
-		Value: dataSourceId.String(),
-	})
 	isMapItem := dataSourceId.IsMap()
 	var name, valueStr string
-	for _, structTags := range cfg.ExportFieldStructTags[dataSourceId] {
+	for i := range cfg.ExportFieldStructTags[dataSourceId] {
+		structTags := &cfg.ExportFieldStructTags[dataSourceId][i]
 		if structTags.CategoryBit&config.TAG == 0 {
 			continue
 		}
@@ -182,7 +181,7 @@ func getPrometheusLabels(e app.Document, uTags0, uTags1 *utag.UniversalTags, cfg
 			continue
 		}
 
-		//  TimeSeries.Samples have exported `time`, no need to export anymore
+		//  the samples/data points have exported `time`, no need to export anymore
 		if structTags.Name == "time" {
 			continue
 		}
@@ -215,12 +214,23 @@ func getPrometheusLabels(e app.Document, uTags0, uTags1 *utag.UniversalTags, cfg
 		} else {
 			name = structTags.Name
 		}
+		fn(structTags, name, valueStr)
+	}
+}
 
+func getPrometheusLabels(e app.Document, uTags0, uTags1 *utag.UniversalTags, cfg *config.ExporterCfg) []prompb.Label {
+	dataSourceId := config.DataSourceID(e.DataSource())
+	labels := make([]prompb.Label, 0, 16)
+	labels = append(labels, prompb.Label{
+		Name:  "datasource",
+		Value: dataSourceId.String(),
+	})
+	rangeExportTags(e, uTags0, uTags1, cfg, func(_ *config.StructTags, name, value string) {
 		labels = append(labels, prompb.Label{
 			Name:  name,
-			Value: valueStr,
+			Value: value,
 		})
-	}
+	})
 	return labels
 }
 
@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unmarshaller
+
+import (
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	exportercommon "github.com/deepflowio/deepflow/server/ingester/exporters/common"
+	"github.com/deepflowio/deepflow/server/ingester/exporters/config"
+	utag "github.com/deepflowio/deepflow/server/ingester/exporters/universal_tag"
+	"github.com/deepflowio/deepflow/server/libs/app"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+)
+
+const (
+	OTLP_SCOPE_NAME = "deepflow.flow_metrics"
+
+	SUFFIX_SUM   = "_sum"
+	SUFFIX_COUNT = "_count"
+	SUFFIX_MAX   = "_max"
+)
+
+// metrics which are not accumulated within the interval, exported as gauges
+var otlpGaugeMetrics = map[string]bool{
+	"direction_score": true,
+	"flow_load":       true,
+}
+
+func documentInterval(e app.Document) time.Duration {
+	if e.Flag()&app.FLAG_PER_SECOND_METRICS != 0 {
+		return time.Second
+	}
+	return time.Minute
+}
+
+// EncodeToOtlp converts the document to OTLP metrics:
+//   - 'xxx_sum' with 'xxx_count' (delay metrics) are merged into a histogram named 'xxx' without buckets
+//   - 'xxx_max' and non-accumulative metrics are exported as gauges
+//   - the others are exported as delta monotonic sums
+func EncodeToOtlp(e app.Document, utags *utag.UniversalTagsManager, cfg *config.ExporterCfg) (interface{}, error) {
+	dataSourceId := config.DataSourceID(e.DataSource())
+	uTags0, uTags1 := QueryUniversalTags0(e, utags), QueryUniversalTags1(e, utags)
+
+	metricsSlice := pmetric.NewResourceMetricsSlice()
+	resMetrics := metricsSlice.AppendEmpty()
+	resAttrs := resMetrics.Resource().Attributes()
+	if cfg.ExportFieldCategoryBits&config.UNIVERSAL_TAG != 0 {
+		exportercommon.PutUniversalTags(resAttrs, uTags0, "_0")
+		exportercommon.PutUniversalTags(resAttrs, uTags1, "_1")
+	}
+	t := e.Tags()
+	if cfg.ExportFieldCategoryBits&config.K8S_LABEL != 0 {
+		if t.PodID != 0 {
+			exportercommon.PutK8sLabels(resAttrs, utags.QueryCustomK8sLabels(e.OrgID(), t.PodID), "_0")
+		}
+		if t.PodID1 != 0 {
+			exportercommon.PutK8sLabels(resAttrs, utags.QueryCustomK8sLabels(e.OrgID(), t.PodID1), "_1")
+		}
+	}
+
+	// universal tags have been put into the resource attributes, the others are data point attributes
+	attrs := pcommon.NewMap()
+	attrs.PutStr("datasource", dataSourceId.String())
+	rangeExportTags(e, uTags0, uTags1, cfg, func(structTags *config.StructTags, name, value string) {
+		if structTags.SubCategoryBit&config.UNIVERSAL_TAG != 0 && cfg.ExportFieldCategoryBits&config.UNIVERSAL_TAG != 0 {
+			return
+		}
+		attrs.PutStr(name, value)
+	})
+
+	names := make([]string, 0, 32)
+	values := make(map[string]float64, 32)
+	for i := range cfg.ExportFieldStructTags[dataSourceId] {
+		structTags := &cfg.ExportFieldStructTags[dataSourceId][i]
+		if structTags.CategoryBit&config.METRICS == 0 {
+			continue
+		}
+		value := e.GetFieldValueByOffsetAndKind(structTags.Offset, structTags.DataKind, structTags.DataType)
+		if utils.IsNil(value) {
+			log.Debug("is nil ", structTags.FieldName)
+			continue
+		}
+		valueFloat64, _, isFloat64 := utils.ConvertToFloat64(value)
+		if !isFloat64 {
+			continue
+		}
+		if _, ok := values[structTags.Name]; !ok {
+			names = append(names, structTags.Name)
+		}
+		values[structTags.Name] = valueFloat64
+	}
+
+	// the time of document is the start of the interval
+	startTime := time.Duration(e.Time()) * time.Second
+	startTimestamp := pcommon.Timestamp(startTime)
+	timestamp := pcommon.Timestamp(startTime + documentInterval(e))
+
+	metrics := resMetrics.ScopeMetrics().AppendEmpty()
+	metrics.Scope().SetName(OTLP_SCOPE_NAME)
+	metricSlice := metrics.Metrics()
+	for _, name := range names {
+		value := values[name]
+		if strings.HasSuffix(name, SUFFIX_COUNT) {
+			if _, ok := values[strings.TrimSuffix(name, SUFFIX_COUNT)+SUFFIX_SUM]; ok {
+				// exported with the histogram of 'xxx_sum'
+				continue
+			}
+		}
+		if cfg.ExportEmptyMetricsDisabled && value == 0 {
+			continue
+		}
+
+		metric := metricSlice.AppendEmpty()
+		var dp pmetric.NumberDataPoint
+		switch {
+		case strings.HasSuffix(name, SUFFIX_SUM):
+			prefix := strings.TrimSuffix(name, SUFFIX_SUM)
+			if count, ok := values[prefix+SUFFIX_COUNT]; ok {
+				metric.SetName(prefix)
+				histogram := metric.SetEmptyHistogram()
+				histogram.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+				hdp := histogram.DataPoints().AppendEmpty()
+				hdp.SetStartTimestamp(startTimestamp)
+				hdp.SetTimestamp(timestamp)
+				hdp.SetSum(value)
+				hdp.SetCount(uint64(count))
+				attrs.CopyTo(hdp.Attributes())
+				continue
+			}
+			dp = newOtlpSumDataPoint(metric)
+		case strings.HasSuffix(name, SUFFIX_MAX) || otlpGaugeMetrics[name]:
+			dp = metric.SetEmptyGauge().DataPoints().AppendEmpty()
+		default:
+			dp = newOtlpSumDataPoint(metric)
+		}
+		metric.SetName(name)
+		dp.SetStartTimestamp(startTimestamp)
+		dp.SetTimestamp(timestamp)
+		dp.SetDoubleValue(value)
+		attrs.CopyTo(dp.Attributes())
+	}
+
+	return metricsSlice, nil
+}
+
+func newOtlpSumDataPoint(metric pmetric.Metric) pmetric.NumberDataPoint {
+	sum := metric.SetEmptySum()
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	sum.SetIsMonotonic(true)
+	return sum.DataPoints().AppendEmpty()
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unmarshaller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/deepflowio/deepflow/server/ingester/exporters/config"
+	utag "github.com/deepflowio/deepflow/server/ingester/exporters/universal_tag"
+	"github.com/deepflowio/deepflow/server/libs/app"
+)
+
+func newTestStructTags(d *app.DocumentApp, name string, field interface{}, categoryBit uint64) config.StructTags {
+	fieldValue := reflect.ValueOf(field)
+	return config.StructTags{
+		Name:        name,
+		CategoryBit: categoryBit,
+		Offset:      fieldValue.Pointer() - uintptr(unsafe.Pointer(d)),
+		DataKind:    fieldValue.Elem().Kind(),
+	}
+}
+
+func newTestOtlpDocument() (*app.DocumentApp, *config.ExporterCfg) {
+	d := &app.DocumentApp{}
+	d.Timestamp = 1700000000
+	d.ServerPort = 8080
+	d.Request = 10
+	d.DirectionScore = 5
+	d.RRTMax = 30
+	d.RRTSum = 100
+	d.RRTCount = 4
+
+	cfg := &config.ExporterCfg{
+		ExportFieldCategoryBits:    config.TAG | config.METRICS,
+		ExportEmptyMetricsDisabled: true,
+	}
+	cfg.ExportFieldStructTags[d.DataSource()] = []config.StructTags{
+		newTestStructTags(d, "server_port", &d.ServerPort, config.TRANSPORT_LAYER),
+		newTestStructTags(d, "request", &d.Request, config.THROUGHPUT),
+		newTestStructTags(d, "response", &d.Response, config.THROUGHPUT),
+		newTestStructTags(d, "direction_score", &d.DirectionScore, config.THROUGHPUT),
+		newTestStructTags(d, "rrt_max", &d.RRTMax, config.DELAY),
+		newTestStructTags(d, "rrt_sum", &d.RRTSum, config.DELAY),
+		newTestStructTags(d, "rrt_count", &d.RRTCount, config.DELAY),
+	}
+	return d, cfg
+}
+
+func TestEncodeToOtlp(t *testing.T) {
+	d, cfg := newTestOtlpDocument()
+	encoded, err := EncodeToOtlp(d, &utag.UniversalTagsManager{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metricsSlice, ok := encoded.(pmetric.ResourceMetricsSlice)
+	if !ok || metricsSlice.Len() != 1 || metricsSlice.At(0).ScopeMetrics().Len() != 1 {
+		t.Fatalf("expect one resource metrics with one scope, got %T", encoded)
+	}
+	scopeMetrics := metricsSlice.At(0).ScopeMetrics().At(0)
+	if scopeMetrics.Scope().Name() != OTLP_SCOPE_NAME {
+		t.Errorf("wrong scope name %s", scopeMetrics.Scope().Name())
+	}
+
+	// the document of 1m starts at its time
+	start := pcommon.Timestamp(1700000000 * time.Second)
+	end := pcommon.Timestamp(1700000060 * time.Second)
+	checkDataPoint := func(name string, attrs pcommon.Map, startTimestamp, timestamp pcommon.Timestamp) {
+		if startTimestamp != start || timestamp != end {
+			t.Errorf("%s should be in [%d, %d], got [%d, %d]", name, start, end, startTimestamp, timestamp)
+		}
+		if port, ok := attrs.Get("server_port"); !ok || port.Str() != "8080" {
+			t.Errorf("%s should have attribute server_port, got %v", name, attrs.AsRaw())
+		}
+		if _, ok := attrs.Get("datasource"); !ok {
+			t.Errorf("%s should have attribute datasource, got %v", name, attrs.AsRaw())
+		}
+	}
+
+	// 'response' is empty and 'rrt_count' is merged into 'rrt'
+	metrics := scopeMetrics.Metrics()
+	expectedNames := []string{"request", "direction_score", "rrt_max", "rrt"}
+	if metrics.Len() != len(expectedNames) {
+		t.Fatalf("expect %d metrics, got %d", len(expectedNames), metrics.Len())
+	}
+	for i, name := range expectedNames {
+		metric := metrics.At(i)
+		if metric.Name() != name {
+			t.Errorf("metric %d should be %s, got %s", i, name, metric.Name())
+			continue
+		}
+		switch name {
+		case "request":
+			sum := metric.Sum()
+			if metric.Type() != pmetric.MetricTypeSum || !sum.IsMonotonic() || sum.AggregationTemporality() != pmetric.AggregationTemporalityDelta {
+				t.Errorf("%s should be a delta monotonic sum", name)
+				continue
+			}
+			dp := sum.DataPoints().At(0)
+			if dp.DoubleValue() != 10 {
+				t.Errorf("%s should be 10, got %f", name, dp.DoubleValue())
+			}
+			checkDataPoint(name, dp.Attributes(), dp.StartTimestamp(), dp.Timestamp())
+		case "direction_score", "rrt_max":
+			if metric.Type() != pmetric.MetricTypeGauge {
+				t.Errorf("%s should be a gauge, got %s", name, metric.Type())
+				continue
+			}
+			dp := metric.Gauge().DataPoints().At(0)
+			checkDataPoint(name, dp.Attributes(), dp.StartTimestamp(), dp.Timestamp())
+		case "rrt":
+			histogram := metric.Histogram()
+			if metric.Type() != pmetric.MetricTypeHistogram || histogram.AggregationTemporality() != pmetric.AggregationTemporalityDelta {
+				t.Errorf("%s should be a delta histogram", name)
+				continue
+			}
+			dp := histogram.DataPoints().At(0)
+			if dp.Sum() != 100 || dp.Count() != 4 {
+				t.Errorf("%s should have sum 100 and count 4, got %f and %d", name, dp.Sum(), dp.Count())
+			}
+			checkDataPoint(name, dp.Attributes(), dp.StartTimestamp(), dp.Timestamp())
+		}
+	}
+}
+
+func TestEncodeToOtlpPerSecond(t *testing.T) {
+	d, cfg := newTestOtlpDocument()
+	structTags := cfg.ExportFieldStructTags[d.DataSource()]
+	d.Flags |= app.FLAG_PER_SECOND_METRICS
+	cfg.ExportFieldStructTags[d.DataSource()] = structTags
+	encoded, err := EncodeToOtlp(d, &utag.UniversalTagsManager{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dp := encoded.(pmetric.ResourceMetricsSlice).At(0).ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints().At(0)
+	start, end := pcommon.Timestamp(1700000000*time.Second), pcommon.Timestamp(1700000001*time.Second)
+	if dp.StartTimestamp() != start || dp.Timestamp() != end {
+		t.Errorf("the document of 1s should be in [%d, %d], got [%d, %d]", start, end, dp.StartTimestamp(), dp.Timestamp())
+	}
+}
@@ -51,9 +51,11 @@ import (
 	flowlogcfg "github.com/deepflowio/deepflow/server/ingester/flow_log/config"
 	flowlog "github.com/deepflowio/deepflow/server/ingester/flow_log/flow_log"
 	flowmetricscfg "github.com/deepflowio/deepflow/server/ingester/flow_metrics/config"
+	flowmetricsdbwriter "github.com/deepflowio/deepflow/server/ingester/flow_metrics/dbwriter"
 	flowmetrics "github.com/deepflowio/deepflow/server/ingester/flow_metrics/flow_metrics"
 	pcapcfg "github.com/deepflowio/deepflow/server/ingester/pcap/config"
 	"github.com/deepflowio/deepflow/server/ingester/pcap/pcap"
+	"github.com/deepflowio/deepflow/server/ingester/pkg/ckwriter"
 	profilecfg "github.com/deepflowio/deepflow/server/ingester/profile/config"
 	"github.com/deepflowio/deepflow/server/ingester/profile/profile"
 	prometheuscfg "github.com/deepflowio/deepflow/server/ingester/prometheus/config"
@@ -93,12 +95,27 @@ func Start(configPath string, shared *servercommon.ControllerIngesterShared) []i
 	stats.SetMinInterval(time.Duration(cfg.StatsInterval) * time.Second)
 	stats.SetRemoteType(stats.REMOTE_TYPE_DFSTATSD)
 	stats.SetDFRemote(net.JoinHostPort("127.0.0.1", strconv.Itoa(int(cfg.ListenPort))))
+	ckwriter.SetSpillConfig(cfg.CKWriterSpill)
 
 	dropletConfig := dropletcfg.Load(cfg, configPath)
 	bytes, _ = yaml.Marshal(dropletConfig)
 	log.Infof("droplet config:\n%s", string(bytes))
 
+	receiverTLSConfig := receiver.TLSConfig{
+		CertFile:       cfg.ReceiverTLS.CertFile,
+		KeyFile:        cfg.ReceiverTLS.KeyFile,
+		ClientCAFile:   cfg.ReceiverTLS.ClientCAFile,
+		IdentityMode:   cfg.ReceiverTLS.IdentityMode,
+		ReloadInterval: time.Duration(cfg.ReceiverTLS.ReloadInterval) * time.Second,
+	}
 	receiver := receiver.NewReceiver(int(cfg.ListenPort), cfg.UDPReadBuffer, cfg.TCPReadBuffer, cfg.TCPReaderBuffer)
+	if cfg.ReceiverTLS.Enabled {
+		if err := receiver.SetTLS(receiverTLSConfig); err != nil {
+			log.Errorf("receiver tls config error: %s", err)
+			time.Sleep(time.Second)
+			os.Exit(1)
+		}
+	}
 
 	ingesterOrgHandler := NewOrgHandler(cfg)
 	closers := []io.Closer{}
@@ -178,22 +195,30 @@ func Start(configPath string, shared *servercommon.ControllerIngesterShared) []i
 			closers = append(closers, exporters)
 		}
 
+		// flow metrics is created before flow log, the application meters aggregated from OTel spans are written by it
+		var flowMetrics *flowmetrics.FlowMetrics
+		var appMeterWriter flowmetricsdbwriter.DbWriter
+		if !cfg.StorageDisabled {
+			var err error
+			flowMetrics, err = flowmetrics.NewFlowMetrics(flowMetricsConfig, receiver, platformDataManager, exporters)
+			checkError(err)
+			appMeterWriter = flowMetrics.DbWriter()
+		}
+
 		// 写流日志数据
-		flowLog, err := flowlog.NewFlowLog(flowLogConfig, receiver, platformDataManager, exporters)
+		flowLog, err := flowlog.NewFlowLog(flowLogConfig, receiver, platformDataManager, exporters, appMeterWriter)
 		checkError(err)
 		flowLog.Start()
 		closers = append(closers, flowLog)
 
 		if !cfg.StorageDisabled {
 			// 写ext_metrics数据
-			extMetrics, err := ext_metrics.NewExtMetrics(extMetricsConfig, receiver, platformDataManager)
+			extMetrics, err := ext_metrics.NewExtMetrics(extMetricsConfig, receiver, platformDataManager, exporters)
 			checkError(err)
 			extMetrics.Start()
 			closers = append(closers, extMetrics)
 
 			// 写遥测数据
-			flowMetrics, err := flowmetrics.NewFlowMetrics(flowMetricsConfig, receiver, platformDataManager, exporters)
-			checkError(err)
 			flowMetrics.Start()
 			closers = append(closers, flowMetrics)
 
@@ -210,7 +235,7 @@ func Start(configPath string, shared *servercommon.ControllerIngesterShared) []i
 			closers = append(closers, pcaper)
 
 			// write profile data
-			profile, err := profile.NewProfile(profileConfig, receiver, platformDataManager)
+			profile, err := profile.NewProfile(profileConfig, receiver, platformDataManager, exporters)
 			checkError(err)
 			profile.Start()
 			closers = append(closers, profile)
@@ -223,7 +248,7 @@ func Start(configPath string, shared *servercommon.ControllerIngesterShared) []i
 			ingesterOrgHandler.SetPromHandler(prometheus)
 
 			// write application log data
-			applicationLog, err := app_log.NewApplicationLogger(applicationLogConfig, receiver, platformDataManager)
+			applicationLog, err := app_log.NewApplicationLogger(applicationLogConfig, receiver, platformDataManager, exporters)
 			checkError(err)
 			applicationLog.Start()
 			closers = append(closers, applicationLog)
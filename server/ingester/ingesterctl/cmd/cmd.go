@@ -107,6 +107,8 @@ func RegisterIngesterCommand(root *cobra.Command) {
 	exportersCmd.AddCommand(debug.ClientRegisterSimple(ingesterctl.CMD_EXPORTER_PLATFORMDATA, debug.CmdHelper{"platformData", "show otlp platformData"}, nil))
 	exportersCmd.AddCommand(debug.ClientRegisterSimple(ingesterctl.CMD_KAFKA_EXPORTER, debug.CmdHelper{Cmd: "kafka", Helper: "show kafka exporter stats"}, nil))
 	exportersCmd.AddCommand(debug.ClientRegisterSimple(ingesterctl.CMD_PROMETHEUS_EXPORTER, debug.CmdHelper{Cmd: "prometheus", Helper: "show prometheus exporter stats"}, nil))
+	exportersCmd.AddCommand(debug.ClientRegisterSimple(ingesterctl.CMD_WEBHOOK_EXPORTER, debug.CmdHelper{Cmd: "webhook", Helper: "show webhook exporter stats"}, nil))
+	exportersCmd.AddCommand(debug.ClientRegisterSimple(ingesterctl.CMD_FILE_EXPORTER, debug.CmdHelper{Cmd: "file", Helper: "show file exporter stats"}, nil))
 
 	profileCmd.AddCommand(debug.ClientRegisterSimple(ingesterctl.CMD_PLATFORMDATA_PROFILE, debug.CmdHelper{"platformData [filter]", "show profile platform data statistics"}, nil))
 
@@ -56,6 +56,8 @@ const (
 	CMD_KAFKA_EXPORTER
 	CMD_PROMETHEUS_EXPORTER
 	CMD_EXPORTER_PLATFORMDATA
+	CMD_WEBHOOK_EXPORTER
+	CMD_FILE_EXPORTER
 )
 
 const (
@@ -18,7 +18,9 @@ package ckwriter
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"sync"
@@ -43,8 +45,15 @@ var log = logging.MustGetLogger("ckwriter")
 const (
 	FLUSH_TIMEOUT  = 10 * time.Second
 	SQL_LOG_LENGTH = 256
+
+	REPLAY_BATCH_COUNT  = 8  // max spilled batches replayed each time, avoid blocking the queue too long
+	REPLAY_MAX_ATTEMPTS = 10 // a spilled batch is dropped after failing to replay so many times while clickhouse is writable
 )
 
+// errSpilledRowInvalid means the spilled rows can not be appended to the batch, e.g. the table schema changed,
+// replaying them again will never succeed
+var errSpilledRowInvalid = errors.New("spilled row write block failed")
+
 type CKWriter struct {
 	addrs         []string
 	user          string
@@ -67,6 +76,12 @@ type CKWriter struct {
 	putCounter   int
 	writeCounter uint64
 	ckdbwatcher  *config.Watcher
+	spillQueues  []*SpillQueue // nil if spill is disabled
+	replayFails  []int         // failed replay attempts of the front spilled batch of each queue
+	writeFailed  []bool        // whether the last write of each queue failed, clickhouse may be unavailable
+	orgPrepares  []string
+
+	replayRows func(queueID, connID int, prepare string, rows [][]interface{}) error
 
 	wg   sync.WaitGroup
 	exit bool
@@ -217,7 +232,26 @@ func NewCKWriter(addrs []string, user, password, counterName, timeZone string, t
 		queue.OptionRelease(func(p interface{}) { p.(CKItem).Release() }),
 		common.QUEUE_STATS_MODULE_INGESTER)
 
-	return &CKWriter{
+	var spillQueues []*SpillQueue
+	if spillCfg := getSpillConfig(); spillCfg.IsTableEnabled(table.Database, table.LocalName) {
+		spillQueues = make([]*SpillQueue, queueCount)
+		for i := range spillQueues {
+			dir := filepath.Join(spillCfg.Dir, name, strconv.Itoa(i))
+			if spillQueues[i], err = NewSpillQueue(dir, (int64(spillCfg.MaxSize)<<20)/int64(queueCount)); err != nil {
+				return nil, fmt.Errorf("create spill queue %s failed: %s", dir, err)
+			}
+			if spillQueues[i].Len() > 0 {
+				log.Infof("spill queue %s has %d batches to replay", dir, spillQueues[i].Len())
+			}
+		}
+	}
+
+	orgPrepares := make([]string, ckdb.MAX_ORG_ID+1)
+	for i := range orgPrepares {
+		orgPrepares[i] = table.MakeOrgPrepareTableInsertSQL(uint16(i))
+	}
+
+	w := &CKWriter{
 		addrs:         addrs,
 		user:          user,
 		password:      password,
@@ -237,7 +271,13 @@ func NewCKWriter(addrs []string, user, password, counterName, timeZone string, t
 		dataQueues:  dataQueues,
 		counters:    make([]Counter, queueCount),
 		ckdbwatcher: ckdbwatcher,
-	}, nil
+		spillQueues: spillQueues,
+		replayFails: make([]int, queueCount),
+		writeFailed: make([]bool, queueCount),
+		orgPrepares: orgPrepares,
+	}
+	w.replayRows = w.writeRows
+	return w, nil
 }
 
 func (w *CKWriter) Run() {
@@ -252,12 +292,22 @@ type Counter struct {
 	RetryCount        int64 `statsd:"retry-count"`
 	RetryFailedCount  int64 `statsd:"retry-failed-count"`
 	OrgInvalidCount   int64 `statsd:"org-invalid-count"`
+
+	SpillCount        int64 `statsd:"spill-count"`        // items persisted to spill queue
+	SpillFailedCount  int64 `statsd:"spill-failed-count"` // items dropped as spill queue is full or failed
+	ReplayCount       int64 `statsd:"replay-count"`       // spilled items written to clickhouse
+	ReplayFailedCount int64 `statsd:"replay-failed-count"`
+	ReplayDropCount   int64 `statsd:"replay-drop-count"` // spilled items dropped as replay failed too many times or can not succeed
+	SpillDepth        int64 `statsd:"spill-depth"`       // batches in spill queue, gauge
+	SpillDepthBytes   int64 `statsd:"spill-depth-bytes"` // gauge
 	utils.Closable
 }
 
 func (i *Counter) GetCounter() interface{} {
 	var counter Counter
 	counter, *i = *i, Counter{}
+	// spill depth is not reset
+	i.SpillDepth, i.SpillDepthBytes = counter.SpillDepth, counter.SpillDepthBytes
 
 	return &counter
 }
@@ -302,8 +352,9 @@ func (w *CKWriter) queueProcess(queueID int) {
 		orgCaches[i] = new(Cache)
 		orgCaches[i].items = make([]CKItem, 0)
 		orgCaches[i].orgID = uint16(i)
-		orgCaches[i].prepare = w.table.MakeOrgPrepareTableInsertSQL(uint16(i))
+		orgCaches[i].prepare = w.orgPrepares[i]
 	}
+	w.updateSpillDepth(queueID)
 
 	for !w.exit {
 		n := w.dataQueues.Gets(queue.HashKey(queueID), rawItems)
@@ -332,6 +383,7 @@ func (w *CKWriter) queueProcess(queueID int) {
 						cache.lastWriteTime = now
 					}
 				}
+				w.replaySpillIfConnected(queueID)
 			} else {
 				log.Warningf("get writer queue data type wrong %T", ck)
 			}
@@ -392,23 +444,121 @@ func (w *CKWriter) Write(queueID int, cache *Cache) {
 		if logEnabled {
 			if err != nil {
 				w.counters[queueID].RetryFailedCount++
-				log.Warningf("retry write table (%s.%s) failed, drop (%d) items: %s", w.table.OrgDatabase(cache.orgID), w.table.LocalName, itemsLen, err)
+				if w.spillQueues != nil {
+					log.Warningf("retry write table (%s.%s) failed, spill (%d) items: %s", w.table.OrgDatabase(cache.orgID), w.table.LocalName, itemsLen, err)
+				} else {
+					log.Warningf("retry write table (%s.%s) failed, drop (%d) items: %s", w.table.OrgDatabase(cache.orgID), w.table.LocalName, itemsLen, err)
+				}
 			} else {
 				log.Infof("retry write table (%s.%s) success, write (%d) items", w.table.OrgDatabase(cache.orgID), w.table.LocalName, itemsLen)
 			}
 		}
+		w.writeFailed[queueID] = err != nil
 		if err != nil {
-			w.counters[queueID].WriteFailedCount += int64(itemsLen)
+			if !w.spill(queueID, cache) {
+				w.counters[queueID].WriteFailedCount += int64(itemsLen)
+			}
 		} else {
 			w.counters[queueID].WriteSuccessCount += int64(itemsLen)
+			w.replaySpill(queueID, connID)
 		}
 	} else {
+		w.writeFailed[queueID] = false
 		w.counters[queueID].WriteSuccessCount += int64(itemsLen)
+		w.replaySpill(queueID, connID)
 	}
 
 	cache.Release()
 }
 
+func (w *CKWriter) updateSpillDepth(queueID int) {
+	if w.spillQueues == nil {
+		return
+	}
+	w.counters[queueID].SpillDepth = int64(w.spillQueues[queueID].Len())
+	w.counters[queueID].SpillDepthBytes = w.spillQueues[queueID].Size()
+}
+
+// spill persists the items which failed to write, returns false if the items are dropped
+func (w *CKWriter) spill(queueID int, cache *Cache) bool {
+	if w.spillQueues == nil {
+		return false
+	}
+	itemsLen := len(cache.items)
+	block := ckdb.NewRowsBlock()
+	for _, item := range cache.items {
+		item.WriteBlock(block)
+		block.WriteAll()
+	}
+	if err := w.spillQueues[queueID].Push(cache.orgID, block.Rows()); err != nil {
+		if w.counters[queueID].SpillFailedCount == 0 {
+			log.Warningf("spill table (%s.%s) failed, drop (%d) items: %s", w.table.OrgDatabase(cache.orgID), w.table.LocalName, itemsLen, err)
+		}
+		w.counters[queueID].SpillFailedCount += int64(itemsLen)
+		return false
+	}
+	w.counters[queueID].SpillCount += int64(itemsLen)
+	w.updateSpillDepth(queueID)
+	return true
+}
+
+// replaySpill writes the spilled batches in order, stops when writing failed.
+// A batch which can never be written, or fails REPLAY_MAX_ATTEMPTS times in a row while new items are written
+// successfully, is dropped to unblock the queue. Failures during clickhouse unavailable are not counted.
+func (w *CKWriter) replaySpill(queueID, connID int) {
+	if w.spillQueues == nil {
+		return
+	}
+	spillQueue := w.spillQueues[queueID]
+	for i := 0; i < REPLAY_BATCH_COUNT && spillQueue.Len() > 0; i++ {
+		orgID, rows, err := spillQueue.Front()
+		if err != nil || orgID > ckdb.MAX_ORG_ID {
+			log.Warningf("read spilled batch of table (%s) failed, drop it: %v", w.name, err)
+			spillQueue.Pop()
+			w.replayFails[queueID] = 0
+			continue
+		}
+		if err := w.replayRows(queueID, connID, w.orgPrepares[orgID], rows); err != nil {
+			w.counters[queueID].ReplayFailedCount++
+			if !w.writeFailed[queueID] {
+				w.replayFails[queueID]++
+			}
+			if errors.Is(err, errSpilledRowInvalid) || w.replayFails[queueID] >= REPLAY_MAX_ATTEMPTS {
+				log.Warningf("replay table (%s.%s) failed %d times, drop (%d) items: %s", w.table.OrgDatabase(orgID), w.table.LocalName, w.replayFails[queueID], len(rows), err)
+				spillQueue.Pop()
+				w.replayFails[queueID] = 0
+				w.counters[queueID].ReplayDropCount += int64(len(rows))
+				continue
+			}
+			if w.counters[queueID].ReplayFailedCount == 1 {
+				log.Warningf("replay table (%s.%s) failed, (%d) batches left: %s", w.table.OrgDatabase(orgID), w.table.LocalName, spillQueue.Len(), err)
+			}
+			break
+		}
+		spillQueue.Pop()
+		w.replayFails[queueID] = 0
+		w.counters[queueID].ReplayCount += int64(len(rows))
+		if spillQueue.Len() == 0 {
+			log.Infof("replay table (%s.%s) finished", w.table.OrgDatabase(orgID), w.table.LocalName)
+		}
+	}
+	w.updateSpillDepth(queueID)
+}
+
+// replaySpillIfConnected replays the spilled batches on flush ticks when no item is written.
+// prepareBatch waits 10s for reconnecting if the connection is nil, which blocks the queue on every tick while
+// clickhouse is down, so the replay is skipped until the connection is reset by the next write.
+func (w *CKWriter) replaySpillIfConnected(queueID int) {
+	if w.spillQueues == nil || w.spillQueues[queueID].Len() == 0 {
+		return
+	}
+	connID := int(atomic.AddUint64(&w.writeCounter, 1) % w.connCount)
+	if IsNil(w.conns[connID]) {
+		return
+	}
+	w.replaySpill(queueID, connID)
+}
+
 func IsNil(i interface{}) bool {
 	if i == nil {
 		return true
@@ -420,15 +570,12 @@ func IsNil(i interface{}) bool {
 	return false
 }
 
-func (w *CKWriter) writeItems(queueID, connID int, cache *Cache) error {
-	if len(cache.items) == 0 {
-		return nil
-	}
+func (w *CKWriter) prepareBatch(queueID, connID int, prepare string) (driver.Batch, error) {
 	ck := w.conns[connID]
 	if IsNil(ck) {
 		if err := w.ResetConnection(connID); err != nil {
 			time.Sleep(time.Second * 10)
-			return fmt.Errorf("write block failed, can not connect to clickhouse: %s", err)
+			return nil, fmt.Errorf("write block failed, can not connect to clickhouse: %s", err)
 		}
 		ck = w.conns[connID]
 	}
@@ -436,18 +583,48 @@ func (w *CKWriter) writeItems(queueID, connID int, cache *Cache) error {
 	batchID := queueID*int(w.connCount) + connID
 	batch := w.batchs[batchID]
 	if IsNil(batch) {
-		w.batchs[batchID], err = ck.PrepareBatch(context.Background(), cache.prepare)
+		w.batchs[batchID], err = ck.PrepareBatch(context.Background(), prepare)
 		if err != nil {
-			return fmt.Errorf("prepare batch item write block failed: %s", err)
+			return nil, fmt.Errorf("prepare batch item write block failed: %s", err)
 		}
 		batch = w.batchs[batchID]
 	} else {
-		batch, err = ck.PrepareReuseBatch(context.Background(), cache.prepare, batch)
+		batch, err = ck.PrepareReuseBatch(context.Background(), prepare, batch)
 		if err != nil {
-			return fmt.Errorf("prepare reuse batch item write block failed: %s", err)
+			return nil, fmt.Errorf("prepare reuse batch item write block failed: %s", err)
 		}
 		w.batchs[batchID] = batch
 	}
+	return batch, nil
+}
+
+func (w *CKWriter) writeRows(queueID, connID int, prepare string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	batch, err := w.prepareBatch(queueID, connID, prepare)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := batch.Append(row...); err != nil {
+			return fmt.Errorf("%w: %s", errSpilledRowInvalid, err)
+		}
+	}
+	if err = batch.Send(); err != nil {
+		return fmt.Errorf("send spilled write block failed: %s", err)
+	}
+	return nil
+}
+
+func (w *CKWriter) writeItems(queueID, connID int, cache *Cache) error {
+	if len(cache.items) == 0 {
+		return nil
+	}
+	batch, err := w.prepareBatch(queueID, connID, cache.prepare)
+	if err != nil {
+		return err
+	}
 
 	ckdbBlock := ckdb.NewBlock(batch)
 	for _, item := range cache.items {
@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckwriter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
+
+	"github.com/deepflowio/deepflow/server/libs/ckdb"
+)
+
+type testItem struct {
+	id       uint32
+	released bool
+}
+
+func (i *testItem) WriteBlock(block *ckdb.Block) {
+	block.Write(i.id)
+}
+
+func (i *testItem) OrgID() uint16 {
+	return ckdb.DEFAULT_ORG_ID
+}
+
+func (i *testItem) Release() {
+	i.released = true
+}
+
+func newTestCKWriter(t *testing.T) *CKWriter {
+	spillQueue, err := NewSpillQueue(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("new spill queue failed: %s", err)
+	}
+	return &CKWriter{
+		name:        "test",
+		table:       &ckdb.Table{Database: "test", LocalName: "test_local"},
+		counters:    make([]Counter, 1),
+		spillQueues: []*SpillQueue{spillQueue},
+		replayFails: make([]int, 1),
+		writeFailed: make([]bool, 1),
+		orgPrepares: make([]string, ckdb.MAX_ORG_ID+1),
+	}
+}
+
+func spillTestItems(t *testing.T, w *CKWriter, ids ...uint32) {
+	cache := &Cache{orgID: ckdb.DEFAULT_ORG_ID}
+	for _, id := range ids {
+		cache.items = append(cache.items, &testItem{id: id})
+	}
+	if !w.spill(0, cache) {
+		t.Fatalf("spill items %v failed", ids)
+	}
+}
+
+func TestCKWriterSpillReplay(t *testing.T) {
+	w := newTestCKWriter(t)
+	var replayed []uint32
+	var replayErr error
+	w.replayRows = func(queueID, connID int, prepare string, rows [][]interface{}) error {
+		if replayErr != nil {
+			return replayErr
+		}
+		for _, row := range rows {
+			replayed = append(replayed, row[0].(uint32))
+		}
+		return nil
+	}
+
+	spillTestItems(t, w, 1, 2)
+	spillTestItems(t, w, 3)
+	if w.counters[0].SpillCount != 3 || w.counters[0].SpillDepth != 2 {
+		t.Fatalf("expect 3 items in 2 batches spilled, got %+v", w.counters[0])
+	}
+
+	// batches are kept while clickhouse is unavailable, no matter how many times replay failed
+	replayErr = errors.New("connection refused")
+	w.writeFailed[0] = true
+	for i := 0; i < REPLAY_MAX_ATTEMPTS*2; i++ {
+		w.replaySpill(0, 0)
+	}
+	if w.spillQueues[0].Len() != 2 || w.counters[0].ReplayDropCount != 0 {
+		t.Fatalf("expect no batch dropped while clickhouse is unavailable, got %+v", w.counters[0])
+	}
+
+	// batches are replayed in order
+	replayErr = nil
+	w.writeFailed[0] = false
+	w.replaySpill(0, 0)
+	if fmt.Sprint(replayed) != "[1 2 3]" || w.counters[0].ReplayCount != 3 || w.counters[0].SpillDepth != 0 {
+		t.Fatalf("expect items [1 2 3] replayed, got %v %+v", replayed, w.counters[0])
+	}
+}
+
+func TestCKWriterReplayDrop(t *testing.T) {
+	w := newTestCKWriter(t)
+	var replayed []uint32
+	poison := uint32(1)
+	w.replayRows = func(queueID, connID int, prepare string, rows [][]interface{}) error {
+		if rows[0][0].(uint32) == poison {
+			return errors.New("code: 241, memory limit exceeded")
+		}
+		replayed = append(replayed, rows[0][0].(uint32))
+		return nil
+	}
+
+	// a batch failing repeatedly while clickhouse is writable is dropped after REPLAY_MAX_ATTEMPTS
+	spillTestItems(t, w, 1, 1)
+	spillTestItems(t, w, 2)
+	for i := 0; i < REPLAY_MAX_ATTEMPTS-1; i++ {
+		w.replaySpill(0, 0)
+	}
+	if w.spillQueues[0].Len() != 2 || len(replayed) != 0 {
+		t.Fatalf("expect batches kept before reaching max attempts, got %d batches", w.spillQueues[0].Len())
+	}
+	w.replaySpill(0, 0)
+	if w.spillQueues[0].Len() != 0 || fmt.Sprint(replayed) != "[2]" || w.counters[0].ReplayDropCount != 2 {
+		t.Fatalf("expect poison batch dropped and next batch replayed, got %v %+v", replayed, w.counters[0])
+	}
+
+	// a batch which can never be appended is dropped at once
+	w.replayRows = func(queueID, connID int, prepare string, rows [][]interface{}) error {
+		if rows[0][0].(uint32) == poison {
+			return fmt.Errorf("%w: converting uint32 to String is unsupported", errSpilledRowInvalid)
+		}
+		replayed = append(replayed, rows[0][0].(uint32))
+		return nil
+	}
+	spillTestItems(t, w, 1)
+	spillTestItems(t, w, 3)
+	w.replaySpill(0, 0)
+	if w.spillQueues[0].Len() != 0 || fmt.Sprint(replayed) != "[2 3]" || w.counters[0].ReplayDropCount != 3 {
+		t.Fatalf("expect invalid batch dropped at once, got %v %+v", replayed, w.counters[0])
+	}
+}
+
+type testConn struct {
+	clickhouse.Conn
+}
+
+func TestCKWriterReplaySkippedWithoutConn(t *testing.T) {
+	w := newTestCKWriter(t)
+	w.conns = []clickhouse.Conn{nil}
+	w.connCount = 1
+	replayCount := 0
+	w.replayRows = func(queueID, connID int, prepare string, rows [][]interface{}) error {
+		replayCount++
+		return nil
+	}
+	spillTestItems(t, w, 1)
+
+	// the replay on flush ticks does not wait for reconnecting
+	w.replaySpillIfConnected(0)
+	if replayCount != 0 || w.spillQueues[0].Len() != 1 {
+		t.Fatalf("expect replay skipped without connection, got %d replays", replayCount)
+	}
+
+	w.conns[0] = &testConn{}
+	w.replaySpillIfConnected(0)
+	if replayCount != 1 || w.spillQueues[0].Len() != 0 {
+		t.Fatalf("expect spilled batch replayed after connected, got %d replays", replayCount)
+	}
+}
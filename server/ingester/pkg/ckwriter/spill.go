@@ -0,0 +1,504 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/deepflowio/deepflow/server/ingester/config"
+)
+
+const (
+	SPILL_FILE_SUFFIX  = ".spill"
+	SPILL_FILE_VERSION = 1
+)
+
+var (
+	spillConfig     config.CKWriterSpill
+	spillConfigLock sync.RWMutex
+)
+
+// SetSpillConfig should be called before creating CKWriters, and only affects the CKWriters created later
+func SetSpillConfig(cfg config.CKWriterSpill) {
+	spillConfigLock.Lock()
+	spillConfig = cfg
+	spillConfigLock.Unlock()
+}
+
+func getSpillConfig() config.CKWriterSpill {
+	spillConfigLock.RLock()
+	defer spillConfigLock.RUnlock()
+	return spillConfig
+}
+
+// SpillQueue persists the batches failed to write to clickhouse, one batch for each file.
+// The file is named as '<seq>.spill', smaller seq is replayed first. It is not goroutine safe,
+// each queue of CKWriter has its own SpillQueue.
+type SpillQueue struct {
+	dir     string
+	maxSize int64
+	size    int64
+	seqs    []uint64
+	sizes   map[uint64]int64
+	nextSeq uint64
+}
+
+func NewSpillQueue(dir string, maxSize int64) (*SpillQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	q := &SpillQueue{
+		dir:     dir,
+		maxSize: maxSize,
+		sizes:   make(map[uint64]int64),
+	}
+
+	// load the batches spilled before restarting
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, SPILL_FILE_SUFFIX) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, SPILL_FILE_SUFFIX), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		q.seqs = append(q.seqs, seq)
+		q.sizes[seq] = info.Size()
+		q.size += info.Size()
+		if seq >= q.nextSeq {
+			q.nextSeq = seq + 1
+		}
+	}
+	sort.Slice(q.seqs, func(i, j int) bool { return q.seqs[i] < q.seqs[j] })
+	return q, nil
+}
+
+func (q *SpillQueue) fileName(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, SPILL_FILE_SUFFIX))
+}
+
+// Len returns the count of spilled batches
+func (q *SpillQueue) Len() int {
+	return len(q.seqs)
+}
+
+// Size returns the total bytes of spilled batches
+func (q *SpillQueue) Size() int64 {
+	return q.size
+}
+
+// Push persists the rows of a batch, returns error if the queue is full
+func (q *SpillQueue) Push(orgID uint16, rows [][]interface{}) error {
+	data, err := encodeSpillRows(orgID, rows)
+	if err != nil {
+		return err
+	}
+	if q.size+int64(len(data)) > q.maxSize {
+		return fmt.Errorf("spill queue %s is full, size %d, max size %d", q.dir, q.size, q.maxSize)
+	}
+
+	seq := q.nextSeq
+	name := q.fileName(seq)
+	// write to a temporary file first, avoid replaying an incomplete file after crash
+	tmpName := name + ".tmp"
+	if err := os.WriteFile(tmpName, data, 0644); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, name); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	q.nextSeq++
+	q.seqs = append(q.seqs, seq)
+	q.sizes[seq] = int64(len(data))
+	q.size += int64(len(data))
+	return nil
+}
+
+// Front reads the oldest batch, it is removed only after calling Pop
+func (q *SpillQueue) Front() (uint16, [][]interface{}, error) {
+	if len(q.seqs) == 0 {
+		return 0, nil, io.EOF
+	}
+	data, err := os.ReadFile(q.fileName(q.seqs[0]))
+	if err != nil {
+		return 0, nil, err
+	}
+	return decodeSpillRows(data)
+}
+
+// Pop removes the oldest batch
+func (q *SpillQueue) Pop() {
+	if len(q.seqs) == 0 {
+		return
+	}
+	seq := q.seqs[0]
+	if err := os.Remove(q.fileName(seq)); err != nil && !os.IsNotExist(err) {
+		log.Warningf("remove spill file %s failed: %s", q.fileName(seq), err)
+	}
+	q.size -= q.sizes[seq]
+	delete(q.sizes, seq)
+	q.seqs = q.seqs[1:]
+}
+
+// value types of the spill file, the values written to ckdb.Block are basic types, net.IP, slices of them,
+// or pointers of them for Nullable columns
+const (
+	spillNil uint8 = iota
+	spillBool
+	spillUInt8
+	spillUInt16
+	spillUInt32
+	spillUInt64
+	spillInt8
+	spillInt16
+	spillInt32
+	spillInt64
+	spillFloat32
+	spillFloat64
+	spillString
+	spillIP
+	spillSlice = 0x40 // | element type
+	spillPtr   = 0x80 // | element type
+)
+
+var ipType = reflect.TypeOf(net.IP{})
+
+var spillKindTypes = map[reflect.Kind]uint8{
+	reflect.Bool:    spillBool,
+	reflect.Uint8:   spillUInt8,
+	reflect.Uint16:  spillUInt16,
+	reflect.Uint32:  spillUInt32,
+	reflect.Uint64:  spillUInt64,
+	reflect.Int8:    spillInt8,
+	reflect.Int16:   spillInt16,
+	reflect.Int32:   spillInt32,
+	reflect.Int64:   spillInt64,
+	reflect.Float32: spillFloat32,
+	reflect.Float64: spillFloat64,
+	reflect.String:  spillString,
+}
+
+type spillEncoder struct {
+	buf     []byte
+	scratch [8]byte
+}
+
+func (e *spillEncoder) putUint(v uint64, size int) {
+	binary.LittleEndian.PutUint64(e.scratch[:], v)
+	e.buf = append(e.buf, e.scratch[:size]...)
+}
+
+func (e *spillEncoder) putBytes(b []byte) {
+	e.putUint(uint64(len(b)), 4)
+	e.buf = append(e.buf, b...)
+}
+
+func spillScalarType(rv reflect.Value) (uint8, bool) {
+	if rv.Type() == ipType {
+		return spillIP, true
+	}
+	t, ok := spillKindTypes[rv.Kind()]
+	return t, ok
+}
+
+func (e *spillEncoder) putScalar(t uint8, rv reflect.Value) {
+	switch t {
+	case spillBool:
+		if rv.Bool() {
+			e.putUint(1, 1)
+		} else {
+			e.putUint(0, 1)
+		}
+	case spillUInt8, spillUInt16, spillUInt32, spillUInt64:
+		e.putUint(rv.Uint(), 1<<(t-spillUInt8))
+	case spillInt8, spillInt16, spillInt32, spillInt64:
+		e.putUint(uint64(rv.Int()), 1<<(t-spillInt8))
+	case spillFloat32:
+		e.putUint(uint64(math.Float32bits(float32(rv.Float()))), 4)
+	case spillFloat64:
+		e.putUint(math.Float64bits(rv.Float()), 8)
+	case spillString:
+		e.putBytes([]byte(rv.String()))
+	case spillIP:
+		e.putBytes(rv.Bytes())
+	}
+}
+
+func (e *spillEncoder) putValue(v interface{}) error {
+	if v == nil {
+		e.buf = append(e.buf, spillNil)
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if t, ok := spillScalarType(rv); ok {
+		e.buf = append(e.buf, t)
+		e.putScalar(t, rv)
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			e.buf = append(e.buf, spillNil)
+			return nil
+		}
+		if t, ok := spillScalarType(rv.Elem()); ok {
+			e.buf = append(e.buf, spillPtr|t)
+			e.putScalar(t, rv.Elem())
+			return nil
+		}
+	case reflect.Slice:
+		if t, ok := spillScalarType(reflect.New(rv.Type().Elem()).Elem()); ok {
+			e.buf = append(e.buf, spillSlice|t)
+			e.putUint(uint64(rv.Len()), 4)
+			for i := 0; i < rv.Len(); i++ {
+				e.putScalar(t, rv.Index(i))
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupport spill value type %T", v)
+}
+
+// format: version(1B) | orgID(2B) | rowCount(4B) | { columnCount(2B) | { type(1B) | value } }
+func encodeSpillRows(orgID uint16, rows [][]interface{}) ([]byte, error) {
+	e := &spillEncoder{buf: make([]byte, 0, 64*len(rows))}
+	e.putUint(SPILL_FILE_VERSION, 1)
+	e.putUint(uint64(orgID), 2)
+	e.putUint(uint64(len(rows)), 4)
+	for _, row := range rows {
+		e.putUint(uint64(len(row)), 2)
+		for _, v := range row {
+			if err := e.putValue(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return e.buf, nil
+}
+
+type spillDecoder struct {
+	r       *bytes.Reader
+	scratch [8]byte
+}
+
+func (d *spillDecoder) getUint(size int) (uint64, error) {
+	d.scratch = [8]byte{}
+	if _, err := io.ReadFull(d.r, d.scratch[:size]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(d.scratch[:]), nil
+}
+
+func (d *spillDecoder) getBytes() ([]byte, error) {
+	n, err := d.getUint(4)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (d *spillDecoder) getScalar(t uint8) (interface{}, error) {
+	switch t {
+	case spillString:
+		b, err := d.getBytes()
+		return string(b), err
+	case spillIP:
+		b, err := d.getBytes()
+		return net.IP(b), err
+	}
+
+	var size int
+	switch t {
+	case spillBool, spillUInt8, spillInt8:
+		size = 1
+	case spillUInt16, spillInt16:
+		size = 2
+	case spillUInt32, spillInt32, spillFloat32:
+		size = 4
+	case spillUInt64, spillInt64, spillFloat64:
+		size = 8
+	default:
+		return nil, fmt.Errorf("invalid spill value type %d", t)
+	}
+	u, err := d.getUint(size)
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case spillBool:
+		return u != 0, nil
+	case spillUInt8:
+		return uint8(u), nil
+	case spillUInt16:
+		return uint16(u), nil
+	case spillUInt32:
+		return uint32(u), nil
+	case spillUInt64:
+		return u, nil
+	case spillInt8:
+		return int8(u), nil
+	case spillInt16:
+		return int16(u), nil
+	case spillInt32:
+		return int32(u), nil
+	case spillInt64:
+		return int64(u), nil
+	case spillFloat32:
+		return math.Float32frombits(uint32(u)), nil
+	default:
+		return math.Float64frombits(u), nil
+	}
+}
+
+func (d *spillDecoder) getValue() (interface{}, error) {
+	t, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case t == spillNil:
+		return nil, nil
+	case t&spillPtr != 0:
+		v, err := d.getScalar(t &^ spillPtr)
+		if err != nil {
+			return nil, err
+		}
+		ptr := reflect.New(reflect.TypeOf(v))
+		ptr.Elem().Set(reflect.ValueOf(v))
+		return ptr.Interface(), nil
+	case t&spillSlice != 0:
+		n, err := d.getUint(4)
+		if err != nil {
+			return nil, err
+		}
+		elemType := t &^ spillSlice
+		empty, err := emptySpillSlice(elemType)
+		if err != nil {
+			return nil, err
+		}
+		slice := reflect.MakeSlice(reflect.TypeOf(empty), 0, int(n))
+		for i := 0; i < int(n); i++ {
+			v, err := d.getScalar(elemType)
+			if err != nil {
+				return nil, err
+			}
+			slice = reflect.Append(slice, reflect.ValueOf(v))
+		}
+		return slice.Interface(), nil
+	default:
+		return d.getScalar(t)
+	}
+}
+
+func emptySpillSlice(t uint8) (interface{}, error) {
+	switch t {
+	case spillBool:
+		return []bool{}, nil
+	case spillUInt8:
+		return []uint8{}, nil
+	case spillUInt16:
+		return []uint16{}, nil
+	case spillUInt32:
+		return []uint32{}, nil
+	case spillUInt64:
+		return []uint64{}, nil
+	case spillInt8:
+		return []int8{}, nil
+	case spillInt16:
+		return []int16{}, nil
+	case spillInt32:
+		return []int32{}, nil
+	case spillInt64:
+		return []int64{}, nil
+	case spillFloat32:
+		return []float32{}, nil
+	case spillFloat64:
+		return []float64{}, nil
+	case spillString:
+		return []string{}, nil
+	case spillIP:
+		return []net.IP{}, nil
+	}
+	return nil, fmt.Errorf("invalid spill slice type %d", t)
+}
+
+func decodeSpillRows(data []byte) (uint16, [][]interface{}, error) {
+	d := &spillDecoder{r: bytes.NewReader(data)}
+	version, err := d.getUint(1)
+	if err != nil {
+		return 0, nil, err
+	}
+	if version != SPILL_FILE_VERSION {
+		return 0, nil, fmt.Errorf("unsupport spill file version %d", version)
+	}
+	orgID, err := d.getUint(2)
+	if err != nil {
+		return 0, nil, err
+	}
+	rowCount, err := d.getUint(4)
+	if err != nil {
+		return 0, nil, err
+	}
+	rows := make([][]interface{}, 0, rowCount)
+	for i := 0; i < int(rowCount); i++ {
+		columnCount, err := d.getUint(2)
+		if err != nil {
+			return 0, nil, err
+		}
+		row := make([]interface{}, 0, columnCount)
+		for j := 0; j < int(columnCount); j++ {
+			v, err := d.getValue()
+			if err != nil {
+				return 0, nil, err
+			}
+			row = append(row, v)
+		}
+		rows = append(rows, row)
+	}
+	if _, err := d.r.ReadByte(); err != io.EOF {
+		return 0, nil, errors.New("unexpected data at the end of spill file")
+	}
+	return uint16(orgID), rows, nil
+}
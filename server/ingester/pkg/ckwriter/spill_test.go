@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckwriter
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+type namedUint8 uint8
+
+func TestSpillRowsEncodeDecode(t *testing.T) {
+	u8 := uint8(3)
+	rows := [][]interface{}{
+		{uint64(1), uint32(2), uint16(3), uint8(4), int64(-5), int32(-6), int16(-7), int8(-8),
+			float64(1.5), float32(2.5), "abc", net.ParseIP("1.2.3.4"), net.ParseIP("::1"), true},
+		{[]string{"a", "b"}, []uint16{1, 2}, []float64{}, []uint8{1}, &u8, (*uint32)(nil), nil},
+	}
+	data, err := encodeSpillRows(3, rows)
+	if err != nil {
+		t.Fatalf("encode failed: %s", err)
+	}
+	orgID, decoded, err := decodeSpillRows(data)
+	if err != nil {
+		t.Fatalf("decode failed: %s", err)
+	}
+	if orgID != 3 {
+		t.Errorf("orgID expect 3, got %d", orgID)
+	}
+	// typed nil pointer is decoded as nil
+	rows[1][5] = nil
+	if !reflect.DeepEqual(rows, decoded) {
+		t.Errorf("expect %v, got %v", rows, decoded)
+	}
+
+	// named types are decoded as basic types
+	data, _ = encodeSpillRows(1, [][]interface{}{{namedUint8(7)}})
+	_, decoded, _ = decodeSpillRows(data)
+	if decoded[0][0] != uint8(7) {
+		t.Errorf("expect uint8(7), got %T(%v)", decoded[0][0], decoded[0][0])
+	}
+
+	if _, err := encodeSpillRows(1, [][]interface{}{{map[string]string{}}}); err == nil {
+		t.Errorf("expect error for unsupport type")
+	}
+}
+
+func TestSpillQueue(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewSpillQueue(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("new spill queue failed: %s", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := q.Push(uint16(i), [][]interface{}{{uint32(i)}}); err != nil {
+			t.Fatalf("push failed: %s", err)
+		}
+	}
+
+	// reload from disk
+	q, _ = NewSpillQueue(dir, 1<<20)
+	if q.Len() != 3 {
+		t.Fatalf("expect 3 batches, got %d", q.Len())
+	}
+	for i := 0; i < 3; i++ {
+		orgID, rows, err := q.Front()
+		if err != nil {
+			t.Fatalf("front failed: %s", err)
+		}
+		if orgID != uint16(i) || rows[0][0] != uint32(i) {
+			t.Errorf("expect batch %d, got org %d rows %v", i, orgID, rows)
+		}
+		q.Pop()
+	}
+	if q.Len() != 0 || q.Size() != 0 {
+		t.Errorf("expect empty queue, got len %d size %d", q.Len(), q.Size())
+	}
+
+	small, _ := NewSpillQueue(t.TempDir(), 8)
+	if err := small.Push(0, [][]interface{}{{"more than 8 bytes"}}); err == nil {
+		t.Errorf("expect error when queue is full")
+	}
+}
@@ -19,10 +19,15 @@ package dbwriter
 import (
 	"fmt"
 	"net"
+	"reflect"
 	"sync/atomic"
 	"time"
+	"unsafe"
 
 	basecommon "github.com/deepflowio/deepflow/server/ingester/common"
+	exportercommon "github.com/deepflowio/deepflow/server/ingester/exporters/common"
+	"github.com/deepflowio/deepflow/server/ingester/exporters/config"
+	utag "github.com/deepflowio/deepflow/server/ingester/exporters/universal_tag"
 	"github.com/deepflowio/deepflow/server/ingester/flow_tag"
 	"github.com/deepflowio/deepflow/server/libs/ckdb"
 	"github.com/deepflowio/deepflow/server/libs/grpc"
@@ -44,57 +49,62 @@ const (
 var InProcessCounter uint32
 
 type InProcessProfile struct {
+	pool.ReferenceCount
+
 	_id  uint64
-	Time uint32
+	Time uint32 `json:"time" category:"$tag" sub:"flow_info"`
 
 	// Profile
-	AppService         string `json:"app_service"`
-	ProfileLocationStr string `json:"profile_location_str"` // package/(class/struct)/function name, e.g.: java/lang/Thread.run
-	ProfileValue       int64  `json:"profile_value"`
+	AppService         string `json:"app_service" category:"$tag" sub:"service_info"`
+	ProfileLocationStr string // package/(class/struct)/function name, e.g.: java/lang/Thread.run, may be compressed by CompressionAlgo
+	ProfileValue       int64  `json:"profile_value" category:"$metrics"`
 	// profile_event_type 的取值与 profile_value_unit 对应关系见下
 	// profile_event_type: relations between profile_event_type and profile_value_unit is under the struct definition
-	ProfileEventType       string   `json:"profile_event_type"` // event_type, e.g.: cpu/itimer...
-	ProfileValueUnit       string   `json:"profile_value_unit"`
-	ProfileCreateTimestamp int64    `json:"profile_create_timestamp"` // 数据上传时间 while data upload to server
-	ProfileInTimestamp     int64    `json:"profile_in_timestamp"`     // 数据写入时间 while data write in storage
-	ProfileLanguageType    string   `json:"profile_language_type"`    // e.g.: Golang/Java/Python...
-	ProfileID              string   `json:"profile_id"`
-	TraceID                string   `json:"trace_id"`
-	SpanName               string   `json:"span_name"`
-	AppInstance            string   `json:"app_instance"`
-	TagNames               []string `json:"tag_names"`
-	TagValues              []string `json:"tag_values"`
-	CompressionAlgo        string   `json:"compression_algo"`
+	ProfileEventType       string   `json:"profile_event_type" category:"$tag" sub:"profile_info"` // event_type, e.g.: cpu/itimer...
+	ProfileValueUnit       string   `json:"profile_value_unit" category:"$tag" sub:"profile_info"`
+	ProfileCreateTimestamp int64    `json:"profile_create_timestamp" category:"$tag" sub:"profile_info"` // 数据上传时间 while data upload to server
+	ProfileInTimestamp     int64    `json:"profile_in_timestamp" category:"$tag" sub:"profile_info"`     // 数据写入时间 while data write in storage
+	ProfileLanguageType    string   `json:"profile_language_type" category:"$tag" sub:"profile_info"`    // e.g.: Golang/Java/Python...
+	ProfileID              string   `json:"profile_id" category:"$tag" sub:"profile_info"`
+	TraceID                string   `json:"trace_id" category:"$tag" sub:"tracing_info"`
+	SpanName               string   `json:"span_name" category:"$tag" sub:"tracing_info"`
+	AppInstance            string   `json:"app_instance" category:"$tag" sub:"service_info"`
+	TagNames               []string `json:"tag_names" category:"$tag" sub:"native_tag" data_type:"[]string"`
+	TagValues              []string `json:"tag_values" category:"$tag" sub:"native_tag" data_type:"[]string"`
+	CompressionAlgo        string
 	// Ebpf Profile Infos
-	ProcessID        uint32 `json:"process_id"`
-	ProcessStartTime int64  `json:"process_start_time"`
-	GPID             uint32
+	ProcessID        uint32 `json:"process_id" category:"$tag" sub:"profile_info"`
+	ProcessStartTime int64  `json:"process_start_time" category:"$tag" sub:"profile_info"`
+	GPID             uint32 `json:"gprocess_id" category:"$tag" sub:"universal_tag"`
 
 	// Universal Tag
-	VtapID       uint16
-	RegionID     uint16
-	AZID         uint16
-	SubnetID     uint16
-	L3EpcID      int32
-	HostID       uint16
-	PodID        uint32
-	PodNodeID    uint32
-	PodNSID      uint16
-	PodClusterID uint16
-	PodGroupID   uint32
-
-	IP4    uint32 `json:"ip4"`
-	IP6    net.IP `json:"ip6"`
-	IsIPv4 bool   `json:"is_ipv4"`
-
-	L3DeviceType uint8
-	L3DeviceID   uint32
-	ServiceID    uint32
+	VtapID       uint16 `json:"agent_id" category:"$tag" sub:"universal_tag"`
+	RegionID     uint16 `json:"region_id" category:"$tag" sub:"universal_tag"`
+	AZID         uint16 `json:"az_id" category:"$tag" sub:"universal_tag"`
+	SubnetID     uint16 `json:"subnet_id" category:"$tag" sub:"universal_tag"`
+	L3EpcID      int32  `json:"l3_epc_id" category:"$tag" sub:"universal_tag"`
+	HostID       uint16 `json:"host_id" category:"$tag" sub:"universal_tag"`
+	PodID        uint32 `json:"pod_id" category:"$tag" sub:"universal_tag"`
+	PodNodeID    uint32 `json:"pod_node_id" category:"$tag" sub:"universal_tag"`
+	PodNSID      uint16 `json:"pod_ns_id" category:"$tag" sub:"universal_tag"`
+	PodClusterID uint16 `json:"pod_cluster_id" category:"$tag" sub:"universal_tag"`
+	PodGroupID   uint32 `json:"pod_group_id" category:"$tag" sub:"universal_tag"`
+
+	IP4    uint32 `json:"ip4" category:"$tag" sub:"network_layer" to_string:"IPv4String"`
+	IP6    net.IP `json:"ip6" category:"$tag" sub:"network_layer" to_string:"IPv6String" data_type:"net.IP"`
+	IsIPv4 bool   `json:"is_ipv4" category:"$tag" sub:"network_layer"`
+
+	L3DeviceType uint8  `json:"l3_device_type" category:"$tag" sub:"universal_tag"`
+	L3DeviceID   uint32 `json:"l3_device_id" category:"$tag" sub:"universal_tag"`
+	ServiceID    uint32 `json:"service_id" category:"$tag" sub:"universal_tag"`
 
 	// Not stored, only determines which database to store in.
 	// When Orgid is 0 or 1, it is stored in database 'profile', otherwise stored in '<OrgId>_profile'.
-	OrgId  uint16
-	TeamID uint16
+	OrgId  uint16 `json:"org_id" category:"$tag"`
+	TeamID uint16 `json:"team_id" category:"$tag"`
+
+	// Not stored, the uncompressed ProfileLocationStr, only filled when the profile is exported.
+	ProfileLocation string `json:"profile_location_str" category:"$tag" sub:"profile_info"`
 }
 
 // profile_event_type <-> profile_value_unit relation
@@ -250,17 +260,50 @@ func (p *InProcessProfile) Release() {
 	ReleaseInProcess(p)
 }
 
+func (p *InProcessProfile) DataSource() uint32 {
+	return uint32(config.PROFILE)
+}
+
+func (p *InProcessProfile) EncodeTo(protocol config.ExportProtocol, utags *utag.UniversalTagsManager, cfg *config.ExporterCfg) (interface{}, error) {
+	switch protocol {
+	case config.PROTOCOL_KAFKA, config.PROTOCOL_WEBHOOK, config.PROTOCOL_FILE:
+		tags := p.QueryUniversalTags(utags)
+		k8sLabels := utags.QueryCustomK8sLabels(p.OrgId, p.PodID)
+		return exportercommon.EncodeToJson(p, int(p.DataSource()), cfg, tags, tags, k8sLabels, k8sLabels), nil
+	default:
+		return nil, fmt.Errorf("profile unsupport export to %s", protocol)
+	}
+}
+
+func (p *InProcessProfile) QueryUniversalTags(utags *utag.UniversalTagsManager) *utag.UniversalTags {
+	return utags.QueryUniversalTags(p.OrgId,
+		p.RegionID, p.AZID, p.HostID, p.PodNSID, p.PodClusterID, p.SubnetID, p.VtapID,
+		p.L3DeviceType, 0, 0,
+		p.L3DeviceID, 0, 0, p.PodNodeID, p.PodGroupID, p.PodID, uint32(p.L3EpcID), p.GPID, p.ServiceID,
+		p.IsIPv4, p.IP4, p.IP6,
+	)
+}
+
+func (p *InProcessProfile) GetFieldValueByOffsetAndKind(offset uintptr, kind reflect.Kind, dataType utils.DataType) interface{} {
+	return utils.GetValueByOffsetAndKind(uintptr(unsafe.Pointer(p)), offset, kind, dataType)
+}
+
+func (p *InProcessProfile) TimestampUs() int64 {
+	return p.ProfileCreateTimestamp
+}
+
 func (p *InProcessProfile) String() string {
 	return fmt.Sprintf("InProcessProfile:  %+v\n", *p)
 }
 
 func AcquireInProcess() *InProcessProfile {
 	l := poolInProcess.Get().(*InProcessProfile)
+	l.Reset()
 	return l
 }
 
 func ReleaseInProcess(p *InProcessProfile) {
-	if p == nil {
+	if p == nil || p.SubReferenceCount() {
 		return
 	}
 	tagNames := p.TagNames[:0]
@@ -274,10 +317,11 @@ func ReleaseInProcess(p *InProcessProfile) {
 func (p *InProcessProfile) Clone() *InProcessProfile {
 	c := AcquireInProcess()
 	*c = *p
+	c.Reset()
 	c.TagNames = make([]string, len(p.TagNames))
-	copy(p.TagNames, p.TagNames)
+	copy(c.TagNames, p.TagNames)
 	c.TagValues = make([]string, len(p.TagValues))
-	copy(p.TagValues, p.TagValues)
+	copy(c.TagValues, p.TagValues)
 	return c
 }
 
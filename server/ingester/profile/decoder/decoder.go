@@ -26,6 +26,9 @@ import (
 	"time"
 
 	"github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/exporters"
+	exportercommon "github.com/deepflowio/deepflow/server/ingester/exporters/common"
+	exporterconfig "github.com/deepflowio/deepflow/server/ingester/exporters/config"
 	profile_common "github.com/deepflowio/deepflow/server/ingester/profile/common"
 	"github.com/deepflowio/deepflow/server/ingester/profile/dbwriter"
 	"github.com/deepflowio/deepflow/server/libs/codec"
@@ -41,7 +44,9 @@ import (
 	"github.com/pyroscope-io/pyroscope/pkg/convert/jfr"
 	"github.com/pyroscope-io/pyroscope/pkg/convert/pprof"
 	pprofile "github.com/pyroscope-io/pyroscope/pkg/convert/profile"
+	"github.com/pyroscope-io/pyroscope/pkg/convert/speedscope"
 	"github.com/pyroscope-io/pyroscope/pkg/ingestion"
+	"github.com/pyroscope-io/pyroscope/pkg/storage"
 	"github.com/pyroscope-io/pyroscope/pkg/storage/metadata"
 	"github.com/pyroscope-io/pyroscope/pkg/storage/segment"
 )
@@ -52,6 +57,9 @@ const (
 	BUFFER_SIZE = 1024
 
 	UNICODE_NULL = "\x00"
+
+	PYROSCOPE_DEFAULT_SAMPLE_RATE = 100
+	PYROSCOPE_DEFAULT_EVENT_TYPE  = "cpu"
 )
 
 type Counter struct {
@@ -60,6 +68,11 @@ type Counter struct {
 	GolangProfileCount int64 `statsd:"golang-profile-count"`
 	EBPFProfileCount   int64 `statsd:"ebpf-profile-count"`
 
+	SpeedscopeProfileCount int64 `statsd:"speedscope-profile-count"`
+	TreeProfileCount       int64 `statsd:"tree-profile-count"`
+	TrieProfileCount       int64 `statsd:"trie-profile-count"`
+	LinesProfileCount      int64 `statsd:"lines-profile-count"`
+
 	UncompressSize int64 `statsd:"uncompress-size"`
 	CompressedSize int64 `statsd:"compressed-size"`
 
@@ -94,6 +107,7 @@ type Decoder struct {
 	platformData    *grpc.PlatformInfoTable
 	inQueue         queue.QueueReader
 	profileWriter   *dbwriter.ProfileWriter
+	exporters       *exporters.Exporters
 	compressionAlgo string
 
 	offCpuSplittingGranularity int
@@ -108,13 +122,15 @@ func NewDecoder(index int, msgType datatype.MessageType, compressionAlgo string,
 	offCpuSplittingGranularity int,
 	platformData *grpc.PlatformInfoTable,
 	inQueue queue.QueueReader,
-	profileWriter *dbwriter.ProfileWriter) *Decoder {
+	profileWriter *dbwriter.ProfileWriter,
+	exporters *exporters.Exporters) *Decoder {
 	return &Decoder{
 		index:                      index,
 		msgType:                    msgType,
 		platformData:               platformData,
 		inQueue:                    inQueue,
 		profileWriter:              profileWriter,
+		exporters:                  exporters,
 		compressionAlgo:            compressionAlgo,
 		offCpuSplittingGranularity: offCpuSplittingGranularity,
 		counter:                    &Counter{},
@@ -141,6 +157,7 @@ func (d *Decoder) Run() {
 		start := time.Now()
 		for i := 0; i < n; i++ {
 			if buffer[i] == nil {
+				d.export(nil)
 				continue
 			}
 			atomic.AddInt64(&d.counter.RawCount, 1)
@@ -160,6 +177,22 @@ func (d *Decoder) Run() {
 	}
 }
 
+func (d *Decoder) write(items []interface{}) {
+	if d.exporters != nil {
+		for _, item := range items {
+			d.export(item.(*dbwriter.InProcessProfile))
+		}
+	}
+	d.profileWriter.Write(items)
+}
+
+func (d *Decoder) export(item exportercommon.ExportItem) {
+	if d.exporters == nil {
+		return
+	}
+	d.exporters.Put(uint32(exporterconfig.PROFILE), d.index, item)
+}
+
 func (d *Decoder) handleProfileData(vtapID uint16, decoder *codec.SimpleDecoder) {
 	for !decoder.IsEnd() {
 		profile := &pb.Profile{}
@@ -174,11 +207,12 @@ func (d *Decoder) handleProfileData(vtapID uint16, decoder *codec.SimpleDecoder)
 			orgId:                      d.orgId,
 			teamId:                     d.teamId,
 			inTimestamp:                time.Now(),
-			callBack:                   d.profileWriter.Write,
+			callBack:                   d.write,
 			platformData:               d.platformData,
 			IP:                         make([]byte, len(profile.Ip)),
 			podID:                      profile.PodId,
 			compressionAlgo:            d.compressionAlgo,
+			keepRawLocation:            d.exporters.IsDataSourceExported(uint32(exporterconfig.PROFILE)),
 			observer:                   &observer{},
 			offCpuSplittingGranularity: d.offCpuSplittingGranularity,
 			Counter:                    d.counter,
@@ -249,12 +283,81 @@ func (d *Decoder) handleProfileData(vtapID uint16, decoder *codec.SimpleDecoder)
 				}
 			}
 		case "speedscope", "tree", "trie", "lines":
-			// not implemented
-			continue
+			err := d.handlePyroscopeProfile(profile, parser)
+			if err != nil {
+				log.Errorf("decode %s profile data failed, offset=%d, len=%d, err=%s", profile.Format, decoder.Offset(), len(decoder.Bytes()), err)
+				return
+			}
 		}
 	}
 }
 
+// profiles in speedscope/tree/trie/lines format are sent by pyroscope clients through the `/ingest` api
+func (d *Decoder) handlePyroscopeProfile(profile *pb.Profile, parser *Parser) error {
+	var rawProfile ingestion.RawProfile
+	switch profile.Format {
+	case "speedscope":
+		atomic.AddInt64(&d.counter.SpeedscopeProfileCount, 1)
+		rawProfile = &speedscopeProfile{speedscope.RawProfile{RawData: profile.Data}}
+	case "tree":
+		atomic.AddInt64(&d.counter.TreeProfileCount, 1)
+		rawProfile = &pprofile.RawProfile{Format: ingestion.FormatTree, RawData: profile.Data}
+	case "trie":
+		atomic.AddInt64(&d.counter.TrieProfileCount, 1)
+		rawProfile = &pprofile.RawProfile{Format: ingestion.FormatTrie, RawData: profile.Data}
+	case "lines":
+		atomic.AddInt64(&d.counter.LinesProfileCount, 1)
+		rawProfile = &pprofile.RawProfile{Format: ingestion.FormatLines, RawData: profile.Data}
+	default:
+		return fmt.Errorf("unsupported profile format %s", profile.Format)
+	}
+	profile = d.fillPyroscopeData(profile)
+	metadata := d.buildMetaData(profile)
+	parser.profileName = pyroscopeProfileName(profile.Format, metadata.Key.AppName())
+	parser.defaultEventType = PYROSCOPE_DEFAULT_EVENT_TYPE
+	return d.sendProfileData(rawProfile, profile.Format, parser, metadata)
+}
+
+// fill the defaults of pyroscope `/ingest` api if not specified
+func (d *Decoder) fillPyroscopeData(profile *pb.Profile) *pb.Profile {
+	if profile.Units == "" {
+		profile.Units = string(metadata.SamplesUnits)
+	}
+	if profile.AggregationType == "" {
+		profile.AggregationType = string(metadata.SumAggregationType)
+	}
+	if profile.SampleRate == 0 {
+		profile.SampleRate = PYROSCOPE_DEFAULT_SAMPLE_RATE
+	}
+	return profile
+}
+
+// app names of tree/trie/lines profiles are suffixed by the event type by pyroscope clients, e.g.: `app.cpu`,
+// while speedscope parser suffixes app name by unit when there are multiple profiles in a file
+func pyroscopeProfileName(format, appName string) string {
+	if format == "speedscope" {
+		return appName
+	}
+	if i := strings.LastIndex(appName, "."); i > 0 {
+		return appName[:i]
+	}
+	return appName
+}
+
+// speedscope parser panics on unknown units, recover it as an error
+type speedscopeProfile struct {
+	speedscope.RawProfile
+}
+
+func (p *speedscopeProfile) Parse(ctx context.Context, putter storage.Putter, exporter storage.MetricsExporter, md ingestion.Metadata) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("parse speedscope profile failed: %v", r)
+		}
+	}()
+	return p.RawProfile.Parse(ctx, putter, exporter, md)
+}
+
 func (d *Decoder) filleBPFData(profile *pb.Profile) *pb.Profile {
 	profile.From = uint32(profile.Timestamp / 1e9) // ns to s
 	profile.Until = uint32(time.Now().Unix())
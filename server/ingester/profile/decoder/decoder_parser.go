@@ -44,14 +44,18 @@ type Parser struct {
 	orgId, teamId uint16
 	IP            net.IP
 	podID         uint32
+	// event type of profiles whose app name is not suffixed by the event type
+	defaultEventType string
 
-	// profileWriter.Write
+	// Decoder.write, export and profileWriter.Write
 	callBack                   func([]interface{})
 	offCpuSplittingGranularity int
 
 	platformData    *grpc.PlatformInfoTable
 	inTimestamp     time.Time
 	compressionAlgo string
+	// keep the uncompressed location for exporting
+	keepRawLocation bool
 	*observer
 	*processTracer
 	*Counter
@@ -121,6 +125,9 @@ func (p *Parser) stackToInProcess(input *storage.PutInput, stack []string, value
 		eventType = p.processTracer.eventType
 	} else {
 		eventType = strings.TrimPrefix(input.Key.AppName(), fmt.Sprintf("%s.", p.profileName))
+		if eventType == input.Key.AppName() && p.defaultEventType != "" {
+			eventType = p.defaultEventType
+		}
 	}
 	ret.FillProfile(input,
 		p.platformData,
@@ -138,6 +145,9 @@ func (p *Parser) stackToInProcess(input *storage.PutInput, stack []string, value
 		stime,
 		tagNames,
 		tagValues)
+	if p.keepRawLocation {
+		ret.ProfileLocation = onelineStack
+	}
 
 	var writeItems []interface{}
 	granularityUs := int64(p.offCpuSplittingGranularity) * int64(time.Second/time.Microsecond)
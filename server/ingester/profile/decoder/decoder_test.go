@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package decoder
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/ingester/profile/dbwriter"
+	"github.com/deepflowio/deepflow/server/libs/flow-metrics/pb"
+	"github.com/deepflowio/deepflow/server/libs/grpc"
+)
+
+func TestHandlePyroscopeProfile(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		format    string
+		fixture   string
+		name      string
+		eventType string
+		unit      string
+		values    map[string]int64
+		counter   func(*Counter) int64
+	}{{
+		format:    "tree",
+		fixture:   "testdata/cpu.tree",
+		name:      "app.cpu",
+		eventType: "cpu",
+		unit:      "samples",
+		values:    map[string]int64{"main;foo;bar": 30, "main;foo;baz": 20, "main;qux": 10},
+		counter:   func(c *Counter) int64 { return c.TreeProfileCount },
+	}, {
+		format:    "trie",
+		fixture:   "testdata/cpu.trie",
+		name:      "app.cpu",
+		eventType: "cpu",
+		unit:      "samples",
+		values:    map[string]int64{"main;foo;bar": 30, "main;foo;baz": 20, "main;qux": 10},
+		counter:   func(c *Counter) int64 { return c.TrieProfileCount },
+	}, {
+		format:    "lines",
+		fixture:   "testdata/cpu.lines",
+		name:      "app.cpu",
+		eventType: "cpu",
+		unit:      "samples",
+		values:    map[string]int64{"main;foo;bar": 3, "main;foo;baz": 2, "main;qux": 1},
+		counter:   func(c *Counter) int64 { return c.LinesProfileCount },
+	}, {
+		// weights of speedscope profiles are multiplied by 100 for time precision
+		format:    "speedscope",
+		fixture:   "testdata/cpu.speedscope.json",
+		name:      "app",
+		eventType: "cpu",
+		unit:      "samples",
+		values:    map[string]int64{"main;foo;bar": 3000, "main;foo;baz": 2000, "main;qux": 1000},
+		counter:   func(c *Counter) int64 { return c.SpeedscopeProfileCount },
+	}}
+
+	platformData := grpc.NewPlatformInfoTable(nil, 0, 0, 0, "", "", nil, true, nil)
+	for _, c := range cases {
+		data, err := os.ReadFile(c.fixture)
+		if err != nil {
+			t.Fatalf("read %s failed: %s", c.fixture, err)
+		}
+		decoder := &Decoder{counter: &Counter{}}
+		values := map[string]int64{}
+		parser := &Parser{
+			inTimestamp:  time.Now(),
+			platformData: platformData,
+			observer:     &observer{},
+			Counter:      decoder.counter,
+			callBack: func(items []interface{}) {
+				for _, item := range items {
+					profile := item.(*dbwriter.InProcessProfile)
+					values[profile.ProfileLocationStr] += profile.ProfileValue
+					if profile.AppService != "app" || profile.ProfileEventType != c.eventType || profile.ProfileValueUnit != c.unit {
+						t.Errorf("format %s: got app_service %s, event type %s, unit %s", c.format, profile.AppService, profile.ProfileEventType, profile.ProfileValueUnit)
+					}
+				}
+			},
+		}
+		profile := &pb.Profile{
+			Name:    c.name,
+			Format:  c.format,
+			From:    uint32(from.Unix()),
+			Until:   uint32(from.Add(10 * time.Second).Unix()),
+			SpyName: "pyspy",
+			Data:    data,
+		}
+		if err := decoder.handlePyroscopeProfile(profile, parser); err != nil {
+			t.Errorf("format %s: handle profile failed: %s", c.format, err)
+			continue
+		}
+		if !reflect.DeepEqual(values, c.values) {
+			t.Errorf("format %s: got values %v, want %v", c.format, values, c.values)
+		}
+		if c.counter(decoder.counter) != 1 {
+			t.Errorf("format %s: counter is not increased", c.format)
+		}
+	}
+}
+
+func TestHandlePyroscopeProfileInvalid(t *testing.T) {
+	decoder := &Decoder{counter: &Counter{}}
+	parser := &Parser{observer: &observer{}, Counter: decoder.counter}
+	// unknown unit makes speedscope parser panic
+	data := []byte(`{"$schema":"https://www.speedscope.app/file-format-schema.json","shared":{"frames":[{"name":"main"}]},` +
+		`"profiles":[{"type":"sampled","unit":"unknown","samples":[[0]],"weights":[1]}]}`)
+	if err := decoder.handlePyroscopeProfile(&pb.Profile{Name: "app", Format: "speedscope", Data: data}, parser); err == nil {
+		t.Error("speedscope profile with unknown unit should fail")
+	}
+	if err := decoder.handlePyroscopeProfile(&pb.Profile{Name: "app.cpu", Format: "groups"}, parser); err == nil {
+		t.Error("groups format should be unsupported")
+	}
+}
@@ -34,6 +34,7 @@ const DEFAULT_COLUMN_COUNT = 256
 type Block struct {
 	batch driver.Batch
 	items []interface{}
+	rows  [][]interface{} // only used when batch is nil
 }
 
 func NewBlock(batch driver.Batch) *Block {
@@ -43,13 +44,35 @@ func NewBlock(batch driver.Batch) *Block {
 	}
 }
 
+// NewRowsBlock creates a block without batch, which keeps the written rows in memory and can not be sent
+func NewRowsBlock() *Block {
+	return &Block{
+		items: make([]interface{}, 0, DEFAULT_COLUMN_COUNT),
+	}
+}
+
 func (b *Block) WriteAll() error {
+	if b.batch == nil {
+		row := make([]interface{}, len(b.items))
+		copy(row, b.items)
+		b.rows = append(b.rows, row)
+		b.items = b.items[:0]
+		return nil
+	}
 	err := b.batch.Append(b.items...)
 	b.items = b.items[:0]
 	return err
 }
 
+// Rows returns the rows written to the block created by 'NewRowsBlock'
+func (b *Block) Rows() [][]interface{} {
+	return b.rows
+}
+
 func (b *Block) Send() error {
+	if b.batch == nil {
+		return fmt.Errorf("block has no batch to send")
+	}
 	return b.batch.Send()
 }
 
@@ -26,30 +26,30 @@ type UniversalTag struct {
 	// 注意：字节对齐！
 	// Note: byte alignment!
 
-	IP6            net.IP // FIXME: merge IP6 and IP
-	IP             uint32
-	L3EpcID        int32 // (8B)
-	L3DeviceID     uint32
-	RegionID       uint16
-	SubnetID       uint16
-	HostID         uint16
-	AZID           uint16
-	PodClusterID   uint16
-	PodNSID        uint16
-	PodID          uint32
-	PodNodeID      uint32
-	PodGroupID     uint32
-	ServiceID      uint32
-	AutoInstanceID uint32
-	AutoServiceID  uint32
-	GPID           uint32
+	IP6            net.IP `json:"ip6" category:"$tag" sub:"network_layer" to_string:"IPv6String" data_type:"net.IP"` // FIXME: merge IP6 and IP
+	IP             uint32 `json:"ip4" category:"$tag" sub:"network_layer" to_string:"IPv4String"`
+	L3EpcID        int32  `json:"l3_epc_id" category:"$tag" sub:"universal_tag"` // (8B)
+	L3DeviceID     uint32 `json:"l3_device_id" category:"$tag" sub:"universal_tag"`
+	RegionID       uint16 `json:"region_id" category:"$tag" sub:"universal_tag"`
+	SubnetID       uint16 `json:"subnet_id" category:"$tag" sub:"universal_tag"`
+	HostID         uint16 `json:"host_id" category:"$tag" sub:"universal_tag"`
+	AZID           uint16 `json:"az_id" category:"$tag" sub:"universal_tag"`
+	PodClusterID   uint16 `json:"pod_cluster_id" category:"$tag" sub:"universal_tag"`
+	PodNSID        uint16 `json:"pod_ns_id" category:"$tag" sub:"universal_tag"`
+	PodID          uint32 `json:"pod_id" category:"$tag" sub:"universal_tag"`
+	PodNodeID      uint32 `json:"pod_node_id" category:"$tag" sub:"universal_tag"`
+	PodGroupID     uint32 `json:"pod_group_id" category:"$tag" sub:"universal_tag"`
+	ServiceID      uint32 `json:"service_id" category:"$tag" sub:"universal_tag"`
+	AutoInstanceID uint32 `json:"auto_instance_id" category:"$tag" sub:"universal_tag"`
+	AutoServiceID  uint32 `json:"auto_service_id" category:"$tag" sub:"universal_tag"`
+	GPID           uint32 `json:"gprocess_id" category:"$tag" sub:"universal_tag"`
 
 	IsIPv6           uint8
-	L3DeviceType     DeviceType
-	AutoInstanceType uint8
-	AutoServiceType  uint8
+	L3DeviceType     DeviceType `json:"l3_device_type" category:"$tag" sub:"universal_tag"`
+	AutoInstanceType uint8      `json:"auto_instance_type" category:"$tag" sub:"universal_tag" enumfile:"auto_instance_type"`
+	AutoServiceType  uint8      `json:"auto_service_type" category:"$tag" sub:"universal_tag" enumfile:"auto_service_type"`
 
-	VTAPID uint16
+	VTAPID uint16 `json:"agent_id" category:"$tag" sub:"universal_tag"`
 	//SignalSource uint16
 }
 
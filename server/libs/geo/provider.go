@@ -0,0 +1,255 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geo
+
+import (
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+const (
+	DEFAULT_LANGUAGE = "en"
+	// decoded records are cached by offset, the cache is cleared when it is full
+	MMDB_RECORD_CACHE_SIZE = 1 << 16
+)
+
+type Location struct {
+	Country string
+	Region  string
+	City    string
+	ASN     uint32
+	Org     string
+}
+
+type GeoProvider interface {
+	// Lookup returns nil if ip is not found, supports both IPv4 and IPv6
+	Lookup(ip net.IP) *Location
+	Close()
+}
+
+type mmdbFile struct {
+	path    string
+	modTime time.Time
+	reader  *maxminddb.Reader
+
+	cacheLock sync.RWMutex
+	cache     map[uintptr]*Location
+}
+
+type mmdbPlace struct {
+	ISOCode string            `maxminddb:"iso_code"`
+	Names   map[string]string `maxminddb:"names"`
+}
+
+// mmdbRecord contains fields of GeoIP2/GeoLite2 City, Country, ASN, ISP databases,
+// DB-IP databases are in the same structure.
+type mmdbRecord struct {
+	Country                      mmdbPlace   `maxminddb:"country"`
+	RegisteredCountry            mmdbPlace   `maxminddb:"registered_country"`
+	Subdivisions                 []mmdbPlace `maxminddb:"subdivisions"`
+	City                         mmdbPlace   `maxminddb:"city"`
+	AutonomousSystemNumber       uint32      `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string      `maxminddb:"autonomous_system_organization"`
+	Organization                 string      `maxminddb:"organization"`
+	ISP                          string      `maxminddb:"isp"`
+}
+
+// MMDBProvider looks up MaxMind/DB-IP MMDB files, results of all files are merged, so that a city database
+// and an ASN database could be used together. Files are reloaded when they are modified.
+type MMDBProvider struct {
+	language string
+	files    atomic.Value // []*mmdbFile
+	exit     chan struct{}
+}
+
+func NewMMDBProvider(paths []string, language string, reloadInterval time.Duration) (*MMDBProvider, error) {
+	if language == "" {
+		language = DEFAULT_LANGUAGE
+	}
+	files := make([]*mmdbFile, 0, len(paths))
+	for _, path := range paths {
+		file, err := loadMMDBFile(path)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("mmdb %s loaded, database type: %s, ip version: %d, build epoch: %d",
+			path, file.reader.Metadata.DatabaseType, file.reader.Metadata.IPVersion, file.reader.Metadata.BuildEpoch)
+		files = append(files, file)
+	}
+	p := &MMDBProvider{language: language, exit: make(chan struct{})}
+	p.files.Store(files)
+	if reloadInterval > 0 {
+		go p.run(reloadInterval)
+	}
+	return p, nil
+}
+
+func loadMMDBFile(path string) (*mmdbFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	// read the whole file instead of mmap, so that the old reader can be dropped without unmapping
+	// while it is still being looked up after reloading
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := maxminddb.FromBytes(buffer)
+	if err != nil {
+		return nil, err
+	}
+	return &mmdbFile{path: path, modTime: info.ModTime(), reader: reader, cache: make(map[uintptr]*Location)}, nil
+}
+
+func (p *MMDBProvider) run(reloadInterval time.Duration) {
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.exit:
+			return
+		case <-ticker.C:
+			p.reloadIfModified()
+		}
+	}
+}
+
+func (p *MMDBProvider) reloadIfModified() {
+	files := p.files.Load().([]*mmdbFile)
+	newFiles := make([]*mmdbFile, len(files))
+	reloaded := false
+	for i, file := range files {
+		newFiles[i] = file
+		info, err := os.Stat(file.path)
+		if err != nil || !info.ModTime().After(file.modTime) {
+			continue
+		}
+		// the file may be partially written, keep the old one and retry next time
+		newFile, err := loadMMDBFile(file.path)
+		if err != nil {
+			log.Warningf("reload mmdb %s failed: %s", file.path, err)
+			continue
+		}
+		log.Infof("mmdb %s reloaded, build epoch: %d", file.path, newFile.reader.Metadata.BuildEpoch)
+		newFiles[i] = newFile
+		reloaded = true
+	}
+	if reloaded {
+		p.files.Store(newFiles)
+	}
+}
+
+func (p *MMDBProvider) Lookup(ip net.IP) *Location {
+	var result *Location
+	for _, file := range p.files.Load().([]*mmdbFile) {
+		location := file.lookup(ip, p.language)
+		if location == nil {
+			continue
+		}
+		if result == nil {
+			result = &Location{}
+		}
+		result.merge(location)
+	}
+	return result
+}
+
+func (p *MMDBProvider) Close() {
+	close(p.exit)
+}
+
+func (f *mmdbFile) lookup(ip net.IP, language string) *Location {
+	offset, err := f.reader.LookupOffset(ip)
+	if err != nil || offset == maxminddb.NotFound {
+		return nil
+	}
+	f.cacheLock.RLock()
+	location, ok := f.cache[offset]
+	f.cacheLock.RUnlock()
+	if ok {
+		return location
+	}
+
+	record := &mmdbRecord{}
+	if err := f.reader.Decode(offset, record); err != nil {
+		return nil
+	}
+	location = recordToLocation(record, language)
+	f.cacheLock.Lock()
+	if len(f.cache) >= MMDB_RECORD_CACHE_SIZE {
+		f.cache = make(map[uintptr]*Location)
+	}
+	f.cache[offset] = location
+	f.cacheLock.Unlock()
+	return location
+}
+
+func (l *Location) merge(o *Location) {
+	if l.Country == "" {
+		l.Country = o.Country
+	}
+	if l.Region == "" {
+		l.Region = o.Region
+	}
+	if l.City == "" {
+		l.City = o.City
+	}
+	if l.ASN == 0 {
+		l.ASN = o.ASN
+	}
+	if l.Org == "" {
+		l.Org = o.Org
+	}
+}
+
+func recordToLocation(record *mmdbRecord, language string) *Location {
+	location := &Location{
+		Country: record.Country.name(language),
+		City:    record.City.name(language),
+		ASN:     record.AutonomousSystemNumber,
+	}
+	if location.Country == "" {
+		location.Country = record.RegisteredCountry.name(language)
+	}
+	if len(record.Subdivisions) > 0 {
+		location.Region = record.Subdivisions[0].name(language)
+	}
+	for _, org := range []string{record.AutonomousSystemOrganization, record.Organization, record.ISP} {
+		if org != "" {
+			location.Org = org
+			break
+		}
+	}
+	return location
+}
+
+// name returns name in language of a place, falls back to English name and ISO code
+func (p *mmdbPlace) name(language string) string {
+	if name := p.Names[language]; name != "" {
+		return name
+	}
+	if name := p.Names[DEFAULT_LANGUAGE]; name != "" {
+		return name
+	}
+	return p.ISOCode
+}
@@ -0,0 +1,246 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geo
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// MaxMind DB file format, ref: https://maxmind.github.io/MaxMind-DB/
+const (
+	mmdbDataSectionSeparate = 16
+
+	mmdbString = 2
+	mmdbUint16 = 5
+	mmdbUint32 = 6
+	mmdbMap    = 7
+	mmdbUint64 = 9
+	mmdbArray  = 11
+	mmdbBool   = 14
+)
+
+var mmdbMetadataStart = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// mmdbWriter writes ipv6 databases with 24 bits records for tests
+type mmdbWriter struct {
+	nodes   [][2]int // >= 0: node index, < 0: -(index of data + 1), nodeEmpty: empty
+	data    bytes.Buffer
+	offsets []int
+}
+
+const nodeEmpty = 1 << 30
+
+func newMMDBWriter() *mmdbWriter {
+	return &mmdbWriter{nodes: [][2]int{{nodeEmpty, nodeEmpty}}}
+}
+
+func (w *mmdbWriter) insert(cidr string, record interface{}) {
+	_, network, _ := net.ParseCIDR(cidr)
+	ip, ones := network.IP, 0
+	prefixLen, _ := network.Mask.Size()
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, ones = net.IP(append(make([]byte, 12), ip4...)), 96
+	}
+	prefixLen += ones
+
+	w.offsets = append(w.offsets, w.data.Len())
+	encodeMMDB(&w.data, record)
+	node := 0
+	for i := 0; i < prefixLen; i++ {
+		bit := (ip[i>>3] >> (7 - uint(i&7))) & 1
+		if i == prefixLen-1 {
+			w.nodes[node][bit] = -len(w.offsets)
+			break
+		}
+		if w.nodes[node][bit] == nodeEmpty {
+			w.nodes = append(w.nodes, [2]int{nodeEmpty, nodeEmpty})
+			w.nodes[node][bit] = len(w.nodes) - 1
+		}
+		node = w.nodes[node][bit]
+	}
+}
+
+func (w *mmdbWriter) bytes() []byte {
+	var buf bytes.Buffer
+	nodeCount := len(w.nodes)
+	for _, node := range w.nodes {
+		for _, record := range node {
+			value := record
+			if record == nodeEmpty {
+				value = nodeCount
+			} else if record < 0 {
+				value = nodeCount + mmdbDataSectionSeparate + w.offsets[-record-1]
+			}
+			buf.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+	buf.Write(make([]byte, mmdbDataSectionSeparate))
+	buf.Write(w.data.Bytes())
+	buf.Write(mmdbMetadataStart)
+	encodeMMDB(&buf, map[string]interface{}{
+		"node_count":    uint32(nodeCount),
+		"record_size":   uint16(24),
+		"ip_version":    uint16(6),
+		"database_type": "Test",
+		"build_epoch":   uint64(1700000000),
+	})
+	return buf.Bytes()
+}
+
+func writeMMDBControl(buf *bytes.Buffer, typeNum, size int) {
+	ctrl := []byte{0}
+	if typeNum > 7 {
+		ctrl = append(ctrl, byte(typeNum-7))
+	} else {
+		ctrl[0] = byte(typeNum << 5)
+	}
+	if size < 29 {
+		ctrl[0] |= byte(size)
+		buf.Write(ctrl)
+	} else {
+		ctrl[0] |= 29
+		buf.Write(append(ctrl, byte(size-29)))
+	}
+}
+
+func encodeMMDB(buf *bytes.Buffer, v interface{}) {
+	switch value := v.(type) {
+	case string:
+		writeMMDBControl(buf, mmdbString, len(value))
+		buf.WriteString(value)
+	case uint16:
+		writeMMDBControl(buf, mmdbUint16, 2)
+		buf.Write([]byte{byte(value >> 8), byte(value)})
+	case uint32:
+		writeMMDBControl(buf, mmdbUint32, 4)
+		buf.Write([]byte{byte(value >> 24), byte(value >> 16), byte(value >> 8), byte(value)})
+	case uint64:
+		writeMMDBControl(buf, mmdbUint64, 8)
+		for i := 7; i >= 0; i-- {
+			buf.WriteByte(byte(value >> (8 * i)))
+		}
+	case bool:
+		size := 0
+		if value {
+			size = 1
+		}
+		writeMMDBControl(buf, mmdbBool, size)
+	case []interface{}:
+		writeMMDBControl(buf, mmdbArray, len(value))
+		for _, e := range value {
+			encodeMMDB(buf, e)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeMMDBControl(buf, mmdbMap, len(value))
+		for _, k := range keys {
+			encodeMMDB(buf, k)
+			encodeMMDB(buf, value[k])
+		}
+	}
+}
+
+func cityRecord(countryCode, country, region, city string) map[string]interface{} {
+	return map[string]interface{}{
+		"country":      map[string]interface{}{"iso_code": countryCode, "names": map[string]interface{}{"en": country}},
+		"subdivisions": []interface{}{map[string]interface{}{"names": map[string]interface{}{"en": region, "zh-CN": region + "-zh"}}},
+		"city":         map[string]interface{}{"names": map[string]interface{}{"en": city}},
+	}
+}
+
+func TestRecordToLocation(t *testing.T) {
+	record := &mmdbRecord{
+		RegisteredCountry: mmdbPlace{ISOCode: "JP"},
+		City:              mmdbPlace{Names: map[string]string{"en": "Tokyo", "zh-CN": "东京"}},
+		Organization:      "NTT",
+		ISP:               "NTT Communications",
+	}
+	location := recordToLocation(record, "zh-CN")
+	if !reflect.DeepEqual(location, &Location{Country: "JP", City: "东京", Org: "NTT"}) {
+		t.Errorf("wrong location %+v", location)
+	}
+}
+
+func TestMMDBProvider(t *testing.T) {
+	dir := t.TempDir()
+	cityFile, asnFile := filepath.Join(dir, "city.mmdb"), filepath.Join(dir, "asn.mmdb")
+	city := newMMDBWriter()
+	city.insert("8.8.8.0/24", cityRecord("US", "United States", "California", "Mountain View"))
+	city.insert("2001:db8::/32", cityRecord("DE", "Germany", "Hesse", "Frankfurt"))
+	asn := newMMDBWriter()
+	asn.insert("8.8.0.0/16", map[string]interface{}{"autonomous_system_number": uint32(15169), "autonomous_system_organization": "Google LLC"})
+	if err := os.WriteFile(cityFile, city.bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(asnFile, asn.bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	invalidFile := filepath.Join(dir, "invalid.mmdb")
+	if err := os.WriteFile(invalidFile, []byte("not a mmdb file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewMMDBProvider([]string{cityFile, invalidFile}, "", 0); err == nil {
+		t.Error("create provider with invalid mmdb should fail")
+	}
+
+	provider, err := NewMMDBProvider([]string{cityFile, asnFile}, "zh-CN", 0)
+	if err != nil {
+		t.Fatalf("create provider failed: %s", err)
+	}
+	defer provider.Close()
+	cases := []struct {
+		ip       string
+		location *Location
+	}{
+		{"8.8.8.8", &Location{Country: "United States", Region: "California-zh", City: "Mountain View", ASN: 15169, Org: "Google LLC"}},
+		{"8.8.4.4", &Location{ASN: 15169, Org: "Google LLC"}},
+		{"2001:db8::1", &Location{Country: "Germany", Region: "Hesse-zh", City: "Frankfurt"}},
+		{"1.1.1.1", nil},
+	}
+	for _, c := range cases {
+		// the second lookup hits the cache
+		for i := 0; i < 2; i++ {
+			if location := provider.Lookup(net.ParseIP(c.ip)); !reflect.DeepEqual(location, c.location) {
+				t.Errorf("lookup %s, got %+v, want %+v", c.ip, location, c.location)
+			}
+		}
+	}
+
+	city = newMMDBWriter()
+	city.insert("8.8.8.0/24", cityRecord("US", "United States", "New York", "New York"))
+	if err := os.WriteFile(cityFile, city.bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(cityFile, future, future)
+	provider.reloadIfModified()
+	if location := provider.Lookup(net.ParseIP("8.8.8.8")); location == nil || location.City != "New York" {
+		t.Errorf("mmdb is not reloaded, got %+v", location)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pcap
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// https://www.ietf.org/archive/id/draft-ietf-opsawg-pcapng-01.html
+const (
+	PCAPNG_BLOCK_SECTION_HEADER  = 0x0A0D0D0A
+	PCAPNG_BLOCK_INTERFACE       = 0x00000001
+	PCAPNG_BLOCK_ENHANCED_PACKET = 0x00000006
+
+	PCAPNG_BYTE_ORDER_MAGIC = 0x1A2B3C4D
+
+	PCAPNG_OPTION_END         = 0
+	PCAPNG_OPTION_COMMENT     = 1
+	PCAPNG_OPTION_SHB_APP     = 4
+	PCAPNG_OPTION_IF_NAME     = 2
+	PCAPNG_OPTION_IF_TSRESOL  = 9
+	PCAPNG_TSRESOL_NANOSECOND = 9
+)
+
+type pcapngOption struct {
+	code  uint16
+	value []byte
+}
+
+// PcapngWriter writes a little endian pcapng file with a single section,
+// timestamps of all interfaces are in nanoseconds.
+type PcapngWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func NewPcapngWriter(w io.Writer) *PcapngWriter {
+	return &PcapngWriter{w: w}
+}
+
+// WriteSectionHeader should be called once before other blocks, each comment is an opt_comment of the section
+func (w *PcapngWriter) WriteSectionHeader(application string, comments ...string) error {
+	body := make([]byte, 16)
+	binary.LittleEndian.PutUint32(body, PCAPNG_BYTE_ORDER_MAGIC)
+	binary.LittleEndian.PutUint16(body[4:], 1) // major version
+	binary.LittleEndian.PutUint16(body[6:], 0) // minor version
+	// section length is not specified
+	binary.LittleEndian.PutUint64(body[8:], 0xFFFFFFFFFFFFFFFF)
+	options := make([]pcapngOption, 0, len(comments)+1)
+	for _, comment := range comments {
+		options = append(options, pcapngOption{PCAPNG_OPTION_COMMENT, []byte(comment)})
+	}
+	if application != "" {
+		options = append(options, pcapngOption{PCAPNG_OPTION_SHB_APP, []byte(application)})
+	}
+	return w.writeBlock(PCAPNG_BLOCK_SECTION_HEADER, body, nil, options)
+}
+
+// WriteInterface writes an interface description block, interface ids are assigned from 0 in the writing order
+func (w *PcapngWriter) WriteInterface(linkType, snapLen uint32, name string) error {
+	body := make([]byte, 8)
+	binary.LittleEndian.PutUint16(body, uint16(linkType))
+	binary.LittleEndian.PutUint32(body[4:], snapLen)
+	options := []pcapngOption{{PCAPNG_OPTION_IF_TSRESOL, []byte{PCAPNG_TSRESOL_NANOSECOND}}}
+	if name != "" {
+		options = append(options, pcapngOption{PCAPNG_OPTION_IF_NAME, []byte(name)})
+	}
+	return w.writeBlock(PCAPNG_BLOCK_INTERFACE, body, nil, options)
+}
+
+func (w *PcapngWriter) WritePacket(interfaceID uint32, packet *Packet) error {
+	body := make([]byte, 20)
+	binary.LittleEndian.PutUint32(body, interfaceID)
+	binary.LittleEndian.PutUint32(body[4:], uint32(uint64(packet.Timestamp)>>32))
+	binary.LittleEndian.PutUint32(body[8:], uint32(packet.Timestamp))
+	binary.LittleEndian.PutUint32(body[12:], uint32(len(packet.Data)))
+	binary.LittleEndian.PutUint32(body[16:], packet.OriginalLen)
+	return w.writeBlock(PCAPNG_BLOCK_ENHANCED_PACKET, body, packet.Data, nil)
+}
+
+// writeBlock writes | type | total length | body | data padded | options | total length |
+func (w *PcapngWriter) writeBlock(blockType uint32, body, data []byte, options []pcapngOption) error {
+	buf := w.buf[:0]
+	buf = appendU32(buf, blockType)
+	buf = appendU32(buf, 0) // total length is filled later
+	buf = append(buf, body...)
+	buf = appendPadded(buf, data)
+	if len(options) > 0 {
+		for _, option := range options {
+			buf = appendU16(buf, option.code)
+			buf = appendU16(buf, uint16(len(option.value)))
+			buf = appendPadded(buf, option.value)
+		}
+		buf = appendU16(buf, PCAPNG_OPTION_END)
+		buf = appendU16(buf, 0)
+	}
+	totalLength := uint32(len(buf) + 4)
+	binary.LittleEndian.PutUint32(buf[4:], totalLength)
+	buf = appendU32(buf, totalLength)
+	w.buf = buf
+	_, err := w.w.Write(buf)
+	return err
+}
+
+func appendU16(buf []byte, v uint16) []byte {
+	return append(buf, byte(v), byte(v>>8))
+}
+
+func appendU32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendPadded(buf []byte, data []byte) []byte {
+	buf = append(buf, data...)
+	for i := len(data); i%4 != 0; i++ {
+		buf = append(buf, 0)
+	}
+	return buf
+}
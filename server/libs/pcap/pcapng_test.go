@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func encodePcap(order binary.ByteOrder, magic uint32, records [][4]uint32, payloads [][]byte) []byte {
+	buf := make([]byte, PCAP_HEADER_LEN)
+	order.PutUint32(buf, magic)
+	order.PutUint16(buf[4:], 2)
+	order.PutUint16(buf[6:], 4)
+	order.PutUint32(buf[16:], 65535)
+	order.PutUint32(buf[20:], LINK_TYPE_ETHERNET)
+	for i, record := range records {
+		header := make([]byte, PCAP_RECORD_HEADER_LEN)
+		for j, v := range record {
+			order.PutUint32(header[j*4:], v)
+		}
+		buf = append(buf, header...)
+		buf = append(buf, payloads[i]...)
+	}
+	return buf
+}
+
+func TestReadPcap(t *testing.T) {
+	payloads := [][]byte{[]byte("abc"), []byte("defgh")}
+	data := encodePcap(binary.LittleEndian, PCAP_MAGIC_MICROSECOND, [][4]uint32{{10, 20, 3, 60}, {11, 0, 5, 5}}, payloads)
+	file, err := ReadPcap(data)
+	if err != nil {
+		t.Fatalf("read pcap failed: %s", err)
+	}
+	if file.LinkType != LINK_TYPE_ETHERNET || file.SnapLen != 65535 || len(file.Packets) != 2 {
+		t.Fatalf("wrong pcap file %+v", file)
+	}
+	if p := file.Packets[0]; p.Timestamp != 10000020000 || p.OriginalLen != 60 || string(p.Data) != "abc" {
+		t.Errorf("wrong packet %+v", p)
+	}
+
+	data = encodePcap(binary.BigEndian, PCAP_MAGIC_NANOSECOND, [][4]uint32{{10, 20, 3, 3}}, payloads[:1])
+	if file, err = ReadPcap(data); err != nil || file.Packets[0].Timestamp != 10000000020 {
+		t.Errorf("read big endian nanosecond pcap failed, file %+v, err %v", file, err)
+	}
+
+	data = encodePcap(binary.LittleEndian, PCAP_MAGIC_MICROSECOND, [][4]uint32{{10, 20, 3, 3}, {11, 0, 5, 5}}, payloads)
+	if file, err = ReadPcap(data[:len(data)-1]); err == nil || len(file.Packets) != 1 {
+		t.Errorf("truncated pcap should return the first packet and an error, file %+v, err %v", file, err)
+	}
+	if _, err = ReadPcap(make([]byte, PCAP_HEADER_LEN)); err == nil {
+		t.Error("read pcap with invalid magic should fail")
+	}
+}
+
+func TestPcapngWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewPcapngWriter(&buf)
+	if err := w.WriteSectionHeader("test", "flow_id: 1"); err != nil {
+		t.Fatal(err)
+	}
+	w.WriteInterface(LINK_TYPE_ETHERNET, 65535, "agent-1")
+	w.WritePacket(0, &Packet{Timestamp: 1<<32 + 5, OriginalLen: 60, Data: []byte("abcde")})
+
+	data := buf.Bytes()
+	blockTypes := []uint32{}
+	for offset := 0; offset < len(data); {
+		blockType := binary.LittleEndian.Uint32(data[offset:])
+		length := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		if length%4 != 0 || offset+length > len(data) || binary.LittleEndian.Uint32(data[offset+length-4:]) != uint32(length) {
+			t.Fatalf("invalid block length %d at offset %d", length, offset)
+		}
+		body := data[offset+8 : offset+length-4]
+		switch blockType {
+		case PCAPNG_BLOCK_SECTION_HEADER:
+			if binary.LittleEndian.Uint32(body) != PCAPNG_BYTE_ORDER_MAGIC || !bytes.Contains(body, []byte("flow_id: 1")) {
+				t.Errorf("wrong section header %v", body)
+			}
+		case PCAPNG_BLOCK_ENHANCED_PACKET:
+			if binary.LittleEndian.Uint32(body[4:]) != 1 || binary.LittleEndian.Uint32(body[8:]) != 5 ||
+				binary.LittleEndian.Uint32(body[12:]) != 5 || binary.LittleEndian.Uint32(body[16:]) != 60 ||
+				string(body[20:25]) != "abcde" || len(body) != 28 {
+				t.Errorf("wrong packet block %v", body)
+			}
+		}
+		blockTypes = append(blockTypes, blockType)
+		offset += length
+	}
+	if len(blockTypes) != 3 || blockTypes[1] != PCAPNG_BLOCK_INTERFACE {
+		t.Errorf("wrong blocks %v", blockTypes)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// https://datatracker.ietf.org/doc/id/draft-gharris-opsawg-pcap-00.html
+const (
+	PCAP_MAGIC_MICROSECOND = 0xa1b2c3d4
+	PCAP_MAGIC_NANOSECOND  = 0xa1b23c4d
+
+	PCAP_HEADER_LEN        = 24
+	PCAP_RECORD_HEADER_LEN = 16
+
+	LINK_TYPE_ETHERNET = 1
+)
+
+var errPcapTruncated = errors.New("pcap is truncated")
+
+type Packet struct {
+	Timestamp   int64 // ns
+	OriginalLen uint32
+	Data        []byte
+}
+
+// PcapFile is a decoded libpcap file, Data of packets refers to the original buffer
+type PcapFile struct {
+	LinkType uint32
+	SnapLen  uint32
+	Packets  []Packet
+}
+
+// ReadPcap decodes a libpcap file with microsecond or nanosecond timestamps in either byte order,
+// packets decoded before a truncated record are returned with the error.
+func ReadPcap(data []byte) (*PcapFile, error) {
+	if len(data) < PCAP_HEADER_LEN {
+		return nil, errPcapTruncated
+	}
+	var order binary.ByteOrder
+	var tsUnit int64
+	for _, o := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch o.Uint32(data) {
+		case PCAP_MAGIC_MICROSECOND:
+			order, tsUnit = o, 1000
+		case PCAP_MAGIC_NANOSECOND:
+			order, tsUnit = o, 1
+		}
+		if order != nil {
+			break
+		}
+	}
+	if order == nil {
+		return nil, fmt.Errorf("invalid pcap magic 0x%x", binary.LittleEndian.Uint32(data))
+	}
+	file := &PcapFile{
+		SnapLen:  order.Uint32(data[16:]),
+		LinkType: order.Uint32(data[20:]),
+	}
+
+	for offset := PCAP_HEADER_LEN; offset < len(data); {
+		if offset+PCAP_RECORD_HEADER_LEN > len(data) {
+			return file, errPcapTruncated
+		}
+		header := data[offset : offset+PCAP_RECORD_HEADER_LEN]
+		capturedLen := int(order.Uint32(header[8:]))
+		offset += PCAP_RECORD_HEADER_LEN
+		if offset+capturedLen > len(data) {
+			return file, errPcapTruncated
+		}
+		file.Packets = append(file.Packets, Packet{
+			Timestamp:   int64(order.Uint32(header))*1000000000 + int64(order.Uint32(header[4:]))*tsUnit,
+			OriginalLen: order.Uint32(header[12:]),
+			Data:        data[offset : offset+capturedLen],
+		})
+		offset += capturedLen
+	}
+	return file, nil
+}
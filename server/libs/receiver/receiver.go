@@ -397,6 +397,8 @@ type Receiver struct {
 	counter *ReceiverCounter
 
 	status *AdapterStatus
+
+	tls *tlsReloader
 }
 
 type ReceiverCounter struct {
@@ -409,6 +411,11 @@ type ReceiverCounter struct {
 	UDPDisorder     uint64 `statsd:"udp_disorder"`      // 乱序个数
 	UDPDisorderSize uint64 `statsd:"udp_disorder_size"` // 乱序最大范围
 	NewBufferCount  uint64 `statsd:"new_buffer_count"`  // If the received data is large, you need to alloc memory, record the times.
+
+	TCPAcceptFailed     uint64 `statsd:"tcp_accept_failed"`
+	TLSHandshakeFailed  uint64 `statsd:"tls_handshake_failed"`
+	TLSIdentityMismatch uint64 `statsd:"tls_identity_mismatch"` // vtap id of the frame is different from the one of the client certificate
+	TLSUnregisteredDrop uint64 `statsd:"tls_unregistered_drop"` // frames without vtap id are dropped if client certificates are bound to vtap ids
 }
 
 func NewReceiver(
@@ -472,6 +479,16 @@ func (r *Receiver) SetServerType(serverType ServerType) {
 	r.serverType = serverType
 }
 
+// SetTLS enables TLS on the TCP listener, should be called before Start
+func (r *Receiver) SetTLS(config TLSConfig) error {
+	reloader, err := newTLSReloader(config)
+	if err != nil {
+		return err
+	}
+	r.tls = reloader
+	return nil
+}
+
 func (r *Receiver) GetCounter() interface{} {
 	counter := &ReceiverCounter{MaxDelay: -ONE_HOUR, MinDelay: ONE_HOUR}
 	counter, r.counter = r.counter, counter
@@ -780,6 +797,7 @@ func (r *Receiver) ProcessTCPServer() {
 	for !r.exit {
 		conn, err := r.TCPListener.Accept()
 		if err != nil {
+			atomic.AddUint64(&r.counter.TCPAcceptFailed, 1)
 			log.Errorf("Accept error.%s ", err.Error())
 			time.Sleep(3 * time.Second)
 			continue
@@ -849,6 +867,24 @@ func ReadN(r *bufio.Reader, buffer []byte) error {
 }
 
 func (r *Receiver) handleTCPConnection(conn net.Conn) {
+	// vtap id which the client certificate of the connection is bound to, 0 if not bound
+	certVtapID := uint16(0)
+	if r.tls != nil {
+		tlsConn, err := tlsHandshake(conn, r.tls.serverConfig())
+		if err == nil {
+			certVtapID, err = tlsIdentity(tlsConn, r.tls.config.IdentityMode)
+			if err != nil {
+				tlsConn.Close()
+			}
+		}
+		if err != nil {
+			atomic.AddUint64(&r.counter.TLSHandshakeFailed, 1)
+			log.Warningf("TCP client (%s) tls handshake failed: %s", conn.RemoteAddr().String(), err)
+			conn.Close()
+			return
+		}
+		conn = tlsConn
+	}
 	defer conn.Close()
 	defer r.flushPutTCPQueues()
 	ip := parseRemoteIP(conn)
@@ -889,6 +925,7 @@ func (r *Receiver) handleTCPConnection(conn net.Conn) {
 
 		headerLen := datatype.MESSAGE_HEADER_LEN
 		metricsTimestamp, vtapID, teamID, orgID := uint32(0), uint16(0), uint32(0), uint32(0)
+		dropFrame := false
 		if baseHeader.Type.HeaderType() == datatype.HEADER_TYPE_LT_VTAP {
 			if err := ReadN(reader, flowHeaderBuffer); err != nil {
 				atomic.AddUint64(&r.counter.Invalid, 1)
@@ -913,8 +950,17 @@ func (r *Receiver) handleTCPConnection(conn net.Conn) {
 			}
 			vtapID = flowHeader.VTAPID
 			orgID, teamID = r.parseOrgIdTeamId(flowHeader)
-		}
 
+			switch checkFrameVtapID(certVtapID, vtapID) {
+			case frameDropped:
+				dropFrame = true
+			case frameRejected:
+				atomic.AddUint64(&r.counter.TLSIdentityMismatch, 1)
+				log.Warningf("TCP client (%s) with certificate of vtap %d sends data of vtap %d", conn.RemoteAddr().String(), certVtapID, vtapID)
+				time.Sleep(10 * time.Second) // 等待10秒，防止日志刷屏
+				return
+			}
+		}
 		dataLen := int(baseHeader.FrameSize) - headerLen
 		if dataLen > RECV_BUFSIZE_MAX {
 			r.logTCPReceiveInvalidData(fmt.Sprintf("TCP client (%s) wrong frame size (%d)", conn.RemoteAddr().String(), baseHeader.FrameSize))
@@ -930,6 +976,11 @@ func (r *Receiver) handleTCPConnection(conn net.Conn) {
 			log.Warningf("TCP client (%s) connection read error: %s", conn.RemoteAddr().String(), err.Error())
 			return
 		}
+		if dropFrame {
+			atomic.AddUint64(&r.counter.TLSUnregisteredDrop, 1)
+			ReleaseRecvBuffer(recvBuffer)
+			continue
+		}
 
 		if baseHeader.Type == datatype.MESSAGE_TYPE_METRICS {
 			metricsTimestamp = r.getMetricsTimestamp(recvBuffer.Buffer)
@@ -969,6 +1020,9 @@ func (r *Receiver) Start() {
 			log.Errorf("TCP listen at %s failed: %s", r.TCPAddress, err)
 			os.Exit(-1)
 		}
+		if r.tls != nil {
+			go r.tls.run(&r.exit)
+		}
 		go r.ProcessTCPServer()
 	}
 
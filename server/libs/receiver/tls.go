@@ -0,0 +1,209 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package receiver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	TLS_HANDSHAKE_TIMEOUT = 10 * time.Second
+
+	// client certificates only prove that the client is a trusted agent, they could be shared by all agents
+	TLS_IDENTITY_MODE_SHARED = "shared"
+	// the common name of a client certificate is the vtap id of the agent, frames of other vtaps are rejected.
+	// the identity does not depend on any state, so it is the same on all ingesters and after restarting
+	TLS_IDENTITY_MODE_VTAP_ID = "vtap-id"
+)
+
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string // if set, client certificates are required and verified
+	IdentityMode string // TLS_IDENTITY_MODE_SHARED or TLS_IDENTITY_MODE_VTAP_ID
+	// certificates are reloaded when the files are modified
+	ReloadInterval time.Duration
+}
+
+// tlsReloader keeps the server tls.Config up to date with the certificate files,
+// new connections use the latest certificates, established connections are not affected.
+type tlsReloader struct {
+	config  TLSConfig
+	current atomic.Value // *tls.Config
+	modTime time.Time
+}
+
+func newTLSReloader(config TLSConfig) (*tlsReloader, error) {
+	switch config.IdentityMode {
+	case "":
+		config.IdentityMode = TLS_IDENTITY_MODE_SHARED
+	case TLS_IDENTITY_MODE_SHARED:
+	case TLS_IDENTITY_MODE_VTAP_ID:
+		if config.ClientCAFile == "" {
+			return nil, fmt.Errorf("client ca is required by identity mode %s", config.IdentityMode)
+		}
+	default:
+		return nil, fmt.Errorf("unknown identity mode %s", config.IdentityMode)
+	}
+	l := &tlsReloader{config: config}
+	modTime, err := l.filesModTime()
+	if err != nil {
+		return nil, err
+	}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	l.modTime = modTime
+	return l, nil
+}
+
+func (l *tlsReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{l.config.CertFile, l.config.KeyFile, l.config.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (l *tlsReloader) load() error {
+	certificate, err := tls.LoadX509KeyPair(l.config.CertFile, l.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate %s and key %s failed: %s", l.config.CertFile, l.config.KeyFile, err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+	}
+	if l.config.ClientCAFile != "" {
+		caPEM, err := os.ReadFile(l.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("read client ca %s failed: %s", l.config.ClientCAFile, err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return fmt.Errorf("no valid certificate in client ca %s", l.config.ClientCAFile)
+		}
+		config.ClientCAs = clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	l.current.Store(config)
+	return nil
+}
+
+func (l *tlsReloader) serverConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return l.current.Load().(*tls.Config), nil
+		},
+	}
+}
+
+func (l *tlsReloader) run(exit *bool) {
+	ticker := time.NewTicker(l.config.ReloadInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if *exit {
+			return
+		}
+		if err := l.reloadIfModified(); err != nil {
+			// the files may be partially written, retry next time
+			log.Warningf("reload tls certificates failed: %s", err)
+		}
+	}
+}
+
+func (l *tlsReloader) reloadIfModified() error {
+	modTime, err := l.filesModTime()
+	if err != nil {
+		return err
+	}
+	if !modTime.After(l.modTime) {
+		return nil
+	}
+	if err := l.load(); err != nil {
+		return err
+	}
+	l.modTime = modTime
+	log.Infof("tls certificates %s reloaded", l.config.CertFile)
+	return nil
+}
+
+// tlsIdentity returns the vtap id which the connection is restricted to by the client certificate,
+// 0 means that frames of any vtap are accepted.
+func tlsIdentity(conn *tls.Conn, mode string) (uint16, error) {
+	if mode != TLS_IDENTITY_MODE_VTAP_ID {
+		return 0, nil
+	}
+	state := conn.ConnectionState()
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return 0, errors.New("client certificate is required")
+	}
+	commonName := state.VerifiedChains[0][0].Subject.CommonName
+	vtapID, err := strconv.ParseUint(commonName, 10, 16)
+	if err != nil || vtapID == 0 {
+		return 0, fmt.Errorf("common name (%s) of client certificate is not a vtap id", commonName)
+	}
+	return uint16(vtapID), nil
+}
+
+func tlsHandshake(conn net.Conn, config *tls.Config) (*tls.Conn, error) {
+	tlsConn := tls.Server(conn, config)
+	tlsConn.SetDeadline(time.Now().Add(TLS_HANDSHAKE_TIMEOUT))
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+type frameVerdict uint8
+
+const (
+	frameAccepted frameVerdict = iota
+	frameDropped               // frames of unregistered agents, the connection is kept until the agent registers
+	frameRejected              // the connection is closed
+)
+
+// checkFrameVtapID checks vtap id of the frame against the one of the client certificate
+func checkFrameVtapID(certVtapID, vtapID uint16) frameVerdict {
+	switch {
+	case certVtapID == 0 || vtapID == certVtapID:
+		return frameAccepted
+	case vtapID == 0:
+		return frameDropped
+	default:
+		return frameRejected
+	}
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package receiver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCert(t *testing.T, commonName string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (c *testCert) writeFiles(t *testing.T, certFile, keyFile string) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, c.pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+// handshake returns the vtap id of client certificate seen by server and the server certificate seen by client
+func handshake(t *testing.T, config *tls.Config, mode string, clientConfig *tls.Config) (uint16, *x509.Certificate, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	serverCert := make(chan *x509.Certificate, 1)
+	go func() {
+		client, err := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+		if err != nil {
+			serverCert <- nil
+			return
+		}
+		defer client.Close()
+		serverCert <- client.ConnectionState().PeerCertificates[0]
+		// wait for the server to verify client certificate
+		client.Read(make([]byte, 1))
+	}()
+	serverConn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverConn.Close()
+	conn, err := tlsHandshake(serverConn, config)
+	if err != nil {
+		return 0, nil, err
+	}
+	vtapID, err := tlsIdentity(conn, mode)
+	return vtapID, <-serverCert, err
+}
+
+func TestTLSReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	certFile, keyFile, caFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.crt")
+	newTestCert(t, "server-1", ca).writeFiles(t, certFile, keyFile)
+	if err := os.WriteFile(caFile, ca.pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	reloader, err := newTLSReloader(TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ReloadInterval: time.Minute})
+	if err != nil {
+		t.Fatalf("load tls config failed: %s", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientConfig := &tls.Config{
+		RootCAs:      roots,
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{newTestCert(t, "agent-1", ca).tlsCertificate()},
+	}
+	vtapID, serverCert, err := handshake(t, reloader.serverConfig(), reloader.config.IdentityMode, clientConfig)
+	if err != nil || vtapID != 0 || serverCert.Subject.CommonName != "server-1" {
+		t.Fatalf("got vtap %d, server %v, err %v", vtapID, serverCert, err)
+	}
+
+	// client certificate is required
+	if _, _, err := handshake(t, reloader.serverConfig(), TLS_IDENTITY_MODE_SHARED, &tls.Config{RootCAs: roots, ServerName: "localhost"}); err == nil {
+		t.Error("handshake without client certificate should fail")
+	}
+	// client certificate signed by other ca is rejected
+	other := newTestCert(t, "other-ca", nil)
+	clientConfig.Certificates = []tls.Certificate{newTestCert(t, "agent-1", other).tlsCertificate()}
+	if _, _, err := handshake(t, reloader.serverConfig(), TLS_IDENTITY_MODE_SHARED, clientConfig); err == nil {
+		t.Error("handshake with untrusted client certificate should fail")
+	}
+
+	// new connections use the reloaded certificate
+	newTestCert(t, "server-2", ca).writeFiles(t, certFile, keyFile)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	if err := reloader.reloadIfModified(); err != nil {
+		t.Fatalf("reload tls config failed: %s", err)
+	}
+	clientConfig.Certificates = []tls.Certificate{newTestCert(t, "agent-1", ca).tlsCertificate()}
+	if _, serverCert, err = handshake(t, reloader.serverConfig(), TLS_IDENTITY_MODE_SHARED, clientConfig); err != nil || serverCert.Subject.CommonName != "server-2" {
+		t.Errorf("certificate is not reloaded, got server %v, err %v", serverCert, err)
+	}
+}
+
+func TestTLSIdentityMode(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	certFile, keyFile, caFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.crt")
+	newTestCert(t, "server", ca).writeFiles(t, certFile, keyFile)
+	if err := os.WriteFile(caFile, ca.pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTLSReloader(TLSConfig{CertFile: certFile, KeyFile: keyFile, IdentityMode: TLS_IDENTITY_MODE_VTAP_ID}); err == nil {
+		t.Error("identity mode vtap-id without client ca should fail")
+	}
+	if _, err := newTLSReloader(TLSConfig{CertFile: certFile, KeyFile: keyFile, IdentityMode: "unknown"}); err == nil {
+		t.Error("unknown identity mode should fail")
+	}
+	reloader, err := newTLSReloader(TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+	if err != nil {
+		t.Fatalf("load tls config failed: %s", err)
+	}
+	if reloader.config.IdentityMode != TLS_IDENTITY_MODE_SHARED {
+		t.Errorf("default identity mode should be %s, got %s", TLS_IDENTITY_MODE_SHARED, reloader.config.IdentityMode)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientConfig := func(commonName string) *tls.Config {
+		return &tls.Config{
+			RootCAs:      roots,
+			ServerName:   "localhost",
+			Certificates: []tls.Certificate{newTestCert(t, commonName, ca).tlsCertificate()},
+		}
+	}
+
+	// all agents share one certificate, none of them is bound to a vtap id
+	for _, vtapID := range []uint16{1, 2, 0, 1} {
+		certVtapID, _, err := handshake(t, reloader.serverConfig(), TLS_IDENTITY_MODE_SHARED, clientConfig("deepflow-agent"))
+		if err != nil {
+			t.Fatalf("handshake with shared certificate failed: %s", err)
+		}
+		if verdict := checkFrameVtapID(certVtapID, vtapID); verdict != frameAccepted {
+			t.Errorf("frame of vtap %d with shared certificate should be accepted, got %d", vtapID, verdict)
+		}
+	}
+
+	// common name of the certificate is the vtap id
+	certVtapID, _, err := handshake(t, reloader.serverConfig(), TLS_IDENTITY_MODE_VTAP_ID, clientConfig("12"))
+	if err != nil || certVtapID != 12 {
+		t.Fatalf("got vtap %d, err %v", certVtapID, err)
+	}
+	for vtapID, expected := range map[uint16]frameVerdict{12: frameAccepted, 0: frameDropped, 13: frameRejected} {
+		if verdict := checkFrameVtapID(certVtapID, vtapID); verdict != expected {
+			t.Errorf("frame of vtap %d with certificate of vtap 12 should be %d, got %d", vtapID, expected, verdict)
+		}
+	}
+	for _, commonName := range []string{"deepflow-agent", "0", "65536"} {
+		if _, _, err := handshake(t, reloader.serverConfig(), TLS_IDENTITY_MODE_VTAP_ID, clientConfig(commonName)); err == nil {
+			t.Errorf("certificate %s is not a vtap id, should fail", commonName)
+		}
+	}
+}
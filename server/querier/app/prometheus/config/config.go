@@ -28,6 +28,7 @@ type Prometheus struct {
 	ThanosReplicaLabels     []string        `yaml:"thanos-replica-labels"`
 	OperatorOffloading      bool            `default:"false" yaml:"operator-offloading"`
 	Cache                   PrometheusCache `yaml:"cache"`
+	Rule                    PrometheusRule  `yaml:"rule"`
 }
 
 type PrometheusCache struct {
@@ -39,3 +40,21 @@ type PrometheusCache struct {
 	CacheCleanInterval int    `default:"3600" yaml:"cache-clean-interval"` // clean interval for cache, unit: s, default: 1h
 	CacheAllowTimeGap  int    `default:"1" yaml:"cache-allow-time-gap"`    // when query end time - cache end time <= allow gap: not update cache, unit: s, default: 1s
 }
+
+type PrometheusRule struct {
+	Enabled            bool              `default:"false" yaml:"enabled"`
+	RuleFiles          []string          `yaml:"rule-files"`                                 // rule files of the default org in prometheus format, glob pattern is supported
+	OrgRuleFiles       map[int][]string  `yaml:"org-rule-files"`                             // org id => rule files of the org
+	EvaluationInterval int               `default:"60" yaml:"evaluation-interval"`           // default evaluation interval of rule groups, unit: s
+	IngesterAddress    string            `default:"127.0.0.1:20033" yaml:"ingester-address"` // where recording results and alarm events are sent to
+	IngesterTLS        PrometheusRuleTLS `yaml:"ingester-tls"`                               // required if 'receiver-tls' of ingester is enabled
+}
+
+// TLS client config connecting to ingester
+type PrometheusRuleTLS struct {
+	Enabled    bool   `default:"false" yaml:"enabled"`
+	CAFile     string `yaml:"ca-file"`     // verifies the certificate of ingester, system CAs are used if empty
+	CertFile   string `yaml:"cert-file"`   // client certificate, required if 'client-ca-file' of ingester is set
+	KeyFile    string `yaml:"key-file"`    // key of client certificate
+	ServerName string `yaml:"server-name"` // name in the certificate of ingester, host of 'ingester-address' is used if empty
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql/parser"
 )
 
@@ -54,11 +55,80 @@ type PromMetaParams struct {
 	StartTime   string
 	EndTime     string
 	LabelName   string
+	Metric      string
+	Limit       int
 	OrgID       string
+	Matchers    []string
 	BlockTeamID []string
 	Context     context.Context
 }
 
+// metadata of metric, ref: https://prometheus.io/docs/prometheus/latest/querying/api/#querying-metric-metadata
+type PromMetadata struct {
+	Type string `json:"type"`
+	Help string `json:"help"`
+	Unit string `json:"unit"`
+}
+
+type PromRulesParams struct {
+	Type  string // `alert` or `record`, all rules are returned when empty
+	OrgID string
+}
+
+// rule groups and their rules, ref: https://prometheus.io/docs/prometheus/latest/querying/api/#rules
+type PromRuleDiscovery struct {
+	RuleGroups []*PromRuleGroup `json:"groups"`
+}
+
+type PromRuleGroup struct {
+	Name           string        `json:"name"`
+	File           string        `json:"file"`
+	Rules          []interface{} `json:"rules"` // PromAlertingRule or PromRecordingRule
+	Interval       float64       `json:"interval"`
+	Limit          int           `json:"limit"`
+	EvaluationTime float64       `json:"evaluationTime"`
+	LastEvaluation time.Time     `json:"lastEvaluation"`
+}
+
+type PromAlertingRule struct {
+	State          string        `json:"state"`
+	Name           string        `json:"name"`
+	Query          string        `json:"query"`
+	Duration       float64       `json:"duration"`
+	Labels         labels.Labels `json:"labels"`
+	Annotations    labels.Labels `json:"annotations"`
+	Alerts         []*PromAlert  `json:"alerts"`
+	Health         string        `json:"health"`
+	LastError      string        `json:"lastError,omitempty"`
+	EvaluationTime float64       `json:"evaluationTime"`
+	LastEvaluation time.Time     `json:"lastEvaluation"`
+	Type           string        `json:"type"`
+}
+
+type PromRecordingRule struct {
+	Name           string        `json:"name"`
+	Query          string        `json:"query"`
+	Labels         labels.Labels `json:"labels,omitempty"`
+	Health         string        `json:"health"`
+	LastError      string        `json:"lastError,omitempty"`
+	EvaluationTime float64       `json:"evaluationTime"`
+	LastEvaluation time.Time     `json:"lastEvaluation"`
+	Type           string        `json:"type"`
+}
+
+// active alerts, ref: https://prometheus.io/docs/prometheus/latest/querying/api/#alerts
+type PromAlertDiscovery struct {
+	Alerts []*PromAlert `json:"alerts"`
+}
+
+type PromAlert struct {
+	Labels      labels.Labels `json:"labels"`
+	Annotations labels.Labels `json:"annotations"`
+	State       string        `json:"state"`
+	ActiveAt    *time.Time    `json:"activeAt,omitempty"`
+	Value       string        `json:"value"`
+}
+
 type PromQueryStats struct {
 	Duration   float64 `json:"duration,omitempty"`
 	SQL        string  `json:"sql,omitempty"`
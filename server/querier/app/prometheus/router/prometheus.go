@@ -63,6 +63,7 @@ func promQuery(svc *service.PrometheusService) gin.HandlerFunc {
 			c.JSON(code, obj)
 			return
 		}
+		args.BlockTeamID = appendUnauthorizedTeamID(c, args.BlockTeamID)
 
 		result, err := svc.PromInstantQueryService(&args, c.Request.Context())
 		if err != nil {
@@ -97,6 +98,7 @@ func promQueryRange(svc *service.PrometheusService) gin.HandlerFunc {
 			c.JSON(code, obj)
 			return
 		}
+		args.BlockTeamID = appendUnauthorizedTeamID(c, args.BlockTeamID)
 
 		result, err := svc.PromRangeQueryService(&args, c.Request.Context())
 		if err != nil {
@@ -127,7 +129,8 @@ func promReader(svc *service.PrometheusService) gin.HandlerFunc {
 		var offloadingArgs bool
 		setRouterArgs(offloading, &offloadingArgs, config.Cfg.Prometheus.OperatorOffloading, strconv.ParseBool)
 		orgID := c.Request.Header.Get(common.HEADER_KEY_X_ORG_ID)
-		resp, err := svc.PromRemoteReadService(&req, c.Request.Context(), offloadingArgs, orgID)
+		blockTeamID := appendUnauthorizedTeamID(c, nil)
+		resp, err := svc.PromRemoteReadService(&req, c.Request.Context(), offloadingArgs, orgID, blockTeamID)
 		if err != nil {
 			code, _ := handleError(err)
 			// remote read use different response, not use `obj`, otherwise it will cause decode response error
@@ -161,7 +164,7 @@ func promTagValuesReader(svc *service.PrometheusService) gin.HandlerFunc {
 			StartTime:   c.Request.FormValue("start"),
 			EndTime:     c.Request.FormValue("end"),
 			Context:     c.Request.Context(),
-			BlockTeamID: block_team_ids,
+			BlockTeamID: appendUnauthorizedTeamID(c, block_team_ids),
 			OrgID:       c.Request.Header.Get(common.HEADER_KEY_X_ORG_ID),
 		}
 		result, err := svc.PromLabelValuesService(&args, c.Request.Context())
@@ -193,6 +196,7 @@ func promSeriesReader(svc *service.PrometheusService) gin.HandlerFunc {
 			c.JSON(code, obj)
 			return
 		}
+		args.BlockTeamID = appendUnauthorizedTeamID(c, args.BlockTeamID)
 		// should show tags when get `Series`
 		ctx := context.WithValue(c.Request.Context(), service.CtxKeyShowTag{}, true)
 		result, err := svc.PromSeriesQueryService(&args, ctx)
@@ -205,6 +209,107 @@ func promSeriesReader(svc *service.PrometheusService) gin.HandlerFunc {
 	})
 }
 
+func promLabelNamesReader(svc *service.PrometheusService) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args, err := parseMetaParams(c)
+		if err != nil {
+			code, obj := handleError(err)
+			c.JSON(code, obj)
+			return
+		}
+		result, err := svc.PromLabelNamesService(args, c.Request.Context())
+		if err != nil {
+			code, obj := handleError(err)
+			c.JSON(code, obj)
+			return
+		}
+		c.JSON(200, result)
+	})
+}
+
+func promMetadataReader(svc *service.PrometheusService) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args, err := parseMetaParams(c)
+		if err != nil {
+			code, obj := handleError(err)
+			c.JSON(code, obj)
+			return
+		}
+		args.Metric = c.Request.FormValue("metric")
+		result, err := svc.PromMetadataService(args, c.Request.Context())
+		if err != nil {
+			code, obj := handleError(err)
+			c.JSON(code, obj)
+			return
+		}
+		c.JSON(200, result)
+	})
+}
+
+func promExemplarsReader(svc *service.PrometheusService) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args := model.PromQueryParams{
+			Promql:    c.Request.FormValue("query"),
+			StartTime: c.Request.FormValue("start"),
+			EndTime:   c.Request.FormValue("end"),
+			Context:   c.Request.Context(),
+			OrgID:     c.Request.Header.Get(common.HEADER_KEY_X_ORG_ID),
+		}
+		result, err := svc.PromExemplarsQueryService(&args, c.Request.Context())
+		if err != nil {
+			code, obj := handleError(err)
+			c.JSON(code, obj)
+			return
+		}
+		c.JSON(200, result)
+	})
+}
+
+func promRulesReader(svc *service.PrometheusService) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args := model.PromRulesParams{
+			Type:  c.Request.FormValue("type"),
+			OrgID: c.Request.Header.Get(common.HEADER_KEY_X_ORG_ID),
+		}
+		result, err := svc.PromRulesService(&args)
+		if err != nil {
+			code, obj := handleError(err)
+			c.JSON(code, obj)
+			return
+		}
+		c.JSON(200, result)
+	})
+}
+
+func promAlertsReader(svc *service.PrometheusService) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		c.JSON(200, svc.PromAlertsService(c.Request.Header.Get(common.HEADER_KEY_X_ORG_ID)))
+	})
+}
+
+// parse common params of metadata api: match[], start, end and limit
+func parseMetaParams(c *gin.Context) (*model.PromMetaParams, error) {
+	// parse form first, so that match[] in both url and body of POST request can be got
+	c.Request.ParseForm()
+	args := &model.PromMetaParams{
+		StartTime: c.Request.FormValue("start"),
+		EndTime:   c.Request.FormValue("end"),
+		Matchers:  c.Request.Form["match[]"],
+		Context:   c.Request.Context(),
+		OrgID:     c.Request.Header.Get(common.HEADER_KEY_X_ORG_ID),
+	}
+	limit := c.Request.FormValue("limit")
+	if err := setRouterArgs(limit, &args.Limit, 0, strconv.Atoi); err != nil {
+		return nil, err
+	}
+	block_team_id := c.Request.FormValue("block-team-id")
+	if err := setRouterArgs(block_team_id, &args.BlockTeamID, nil, splitStrings); err != nil {
+		return nil, err
+	}
+	args.BlockTeamID = appendUnauthorizedTeamID(c, args.BlockTeamID)
+	return args, nil
+}
+
 func promQLAnalysis(svc *service.PrometheusService) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		metric := c.Query("metric")
@@ -309,6 +414,11 @@ func setRouterArgs[T any](flag string, target *T, defaultValue T, parser func(st
 	return nil
 }
 
+// teams which the caller is not authorized to are always blocked, besides block-team-id in params
+func appendUnauthorizedTeamID(c *gin.Context, blockTeamID []string) []string {
+	return append(blockTeamID, c.GetStringSlice(common.CONTEXT_KEY_BLOCK_TEAM_ID)...)
+}
+
 func splitStrings(s string) ([]string, error) {
 	splitStr := strings.Split(s, ",")
 	result := make([]string, 0, len(splitStr))
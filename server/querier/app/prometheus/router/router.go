@@ -44,6 +44,13 @@ func PrometheusRouter(e *gin.Engine) {
 		promGroup.GET("/api/v1/series", promSeriesReader(prometheusService))
 		promGroup.POST("/api/v1/series", promSeriesReader(prometheusService))
 		promGroup.GET("/api/v1/label/:labelName/values", promTagValuesReader(prometheusService))
+		promGroup.GET("/api/v1/labels", promLabelNamesReader(prometheusService))
+		promGroup.POST("/api/v1/labels", promLabelNamesReader(prometheusService))
+		promGroup.GET("/api/v1/metadata", promMetadataReader(prometheusService))
+		promGroup.GET("/api/v1/query_exemplars", promExemplarsReader(prometheusService))
+		promGroup.POST("/api/v1/query_exemplars", promExemplarsReader(prometheusService))
+		promGroup.GET("/api/v1/rules", promRulesReader(prometheusService))
+		promGroup.GET("/api/v1/alerts", promAlertsReader(prometheusService))
 
 		// not use "/prom/api/v1/adapter/:name", suitable for map[rouer key]counter in statsd
 		for _, v := range []string{"label", "query_range", "query", "series"} {
@@ -19,10 +19,13 @@ package service
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/model/textparse"
 
 	"github.com/deepflowio/deepflow/server/querier/app/prometheus/model"
-	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse"
 	chCommon "github.com/deepflowio/deepflow/server/querier/engine/clickhouse/common"
 	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/metrics"
 )
@@ -38,56 +41,113 @@ const (
 	METRICS_CATEGORY_TAG    = "Tag"
 )
 
+// flow_tag rows of a metric are written again only after flow-tag-cache-flush-timeout of ingester (1800s by default),
+// the start time of flow_tag queries is moved forward by it to find the metrics which are written before start
+const flowTagFlushTimeout = 1800 * time.Second
+
 func (p *prometheusExecutor) getTagValues(ctx context.Context, args *model.PromMetaParams) (result *model.PromQueryResponse, err error) {
 	if args.LabelName == LABEL_NAME_METRICS {
+		metricNames, err := getMetrics(ctx, args)
+		if err != nil {
+			return nil, err
+		}
 		return &model.PromQueryResponse{
-			Data: getMetrics(ctx, args),
+			Data: metricNames,
 		}, nil
 	}
 	return result, err
 }
 
-func getMetrics(ctx context.Context, args *model.PromMetaParams) (resp []string) {
-	// We speed up the return of the metrics list by querying the aggregation information in
-	// `flow_tag.ext_metrics_custom_field_value`. Since we do not query the original time series
-	// data, filtering metrics by time is currently not supported.
-	where := ""
+func getMetrics(ctx context.Context, args *model.PromMetaParams) (resp []string, err error) {
+	metricsMetadata, err := getMetricsMetadata(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	resp = make([]string, 0, len(metricsMetadata))
+	for _, m := range metricsMetadata {
+		resp = append(resp, m.name)
+	}
+	return resp, nil
+}
+
+// getFlowTagFilter returns the filter of team and time range for flow_tag queries
+func getFlowTagFilter(args *model.PromMetaParams) (string, error) {
+	var conditions []string
 	if len(args.BlockTeamID) > 0 {
-		where = fmt.Sprintf("team_id not in (%s)", strings.Join(args.BlockTeamID, ","))
+		conditions = append(conditions, fmt.Sprintf("team_id not in (%s)", strings.Join(args.BlockTeamID, ",")))
+	}
+	if args.StartTime != "" {
+		start, err := parseTime(args.StartTime)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, fmt.Sprintf("time>=%d", start.Add(-flowTagFlushTimeout).Unix()))
+	}
+	if args.EndTime != "" {
+		end, err := parseTime(args.EndTime)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, fmt.Sprintf("time<=%d", end.Unix()))
+	}
+	return strings.Join(conditions, " AND "), nil
+}
+
+type metricMetadata struct {
+	name     string
+	metadata model.PromMetadata
+}
+
+func newPromMetadata(m *metrics.Metrics) model.PromMetadata {
+	metadata := model.PromMetadata{Type: string(textparse.MetricTypeUnknown), Help: m.Description, Unit: m.Unit}
+	if metadata.Help == "" {
+		metadata.Help = m.DisplayName
+	}
+	switch m.Type {
+	case metrics.METRICS_TYPE_COUNTER:
+		metadata.Type = string(textparse.MetricTypeCounter)
+	case metrics.METRICS_TYPE_GAUGE, metrics.METRICS_TYPE_BOUNDED_GAUGE, metrics.METRICS_TYPE_DELAY,
+		metrics.METRICS_TYPE_PERCENTAGE, metrics.METRICS_TYPE_QUOTIENT:
+		metadata.Type = string(textparse.MetricTypeGauge)
+	}
+	return metadata
+}
+
+func getMetricsMetadata(ctx context.Context, args *model.PromMetaParams) (resp []metricMetadata, err error) {
+	// We speed up the return of the metrics list by querying the aggregation information in
+	// `flow_tag.<db>_custom_field` instead of the original time series data, metrics written in
+	// the time range are found by the time of flow_tag rows.
+	where, err := getFlowTagFilter(args)
+	if err != nil {
+		return nil, err
 	}
-	//if args.StartTime != "" && args.EndTime != "" {
-	//	where = fmt.Sprintf("time>=%s AND time<=%s", args.StartTime, args.EndTime)
-	//} else if args.StartTime != "" {
-	//	where = fmt.Sprintf("time>=%s", args.StartTime)
-	//} else if args.EndTime != "" {
-	//	where = fmt.Sprintf("time<=%s", args.EndTime)
-	//}
 
-	resp = []string{}
+	resp = []metricMetadata{}
 	for db, tables := range chCommon.DB_TABLE_MAP {
 		if db == DB_NAME_EXT_METRICS {
 			extMetrics, _ := metrics.GetExtMetrics(DB_NAME_EXT_METRICS, "", where, "", args.OrgID, false, args.Context)
 			for _, v := range extMetrics {
 				// append telegraf metrics, e.g.: influxdb_internal_statsd__tcp_current_connections[influxdb_target__metric]
 				metricName := fmt.Sprintf("%s__%s__%s__%s", db, "metrics", strings.Replace(v.Table, ".", "_", 1), strings.TrimPrefix(v.DisplayName, "metrics."))
-				resp = append(resp, metricName)
+				resp = append(resp, metricMetadata{metricName, newPromMetadata(v)})
 			}
 		} else if db == chCommon.DB_NAME_PROMETHEUS {
 			// prometheus samples should get all metrcis from `table`
-			samples := clickhouse.GetTables(db, "", args.OrgID, false, ctx)
-			for _, v := range samples.Values {
-				tableName := v.([]interface{})[0].(string)
+			tableNames, _ := metrics.GetPrometheusTables(db, "", where, "", args.OrgID, false, args.Context)
+			for _, tableName := range tableNames {
+				// type of prometheus samples is not stored
+				metadata := model.PromMetadata{Type: string(textparse.MetricTypeUnknown)}
 				// append ${metrics_name}
-				resp = append(resp, tableName)
+				resp = append(resp, metricMetadata{tableName, metadata})
 				// append prometheus__samples__${metrics_name}
 				metricsName := fmt.Sprintf("%s__%s__%s", db, TABLE_NAME_SAMPLES, tableName)
-				resp = append(resp, metricsName)
+				resp = append(resp, metricMetadata{metricsName, metadata})
 			}
 		} else if db == DB_NAME_DEEPFLOW_SYSTEM {
 			deepflowSystem, _ := metrics.GetExtMetrics(DB_NAME_DEEPFLOW_SYSTEM, "", where, "", args.OrgID, false, args.Context)
 			for _, v := range deepflowSystem {
 				metricName := fmt.Sprintf("%s__%s__%s", db, strings.ReplaceAll(v.Table, ".", "_"), strings.TrimPrefix(v.DisplayName, "metrics."))
-				resp = append(resp, metricName)
+				resp = append(resp, metricMetadata{metricName, newPromMetadata(v)})
 			}
 		} else {
 			for _, table := range tables {
@@ -102,16 +162,20 @@ func getMetrics(ctx context.Context, args *model.PromMetaParams) (resp []string)
 					metricsName := ""
 					if db == DB_NAME_FLOW_METRICS {
 						metricsName = fmt.Sprintf("%s__%s__%s__%s", db, table, field, "1m")
-						resp = append(resp, metricsName)
+						resp = append(resp, metricMetadata{metricsName, newPromMetadata(v)})
 						metricsName = fmt.Sprintf("%s__%s__%s__%s", db, table, field, "1s")
-						resp = append(resp, metricsName)
+						resp = append(resp, metricMetadata{metricsName, newPromMetadata(v)})
 					} else {
 						metricsName = fmt.Sprintf("%s__%s__%s", db, table, field)
-						resp = append(resp, metricsName)
+						resp = append(resp, metricMetadata{metricsName, newPromMetadata(v)})
 					}
 				}
 			}
 		}
 	}
-	return resp
+	// metrics are sorted so that the same metrics are returned by metadata api with limit
+	sort.SliceStable(resp, func(i, j int) bool {
+		return resp[i].name < resp[j].name
+	})
+	return resp, nil
 }
@@ -0,0 +1,205 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/deepflowio/deepflow/server/querier/app/prometheus/model"
+	"github.com/deepflowio/deepflow/server/querier/common"
+	chCommon "github.com/deepflowio/deepflow/server/querier/engine/clickhouse/common"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/metrics"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/trans_prometheus"
+)
+
+// when start or end is not given in metadata api, query the latest hour
+const defaultMetaQueryRange = time.Hour
+
+// fillMetaTimeRange fills the default time range of metadata api, prometheus queries all the time
+// when start or end is not given, but we only query the latest hour to avoid scanning all the data
+func fillMetaTimeRange(startTime, endTime *string) {
+	if *endTime == "" {
+		*endTime = strconv.FormatInt(time.Now().Unix(), 10)
+	}
+	if *startTime == "" {
+		end, err := parseTime(*endTime)
+		if err != nil {
+			return
+		}
+		*startTime = strconv.FormatInt(end.Add(-defaultMetaQueryRange).Unix(), 10)
+	}
+}
+
+// API Spec: https://prometheus.io/docs/prometheus/latest/querying/api/#getting-label-names
+func (p *prometheusExecutor) getLabelNames(ctx context.Context, args *model.PromMetaParams) (*model.PromQueryResponse, error) {
+	var names []string
+	fillMetaTimeRange(&args.StartTime, &args.EndTime)
+	if len(args.Matchers) > 0 {
+		// label names of series matched
+		seriesResult, err := p.series(ctx, &model.PromQueryParams{
+			StartTime:   args.StartTime,
+			EndTime:     args.EndTime,
+			Matchers:    args.Matchers,
+			OrgID:       args.OrgID,
+			BlockTeamID: args.BlockTeamID,
+			Context:     args.Context,
+		})
+		if err != nil {
+			return nil, err
+		}
+		nameSet := map[string]struct{}{}
+		for _, series := range seriesResult.Data.([]labels.Labels) {
+			for _, l := range series {
+				nameSet[l.Name] = struct{}{}
+			}
+		}
+		names = make([]string, 0, len(nameSet))
+		for name := range nameSet {
+			names = append(names, name)
+		}
+	} else {
+		// label names of prometheus metrics written in the time range
+		where, err := getFlowTagFilter(args)
+		if err != nil {
+			return nil, err
+		}
+		orgID := args.OrgID
+		if orgID == "" {
+			orgID = common.DEFAULT_ORG_ID
+		}
+		metricNames, err := metrics.GetPrometheusTables(chCommon.DB_NAME_PROMETHEUS, "", where, "", orgID, false, args.Context)
+		if err != nil {
+			return nil, err
+		}
+		names = getAppLabelNames(metricNames, trans_prometheus.ORGPrometheus[orgID].MetricAppLabelLayout)
+	}
+	sort.Strings(names)
+	if args.Limit > 0 && len(names) > args.Limit {
+		names = names[:args.Limit]
+	}
+	return &model.PromQueryResponse{Data: names, Status: _SUCCESS}, nil
+}
+
+// getAppLabelNames returns the label names of the metrics, including `__name__`
+func getAppLabelNames(metricNames []string, metricAppLabelLayout map[string][]trans_prometheus.AppLabel) []string {
+	nameSet := map[string]struct{}{PROMETHEUS_METRICS_NAME: {}}
+	for _, metricName := range metricNames {
+		for _, appLabel := range metricAppLabelLayout[metricName] {
+			nameSet[appLabel.AppLabelName] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(nameSet))
+	for name := range nameSet {
+		names = append(names, name)
+	}
+	return names
+}
+
+// API Spec: https://prometheus.io/docs/prometheus/latest/querying/api/#querying-metric-metadata
+// besides `metric` and `limit`, `match[]` is supported to filter metrics by the matchers of `__name__`,
+// and `start` and `end` are supported to filter metrics written in the time range
+func (p *prometheusExecutor) getMetadata(ctx context.Context, args *model.PromMetaParams) (*model.PromQueryResponse, error) {
+	fillMetaTimeRange(&args.StartTime, &args.EndTime)
+	var nameMatcherSets [][]*labels.Matcher
+	if len(args.Matchers) > 0 {
+		matcherSets, err := parseMatchersParam(args.Matchers)
+		if err != nil {
+			return nil, err
+		}
+		for _, matchers := range matcherSets {
+			var nameMatchers []*labels.Matcher
+			for _, m := range matchers {
+				if m.Name == PROMETHEUS_METRICS_NAME {
+					nameMatchers = append(nameMatchers, m)
+				}
+			}
+			nameMatcherSets = append(nameMatcherSets, nameMatchers)
+		}
+	}
+
+	metricsMetadata, err := getMetricsMetadata(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string][]model.PromMetadata{}
+	for _, m := range metricsMetadata {
+		if args.Metric != "" && m.name != args.Metric {
+			continue
+		}
+		if !matchMetricName(m.name, nameMatcherSets) {
+			continue
+		}
+		if args.Limit > 0 && len(result) >= args.Limit {
+			break
+		}
+		if _, ok := result[m.name]; ok {
+			continue
+		}
+		result[m.name] = []model.PromMetadata{m.metadata}
+	}
+	return &model.PromQueryResponse{Data: result, Status: _SUCCESS}, nil
+}
+
+// matchMetricName returns true if the name matches any of the matcher sets, or there is no matcher set
+func matchMetricName(name string, matcherSets [][]*labels.Matcher) bool {
+	if len(matcherSets) == 0 {
+		return true
+	}
+	for _, matchers := range matcherSets {
+		matched := true
+		for _, m := range matchers {
+			if !m.Matches(name) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// API Spec: https://prometheus.io/docs/prometheus/latest/querying/api/#querying-exemplars
+// exemplars of prometheus samples are not stored by deepflow, the query is checked and an empty result is returned
+// as prometheus does when there is no exemplar, so that grafana can query exemplars without errors
+func (p *prometheusExecutor) queryExemplars(ctx context.Context, args *model.PromQueryParams) (*model.PromQueryResponse, error) {
+	if _, err := parser.ParseExpr(args.Promql); err != nil {
+		return nil, err
+	}
+	fillMetaTimeRange(&args.StartTime, &args.EndTime)
+	start, err := parseTime(args.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTime(args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	if end.Before(start) {
+		return nil, errors.New("end timestamp must not be before start timestamp")
+	}
+	return &model.PromQueryResponse{Data: []exemplar.QueryResult{}, Status: _SUCCESS}, nil
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/deepflowio/deepflow/server/querier/app/prometheus/model"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/metrics"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/trans_prometheus"
+)
+
+func TestGetLabelNames(t *testing.T) {
+	p := NewPrometheusExecutor(defaultLookbackDelta)
+
+	Convey("TestCase_GetLabelNames_Failed", t, func() {
+		_, err := p.getLabelNames(context.Background(), &model.PromMetaParams{StartTime: "a"})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestGetAppLabelNames(t *testing.T) {
+	layout := map[string][]trans_prometheus.AppLabel{
+		"node_cpu_seconds_total": {{AppLabelName: "instance", AppLabelColumnIndex: 1}, {AppLabelName: "cpu", AppLabelColumnIndex: 2}},
+		"up":                     {{AppLabelName: "instance", AppLabelColumnIndex: 1}, {AppLabelName: "job", AppLabelColumnIndex: 2}},
+	}
+
+	Convey("TestCase_GetAppLabelNames", t, func() {
+		names := getAppLabelNames([]string{"node_cpu_seconds_total", "unknown_metric"}, layout)
+		sort.Strings(names)
+		So(names, ShouldResemble, []string{"__name__", "cpu", "instance"})
+		So(getAppLabelNames(nil, layout), ShouldResemble, []string{"__name__"})
+	})
+}
+
+func TestGetFlowTagFilter(t *testing.T) {
+	Convey("TestCase_GetFlowTagFilter", t, func() {
+		where, err := getFlowTagFilter(&model.PromMetaParams{StartTime: "1700000000", EndTime: "1700003600", BlockTeamID: []string{"2", "3"}})
+		So(err, ShouldBeNil)
+		So(where, ShouldEqual, "team_id not in (2,3) AND time>=1699998200 AND time<=1700003600")
+
+		where, err = getFlowTagFilter(&model.PromMetaParams{})
+		So(err, ShouldBeNil)
+		So(where, ShouldEqual, "")
+
+		_, err = getFlowTagFilter(&model.PromMetaParams{EndTime: "a"})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestMatchMetricName(t *testing.T) {
+	Convey("TestCase_MatchMetricName", t, func() {
+		So(matchMetricName("flow_metrics__network__byte__1m", nil), ShouldBeTrue)
+
+		matcherSets, err := parseMatchersParam([]string{`{__name__=~"flow_metrics__.*", job="a"}`, "node_cpu_seconds_total"})
+		So(err, ShouldBeNil)
+		nameMatcherSets := [][]*labels.Matcher{{matcherSets[0][0]}, {matcherSets[1][0]}}
+		So(matchMetricName("flow_metrics__network__byte__1m", nameMatcherSets), ShouldBeTrue)
+		So(matchMetricName("node_cpu_seconds_total", nameMatcherSets), ShouldBeTrue)
+		So(matchMetricName("node_memory_bytes", nameMatcherSets), ShouldBeFalse)
+	})
+}
+
+func TestNewPromMetadata(t *testing.T) {
+	Convey("TestCase_NewPromMetadata", t, func() {
+		So(newPromMetadata(&metrics.Metrics{Type: metrics.METRICS_TYPE_COUNTER, DisplayName: "byte", Unit: "byte"}),
+			ShouldResemble, model.PromMetadata{Type: "counter", Help: "byte", Unit: "byte"})
+		So(newPromMetadata(&metrics.Metrics{Type: metrics.METRICS_TYPE_DELAY, DisplayName: "rtt", Description: "round trip time", Unit: "us"}),
+			ShouldResemble, model.PromMetadata{Type: "gauge", Help: "round trip time", Unit: "us"})
+		So(newPromMetadata(&metrics.Metrics{Type: metrics.METRICS_TYPE_TAG}).Type, ShouldEqual, "unknown")
+	})
+}
+
+func TestGetMetadataLimit(t *testing.T) {
+	p := NewPrometheusExecutor(defaultLookbackDelta)
+
+	Convey("TestCase_GetMetadata_Limit", t, func() {
+		names, err := getMetrics(context.Background(), &model.PromMetaParams{})
+		So(err, ShouldBeNil)
+		So(sort.StringsAreSorted(names), ShouldBeTrue)
+
+		// the first metrics in order are returned
+		expected := map[string]struct{}{}
+		for _, name := range names {
+			if len(expected) >= 3 {
+				break
+			}
+			expected[name] = struct{}{}
+		}
+		result, err := p.getMetadata(context.Background(), &model.PromMetaParams{Limit: 3})
+		So(err, ShouldBeNil)
+		metadata := result.Data.(map[string][]model.PromMetadata)
+		So(len(metadata), ShouldEqual, len(expected))
+		for name := range metadata {
+			So(expected, ShouldContainKey, name)
+		}
+	})
+}
+
+func TestQueryExemplars(t *testing.T) {
+	p := NewPrometheusExecutor(defaultLookbackDelta)
+
+	Convey("TestCase_QueryExemplars_Success", t, func() {
+		result, err := p.queryExemplars(context.Background(), &model.PromQueryParams{Promql: "rate(node_cpu_seconds_total[1m])", StartTime: "1700000000", EndTime: "1700003600"})
+		So(err, ShouldBeNil)
+		data, _ := json.Marshal(result)
+		So(string(data), ShouldEqual, `{"status":"success","data":[]}`)
+	})
+
+	Convey("TestCase_QueryExemplars_Failed", t, func() {
+		_, err := p.queryExemplars(context.Background(), &model.PromQueryParams{Promql: "rate(", StartTime: "1700000000", EndTime: "1700003600"})
+		So(err, ShouldNotBeNil)
+		_, err = p.queryExemplars(context.Background(), &model.PromQueryParams{Promql: "up", StartTime: "1700003600", EndTime: "1700000000"})
+		So(err, ShouldNotBeNil)
+	})
+}
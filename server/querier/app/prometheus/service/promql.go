@@ -514,7 +514,7 @@ func (p *prometheusExecutor) offloadInstantQueryExecute(ctx context.Context, arg
 	return result, err
 }
 
-func (p *prometheusExecutor) promRemoteReadOffloadingExecute(ctx context.Context, req *prompb.ReadRequest, orgID string) (resp *prompb.ReadResponse, err error) {
+func (p *prometheusExecutor) promRemoteReadOffloadingExecute(ctx context.Context, req *prompb.ReadRequest, orgID string, blockTeamID []string) (resp *prompb.ReadResponse, err error) {
 	var query *prompb.Query
 	var queryType model.QueryType
 	if len(req.Queries) > 0 {
@@ -545,6 +545,7 @@ func (p *prometheusExecutor) promRemoteReadOffloadingExecute(ctx context.Context
 	reader := &prometheusReader{
 		slimit:                  config.Cfg.Prometheus.SeriesLimit,
 		orgID:                   orgID,
+		blockTeamID:             blockTeamID,
 		getExternalTagFromCache: p.convertExternalTagToQuerierAllowTag,
 		addExternalTagToCache:   p.addExtraLabelsToCache,
 	}
@@ -590,10 +591,11 @@ func promMatchersToMatchers(matchers *[]*prompb.LabelMatcher) []*labels.Matcher
 	return lm
 }
 
-func (p *prometheusExecutor) promRemoteReadExecute(ctx context.Context, req *prompb.ReadRequest, orgID string) (resp *prompb.ReadResponse, err error) {
+func (p *prometheusExecutor) promRemoteReadExecute(ctx context.Context, req *prompb.ReadRequest, orgID string, blockTeamID []string) (resp *prompb.ReadResponse, err error) {
 	reader := &prometheusReader{
 		slimit:                  config.Cfg.Prometheus.SeriesLimit,
 		orgID:                   orgID,
+		blockTeamID:             blockTeamID,
 		getExternalTagFromCache: p.convertExternalTagToQuerierAllowTag,
 		addExternalTagToCache:   p.addExtraLabelsToCache,
 	}
@@ -859,7 +861,7 @@ func (p *prometheusExecutor) getAllOrganizations() []string {
 func (p *prometheusExecutor) loadExtraLabelsCache(orgID string) {
 	// DeepFlow Source have same tag collections, so just try query 1 table to add all external tags
 	showTags := fmt.Sprintf("show tags from %s", VTAP_FLOW_PORT_TABLE)
-	data, err := tagdescription.GetTagDescriptions(chCommon.DB_NAME_FLOW_METRICS, VTAP_FLOW_PORT_TABLE, showTags, "", orgID, false, context.Background())
+	data, err := tagdescription.GetTagDescriptions(chCommon.DB_NAME_FLOW_METRICS, VTAP_FLOW_PORT_TABLE, showTags, "", orgID, nil, false, context.Background())
 	if err != nil {
 		log.Errorf("load external tag error when start up prometheus executor: %s", err)
 		return
@@ -0,0 +1,389 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/deepflowio/deepflow/server/controller/election"
+	"github.com/deepflowio/deepflow/server/libs/ckdb"
+	prometheusConfig "github.com/deepflowio/deepflow/server/querier/app/prometheus/config"
+	"github.com/deepflowio/deepflow/server/querier/app/prometheus/model"
+	"github.com/deepflowio/deepflow/server/querier/config"
+)
+
+const (
+	RULE_TYPE_ALERT  = "alert"
+	RULE_TYPE_RECORD = "record"
+
+	ruleLeaderCheckInterval = 10 * time.Second
+)
+
+// ruleManager evaluates alerting and recording rules in prometheus format by prometheusExecutor,
+// results of recording rules are written to ext_metrics, and alerts are written to event.alarm_event.
+// rules are only evaluated by the querier beside the leader controller, otherwise every querier replica
+// would write the same alerts and recording results.
+type ruleManager struct {
+	cfg      *prometheusConfig.PrometheusRule
+	engine   *promql.Engine
+	executor *prometheusExecutor
+	files    []string
+	orgs     ruleOrgs
+	writer   *ingesterWriter
+	isLeader func() (bool, error)
+	done     chan struct{}
+
+	sync.RWMutex
+	// running manager, nil if this querier is not the leader
+	manager *rules.Manager
+	cancel  context.CancelFunc
+}
+
+func newRuleManager(cfg *prometheusConfig.PrometheusRule, engine *promql.Engine, executor *prometheusExecutor) (*ruleManager, error) {
+	files, orgs, err := expandRuleFiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if _, errs := rulefmt.ParseFile(file); len(errs) > 0 {
+			return nil, fmt.Errorf("load rule file %s failed: %v", file, errs)
+		}
+	}
+	tlsConfig, err := newIngesterTLSConfig(cfg.IngesterAddress, &cfg.IngesterTLS)
+	if err != nil {
+		return nil, err
+	}
+	return &ruleManager{
+		cfg:      cfg,
+		engine:   engine,
+		executor: executor,
+		files:    files,
+		orgs:     orgs,
+		writer:   newIngesterWriter(cfg.IngesterAddress, tlsConfig),
+		isLeader: election.IsMasterController,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+func (r *ruleManager) run() {
+	ticker := time.NewTicker(ruleLeaderCheckInterval)
+	defer ticker.Stop()
+	for {
+		r.checkLeader()
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkLeader starts evaluating rules when this querier becomes the leader, and stops when it is not
+func (r *ruleManager) checkLeader() {
+	isLeader, err := r.isLeader()
+	if err != nil {
+		log.Warningf("check leader of prometheus rules failed: %s", err)
+		isLeader = false
+	}
+	running := r.getManager() != nil
+	if isLeader && !running {
+		if err := r.startManager(); err != nil {
+			log.Errorf("start prometheus rule manager failed: %s", err)
+		}
+	} else if !isLeader && running {
+		log.Info("stop evaluating prometheus rules, which are evaluated by the leader")
+		r.stopManager()
+	}
+}
+
+// a stopped rules.Manager could not be restarted, so a new one is created every time the querier becomes the leader.
+// ALERTS_FOR_STATE is not stored, so the `for` state of alerts could not be restored after restart or leader change.
+func (r *ruleManager) startManager() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	manager := rules.NewManager(&rules.ManagerOptions{
+		QueryFunc:  ruleQueryFunc(r.executor, r.engine, r.orgs),
+		NotifyFunc: newAlertNotifier(r.writer, r.orgs).notify,
+		Context:    ctx,
+		Appendable: &ruleAppendable{writer: r.writer, orgs: r.orgs},
+		Queryable: storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+			return storage.NoopQuerier(), nil
+		}),
+		Logger: newPrometheusLogger(),
+	})
+	if err := manager.Update(time.Duration(r.cfg.EvaluationInterval)*time.Second, r.files, nil, "", nil); err != nil {
+		cancel()
+		return fmt.Errorf("load rule files %v failed: %s", r.files, err)
+	}
+	go manager.Run()
+	r.Lock()
+	r.manager, r.cancel = manager, cancel
+	r.Unlock()
+	log.Infof("start evaluating %d prometheus rule groups from %v", len(manager.RuleGroups()), r.files)
+	return nil
+}
+
+func (r *ruleManager) stopManager() {
+	r.Lock()
+	manager, cancel := r.manager, r.cancel
+	r.manager, r.cancel = nil, nil
+	r.Unlock()
+	if manager != nil {
+		manager.Stop()
+		cancel()
+	}
+}
+
+func (r *ruleManager) getManager() *rules.Manager {
+	r.RLock()
+	defer r.RUnlock()
+	return r.manager
+}
+
+// ruleGroups returns rule groups of the org, which are empty if this querier is not the leader
+func (r *ruleManager) ruleGroups(orgID int) []*rules.Group {
+	manager := r.getManager()
+	if manager == nil {
+		return nil
+	}
+	groups := []*rules.Group{}
+	for _, group := range manager.RuleGroups() {
+		if r.orgs.orgIDOfFile(group.File()) == orgID {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+func (r *ruleManager) alertingRules(orgID int) []*rules.AlertingRule {
+	alertingRules := []*rules.AlertingRule{}
+	for _, group := range r.ruleGroups(orgID) {
+		for _, rule := range group.Rules() {
+			if alertingRule, ok := rule.(*rules.AlertingRule); ok {
+				alertingRules = append(alertingRules, alertingRule)
+			}
+		}
+	}
+	return alertingRules
+}
+
+func (r *ruleManager) stop() {
+	close(r.done)
+	r.stopManager()
+	r.writer.close()
+}
+
+// ruleOrgs maps rule files to their orgs, rule groups in a file belong to the org of the file
+type ruleOrgs map[string]int
+
+func (o ruleOrgs) orgIDOfFile(file string) int {
+	if orgID, ok := o[file]; ok {
+		return orgID
+	}
+	return ckdb.DEFAULT_ORG_ID
+}
+
+// orgID returns the org of the rule group being evaluated, rules.Group sets its file and name in ctx
+func (o ruleOrgs) orgID(ctx context.Context) int {
+	origin, _ := ctx.Value(promql.QueryOrigin{}).(map[string]interface{})
+	group, _ := origin["ruleGroup"].(map[string]string)
+	return o.orgIDOfFile(group["file"])
+}
+
+// parseRuleOrgID returns org id of the request, empty means the default org
+func parseRuleOrgID(orgID string) int {
+	if id, err := strconv.Atoi(orgID); err == nil && id > 0 {
+		return id
+	}
+	return ckdb.DEFAULT_ORG_ID
+}
+
+// expandRuleFiles returns rule files of all orgs, `rule-files` belong to the default org
+func expandRuleFiles(cfg *prometheusConfig.PrometheusRule) ([]string, ruleOrgs, error) {
+	files := []string{}
+	orgs := ruleOrgs{}
+	orgPatterns := map[int][]string{ckdb.DEFAULT_ORG_ID: cfg.RuleFiles}
+	for orgID, patterns := range cfg.OrgRuleFiles {
+		if orgID == ckdb.DEFAULT_ORG_ID {
+			orgPatterns[orgID] = append(orgPatterns[orgID], patterns...)
+			continue
+		}
+		orgPatterns[orgID] = patterns
+	}
+	orgIDs := make([]int, 0, len(orgPatterns))
+	for orgID := range orgPatterns {
+		orgIDs = append(orgIDs, orgID)
+	}
+	sort.Ints(orgIDs)
+	for _, orgID := range orgIDs {
+		if orgID <= 0 || orgID > ckdb.MAX_ORG_ID {
+			return nil, nil, fmt.Errorf("invalid org id %d of rule files", orgID)
+		}
+		for _, pattern := range orgPatterns[orgID] {
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid rule file pattern %s: %s", pattern, err)
+			}
+			for _, file := range matches {
+				if prev, ok := orgs[file]; ok {
+					if prev != orgID {
+						return nil, nil, fmt.Errorf("rule file %s belongs to both org %d and org %d", file, prev, orgID)
+					}
+					continue
+				}
+				orgs[file] = orgID
+				files = append(files, file)
+			}
+		}
+	}
+	return files, orgs, nil
+}
+
+// ruleQueryFunc executes instant queries of rules in the org of their rule groups, same as rules.EngineQueryFunc,
+// scalar result is converted into vector
+func ruleQueryFunc(executor *prometheusExecutor, engine *promql.Engine, orgs ruleOrgs) rules.QueryFunc {
+	return func(ctx context.Context, q string, t time.Time) (promql.Vector, error) {
+		queryTime := strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', 3, 64)
+		result, err := executor.promQueryExecute(ctx, &model.PromQueryParams{
+			Promql:    q,
+			StartTime: queryTime,
+			EndTime:   queryTime,
+			Slimit:    config.Cfg.Prometheus.SeriesLimit,
+			OrgID:     strconv.Itoa(orgs.orgID(ctx)),
+			Context:   ctx,
+		}, engine)
+		if err != nil {
+			return nil, err
+		}
+		return ruleResultToVector(result)
+	}
+}
+
+func ruleResultToVector(result *model.PromQueryResponse) (promql.Vector, error) {
+	data, ok := result.Data.(*model.PromQueryData)
+	if !ok {
+		return nil, errors.New("rule result is invalid")
+	}
+	switch v := data.Result.(type) {
+	case promql.Vector:
+		return v, nil
+	case promql.Scalar:
+		return promql.Vector{promql.Sample{
+			Point:  promql.Point(v),
+			Metric: labels.Labels{},
+		}}, nil
+	default:
+		return nil, errors.New("rule result is not a vector or scalar")
+	}
+}
+
+// API Spec: https://prometheus.io/docs/prometheus/latest/querying/api/#rules
+func getRules(groups []*rules.Group, args *model.PromRulesParams) (*model.PromQueryResponse, error) {
+	if args.Type != "" && args.Type != RULE_TYPE_ALERT && args.Type != RULE_TYPE_RECORD {
+		return nil, fmt.Errorf("invalid rule type %q, should be %s or %s", args.Type, RULE_TYPE_ALERT, RULE_TYPE_RECORD)
+	}
+	ruleGroups := make([]*model.PromRuleGroup, 0, len(groups))
+	for _, group := range groups {
+		ruleGroup := &model.PromRuleGroup{
+			Name:           group.Name(),
+			File:           group.File(),
+			Rules:          []interface{}{},
+			Interval:       group.Interval().Seconds(),
+			Limit:          group.Limit(),
+			EvaluationTime: group.GetEvaluationTime().Seconds(),
+			LastEvaluation: group.GetLastEvaluation(),
+		}
+		for _, rule := range group.Rules() {
+			lastError := ""
+			if rule.LastError() != nil {
+				lastError = rule.LastError().Error()
+			}
+			switch r := rule.(type) {
+			case *rules.AlertingRule:
+				if args.Type == RULE_TYPE_RECORD {
+					continue
+				}
+				ruleGroup.Rules = append(ruleGroup.Rules, &model.PromAlertingRule{
+					State:          r.State().String(),
+					Name:           r.Name(),
+					Query:          r.Query().String(),
+					Duration:       r.HoldDuration().Seconds(),
+					Labels:         r.Labels(),
+					Annotations:    r.Annotations(),
+					Alerts:         newPromAlerts(r.ActiveAlerts()),
+					Health:         string(r.Health()),
+					LastError:      lastError,
+					EvaluationTime: r.GetEvaluationDuration().Seconds(),
+					LastEvaluation: r.GetEvaluationTimestamp(),
+					Type:           "alerting",
+				})
+			case *rules.RecordingRule:
+				if args.Type == RULE_TYPE_ALERT {
+					continue
+				}
+				ruleGroup.Rules = append(ruleGroup.Rules, &model.PromRecordingRule{
+					Name:           r.Name(),
+					Query:          r.Query().String(),
+					Labels:         r.Labels(),
+					Health:         string(r.Health()),
+					LastError:      lastError,
+					EvaluationTime: r.GetEvaluationDuration().Seconds(),
+					LastEvaluation: r.GetEvaluationTimestamp(),
+					Type:           "recording",
+				})
+			}
+		}
+		ruleGroups = append(ruleGroups, ruleGroup)
+	}
+	return &model.PromQueryResponse{Data: &model.PromRuleDiscovery{RuleGroups: ruleGroups}, Status: _SUCCESS}, nil
+}
+
+// API Spec: https://prometheus.io/docs/prometheus/latest/querying/api/#alerts
+func getAlerts(alertingRules []*rules.AlertingRule) *model.PromQueryResponse {
+	alerts := []*model.PromAlert{}
+	for _, rule := range alertingRules {
+		alerts = append(alerts, newPromAlerts(rule.ActiveAlerts())...)
+	}
+	return &model.PromQueryResponse{Data: &model.PromAlertDiscovery{Alerts: alerts}, Status: _SUCCESS}
+}
+
+func newPromAlerts(alerts []*rules.Alert) []*model.PromAlert {
+	result := make([]*model.PromAlert, 0, len(alerts))
+	for _, alert := range alerts {
+		activeAt := alert.ActiveAt
+		result = append(result, &model.PromAlert{
+			Labels:      alert.Labels,
+			Annotations: alert.Annotations,
+			State:       alert.State.String(),
+			ActiveAt:    &activeAt,
+			Value:       strconv.FormatFloat(alert.Value, 'e', -1, 64),
+		})
+	}
+	return result
+}
@@ -0,0 +1,370 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+
+	"github.com/deepflowio/deepflow/server/libs/codec"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+	prometheusConfig "github.com/deepflowio/deepflow/server/querier/app/prometheus/config"
+	"github.com/deepflowio/deepflow/server/querier/app/prometheus/model"
+)
+
+func TestEncodeIngesterFrame(t *testing.T) {
+	Convey("TestCase_EncodeIngesterFrame", t, func() {
+		frame := encodeIngesterFrame(2, datatype.MESSAGE_TYPE_TELEGRAF, [][]byte{[]byte("cpu value=1"), []byte("mem value=2")})
+
+		baseHeader := datatype.BaseHeader{}
+		So(baseHeader.Decode(frame), ShouldBeNil)
+		So(baseHeader.FrameSize, ShouldEqual, len(frame))
+		So(baseHeader.Type, ShouldEqual, datatype.MESSAGE_TYPE_TELEGRAF)
+		flowHeader := datatype.FlowHeader{}
+		flowHeader.Decode(frame[datatype.MESSAGE_HEADER_LEN:])
+		So(flowHeader.OrgID, ShouldEqual, 2)
+
+		decoder := &codec.SimpleDecoder{}
+		decoder.Init(frame[datatype.MESSAGE_HEADER_LEN+datatype.FLOW_HEADER_LEN:])
+		So(string(decoder.ReadBytes()), ShouldEqual, "cpu value=1")
+		So(string(decoder.ReadBytes()), ShouldEqual, "mem value=2")
+		So(decoder.IsEnd(), ShouldBeTrue)
+		So(decoder.Failed(), ShouldBeFalse)
+	})
+}
+
+func TestRuleAppender(t *testing.T) {
+	Convey("TestCase_RuleAppender", t, func() {
+		appender := &ruleAppender{}
+		ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+		appender.Append(0, labels.FromStrings(labels.MetricName, "job:http_requests:rate5m", "job", "api"), ts, 1.5)
+		// alerts, stale markers and NaN are not written
+		appender.Append(0, labels.FromStrings(labels.MetricName, ruleAlertsMetricName, labels.AlertName, "HighLatency"), ts, 1)
+		appender.Append(0, labels.FromStrings(labels.MetricName, "job:http_requests:rate5m", "job", "web"), ts, math.Float64frombits(value.StaleNaN))
+		appender.Append(0, labels.FromStrings(labels.MetricName, "job:http_requests:rate5m", "job", "db"), ts, math.NaN())
+
+		So(appender.lines, ShouldResemble, []string{"job:http_requests:rate5m,job=api value=1.5 1704067200000000000"})
+		So(appender.Rollback(), ShouldBeNil)
+		So(appender.lines, ShouldBeEmpty)
+		So(appender.Commit(), ShouldBeNil)
+	})
+}
+
+func TestAlertNotifierTransitions(t *testing.T) {
+	notifier := newAlertNotifier(nil, nil)
+	expr := "http_request_duration_seconds > 1"
+	firedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	alert := &rules.Alert{
+		State:       rules.StateFiring,
+		Labels:      labels.FromStrings(labels.AlertName, "HighLatency", "severity", "critical", "job", "api"),
+		Annotations: labels.FromStrings("summary", "high latency"),
+		Value:       2,
+		ActiveAt:    firedAt,
+		FiredAt:     firedAt,
+	}
+
+	Convey("TestCase_AlertNotifier_Firing", t, func() {
+		events := notifier.transitions(1, expr, []*rules.Alert{alert})
+		So(len(events), ShouldEqual, 1)
+		So(events[0].GetPolicyName(), ShouldEqual, "HighLatency")
+		So(events[0].GetEventLevel(), ShouldEqual, alarmEventLevelCritical)
+		So(events[0].GetPolicyLevel(), ShouldEqual, alarmPolicyLevelHigh)
+		So(events[0].GetTimestamp(), ShouldEqual, firedAt.Unix())
+		So(events[0].GetTriggerCondition(), ShouldEqual, expr)
+		So(events[0].GetTriggerValue(), ShouldEqual, 2)
+		So(events[0].GetAlarmTarget(), ShouldEqual, `{job="api", severity="critical"}`)
+
+		// resent alerts are not written again
+		So(notifier.transitions(1, expr, []*rules.Alert{alert}), ShouldBeEmpty)
+	})
+
+	Convey("TestCase_AlertNotifier_Resolved", t, func() {
+		lcuuid := notifier.firing[alertKey(1, expr, alert)]
+		resolved := *alert
+		resolved.State = rules.StateInactive
+		resolved.ResolvedAt = firedAt.Add(time.Minute)
+		events := notifier.transitions(1, expr, []*rules.Alert{&resolved})
+		So(len(events), ShouldEqual, 1)
+		So(events[0].GetLcuuid(), ShouldEqual, lcuuid)
+		So(events[0].GetEventLevel(), ShouldEqual, alarmEventLevelRecovered)
+		So(events[0].GetTimestamp(), ShouldEqual, resolved.ResolvedAt.Unix())
+
+		So(notifier.transitions(1, expr, []*rules.Alert{&resolved}), ShouldBeEmpty)
+	})
+}
+
+func TestGetRules(t *testing.T) {
+	alertExpr, _ := parser.ParseExpr("up == 0")
+	recordExpr, _ := parser.ParseExpr("sum by (job) (up)")
+	alertingRule := rules.NewAlertingRule("InstanceDown", alertExpr, 0, labels.FromStrings("severity", "critical"),
+		labels.FromStrings("summary", "instance down"), nil, "", true, newPrometheusLogger())
+	recordingRule := rules.NewRecordingRule("job:up:sum", recordExpr, nil)
+	queryFunc := func(ctx context.Context, q string, t time.Time) (promql.Vector, error) {
+		return promql.Vector{promql.Sample{
+			Point:  promql.Point{T: t.UnixMilli(), V: 0},
+			Metric: labels.FromStrings(labels.MetricName, "up", "job", "api"),
+		}}, nil
+	}
+	group := rules.NewGroup(rules.GroupOptions{
+		Name:     "deepflow",
+		File:     "rules.yaml",
+		Interval: time.Minute,
+		Rules:    []rules.Rule{alertingRule, recordingRule},
+		Opts: &rules.ManagerOptions{
+			QueryFunc:  queryFunc,
+			NotifyFunc: func(ctx context.Context, expr string, alerts ...*rules.Alert) {},
+			Appendable: &ruleAppendable{},
+			Context:    context.Background(),
+			Logger:     newPrometheusLogger(),
+		},
+	})
+	alertingRule.Eval(context.Background(), time.Now(), queryFunc, nil, 0)
+
+	Convey("TestCase_GetRules_All", t, func() {
+		result, err := getRules([]*rules.Group{group}, &model.PromRulesParams{})
+		So(err, ShouldBeNil)
+		ruleGroups := result.Data.(*model.PromRuleDiscovery).RuleGroups
+		So(len(ruleGroups), ShouldEqual, 1)
+		So(ruleGroups[0].Name, ShouldEqual, "deepflow")
+		So(ruleGroups[0].Interval, ShouldEqual, 60)
+		So(len(ruleGroups[0].Rules), ShouldEqual, 2)
+		alerting := ruleGroups[0].Rules[0].(*model.PromAlertingRule)
+		So(alerting.State, ShouldEqual, "firing")
+		So(alerting.Type, ShouldEqual, "alerting")
+		So(len(alerting.Alerts), ShouldEqual, 1)
+		So(ruleGroups[0].Rules[1].(*model.PromRecordingRule).Name, ShouldEqual, "job:up:sum")
+	})
+
+	Convey("TestCase_GetRules_Type", t, func() {
+		result, err := getRules([]*rules.Group{group}, &model.PromRulesParams{Type: RULE_TYPE_RECORD})
+		So(err, ShouldBeNil)
+		ruleGroups := result.Data.(*model.PromRuleDiscovery).RuleGroups
+		So(len(ruleGroups[0].Rules), ShouldEqual, 1)
+		So(ruleGroups[0].Rules[0].(*model.PromRecordingRule).Type, ShouldEqual, "recording")
+
+		_, err = getRules([]*rules.Group{group}, &model.PromRulesParams{Type: "unknown"})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("TestCase_GetAlerts", t, func() {
+		alerts := getAlerts([]*rules.AlertingRule{alertingRule}).Data.(*model.PromAlertDiscovery).Alerts
+		So(len(alerts), ShouldEqual, 1)
+		So(alerts[0].State, ShouldEqual, "firing")
+		So(alerts[0].Labels.Get("job"), ShouldEqual, "api")
+		So(alerts[0].Value, ShouldEqual, "0e+00")
+	})
+}
+
+func TestRuleOrgs(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"default.yaml", "org2/a.yaml", "org2/b.yaml"} {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0755)
+		os.WriteFile(filepath.Join(dir, file), []byte("groups: []\n"), 0644)
+	}
+
+	Convey("TestCase_RuleOrgs_Expand", t, func() {
+		files, orgs, err := expandRuleFiles(&prometheusConfig.PrometheusRule{
+			RuleFiles:    []string{filepath.Join(dir, "*.yaml")},
+			OrgRuleFiles: map[int][]string{2: {filepath.Join(dir, "org2", "*.yaml")}},
+		})
+		So(err, ShouldBeNil)
+		So(len(files), ShouldEqual, 3)
+		So(orgs.orgIDOfFile(filepath.Join(dir, "default.yaml")), ShouldEqual, 1)
+		So(orgs.orgIDOfFile(filepath.Join(dir, "org2", "b.yaml")), ShouldEqual, 2)
+
+		// rule groups carry their files in the context of evaluation
+		ctx := promql.NewOriginContext(context.Background(), map[string]interface{}{
+			"ruleGroup": map[string]string{"file": filepath.Join(dir, "org2", "a.yaml"), "name": "deepflow"},
+		})
+		So(orgs.orgID(ctx), ShouldEqual, 2)
+		So(orgs.orgID(context.Background()), ShouldEqual, 1)
+		So((&ruleAppendable{orgs: orgs}).Appender(ctx).(*ruleAppender).orgID, ShouldEqual, 2)
+
+		_, _, err = expandRuleFiles(&prometheusConfig.PrometheusRule{
+			RuleFiles:    []string{filepath.Join(dir, "org2", "a.yaml")},
+			OrgRuleFiles: map[int][]string{2: {filepath.Join(dir, "org2", "*.yaml")}},
+		})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("TestCase_RuleOrgs_ParseOrgID", t, func() {
+		So(parseRuleOrgID(""), ShouldEqual, 1)
+		So(parseRuleOrgID("3"), ShouldEqual, 3)
+		So(parseRuleOrgID("x"), ShouldEqual, 1)
+	})
+}
+
+func TestRuleManagerLeader(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.yaml")
+	os.WriteFile(file, []byte(`groups:
+- name: deepflow
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+`), 0644)
+	manager, err := newRuleManager(&prometheusConfig.PrometheusRule{RuleFiles: []string{file}, EvaluationInterval: 60}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	isLeader := false
+	manager.isLeader = func() (bool, error) { return isLeader, nil }
+	defer manager.stop()
+
+	Convey("TestCase_RuleManager_Leader", t, func() {
+		// rules are not evaluated by followers
+		manager.checkLeader()
+		So(manager.getManager(), ShouldBeNil)
+		So(manager.ruleGroups(1), ShouldBeEmpty)
+
+		isLeader = true
+		manager.checkLeader()
+		So(manager.getManager(), ShouldNotBeNil)
+		So(len(manager.ruleGroups(1)), ShouldEqual, 1)
+		So(manager.ruleGroups(2), ShouldBeEmpty)
+
+		isLeader = false
+		manager.checkLeader()
+		So(manager.getManager(), ShouldBeNil)
+
+		// a new manager is started when becoming the leader again
+		isLeader = true
+		manager.checkLeader()
+		So(len(manager.ruleGroups(1)), ShouldEqual, 1)
+	})
+}
+
+// writeTestCert writes a certificate signed by the parent, a self-signed CA if parent is nil
+func writeTestCert(t *testing.T, dir, name string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, interface{}(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestIngesterWriterTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := writeTestCert(t, dir, "ca", nil)
+	server := writeTestCert(t, dir, "server", &ca)
+	writeTestCert(t, dir, "client", &ca)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.Leaf)
+	// the receiver of ingester with 'receiver-tls' and 'client-ca-file'
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{server},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	address := listener.Addr().String()
+	messages := [][]byte{[]byte("cpu value=1")}
+	frame := encodeIngesterFrame(1, datatype.MESSAGE_TYPE_TELEGRAF, messages)
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		buffer := make([]byte, len(frame))
+		if _, err := io.ReadFull(conn, buffer); err != nil {
+			buffer = nil
+		}
+		received <- buffer
+	}()
+
+	Convey("TestCase_IngesterWriter_TLS", t, func() {
+		tlsConfig, err := newIngesterTLSConfig(address, &prometheusConfig.PrometheusRuleTLS{})
+		So(tlsConfig, ShouldBeNil)
+		So(err, ShouldBeNil)
+
+		// server name is the host of address by default
+		tlsConfig, err = newIngesterTLSConfig(address, &prometheusConfig.PrometheusRuleTLS{Enabled: true})
+		So(err, ShouldBeNil)
+		So(tlsConfig.ServerName, ShouldEqual, "127.0.0.1")
+
+		_, err = newIngesterTLSConfig(address, &prometheusConfig.PrometheusRuleTLS{Enabled: true, CAFile: filepath.Join(dir, "none.crt")})
+		So(err, ShouldNotBeNil)
+
+		tlsConfig, err = newIngesterTLSConfig(address, &prometheusConfig.PrometheusRuleTLS{
+			Enabled:    true,
+			CAFile:     filepath.Join(dir, "ca.crt"),
+			CertFile:   filepath.Join(dir, "client.crt"),
+			KeyFile:    filepath.Join(dir, "client.key"),
+			ServerName: "localhost",
+		})
+		So(err, ShouldBeNil)
+		writer := newIngesterWriter(address, tlsConfig)
+		defer writer.close()
+		So(writer.write(1, datatype.MESSAGE_TYPE_TELEGRAF, messages), ShouldBeNil)
+		select {
+		case buffer := <-received:
+			So(buffer, ShouldResemble, frame)
+		case <-time.After(10 * time.Second):
+			t.Fatal("frame is not received by ingester")
+		}
+	})
+}
@@ -0,0 +1,351 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/influxdata/influxdb/models"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/deepflowio/deepflow/message/alarm_event"
+	"github.com/deepflowio/deepflow/server/libs/codec"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+	prometheusConfig "github.com/deepflowio/deepflow/server/querier/app/prometheus/config"
+)
+
+const (
+	ingesterDialTimeout  = 5 * time.Second
+	ingesterWriteTimeout = 10 * time.Second
+
+	// series appended by alerting rules, only results of recording rules are written to ext_metrics
+	ruleAlertsMetricName         = "ALERTS"
+	ruleAlertsForStateMetricName = "ALERTS_FOR_STATE"
+	// field name of recording results in ext_metrics, the metric could be queried as
+	// `ext_metrics__metrics__influxdb_${record}__value`
+	ruleRecordFieldName = "value"
+)
+
+// values of policy_app_type/policy_level/event_level in event.alarm_event,
+// ref: querier/db_descriptions/clickhouse/tag/enum
+const (
+	alarmPolicyAppTypeMetric = 3
+
+	alarmPolicyLevelLow    = 0
+	alarmPolicyLevelMedium = 1
+	alarmPolicyLevelHigh   = 2
+
+	alarmEventLevelCritical  = 1
+	alarmEventLevelError     = 2
+	alarmEventLevelWarn      = 3
+	alarmEventLevelRecovered = 5
+	alarmEventLevelInfo      = 6
+)
+
+// ingesterWriter sends messages to ingester in the same frame as deepflow-agent does:
+//
+//	| frame_size u32 | msg_type u8 | version u32 | team_id u32 | org_id u32 | agent_id u16 | len u32 | message | len u32 | message | ... |
+//
+// frame_size and msg_type are big endian, others are little endian.
+type ingesterWriter struct {
+	sync.Mutex
+	address   string
+	tlsConfig *tls.Config // nil if ingester is connected without TLS
+	conn      net.Conn
+}
+
+func newIngesterWriter(address string, tlsConfig *tls.Config) *ingesterWriter {
+	return &ingesterWriter{address: address, tlsConfig: tlsConfig}
+}
+
+// newIngesterTLSConfig returns the client TLS config connecting to the 'receiver-tls' of ingester, nil if disabled
+func newIngesterTLSConfig(address string, cfg *prometheusConfig.PrometheusRuleTLS) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cfg.ServerName,
+	}
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ingester address %s: %s", address, err)
+		}
+		config.ServerName = host
+	}
+	if cfg.CAFile != "" {
+		caPEM, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca %s failed: %s", cfg.CAFile, err)
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no valid certificate in ca %s", cfg.CAFile)
+		}
+		config.RootCAs = rootCAs
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load certificate %s and key %s failed: %s", cfg.CertFile, cfg.KeyFile, err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+func (w *ingesterWriter) dial() (net.Conn, error) {
+	if w.tlsConfig == nil {
+		return net.DialTimeout("tcp", w.address, ingesterDialTimeout)
+	}
+	// the timeout includes the TLS handshake
+	return tls.DialWithDialer(&net.Dialer{Timeout: ingesterDialTimeout}, "tcp", w.address, w.tlsConfig)
+}
+
+func encodeIngesterFrame(orgID int, msgType datatype.MessageType, messages [][]byte) []byte {
+	encoder := &codec.SimpleEncoder{}
+	header := make([]byte, datatype.MESSAGE_HEADER_LEN+datatype.FLOW_HEADER_LEN)
+	encoder.WriteRawString(string(header))
+	for _, message := range messages {
+		encoder.WriteBytes(message)
+	}
+	frame := encoder.Bytes()
+	baseHeader := datatype.BaseHeader{FrameSize: uint32(len(frame)), Type: msgType}
+	baseHeader.Encode(frame)
+	// team is left 0, which is the default team in ingester
+	flowHeader := datatype.FlowHeader{OrgID: uint32(orgID)}
+	flowHeader.Encode(frame[datatype.MESSAGE_HEADER_LEN:])
+	return frame
+}
+
+func (w *ingesterWriter) write(orgID int, msgType datatype.MessageType, messages [][]byte) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	frame := encodeIngesterFrame(orgID, msgType, messages)
+
+	w.Lock()
+	defer w.Unlock()
+	if w.conn == nil {
+		conn, err := w.dial()
+		if err != nil {
+			return fmt.Errorf("connect to ingester %s failed: %s", w.address, err)
+		}
+		w.conn = conn
+	}
+	w.conn.SetWriteDeadline(time.Now().Add(ingesterWriteTimeout))
+	if _, err := w.conn.Write(frame); err != nil {
+		// reconnect next time, the frame may be half sent
+		w.conn.Close()
+		w.conn = nil
+		return fmt.Errorf("write to ingester %s failed: %s", w.address, err)
+	}
+	return nil
+}
+
+func (w *ingesterWriter) close() {
+	w.Lock()
+	defer w.Unlock()
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// ruleAppendable writes results of recording rules to ext_metrics in influxdb line protocol,
+// a recording rule `record: ${record}` is written as measurement `${record}` with field `value`
+type ruleAppendable struct {
+	writer *ingesterWriter
+	orgs   ruleOrgs
+}
+
+func (a *ruleAppendable) Appender(ctx context.Context) storage.Appender {
+	return &ruleAppender{writer: a.writer, orgID: a.orgs.orgID(ctx)}
+}
+
+type ruleAppender struct {
+	writer *ingesterWriter
+	orgID  int
+	lines  []string
+}
+
+func (a *ruleAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	name := l.Get(labels.MetricName)
+	// alerts are written as alarm events by alertNotifier,
+	// stale markers are skipped because ext_metrics has no staleness
+	if name == "" || name == ruleAlertsMetricName || name == ruleAlertsForStateMetricName || value.IsStaleNaN(v) {
+		return 0, nil
+	}
+	// NaN and Inf are not supported by line protocol
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, nil
+	}
+	tags := make(map[string]string, len(l)-1)
+	for _, label := range l {
+		if label.Name != labels.MetricName {
+			tags[label.Name] = label.Value
+		}
+	}
+	point, err := models.NewPoint(name, models.NewTags(tags), models.Fields{ruleRecordFieldName: v}, time.Unix(0, t*int64(time.Millisecond)))
+	if err != nil {
+		return 0, err
+	}
+	a.lines = append(a.lines, point.String())
+	return 0, nil
+}
+
+func (a *ruleAppender) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar) (storage.SeriesRef, error) {
+	return 0, nil
+}
+
+func (a *ruleAppender) Commit() error {
+	if len(a.lines) == 0 {
+		return nil
+	}
+	err := a.writer.write(a.orgID, datatype.MESSAGE_TYPE_TELEGRAF, [][]byte{[]byte(strings.Join(a.lines, "\n"))})
+	a.lines = nil
+	return err
+}
+
+func (a *ruleAppender) Rollback() error {
+	a.lines = nil
+	return nil
+}
+
+// alertNotifier writes alarm events when alerts start firing or get resolved,
+// rules.Manager resends active alerts periodically, which are ignored here.
+type alertNotifier struct {
+	sync.Mutex
+	writer *ingesterWriter
+	orgs   ruleOrgs
+	// key of firing alerts => lcuuid of the alarm event
+	firing map[string]string
+}
+
+func newAlertNotifier(writer *ingesterWriter, orgs ruleOrgs) *alertNotifier {
+	return &alertNotifier{writer: writer, orgs: orgs, firing: map[string]string{}}
+}
+
+func alertKey(orgID int, expr string, alert *rules.Alert) string {
+	return strconv.Itoa(orgID) + expr + strconv.FormatUint(alert.Labels.Hash(), 16)
+}
+
+func (n *alertNotifier) notify(ctx context.Context, expr string, alerts ...*rules.Alert) {
+	orgID := n.orgs.orgID(ctx)
+	events := n.transitions(orgID, expr, alerts)
+	if len(events) == 0 {
+		return
+	}
+	messages := make([][]byte, 0, len(events))
+	for _, event := range events {
+		bytes, err := event.Marshal()
+		if err != nil {
+			log.Errorf("marshal alarm event of %s failed: %s", event.GetPolicyName(), err)
+			continue
+		}
+		messages = append(messages, bytes)
+	}
+	if err := n.writer.write(orgID, datatype.MESSAGE_TYPE_ALARM_EVENT, messages); err != nil {
+		log.Errorf("write %d alarm events of org %d failed: %s", len(messages), orgID, err)
+	}
+}
+
+// transitions returns alarm events of alerts which start firing or get resolved,
+// the firing and resolved events of an alert share the same lcuuid
+func (n *alertNotifier) transitions(orgID int, expr string, alerts []*rules.Alert) []*alarm_event.AlarmEvent {
+	n.Lock()
+	defer n.Unlock()
+	var events []*alarm_event.AlarmEvent
+	for _, alert := range alerts {
+		key := alertKey(orgID, expr, alert)
+		lcuuid, isFiring := n.firing[key]
+		if !alert.ResolvedAt.IsZero() {
+			if isFiring {
+				events = append(events, newAlarmEvent(lcuuid, expr, alert, alert.ResolvedAt, alarmEventLevelRecovered))
+				delete(n.firing, key)
+			}
+			continue
+		}
+		if alert.State != rules.StateFiring || isFiring {
+			continue
+		}
+		lcuuid = uuid.NewString()
+		n.firing[key] = lcuuid
+		events = append(events, newAlarmEvent(lcuuid, expr, alert, alert.FiredAt, severityToEventLevel(alert.Labels.Get("severity"))))
+	}
+	return events
+}
+
+func newAlarmEvent(lcuuid, expr string, alert *rules.Alert, ts time.Time, eventLevel uint32) *alarm_event.AlarmEvent {
+	alertLabels := labels.NewBuilder(alert.Labels).Del(labels.AlertName).Labels()
+	conditions, _ := json.Marshal(alert.Annotations.Map())
+	return &alarm_event.AlarmEvent{
+		Lcuuid:                proto.String(lcuuid),
+		Timestamp:             proto.Uint32(uint32(ts.Unix())),
+		PolicyName:            proto.String(alert.Labels.Get(labels.AlertName)),
+		PolicyLevel:           proto.Uint32(severityToPolicyLevel(alert.Labels.Get("severity"))),
+		PolicyAppType:         proto.Uint32(alarmPolicyAppTypeMetric),
+		PolicyQueryConditions: proto.String(string(conditions)),
+		TriggerCondition:      proto.String(expr),
+		TriggerValue:          proto.Float64(alert.Value),
+		EventLevel:            proto.Uint32(eventLevel),
+		AlarmTarget:           proto.String(alertLabels.String()),
+	}
+}
+
+// the `severity` label is commonly used in prometheus alerting rules
+func severityToEventLevel(severity string) uint32 {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return alarmEventLevelCritical
+	case "error":
+		return alarmEventLevelError
+	case "info", "none":
+		return alarmEventLevelInfo
+	default:
+		return alarmEventLevelWarn
+	}
+}
+
+func severityToPolicyLevel(severity string) uint32 {
+	switch strings.ToLower(severity) {
+	case "critical", "error":
+		return alarmPolicyLevelHigh
+	case "info", "none":
+		return alarmPolicyLevelLow
+	default:
+		return alarmPolicyLevelMedium
+	}
+}
@@ -24,6 +24,7 @@ import (
 	logging "github.com/op/go-logging"
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
 
 	"github.com/deepflowio/deepflow/server/libs/datastructure"
 	"github.com/deepflowio/deepflow/server/querier/app/prometheus/model"
@@ -44,6 +45,8 @@ type PrometheusService struct {
 	// keep only 1 instance of prometheus engine during server lifetime
 	engine   *promql.Engine
 	executor *prometheusExecutor
+	// evaluator of alerting and recording rules, nil if disabled
+	rules *ruleManager
 	// prometheus query rate limit
 	QPSLeakyBucket *datastructure.LeakyBucket
 }
@@ -61,18 +64,28 @@ func NewPrometheusService() *PrometheusService {
 		EnableNegativeOffset:     true,
 		EnablePerStepStats:       true,
 	}
-	return &PrometheusService{
+	s := &PrometheusService{
 		engine:         promql.NewEngine(opts),
 		executor:       NewPrometheusExecutor(opts.LookbackDelta),
 		QPSLeakyBucket: &datastructure.LeakyBucket{},
 	}
+	if config.Cfg.Prometheus.Rule.Enabled {
+		manager, err := newRuleManager(&config.Cfg.Prometheus.Rule, s.engine, s.executor)
+		if err != nil {
+			log.Errorf("load prometheus rules failed: %s", err)
+		} else {
+			s.rules = manager
+			go manager.run()
+		}
+	}
+	return s
 }
 
-func (s *PrometheusService) PromRemoteReadService(req *prompb.ReadRequest, ctx context.Context, offloading bool, orgID string) (resp *prompb.ReadResponse, err error) {
+func (s *PrometheusService) PromRemoteReadService(req *prompb.ReadRequest, ctx context.Context, offloading bool, orgID string, blockTeamID []string) (resp *prompb.ReadResponse, err error) {
 	if offloading {
-		return s.executor.promRemoteReadOffloadingExecute(ctx, req, orgID)
+		return s.executor.promRemoteReadOffloadingExecute(ctx, req, orgID, blockTeamID)
 	} else {
-		return s.executor.promRemoteReadExecute(ctx, req, orgID)
+		return s.executor.promRemoteReadExecute(ctx, req, orgID, blockTeamID)
 	}
 }
 
@@ -100,6 +113,34 @@ func (s *PrometheusService) PromSeriesQueryService(args *model.PromQueryParams,
 	return s.executor.series(ctx, args)
 }
 
+func (s *PrometheusService) PromLabelNamesService(args *model.PromMetaParams, ctx context.Context) (*model.PromQueryResponse, error) {
+	return s.executor.getLabelNames(ctx, args)
+}
+
+func (s *PrometheusService) PromMetadataService(args *model.PromMetaParams, ctx context.Context) (*model.PromQueryResponse, error) {
+	return s.executor.getMetadata(ctx, args)
+}
+
+func (s *PrometheusService) PromExemplarsQueryService(args *model.PromQueryParams, ctx context.Context) (*model.PromQueryResponse, error) {
+	return s.executor.queryExemplars(ctx, args)
+}
+
+func (s *PrometheusService) PromRulesService(args *model.PromRulesParams) (*model.PromQueryResponse, error) {
+	var groups []*rules.Group
+	if s.rules != nil {
+		groups = s.rules.ruleGroups(parseRuleOrgID(args.OrgID))
+	}
+	return getRules(groups, args)
+}
+
+func (s *PrometheusService) PromAlertsService(orgID string) *model.PromQueryResponse {
+	var alertingRules []*rules.AlertingRule
+	if s.rules != nil {
+		alertingRules = s.rules.alertingRules(parseRuleOrgID(orgID))
+	}
+	return getAlerts(alertingRules)
+}
+
 func (s *PrometheusService) PromQLAnalysis(ctx context.Context, metric string, targetLabels []string, appLabels []string, startTime string, endTime string, orgID string) (*common.Result, error) {
 	return s.executor.promQLAnalysis(ctx, metric, targetLabels, appLabels, startTime, endTime, orgID)
 }
@@ -0,0 +1,230 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/common"
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/config"
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/model"
+	"github.com/mitchellh/mapstructure"
+	"github.com/op/go-logging"
+)
+
+const (
+	// jaeger-query http api, it is used by jaeger-ui and has been stable since v1.0
+	// ref: https://github.com/jaegertracing/jaeger/blob/main/cmd/query/app/http_handler.go
+	jaeger_query_url = "api/traces"
+
+	JaegerRefTypeChildOf     = "CHILD_OF"
+	JaegerRefTypeFollowsFrom = "FOLLOWS_FROM"
+
+	JaegerTagSpanKind          = "span.kind"
+	JaegerTagHostname          = "hostname"
+	JaegerTagServiceInstanceID = "service.instance.id"
+)
+
+// jaeger ui model
+// ref: https://github.com/jaegertracing/jaeger/blob/main/model/json/model.go
+type jaegerKeyValue struct {
+	Key   string      `json:"key"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+type jaegerReference struct {
+	RefType string `json:"refType"`
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+type jaegerSpan struct {
+	TraceID       string            `json:"traceID"`
+	SpanID        string            `json:"spanID"`
+	OperationName string            `json:"operationName"`
+	References    []jaegerReference `json:"references"`
+	StartTime     int64             `json:"startTime"` // microseconds
+	Duration      int64             `json:"duration"`  // microseconds
+	Tags          []jaegerKeyValue  `json:"tags"`
+	ProcessID     string            `json:"processID"`
+}
+
+type jaegerProcess struct {
+	ServiceName string           `json:"serviceName"`
+	Tags        []jaegerKeyValue `json:"tags"`
+}
+
+type jaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []jaegerSpan             `json:"spans"`
+	Processes map[string]jaegerProcess `json:"processes"`
+}
+
+type jaegerError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+type jaegerTraceResponse struct {
+	Data   []jaegerTrace `json:"data"`
+	Errors []jaegerError `json:"errors"`
+}
+
+type jaegerConfig struct {
+	Auth string `mapstructure:"auth"` // basic auth
+}
+
+type JaegerAdapter struct {
+}
+
+var log_jaeger = logging.MustGetLogger("tracing-adapter.jaeger")
+
+func (j *JaegerAdapter) GetTrace(traceID string, c *config.ExternalAPM) (*model.ExTrace, error) {
+	jaegerConfig := &jaegerConfig{}
+	err := mapstructure.Decode(c.ExtraConfig, jaegerConfig)
+	if err != nil {
+		log_jaeger.Errorf("cannot decode jaeger extra config %v, err: %s", c.ExtraConfig, err)
+		return nil, err
+	}
+	traces, err := j.getTrace(traceID, c, jaegerConfig)
+	if err != nil || traces == nil {
+		return nil, err
+	}
+	return j.jaegerTracesToExTraces(traces), nil
+}
+
+func (j *JaegerAdapter) getTrace(traceID string, c *config.ExternalAPM, jaegerConfig *jaegerConfig) (*jaegerTraceResponse, error) {
+	scheme := "http"
+	if c.TLS != nil {
+		scheme = "https"
+	}
+	result, err := common.DoRequest(http.MethodGet, fmt.Sprintf("%s://%s/%s/%s", scheme, c.Addr, jaeger_query_url, traceID), nil, appendBasicAuthHeader(jaegerConfig.Auth), c.Timeout, c.TLS)
+	if err != nil || result == nil {
+		log_jaeger.Errorf("query jaeger trace %s at %s failed! err: %s", traceID, c.Addr, err)
+		return nil, err
+	}
+	traces, err := common.Deserialize[jaegerTraceResponse](result)
+	if err != nil || traces == nil {
+		log_jaeger.Errorf("deserialize failed! err: %s", err)
+		return nil, err
+	}
+	if len(traces.Errors) > 0 {
+		err = fmt.Errorf("query jaeger trace %s failed, code: %d, msg: %s", traceID, traces.Errors[0].Code, traces.Errors[0].Msg)
+		log_jaeger.Error(err)
+		return nil, err
+	}
+	return traces, nil
+}
+
+func (j *JaegerAdapter) jaegerTracesToExTraces(traces *jaegerTraceResponse) *model.ExTrace {
+	exTrace := &model.ExTrace{}
+	spanCount := 0
+	for i := range traces.Data {
+		spanCount += len(traces.Data[i].Spans)
+	}
+	exTrace.Spans = make([]model.ExSpan, 0, spanCount)
+	for i := range traces.Data {
+		trace := &traces.Data[i]
+		for k := range trace.Spans {
+			jaegerSpan := &trace.Spans[k]
+			process := trace.Processes[jaegerSpan.ProcessID]
+			processTags := j.jaegerTagsToAttributes(process.Tags)
+			attrs := j.jaegerTagsToAttributes(jaegerSpan.Tags)
+			spanKind := otelSpanKind(attrs[JaegerTagSpanKind])
+			span := model.ExSpan{
+				Name:            jaegerSpan.OperationName,
+				ID:              generateUniqueID(jaegerSpan.SpanID, jaegerSpan.StartTime, len(exTrace.Spans)),
+				StartTimeUs:     jaegerSpan.StartTime,
+				EndTimeUs:       jaegerSpan.StartTime + jaegerSpan.Duration,
+				TapSide:         spanKindToTapSide(spanKind),
+				TraceID:         jaegerSpan.TraceID,
+				SpanID:          jaegerSpan.SpanID,
+				ParentSpanID:    j.jaegerRefsToParentSpanID(jaegerSpan.References),
+				SpanKind:        spanKind,
+				Endpoint:        jaegerSpan.OperationName,
+				AppService:      process.ServiceName,
+				AppInstance:     firstAttribute(processTags, JaegerTagServiceInstanceID, JaegerTagHostname),
+				ServiceUname:    process.ServiceName,
+				RequestResource: jaegerSpan.OperationName, // maybe overwrite by tags
+				SignalSource:    model.L7_FLOW_SIGNAL_SOURCE_OTEL,
+				Attribute:       attrs,
+			}
+			attributesToSpanRequestInfo(attrs, &span)
+			exTrace.Spans = append(exTrace.Spans, span)
+		}
+	}
+	return exTrace
+}
+
+func (j *JaegerAdapter) jaegerRefsToParentSpanID(refs []jaegerReference) string {
+	// a span only have ONE parent in deepflow, prefer CHILD_OF to FOLLOWS_FROM
+	parentSpanID := ""
+	for _, ref := range refs {
+		if ref.RefType == JaegerRefTypeChildOf {
+			return ref.SpanID
+		}
+		if parentSpanID == "" {
+			parentSpanID = ref.SpanID
+		}
+	}
+	return parentSpanID
+}
+
+func (j *JaegerAdapter) jaegerTagsToAttributes(tags []jaegerKeyValue) map[string]string {
+	attr := make(map[string]string, len(tags))
+	for _, v := range tags {
+		switch value := v.Value.(type) {
+		case string:
+			attr[v.Key] = value
+		case float64:
+			// numbers are always decoded as float64, format integers without exponent
+			attr[v.Key] = strconv.FormatFloat(value, 'f', -1, 64)
+		case nil:
+			attr[v.Key] = ""
+		default:
+			attr[v.Key] = fmt.Sprint(value)
+		}
+	}
+	return attr
+}
+
+func appendBasicAuthHeader(auth string) map[string]string {
+	header := common.DefaultContentTypeHeader()
+	if auth == "" {
+		return header
+	}
+	header["Authorization"] = fmt.Sprintf("Basic %s", auth)
+	return header
+}
+
+// generateUniqueID generates the unique id by hex span id of jaeger or zipkin,
+// index makes the ids of zipkin shared spans (client and server have the same span id) different
+func generateUniqueID(spanID string, startTimeUs int64, index int) uint64 {
+	encodeID, err := strconv.ParseUint(spanID, 16, 64)
+	if err != nil {
+		h := fnv.New64a()
+		h.Write([]byte(spanID))
+		encodeID = h.Sum64() ^ uint64(startTimeUs)
+	}
+	// high 40 bits: encodeID
+	// last 24 bits: index
+	return encodeID<<24 | uint64(index)&0xffffff
+}
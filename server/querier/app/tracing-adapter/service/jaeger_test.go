@@ -0,0 +1,100 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/common"
+	. "github.com/smartystreets/goconvey/convey"
+	v1 "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+var jaeger_mock_data = `{
+"data": [
+    {
+        "traceID": "4a7d3c2f1b0e9d8c",
+        "spans": [
+            {
+                "traceID": "4a7d3c2f1b0e9d8c",
+                "spanID": "4a7d3c2f1b0e9d8c",
+                "operationName": "GET /api/orders",
+                "references": [],
+                "startTime": 1694428678774000,
+                "duration": 53000,
+                "tags": [
+                    {"key": "span.kind", "type": "string", "value": "server"},
+                    {"key": "http.method", "type": "string", "value": "GET"},
+                    {"key": "http.target", "type": "string", "value": "/api/orders?id=1"},
+                    {"key": "http.status_code", "type": "int64", "value": 200},
+                    {"key": "error", "type": "bool", "value": false}
+                ],
+                "processID": "p1"
+            },
+            {
+                "traceID": "4a7d3c2f1b0e9d8c",
+                "spanID": "11aa22bb33cc44dd",
+                "operationName": "SELECT orders",
+                "references": [
+                    {"refType": "FOLLOWS_FROM", "traceID": "4a7d3c2f1b0e9d8c", "spanID": "0000000000000001"},
+                    {"refType": "CHILD_OF", "traceID": "4a7d3c2f1b0e9d8c", "spanID": "4a7d3c2f1b0e9d8c"}
+                ],
+                "startTime": 1694428678780000,
+                "duration": 1200,
+                "tags": [
+                    {"key": "span.kind", "type": "string", "value": "client"},
+                    {"key": "db.system", "type": "string", "value": "mysql"},
+                    {"key": "db.operation", "type": "string", "value": "SELECT"},
+                    {"key": "db.statement", "type": "string", "value": "SELECT * FROM orders WHERE id = ?"}
+                ],
+                "processID": "p1"
+            }
+        ],
+        "processes": {
+            "p1": {
+                "serviceName": "order-service",
+                "tags": [
+                    {"key": "hostname", "type": "string", "value": "order-7d9c5"}
+                ]
+            }
+        }
+    }
+],
+"total": 0,
+"limit": 0,
+"offset": 0,
+"errors": null
+}`
+
+func TestGetJaegerTrace(t *testing.T) {
+	jaegerAdapter := &JaegerAdapter{}
+	Convey("TestGetJaegerTrace_Success", t, func() {
+		traces, err := common.Deserialize[jaegerTraceResponse]([]byte(jaeger_mock_data))
+		So(err, ShouldBeNil)
+		So(len(traces.Data), ShouldEqual, 1)
+		result := jaegerAdapter.jaegerTracesToExTraces(traces)
+		So(result, ShouldNotBeNil)
+		So(len(result.Spans), ShouldEqual, 2)
+
+		server := result.Spans[0]
+		So(server.ID, ShouldBeGreaterThan, 0)
+		So(server.TapSide, ShouldEqual, "s-app")
+		So(server.SpanKind, ShouldEqual, int(v1.Span_SPAN_KIND_SERVER))
+		So(server.ParentSpanID, ShouldEqual, "")
+		So(server.EndTimeUs-server.StartTimeUs, ShouldEqual, 53000)
+		So(server.AppService, ShouldEqual, "order-service")
+		So(server.AppInstance, ShouldEqual, "order-7d9c5")
+		So(server.L7ProtocolStr, ShouldEqual, "HTTP")
+		So(server.RequestType, ShouldEqual, "GET")
+		So(server.RequestResource, ShouldEqual, "/api/orders?id=1")
+		So(server.ResponseStatus, ShouldEqual, 200)
+		So(server.Attribute["http.status_code"], ShouldEqual, "200")
+		So(server.Attribute["error"], ShouldEqual, "false")
+
+		client := result.Spans[1]
+		So(client.ID, ShouldNotEqual, server.ID)
+		So(client.TapSide, ShouldEqual, "c-app")
+		So(client.ParentSpanID, ShouldEqual, server.SpanID)
+		So(client.L7ProtocolStr, ShouldEqual, "MySQL")
+		So(client.RequestType, ShouldEqual, "SELECT")
+		So(client.RequestResource, ShouldEqual, "SELECT * FROM orders WHERE id = ?")
+	})
+}
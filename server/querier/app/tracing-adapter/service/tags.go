@@ -0,0 +1,186 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/model"
+	v1 "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// tags (and old names) of opentelemetry semantic conventions, used by jaeger and zipkin spans
+// ref: https://opentelemetry.io/docs/specs/semconv/
+const (
+	AttributeHTTPRequestMethod      = "http.request.method"
+	AttributeHTTPResponseStatusCode = "http.response.status_code"
+	AttributeHTTPFlavor             = "http.flavor"
+	AttributeNetworkProtocolVersion = "network.protocol.version"
+	AttributeHTTPTarget             = "http.target"
+	AttributeHTTPPath               = "http.path" // zipkin brave
+	AttributeHTTPRoute              = "http.route"
+	AttributeHTTPURL                = "http.url"
+	AttributeURLPath                = "url.path"
+	AttributeURLFull                = "url.full"
+
+	AttributeDbSystem    = "db.system"
+	AttributeDbOperation = "db.operation"
+	AttributeSQLQuery    = "sql.query" // zipkin brave
+
+	AttributeRPCSystem  = "rpc.system"
+	AttributeRPCService = "rpc.service"
+	AttributeRPCMethod  = "rpc.method"
+
+	AttributeMessagingSystem          = "messaging.system"
+	AttributeMessagingDestination     = "messaging.destination"
+	AttributeMessagingDestinationName = "messaging.destination.name"
+	AttributeMessagingOperation       = "messaging.operation"
+)
+
+const (
+	SpanKindClient   = "client"
+	SpanKindServer   = "server"
+	SpanKindProducer = "producer"
+	SpanKindConsumer = "consumer"
+	SpanKindInternal = "internal"
+)
+
+// l7 protocols of deepflow, same as libs/datatype.L7Protocol
+type l7Protocol struct {
+	id   int
+	name string
+}
+
+var (
+	l7ProtocolHTTP1   = l7Protocol{20, "HTTP"}
+	l7ProtocolHTTP2   = l7Protocol{21, "HTTP2"}
+	l7ProtocolDubbo   = l7Protocol{40, "Dubbo"}
+	l7ProtocolGRPC    = l7Protocol{41, "gRPC"}
+	l7ProtocolMySQL   = l7Protocol{60, "MySQL"}
+	l7ProtocolPostgre = l7Protocol{61, "PostgreSQL"}
+	l7ProtocolOracle  = l7Protocol{62, "Oracle"}
+	l7ProtocolRedis   = l7Protocol{80, "Redis"}
+	l7ProtocolMongoDB = l7Protocol{81, "MongoDB"}
+	l7ProtocolKafka   = l7Protocol{100, "Kafka"}
+	l7ProtocolAMQP    = l7Protocol{102, "AMQP"}
+	l7ProtocolNATS    = l7Protocol{104, "NATS"}
+	l7ProtocolPulsar  = l7Protocol{105, "Pulsar"}
+)
+
+var dbSystemToL7Protocol = map[string]l7Protocol{
+	"mysql":      l7ProtocolMySQL,
+	"mariadb":    l7ProtocolMySQL,
+	"postgresql": l7ProtocolPostgre,
+	"oracle":     l7ProtocolOracle,
+	"redis":      l7ProtocolRedis,
+	"mongodb":    l7ProtocolMongoDB,
+}
+
+var rpcSystemToL7Protocol = map[string]l7Protocol{
+	"grpc":  l7ProtocolGRPC,
+	"dubbo": l7ProtocolDubbo,
+}
+
+var messagingSystemToL7Protocol = map[string]l7Protocol{
+	"kafka":    l7ProtocolKafka,
+	"rabbitmq": l7ProtocolAMQP,
+	"pulsar":   l7ProtocolPulsar,
+	"nats":     l7ProtocolNATS,
+}
+
+// otelSpanKind converts the case-insensitive span kind name to opentelemetry span kind
+func otelSpanKind(kind string) int {
+	switch strings.ToLower(kind) {
+	case SpanKindClient:
+		return int(v1.Span_SPAN_KIND_CLIENT)
+	case SpanKindServer:
+		return int(v1.Span_SPAN_KIND_SERVER)
+	case SpanKindProducer:
+		return int(v1.Span_SPAN_KIND_PRODUCER)
+	case SpanKindConsumer:
+		return int(v1.Span_SPAN_KIND_CONSUMER)
+	case SpanKindInternal:
+		return int(v1.Span_SPAN_KIND_INTERNAL)
+	default:
+		return int(v1.Span_SPAN_KIND_UNSPECIFIED)
+	}
+}
+
+func spanKindToTapSide(spanKind int) string {
+	switch v1.Span_SpanKind(spanKind) {
+	case v1.Span_SPAN_KIND_CLIENT, v1.Span_SPAN_KIND_PRODUCER:
+		// client-side span
+		return "c-app"
+	case v1.Span_SPAN_KIND_SERVER, v1.Span_SPAN_KIND_CONSUMER:
+		// server-side span
+		return "s-app"
+	default:
+		return "app"
+	}
+}
+
+func firstAttribute(attrs map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := attrs[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// attributesToSpanRequestInfo fills l7 protocol, request and response info of the span by opentelemetry semantic conventions
+func attributesToSpanRequestInfo(attrs map[string]string, span *model.ExSpan) {
+	var protocol l7Protocol
+	if method := firstAttribute(attrs, AttributeHTTPMethod, AttributeHTTPRequestMethod); method != "" {
+		protocol = l7ProtocolHTTP1
+		if version := firstAttribute(attrs, AttributeHTTPFlavor, AttributeNetworkProtocolVersion); strings.HasPrefix(version, "2") {
+			protocol = l7ProtocolHTTP2
+		}
+		span.RequestType = method
+		if resource := firstAttribute(attrs, AttributeURLPath, AttributeHTTPTarget, AttributeHTTPPath, AttributeHTTPRoute, AttributeHTTPURL, AttributeURLFull); resource != "" {
+			span.RequestResource = resource
+		}
+		if code, err := strconv.Atoi(firstAttribute(attrs, AttributeHTTPStatus_Code, AttributeHTTPResponseStatusCode)); err == nil {
+			span.ResponseStatus = code
+		}
+	} else if dbSystem, ok := attrs[AttributeDbSystem]; ok {
+		protocol = dbSystemToL7Protocol[strings.ToLower(dbSystem)]
+		span.RequestType = attrs[AttributeDbOperation]
+		if statement := firstAttribute(attrs, AttributeDbStatement, AttributeSQLQuery); statement != "" {
+			span.RequestResource = statement
+		}
+	} else if statement, ok := attrs[AttributeSQLQuery]; ok {
+		span.RequestResource = statement
+	} else if rpcSystem, ok := attrs[AttributeRPCSystem]; ok {
+		protocol = rpcSystemToL7Protocol[strings.ToLower(rpcSystem)]
+		service, method := attrs[AttributeRPCService], attrs[AttributeRPCMethod]
+		span.RequestType = method
+		if service != "" && method != "" {
+			span.RequestResource = "/" + service + "/" + method
+		}
+	} else if messagingSystem, ok := attrs[AttributeMessagingSystem]; ok {
+		protocol = messagingSystemToL7Protocol[strings.ToLower(messagingSystem)]
+		span.RequestType = attrs[AttributeMessagingOperation]
+		if destination := firstAttribute(attrs, AttributeMessagingDestinationName, AttributeMessagingDestination); destination != "" {
+			span.RequestResource = destination
+		}
+	}
+	if protocol.id != 0 {
+		span.L7Protocol, span.L7ProtocolStr = protocol.id, protocol.name
+	}
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/common"
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/config"
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/model"
+	"github.com/mitchellh/mapstructure"
+	"github.com/op/go-logging"
+)
+
+const (
+	// ref: https://zipkin.io/zipkin-api/#/default/get_trace__traceId_
+	zipkin_query_url = "api/v2/trace"
+
+	// the client half of a shared span uses '<span id>-client' as span id,
+	// so that the server half and its children are linked to it
+	zipkinClientSpanIDSuffix = "-client"
+)
+
+// ref: https://github.com/openzipkin/zipkin-api/blob/master/zipkin2-api.yaml
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int    `json:"port"`
+}
+
+type zipkinSpan struct {
+	TraceID        string            `json:"traceId"`
+	ParentID       string            `json:"parentId"`
+	ID             string            `json:"id"`
+	Kind           string            `json:"kind"` // CLIENT, SERVER, PRODUCER, CONSUMER or empty
+	Name           string            `json:"name"`
+	Timestamp      int64             `json:"timestamp"` // microseconds
+	Duration       int64             `json:"duration"`  // microseconds
+	LocalEndpoint  *zipkinEndpoint   `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint   `json:"remoteEndpoint"`
+	Tags           map[string]string `json:"tags"`
+	Shared         bool              `json:"shared"`
+}
+
+type zipkinConfig struct {
+	Auth string `mapstructure:"auth"` // basic auth
+}
+
+type ZipkinAdapter struct {
+}
+
+var log_zipkin = logging.MustGetLogger("tracing-adapter.zipkin")
+
+func (z *ZipkinAdapter) GetTrace(traceID string, c *config.ExternalAPM) (*model.ExTrace, error) {
+	zipkinConfig := &zipkinConfig{}
+	err := mapstructure.Decode(c.ExtraConfig, zipkinConfig)
+	if err != nil {
+		log_zipkin.Errorf("cannot decode zipkin extra config %v, err: %s", c.ExtraConfig, err)
+		return nil, err
+	}
+	spans, err := z.getTrace(traceID, c, zipkinConfig)
+	if err != nil || spans == nil {
+		return nil, err
+	}
+	return z.zipkinSpansToExTraces(*spans), nil
+}
+
+func (z *ZipkinAdapter) getTrace(traceID string, c *config.ExternalAPM, zipkinConfig *zipkinConfig) (*[]zipkinSpan, error) {
+	scheme := "http"
+	if c.TLS != nil {
+		scheme = "https"
+	}
+	result, err := common.DoRequest(http.MethodGet, fmt.Sprintf("%s://%s/%s/%s", scheme, c.Addr, zipkin_query_url, traceID), nil, appendBasicAuthHeader(zipkinConfig.Auth), c.Timeout, c.TLS)
+	if err != nil || result == nil {
+		log_zipkin.Errorf("query zipkin trace %s at %s failed! err: %s", traceID, c.Addr, err)
+		return nil, err
+	}
+	spans, err := common.Deserialize[[]zipkinSpan](result)
+	if err != nil || spans == nil {
+		log_zipkin.Errorf("deserialize failed! err: %s", err)
+		return nil, err
+	}
+	return spans, nil
+}
+
+func (z *ZipkinAdapter) zipkinSpansToExTraces(spans []zipkinSpan) *model.ExTrace {
+	exTrace := &model.ExTrace{}
+	exTrace.Spans = make([]model.ExSpan, 0, len(spans))
+
+	// in zipkin, the client and server of a RPC may share the same span id (B3 shared span)
+	sharedSpanIDs := make(map[string]bool)
+	for i := range spans {
+		if spans[i].Shared && otelSpanKind(spans[i].Kind) == otelSpanKind(SpanKindServer) {
+			sharedSpanIDs[spans[i].ID] = true
+		}
+	}
+	clientSpanIDs := make(map[string]bool)
+	for i := range spans {
+		if sharedSpanIDs[spans[i].ID] && !spans[i].Shared {
+			clientSpanIDs[spans[i].ID] = true
+		}
+	}
+
+	for i := range spans {
+		zipkinSpan := &spans[i]
+		spanKind := otelSpanKind(zipkinSpan.Kind)
+		spanID, parentSpanID := zipkinSpan.ID, zipkinSpan.ParentID
+		if clientSpanIDs[zipkinSpan.ID] {
+			if zipkinSpan.Shared {
+				parentSpanID = zipkinSpan.ID + zipkinClientSpanIDSuffix
+			} else {
+				spanID = zipkinSpan.ID + zipkinClientSpanIDSuffix
+			}
+		}
+		attrs := z.zipkinTagsToAttributes(zipkinSpan.Tags)
+		serviceName := ""
+		if zipkinSpan.LocalEndpoint != nil {
+			serviceName = zipkinSpan.LocalEndpoint.ServiceName
+		}
+		span := model.ExSpan{
+			Name:            zipkinSpan.Name,
+			ID:              generateUniqueID(zipkinSpan.ID, zipkinSpan.Timestamp, i),
+			StartTimeUs:     zipkinSpan.Timestamp,
+			EndTimeUs:       zipkinSpan.Timestamp + zipkinSpan.Duration,
+			TapSide:         spanKindToTapSide(spanKind),
+			TraceID:         zipkinSpan.TraceID,
+			SpanID:          spanID,
+			ParentSpanID:    parentSpanID,
+			SpanKind:        spanKind,
+			Endpoint:        zipkinSpan.Name,
+			AppService:      serviceName,
+			AppInstance:     z.zipkinEndpointToInstance(zipkinSpan.LocalEndpoint),
+			ServiceUname:    serviceName,
+			RequestResource: zipkinSpan.Name, // maybe overwrite by tags
+			SignalSource:    model.L7_FLOW_SIGNAL_SOURCE_OTEL,
+			Attribute:       attrs,
+		}
+		attributesToSpanRequestInfo(attrs, &span)
+		exTrace.Spans = append(exTrace.Spans, span)
+	}
+	return exTrace
+}
+
+func (z *ZipkinAdapter) zipkinEndpointToInstance(endpoint *zipkinEndpoint) string {
+	if endpoint == nil {
+		return ""
+	}
+	ip := endpoint.IPv4
+	if ip == "" {
+		ip = endpoint.IPv6
+	}
+	if ip == "" || endpoint.Port == 0 {
+		return ip
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(endpoint.Port))
+}
+
+func (z *ZipkinAdapter) zipkinTagsToAttributes(tags map[string]string) map[string]string {
+	attr := make(map[string]string, len(tags))
+	for k, v := range tags {
+		attr[k] = v
+	}
+	return attr
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var zipkin_mock_data = `[
+    {
+        "traceId": "5af7183fb1d4cf5f",
+        "id": "5af7183fb1d4cf5f",
+        "kind": "SERVER",
+        "name": "get /checkout",
+        "timestamp": 1694428678774000,
+        "duration": 207000,
+        "localEndpoint": {"serviceName": "frontend", "ipv4": "10.1.2.3", "port": 8080},
+        "tags": {"http.method": "GET", "http.path": "/checkout", "http.status_code": "503"}
+    },
+    {
+        "traceId": "5af7183fb1d4cf5f",
+        "parentId": "5af7183fb1d4cf5f",
+        "id": "352bff9a74ca9ad2",
+        "kind": "CLIENT",
+        "name": "post /pay",
+        "timestamp": 1694428678780000,
+        "duration": 100000,
+        "localEndpoint": {"serviceName": "frontend", "ipv4": "10.1.2.3"},
+        "remoteEndpoint": {"serviceName": "payment"},
+        "tags": {"http.method": "POST", "http.path": "/pay"}
+    },
+    {
+        "traceId": "5af7183fb1d4cf5f",
+        "parentId": "5af7183fb1d4cf5f",
+        "id": "352bff9a74ca9ad2",
+        "kind": "SERVER",
+        "name": "post /pay",
+        "timestamp": 1694428678782000,
+        "duration": 90000,
+        "localEndpoint": {"serviceName": "payment", "ipv6": "::1", "port": 9000},
+        "shared": true
+    },
+    {
+        "traceId": "5af7183fb1d4cf5f",
+        "parentId": "352bff9a74ca9ad2",
+        "id": "0000000000000003",
+        "name": "sign",
+        "timestamp": 1694428678790000,
+        "duration": 1000,
+        "localEndpoint": {"serviceName": "payment"}
+    }
+]`
+
+func TestGetZipkinTrace(t *testing.T) {
+	zipkinAdapter := &ZipkinAdapter{}
+	Convey("TestGetZipkinTrace_Success", t, func() {
+		spans, err := common.Deserialize[[]zipkinSpan]([]byte(zipkin_mock_data))
+		So(err, ShouldBeNil)
+		result := zipkinAdapter.zipkinSpansToExTraces(*spans)
+		So(result, ShouldNotBeNil)
+		So(len(result.Spans), ShouldEqual, 4)
+
+		ids := make(map[uint64]bool)
+		for i := range result.Spans {
+			So(result.Spans[i].ID, ShouldBeGreaterThan, 0)
+			ids[result.Spans[i].ID] = true
+		}
+		So(len(ids), ShouldEqual, 4)
+
+		root := result.Spans[0]
+		So(root.TapSide, ShouldEqual, "s-app")
+		So(root.AppService, ShouldEqual, "frontend")
+		So(root.AppInstance, ShouldEqual, "10.1.2.3:8080")
+		So(root.RequestType, ShouldEqual, "GET")
+		So(root.RequestResource, ShouldEqual, "/checkout")
+		So(root.ResponseStatus, ShouldEqual, 503)
+		So(root.L7ProtocolStr, ShouldEqual, "HTTP")
+	})
+
+	Convey("TestGetZipkinTrace_Shared_Span", t, func() {
+		spans, err := common.Deserialize[[]zipkinSpan]([]byte(zipkin_mock_data))
+		So(err, ShouldBeNil)
+		result := zipkinAdapter.zipkinSpansToExTraces(*spans)
+
+		client, server, local := result.Spans[1], result.Spans[2], result.Spans[3]
+		So(client.TapSide, ShouldEqual, "c-app")
+		So(client.SpanID, ShouldEqual, "352bff9a74ca9ad2-client")
+		So(client.ParentSpanID, ShouldEqual, "5af7183fb1d4cf5f")
+		So(server.TapSide, ShouldEqual, "s-app")
+		So(server.SpanID, ShouldEqual, "352bff9a74ca9ad2")
+		So(server.ParentSpanID, ShouldEqual, client.SpanID)
+		So(server.AppInstance, ShouldEqual, "[::1]:9000")
+		So(local.TapSide, ShouldEqual, "app")
+		So(local.ParentSpanID, ShouldEqual, server.SpanID)
+	})
+}
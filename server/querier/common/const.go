@@ -46,6 +46,13 @@ const (
 )
 
 const (
-	HEADER_KEY_X_ORG_ID = "X-Org-Id"
-	DEFAULT_ORG_ID      = "1"
+	HEADER_KEY_X_ORG_ID    = "X-Org-Id"
+	HEADER_KEY_X_USER_TYPE = "X-User-Type"
+	HEADER_KEY_X_USER_ID   = "X-User-Id"
+	DEFAULT_ORG_ID         = "1"
+	DEFAULT_USER_TYPE      = "1"
+	DEFAULT_USER_ID        = "1"
+
+	// key in gin context of team ids which the caller is not authorized to
+	CONTEXT_KEY_BLOCK_TEAM_ID = "block_team_id"
 )
@@ -33,6 +33,20 @@ type QuerierParams struct {
 	Context       context.Context
 	NoPreWhere    bool
 	ORGID         string
+	// team_id of these teams are filtered out in all tables which have team_id
+	BlockTeamID []string
+}
+
+// PcapParams selects the stored packets of flows captured by an agent, time range is in seconds
+type PcapParams struct {
+	FlowIDs     []uint64 `json:"flow_ids" binding:"required"`
+	AgentID     uint16   `json:"agent_id" binding:"required"`
+	TimeStart   int64    `json:"time_start" binding:"required"`
+	TimeEnd     int64    `json:"time_end" binding:"required"`
+	Debug       bool     `json:"debug"`
+	Context     context.Context
+	ORGID       string
+	BlockTeamID []string
 }
 
 type TempoParams struct {
@@ -45,7 +59,12 @@ type TempoParams struct {
 	Limit       string
 	Debug       string
 	Filters     []*KeyValue
+	Query       string // TraceQL
 	Context     context.Context
+	ORGID       string
+	UserType    string
+	UserID      string
+	BlockTeamID []string
 }
 
 func (p *TempoParams) SetFilters(filterStr string) {
@@ -17,6 +17,7 @@
 package config
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"reflect"
@@ -26,6 +27,7 @@ import (
 	"github.com/op/go-logging"
 	"gopkg.in/yaml.v2"
 
+	controller_common "github.com/deepflowio/deepflow/server/controller/common"
 	prometheus "github.com/deepflowio/deepflow/server/querier/app/prometheus/config"
 	tracing_adapter "github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/config"
 	profile "github.com/deepflowio/deepflow/server/querier/profile/config"
@@ -40,6 +42,7 @@ type Config struct {
 	QuerierConfig    QuerierConfig    `yaml:"querier"`
 	TraceIdWithIndex TraceIdWithIndex `yaml:"trace-id-with-index"`
 	ControllerConfig ControllerConfig `yaml:"controller"`
+	IngesterConfig   IngesterConfig   `yaml:"ingester"`
 }
 
 type QuerierConfig struct {
@@ -49,6 +52,7 @@ type QuerierConfig struct {
 	Clickhouse                      Clickhouse                    `yaml:clickhouse`
 	Profile                         profile.ProfileConfig         `yaml:profile`
 	DeepflowApp                     DeepflowApp                   `yaml:"deepflow-app"`
+	L7Tracing                       L7Tracing                     `yaml:"l7-tracing"`
 	Prometheus                      prometheus.Prometheus         `yaml:"prometheus"`
 	ExternalAPM                     []tracing_adapter.ExternalAPM `yaml:"external-apm"`
 	Language                        string                        `default:"en" yaml:"language"`
@@ -67,6 +71,16 @@ type DeepflowApp struct {
 	Port string `default:"20418" yaml:"port"`
 }
 
+// L7Tracing is the config of assembling traces from l7_flow_log in querier,
+// deepflow-app is requested for traces if native is false.
+type L7Tracing struct {
+	Native            bool `default:"false" yaml:"native"`
+	MaxIteration      int  `default:"30" yaml:"max-iteration"`
+	QueryLimit        int  `default:"1000" yaml:"query-limit"`
+	HostClockOffsetUs int  `default:"10000" yaml:"host-clock-offset-us"`
+	NetworkDelayUs    int  `default:"50000" yaml:"network-delay-us"`
+}
+
 type Location struct {
 	Start  int    `yaml:"start"`
 	Length int    `yaml:"length"`
@@ -96,7 +110,16 @@ type AutoCustomTags struct {
 }
 
 type ControllerConfig struct {
-	ListenPort int `default:"20417" yaml:"listen-port"`
+	ListenPort int                       `default:"20417" yaml:"listen-port"`
+	FPermit    controller_common.FPermit `yaml:"fpermit"`
+}
+
+type IngesterConfig struct {
+	ReceiverTLS IngesterReceiverTLS `yaml:"receiver-tls"`
+}
+
+type IngesterReceiverTLS struct {
+	Enabled bool `default:"false" yaml:"enabled"`
 }
 
 func (c *Config) expendEnv() {
@@ -119,6 +142,17 @@ func (c *Config) expendEnv() {
 }
 
 func (c *Config) Validate() error {
+	rule := &c.QuerierConfig.Prometheus.Rule
+	if !rule.Enabled {
+		return nil
+	}
+	// the ingester in the same server only accepts TLS connections
+	if c.IngesterConfig.ReceiverTLS.Enabled && !rule.IngesterTLS.Enabled {
+		return errors.New("'ingester-tls' of querier prometheus rule should be enabled when 'receiver-tls' of ingester is enabled")
+	}
+	if rule.IngesterTLS.Enabled && (rule.IngesterTLS.CertFile == "") != (rule.IngesterTLS.KeyFile == "") {
+		return errors.New("'cert-file' and 'key-file' of querier prometheus rule 'ingester-tls' should be set together")
+	}
 	return nil
 }
 
@@ -83,6 +83,7 @@ type CHEngine struct {
 	IsDerivative       bool
 	DerivativeGroupBy  []string
 	ORGID              string
+	BlockTeamID        []string
 }
 
 func (e *CHEngine) ExecuteQuery(args *common.QuerierParams) (*common.Result, map[string]interface{}, error) {
@@ -97,6 +98,7 @@ func (e *CHEngine) ExecuteQuery(args *common.QuerierParams) (*common.Result, map
 	if args.ORGID != "" {
 		e.ORGID = args.ORGID
 	}
+	e.BlockTeamID = args.BlockTeamID
 	query_uuid := args.QueryUUID // FIXME: should be queryUUID
 	log.Debugf("query_uuid: %s | raw sql: %s", query_uuid, sql)
 
@@ -149,7 +151,7 @@ func (e *CHEngine) ExecuteQuery(args *common.QuerierParams) (*common.Result, map
 			ColumnSchemaMap[ColumnSchema.Name] = ColumnSchema
 		}
 		for _, showSql := range sqlList {
-			showEngine := &CHEngine{DB: e.DB, DataSource: e.DataSource, Context: e.Context, ORGID: e.ORGID}
+			showEngine := &CHEngine{DB: e.DB, DataSource: e.DataSource, Context: e.Context, ORGID: e.ORGID, BlockTeamID: e.BlockTeamID}
 			showEngine.Init()
 			showParser := parse.Parser{Engine: showEngine}
 			err = showParser.ParseSQL(showSql)
@@ -353,7 +355,7 @@ func (e *CHEngine) ParseShowSql(sql string, args *common.QuerierParams) (*common
 			}
 
 			// tag metrics
-			tagDescriptions, err := tag.GetTagDescriptions(e.DB, table, sql, "", e.ORGID, true, e.Context)
+			tagDescriptions, err := tag.GetTagDescriptions(e.DB, table, sql, "", e.ORGID, e.BlockTeamID, true, e.Context)
 			if err != nil {
 				log.Error("Failed to get tag type metrics")
 				return nil, []string{}, true, err
@@ -373,7 +375,7 @@ func (e *CHEngine) ParseShowSql(sql string, args *common.QuerierParams) (*common
 		}
 		return nil, []string{}, true, fmt.Errorf("parse show sql error, sql: '%s' not support", sql)
 	case "tags":
-		data, err := tagdescription.GetTagDescriptions(e.DB, table, sql, args.QueryCacheTTL, args.ORGID, args.UseQueryCache, e.Context)
+		data, err := tagdescription.GetTagDescriptions(e.DB, table, sql, args.QueryCacheTTL, args.ORGID, e.BlockTeamID, args.UseQueryCache, e.Context)
 		return data, []string{}, true, err
 	case "tables":
 		return GetTables(e.DB, args.QueryCacheTTL, args.ORGID, args.UseQueryCache, e.Context), []string{}, true, nil
@@ -665,7 +667,7 @@ func (e *CHEngine) ParseSlimitSql(sql string, args *common.QuerierParams) (strin
 				}
 			}
 		}
-		innerEngine := &CHEngine{DB: e.DB, DataSource: e.DataSource, Context: e.Context, ORGID: e.ORGID}
+		innerEngine := &CHEngine{DB: e.DB, DataSource: e.DataSource, Context: e.Context, ORGID: e.ORGID, BlockTeamID: e.BlockTeamID}
 		innerEngine.Init()
 		if strings.Contains(innerSql, "Derivative") {
 			innerEngine.IsDerivative = true
@@ -685,7 +687,7 @@ func (e *CHEngine) ParseSlimitSql(sql string, args *common.QuerierParams) (strin
 		innerEngine.View = view.NewView(innerEngine.Model)
 		innerTransSql = innerEngine.ToSQLString()
 	}
-	outerEngine := &CHEngine{DB: e.DB, DataSource: e.DataSource, Context: e.Context, ORGID: e.ORGID}
+	outerEngine := &CHEngine{DB: e.DB, DataSource: e.DataSource, Context: e.Context, ORGID: e.ORGID, BlockTeamID: e.BlockTeamID}
 	outerEngine.Init()
 	if strings.Contains(newSql, "Derivative") {
 		outerEngine.IsDerivative = true
@@ -798,7 +800,7 @@ func (e *CHEngine) ParseWithSql(sql string) (string, map[string]func(*common.Res
 	for _, match := range subMatches {
 		match = strings.TrimPrefix(match, "(")
 		match = strings.TrimSuffix(match, ")")
-		matchEngine := &CHEngine{DB: e.DB, DataSource: e.DataSource, Context: e.Context, ORGID: e.ORGID}
+		matchEngine := &CHEngine{DB: e.DB, DataSource: e.DataSource, Context: e.Context, ORGID: e.ORGID, BlockTeamID: e.BlockTeamID}
 		matchEngine.Init()
 		matchParser := parse.Parser{Engine: matchEngine}
 		err := matchParser.ParseSQL(match)
@@ -1127,6 +1129,13 @@ func (e *CHEngine) TransFrom(froms sqlparser.TableExprs) error {
 				whereStmt.filter = &filter
 				e.Statements = append(e.Statements, &whereStmt)
 			}
+			teamIDFilter, ok := GetTeamIDFilter(e.DB, e.Table, e.BlockTeamID)
+			if ok {
+				whereStmt := Where{}
+				filter := view.Filters{Expr: teamIDFilter}
+				whereStmt.filter = &filter
+				e.Statements = append(e.Statements, &whereStmt)
+			}
 		}
 	}
 	return nil
@@ -34,6 +34,7 @@ import (
 	"github.com/deepflowio/deepflow/server/querier/config"
 	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/client"
 	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/metrics"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/tag"
 	"github.com/deepflowio/deepflow/server/querier/parse"
 )
 
@@ -53,6 +54,7 @@ var (
 		db         string
 		datasource string
 		wantErr    string
+		teamIDs    []string
 	}{{
 		input:  "select byte from l4_flow_log limit 1",
 		output: "SELECT byte_tx+byte_rx AS `byte` FROM flow_log.`l4_flow_log` LIMIT 1",
@@ -535,6 +537,23 @@ var (
 		db:     "application_log",
 		input:  "SELECT user, user_id FROM log WHERE body!='log' LIMIT 1",
 		output: "SELECT dictGet(flow_tag.user_map, 'name', (toUInt64(user_id))) AS `user`, user_id FROM application_log.`log` PREWHERE NOT (hasToken(body,'log')) LIMIT 1",
+	}, {
+		name:    "test_block_team_id",
+		input:   "select byte from l4_flow_log limit 1",
+		output:  "SELECT byte_tx+byte_rx AS `byte` FROM flow_log.`l4_flow_log` PREWHERE (team_id NOT IN (2,3)) LIMIT 1",
+		teamIDs: []string{"2", "3"},
+	}, {
+		name:    "test_block_team_id_virtual_table",
+		db:      "ext_metrics",
+		input:   "select `metrics.xxx` as xxx from cpu",
+		output:  "SELECT if(indexOf(metrics_float_names, 'xxx')=0,null,metrics_float_values[indexOf(metrics_float_names, 'xxx')]) AS `xxx` FROM ext_metrics.`metrics` PREWHERE (virtual_table_name='cpu') AND (team_id NOT IN (2)) LIMIT 10000",
+		teamIDs: []string{"2"},
+	}, {
+		name:    "test_block_team_id_flow_tag",
+		db:      "flow_metrics",
+		input:   "SHOW tag chost_ip values from network",
+		output:  "SELECT id AS `value`, ip AS `display_name` FROM flow_tag.`chost_map` WHERE not(display_name = '') GROUP BY `value`, `display_name` ORDER BY `value` asc LIMIT 10000",
+		teamIDs: []string{"2"},
 	}}
 )
 
@@ -558,7 +577,7 @@ func TestGetSql(t *testing.T) {
 		if db == "" {
 			db = "flow_log"
 		}
-		e := CHEngine{DB: db}
+		e := CHEngine{DB: db, BlockTeamID: pcase.teamIDs}
 		if pcase.datasource != "" {
 			e.DataSource = pcase.datasource
 		}
@@ -601,6 +620,57 @@ func TestGetSql(t *testing.T) {
 	}
 }
 
+func TestTeamIDFilterOfCustomField(t *testing.T) {
+	var c *client.Client
+	var querySqls []string
+	monkey.PatchInstanceMethod(reflect.TypeOf(c), "DoQuery", func(_ *client.Client, params *client.QueryParams) (*common.Result, error) {
+		querySqls = append(querySqls, params.Sql)
+		return &common.Result{}, nil
+	})
+	Load()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockDatasources()
+
+	for _, teamIDs := range [][]string{nil, {"2"}} {
+		e := CHEngine{DB: "ext_metrics", BlockTeamID: teamIDs, Context: context.Background()}
+		e.Init()
+		_, sqlList, _, err := e.ParseShowSql("SHOW tag `tag.host` values from cpu", &common.QuerierParams{})
+		if err != nil || len(sqlList) == 0 {
+			t.Fatalf("parse show sql failed: %v", err)
+		}
+		parser := parse.Parser{Engine: &e}
+		if err := parser.ParseSQL(sqlList[0]); err != nil {
+			t.Fatalf("parse %s failed: %v", sqlList[0], err)
+		}
+		out := parser.Engine.ToSQLString()
+		if !strings.Contains(out, "flow_tag.`ext_metrics_custom_field_value`") {
+			t.Errorf("values of custom field should be queried from custom_field_value, get %s", out)
+		}
+		if blocked := strings.Contains(out, "team_id NOT IN (2)"); blocked != (len(teamIDs) > 0) {
+			t.Errorf("values of blocked teams should be filtered out, block teams %v, get %s", teamIDs, out)
+		}
+	}
+
+	querySqls = nil
+	if _, err := tag.GetTagDescriptions("ext_metrics", "cpu", "show tags from cpu", "", "1", []string{"2"}, false, context.Background()); err != nil {
+		t.Fatalf("get tag descriptions failed: %v", err)
+	}
+	customFieldQueried := false
+	for _, sql := range querySqls {
+		if !strings.Contains(sql, "ext_metrics_custom_field") {
+			continue
+		}
+		customFieldQueried = true
+		if !strings.Contains(sql, "team_id NOT IN (2)") {
+			t.Errorf("custom tags of blocked teams should be filtered out, get %s", sql)
+		}
+	}
+	if !customFieldQueried {
+		t.Errorf("custom tags are not queried, sqls %v", querySqls)
+	}
+}
+
 /* func TestGetSqltest(t *testing.T) {
 	for _, pcase := range parsetest {
 		e := CHEngine{DB: "flow_log"}
@@ -39,6 +39,9 @@ import (
 
 var log = logging.MustGetLogger("clickhouse.client")
 
+// the query fails if the callback of packet_batch fails
+const PACKET_BATCH_COLUMN = "packet_batch"
+
 type QueryParams struct {
 	Sql             string
 	UseQueryCache   bool
@@ -204,10 +207,14 @@ func (c *Client) DoQuery(params *QueryParams) (result *common.Result, err error)
 		Values:  values,
 		Schemas: columnSchemas,
 	}
-	for _, callback := range callbacks {
-		err := callback(result)
-		if err != nil {
-			log.Error("Execute Callback %v Error: %v", callback, err)
+	for column, callback := range callbacks {
+		if err := callback(result); err != nil {
+			log.Errorf("execute callback of column %s error: %s, query_uuid: %s", column, err, c.Debug.QueryUUID)
+			// errors of other callbacks only affect the display, but a packet_batch not decoded is useless
+			if column == PACKET_BATCH_COLUMN {
+				c.Debug.Error = fmt.Sprintf("%s", err)
+				return nil, err
+			}
 		}
 	}
 	log.Debugf("sql: %s, query_uuid: %s", sqlstr, c.Debug.QueryUUID)
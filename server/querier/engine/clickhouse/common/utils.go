@@ -97,6 +97,19 @@ func IPFilterStringToHex(ip string) string {
 	}
 }
 
+// custom field tables of flow_tag (<db>_custom_field and <db>_custom_field_value) are written with team_id
+func IsCustomFieldTable(table string) bool {
+	return strings.HasSuffix(table, "_custom_field") || strings.HasSuffix(table, "_custom_field_value")
+}
+
+// GetTeamIDFilterSql returns the condition filtering out data of unauthorized teams, empty if no team is blocked
+func GetTeamIDFilterSql(blockTeamID []string) string {
+	if len(blockTeamID) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("team_id NOT IN (%s)", strings.Join(blockTeamID, ","))
+}
+
 func ParseResponse(response *http.Response) (map[string]interface{}, error) {
 	var result map[string]interface{}
 	body, err := ioutil.ReadAll(response.Body)
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/deepflowio/deepflow/server/querier/common"
+	chCommon "github.com/deepflowio/deepflow/server/querier/engine/clickhouse/common"
 	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/trans_prometheus"
 	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/view"
 )
@@ -40,6 +41,18 @@ func GetVirtualTableFilter(db, table string) (view.Node, bool) {
 	return nil, false
 }
 
+// data of unauthorized teams is filtered out in all tables which have team_id, including data tables
+// and custom field tables of flow_tag, other flow_tag tables are dictionaries of resources without team_id
+func GetTeamIDFilter(db, table string, blockTeamID []string) (view.Node, bool) {
+	if len(blockTeamID) == 0 {
+		return nil, false
+	}
+	if _, ok := chCommon.DB_TABLE_MAP[db]; !ok && !(db == chCommon.DB_NAME_FLOW_TAG && chCommon.IsCustomFieldTable(table)) {
+		return nil, false
+	}
+	return &view.Expr{Value: "(" + chCommon.GetTeamIDFilterSql(blockTeamID) + ")"}, true
+}
+
 func GetMetricIDFilter(e *CHEngine) (view.Node, error) {
 	table := e.Table
 	metricID, ok := trans_prometheus.ORGPrometheus[e.ORGID].MetricNameToID[table]
@@ -50,6 +50,7 @@ const (
 	TAG_FUNCTION_ENUM                       = "enum"
 	TAG_FUNCTION_FAST_FILTER                = "FastFilter"
 	TAG_FUNCTION_FAST_TRANS                 = "FastTrans"
+	TAG_FUNCTION_TO_FLOAT64_OR_NULL         = "toFloat64OrNull"
 )
 
 const INTERVAL_1D = 86400
@@ -59,6 +60,7 @@ var TAG_FUNCTIONS = []string{
 	TAG_FUNCTION_TO_UNIX_TIMESTAMP_64_MICRO, TAG_FUNCTION_TO_STRING, TAG_FUNCTION_IF,
 	TAG_FUNCTION_UNIQ, TAG_FUNCTION_ANY, TAG_FUNCTION_TOPK, TAG_FUNCTION_TO_UNIX_TIMESTAMP,
 	TAG_FUNCTION_NEW_TAG, TAG_FUNCTION_ENUM, TAG_FUNCTION_FAST_FILTER, TAG_FUNCTION_FAST_TRANS,
+	TAG_FUNCTION_TO_FLOAT64_OR_NULL,
 }
 
 type Function interface {
@@ -891,6 +893,17 @@ func (f *TagFunction) Trans(m *view.Model) view.Node {
 		} else {
 			tagField = tagDes.TagTranslator
 		}
+		// single attribute of l7_flow_log or tag of ext_metrics, as toFloat64OrNull(`attribute.xxx`)
+		name := strings.Trim(field, "`")
+		if tagField == "" && f.DB != chCommon.DB_NAME_PROMETHEUS && (strings.HasPrefix(name, "tag.") || strings.HasPrefix(name, "attribute.")) {
+			prefix := "attribute."
+			if strings.HasPrefix(name, "tag.") {
+				prefix = "tag."
+			}
+			if tagDes, ok = tag.GetTag(prefix, f.DB, f.Table, "default"); ok {
+				tagField = fmt.Sprintf(tagDes.TagTranslator, strings.TrimPrefix(name, prefix))
+			}
+		}
 		if tagField == "" {
 			tagField = field
 		}
@@ -82,7 +82,37 @@ func GetExtMetrics(db, table, where, queryCacheTTL, orgID string, useQueryCache
 func GetPrometheusMetrics(db, table, where, queryCacheTTL, orgID string, useQueryCache bool, ctx context.Context) (map[string]*Metrics, error) {
 	loadMetrics := make(map[string]*Metrics)
 	allMetrics := GetSamplesMetrics()
-	var err error
+	if config.Cfg == nil {
+		return nil, nil
+	}
+	tableNames, err := GetPrometheusTables(db, table, where, queryCacheTTL, orgID, useQueryCache, ctx)
+	if err != nil {
+		return nil, err
+	}
+	index := 0
+	for field, metric := range allMetrics {
+		metricType := METRICS_TYPE_COUNTER
+		isAgg := false
+		if field == COUNT_METRICS_NAME {
+			metricType = METRICS_TYPE_OTHER
+			isAgg = true
+		}
+		for _, tableName := range tableNames {
+			lm := NewMetrics(
+				index, metric.DBField, metric.DisplayName, "", metricType,
+				"metrics", []bool{true, true, true}, "", tableName, "", "",
+			)
+			lm.IsAgg = isAgg
+			loadMetrics[strings.Join([]string{field, strconv.Itoa(index)}, "-")] = lm
+			index++
+		}
+	}
+	return loadMetrics, err
+}
+
+// GetPrometheusTables returns names of the prometheus metrics in flow_tag.<db>_custom_field filtered by where
+func GetPrometheusTables(db, table, where, queryCacheTTL, orgID string, useQueryCache bool, ctx context.Context) ([]string, error) {
+	// Avoid UT failures
 	if config.Cfg == nil {
 		return nil, nil
 	}
@@ -94,10 +124,9 @@ func GetPrometheusMetrics(db, table, where, queryCacheTTL, orgID string, useQuer
 		DB:       "flow_tag",
 		Context:  ctx,
 	}
-	var prometheusTableSql string
 	var tableFilter string
 	var whereSql string
-	prometheusTableSql = "SELECT table FROM flow_tag.%s_custom_field WHERE %s field_type!='' %s GROUP BY table ORDER BY table ASC"
+	prometheusTableSql := "SELECT table FROM flow_tag.%s_custom_field WHERE %s field_type!='' %s GROUP BY table ORDER BY table ASC"
 	if table != "" {
 		tableFilter = fmt.Sprintf("table='%s' AND", table)
 	}
@@ -111,27 +140,13 @@ func GetPrometheusMetrics(db, table, where, queryCacheTTL, orgID string, useQuer
 		log.Error(err)
 		return nil, err
 	}
-	index := 0
-	for field, metric := range allMetrics {
-		metricType := METRICS_TYPE_COUNTER
-		isAgg := false
-		if field == COUNT_METRICS_NAME {
-			metricType = METRICS_TYPE_OTHER
-			isAgg = true
-		}
-		for _, value := range prometheusTableRst.Values {
-			tableName := value.([]interface{})[0].(string)
-			if tableName == "" {
-				continue
-			}
-			lm := NewMetrics(
-				index, metric.DBField, metric.DisplayName, "", metricType,
-				"metrics", []bool{true, true, true}, "", tableName, "", "",
-			)
-			lm.IsAgg = isAgg
-			loadMetrics[strings.Join([]string{field, strconv.Itoa(index)}, "-")] = lm
-			index++
+	tableNames := make([]string, 0, len(prometheusTableRst.Values))
+	for _, value := range prometheusTableRst.Values {
+		tableName := value.([]interface{})[0].(string)
+		if tableName == "" {
+			continue
 		}
+		tableNames = append(tableNames, tableName)
 	}
-	return loadMetrics, err
+	return tableNames, nil
 }
@@ -238,7 +238,7 @@ func GetMetrics(field, db, table, orgID string) (*Metrics, bool) {
 	}
 
 	// tag metrics
-	tagDescriptions, err := tag.GetTagDescriptions(db, table, "", "", orgID, true, context.Background())
+	tagDescriptions, err := tag.GetTagDescriptions(db, table, "", "", orgID, nil, true, context.Background())
 	if err != nil {
 		log.Error("Failed to get tag type metrics")
 		return nil, false
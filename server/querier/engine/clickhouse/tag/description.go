@@ -607,7 +607,7 @@ func LoadTagDescriptions(tagData map[string]interface{}) error {
 	return nil
 }
 
-func GetTagDescriptions(db, table, rawSql, queryCacheTTL, orgID string, useQueryCache bool, ctx context.Context) (response *common.Result, err error) {
+func GetTagDescriptions(db, table, rawSql, queryCacheTTL, orgID string, blockTeamID []string, useQueryCache bool, ctx context.Context) (response *common.Result, err error) {
 	// 把`1m`的反引号去掉
 	table = strings.Trim(table, "`")
 	response = &common.Result{
@@ -797,6 +797,13 @@ func GetTagDescriptions(db, table, rawSql, queryCacheTTL, orgID string, useQuery
 	if strings.Contains(rawSql, "WHERE") {
 		whereSql = strings.Split(rawSql, "WHERE")[1]
 	}
+	if teamIDFilter := ckcommon.GetTeamIDFilterSql(blockTeamID); teamIDFilter != "" {
+		if whereSql != "" {
+			whereSql = fmt.Sprintf("(%s) AND %s", whereSql, teamIDFilter)
+		} else {
+			whereSql = teamIDFilter
+		}
+	}
 	externalSql := ""
 	if whereSql != "" {
 		externalSql = fmt.Sprintf("SELECT field_name AS tag_name FROM flow_tag.%s_custom_field WHERE table='%s' AND field_type='tag' AND (%s) GROUP BY tag_name ORDER BY tag_name ASC", db, table, whereSql)
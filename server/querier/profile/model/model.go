@@ -29,6 +29,7 @@ type ProfileTracing struct {
 	Debug               bool   `json:"debug"`
 	Context             context.Context
 	OrgID               string
+	BlockTeamID         []string
 	MaxKernelStackDepth *int `json:"max_kernel_stack_depth"` // default: -1
 }
 
@@ -40,6 +41,25 @@ type ProfileTreeNode struct {
 	TotalValue         int    `json:"total_value"`
 }
 
+// ProfileDiff compares the profile of comparison time range or tag filter with the baseline one,
+// comparison uses the same time range and tag filter as baseline if not specified
+type ProfileDiff struct {
+	ProfileTracing
+	ComparisonTimeStart int    `json:"comparison_time_start"`
+	ComparisonTimeEnd   int    `json:"comparison_time_end"`
+	ComparisonTagFilter string `json:"comparison_tag_filter"`
+}
+
+type ProfileDiffTreeNode struct {
+	ProfileLocationStr   string `json:"profile_location_str"`
+	NodeID               string `json:"node_id"`
+	ParentNodeID         string `json:"parent_node_id"`
+	BaselineSelfValue    int    `json:"baseline_self_value"`
+	BaselineTotalValue   int    `json:"baseline_total_value"`
+	ComparisonSelfValue  int    `json:"comparison_self_value"`
+	ComparisonTotalValue int    `json:"comparison_total_value"`
+}
+
 type Debug struct {
 	IP        string `json:"ip"`
 	Sql       string `json:"sql"`
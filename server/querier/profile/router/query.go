@@ -17,9 +17,12 @@
 package router
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 
+	querier_common "github.com/deepflowio/deepflow/server/querier/common"
 	"github.com/deepflowio/deepflow/server/querier/config"
 	"github.com/deepflowio/deepflow/server/querier/profile/common"
 	"github.com/deepflowio/deepflow/server/querier/profile/model"
@@ -29,6 +32,8 @@ import (
 
 func ProfileRouter(e *gin.Engine, cfg *config.QuerierConfig) {
 	e.POST("/v1/profile/ProfileTracing", profileTracing(cfg))
+	e.POST("/v1/profile/ProfileDiff", profileDiff(cfg))
+	e.POST("/v1/profile/ProfilePprof", profilePprof(cfg))
 
 }
 
@@ -42,12 +47,7 @@ func profileTracing(cfg *config.QuerierConfig) gin.HandlerFunc {
 			router.BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
 			return
 		}
-		profileTracing.Context = c.Request.Context()
-		profileTracing.OrgID = c.Request.Header.Get(common.HEADER_KEY_X_ORG_ID)
-		if profileTracing.MaxKernelStackDepth == nil {
-			var maxKernelStackDepth = common.MAX_KERNEL_STACK_DEPTH_DEFAULT
-			profileTracing.MaxKernelStackDepth = &maxKernelStackDepth
-		}
+		setProfileTracingArgs(c, &profileTracing)
 		result, debug, err := service.Tracing(profileTracing, cfg)
 		if err == nil && !profileTracing.Debug {
 			debug = nil
@@ -55,3 +55,61 @@ func profileTracing(cfg *config.QuerierConfig) gin.HandlerFunc {
 		router.JsonResponse(c, result, debug, err)
 	})
 }
+
+func profileDiff(cfg *config.QuerierConfig) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var profileDiff model.ProfileDiff
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&profileDiff, binding.JSON)
+		if err != nil {
+			router.BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+		if profileDiff.ComparisonTimeStart == 0 && profileDiff.ComparisonTimeEnd == 0 && profileDiff.ComparisonTagFilter == "" {
+			router.BadRequestResponse(c, common.INVALID_POST_DATA, "comparison_time_start/comparison_time_end or comparison_tag_filter is required")
+			return
+		}
+		if (profileDiff.ComparisonTimeStart != 0 || profileDiff.ComparisonTimeEnd != 0) && profileDiff.ComparisonTimeStart >= profileDiff.ComparisonTimeEnd {
+			router.BadRequestResponse(c, common.INVALID_POST_DATA, "comparison_time_start should be less than comparison_time_end")
+			return
+		}
+		setProfileTracingArgs(c, &profileDiff.ProfileTracing)
+		result, debug, err := service.Diff(profileDiff, cfg)
+		if err == nil && !profileDiff.Debug {
+			debug = nil
+		}
+		router.JsonResponse(c, result, debug, err)
+	})
+}
+
+func profilePprof(cfg *config.QuerierConfig) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var profileTracing model.ProfileTracing
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&profileTracing, binding.JSON)
+		if err != nil {
+			router.BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+		setProfileTracingArgs(c, &profileTracing)
+		result, debug, err := service.Pprof(profileTracing, cfg)
+		if err != nil {
+			router.JsonResponse(c, nil, debug, err)
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename=profile.pb.gz")
+		c.Data(http.StatusOK, "application/octet-stream", result)
+	})
+}
+
+func setProfileTracingArgs(c *gin.Context, args *model.ProfileTracing) {
+	args.Context = c.Request.Context()
+	args.OrgID = c.Request.Header.Get(common.HEADER_KEY_X_ORG_ID)
+	args.BlockTeamID = c.GetStringSlice(querier_common.CONTEXT_KEY_BLOCK_TEAM_ID)
+	if args.MaxKernelStackDepth == nil {
+		var maxKernelStackDepth = common.MAX_KERNEL_STACK_DEPTH_DEFAULT
+		args.MaxKernelStackDepth = &maxKernelStackDepth
+	}
+}
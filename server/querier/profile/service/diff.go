@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/profile/model"
+)
+
+func Diff(args model.ProfileDiff, cfg *config.QuerierConfig) (result []*model.ProfileDiffTreeNode, debug interface{}, err error) {
+	comparisonArgs := args.ProfileTracing
+	if args.ComparisonTimeStart != 0 || args.ComparisonTimeEnd != 0 {
+		comparisonArgs.TimeStart, comparisonArgs.TimeEnd = args.ComparisonTimeStart, args.ComparisonTimeEnd
+	}
+	if args.ComparisonTagFilter != "" {
+		comparisonArgs.TagFilter = args.ComparisonTagFilter
+	}
+
+	debugs := model.ProfileDebug{}
+	baselineStackMap, _, err := queryProfileStacks(args.ProfileTracing, cfg, &debugs)
+	if err != nil {
+		return
+	}
+	comparisonStackMap, _, err := queryProfileStacks(comparisonArgs, cfg, &debugs)
+	if err != nil {
+		return
+	}
+
+	formatStartTime := time.Now()
+	result = NewProfileDiffTree(NewProfileTree(args.AppService, baselineStackMap), NewProfileTree(args.AppService, comparisonStackMap))
+	formatEndTime := int64(time.Since(formatStartTime))
+	formatTime := fmt.Sprintf("%.9fs", float64(formatEndTime)/1e9)
+	debugs.FormatTime = formatTime
+	debug = debugs
+	return
+}
+
+// NewProfileDiffTree merges the baseline and comparison profile trees by node id,
+// which is generated from the function stack, so nodes of the same stack in the two trees are merged.
+func NewProfileDiffTree(baseline, comparison []*model.ProfileTreeNode) []*model.ProfileDiffTreeNode {
+	result := make([]*model.ProfileDiffTreeNode, 0, len(baseline))
+	NodeIDToDiffNode := make(map[string]*model.ProfileDiffTreeNode, len(baseline))
+	getDiffNode := func(node *model.ProfileTreeNode) *model.ProfileDiffTreeNode {
+		if diffNode, ok := NodeIDToDiffNode[node.NodeID]; ok {
+			return diffNode
+		}
+		diffNode := &model.ProfileDiffTreeNode{
+			ProfileLocationStr: node.ProfileLocationStr,
+			NodeID:             node.NodeID,
+			ParentNodeID:       node.ParentNodeID,
+		}
+		NodeIDToDiffNode[node.NodeID] = diffNode
+		result = append(result, diffNode)
+		return diffNode
+	}
+	for _, node := range baseline {
+		diffNode := getDiffNode(node)
+		diffNode.BaselineSelfValue = node.SelfValue
+		diffNode.BaselineTotalValue = node.TotalValue
+	}
+	for _, node := range comparison {
+		diffNode := getDiffNode(node)
+		diffNode.ComparisonSelfValue = node.SelfValue
+		diffNode.ComparisonTotalValue = node.TotalValue
+	}
+	return result
+}
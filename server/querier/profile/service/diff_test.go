@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pyroscope-io/pyroscope/pkg/storage/tree"
+
+	"github.com/deepflowio/deepflow/server/querier/profile/model"
+)
+
+func TestNewProfileDiffTree(t *testing.T) {
+	baseline := NewProfileTree("app", map[string]int{"main;foo": 3, "main;bar": 1})
+	comparison := NewProfileTree("app", map[string]int{"main;foo": 1, "main;baz": 2})
+	result := NewProfileDiffTree(baseline, comparison)
+
+	// [baseline self, baseline total, comparison self, comparison total]
+	want := map[string][4]int{
+		"app":  {0, 4, 0, 3},
+		"main": {0, 4, 0, 3},
+		"foo":  {3, 3, 1, 1},
+		"bar":  {1, 1, 0, 0},
+		"baz":  {0, 0, 2, 2},
+	}
+	got := map[string][4]int{}
+	for _, node := range result {
+		got[node.ProfileLocationStr] = [4]int{node.BaselineSelfValue, node.BaselineTotalValue, node.ComparisonSelfValue, node.ComparisonTotalValue}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if result[0].NodeID != "" || result[0].ParentNodeID != "-1" {
+		t.Errorf("the first node should be root, got %+v", result[0])
+	}
+}
+
+func TestNewPprof(t *testing.T) {
+	args := model.ProfileTracing{ProfileEventType: "on-cpu", TimeStart: 100, TimeEnd: 160}
+	data, err := NewPprof(args, map[string]int{"main;foo;bar": 3, "main;baz": 2, "": 1}, "ns")
+	if err != nil {
+		t.Fatalf("encode pprof failed: %s", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("pprof is not gzipped: %s", err)
+	}
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("decompress pprof failed: %s", err)
+	}
+	profile := &tree.Profile{}
+	if err := profile.UnmarshalVT(raw); err != nil {
+		t.Fatalf("decode pprof failed: %s", err)
+	}
+
+	if profile.TimeNanos != 100e9 || profile.DurationNanos != 60e9 {
+		t.Errorf("got time %d, duration %d", profile.TimeNanos, profile.DurationNanos)
+	}
+	sampleType := profile.SampleType[0]
+	if profile.StringTable[sampleType.Type] != "on-cpu" || profile.StringTable[sampleType.Unit] != "ns" {
+		t.Errorf("got sample type %s, unit %s", profile.StringTable[sampleType.Type], profile.StringTable[sampleType.Unit])
+	}
+	functions := map[uint64]string{}
+	for _, function := range profile.Function {
+		functions[function.Id] = profile.StringTable[function.Name]
+	}
+	got := map[string]int64{}
+	for _, sample := range profile.Sample {
+		// locations start from the leaf function
+		stack := make([]string, len(sample.LocationId))
+		for i, id := range sample.LocationId {
+			stack[len(stack)-1-i] = functions[profile.Location[id-1].Line[0].FunctionId]
+		}
+		got[strings.Join(stack, ";")] = sample.Value[0]
+	}
+	want := map[string]int64{"main;foo;bar": 3, "main;baz": 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got samples %v, want %v", got, want)
+	}
+}
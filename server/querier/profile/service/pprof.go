@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pyroscope-io/pyroscope/pkg/storage/tree"
+
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/profile/model"
+)
+
+// Pprof exports the profile as gzipped pprof protobuf, which could be opened by `go tool pprof`
+func Pprof(args model.ProfileTracing, cfg *config.QuerierConfig) (result []byte, debug interface{}, err error) {
+	debugs := model.ProfileDebug{}
+	stackMap, unit, err := queryProfileStacks(args, cfg, &debugs)
+	if err != nil {
+		return
+	}
+	result, err = NewPprof(args, stackMap, unit)
+	debug = debugs
+	return
+}
+
+// NewPprof converts function stacks to pprof, each function is a location without address and line number
+func NewPprof(args model.ProfileTracing, stackMap map[string]int, unit string) ([]byte, error) {
+	profile := &tree.Profile{
+		StringTable:   []string{""},
+		TimeNanos:     int64(args.TimeStart) * int64(time.Second),
+		DurationNanos: int64(args.TimeEnd-args.TimeStart) * int64(time.Second),
+	}
+	stringIndex := map[string]int64{"": 0}
+	getString := func(s string) int64 {
+		if i, ok := stringIndex[s]; ok {
+			return i
+		}
+		i := int64(len(profile.StringTable))
+		profile.StringTable = append(profile.StringTable, s)
+		stringIndex[s] = i
+		return i
+	}
+	locationIDs := map[string]uint64{}
+	getLocation := func(function string) uint64 {
+		if id, ok := locationIDs[function]; ok {
+			return id
+		}
+		id := uint64(len(profile.Location) + 1)
+		profile.Function = append(profile.Function, &tree.Function{Id: id, Name: getString(function), SystemName: getString(function)})
+		profile.Location = append(profile.Location, &tree.Location{Id: id, Line: []*tree.Line{{FunctionId: id}}})
+		locationIDs[function] = id
+		return id
+	}
+	profile.SampleType = []*tree.ValueType{{Type: getString(args.ProfileEventType), Unit: getString(unit)}}
+
+	// sort stacks to make the output stable
+	stacks := make([]string, 0, len(stackMap))
+	for stack := range stackMap {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+	for _, stack := range stacks {
+		if stack == "" || stackMap[stack] == 0 {
+			continue
+		}
+		functions := strings.Split(stack, ";")
+		// locations of pprof samples start from the leaf function
+		sampleLocations := make([]uint64, len(functions))
+		for i, function := range functions {
+			sampleLocations[len(functions)-1-i] = getLocation(function)
+		}
+		profile.Sample = append(profile.Sample, &tree.Sample{LocationId: sampleLocations, Value: []int64{int64(stackMap[stack])}})
+	}
+
+	data, err := profile.MarshalVT()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -41,6 +41,22 @@ var InstanceProfileEventType = []string{"inuse_objects", "alloc_objects", "inuse
 
 func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*model.ProfileTreeNode, debug interface{}, err error) {
 	debugs := model.ProfileDebug{}
+	stackMap, _, err := queryProfileStacks(args, cfg, &debugs)
+	if err != nil {
+		return
+	}
+
+	formatStartTime := time.Now()
+	result = NewProfileTree(args.AppService, stackMap)
+	formatEndTime := int64(time.Since(formatStartTime))
+	formatTime := fmt.Sprintf("%.9fs", float64(formatEndTime)/1e9)
+	debugs.FormatTime = formatTime
+	debug = debugs
+	return
+}
+
+// queryProfileStacks returns the merged function stacks and the unit of profile values
+func queryProfileStacks(args model.ProfileTracing, cfg *config.QuerierConfig, debugs *model.ProfileDebug) (stackMap map[string]int, unit string, err error) {
 	whereSlice := []string{}
 	whereSlice = append(whereSlice, fmt.Sprintf(" time>=%d", args.TimeStart))
 	whereSlice = append(whereSlice, fmt.Sprintf(" time<=%d", args.TimeEnd))
@@ -53,8 +69,8 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 	whereSql := strings.Join(whereSlice, " AND")
 	limitSql := cfg.Profile.FlameQueryLimit
 	sql := fmt.Sprintf(
-		"SELECT %s, %s FROM %s WHERE %s LIMIT %d",
-		common.PROFILE_LOCATION_STR, common.PROFILE_VALUE, common.TABLE_PROFILE, whereSql, limitSql,
+		"SELECT %s, %s, %s FROM %s WHERE %s LIMIT %d",
+		common.PROFILE_LOCATION_STR, common.PROFILE_VALUE, common.PROFILE_VALUE_UNIT, common.TABLE_PROFILE, whereSql, limitSql,
 	)
 
 	if slices.Contains[[]string, string](InstanceProfileEventType, args.ProfileEventType) {
@@ -63,11 +79,12 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 			common.TABLE_PROFILE, whereSql,
 		)
 		timeArgs := querier_common.QuerierParams{
-			DB:      common.DATABASE_PROFILE,
-			Sql:     timeSql,
-			Debug:   strconv.FormatBool(args.Debug),
-			Context: args.Context,
-			ORGID:   args.OrgID,
+			DB:          common.DATABASE_PROFILE,
+			Sql:         timeSql,
+			Debug:       strconv.FormatBool(args.Debug),
+			Context:     args.Context,
+			ORGID:       args.OrgID,
+			BlockTeamID: args.BlockTeamID,
 		}
 		timeEngine := &clickhouse.CHEngine{DB: common.DATABASE_PROFILE}
 		timeEngine.Init()
@@ -97,8 +114,8 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 		}
 		if timeValue > 0 {
 			sql = fmt.Sprintf(
-				"SELECT %s, %s FROM %s WHERE %s AND time=%d LIMIT %d",
-				common.PROFILE_LOCATION_STR, common.PROFILE_VALUE, common.TABLE_PROFILE, whereSql, timeValue, limitSql,
+				"SELECT %s, %s, %s FROM %s WHERE %s AND time=%d LIMIT %d",
+				common.PROFILE_LOCATION_STR, common.PROFILE_VALUE, common.PROFILE_VALUE_UNIT, common.TABLE_PROFILE, whereSql, timeValue, limitSql,
 			)
 		}
 
@@ -106,11 +123,12 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 	ckEngine := &clickhouse.CHEngine{DB: common.DATABASE_PROFILE}
 	ckEngine.Init()
 	querierArgs := querier_common.QuerierParams{
-		DB:      common.DATABASE_PROFILE,
-		Sql:     sql,
-		Debug:   strconv.FormatBool(args.Debug),
-		Context: args.Context,
-		ORGID:   args.OrgID,
+		DB:          common.DATABASE_PROFILE,
+		Sql:         sql,
+		Debug:       strconv.FormatBool(args.Debug),
+		Context:     args.Context,
+		ORGID:       args.OrgID,
+		BlockTeamID: args.BlockTeamID,
 	}
 	// XXX: change to streaming read, reduce memory
 	querierResult, querierDebug, err := ckEngine.ExecuteQuery(&querierArgs)
@@ -128,10 +146,9 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 	profileDebug.QueryTime = querierDebug["query_time"].(string)
 	debugs.QuerierDebug = append(debugs.QuerierDebug, profileDebug)
 
-	formatStartTime := time.Now()
 	profileLocationStrIndex := -1
 	profileValueIndex := -1
-	NodeIDToProfileTree := map[string]*model.ProfileTreeNode{}
+	profileValueUnitIndex := -1
 	columns := querierResult.Columns
 	values := querierResult.Values
 	for columnIndex, col := range columns {
@@ -142,10 +159,12 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 				profileLocationStrIndex = columnIndex
 			case "profile_value":
 				profileValueIndex = columnIndex
+			case "profile_value_unit":
+				profileValueUnitIndex = columnIndex
 			}
 		}
 	}
-	indexOK := slices.Contains[[]int, int]([]int{profileLocationStrIndex, profileValueIndex}, -1)
+	indexOK := slices.Contains[[]int, int]([]int{profileLocationStrIndex, profileValueIndex, profileValueUnitIndex}, -1)
 	if indexOK {
 		log.Error("Not all fields found")
 		err = errors.New("Not all fields found")
@@ -153,7 +172,7 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 	}
 
 	// step 1: merge to uniq function stacks
-	stackMap := make(map[string]int)
+	stackMap = make(map[string]int)
 	for _, value := range values {
 		switch valueSlice := value.(type) {
 		case []interface{}:
@@ -175,6 +194,9 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 			if profileValueInt, ok := valueSlice[profileValueIndex].(int); ok {
 				profileValue = profileValueInt
 			}
+			if profileValueUnit, ok := valueSlice[profileValueUnitIndex].(string); ok && unit == "" {
+				unit = profileValueUnit
+			}
 			if _, ok := stackMap[profileLocationStr]; ok {
 				stackMap[profileLocationStr] += profileValue
 			} else {
@@ -182,8 +204,12 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 			}
 		}
 	}
+	return
+}
 
-	// step 2: merge function stacks to profile tree
+// NewProfileTree merges function stacks to profile tree, the root node is named by appService
+func NewProfileTree(appService string, stackMap map[string]int) (result []*model.ProfileTreeNode) {
+	NodeIDToProfileTree := map[string]*model.ProfileTreeNode{}
 	rootTotalValue := 0
 	for profileLocationStr, profileValue := range stackMap {
 		profileLocationStrSlice := strings.Split(profileLocationStr, ";")
@@ -215,10 +241,6 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 	}
 
 	if len(NodeIDToProfileTree) == 0 {
-		formatEndTime := int64(time.Since(formatStartTime))
-		formatTime := fmt.Sprintf("%.9fs", float64(formatEndTime)/1e9)
-		debugs.FormatTime = formatTime
-		debug = debugs
 		return
 	}
 
@@ -234,7 +256,7 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 
 	}
 	// format root node
-	rootNode := NewProfileTreeNode(args.AppService, "", 0)
+	rootNode := NewProfileTreeNode(appService, "", 0)
 	rootNode.ParentNodeID = "-1"
 	rootNode.TotalValue = rootTotalValue
 
@@ -242,10 +264,6 @@ func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*mo
 	for _, node := range NodeIDToProfileTree {
 		result = append(result, node)
 	}
-	formatEndTime := int64(time.Since(formatStartTime))
-	formatTime := fmt.Sprintf("%.9fs", float64(formatEndTime)/1e9)
-	debugs.FormatTime = formatTime
-	debug = debugs
 	return
 }
 
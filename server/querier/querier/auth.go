@@ -0,0 +1,184 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package querier
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	controller_common "github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/router"
+)
+
+// permissions of a user are cached for a while, to avoid requesting fpermit for every query,
+// fpermit should call DELETE /v1/team-scopes/ of each querier when teams change to invalidate the cache
+const teamScopeCacheTimeout = time.Minute
+
+type userInfo struct {
+	userType string
+	userID   string
+	orgID    string
+}
+
+type teamScope struct {
+	blockTeamIDs []string
+	updatedAt    time.Time
+}
+
+var teamScopeCache = struct {
+	sync.Mutex
+	scopes map[userInfo]*teamScope
+}{scopes: map[userInfo]*teamScope{}}
+
+// AuthHandle resolves the identity of the caller by X-Org-Id/X-User-Type/X-User-Id headers,
+// ids of the teams which the caller is not authorized to are set to gin context,
+// and filtered out by team_id in all queries.
+func AuthHandle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config.ControllerCfg.FPermit.Enabled {
+			c.Next()
+			return
+		}
+		user, err := getUserInfo(c.Request.Header)
+		if err != nil {
+			router.BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+			c.Abort()
+			return
+		}
+		blockTeamIDs, err := getBlockTeamIDs(user, &config.ControllerCfg.FPermit)
+		if err != nil {
+			log.Errorf("get unauthorized teams of user(type: %s, id: %s, org: %s) failed: %s", user.userType, user.userID, user.orgID, err)
+			router.InternalErrorResponse(c, nil, nil, common.SERVER_ERROR, fmt.Sprintf("get unauthorized teams failed: %s", err))
+			c.Abort()
+			return
+		}
+		c.Set(common.CONTEXT_KEY_BLOCK_TEAM_ID, blockTeamIDs)
+		c.Next()
+	}
+}
+
+func AuthRouter(e *gin.Engine) {
+	e.DELETE("/v1/team-scopes/", invalidateTeamScopes())
+}
+
+// invalidateTeamScopes clears cached permissions of users in the org, or of all users if org_id is not specified,
+// so that created or modified teams take effect in the next query
+func invalidateTeamScopes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID := c.Query("org_id")
+		if orgID != "" {
+			if _, err := strconv.Atoi(orgID); err != nil {
+				router.BadRequestResponse(c, common.INVALID_PARAMETERS, fmt.Sprintf("invalid org_id: %s", orgID))
+				return
+			}
+		}
+		count := clearTeamScopes(orgID)
+		log.Infof("invalidate %d cached team scopes of org(%s)", count, orgID)
+		router.JsonResponse(c, nil, nil, nil)
+	}
+}
+
+func clearTeamScopes(orgID string) int {
+	teamScopeCache.Lock()
+	defer teamScopeCache.Unlock()
+	count := 0
+	for user := range teamScopeCache.scopes {
+		if orgID == "" || user.orgID == orgID {
+			delete(teamScopeCache.scopes, user)
+			count++
+		}
+	}
+	return count
+}
+
+func getUserInfo(header http.Header) (userInfo, error) {
+	user := userInfo{
+		userType: header.Get(common.HEADER_KEY_X_USER_TYPE),
+		userID:   header.Get(common.HEADER_KEY_X_USER_ID),
+		orgID:    header.Get(common.HEADER_KEY_X_ORG_ID),
+	}
+	if user.userType == "" {
+		user.userType = common.DEFAULT_USER_TYPE
+	}
+	if user.userID == "" {
+		user.userID = common.DEFAULT_USER_ID
+	}
+	if user.orgID == "" {
+		user.orgID = common.DEFAULT_ORG_ID
+	}
+	for key, value := range map[string]string{
+		common.HEADER_KEY_X_USER_TYPE: user.userType,
+		common.HEADER_KEY_X_USER_ID:   user.userID,
+		common.HEADER_KEY_X_ORG_ID:    user.orgID,
+	} {
+		if _, err := strconv.Atoi(value); err != nil {
+			return user, fmt.Errorf("invalid header %s: %s", key, value)
+		}
+	}
+	return user, nil
+}
+
+func getBlockTeamIDs(user userInfo, fpermitCfg *controller_common.FPermit) ([]string, error) {
+	teamScopeCache.Lock()
+	scope, ok := teamScopeCache.scopes[user]
+	teamScopeCache.Unlock()
+	if ok && time.Since(scope.updatedAt) < teamScopeCacheTimeout {
+		return scope.blockTeamIDs, nil
+	}
+
+	response, err := controller_common.CURLPerform(
+		http.MethodGet,
+		fmt.Sprintf(
+			"http://%s:%d/v1/org/%s/scope_teams?reverse=true",
+			fpermitCfg.Host, fpermitCfg.Port, user.orgID,
+		),
+		map[string]interface{}{},
+		controller_common.WithHeader(common.HEADER_KEY_X_USER_TYPE, user.userType),
+		controller_common.WithHeader(common.HEADER_KEY_X_USER_ID, user.userID),
+		controller_common.WithHeader(common.HEADER_KEY_X_ORG_ID, user.orgID),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// team ids are spliced into sql, make sure that all of them are integers
+	teamIDs := []int{}
+	for k := range response.Get("DATA").MustMap() {
+		teamID, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid team id %s", k)
+		}
+		teamIDs = append(teamIDs, teamID)
+	}
+	sort.Ints(teamIDs)
+	blockTeamIDs := make([]string, 0, len(teamIDs))
+	for _, teamID := range teamIDs {
+		blockTeamIDs = append(blockTeamIDs, strconv.Itoa(teamID))
+	}
+
+	teamScopeCache.Lock()
+	teamScopeCache.scopes[user] = &teamScope{blockTeamIDs: blockTeamIDs, updatedAt: time.Now()}
+	teamScopeCache.Unlock()
+	return blockTeamIDs, nil
+}
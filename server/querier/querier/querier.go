@@ -83,6 +83,8 @@ func Start(configPath, serverLogFile string) {
 	r.Use(gin.LoggerWithFormatter(logger.GinLogFormat))
 	r.Use(StatdHandle())
 	r.Use(ErrHandle())
+	r.Use(AuthHandle())
+	AuthRouter(r)
 	router.QueryRouter(r)
 	profile_router.ProfileRouter(r, &cfg)
 	prometheus_router.PrometheusRouter(r)
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	logging "github.com/op/go-logging"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/service"
+)
+
+var log = logging.MustGetLogger("querier.router")
+
+func downloadPcap() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args := common.PcapParams{}
+		if err := c.ShouldBindJSON(&args); err != nil {
+			BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+		if len(args.FlowIDs) == 0 {
+			BadRequestResponse(c, common.INVALID_POST_DATA, "flow_ids should not be empty")
+			return
+		}
+		if args.TimeStart > args.TimeEnd {
+			BadRequestResponse(c, common.INVALID_POST_DATA, "time_start should not be greater than time_end")
+			return
+		}
+		args.Context = c.Request.Context()
+		args.ORGID = c.Request.Header.Get(common.HEADER_KEY_X_ORG_ID)
+		if args.ORGID == "" {
+			args.ORGID = common.DEFAULT_ORG_ID
+		}
+		args.BlockTeamID = c.GetStringSlice(common.CONTEXT_KEY_BLOCK_TEAM_ID)
+
+		batches, debug, err := service.QueryPcap(&args)
+		if err != nil {
+			if !args.Debug {
+				debug = nil
+			}
+			JsonResponse(c, nil, debug, err)
+			return
+		}
+		filename := fmt.Sprintf("flow_%d.pcapng", args.FlowIDs[0])
+		if len(args.FlowIDs) > 1 {
+			filename = fmt.Sprintf("agent_%d_flows.pcapng", args.AgentID)
+		}
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.Header("Content-Type", "application/octet-stream")
+		c.Status(http.StatusOK)
+		if err := service.WritePcapng(c.Writer, &args, batches); err != nil {
+			// the response has been partially sent, nothing could be returned to client
+			log.Warningf("write pcapng failed: %s", err)
+		}
+	})
+}
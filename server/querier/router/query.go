@@ -28,6 +28,7 @@ import (
 
 func QueryRouter(e *gin.Engine) {
 	e.POST("/v1/query/", executeQuery())
+	e.POST("/v1/pcap/", downloadPcap())
 
 	// api router for tempo
 	e.GET("/api/traces/:traceId", tempoTraceReader())
@@ -35,6 +36,8 @@ func QueryRouter(e *gin.Engine) {
 	e.GET("/api/search/tags", tempoTagsReader())
 	e.GET("/api/search/tag/:tagName/values", tempoTagValuesReader())
 	e.GET("/api/search", tempoSearchReader())
+	e.GET("/api/v2/search/tags", tempoV2TagsReader())
+	e.GET("/api/v2/search/tag/:tagName/values", tempoV2TagValuesReader())
 }
 
 func executeQuery() gin.HandlerFunc {
@@ -51,6 +54,7 @@ func executeQuery() gin.HandlerFunc {
 		if args.ORGID == "" {
 			args.ORGID = common.DEFAULT_ORG_ID
 		}
+		args.BlockTeamID = c.GetStringSlice(common.CONTEXT_KEY_BLOCK_TEAM_ID)
 		if args.QueryUUID == "" {
 			query_uuid := uuid.New()
 			args.QueryUUID = query_uuid.String()
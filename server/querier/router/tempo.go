@@ -32,12 +32,30 @@ import (
 	//"github.com/k0kubun/pp"
 )
 
+// identity of the caller is passed to tempo queries, data of unauthorized teams is filtered out
+func setUserArgs(c *gin.Context, args *common.TempoParams) {
+	args.ORGID = c.Request.Header.Get(common.HEADER_KEY_X_ORG_ID)
+	if args.ORGID == "" {
+		args.ORGID = common.DEFAULT_ORG_ID
+	}
+	args.UserType = c.Request.Header.Get(common.HEADER_KEY_X_USER_TYPE)
+	if args.UserType == "" {
+		args.UserType = common.DEFAULT_USER_TYPE
+	}
+	args.UserID = c.Request.Header.Get(common.HEADER_KEY_X_USER_ID)
+	if args.UserID == "" {
+		args.UserID = common.DEFAULT_USER_ID
+	}
+	args.BlockTeamID = c.GetStringSlice(common.CONTEXT_KEY_BLOCK_TEAM_ID)
+}
+
 func tempoTagValuesReader() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		args := common.TempoParams{
 			TagName: c.Param("tagName"),
 			Context: c.Request.Context(),
 		}
+		setUserArgs(c, &args)
 		result, _, err := tempo.ShowTagValues(&args)
 		if err != nil {
 			c.JSON(500, err)
@@ -52,6 +70,7 @@ func tempoTagsReader() gin.HandlerFunc {
 		args := common.TempoParams{
 			Context: c.Request.Context(),
 		}
+		setUserArgs(c, &args)
 		result, _, err := tempo.ShowTags(&args)
 		if err != nil {
 			c.JSON(500, err)
@@ -61,6 +80,37 @@ func tempoTagsReader() gin.HandlerFunc {
 	})
 }
 
+func tempoV2TagValuesReader() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args := common.TempoParams{
+			TagName: c.Param("tagName"),
+			Context: c.Request.Context(),
+		}
+		setUserArgs(c, &args)
+		result, _, err := tempo.TraceQLTagValues(&args)
+		if err != nil {
+			c.JSON(500, err)
+			return
+		}
+		c.JSON(200, result)
+	})
+}
+
+func tempoV2TagsReader() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args := common.TempoParams{
+			Context: c.Request.Context(),
+		}
+		setUserArgs(c, &args)
+		result, _, err := tempo.TraceQLTags(&args)
+		if err != nil {
+			c.JSON(500, err)
+			return
+		}
+		c.JSON(200, result)
+	})
+}
+
 func tempoSearchReader() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		args := common.TempoParams{
@@ -70,8 +120,10 @@ func tempoSearchReader() gin.HandlerFunc {
 			StartTime:   c.Query("start"),
 			EndTime:     c.Query("end"),
 			Debug:       c.Query("debug"),
+			Query:       c.Query("q"),
 			Context:     c.Request.Context(),
 		}
+		setUserArgs(c, &args)
 		args.SetFilters(c.Query("tags"))
 		result, _, err := tempo.TraceSearch(&args)
 		if err != nil {
@@ -90,6 +142,7 @@ func tempoTraceReader() gin.HandlerFunc {
 			EndTime:   c.Query("end"),
 			Context:   c.Request.Context(),
 		}
+		setUserArgs(c, &args)
 		resp, err := tempo.FindTraceByTraceID(&args)
 		if err != nil {
 			// fmt.Println(err)
@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	logging "github.com/op/go-logging"
+
+	"github.com/deepflowio/deepflow/server/libs/pcap"
+	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse"
+)
+
+var log = logging.MustGetLogger("querier.service")
+
+const (
+	PCAP_DB    = "flow_log"
+	PCAP_TABLE = "l7_packet"
+	// each row is a batch of packets, limit the rows to avoid exhausting memory
+	PCAP_QUERY_LIMIT = 10000
+	PCAP_APPLICATION = "DeepFlow"
+)
+
+type PcapBatch struct {
+	FlowID uint64
+	*pcap.PcapFile
+}
+
+// QueryPcap returns the stored packet batches of the flows
+func QueryPcap(args *common.PcapParams) (batches []*PcapBatch, debug map[string]interface{}, err error) {
+	flowIDs := make([]string, 0, len(args.FlowIDs))
+	for _, id := range args.FlowIDs {
+		flowIDs = append(flowIDs, strconv.FormatUint(id, 10))
+	}
+	sql := fmt.Sprintf(
+		"SELECT flow_id, packet_batch FROM %s WHERE time>=%d AND time<=%d AND vtap_id=%d AND flow_id IN (%s) LIMIT %d",
+		PCAP_TABLE, args.TimeStart, args.TimeEnd, args.AgentID, strings.Join(flowIDs, ","), PCAP_QUERY_LIMIT,
+	)
+	querierArgs := common.QuerierParams{
+		DB:          PCAP_DB,
+		Sql:         sql,
+		Debug:       strconv.FormatBool(args.Debug),
+		Context:     args.Context,
+		ORGID:       args.ORGID,
+		BlockTeamID: args.BlockTeamID,
+	}
+	ckEngine := &clickhouse.CHEngine{DB: PCAP_DB, Context: args.Context}
+	ckEngine.Init()
+	result, debug, err := ckEngine.ExecuteQuery(&querierArgs)
+	if err != nil {
+		log.Errorf("query pcap failed: %s, debug: %v", err, debug)
+		return nil, debug, err
+	}
+
+	flowIDIndex, packetBatchIndex := -1, -1
+	for i, column := range result.Columns {
+		switch column {
+		case "flow_id":
+			flowIDIndex = i
+		case "packet_batch":
+			packetBatchIndex = i
+		}
+	}
+	if flowIDIndex < 0 || packetBatchIndex < 0 {
+		return nil, debug, common.NewError(common.SERVER_ERROR, "flow_id or packet_batch is not found in query result")
+	}
+	for _, value := range result.Values {
+		row, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+		encoded, _ := row[packetBatchIndex].(string)
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			log.Warningf("decode packet_batch failed: %s", err)
+			continue
+		}
+		file, err := pcap.ReadPcap(data)
+		if file == nil {
+			log.Warningf("read packet_batch failed: %s", err)
+			continue
+		}
+		// keep the packets before a truncated record
+		flowID, _ := row[flowIDIndex].(int)
+		batches = append(batches, &PcapBatch{FlowID: uint64(flowID), PcapFile: file})
+	}
+	if len(batches) == 0 {
+		return nil, debug, common.NewError(common.RESOURCE_NOT_FOUND, "no packets found")
+	}
+	return batches, debug, nil
+}
+
+// WritePcapng merges packets of all batches in timestamp order and writes a pcapng file,
+// the section comment describes the flows, batches of different link types are written to different interfaces.
+func WritePcapng(w io.Writer, args *common.PcapParams, batches []*PcapBatch) error {
+	type indexedPacket struct {
+		interfaceID uint32
+		packet      *pcap.Packet
+	}
+	var packets []indexedPacket
+	interfaces := make(map[uint32]uint32) // link type -> interface id
+	linkTypes, snapLens := []uint32{}, []uint32{}
+	packetCounts := make(map[uint64]int)
+	for _, batch := range batches {
+		id, ok := interfaces[batch.LinkType]
+		if !ok {
+			id = uint32(len(linkTypes))
+			interfaces[batch.LinkType] = id
+			linkTypes, snapLens = append(linkTypes, batch.LinkType), append(snapLens, batch.SnapLen)
+		} else if batch.SnapLen > snapLens[id] {
+			snapLens[id] = batch.SnapLen
+		}
+		for i := range batch.Packets {
+			packets = append(packets, indexedPacket{id, &batch.Packets[i]})
+		}
+		packetCounts[batch.FlowID] += len(batch.Packets)
+	}
+	sort.SliceStable(packets, func(i, j int) bool {
+		return packets[i].packet.Timestamp < packets[j].packet.Timestamp
+	})
+
+	comments := []string{fmt.Sprintf("agent_id: %d, time range: %s - %s", args.AgentID,
+		time.Unix(args.TimeStart, 0).UTC().Format(time.RFC3339), time.Unix(args.TimeEnd, 0).UTC().Format(time.RFC3339))}
+	for _, flowID := range args.FlowIDs {
+		comments = append(comments, fmt.Sprintf("flow_id: %d, packet count: %d", flowID, packetCounts[flowID]))
+	}
+	if len(packets) > 0 {
+		comments = append(comments, fmt.Sprintf("first packet: %s, last packet: %s",
+			time.Unix(0, packets[0].packet.Timestamp).UTC().Format(time.RFC3339Nano),
+			time.Unix(0, packets[len(packets)-1].packet.Timestamp).UTC().Format(time.RFC3339Nano)))
+	}
+
+	writer := pcap.NewPcapngWriter(w)
+	if err := writer.WriteSectionHeader(PCAP_APPLICATION, comments...); err != nil {
+		return err
+	}
+	for i, linkType := range linkTypes {
+		if err := writer.WriteInterface(linkType, snapLens[i], fmt.Sprintf("agent-%d", args.AgentID)); err != nil {
+			return err
+		}
+	}
+	for _, p := range packets {
+		if err := writer.WritePacket(p.interfaceID, p.packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,715 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tempo
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse"
+)
+
+const (
+	L7_TRACING_DEFAULT_TIME_RANGE = 24 * 3600 // unit: s
+	// time range of the expanding queries is widened by the margin for clock skew between agents
+	L7_TRACING_TIME_MARGIN = 10 // unit: s
+
+	L7_SIGNAL_SOURCE_OTEL = 4
+)
+
+var L7_TRACING_FIELDS = []string{
+	"_id", "vtap_id", "tap_side", "Enum(tap_side) AS tap_side_name", "signal_source", "l7_protocol", "l7_protocol_str",
+	"toUnixTimestamp64Micro(start_time) AS start_time_us", "toUnixTimestamp64Micro(end_time) AS end_time_us", "flow_id",
+	"trace_id", "span_id", "parent_span_id", "x_request_id_0", "x_request_id_1",
+	"syscall_trace_id_request", "syscall_trace_id_response", "req_tcp_seq", "resp_tcp_seq",
+	"endpoint", "request_type", "request_resource", "response_status",
+	"app_service", "app_instance", "process_kname_0", "process_kname_1", "attribute",
+}
+
+// position of the observation points from client to server, spans of a request are chained in this order
+var TAP_SIDE_ORDER = map[string]int{
+	"c-app": 0, "app": 0, "c-p": 1, "c": 2, "c-nd": 3, "c-hv": 4, "c-gw-hv": 5, "c-gw": 6, "local": 7, "rest": 8,
+	"s-gw": 9, "s-gw-hv": 10, "s-hv": 11, "s-nd": 12, "s": 13, "s-p": 14, "s-app": 15,
+}
+
+// L7TracingSpan is a row of l7_flow_log in a trace
+type L7TracingSpan struct {
+	ID                     uint64
+	VtapID                 int
+	TapSide                string
+	TapSideName            string
+	SignalSource           int
+	L7Protocol             int
+	L7ProtocolStr          string
+	StartTimeUs            int64
+	EndTimeUs              int64
+	FlowID                 uint64
+	TraceID                string
+	SpanID                 string
+	ParentSpanID           string
+	XRequestID0            string
+	XRequestID1            string
+	SyscallTraceIDRequest  uint64
+	SyscallTraceIDResponse uint64
+	ReqTCPSeq              uint64
+	RespTCPSeq             uint64
+	Endpoint               string
+	RequestType            string
+	RequestResource        string
+	ResponseStatus         int
+	AppService             string
+	AppInstance            string
+	ProcessKname0          string
+	ProcessKname1          string
+	Attributes             string
+
+	parent         *L7TracingSpan
+	deepflowSpanID string
+}
+
+func (s *L7TracingSpan) isApp() bool {
+	return s.SignalSource == L7_SIGNAL_SOURCE_OTEL || s.TapSide == "c-app" || s.TapSide == "s-app" || s.TapSide == "app"
+}
+
+func (s *L7TracingSpan) isServerSide() bool {
+	return strings.HasPrefix(s.TapSide, "s")
+}
+
+func (s *L7TracingSpan) hasSyscallTraceID(id uint64) bool {
+	return id != 0 && (s.SyscallTraceIDRequest == id || s.SyscallTraceIDResponse == id)
+}
+
+func (s *L7TracingSpan) hasXRequestID(id string) bool {
+	return id != "" && (s.XRequestID0 == id || s.XRequestID1 == id)
+}
+
+// service of the span, spans without service are network spans and are hidden in tempo traces
+func (s *L7TracingSpan) service() string {
+	switch {
+	case s.isApp():
+		return s.AppService
+	case s.TapSide == "c-p":
+		return s.ProcessKname0
+	case s.TapSide == "s-p":
+		return s.ProcessKname1
+	}
+	return ""
+}
+
+// dedupeKey is the same for the spans of a request captured repeatedly at the same observation point
+func (s *L7TracingSpan) dedupeKey() string {
+	if s.isApp() {
+		if s.SpanID == "" {
+			return ""
+		}
+		return fmt.Sprintf("app-%s-%s", s.TapSide, s.SpanID)
+	}
+	if s.ReqTCPSeq == 0 && s.RespTCPSeq == 0 {
+		return ""
+	}
+	return fmt.Sprintf("net-%d-%s-%d-%d-%d-%d", s.VtapID, s.TapSide, s.FlowID, s.ReqTCPSeq, s.RespTCPSeq, s.L7Protocol)
+}
+
+// chainKey is the same for the network and system spans of a request captured at different observation points,
+// spans of the same key are chained only if they are close in time, as tcp seq is reused by other flows.
+func (s *L7TracingSpan) chainKey() string {
+	if s.isApp() {
+		return ""
+	}
+	if s.ReqTCPSeq != 0 {
+		return fmt.Sprintf("req-%d", s.ReqTCPSeq)
+	}
+	if s.RespTCPSeq != 0 {
+		return fmt.Sprintf("resp-%d", s.RespTCPSeq)
+	}
+	return ""
+}
+
+func NewL7TracingSpans(columns []interface{}, values []interface{}) []*L7TracingSpan {
+	spans := make([]*L7TracingSpan, 0, len(values))
+	for _, value := range values {
+		row, ok := value.([]interface{})
+		if !ok || len(row) != len(columns) {
+			continue
+		}
+		span := &L7TracingSpan{}
+		for i, column := range columns {
+			v := row[i]
+			switch column {
+			case "_id":
+				span.ID = toUint64(v)
+			case "vtap_id":
+				span.VtapID = int(toUint64(v))
+			case "tap_side":
+				span.TapSide = toString(v)
+			case "tap_side_name":
+				span.TapSideName = toString(v)
+			case "signal_source":
+				span.SignalSource = int(toUint64(v))
+			case "l7_protocol":
+				span.L7Protocol = int(toUint64(v))
+			case "l7_protocol_str":
+				span.L7ProtocolStr = toString(v)
+			case "start_time_us":
+				span.StartTimeUs = int64(toUint64(v))
+			case "end_time_us":
+				span.EndTimeUs = int64(toUint64(v))
+			case "flow_id":
+				span.FlowID = toUint64(v)
+			case "trace_id":
+				span.TraceID = toString(v)
+			case "span_id":
+				span.SpanID = toString(v)
+			case "parent_span_id":
+				span.ParentSpanID = toString(v)
+			case "x_request_id_0":
+				span.XRequestID0 = toString(v)
+			case "x_request_id_1":
+				span.XRequestID1 = toString(v)
+			case "syscall_trace_id_request":
+				span.SyscallTraceIDRequest = toUint64(v)
+			case "syscall_trace_id_response":
+				span.SyscallTraceIDResponse = toUint64(v)
+			case "req_tcp_seq":
+				span.ReqTCPSeq = toUint64(v)
+			case "resp_tcp_seq":
+				span.RespTCPSeq = toUint64(v)
+			case "endpoint":
+				span.Endpoint = toString(v)
+			case "request_type":
+				span.RequestType = toString(v)
+			case "request_resource":
+				span.RequestResource = toString(v)
+			case "response_status":
+				span.ResponseStatus = int(toUint64(v))
+			case "app_service":
+				span.AppService = toString(v)
+			case "app_instance":
+				span.AppInstance = toString(v)
+			case "process_kname_0":
+				span.ProcessKname0 = toString(v)
+			case "process_kname_1":
+				span.ProcessKname1 = toString(v)
+			case "attribute":
+				span.Attributes = toString(v)
+			}
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// l7TracingQuerier returns the spans matching the filter in the time range,
+// truncated is true if the spans are truncated by the query limit
+type l7TracingQuerier func(filter string, timeStart, timeEnd int64) (spans []*L7TracingSpan, truncated bool, err error)
+
+// l7TracingSeq is a tcp seq searched, only the spans of the same direction close in time are matched
+type l7TracingSeq struct {
+	isResp      bool
+	seq         uint64
+	otherSeq    uint64 // seq of the other direction, 0 if unknown
+	startTimeUs int64
+	endTimeUs   int64
+}
+
+func (q *l7TracingSeq) match(span *L7TracingSpan, windowUs int64) bool {
+	seq, otherSeq := span.ReqTCPSeq, span.RespTCPSeq
+	if q.isResp {
+		seq, otherSeq = otherSeq, seq
+	}
+	if seq != q.seq || (q.otherSeq != 0 && otherSeq != 0 && q.otherSeq != otherSeq) {
+		return false
+	}
+	return span.StartTimeUs >= q.startTimeUs-windowUs && span.StartTimeUs <= q.startTimeUs+windowUs
+}
+
+func (q *l7TracingSeq) filter(windowUs int64) string {
+	column := "req_tcp_seq"
+	if q.isResp {
+		column = "resp_tcp_seq"
+	}
+	// time is the end time in seconds, the precise start time is checked after query
+	start := (q.startTimeUs - windowUs) / 1000000
+	end := (q.endTimeUs+windowUs)/1000000 + 1
+	return fmt.Sprintf("%s=%d AND time>=%d AND time<=%d", column, q.seq, start, end)
+}
+
+// l7TracingKeys records the keys which have been used to search spans
+type l7TracingKeys struct {
+	traceIDs        map[string]bool
+	xRequestIDs     map[string]bool
+	syscallTraceIDs map[uint64]bool
+	tcpSeqs         map[string]*l7TracingSeq
+	// max time difference of a request observed by different agents, tcp seq is matched in the window
+	seqWindowUs int64
+}
+
+func newL7TracingKeys(seqWindowUs int64) *l7TracingKeys {
+	return &l7TracingKeys{
+		traceIDs:        make(map[string]bool),
+		xRequestIDs:     make(map[string]bool),
+		syscallTraceIDs: make(map[uint64]bool),
+		tcpSeqs:         make(map[string]*l7TracingSeq),
+		seqWindowUs:     seqWindowUs,
+	}
+}
+
+// match returns whether the span matches any key searched, spans matched by tcp seq out of the window are dropped
+func (k *l7TracingKeys) match(span *L7TracingSpan) bool {
+	if k.traceIDs[span.TraceID] || k.xRequestIDs[span.XRequestID0] || k.xRequestIDs[span.XRequestID1] ||
+		k.syscallTraceIDs[span.SyscallTraceIDRequest] || k.syscallTraceIDs[span.SyscallTraceIDResponse] {
+		return true
+	}
+	for _, key := range []string{fmt.Sprintf("req-%d", span.ReqTCPSeq), fmt.Sprintf("resp-%d", span.RespTCPSeq)} {
+		if seq, ok := k.tcpSeqs[key]; ok && seq.match(span, k.seqWindowUs) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextFilter returns the filter of the keys in the spans which have not been searched
+func (k *l7TracingKeys) nextFilter(spans []*L7TracingSpan) string {
+	var traceIDs, xRequestIDs []string
+	var syscallTraceIDs, tcpSeqs []string
+	addString := func(searched map[string]bool, keys *[]string, key string) {
+		if key != "" && !searched[key] {
+			searched[key] = true
+			*keys = append(*keys, quoteString(key))
+		}
+	}
+	addUint := func(searched map[uint64]bool, keys *[]string, key uint64) {
+		if key != 0 && !searched[key] {
+			searched[key] = true
+			*keys = append(*keys, strconv.FormatUint(key, 10))
+		}
+	}
+	addSeq := func(span *L7TracingSpan, isResp bool) {
+		seq := &l7TracingSeq{isResp: isResp, seq: span.ReqTCPSeq, otherSeq: span.RespTCPSeq,
+			startTimeUs: span.StartTimeUs, endTimeUs: span.EndTimeUs}
+		key := fmt.Sprintf("req-%d", seq.seq)
+		if isResp {
+			seq.seq, seq.otherSeq = seq.otherSeq, seq.seq
+			key = fmt.Sprintf("resp-%d", seq.seq)
+		}
+		if seq.seq == 0 || k.tcpSeqs[key] != nil {
+			return
+		}
+		k.tcpSeqs[key] = seq
+		tcpSeqs = append(tcpSeqs, seq.filter(k.seqWindowUs))
+	}
+	for _, span := range spans {
+		addString(k.traceIDs, &traceIDs, span.TraceID)
+		addString(k.xRequestIDs, &xRequestIDs, span.XRequestID0)
+		addString(k.xRequestIDs, &xRequestIDs, span.XRequestID1)
+		addUint(k.syscallTraceIDs, &syscallTraceIDs, span.SyscallTraceIDRequest)
+		addUint(k.syscallTraceIDs, &syscallTraceIDs, span.SyscallTraceIDResponse)
+		if !span.isApp() {
+			addSeq(span, false)
+			addSeq(span, true)
+		}
+	}
+
+	filters := []string{}
+	if len(traceIDs) > 0 {
+		filters = append(filters, fmt.Sprintf("trace_id IN (%s)", strings.Join(traceIDs, ",")))
+	}
+	if len(xRequestIDs) > 0 {
+		ids := strings.Join(xRequestIDs, ",")
+		filters = append(filters, fmt.Sprintf("x_request_id_0 IN (%s) OR x_request_id_1 IN (%s)", ids, ids))
+	}
+	if len(syscallTraceIDs) > 0 {
+		ids := strings.Join(syscallTraceIDs, ",")
+		filters = append(filters, fmt.Sprintf("syscall_trace_id_request IN (%s) OR syscall_trace_id_response IN (%s)", ids, ids))
+	}
+	filters = append(filters, tcpSeqs...)
+	if len(filters) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(filters, ") OR (") + ")"
+}
+
+// searchL7TracingSpans searches the spans of the trace id, then iteratively expands the trace by trace ids,
+// x_request_ids, syscall trace ids and tcp seqs of the found spans until no new span is found.
+// truncated is true if any query is truncated by the query limit, or the search stops at the max iteration.
+func searchL7TracingSpans(traceID string, timeStart, timeEnd int64, query l7TracingQuerier, maxIteration int, seqWindowUs int64) (
+	spans []*L7TracingSpan, truncated bool, err error) {
+	keys := newL7TracingKeys(seqWindowUs)
+	keys.traceIDs[traceID] = true
+	newSpans, truncated, err := query(fmt.Sprintf("trace_id=%s", quoteString(traceID)), timeStart, timeEnd)
+	if err != nil {
+		return nil, false, err
+	}
+	idToSpan := make(map[uint64]*L7TracingSpan)
+	for i := 0; ; i++ {
+		found := []*L7TracingSpan{}
+		for _, span := range newSpans {
+			if _, ok := idToSpan[span.ID]; !ok && keys.match(span) {
+				idToSpan[span.ID] = span
+				found = append(found, span)
+			}
+		}
+		if len(found) == 0 {
+			break
+		}
+		spans = append(spans, found...)
+		filter := keys.nextFilter(found)
+		if filter == "" {
+			break
+		}
+		if i+1 >= maxIteration {
+			truncated = true
+			break
+		}
+		start, end := l7TracingTimeRange(spans)
+		var queryTruncated bool
+		newSpans, queryTruncated, err = query(filter, start, end)
+		if err != nil {
+			return nil, false, err
+		}
+		truncated = truncated || queryTruncated
+	}
+	return spans, truncated, nil
+}
+
+// l7TracingTimeRange returns the time range of the spans in seconds
+func l7TracingTimeRange(spans []*L7TracingSpan) (int64, int64) {
+	var start, end int64
+	for i, span := range spans {
+		if i == 0 || span.StartTimeUs < start {
+			start = span.StartTimeUs
+		}
+		if span.EndTimeUs > end {
+			end = span.EndTimeUs
+		}
+	}
+	return start/1000000 - L7_TRACING_TIME_MARGIN, end/1000000 + L7_TRACING_TIME_MARGIN
+}
+
+// dedupeL7TracingSpans keeps the span with the longest duration in the spans of the same dedupe key
+func dedupeL7TracingSpans(spans []*L7TracingSpan) []*L7TracingSpan {
+	keyToIndex := make(map[string]int)
+	result := make([]*L7TracingSpan, 0, len(spans))
+	for _, span := range spans {
+		key := span.dedupeKey()
+		if key == "" {
+			result = append(result, span)
+			continue
+		}
+		if i, ok := keyToIndex[key]; ok {
+			if span.EndTimeUs-span.StartTimeUs > result[i].EndTimeUs-result[i].StartTimeUs {
+				result[i] = span
+			}
+			continue
+		}
+		keyToIndex[key] = len(result)
+		result = append(result, span)
+	}
+	return result
+}
+
+func sortL7TracingSpans(spans []*L7TracingSpan) {
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].StartTimeUs != spans[j].StartTimeUs {
+			return spans[i].StartTimeUs < spans[j].StartTimeUs
+		}
+		if TAP_SIDE_ORDER[spans[i].TapSide] != TAP_SIDE_ORDER[spans[j].TapSide] {
+			return TAP_SIDE_ORDER[spans[i].TapSide] < TAP_SIDE_ORDER[spans[j].TapSide]
+		}
+		return spans[i].ID < spans[j].ID
+	})
+}
+
+// setL7TracingParents builds the span tree:
+//   - network and system spans of a request are chained from client to server by tcp seq
+//   - the head of a chain is the child of the client app span with the same span id,
+//     or the server process span with the same syscall trace id on the same agent,
+//     or the server span with the same x_request_id
+//   - an app span is the child of the chain whose span id is its parent span id, or the app span of its parent span id
+func setL7TracingParents(spans []*L7TracingSpan, seqWindowUs int64) {
+	sortL7TracingSpans(spans)
+	chains := make(map[string][]*L7TracingSpan)
+	chainKeys := []string{}
+	spanToChain := make(map[*L7TracingSpan]string)
+	keyToChain := make(map[string]string) // chain key -> latest chain of the key
+	for _, span := range spans {
+		key := span.chainKey()
+		if key == "" {
+			continue
+		}
+		// spans are sorted by start time, a span far from the head of the chain belongs to another request
+		chainID, ok := keyToChain[key]
+		if ok {
+			head := chains[chainID][0]
+			if span.StartTimeUs-head.StartTimeUs > seqWindowUs ||
+				(span.RespTCPSeq != 0 && head.RespTCPSeq != 0 && span.RespTCPSeq != head.RespTCPSeq) {
+				ok = false
+			}
+		}
+		if !ok {
+			chainID = fmt.Sprintf("%s-%d", key, len(chainKeys))
+			keyToChain[key] = chainID
+			chainKeys = append(chainKeys, chainID)
+		}
+		chains[chainID] = append(chains[chainID], span)
+		spanToChain[span] = chainID
+	}
+	heads := []*L7TracingSpan{}
+	tails := make(map[string]*L7TracingSpan) // span id -> tail of the chain
+	for _, key := range chainKeys {
+		chain := chains[key]
+		sort.SliceStable(chain, func(i, j int) bool {
+			return TAP_SIDE_ORDER[chain[i].TapSide] < TAP_SIDE_ORDER[chain[j].TapSide]
+		})
+		for i := 1; i < len(chain); i++ {
+			chain[i].parent = chain[i-1]
+		}
+		heads = append(heads, chain[0])
+		if tail := chain[len(chain)-1]; tail.SpanID != "" {
+			tails[tail.SpanID] = tail
+		}
+	}
+	for _, span := range spans {
+		if span.chainKey() == "" && !span.isApp() {
+			heads = append(heads, span)
+		}
+	}
+
+	appSpans := make(map[string]*L7TracingSpan) // span id -> app span
+	for _, span := range spans {
+		if span.isApp() && span.SpanID != "" {
+			if _, ok := appSpans[span.SpanID]; !ok || span.TapSide != "s-app" {
+				appSpans[span.SpanID] = span
+			}
+		}
+	}
+
+	for _, head := range heads {
+		if parent, ok := appSpans[head.SpanID]; ok && head.SpanID != "" && parent.TapSide != "s-app" {
+			head.parent = parent
+			continue
+		}
+		var parent *L7TracingSpan
+		for _, candidate := range spans {
+			if candidate == head || !candidate.isServerSide() || candidate.isApp() ||
+				candidate.StartTimeUs > head.StartTimeUs ||
+				(spanToChain[candidate] != "" && spanToChain[candidate] == spanToChain[head]) {
+				continue
+			}
+			matched := false
+			if candidate.TapSide == "s-p" && candidate.VtapID == head.VtapID &&
+				(candidate.hasSyscallTraceID(head.SyscallTraceIDRequest) || candidate.hasSyscallTraceID(head.SyscallTraceIDResponse)) {
+				matched = true
+			} else if candidate.hasXRequestID(head.XRequestID0) {
+				matched = true
+			}
+			// the latest started server span is the nearest parent
+			if matched && (parent == nil || candidate.StartTimeUs >= parent.StartTimeUs) {
+				parent = candidate
+			}
+		}
+		head.parent = parent
+	}
+
+	for _, span := range spans {
+		if !span.isApp() || span.ParentSpanID == "" {
+			continue
+		}
+		if tail, ok := tails[span.ParentSpanID]; ok {
+			span.parent = tail
+		} else if parent, ok := appSpans[span.ParentSpanID]; ok && parent != span {
+			span.parent = parent
+		}
+	}
+
+	// break the cycles caused by unexpected data
+	for _, span := range spans {
+		visited := map[*L7TracingSpan]bool{span: true}
+		for p := span.parent; p != nil; p = p.parent {
+			if visited[p] {
+				span.parent = nil
+				break
+			}
+			visited[p] = true
+		}
+	}
+}
+
+// BuildL7Tracing dedupes the spans and builds the span tree, the result is in the format of deepflow-app L7FlowTracing.
+// seqWindowUs is the max time difference of the spans of a request chained by tcp seq.
+func BuildL7Tracing(spans []*L7TracingSpan, seqWindowUs int64) map[string]interface{} {
+	spans = dedupeL7TracingSpans(spans)
+	setL7TracingParents(spans, seqWindowUs)
+
+	usedSpanIDs := make(map[string]bool)
+	for _, span := range spans {
+		span.deepflowSpanID = fmt.Sprintf("%016x", span.ID)
+		if span.isApp() && len(span.SpanID) == 16 && !usedSpanIDs[span.SpanID] {
+			if _, err := hex.DecodeString(span.SpanID); err == nil {
+				span.deepflowSpanID = span.SpanID
+			}
+		}
+		usedSpanIDs[span.deepflowSpanID] = true
+	}
+
+	services := []interface{}{}
+	serviceSet := make(map[string]bool)
+	tracing := make([]interface{}, 0, len(spans))
+	for _, span := range spans {
+		parentSpanID := ""
+		if span.parent != nil {
+			parentSpanID = span.parent.deepflowSpanID
+		}
+		trace := map[string]interface{}{
+			"_id":                       strconv.FormatUint(span.ID, 10),
+			"vtap_id":                   span.VtapID,
+			"tap_side":                  span.TapSide,
+			"Enum(tap_side)":            span.TapSideName,
+			"l7_protocol":               span.L7Protocol,
+			"l7_protocol_str":           span.L7ProtocolStr,
+			"start_time_us":             float64(span.StartTimeUs),
+			"end_time_us":               float64(span.EndTimeUs),
+			"duration":                  float64(span.EndTimeUs - span.StartTimeUs),
+			"flow_id":                   strconv.FormatUint(span.FlowID, 10),
+			"trace_id":                  span.TraceID,
+			"span_id":                   span.SpanID,
+			"parent_span_id":            span.ParentSpanID,
+			"x_request_id_0":            span.XRequestID0,
+			"x_request_id_1":            span.XRequestID1,
+			"syscall_trace_id_request":  strconv.FormatUint(span.SyscallTraceIDRequest, 10),
+			"syscall_trace_id_response": strconv.FormatUint(span.SyscallTraceIDResponse, 10),
+			"req_tcp_seq":               span.ReqTCPSeq,
+			"resp_tcp_seq":              span.RespTCPSeq,
+			"endpoint":                  span.Endpoint,
+			"request_type":              span.RequestType,
+			"request_resource":          span.RequestResource,
+			"response_status":           span.ResponseStatus,
+			"app_instance":              span.AppInstance,
+			"attributes":                span.Attributes,
+			"deepflow_span_id":          span.deepflowSpanID,
+			"deepflow_parent_span_id":   parentSpanID,
+		}
+		if service := span.service(); service != "" {
+			trace[L7_TRACING_SERVICE_UID] = service
+			trace[L7_TRACING_SERVICE_UNAME] = service
+			if !serviceSet[service] {
+				serviceSet[service] = true
+				services = append(services, map[string]interface{}{
+					L7_TRACING_SERVICE_UID:   service,
+					L7_TRACING_SERVICE_UNAME: service,
+				})
+			}
+		}
+		tracing = append(tracing, trace)
+	}
+	return map[string]interface{}{
+		"services": services,
+		"tracing":  tracing,
+	}
+}
+
+// L7FlowTracing assembles the trace from l7_flow_log in querier, the result is the same as L7TracingRequest
+func L7FlowTracing(args *common.TempoParams) (map[string]interface{}, error) {
+	timeEnd, err := strconv.ParseInt(args.EndTime, 10, 64)
+	if err != nil {
+		timeEnd = time.Now().Unix()
+	}
+	timeStart, err := strconv.ParseInt(args.StartTime, 10, 64)
+	if err != nil {
+		timeStart = timeEnd - L7_TRACING_DEFAULT_TIME_RANGE
+	}
+	limit := config.Cfg.L7Tracing.QueryLimit
+	query := func(filter string, start, end int64) ([]*L7TracingSpan, bool, error) {
+		sql := fmt.Sprintf("SELECT %s FROM %s WHERE time>=%d AND time<=%d AND (%s) ORDER BY start_time LIMIT %d",
+			strings.Join(L7_TRACING_FIELDS, ", "), TABLE_NAME_L7_FLOW_LOG, start, end, filter, limit)
+		querierArgs := common.QuerierParams{
+			DB:          "flow_log",
+			Sql:         sql,
+			DataSource:  "",
+			Debug:       "false",
+			QueryUUID:   uuid.New().String(),
+			Context:     args.Context,
+			ORGID:       args.ORGID,
+			BlockTeamID: args.BlockTeamID,
+		}
+		ckEngine := &clickhouse.CHEngine{DB: querierArgs.DB, DataSource: querierArgs.DataSource}
+		ckEngine.Init()
+		result, debug, err := ckEngine.ExecuteQuery(&querierArgs)
+		if err != nil {
+			log.Errorf("%v %v", debug, err)
+			return nil, false, err
+		}
+		return NewL7TracingSpans(result.Columns, result.Values), len(result.Values) >= limit, nil
+	}
+	seqWindowUs := int64(config.Cfg.L7Tracing.HostClockOffsetUs + config.Cfg.L7Tracing.NetworkDelayUs)
+	spans, truncated, err := searchL7TracingSpans(args.TraceId, timeStart, timeEnd, query, config.Cfg.L7Tracing.MaxIteration, seqWindowUs)
+	if err != nil {
+		return nil, err
+	}
+	if len(spans) == 0 {
+		return nil, nil
+	}
+	if truncated {
+		log.Warningf("trace (%s) is truncated with %d spans, increase query-limit or max-iteration of l7-tracing to get the full trace",
+			args.TraceId, len(spans))
+	}
+	data := BuildL7Tracing(spans, seqWindowUs)
+	data["truncated"] = truncated
+	return data, nil
+}
+
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	default:
+		return fmt.Sprint(s)
+	}
+}
+
+func toUint64(v interface{}) uint64 {
+	switch n := v.(type) {
+	case int:
+		return uint64(n)
+	case int64:
+		return uint64(n)
+	case uint64:
+		return n
+	case uint32:
+		return uint64(n)
+	case float64:
+		return uint64(n)
+	case string:
+		u, _ := strconv.ParseUint(n, 10, 64)
+		return u
+	}
+	return 0
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tempo
+
+import (
+	"strings"
+	"testing"
+)
+
+var l7TracingColumns = []interface{}{
+	"_id", "vtap_id", "tap_side", "signal_source", "start_time_us", "end_time_us", "trace_id", "span_id", "parent_span_id",
+	"syscall_trace_id_request", "syscall_trace_id_response", "req_tcp_seq", "resp_tcp_seq", "app_service", "process_kname_0", "process_kname_1",
+}
+
+// frontend app -> frontend process -> client nic -> server nic -> backend process -> backend app,
+// and the backend process calls mysql in the same thread
+var l7TracingRows = []interface{}{
+	[]interface{}{1, 1, "c-app", 4, 1000, 9000, "trace-1", "00000000000000a1", "", 0, 0, 0, 0, "frontend", "", ""},
+	[]interface{}{2, 1, "c-p", 3, 1100, 8900, "trace-1", "00000000000000a1", "", 11, 12, 100, 500, "", "frontend", ""},
+	[]interface{}{3, 1, "c", 0, 1200, 8800, "", "", "", 0, 0, 100, 500, "", "", ""},
+	[]interface{}{4, 2, "s", 0, 1300, 8700, "", "", "", 0, 0, 100, 500, "", "", ""},
+	[]interface{}{5, 2, "s-p", 3, 1400, 8600, "trace-1", "00000000000000a1", "", 21, 22, 100, 500, "", "", "backend"},
+	// captured repeatedly
+	[]interface{}{6, 2, "s-p", 3, 1400, 1500, "trace-1", "00000000000000a1", "", 21, 22, 100, 500, "", "", "backend"},
+	[]interface{}{7, 2, "s-app", 4, 1500, 8500, "trace-1", "00000000000000b1", "00000000000000a1", 0, 0, 0, 0, "backend", "", ""},
+	[]interface{}{8, 2, "c-p", 3, 2000, 3000, "", "", "", 21, 23, 200, 600, "", "backend", ""},
+	// another flow reusing the tcp seq long after the trace
+	[]interface{}{9, 3, "s", 0, 60001300, 60008700, "", "", "", 0, 0, 100, 900, "", "", ""},
+	// the response of another flow with the same seq as the request
+	[]interface{}{10, 3, "s", 0, 1300, 8700, "", "", "", 0, 0, 700, 100, "", "", ""},
+}
+
+const l7TracingSeqWindowUs = 60000
+
+func TestSearchL7TracingSpans(t *testing.T) {
+	spans := NewL7TracingSpans(l7TracingColumns, l7TracingRows)
+	if len(spans) != len(l7TracingRows) || spans[1].TapSide != "c-p" || spans[1].ReqTCPSeq != 100 || spans[1].ProcessKname0 != "frontend" {
+		t.Fatalf("wrong spans %+v", spans)
+	}
+	filters := []string{}
+	truncated := false
+	query := func(filter string, start, end int64) ([]*L7TracingSpan, bool, error) {
+		filters = append(filters, filter)
+		result := []*L7TracingSpan{}
+		for _, span := range spans {
+			// the query returns all spans of the seq regardless of time and direction
+			switch {
+			case strings.Contains(filter, "'trace-1'") && span.TraceID == "trace-1",
+				strings.Contains(filter, "req_tcp_seq=100 AND time>=0 AND time<=1") && (span.ReqTCPSeq == 100 || span.RespTCPSeq == 100),
+				strings.Contains(filter, "syscall_trace_id_request IN (11,12,21,22)") && span.SyscallTraceIDRequest == 21:
+				result = append(result, span)
+			}
+		}
+		return result, truncated, nil
+	}
+	result, truncatedResult, err := searchL7TracingSpans("trace-1", 0, 10, query, 30, l7TracingSeqWindowUs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[uint64]bool{}
+	for _, span := range result {
+		ids[span.ID] = true
+	}
+	if len(result) != 8 || ids[9] || ids[10] || truncatedResult {
+		t.Errorf("spans 1-8 should be found, but %d found, filters: %v", len(result), filters)
+	}
+	if len(filters) > 4 || filters[0] != "trace_id='trace-1'" {
+		t.Errorf("wrong filters %v", filters)
+	}
+
+	result, truncatedResult, _ = searchL7TracingSpans("trace-1", 0, 10, query, 1, l7TracingSeqWindowUs)
+	if len(result) == 8 || !truncatedResult {
+		t.Errorf("search should stop after max iteration and be truncated")
+	}
+	truncated = true
+	if _, truncatedResult, _ = searchL7TracingSpans("trace-1", 0, 10, query, 30, l7TracingSeqWindowUs); !truncatedResult {
+		t.Errorf("search should be truncated by query limit")
+	}
+}
+
+func TestBuildL7Tracing(t *testing.T) {
+	data := BuildL7Tracing(NewL7TracingSpans(l7TracingColumns, l7TracingRows), l7TracingSeqWindowUs)
+	tracing := data["tracing"].([]interface{})
+	if len(tracing) != 9 {
+		t.Fatalf("duplicate span should be removed, %d spans", len(tracing))
+	}
+	idToSpan := map[string]map[string]interface{}{}
+	for _, s := range tracing {
+		span := s.(map[string]interface{})
+		idToSpan[span["_id"].(string)] = span
+	}
+	if idToSpan["5"] == nil || idToSpan["6"] != nil {
+		t.Errorf("the span with longer duration should be kept")
+	}
+	// child _id -> parent _id
+	// span 9 of the same seq is not chained as it is far in time, nor span 10 with the seq as response
+	expected := map[string]string{"1": "", "2": "1", "3": "2", "4": "3", "5": "4", "7": "5", "8": "5", "9": "", "10": ""}
+	for id, parentID := range expected {
+		parentSpanID := ""
+		if parentID != "" {
+			parentSpanID = idToSpan[parentID]["deepflow_span_id"].(string)
+		}
+		if idToSpan[id]["deepflow_parent_span_id"] != parentSpanID {
+			t.Errorf("parent of span %s should be %s, but %s", id, parentID, idToSpan[id]["deepflow_parent_span_id"])
+		}
+	}
+	if idToSpan["1"]["deepflow_span_id"] != "00000000000000a1" || idToSpan["3"]["deepflow_span_id"] != "0000000000000003" {
+		t.Errorf("wrong span ids %s %s", idToSpan["1"]["deepflow_span_id"], idToSpan["3"]["deepflow_span_id"])
+	}
+	if _, ok := idToSpan["3"][L7_TRACING_SERVICE_UID]; ok {
+		t.Errorf("network span should not have service")
+	}
+	if services := data["services"].([]interface{}); len(services) != 2 {
+		t.Errorf("wrong services %v", services)
+	}
+
+	trace := ConvertL7TracingRespToProto(data, "trace-1")
+	spanCount := 0
+	for _, batch := range trace.Batches {
+		for _, il := range batch.InstrumentationLibrarySpans {
+			spanCount += len(il.Spans)
+		}
+	}
+	// app and process spans
+	if spanCount != 5 {
+		t.Errorf("wrong tempo span count %d", spanCount)
+	}
+}
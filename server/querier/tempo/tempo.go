@@ -94,6 +94,10 @@ func L7TracingRequest(args *common.TempoParams) (map[string]interface{}, error)
 		return nil, err
 	}
 	reqest.Header.Add("Content-Type", "application/json")
+	// deepflow-app queries querier with the same user, so that the same teams are blocked
+	reqest.Header.Add(common.HEADER_KEY_X_ORG_ID, args.ORGID)
+	reqest.Header.Add(common.HEADER_KEY_X_USER_TYPE, args.UserType)
+	reqest.Header.Add(common.HEADER_KEY_X_USER_ID, args.UserID)
 	response, err := client.Do(reqest)
 	if err != nil {
 		return nil, err
@@ -262,7 +266,12 @@ func ConvertL7TracingRespToProto(data map[string]interface{}, argTraceId string)
 
 func FindTraceByTraceID(args *common.TempoParams) (req *tempopb.Trace, err error) {
 	//return xxx(), err
-	data, err := L7TracingRequest(args)
+	var data map[string]interface{}
+	if config.Cfg.L7Tracing.Native {
+		data, err = L7FlowTracing(args)
+	} else {
+		data, err = L7TracingRequest(args)
+	}
 	if err != nil {
 		return req, err
 	}
@@ -286,12 +295,14 @@ func ShowTags(args *common.TempoParams) (resp map[string][]interface{}, debug ma
 	sql := fmt.Sprintf("show tags from %s", TABLE_NAME_L7_FLOW_LOG)
 	query_uuid := uuid.New()
 	querierArgs := common.QuerierParams{
-		DB:         "flow_log",
-		Sql:        sql,
-		DataSource: "",
-		Debug:      args.Debug,
-		QueryUUID:  query_uuid.String(),
-		Context:    args.Context,
+		DB:          "flow_log",
+		Sql:         sql,
+		DataSource:  "",
+		Debug:       args.Debug,
+		QueryUUID:   query_uuid.String(),
+		Context:     args.Context,
+		ORGID:       args.ORGID,
+		BlockTeamID: args.BlockTeamID,
 	}
 	ckEngine := &clickhouse.CHEngine{DB: querierArgs.DB, DataSource: querierArgs.DataSource}
 	ckEngine.Init()
@@ -323,12 +334,14 @@ func ShowTagValues(args *common.TempoParams) (resp map[string][]interface{}, deb
 	sql := fmt.Sprintf("show tag %s values from %s", tagName, TABLE_NAME_L7_FLOW_LOG)
 	query_uuid := uuid.New()
 	querierArgs := common.QuerierParams{
-		DB:         "flow_log",
-		Sql:        sql,
-		DataSource: "",
-		Debug:      args.Debug,
-		QueryUUID:  query_uuid.String(),
-		Context:    args.Context,
+		DB:          "flow_log",
+		Sql:         sql,
+		DataSource:  "",
+		Debug:       args.Debug,
+		QueryUUID:   query_uuid.String(),
+		Context:     args.Context,
+		ORGID:       args.ORGID,
+		BlockTeamID: args.BlockTeamID,
 	}
 	ckEngine := &clickhouse.CHEngine{DB: querierArgs.DB, DataSource: querierArgs.DataSource}
 	ckEngine.Init()
@@ -350,6 +363,9 @@ func ShowTagValues(args *common.TempoParams) (resp map[string][]interface{}, deb
 }
 
 func TraceSearch(args *common.TempoParams) (resp map[string]interface{}, debug map[string]interface{}, err error) {
+	if args.Query != "" {
+		return TraceQLSearch(args)
+	}
 	resp = map[string]interface{}{
 		"metrics": map[string]interface{}{
 			/* 			"inspectedBlocks": 1,
@@ -399,12 +415,14 @@ func TraceSearch(args *common.TempoParams) (resp map[string]interface{}, debug m
 
 	query_uuid := uuid.New()
 	querierArgs := common.QuerierParams{
-		DB:         "flow_log",
-		Sql:        sql,
-		DataSource: "",
-		Debug:      "false",
-		QueryUUID:  query_uuid.String(),
-		Context:    args.Context,
+		DB:          "flow_log",
+		Sql:         sql,
+		DataSource:  "",
+		Debug:       "false",
+		QueryUUID:   query_uuid.String(),
+		Context:     args.Context,
+		ORGID:       args.ORGID,
+		BlockTeamID: args.BlockTeamID,
 	}
 	ckEngine := &clickhouse.CHEngine{DB: querierArgs.DB, DataSource: querierArgs.DataSource}
 	ckEngine.Init()
@@ -0,0 +1,488 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tempo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// TraceQL subset supported by querier:
+//
+//	query      = spanset { "||" spanset }
+//	spanset    = structural { "&&" structural }
+//	structural = primary { ">>" primary }
+//	primary    = "{" [ field ] "}" | "(" query ")"
+//	field      = fieldAnd { "||" fieldAnd }
+//	fieldAnd   = fieldUnary { "&&" fieldUnary }
+//	fieldUnary = "(" field ")" | attribute op value
+//	op         = "=" | "!=" | ">" | ">=" | "<" | "<=" | "=~" | "!~"
+//
+// attributes are intrinsics (name, duration, status, kind), or span/resource attributes like
+// span.http.method, resource.service.name and .service.name.
+
+const (
+	TRACEQL_SCOPE_SPAN      = "span"
+	TRACEQL_SCOPE_RESOURCE  = "resource"
+	TRACEQL_SCOPE_INTRINSIC = "intrinsic"
+
+	TRACEQL_INTRINSIC_NAME     = "name"
+	TRACEQL_INTRINSIC_DURATION = "duration"
+	TRACEQL_INTRINSIC_STATUS   = "status"
+	TRACEQL_INTRINSIC_KIND     = "kind"
+)
+
+var TRACEQL_INTRINSICS = []string{TRACEQL_INTRINSIC_NAME, TRACEQL_INTRINSIC_DURATION, TRACEQL_INTRINSIC_STATUS, TRACEQL_INTRINSIC_KIND}
+
+// span and resource attributes stored in the native columns of l7_flow_log
+var TRACEQL_ATTRIBUTE_FIELDS = map[string]string{
+	"service.name":        L7_FLOW_LOG_SERVICE_NAME,
+	"service.instance.id": "app_instance",
+	"http.method":         "request_type",
+	"http.status_code":    "response_code",
+}
+
+var TRACEQL_RESOURCE_ATTRIBUTES = []string{"service.name", "service.instance.id"}
+
+// status value -> response_status values
+var TRACEQL_STATUS_VALUES = map[string][]string{
+	"ok":    {"0"},
+	"unset": {"2"},
+	"error": {"3", "4"},
+}
+
+// kind value -> tap_side values
+var TRACEQL_KIND_VALUES = map[string][]string{
+	"server":      {"'s-app'", "'s-p'", "'s'", "'s-nd'", "'s-hv'", "'s-gw-hv'", "'s-gw'"},
+	"client":      {"'c-app'", "'c-p'", "'c'", "'c-nd'", "'c-hv'", "'c-gw-hv'", "'c-gw'"},
+	"unspecified": {"'app'", "'local'", "'rest'"},
+}
+
+type traceQLTokenType int
+
+const (
+	traceQLTokenEOF traceQLTokenType = iota
+	traceQLTokenOperator
+	traceQLTokenIdent
+	traceQLTokenString
+	traceQLTokenNumber
+	traceQLTokenDuration
+)
+
+type traceQLToken struct {
+	typ   traceQLTokenType
+	value string
+	pos   int
+}
+
+// operators sorted by length so that the longest one is matched first
+var traceQLOperators = []string{"&&", "||", ">>", "!=", ">=", "<=", "=~", "!~", "{", "}", "(", ")", "=", ">", "<", "|"}
+
+func traceQLTokenize(query string) ([]traceQLToken, error) {
+	tokens := []traceQLToken{}
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '`':
+			end := i + 1
+			var value strings.Builder
+			for ; end < len(runes) && runes[end] != r; end++ {
+				if runes[end] == '\\' && r == '"' && end+1 < len(runes) {
+					end++
+				}
+				value.WriteRune(runes[end])
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, traceQLToken{traceQLTokenString, value.String(), i})
+			i = end + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			end := i + 1
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			typ := traceQLTokenNumber
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || runes[end] == 'µ') {
+				typ = traceQLTokenDuration
+				end++
+			}
+			tokens = append(tokens, traceQLToken{typ, string(runes[i:end]), i})
+			i = end
+		case unicode.IsLetter(r) || r == '.' || r == '_':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || strings.ContainsRune("._-/:", runes[end])) {
+				end++
+			}
+			tokens = append(tokens, traceQLToken{traceQLTokenIdent, string(runes[i:end]), i})
+			i = end
+		default:
+			matched := false
+			for _, op := range traceQLOperators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, traceQLToken{traceQLTokenOperator, op, i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at %d", r, i)
+			}
+		}
+	}
+	return append(tokens, traceQLToken{typ: traceQLTokenEOF, pos: len(runes)}), nil
+}
+
+// TraceQLSpanset is a spanset filter or an operation on spansets
+type TraceQLSpanset struct {
+	// Op is one of "&&", "||" and ">>", Filter is set if Op is empty
+	Op          string
+	Left, Right *TraceQLSpanset
+	Filter      *TraceQLField // nil matches all spans
+}
+
+// TraceQLField is a comparison or a logical operation on field expressions
+type TraceQLField struct {
+	// Op is one of "&&" and "||" for logical operations
+	Op          string
+	Left, Right *TraceQLField
+
+	Comparison *TraceQLComparison
+}
+
+type TraceQLComparison struct {
+	Scope     string // span, resource or intrinsic, empty for the unscoped attribute
+	Attribute string
+	Op        string
+	Value     traceQLToken
+}
+
+type traceQLParser struct {
+	tokens []traceQLToken
+	pos    int
+}
+
+func ParseTraceQL(query string) (*TraceQLSpanset, error) {
+	tokens, err := traceQLTokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &traceQLParser{tokens: tokens}
+	spanset, err := p.parseSpansetOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := p.peek(); token.typ != traceQLTokenEOF {
+		if token.value == "|" {
+			return nil, fmt.Errorf("pipeline at %d is not supported", token.pos)
+		}
+		return nil, fmt.Errorf("unexpected %q at %d", token.value, token.pos)
+	}
+	return spanset, nil
+}
+
+func (p *traceQLParser) peek() traceQLToken {
+	return p.tokens[p.pos]
+}
+
+func (p *traceQLParser) next() traceQLToken {
+	token := p.tokens[p.pos]
+	if token.typ != traceQLTokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *traceQLParser) acceptOperator(op string) bool {
+	if token := p.peek(); token.typ == traceQLTokenOperator && token.value == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *traceQLParser) expectOperator(op string) error {
+	if !p.acceptOperator(op) {
+		token := p.peek()
+		return fmt.Errorf("expect %q but got %q at %d", op, token.value, token.pos)
+	}
+	return nil
+}
+
+func (p *traceQLParser) parseSpansetOr() (*TraceQLSpanset, error) {
+	left, err := p.parseSpansetAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator("||") {
+		right, err := p.parseSpansetAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &TraceQLSpanset{Op: "||", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *traceQLParser) parseSpansetAnd() (*TraceQLSpanset, error) {
+	left, err := p.parseStructural()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator("&&") {
+		right, err := p.parseStructural()
+		if err != nil {
+			return nil, err
+		}
+		left = &TraceQLSpanset{Op: "&&", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *traceQLParser) parseStructural() (*TraceQLSpanset, error) {
+	left, err := p.parseSpansetPrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator(">>") {
+		right, err := p.parseSpansetPrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = &TraceQLSpanset{Op: ">>", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *traceQLParser) parseSpansetPrimary() (*TraceQLSpanset, error) {
+	if p.acceptOperator("(") {
+		spanset, err := p.parseSpansetOr()
+		if err != nil {
+			return nil, err
+		}
+		return spanset, p.expectOperator(")")
+	}
+	if err := p.expectOperator("{"); err != nil {
+		return nil, err
+	}
+	if p.acceptOperator("}") {
+		return &TraceQLSpanset{}, nil
+	}
+	field, err := p.parseFieldOr()
+	if err != nil {
+		return nil, err
+	}
+	return &TraceQLSpanset{Filter: field}, p.expectOperator("}")
+}
+
+func (p *traceQLParser) parseFieldOr() (*TraceQLField, error) {
+	left, err := p.parseFieldAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator("||") {
+		right, err := p.parseFieldAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &TraceQLField{Op: "||", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *traceQLParser) parseFieldAnd() (*TraceQLField, error) {
+	left, err := p.parseFieldUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator("&&") {
+		right, err := p.parseFieldUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &TraceQLField{Op: "&&", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *traceQLParser) parseFieldUnary() (*TraceQLField, error) {
+	if p.acceptOperator("(") {
+		field, err := p.parseFieldOr()
+		if err != nil {
+			return nil, err
+		}
+		return field, p.expectOperator(")")
+	}
+	token := p.next()
+	if token.typ != traceQLTokenIdent {
+		return nil, fmt.Errorf("expect attribute but got %q at %d", token.value, token.pos)
+	}
+	comparison := &TraceQLComparison{}
+	comparison.Scope, comparison.Attribute = splitTraceQLAttribute(token.value)
+	op := p.next()
+	switch op.value {
+	case "=", "!=", ">", ">=", "<", "<=", "=~", "!~":
+		comparison.Op = op.value
+	default:
+		return nil, fmt.Errorf("expect comparison operator but got %q at %d", op.value, op.pos)
+	}
+	value := p.next()
+	switch value.typ {
+	case traceQLTokenString, traceQLTokenNumber, traceQLTokenDuration, traceQLTokenIdent:
+		comparison.Value = value
+	default:
+		return nil, fmt.Errorf("expect value but got %q at %d", value.value, value.pos)
+	}
+	return &TraceQLField{Comparison: comparison}, nil
+}
+
+// splitTraceQLAttribute returns the scope and the attribute name
+func splitTraceQLAttribute(name string) (string, string) {
+	for _, scope := range []string{TRACEQL_SCOPE_SPAN, TRACEQL_SCOPE_RESOURCE} {
+		if strings.HasPrefix(name, scope+".") {
+			return scope, strings.TrimPrefix(name, scope+".")
+		}
+	}
+	if strings.HasPrefix(name, ".") {
+		return "", strings.TrimPrefix(name, ".")
+	}
+	return TRACEQL_SCOPE_INTRINSIC, name
+}
+
+// TraceQLAttributeToField returns the l7_flow_log tag of the attribute
+func TraceQLAttributeToField(scope, attribute string) (string, error) {
+	if scope == TRACEQL_SCOPE_INTRINSIC {
+		switch attribute {
+		case TRACEQL_INTRINSIC_NAME:
+			return L7_TRACING_ENDPOINT, nil
+		case TRACEQL_INTRINSIC_DURATION:
+			return "response_duration", nil
+		case TRACEQL_INTRINSIC_STATUS:
+			return "response_status", nil
+		case TRACEQL_INTRINSIC_KIND:
+			return "tap_side", nil
+		}
+		return "", fmt.Errorf("intrinsic %s is not supported", attribute)
+	}
+	if field, ok := TRACEQL_ATTRIBUTE_FIELDS[attribute]; ok {
+		return field, nil
+	}
+	return fmt.Sprintf("`attribute.%s`", attribute), nil
+}
+
+// ToSQL translates the field expression to the where clause of l7_flow_log
+func (f *TraceQLField) ToSQL() (string, error) {
+	if f.Comparison != nil {
+		return f.Comparison.toSQL()
+	}
+	left, err := f.Left.ToSQL()
+	if err != nil {
+		return "", err
+	}
+	right, err := f.Right.ToSQL()
+	if err != nil {
+		return "", err
+	}
+	op := "AND"
+	if f.Op == "||" {
+		op = "OR"
+	}
+	return fmt.Sprintf("(%s %s %s)", left, op, right), nil
+}
+
+func (c *TraceQLComparison) toSQL() (string, error) {
+	field, err := TraceQLAttributeToField(c.Scope, c.Attribute)
+	if err != nil {
+		return "", err
+	}
+	if c.Scope == TRACEQL_SCOPE_INTRINSIC {
+		switch c.Attribute {
+		case TRACEQL_INTRINSIC_DURATION:
+			if c.Op == "=~" || c.Op == "!~" {
+				return "", fmt.Errorf("operator %s is not supported by duration", c.Op)
+			}
+			duration, err := parseTraceQLDuration(c.Value)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s %s %d", field, c.Op, duration.Microseconds()), nil
+		case TRACEQL_INTRINSIC_STATUS:
+			return traceQLEnumToSQL(field, c.Op, c.Value.value, TRACEQL_STATUS_VALUES)
+		case TRACEQL_INTRINSIC_KIND:
+			return traceQLEnumToSQL(field, c.Op, c.Value.value, TRACEQL_KIND_VALUES)
+		}
+	}
+
+	value := c.Value.value
+	switch c.Op {
+	case "=~", "!~":
+		op := "regexp"
+		if c.Op == "!~" {
+			op = "not regexp"
+		}
+		// regular expressions of TraceQL match the whole value
+		return fmt.Sprintf("%s %s %s", field, op, quoteString("^(?:"+value+")$")), nil
+	}
+	if c.Value.typ == traceQLTokenNumber {
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("invalid number %s", value)
+		}
+		if !strings.HasPrefix(field, "`attribute.") {
+			return fmt.Sprintf("%s %s %s", field, c.Op, value), nil
+		}
+		switch c.Op {
+		case "<", "<=", ">", ">=":
+			// attribute values are strings, compare them as numbers, values which are not numbers are not matched
+			return fmt.Sprintf("toFloat64OrNull(%s) %s %s", field, c.Op, value), nil
+		}
+	}
+	// attribute values are strings
+	return fmt.Sprintf("%s %s %s", field, c.Op, quoteString(value)), nil
+}
+
+func traceQLEnumToSQL(field, op, value string, enumValues map[string][]string) (string, error) {
+	values, ok := enumValues[value]
+	if !ok {
+		return "", fmt.Errorf("invalid value %s of %s", value, field)
+	}
+	switch op {
+	case "=":
+		return fmt.Sprintf("%s IN (%s)", field, strings.Join(values, ",")), nil
+	case "!=":
+		return fmt.Sprintf("%s NOT IN (%s)", field, strings.Join(values, ",")), nil
+	}
+	return "", fmt.Errorf("operator %s is not supported by %s", op, field)
+}
+
+func parseTraceQLDuration(token traceQLToken) (time.Duration, error) {
+	switch token.typ {
+	case traceQLTokenDuration:
+		return time.ParseDuration(token.value)
+	case traceQLTokenNumber:
+		// duration without unit is in seconds
+		seconds, err := strconv.ParseFloat(token.value, 64)
+		return time.Duration(seconds * float64(time.Second)), err
+	}
+	return 0, fmt.Errorf("invalid duration %s", token.value)
+}
@@ -0,0 +1,490 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tempo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse"
+)
+
+const (
+	TRACEQL_DEFAULT_LIMIT = 20
+	// spans of a trace returned in spanSet
+	TRACEQL_SPANSET_LIMIT = 3
+)
+
+var TRACEQL_SPAN_FIELDS = []string{
+	"_id", "trace_id", "span_id", "parent_span_id", "toUnixTimestamp64Micro(start_time) AS start_time_us",
+	"toUnixTimestamp64Micro(end_time) AS end_time_us", "app_service", "endpoint",
+}
+
+// TraceQLSpan is a row of l7_flow_log matched by TraceQL
+type TraceQLSpan struct {
+	ID           uint64
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	StartTimeUs  int64
+	EndTimeUs    int64
+	Service      string
+	Name         string
+}
+
+func NewTraceQLSpans(columns []interface{}, values []interface{}) []*TraceQLSpan {
+	columnIndex := map[string]int{}
+	for i, column := range columns {
+		columnIndex[toString(column)] = i
+	}
+	get := func(row []interface{}, name string) interface{} {
+		if i, ok := columnIndex[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return nil
+	}
+	spans := make([]*TraceQLSpan, 0, len(values))
+	for _, v := range values {
+		row, ok := v.([]interface{})
+		if !ok {
+			continue
+		}
+		spans = append(spans, &TraceQLSpan{
+			ID:           toUint64(get(row, "_id")),
+			TraceID:      toString(get(row, "trace_id")),
+			SpanID:       toString(get(row, "span_id")),
+			ParentSpanID: toString(get(row, "parent_span_id")),
+			StartTimeUs:  int64(toUint64(get(row, "start_time_us"))),
+			EndTimeUs:    int64(toUint64(get(row, "end_time_us"))),
+			Service:      toString(get(row, L7_FLOW_LOG_SERVICE_NAME)),
+			Name:         toString(get(row, L7_TRACING_ENDPOINT)),
+		})
+	}
+	return spans
+}
+
+// spanID returns span_id, or the id generated from _id for spans without span_id like BuildL7Tracing
+func (s *TraceQLSpan) spanID() string {
+	if s.SpanID != "" {
+		return s.SpanID
+	}
+	return fmt.Sprintf("%016x", s.ID)
+}
+
+// traceQLQuerier returns spans of l7_flow_log matched by the filter,
+// truncated is true if the spans are truncated by the query limit
+type traceQLQuerier func(filter string) (spans []*TraceQLSpan, truncated bool, err error)
+
+// traceQLSpansets is the matched spans of each trace
+type traceQLSpansets map[string][]*TraceQLSpan
+
+func groupTraceQLSpans(spans []*TraceQLSpan) traceQLSpansets {
+	spansets := traceQLSpansets{}
+	for _, span := range spans {
+		if span.TraceID == "" {
+			continue
+		}
+		spansets[span.TraceID] = append(spansets[span.TraceID], span)
+	}
+	return spansets
+}
+
+func traceIDsFilter(traceIDs []string) string {
+	quoted := make([]string, 0, len(traceIDs))
+	for _, traceID := range traceIDs {
+		quoted = append(quoted, quoteString(traceID))
+	}
+	return fmt.Sprintf("trace_id IN (%s)", strings.Join(quoted, ","))
+}
+
+func sortedTraceIDs(spansets traceQLSpansets) []string {
+	traceIDs := make([]string, 0, len(spansets))
+	for traceID := range spansets {
+		traceIDs = append(traceIDs, traceID)
+	}
+	sort.Strings(traceIDs)
+	return traceIDs
+}
+
+func appendUniqueSpans(spans []*TraceQLSpan, others []*TraceQLSpan) []*TraceQLSpan {
+	ids := make(map[uint64]bool, len(spans))
+	for _, span := range spans {
+		ids[span.ID] = true
+	}
+	for _, span := range others {
+		if !ids[span.ID] {
+			ids[span.ID] = true
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// evaluateTraceQL returns the spans matched by the spanset expression, grouped by trace.
+// truncated is true if any query is truncated by the query limit, the result may miss some matched traces.
+func evaluateTraceQL(spanset *TraceQLSpanset, query traceQLQuerier) (traceQLSpansets, bool, error) {
+	if spanset.Op == "" {
+		filter := "trace_id != ''"
+		if spanset.Filter != nil {
+			sql, err := spanset.Filter.ToSQL()
+			if err != nil {
+				return nil, false, err
+			}
+			filter = fmt.Sprintf("%s AND %s", filter, sql)
+		}
+		spans, truncated, err := query(filter)
+		if err != nil {
+			return nil, false, err
+		}
+		return groupTraceQLSpans(spans), truncated, nil
+	}
+
+	left, leftTruncated, err := evaluateTraceQL(spanset.Left, query)
+	if err != nil {
+		return nil, false, err
+	}
+	right, rightTruncated, err := evaluateTraceQL(spanset.Right, query)
+	if err != nil {
+		return nil, false, err
+	}
+	truncated := leftTruncated || rightTruncated
+	result := traceQLSpansets{}
+	switch spanset.Op {
+	case "||":
+		for traceID, spans := range left {
+			result[traceID] = spans
+		}
+		for traceID, spans := range right {
+			result[traceID] = appendUniqueSpans(result[traceID], spans)
+		}
+	case "&&":
+		for traceID, spans := range left {
+			if rightSpans, ok := right[traceID]; ok {
+				result[traceID] = appendUniqueSpans(spans, rightSpans)
+			}
+		}
+	case ">>":
+		candidates := traceQLSpansets{}
+		for traceID := range left {
+			if _, ok := right[traceID]; ok {
+				candidates[traceID] = nil
+			}
+		}
+		if len(candidates) == 0 {
+			return result, truncated, nil
+		}
+		spans, spansTruncated, err := query(traceIDsFilter(sortedTraceIDs(candidates)))
+		if err != nil {
+			return nil, false, err
+		}
+		truncated = truncated || spansTruncated
+		for traceID, traceSpans := range groupTraceQLSpans(spans) {
+			if _, ok := candidates[traceID]; !ok {
+				continue
+			}
+			if descendants := traceQLDescendants(traceSpans, left[traceID], right[traceID]); len(descendants) > 0 {
+				result[traceID] = descendants
+			}
+		}
+	default:
+		return nil, false, fmt.Errorf("unknown spanset operator %s", spanset.Op)
+	}
+	return result, truncated, nil
+}
+
+// traceQLDescendants returns spans of descendants which have an ancestor in ancestors
+func traceQLDescendants(traceSpans, ancestors, descendants []*TraceQLSpan) []*TraceQLSpan {
+	// spans of different tap sides share the same span_id
+	parents := map[string]string{}
+	for _, span := range traceSpans {
+		if span.SpanID != "" && span.ParentSpanID != "" && span.ParentSpanID != span.SpanID {
+			parents[span.SpanID] = span.ParentSpanID
+		}
+	}
+	ancestorIDs := map[string]bool{}
+	for _, span := range ancestors {
+		if span.SpanID != "" {
+			ancestorIDs[span.SpanID] = true
+		}
+	}
+	result := []*TraceQLSpan{}
+	for _, span := range descendants {
+		visited := map[string]bool{span.SpanID: true}
+		for parent := span.ParentSpanID; parent != "" && !visited[parent]; parent = parents[parent] {
+			if ancestorIDs[parent] {
+				result = append(result, span)
+				break
+			}
+			visited[parent] = true
+		}
+	}
+	return result
+}
+
+// buildTraceQLTraces returns the traces in the format of tempo search api, latest traces first
+func buildTraceQLTraces(spansets traceQLSpansets, traceSpans traceQLSpansets) []map[string]interface{} {
+	type traceQLTrace struct {
+		startTimeUs int64
+		value       map[string]interface{}
+	}
+	traces := []traceQLTrace{}
+	for _, traceID := range sortedTraceIDs(spansets) {
+		spans := traceSpans[traceID]
+		if len(spans) == 0 {
+			spans = spansets[traceID]
+		}
+		// root is the earliest span without parent, or the earliest span if the root span is not found
+		var root, earliest *TraceQLSpan
+		endTimeUs := int64(0)
+		for _, span := range spans {
+			if earliest == nil || span.StartTimeUs < earliest.StartTimeUs {
+				earliest = span
+			}
+			if span.ParentSpanID == "" && (root == nil || span.StartTimeUs < root.StartTimeUs) {
+				root = span
+			}
+			if span.EndTimeUs > endTimeUs {
+				endTimeUs = span.EndTimeUs
+			}
+		}
+		if root == nil {
+			root = earliest
+		}
+
+		matched := spansets[traceID]
+		sort.Slice(matched, func(i, j int) bool { return matched[i].StartTimeUs < matched[j].StartTimeUs })
+		spansetSpans := []map[string]interface{}{}
+		for i, span := range matched {
+			if i >= TRACEQL_SPANSET_LIMIT {
+				break
+			}
+			spansetSpans = append(spansetSpans, map[string]interface{}{
+				"spanID":            span.spanID(),
+				"startTimeUnixNano": strconv.FormatInt(span.StartTimeUs*1000, 10),
+				"durationNanos":     strconv.FormatInt((span.EndTimeUs-span.StartTimeUs)*1000, 10),
+			})
+		}
+		traces = append(traces, traceQLTrace{
+			startTimeUs: earliest.StartTimeUs,
+			value: map[string]interface{}{
+				"traceID":           traceID,
+				"rootServiceName":   root.Service,
+				"rootTraceName":     root.Name,
+				"startTimeUnixNano": strconv.FormatInt(earliest.StartTimeUs*1000, 10),
+				"durationMs":        (endTimeUs - earliest.StartTimeUs) / 1000,
+				"spanSet": map[string]interface{}{
+					"spans":   spansetSpans,
+					"matched": len(matched),
+				},
+			},
+		})
+	}
+	sort.SliceStable(traces, func(i, j int) bool { return traces[i].startTimeUs > traces[j].startTimeUs })
+	result := make([]map[string]interface{}, 0, len(traces))
+	for _, trace := range traces {
+		result = append(result, trace.value)
+	}
+	return result
+}
+
+// searchTraceQL evaluates the query, and returns at most limit traces with the latest start time.
+// truncated is true if any query is truncated by the query limit, the traces may be incomplete or missed, and
+// the root span and duration may be calculated with part of the spans.
+func searchTraceQL(spanset *TraceQLSpanset, query traceQLQuerier, limit int, minDuration, maxDuration time.Duration) (
+	traces []map[string]interface{}, truncated bool, err error) {
+	spansets, truncated, err := evaluateTraceQL(spanset, query)
+	if err != nil {
+		return nil, false, err
+	}
+	traces = []map[string]interface{}{}
+	if len(spansets) == 0 {
+		return traces, truncated, nil
+	}
+	// root span and duration are calculated with all spans of the trace
+	spans, spansTruncated, err := query(traceIDsFilter(sortedTraceIDs(spansets)))
+	if err != nil {
+		return nil, false, err
+	}
+	truncated = truncated || spansTruncated
+	for _, trace := range buildTraceQLTraces(spansets, groupTraceQLSpans(spans)) {
+		durationMs := time.Duration(trace["durationMs"].(int64)) * time.Millisecond
+		if (minDuration > 0 && durationMs < minDuration) || (maxDuration > 0 && durationMs > maxDuration) {
+			continue
+		}
+		traces = append(traces, trace)
+		if len(traces) >= limit {
+			break
+		}
+	}
+	return traces, truncated, nil
+}
+
+// TraceQLSearch searches traces with the TraceQL query in args.Query
+func TraceQLSearch(args *common.TempoParams) (resp map[string]interface{}, debug map[string]interface{}, err error) {
+	spanset, err := ParseTraceQL(args.Query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid TraceQL %s: %s", args.Query, err)
+	}
+	limit := TRACEQL_DEFAULT_LIMIT
+	if args.Limit != "" {
+		if limit, err = strconv.Atoi(args.Limit); err != nil {
+			return nil, nil, err
+		}
+		if limit <= 0 {
+			return nil, nil, fmt.Errorf("invalid limit %d, should be greater than 0", limit)
+		}
+	}
+	var minDuration, maxDuration time.Duration
+	if args.MinDuration != "" {
+		if minDuration, err = time.ParseDuration(args.MinDuration); err != nil {
+			return nil, nil, err
+		}
+	}
+	if args.MaxDuration != "" {
+		if maxDuration, err = time.ParseDuration(args.MaxDuration); err != nil {
+			return nil, nil, err
+		}
+	}
+	timeFilters := []string{}
+	if args.StartTime != "" {
+		timeFilters = append(timeFilters, fmt.Sprintf("time>=%s", args.StartTime))
+	}
+	if args.EndTime != "" {
+		timeFilters = append(timeFilters, fmt.Sprintf("time<=%s", args.EndTime))
+	}
+	queryLimit := config.Cfg.L7Tracing.QueryLimit
+	query := func(filter string) ([]*TraceQLSpan, bool, error) {
+		filters := append([]string{filter}, timeFilters...)
+		sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY start_time DESC LIMIT %d",
+			strings.Join(TRACEQL_SPAN_FIELDS, ", "), TABLE_NAME_L7_FLOW_LOG, strings.Join(filters, " AND "), queryLimit)
+		querierArgs := common.QuerierParams{
+			DB:          "flow_log",
+			Sql:         sql,
+			DataSource:  "",
+			Debug:       "false",
+			QueryUUID:   uuid.New().String(),
+			Context:     args.Context,
+			ORGID:       args.ORGID,
+			BlockTeamID: args.BlockTeamID,
+		}
+		ckEngine := &clickhouse.CHEngine{DB: querierArgs.DB, DataSource: querierArgs.DataSource}
+		ckEngine.Init()
+		result, debug, err := ckEngine.ExecuteQuery(&querierArgs)
+		if err != nil {
+			log.Errorf("%v %v", debug, err)
+			return nil, false, err
+		}
+		return NewTraceQLSpans(result.Columns, result.Values), len(result.Values) >= queryLimit, nil
+	}
+	traces, truncated, err := searchTraceQL(spanset, query, limit, minDuration, maxDuration)
+	if err != nil {
+		return nil, nil, err
+	}
+	if truncated {
+		log.Warningf("traces of TraceQL (%s) are truncated, increase query-limit of l7-tracing or narrow the time range to get all traces",
+			args.Query)
+	}
+	resp = map[string]interface{}{
+		"metrics":   map[string]interface{}{},
+		"traces":    traces,
+		"truncated": truncated,
+	}
+	return resp, nil, nil
+}
+
+// TraceQLTags returns the attributes of each scope for the tempo v2 tags api
+func TraceQLTags(args *common.TempoParams) (resp map[string]interface{}, debug map[string]interface{}, err error) {
+	result, debug, err := ShowTags(args)
+	if err != nil {
+		return nil, debug, err
+	}
+	spanTags := []string{}
+	for attribute := range TRACEQL_ATTRIBUTE_FIELDS {
+		spanTags = append(spanTags, attribute)
+	}
+	sort.Strings(spanTags)
+	for _, tagName := range result["tagNames"] {
+		spanTags = append(spanTags, strings.TrimPrefix(toString(tagName), "attribute."))
+	}
+	resp = map[string]interface{}{
+		"scopes": []map[string]interface{}{
+			{"name": TRACEQL_SCOPE_SPAN, "tags": spanTags},
+			{"name": TRACEQL_SCOPE_RESOURCE, "tags": TRACEQL_RESOURCE_ATTRIBUTES},
+			{"name": TRACEQL_SCOPE_INTRINSIC, "tags": TRACEQL_INTRINSICS},
+		},
+	}
+	return resp, debug, nil
+}
+
+// TraceQLTagValues returns values of the attribute in args.TagName for the tempo v2 tag values api,
+// the attribute is scoped like span.http.method
+func TraceQLTagValues(args *common.TempoParams) (resp map[string]interface{}, debug map[string]interface{}, err error) {
+	tagValues := []map[string]interface{}{}
+	resp = map[string]interface{}{"tagValues": tagValues}
+	scope, attribute := splitTraceQLAttribute(args.TagName)
+	if scope == TRACEQL_SCOPE_INTRINSIC {
+		enumValues := map[string][]string{}
+		switch attribute {
+		case TRACEQL_INTRINSIC_STATUS:
+			enumValues = TRACEQL_STATUS_VALUES
+		case TRACEQL_INTRINSIC_KIND:
+			enumValues = TRACEQL_KIND_VALUES
+		case TRACEQL_INTRINSIC_DURATION:
+			return resp, nil, nil
+		}
+		if len(enumValues) > 0 {
+			values := []string{}
+			for value := range enumValues {
+				values = append(values, value)
+			}
+			sort.Strings(values)
+			for _, value := range values {
+				tagValues = append(tagValues, map[string]interface{}{"type": "keyword", "value": value})
+			}
+			resp["tagValues"] = tagValues
+			return resp, nil, nil
+		}
+	}
+
+	field, err := TraceQLAttributeToField(scope, attribute)
+	if err != nil {
+		return nil, nil, err
+	}
+	tagValuesArgs := *args
+	tagValuesArgs.TagName = strings.Trim(field, "`")
+	result, debug, err := ShowTagValues(&tagValuesArgs)
+	if err != nil {
+		return nil, debug, err
+	}
+	for _, value := range result["tagValues"] {
+		valueType := "string"
+		switch value.(type) {
+		case int, int64, uint64, float64:
+			valueType = "int"
+		}
+		tagValues = append(tagValues, map[string]interface{}{"type": valueType, "value": value})
+	}
+	resp["tagValues"] = tagValues
+	return resp, debug, nil
+}
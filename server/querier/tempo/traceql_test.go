@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tempo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTraceQLToSQL(t *testing.T) {
+	cases := []struct {
+		query string
+		sql   string
+	}{
+		{`{ .service.name = "frontend" }`, "app_service = 'frontend'"},
+		{`{ resource.service.name != "frontend" && duration > 100ms }`, "(app_service != 'frontend' AND response_duration > 100000)"},
+		{`{ span.http.status_code >= 500 || status = error }`, "(response_code >= 500 OR response_status IN (3,4))"},
+		{`{ name =~ "GET /api/.*" }`, "endpoint regexp '^(?:GET /api/.*)$'"},
+		{`{ span.db.system !~ "my'sql" && kind = server }`, "(`attribute.db.system` not regexp '^(?:my\\'sql)$' AND tap_side IN ('s-app','s-p','s','s-nd','s-hv','s-gw-hv','s-gw'))"},
+		{`{ span.peer.port = 3306 && (status != ok || duration < 1.5) }`, "(`attribute.peer.port` = '3306' AND (response_status NOT IN (0) OR response_duration < 1500000))"},
+		{`{ span.peer.port >= 3000 && span.peer.port < 3306.5 }`, "(toFloat64OrNull(`attribute.peer.port`) >= 3000 AND toFloat64OrNull(`attribute.peer.port`) < 3306.5)"},
+	}
+	for _, c := range cases {
+		spanset, err := ParseTraceQL(c.query)
+		if err != nil {
+			t.Errorf("parse %s failed: %v", c.query, err)
+			continue
+		}
+		sql, err := spanset.Filter.ToSQL()
+		if err != nil || sql != c.sql {
+			t.Errorf("sql of %s should be %s, but %s, err %v", c.query, c.sql, sql, err)
+		}
+	}
+
+	spanset, err := ParseTraceQL(`{ .a = "1" } && { .b = "2" } >> { .c = "3" } || {}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spanset.Op != "||" || spanset.Left.Op != "&&" || spanset.Left.Right.Op != ">>" || spanset.Right.Filter != nil {
+		t.Errorf("wrong precedence of spanset operators")
+	}
+
+	for _, query := range []string{`{ .a = }`, `{ .a = "1"`, `{ .a = "1" } | count() > 1`, `{ status = failed }`, `{ duration =~ "1s" }`} {
+		spanset, err := ParseTraceQL(query)
+		if err == nil {
+			_, err = spanset.Filter.ToSQL()
+		}
+		if err == nil {
+			t.Errorf("%s should be invalid", query)
+		}
+	}
+}
+
+// trace-1: frontend -> backend -> mysql, trace-2: backend
+var traceQLSpans = []*TraceQLSpan{
+	{ID: 1, TraceID: "trace-1", SpanID: "a1", StartTimeUs: 1000, EndTimeUs: 9000, Service: "frontend", Name: "GET /"},
+	{ID: 2, TraceID: "trace-1", SpanID: "b1", ParentSpanID: "a1", StartTimeUs: 2000, EndTimeUs: 8000, Service: "backend", Name: "GET /api"},
+	{ID: 3, TraceID: "trace-1", SpanID: "c1", ParentSpanID: "b1", StartTimeUs: 3000, EndTimeUs: 4000, Service: "mysql", Name: "SELECT"},
+	{ID: 4, TraceID: "trace-2", SpanID: "b2", StartTimeUs: 20000, EndTimeUs: 21000, Service: "backend", Name: "GET /api"},
+}
+
+func TestSearchTraceQL(t *testing.T) {
+	query := func(filter string) ([]*TraceQLSpan, bool, error) {
+		result := []*TraceQLSpan{}
+		for _, span := range traceQLSpans {
+			switch {
+			case strings.HasPrefix(filter, "trace_id IN"):
+				if strings.Contains(filter, quoteString(span.TraceID)) {
+					result = append(result, span)
+				}
+			case strings.Contains(filter, quoteString(span.Service)):
+				result = append(result, span)
+			}
+		}
+		return result, false, nil
+	}
+	cases := []struct {
+		query  string
+		traces []string
+		spans  int
+	}{
+		{`{ .service.name = "backend" }`, []string{"trace-2", "trace-1"}, 1},
+		{`{ .service.name = "frontend" } && { .service.name = "mysql" }`, []string{"trace-1"}, 2},
+		{`{ .service.name = "frontend" } || { .service.name = "backend" }`, []string{"trace-2", "trace-1"}, 2},
+		{`{ .service.name = "frontend" } >> { .service.name = "mysql" }`, []string{"trace-1"}, 1},
+		{`{ .service.name = "mysql" } >> { .service.name = "frontend" }`, []string{}, 0},
+	}
+	for _, c := range cases {
+		spanset, err := ParseTraceQL(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		traces, truncated, err := searchTraceQL(spanset, query, 10, 0, 0)
+		if err != nil || truncated {
+			t.Fatalf("search %s failed, truncated %v, err %v", c.query, truncated, err)
+		}
+		traceIDs := []string{}
+		for _, trace := range traces {
+			traceIDs = append(traceIDs, trace["traceID"].(string))
+		}
+		if strings.Join(traceIDs, ",") != strings.Join(c.traces, ",") {
+			t.Errorf("traces of %s should be %v, but %v", c.query, c.traces, traceIDs)
+			continue
+		}
+		if len(traces) > 0 {
+			trace := traces[len(traces)-1]
+			if trace["rootServiceName"] != "frontend" || trace["durationMs"] != int64(8) || trace["startTimeUnixNano"] != "1000000" {
+				t.Errorf("wrong trace %v", trace)
+			}
+			if matched := trace["spanSet"].(map[string]interface{})["matched"]; matched != c.spans {
+				t.Errorf("%d spans of %s should be matched, but %v", c.spans, c.query, matched)
+			}
+		}
+	}
+
+	spanset, _ := ParseTraceQL(`{ .service.name = "backend" }`)
+	if traces, _, _ := searchTraceQL(spanset, query, 1, 0, 0); len(traces) != 1 || traces[0]["traceID"] != "trace-2" {
+		t.Errorf("the latest trace should be returned")
+	}
+
+	// spans of trace-1 are truncated by the query limit
+	truncatedQuery := func(filter string) ([]*TraceQLSpan, bool, error) {
+		spans, _, err := query(filter)
+		if strings.HasPrefix(filter, "trace_id IN") && len(spans) > 2 {
+			return spans[len(spans)-2:], true, err
+		}
+		return spans, false, err
+	}
+	spanset, _ = ParseTraceQL(`{ .service.name = "frontend" } >> { .service.name = "mysql" }`)
+	if _, truncated, err := searchTraceQL(spanset, truncatedQuery, 10, 0, 0); err != nil || !truncated {
+		t.Errorf("traces should be truncated, err %v", err)
+	}
+}